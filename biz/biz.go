@@ -7,11 +7,8 @@ import (
 	"fmt"
 	"maps"
 	"path/filepath"
-	"slices"
 	"strings"
-	"sync"
 
-	"github.com/banbox/banbot/btime"
 	"github.com/banbox/banbot/config"
 	"github.com/banbox/banbot/core"
 	"github.com/banbox/banbot/exg"
@@ -21,11 +18,9 @@ import (
 	"github.com/banbox/banbot/rpc"
 	"github.com/banbox/banbot/strat"
 	"github.com/banbox/banbot/utils"
-	"github.com/banbox/banexg"
 	"github.com/banbox/banexg/errs"
 	"github.com/banbox/banexg/log"
 	utils2 "github.com/banbox/banexg/utils"
-	ta "github.com/banbox/banta"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -82,30 +77,51 @@ func SetupComsExg(args *config.CmdArgs) *errs.Error {
 	if err != nil {
 		return err
 	}
-	return orm.InitExg(exg.Default)
+	err = orm.InitExg(exg.Default)
+	if err != nil {
+		return err
+	}
+	return orm.InitPairExgs()
 }
 
-func RefreshPairs(showLog, cronStart bool, pBar *utils.StagedPrg) ([]string, map[string]map[string]float64, *errs.Error) {
-	goods.ShowLog = showLog
-	pairs, err := goods.RefreshPairList(cronStart)
+func (c *Ctx) RefreshPairs(showLog, cronStart bool, pBar *utils.StagedPrg) ([]string, map[string]map[string]float64, *errs.Error) {
+	if goods.ShowLog != showLog {
+		goods.ShowLog = showLog
+	}
+	// bind pairs to the exchange/market declared by run_policy, may be changed by reload
+	// 绑定品种到run_policy声明的交易所/市场，重载时可能变化
+	err := exg.LoadPolicyExgs(c.RunPolicy())
 	if err != nil {
 		return nil, nil, err
 	}
-	if pBar != nil {
-		pBar.SetProgress("loadPairs", 1)
+	err = orm.InitPairExgs()
+	if err != nil {
+		return nil, nil, err
 	}
-	pairTfScores, err := strat.CalcPairTfScores(exg.Default, pairs)
+	c.refreshLiveOdMgrs()
+	pairs, err := goods.RefreshPairList(c.BotCtx, cronStart)
 	if err != nil {
 		return nil, nil, err
 	}
+	if pBar != nil {
+		pBar.SetProgress("loadPairs", 1)
+	}
+	pairTfScores := make(map[string]map[string]float64)
+	for exchange, items := range exg.GroupPairs(pairs) {
+		scores, err := c.CalcPairTfScores(exchange, items)
+		if err != nil {
+			return nil, nil, err
+		}
+		maps.Copy(pairTfScores, scores)
+	}
 	if pBar != nil {
 		pBar.SetProgress("tfScores", 1)
 	}
 	return pairs, pairTfScores, nil
 }
 
-func RefreshJobs(pairs []string, pairTfScores map[string]map[string]float64, showLog bool, pBar *utils.StagedPrg) (map[string]map[string]int, *errs.Error) {
-	warms, accOds, err := strat.LoadStratJobs(pairs, pairTfScores)
+func (c *Ctx) RefreshJobs(pairs []string, pairTfScores map[string]map[string]float64, showLog bool, pBar *utils.StagedPrg) (map[string]map[string]int, *errs.Error) {
+	warms, accOds, err := c.LoadStratJobs(pairs, pairTfScores)
 	if err != nil {
 		return nil, err
 	}
@@ -120,7 +136,7 @@ func RefreshJobs(pairs []string, pairTfScores map[string]map[string]float64, sho
 			defer conn.Close()
 		}
 		for acc, odList := range accOds {
-			odMgr := GetOdMgr(acc)
+			odMgr := c.GetOdMgr(acc)
 			err = odMgr.ExitAndFill(sess, odList, &strat.ExitReq{Tag: core.ExitTagPairDel})
 			if err != nil {
 				return nil, err
@@ -129,7 +145,7 @@ func RefreshJobs(pairs []string, pairTfScores map[string]map[string]float64, sho
 		}
 	}
 	if showLog {
-		core.PrintStratGroups()
+		c.PrintStratGroups()
 	}
 	if pBar != nil {
 		pBar.SetProgress("loadJobs", 1)
@@ -142,9 +158,9 @@ InitOdSubs 为所有策略OnOrderChange注册订单事件监听。
 
 只需在LoadStratJobs后调用一次，交易的Accounts不变就始终生效
 */
-func InitOdSubs() {
+func (c *Ctx) InitOdSubs() {
 	var subStgys = map[string]*strat.TradeStrat{}
-	for _, items := range strat.PairStrats {
+	for _, items := range c.PairStrats {
 		for stgName, stagy := range items {
 			if stagy.OnOrderChange != nil {
 				subStgys[stgName] = stagy
@@ -154,15 +170,15 @@ func InitOdSubs() {
 	if len(subStgys) == 0 {
 		return
 	}
-	for acc := range strat.AccJobs {
-		strat.AddOdSub(acc, func(acc string, od *ormo.InOutOrder, evt int) {
+	for acc := range c.AccJobs {
+		c.AddOdSub(acc, func(acc string, od *ormo.InOutOrder, evt int) {
 			stgy, ok := subStgys[od.Strategy]
 			if !ok {
 				// The current strategy does not monitor order status
 				// 当前策略未监听订单状态
 				return
 			}
-			items, _ := strat.AccJobs[acc]
+			items, _ := c.AccJobs[acc]
 			if len(items) == 0 {
 				return
 			}
@@ -179,9 +195,6 @@ func InitOdSubs() {
 	}
 }
 
-// Preventing Concurrent Modification of BatchTasks
-var lockBatch = sync.Mutex{} // 防止并发修改BatchTasks
-
 /*
 AddBatchJob
 Add batch entry tasks.
@@ -189,31 +202,31 @@ Even if the job has no entry tasks, this method should be called to postpone the
 添加批量入场任务。
 即使job没有入场任务，也应该调用此方法，用于推迟入场时间TFEnterMS
 */
-func AddBatchJob(account, tf string, job *strat.StratJob, isInfo bool) {
-	lockBatch.Lock()
-	defer lockBatch.Unlock()
+func (c *Ctx) AddBatchJob(account, tf string, job *strat.StratJob, isInfo bool) {
+	c.lockBatch.Lock()
+	defer c.lockBatch.Unlock()
 	key := fmt.Sprintf("%s_%s_%s", tf, account, job.Strat.Name)
-	tasks, ok := strat.BatchTasks[key]
+	tasks, ok := c.BatchTasks[key]
 	if !ok {
 		tasks = &strat.BatchMap{
 			Map:     make(map[string]*strat.BatchTask),
 			TFMSecs: int64(utils2.TFToSecs(tf) * 1000),
 		}
-		strat.BatchTasks[key] = tasks
+		c.BatchTasks[key] = tasks
 	}
 	// Delay 3s to wait for execution
 	// 推迟3s等待执行
-	tasks.ExecMS = btime.TimeMS() + core.DelayBatchMS
+	tasks.ExecMS = c.TimeMS() + core.DelayBatchMS
 	var batchType = strat.BatchTypeInOut
 	if isInfo {
 		batchType = strat.BatchTypeInfo
 	}
-	tasks.Map[job.Symbol.Symbol] = &strat.BatchTask{Job: job, Type: batchType}
+	tasks.Map[job.Symbol.PairKey()] = &strat.BatchTask{Job: job, Type: batchType}
 }
 
-func TryFireBatches(currMS int64) int {
-	lockBatch.Lock()
-	defer lockBatch.Unlock()
+func (c *Ctx) TryFireBatches(currMS int64) int {
+	c.lockBatch.Lock()
+	defer c.lockBatch.Unlock()
 	var sess *ormo.Queries
 	var conn *sql.DB
 	var err *errs.Error
@@ -228,7 +241,7 @@ func TryFireBatches(currMS int64) int {
 		defer conn.Close()
 	}
 	var waitNum = 0
-	for key, tasks := range strat.BatchTasks {
+	for key, tasks := range c.BatchTasks {
 		if currMS < tasks.ExecMS {
 			if tasks.ExecMS-currMS < tasks.TFMSecs/2 {
 				// Batch processing time has not yet arrived
@@ -250,7 +263,7 @@ func TryFireBatches(currMS int64) int {
 				panic(fmt.Sprintf("unsupport BatchType: %v", task.Type))
 			}
 		}
-		delete(strat.BatchTasks, key)
+		delete(c.BatchTasks, key)
 		if len(enterJobs) > 0 {
 			// Check all batch tasks at this time and decide which ones to enter or exit
 			// 检查此时间所有批量任务，决定哪些入场或那些出场
@@ -258,7 +271,7 @@ func TryFireBatches(currMS int64) int {
 			// Perform entry/exit tasks
 			// 执行入场/出场任务
 			keyParts := strings.Split(key, "_")
-			odMgr := GetOdMgr(keyParts[1])
+			odMgr := c.GetOdMgr(keyParts[1])
 			var ents []*ormo.InOutOrder
 			var exits []*ormo.InOutOrder
 			for _, job := range enterJobs {
@@ -286,107 +299,6 @@ func TryFireBatches(currMS int64) int {
 	return waitNum
 }
 
-func ResetVars() {
-	core.NoEnterUntil = make(map[string]int64)
-	core.PairCopiedMs = make(map[string][2]int64)
-	core.TfPairHits = make(map[string]map[string]int)
-	core.JobPerfs = make(map[string]*core.JobPerf)
-	core.StratPerfSta = make(map[string]*core.PerfSta)
-	accLiveOdMgrs = make(map[string]*LiveOrderMgr)
-	accOdMgrs = make(map[string]IOrderMgr)
-	accWallets = make(map[string]*BanWallets)
-	core.LastBarMs = 0
-	core.OdBooks = make(map[string]*banexg.OrderBook)
-	ormo.HistODs = make([]*ormo.InOutOrder, 0)
-	ormo.ResetVars()
-	strat.Envs = make(map[string]*ta.BarEnv)
-	strat.AccJobs = make(map[string]map[string]map[string]*strat.StratJob)
-	strat.AccInfoJobs = make(map[string]map[string]map[string]*strat.StratJob)
-	strat.PairStrats = make(map[string]map[string]*strat.TradeStrat)
-	strat.BatchTasks = make(map[string]*strat.BatchMap)
-	strat.ForbidJobs = make(map[string]map[string]bool)
-	strat.LastBatchMS = 0
-}
-
-type VarsBackup struct {
-	Pairs         []string
-	PairMap       map[string]bool
-	NoEnterUntil  map[string]int64
-	PairCopiedMs  map[string][2]int64
-	TfPairHits    map[string]map[string]int
-	JobPerfs      map[string]*core.JobPerf
-	StratPerfSta  map[string]*core.PerfSta
-	AccLiveOdMgrs map[string]*LiveOrderMgr
-	AccOdMgrs     map[string]IOrderMgr
-	AccWallets    map[string]*BanWallets
-	LastBarMs     int64
-	OdBooks       map[string]*banexg.OrderBook
-	HistODs       []*ormo.InOutOrder
-	Envs          map[string]*ta.BarEnv
-	AccJobs       map[string]map[string]map[string]*strat.StratJob
-	AccInfoJobs   map[string]map[string]map[string]*strat.StratJob
-	PairStrats    map[string]map[string]*strat.TradeStrat
-	BatchTasks    map[string]*strat.BatchMap
-	ForbidJobs    map[string]map[string]bool
-	LastBatchMS   int64
-	OrmoBackup    *ormo.VarsBackup
-}
-
-// BackupVars 备份所有全局变量
-func BackupVars() *VarsBackup {
-	return &VarsBackup{
-		Pairs:         slices.Clone(core.Pairs),
-		PairMap:       maps.Clone(core.PairsMap),
-		NoEnterUntil:  core.NoEnterUntil,
-		PairCopiedMs:  core.PairCopiedMs,
-		TfPairHits:    core.TfPairHits,
-		JobPerfs:      core.JobPerfs,
-		StratPerfSta:  core.StratPerfSta,
-		AccLiveOdMgrs: accLiveOdMgrs,
-		AccOdMgrs:     accOdMgrs,
-		AccWallets:    accWallets,
-		LastBarMs:     core.LastBarMs,
-		OdBooks:       core.OdBooks,
-		HistODs:       ormo.HistODs,
-		Envs:          strat.Envs,
-		AccJobs:       strat.AccJobs,
-		AccInfoJobs:   strat.AccInfoJobs,
-		PairStrats:    strat.PairStrats,
-		BatchTasks:    strat.BatchTasks,
-		ForbidJobs:    strat.ForbidJobs,
-		LastBatchMS:   strat.LastBatchMS,
-		OrmoBackup:    ormo.BackupVars(),
-	}
-}
-
-// RestoreVars 从备份中恢复所有全局变量
-func RestoreVars(backup *VarsBackup) {
-	if backup == nil {
-		return
-	}
-	core.Pairs = backup.Pairs
-	core.PairsMap = backup.PairMap
-	core.NoEnterUntil = backup.NoEnterUntil
-	core.PairCopiedMs = backup.PairCopiedMs
-	core.TfPairHits = backup.TfPairHits
-	core.JobPerfs = backup.JobPerfs
-	core.StratPerfSta = backup.StratPerfSta
-	accLiveOdMgrs = backup.AccLiveOdMgrs
-	accOdMgrs = backup.AccOdMgrs
-	accWallets = backup.AccWallets
-	core.LastBarMs = backup.LastBarMs
-	core.OdBooks = backup.OdBooks
-	ormo.HistODs = backup.HistODs
-	strat.Envs = backup.Envs
-	strat.AccJobs = backup.AccJobs
-	strat.AccInfoJobs = backup.AccInfoJobs
-	strat.PairStrats = backup.PairStrats
-	strat.BatchTasks = backup.BatchTasks
-	strat.ForbidJobs = backup.ForbidJobs
-	strat.LastBatchMS = backup.LastBatchMS
-	ormo.RestoreVars(backup.OrmoBackup)
-}
-
 func replaceDockerHosts(data []byte) []byte {
 	if !utils.IsDocker() {
 		return data
@@ -0,0 +1,63 @@
+package biz
+
+import (
+	"sync"
+
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/orm/ormo"
+	"github.com/banbox/banbot/strat"
+)
+
+/*
+Ctx
+Order managers and wallets of a running bot, based on strat.Ctx.
+The live bot uses DefCtx; each backtest creates its own by NewRunCtx, so multiple backtests can run in parallel
+in one process, sharing the read-only klines and exchange markets.
+正在运行的机器人的订单管理器和钱包，基于strat.Ctx。
+实盘使用DefCtx；每个回测通过NewRunCtx创建自己的上下文，以便一个进程中并行运行多个回测，共享只读的K线和交易所市场。
+*/
+type Ctx struct {
+	*strat.Ctx
+	accOdMgrs     map[string]IOrderMgr
+	accLiveOdMgrs map[string]map[string]*LiveOrderMgr // account: exchange.market: LiveOrderMgr
+	accWallets    map[string]*BanWallets
+	lockBatch     sync.Mutex // Preventing Concurrent Modification of BatchTasks 防止并发修改BatchTasks
+	liveOdStarted bool       // whether StartLiveOdMgr is called 是否已调用StartLiveOdMgr
+}
+
+// DefCtx context of the live bot, based on strat.DefCtx 实盘使用的上下文，基于strat.DefCtx
+var DefCtx = NewCtx(strat.DefCtx)
+
+func NewCtx(st *strat.Ctx) *Ctx {
+	c := &Ctx{Ctx: st}
+	c.clearMgrs()
+	st.AccEquity = func(account string) float64 {
+		return c.AccTotalLegal(account, true)
+	}
+	return c
+}
+
+/*
+NewRunCtx
+Create an empty context with all layers for an independent run like backtest
+为回测等独立运行创建一个包含所有层的空上下文
+*/
+func NewRunCtx() *Ctx {
+	return NewCtx(strat.NewCtx(ormo.NewCtx(core.NewBotCtx())))
+}
+
+/*
+Reset
+Clear all state of this context, subscriptions of order changes are kept.
+清空此上下文的所有状态，保留订单变化的订阅。
+*/
+func (c *Ctx) Reset() {
+	c.Ctx.Reset()
+	c.clearMgrs()
+}
+
+func (c *Ctx) clearMgrs() {
+	c.accOdMgrs = make(map[string]IOrderMgr)
+	c.accLiveOdMgrs = make(map[string]map[string]*LiveOrderMgr)
+	c.accWallets = make(map[string]*BanWallets)
+}
@@ -0,0 +1,80 @@
+package biz
+
+import (
+	"sync"
+	"time"
+
+	"github.com/banbox/banexg/errs"
+)
+
+// SubmitBuckets latency buckets(seconds) of order submit 订单提交延迟的分桶(秒)
+var SubmitBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+/*
+SubmitStat
+Latency and error statistics of orders submitted to exchange by LiveOrderMgr, grouped by account and action.
+LiveOrderMgr向交易所提交订单的延迟和错误统计，按账户和动作分组。
+*/
+type SubmitStat struct {
+	Account string
+	Action  string  // create/edit/cancel
+	Count   int64   // total requests 总请求数
+	Errors  int64   // failed requests 失败请求数
+	SumSecs float64 // total latency in seconds 总耗时(秒)
+	Buckets []int64 // cumulative count for each SubmitBuckets 每个SubmitBuckets的累计数量
+}
+
+var (
+	submitStats    = make(map[string]*SubmitStat)
+	lockSubmitStat sync.Mutex
+)
+
+const (
+	SubmitCreate = "create"
+	SubmitEdit   = "edit"
+	SubmitCancel = "cancel"
+)
+
+/*
+observeSubmit
+record latency and result of one request to exchange
+记录一次交易所请求的耗时和结果
+*/
+func observeSubmit(account, action string, start time.Time, err *errs.Error) {
+	secs := time.Since(start).Seconds()
+	key := account + "_" + action
+	lockSubmitStat.Lock()
+	sta, ok := submitStats[key]
+	if !ok {
+		sta = &SubmitStat{Account: account, Action: action, Buckets: make([]int64, len(SubmitBuckets))}
+		submitStats[key] = sta
+	}
+	sta.Count += 1
+	sta.SumSecs += secs
+	if err != nil {
+		sta.Errors += 1
+	}
+	for i, le := range SubmitBuckets {
+		if secs <= le {
+			sta.Buckets[i] += 1
+		}
+	}
+	lockSubmitStat.Unlock()
+}
+
+/*
+GetSubmitStats
+return a copy of the order submit statistics
+返回订单提交统计的副本
+*/
+func GetSubmitStats() []*SubmitStat {
+	lockSubmitStat.Lock()
+	res := make([]*SubmitStat, 0, len(submitStats))
+	for _, sta := range submitStats {
+		item := *sta
+		item.Buckets = append([]int64{}, sta.Buckets...)
+		res = append(res, &item)
+	}
+	lockSubmitStat.Unlock()
+	return res
+}
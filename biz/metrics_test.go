@@ -0,0 +1,32 @@
+package biz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banexg/errs"
+)
+
+func TestObserveSubmit(t *testing.T) {
+	start := time.Now()
+	observeSubmit("acc1", SubmitCreate, start, nil)
+	observeSubmit("acc1", SubmitCreate, start.Add(-3*time.Second), errs.NewMsg(core.ErrRunTime, "timeout"))
+	observeSubmit("acc1", SubmitCancel, start, nil)
+	var create *SubmitStat
+	for _, sta := range GetSubmitStats() {
+		if sta.Account == "acc1" && sta.Action == SubmitCreate {
+			create = sta
+		}
+	}
+	if create == nil {
+		t.Fatal("create stat missing")
+	}
+	if create.Count != 2 || create.Errors != 1 || create.SumSecs < 3 {
+		t.Errorf("invalid stat: %+v", create)
+	}
+	// 0.05s bucket only contains the fast one, 5s bucket contains both 0.05s分桶仅包含快的，5s分桶包含两个
+	if create.Buckets[0] != 1 || create.Buckets[len(create.Buckets)-2] != 2 {
+		t.Errorf("invalid buckets: %v", create.Buckets)
+	}
+}
@@ -1,7 +1,6 @@
 package biz
 
 import (
-	"github.com/banbox/banbot/btime"
 	"github.com/banbox/banbot/config"
 	"github.com/banbox/banbot/core"
 	"github.com/banbox/banbot/exg"
@@ -20,11 +19,6 @@ import (
 	"strings"
 )
 
-var (
-	accOdMgrs     = make(map[string]IOrderMgr)
-	accLiveOdMgrs = make(map[string]*LiveOrderMgr)
-)
-
 type IOrderMgr interface {
 	ProcessOrders(sess *ormo.Queries, env *banta.BarEnv, enters []*strat.EnterReq,
 		exits []*strat.ExitReq, edits []*ormo.InOutEdit) ([]*ormo.InOutOrder, []*ormo.InOutOrder, *errs.Error)
@@ -33,6 +27,7 @@ type IOrderMgr interface {
 	ExitOpenOrders(sess *ormo.Queries, pairs string, req *strat.ExitReq) ([]*ormo.InOutOrder, *errs.Error)
 	ExitOrder(sess *ormo.Queries, od *ormo.InOutOrder, req *strat.ExitReq) (*ormo.InOutOrder, *errs.Error)
 	UpdateByBar(allOpens []*ormo.InOutOrder, bar *orm.InfoKline) *errs.Error
+	UpdateByTrades(allOpens []*ormo.InOutOrder, pair string, trades []*banexg.Trade) *errs.Error
 	ExitAndFill(sess *ormo.Queries, orders []*ormo.InOutOrder, req *strat.ExitReq) *errs.Error
 	OnEnvEnd(bar *banexg.PairTFKline, adj *orm.AdjInfo) *errs.Error
 	CleanUp() *errs.Error
@@ -49,6 +44,7 @@ type IOrderMgrLive interface {
 type FuncHandleIOrder = func(order *ormo.InOutOrder) *errs.Error
 
 type OrderMgr struct {
+	ctx         *Ctx
 	callBack    func(order *ormo.InOutOrder, isEnter bool)
 	afterEnter  FuncHandleIOrder
 	afterExit   FuncHandleIOrder
@@ -56,50 +52,78 @@ type OrderMgr struct {
 	BarMS       int64
 	simulOpen   int // Simultaneously open number in the current bar
 	simulOpenSt map[string]int
+	risk        *RiskMgr // nil if risk is not configured 未配置risk时为nil
+	exgName     string   // exchange of orders handled, empty for all 处理的订单的交易所，为空时处理全部
+	market      string   // market of orders handled 处理的订单的市场
 }
 
-func GetOdMgr(account string) IOrderMgr {
+func (c *Ctx) GetOdMgr(account string) IOrderMgr {
 	if !core.EnvReal {
 		account = config.DefAcc
 	}
-	val, _ := accOdMgrs[account]
+	val, _ := c.accOdMgrs[account]
 	return val
 }
 
-func GetAllOdMgr() map[string]IOrderMgr {
-	var result = make(map[string]IOrderMgr)
-	if core.EnvReal {
-		for acc, mgr := range accLiveOdMgrs {
-			result[acc] = mgr
-		}
-	} else {
-		for acc, mgr := range accOdMgrs {
-			result[acc] = mgr
-		}
-	}
-	return result
+func (c *Ctx) GetAllOdMgr() map[string]IOrderMgr {
+	return maps.Clone(c.accOdMgrs)
 }
 
-func GetLiveOdMgr(account string) *LiveOrderMgr {
+func venueKey(exgName, market string) string {
+	return exgName + "." + market
+}
+
+// GetLiveOdMgr return the LiveOrderMgr of account on default exchange/market 返回账户在默认交易所/市场的LiveOrderMgr
+func (c *Ctx) GetLiveOdMgr(account string) *LiveOrderMgr {
+	return c.GetVenueLiveOdMgr(account, core.ExgName, core.Market)
+}
+
+// GetVenueLiveOdMgr return the LiveOrderMgr of account on exchange/market 返回账户在交易所/市场的LiveOrderMgr
+func (c *Ctx) GetVenueLiveOdMgr(account, exgName, market string) *LiveOrderMgr {
 	if !core.EnvReal {
 		panic("call GetLiveOdMgr in FakeEnv is forbidden: " + core.RunEnv)
 	}
-	val, _ := accLiveOdMgrs[account]
+	val, _ := c.accLiveOdMgrs[account][venueKey(exgName, market)]
 	return val
 }
 
-func CleanUpOdMgr() *errs.Error {
+// GetPairLiveOdMgr return the LiveOrderMgr of account which handles the pair key 返回账户中处理此品种键的LiveOrderMgr
+func (c *Ctx) GetPairLiveOdMgr(account, pair string) *LiveOrderMgr {
+	exgName, market, _ := core.SplitPairKey(pair)
+	return c.GetVenueLiveOdMgr(account, exgName, market)
+}
+
+/*
+GetLiveOdMgrs
+Return LiveOrderMgr of all exchanges/markets of account, the default exchange/market is the first.
+返回账户所有交易所/市场的LiveOrderMgr，默认交易所/市场为第一个
+*/
+func (c *Ctx) GetLiveOdMgrs(account string) []*LiveOrderMgr {
+	if !core.EnvReal {
+		panic("call GetLiveOdMgrs in FakeEnv is forbidden: " + core.RunEnv)
+	}
+	venues, _ := c.accLiveOdMgrs[account]
+	defKey := venueKey(core.ExgName, core.Market)
+	res := make([]*LiveOrderMgr, 0, len(venues))
+	if mgr, ok := venues[defKey]; ok {
+		res = append(res, mgr)
+	}
+	keys := utils.KeysOfMap(venues)
+	slices.Sort(keys)
+	for _, key := range keys {
+		if key != defKey {
+			res = append(res, venues[key])
+		}
+	}
+	return res
+}
+
+func (c *Ctx) CleanUpOdMgr() *errs.Error {
 	var err *errs.Error
 	for account := range config.Accounts {
 		var curErr *errs.Error
-		if core.EnvReal {
-			if mgr, ok := accLiveOdMgrs[account]; ok {
-				curErr = mgr.CleanUp()
-			}
-		} else {
-			if mgr, ok := accOdMgrs[account]; ok {
-				curErr = mgr.CleanUp()
-			}
+		if mgr, ok := c.accOdMgrs[account]; ok {
+			curErr = mgr.CleanUp()
 		}
 		if curErr != nil {
 			if err != nil {
@@ -112,13 +136,22 @@ func CleanUpOdMgr() *errs.Error {
 	return err
 }
 
+// hasPair whether orders of the pair key are handled by this manager 此管理器是否处理此品种键的订单
+func (o *OrderMgr) hasPair(pair string) bool {
+	if o.exgName == "" {
+		return true
+	}
+	exgName, market, _ := core.SplitPairKey(pair)
+	return exgName == o.exgName && market == o.market
+}
+
 func (o *OrderMgr) allowOrderEnter(env *banta.BarEnv, enters []*strat.EnterReq) []*strat.EnterReq {
-	curMS := btime.TimeMS()
-	if banUntil, ok := core.BanPairsUntil[env.Symbol]; ok {
+	curMS := o.ctx.TimeMS()
+	if banUntil, ok := o.ctx.BanPairsUntil[env.Symbol]; ok {
 		if curMS < banUntil {
 			return nil
 		} else {
-			delete(core.BanPairsUntil, env.Symbol)
+			delete(o.ctx.BanPairsUntil, env.Symbol)
 		}
 	}
 	if core.RunMode == core.RunModeOther {
@@ -127,20 +160,20 @@ func (o *OrderMgr) allowOrderEnter(env *banta.BarEnv, enters []*strat.EnterReq)
 		return nil
 	}
 	pairZapField := zap.String("pair", env.Symbol)
-	stopUntil, _ := core.NoEnterUntil[o.Account]
+	stopUntil, _ := o.ctx.NoEnterUntil[o.Account]
 	if curMS < stopUntil {
 		if core.LiveMode {
 			log.Warn("any enter forbid", pairZapField)
 		}
-		strat.AddAccFailOpens(o.Account, strat.FailOpenNoEntry, len(enters))
+		o.ctx.AddAccFailOpens(o.Account, strat.FailOpenNoEntry, len(enters))
 		return nil
 	}
-	if core.LiveMode {
-		// The real order is submitted to the exchange, and the inspection delay cannot exceed 80%
-		// 实盘订单提交到交易所，检查延迟不能超过80%
+	if core.LiveMode && slices.ContainsFunc(enters, func(e *strat.EnterReq) bool { return !e.UserOpen }) {
+		// The real order is submitted to the exchange, and the inspection delay cannot exceed 80%. Skip for manual orders
+		// 实盘订单提交到交易所，检查延迟不能超过80%。手动开单跳过
 		rate := float64(curMS-env.TimeStop) / float64(env.TimeStop-env.TimeStart)
 		if rate > 0.8 {
-			strat.AddAccFailOpens(o.Account, strat.FailOpenBarTooLate, len(enters))
+			o.ctx.AddAccFailOpens(o.Account, strat.FailOpenBarTooLate, len(enters))
 			return nil
 		}
 	}
@@ -149,7 +182,15 @@ func (o *OrderMgr) allowOrderEnter(env *banta.BarEnv, enters []*strat.EnterReq)
 		o.simulOpen = 0
 		o.simulOpenSt = make(map[string]int)
 	}
-	openOds, lock := ormo.GetOpenODs(o.Account)
+	if o.risk != nil {
+		// Check exposure caps and daily loss
+		// 检查敞口上限和每日亏损
+		enters = o.risk.CheckEnters(env.Symbol, enters)
+		if len(enters) == 0 {
+			return nil
+		}
+	}
+	openOds, lock := o.ctx.GetOpenODs(o.Account)
 	lock.Lock()
 	maxOpenNum := config.MaxOpenOrders
 	acc, _ := config.Accounts[o.Account]
@@ -162,7 +203,7 @@ func (o *OrderMgr) allowOrderEnter(env *banta.BarEnv, enters []*strat.EnterReq)
 		enters = checkOrderNum(enters, o.simulOpen, config.MaxSimulOpen, "max_simul_open")
 	}
 	if orgNum > len(enters) {
-		strat.AddAccFailOpens(o.Account, strat.FailOpenNumLimit, orgNum-len(enters))
+		o.ctx.AddAccFailOpens(o.Account, strat.FailOpenNumLimit, orgNum-len(enters))
 	}
 	if len(enters) == 0 {
 		lock.Unlock()
@@ -180,7 +221,7 @@ func (o *OrderMgr) allowOrderEnter(env *banta.BarEnv, enters []*strat.EnterReq)
 	for _, req := range enters {
 		num, _ := stratOdNum[req.StratName]
 		simulNum, _ := o.simulOpenSt[req.StratName]
-		pol := strat.Get(env.Symbol, req.StratName).Policy
+		pol := o.ctx.Get(env.Symbol, req.StratName).Policy
 		if pol != nil {
 			if pol.MaxOpen > 0 && num >= pol.MaxOpen {
 				skipNum += 1
@@ -198,7 +239,7 @@ func (o *OrderMgr) allowOrderEnter(env *banta.BarEnv, enters []*strat.EnterReq)
 	}
 	lock.Unlock()
 	if skipNum > 0 {
-		strat.AddAccFailOpens(o.Account, strat.FailOpenNumLimitPol, skipNum)
+		o.ctx.AddAccFailOpens(o.Account, strat.FailOpenNumLimitPol, skipNum)
 	}
 	return res
 }
@@ -259,14 +300,13 @@ func (o *OrderMgr) ProcessOrders(sess *ormo.Queries, env *banta.BarEnv, enters [
 }
 
 func (o *OrderMgr) RelayOrders(sess *ormo.Queries, orders []*ormo.InOutOrder) *errs.Error {
-	symbolMap := orm.GetExSymbolMap(core.ExgName, core.Market)
-	taskId := ormo.GetTaskID(o.Account)
+	taskId := o.ctx.GetTaskID(o.Account)
 	for _, odr := range orders {
-		exs, ok := symbolMap[odr.Symbol]
-		if !ok {
+		exs, err := orm.GetExSymbolCur(odr.Symbol)
+		if err != nil {
 			return errs.NewMsg(errs.CodeNoMarketForPair, "%s not found", odr.Symbol)
 		}
-		price := core.GetPrice(odr.Symbol)
+		price := o.ctx.GetPrice(odr.Symbol)
 		od := &ormo.InOutOrder{
 			IOrder: &ormo.IOrder{
 				TaskID:    taskId,
@@ -294,7 +334,7 @@ func (o *OrderMgr) RelayOrders(sess *ormo.Queries, orders []*ormo.InOutOrder) *e
 				OrderType: odr.Enter.OrderType,
 				//OrderID:   odr.Enter.OrderID,
 				Side:     odr.Enter.Side,
-				CreateAt: btime.TimeMS(),
+				CreateAt: o.ctx.TimeMS(),
 				Price:    price,
 				Amount:   odr.Enter.Amount,
 				Status:   ormo.OdStatusInit,
@@ -303,6 +343,7 @@ func (o *OrderMgr) RelayOrders(sess *ormo.Queries, orders []*ormo.InOutOrder) *e
 			DirtyMain:  true,
 			DirtyEnter: true,
 		}
+		od.SetCtx(o.ctx.OdCtx())
 		if odr.Exit != nil && odr.Exit.Filled > 0 {
 			od.Enter.Amount -= odr.Exit.Filled
 			od.QuoteCost = od.Enter.Price * od.Enter.Amount
@@ -310,7 +351,7 @@ func (o *OrderMgr) RelayOrders(sess *ormo.Queries, orders []*ormo.InOutOrder) *e
 		if len(odr.Info) > 0 {
 			maps.Copy(od.Info, odr.Info)
 		}
-		err := od.Save(sess)
+		err = od.Save(sess)
 		if err == nil {
 			if o.afterEnter != nil {
 				err = o.afterEnter(od)
@@ -324,7 +365,7 @@ func (o *OrderMgr) RelayOrders(sess *ormo.Queries, orders []*ormo.InOutOrder) *e
 }
 
 func (o *OrderMgr) EnterOrder(sess *ormo.Queries, env *banta.BarEnv, req *strat.EnterReq, doCheck bool) (*ormo.InOutOrder, *errs.Error) {
-	isSpot := core.Market == banexg.MarketSpot
+	isSpot := env.MarketType == banexg.MarketSpot
 	if req.Short && isSpot {
 		return nil, errs.NewMsg(core.ErrRunTime, "short oder is invalid for spot")
 	}
@@ -337,21 +378,21 @@ func (o *OrderMgr) EnterOrder(sess *ormo.Queries, env *banta.BarEnv, req *strat.
 	if req.Leverage == 0 {
 		req.Leverage = 1
 		if !isSpot {
-			exchange := exg.Default
+			exchange := exg.GetPairExg(env.Symbol)
 			exInfo := exchange.Info()
 			if exInfo.FixedLvg {
-				req.Leverage, _ = exchange.GetLeverage(env.Symbol, 0, o.Account)
+				req.Leverage, _ = exchange.GetLeverage(core.PairSymbol(env.Symbol), 0, o.Account)
 			} else {
 				req.Leverage = config.GetAccLeverage(o.Account)
 			}
 		}
 	}
-	stgVer, _ := strat.Versions[req.StratName]
+	stgVer, _ := o.ctx.Versions[req.StratName]
 	odSide := banexg.OdSideBuy
 	if req.Short {
 		odSide = banexg.OdSideSell
 	}
-	taskId := ormo.GetTaskID(o.Account)
+	taskId := o.ctx.GetTaskID(o.Account)
 	od := &ormo.InOutOrder{
 		IOrder: &ormo.IOrder{
 			TaskID:    taskId,
@@ -361,9 +402,9 @@ func (o *OrderMgr) EnterOrder(sess *ormo.Queries, env *banta.BarEnv, req *strat.
 			Short:     req.Short,
 			Status:    ormo.InOutStatusInit,
 			EnterTag:  req.Tag,
-			InitPrice: core.GetPrice(env.Symbol),
+			InitPrice: o.ctx.GetPrice(env.Symbol),
 			Leverage:  req.Leverage,
-			EnterAt:   btime.TimeMS(),
+			EnterAt:   o.ctx.TimeMS(),
 			Strategy:  req.StratName,
 			StgVer:    int64(stgVer),
 		},
@@ -381,6 +422,7 @@ func (o *OrderMgr) EnterOrder(sess *ormo.Queries, env *banta.BarEnv, req *strat.
 		DirtyMain:  true,
 		DirtyEnter: true,
 	}
+	od.SetCtx(o.ctx.OdCtx())
 	if od.Enter.OrderType == "" {
 		od.Enter.OrderType = config.OrderType
 	}
@@ -390,17 +432,20 @@ func (o *OrderMgr) EnterOrder(sess *ormo.Queries, env *banta.BarEnv, req *strat.
 			req.StopBars = config.StopEnterBars
 		}
 		if req.StopBars > 0 {
-			stopAfter := btime.TimeMS() + int64(req.StopBars*utils.TFToSecs(od.Timeframe))*1000
+			stopAfter := o.ctx.TimeMS() + int64(req.StopBars*utils.TFToSecs(od.Timeframe))*1000
 			od.SetInfo(ormo.OdInfoStopAfter, stopAfter)
 		}
 	}
 	od.SetInfo(ormo.OdInfoLegalCost, req.LegalCost)
-	if req.StopLoss > 0 {
+	if req.StopLoss > 0 || req.TrailCallback > 0 || req.TrailDistance > 0 {
 		od.SetStopLoss(&ormo.ExitTrigger{
-			Price: req.StopLoss,
-			Limit: req.StopLossLimit,
-			Rate:  req.StopLossRate,
-			Tag:   req.StopLossTag,
+			Price:    req.StopLoss,
+			Limit:    req.StopLossLimit,
+			Rate:     req.StopLossRate,
+			Tag:      req.StopLossTag,
+			Activate: req.TrailActivate,
+			Callback: req.TrailCallback,
+			Distance: req.TrailDistance,
 		})
 	}
 	if req.TakeProfit > 0 {
@@ -424,7 +469,7 @@ func (o *OrderMgr) EnterOrder(sess *ormo.Queries, env *banta.BarEnv, req *strat.
 func (o *OrderMgr) ExitOpenOrders(sess *ormo.Queries, pairs string, req *strat.ExitReq) ([]*ormo.InOutOrder, *errs.Error) {
 	// Filter matching orders 筛选匹配的订单
 	var matches []*ormo.InOutOrder
-	openOds, lock := ormo.GetOpenODs(o.Account)
+	openOds, lock := o.ctx.GetOpenODs(o.Account)
 	if req.OrderID > 0 {
 		// Specify the exact order ID to exit 精确指定退出的订单ID
 		lock.Lock()
@@ -447,7 +492,7 @@ func (o *OrderMgr) ExitOpenOrders(sess *ormo.Queries, pairs string, req *strat.E
 		isShort := req.Dirt == core.OdDirtShort
 		lock.Lock()
 		for _, od := range openOds {
-			if req.StratName != "" && od.Strategy != req.StratName {
+			if req.StratName != "" && od.Strategy != req.StratName || !o.hasPair(od.Symbol) {
 				continue
 			}
 			if len(pairMap) > 0 {
@@ -504,7 +549,7 @@ func (o *OrderMgr) ExitOpenOrders(sess *ormo.Queries, pairs string, req *strat.E
 				return nil, errs.NewMsg(errs.CodeParamInvalid, "ExitReq.Limit invalid for multi pairs")
 			}
 		}
-		price := core.GetPrice(symbol)
+		price := o.ctx.GetPrice(symbol)
 		if price > 0 && (req.Limit-price)*float64(req.Dirt) > 0 {
 			isTakeProfit = true
 		}
@@ -600,7 +645,7 @@ func (o *OrderMgr) ExitOrder(sess *ormo.Queries, od *ormo.InOutOrder, req *strat
 		return nil, errs.NewMsg(errs.CodeParamInvalid, "`ExitReq.Dirt` mismatch with Order")
 	}
 	if req.Limit > 0 && core.IsLimitOrder(req.OrderType) {
-		price := core.GetPrice(od.Symbol)
+		price := o.ctx.GetPrice(od.Symbol)
 		if price > 0 && (req.Limit-price)*float64(req.Dirt) > 0 {
 			// It is a valid limit order, set to take profit
 			// 是有效的限价出场单，设置到止盈中
@@ -654,6 +699,7 @@ Use the price to update the profit of the order, etc. It may trigger a margin ca
 使用价格更新订单的利润等。可能会触发爆仓
 */
 func (o *OrderMgr) UpdateByBar(allOpens []*ormo.InOutOrder, bar *orm.InfoKline) *errs.Error {
+	o.rollRiskDay()
 	for _, od := range allOpens {
 		if od.Symbol != bar.Symbol || od.Timeframe != bar.TimeFrame || od.Status >= ormo.InOutStatusFullExit {
 			continue
@@ -663,13 +709,29 @@ func (o *OrderMgr) UpdateByBar(allOpens []*ormo.InOutOrder, bar *orm.InfoKline)
 	return nil
 }
 
+// rollRiskDay sample the day start equity for daily loss limit 为每日亏损限制采样当日开始权益
+func (o *OrderMgr) rollRiskDay() {
+	if o.risk != nil {
+		o.risk.RollDay(o.ctx.TimeMS())
+	}
+}
+
+/*
+UpdateByTrades
+Fill pending orders and triggers by trades in tick backtest. Do nothing by default
+在逐笔回测中使用成交记录撮合挂单和触发单。默认不执行任何操作
+*/
+func (o *OrderMgr) UpdateByTrades(_ []*ormo.InOutOrder, _ string, _ []*banexg.Trade) *errs.Error {
+	return nil
+}
+
 func (o *OrderMgr) CutOrder(od *ormo.InOutOrder, enterRate, exitRate float64) *ormo.InOutOrder {
 	part := od.CutPart(od.Enter.Amount*enterRate, od.Enter.Amount*exitRate)
 	// Here the key of part is the same as the original one, so part is used as src_key
 	// 这里part的key和原始的一样，所以part作为src_key
 	tgtKey, srcKey := od.Key(), part.Key()
 	base, quote, _, _ := core.SplitSymbol(od.Symbol)
-	wallets := GetWallets(o.Account)
+	wallets := o.ctx.GetPairWallets(o.Account, od.Symbol)
 	wallets.CutPart(srcKey, tgtKey, base, 1-enterRate)
 	wallets.CutPart(srcKey, tgtKey, quote, 1-enterRate)
 	return part
@@ -684,9 +746,9 @@ It will be saved internally to the database during the actual trading.
 func (o *OrderMgr) finishOrder(od *ormo.InOutOrder, sess *ormo.Queries) *errs.Error {
 	od.UpdateProfits(0)
 	err := od.Save(sess)
-	cfg := strat.GetStratPerf(od.Symbol, od.Strategy)
+	cfg := o.ctx.GetStratPerf(od.Symbol, od.Strategy)
 	if cfg != nil && cfg.Enable && o.Account == config.DefAcc {
-		err2 := strat.CalcJobScores(od.Symbol, od.Timeframe, od.Strategy)
+		err2 := o.ctx.CalcJobScores(od.Symbol, od.Timeframe, od.Strategy)
 		if err2 != nil {
 			log.Error("calc job performance fail", zap.Error(err2),
 				zap.Strings("job", []string{od.Symbol, od.Timeframe, od.Strategy}))
@@ -17,6 +17,7 @@ import (
 	"github.com/banbox/banta"
 	"go.uber.org/zap"
 	"math"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -44,6 +45,8 @@ type LiveOrderMgr struct {
 	lockUnMatches    sync.Mutex                 // Prevent concurrent reading and writing of unMatchTrades 防止并发读写unMatchTrades
 	exitByMyOrder    FuncHandleMyOrder          // Try to use the transaction results of other end operations to update the current order status 尝试使用其他端操作的交易结果，更新当前订单状态
 	traceExgOrder    FuncHandleMyOrder
+	nativeTrail      bool               // Whether the exchange supports native trailing stop orders 交易所是否支持原生跟踪止损单
+	exchange         banexg.BanExchange // client of the exchange/market handled 处理的交易所/市场的客户端
 }
 
 type OdQItem struct {
@@ -68,25 +71,92 @@ type PairValItem struct {
 	ExpireMS int64
 }
 
-func InitLiveOrderMgr(callBack func(od *ormo.InOutOrder, isEnter bool)) {
+/*
+InitLiveOrderMgr
+Create a LiveOrderMgr for each exchange/market of exg.AllExgs in every account. Accounts trading on several
+exchanges/markets use liveOdRouter to dispatch orders by pair key. Can be called again after pairs refreshed.
+为每个账户的exg.AllExgs中每个交易所/市场创建LiveOrderMgr。在多个交易所/市场交易的账户使用liveOdRouter按品种键分发订单。
+刷新品种后可再次调用。返回新创建的LiveOrderMgr
+*/
+func (c *Ctx) InitLiveOrderMgr(callBack func(od *ormo.InOutOrder, isEnter bool)) []*LiveOrderMgr {
+	var created []*LiveOrderMgr
+	exchanges := exg.AllExgs()
 	for account := range config.Accounts {
-		mgr, ok := accLiveOdMgrs[account]
+		venues, ok := c.accLiveOdMgrs[account]
 		if !ok {
-			odMgr := newLiveOrderMgr(account, callBack)
-			accLiveOdMgrs[account] = odMgr
-			accOdMgrs[account] = odMgr
+			venues = make(map[string]*LiveOrderMgr)
+			c.accLiveOdMgrs[account] = venues
+		}
+		var risk *RiskMgr
+		for _, mgr := range venues {
+			risk = mgr.risk
+			break
+		}
+		if len(venues) == 0 {
+			risk = NewRiskMgr(c, account)
+		}
+		for _, exchange := range exchanges {
+			info := exchange.Info()
+			venue := venueKey(info.ID, info.MarketType)
+			if mgr, ok := venues[venue]; ok {
+				mgr.callBack = callBack
+				continue
+			}
+			mgr := newLiveOrderMgr(c, account, exchange, risk, callBack)
+			venues[venue] = mgr
+			created = append(created, mgr)
+		}
+		if len(venues) == 1 {
+			c.accOdMgrs[account] = c.GetLiveOdMgr(account)
 		} else {
-			mgr.callBack = callBack
+			c.accOdMgrs[account] = &liveOdRouter{ctx: c, Account: account}
+		}
+	}
+	return created
+}
+
+/*
+refreshLiveOdMgrs
+Create LiveOrderMgr for exchanges/markets newly added to run_policy after pairs refreshed in live trading
+实盘中刷新品种后，为run_policy新增的交易所/市场创建LiveOrderMgr
+*/
+func (c *Ctx) refreshLiveOdMgrs() {
+	if !core.EnvReal || len(c.accLiveOdMgrs) == 0 {
+		return
+	}
+	var callBack func(od *ormo.InOutOrder, isEnter bool)
+	for account := range c.accLiveOdMgrs {
+		if mgr := c.GetLiveOdMgr(account); mgr != nil {
+			callBack = mgr.callBack
+			break
 		}
 	}
+	created := c.InitLiveOrderMgr(callBack)
+	for _, mgr := range created {
+		_, _, _, err := mgr.SyncExgOrders()
+		if err != nil {
+			log.Error("SyncExgOrders for new LiveOrderMgr fail", zap.String("acc", mgr.Account),
+				zap.String("market", mgr.market), zap.Error(err))
+		}
+	}
+	if len(created) > 0 && c.liveOdStarted {
+		c.StartLiveOdMgr()
+	}
 }
 
-func newLiveOrderMgr(account string, callBack func(od *ormo.InOutOrder, isEnter bool)) *LiveOrderMgr {
+func newLiveOrderMgr(c *Ctx, account string, exchange banexg.BanExchange, risk *RiskMgr,
+	callBack func(od *ormo.InOutOrder, isEnter bool)) *LiveOrderMgr {
+	info := exchange.Info()
 	res := &LiveOrderMgr{
 		OrderMgr: OrderMgr{
+			ctx:      c,
 			callBack: callBack,
 			Account:  account,
+			risk:     risk,
+			exgName:  info.ID,
+			market:   info.MarketType,
 		},
+		exchange:      exchange,
 		queue:         make(chan *OdQItem, 1000),
 		doneKeys:      map[string]bool{},
 		exgIdMap:      map[string]*ormo.InOutOrder{},
@@ -95,25 +165,103 @@ func newLiveOrderMgr(account string, callBack func(od *ormo.InOutOrder, isEnter
 	}
 	res.afterEnter = makeAfterEnter(res)
 	res.afterExit = makeAfterExit(res)
-	if core.ExgName == "binance" {
+	if info.ID == "binance" {
 		res.exitByMyOrder = bnbExitByMyOrder(res)
 		res.traceExgOrder = bnbTraceExgOrder(res)
+		// only binance futures support TRAILING_STOP_MARKET
+		// 仅币安合约支持TRAILING_STOP_MARKET
+		res.nativeTrail = res.isContract()
 	} else {
-		panic("unsupport exchange for LiveOrderMgr: " + core.ExgName)
+		panic("unsupport exchange for LiveOrderMgr: " + info.ID)
+	}
+	return res
+}
+
+// pairKey return the pair key of symbol from exchange 返回交易所品种的品种键
+func (o *LiveOrderMgr) pairKey(symbol string) string {
+	return core.PairKey(o.exgName, o.market, symbol)
+}
+
+// isContract whether the market handled is contract 处理的市场是否是合约
+func (o *LiveOrderMgr) isContract() bool {
+	return banexg.IsContract(o.market)
+}
+
+// openOrders return open orders of the exchange/market handled 返回处理的交易所/市场的未平仓订单
+func (o *LiveOrderMgr) openOrders() []*ormo.InOutOrder {
+	openOds, lock := o.ctx.GetOpenODs(o.Account)
+	lock.Lock()
+	defer lock.Unlock()
+	res := make([]*ormo.InOutOrder, 0, len(openOds))
+	for _, od := range openOds {
+		if o.hasPair(od.Symbol) {
+			res = append(res, od)
+		}
 	}
 	return res
 }
 
+/*
+fetchPositions
+Fetch positions of the exchange/market handled, symbols are converted to pair keys.
+Spot has no position, the balances of base coins of pairs are used as long positions.
+获取处理的交易所/市场的持仓，品种转为品种键。现货没有持仓，使用品种基础币的余额作为多头持仓
+*/
+func (o *LiveOrderMgr) fetchPositions(pairs []string) ([]*banexg.Position, *errs.Error) {
+	args := map[string]interface{}{
+		banexg.ParamAccount: o.Account,
+	}
+	if o.isContract() {
+		posList, err := o.exchange.FetchAccountPositions(nil, args)
+		if err != nil {
+			return nil, err
+		}
+		for _, pos := range posList {
+			pos.Symbol = o.pairKey(pos.Symbol)
+		}
+		return posList, nil
+	}
+	balances, err := o.exchange.FetchBalance(args)
+	if err != nil {
+		return nil, err
+	}
+	var res []*banexg.Position
+	var handled = make(map[string]bool)
+	for _, pair := range pairs {
+		if handled[pair] {
+			continue
+		}
+		handled[pair] = true
+		base, _, _, _ := core.SplitSymbol(pair)
+		asset, ok := balances.Assets[base]
+		if !ok || asset.Total <= AmtDust {
+			continue
+		}
+		res = append(res, &banexg.Position{
+			Symbol:    pair,
+			Side:      banexg.PosSideLong,
+			Contracts: asset.Total,
+		})
+	}
+	return res, nil
+}
+
 /*
 SyncLocalOrders 将交易所仓位和本地仓位对比，关闭本地多余仓位对应订单
 
 定期执行，用于解决币安偶发止损成交但状态为expired导致本地订单未更新问题。
 */
 func (o *LiveOrderMgr) SyncLocalOrders() ([]*ormo.InOutOrder, *errs.Error) {
+	openOds := o.openOrders()
+	if len(openOds) == 0 {
+		return nil, nil
+	}
 	// 获取交易所所有持仓
-	posList, err := exg.Default.FetchAccountPositions(nil, map[string]interface{}{
-		banexg.ParamAccount: o.Account,
-	})
+	pairs := make([]string, 0, len(openOds))
+	for _, od := range openOds {
+		pairs = append(pairs, od.Symbol)
+	}
+	posList, err := o.fetchPositions(pairs)
 	if err != nil {
 		return nil, err
 	}
@@ -132,8 +280,6 @@ func (o *LiveOrderMgr) SyncLocalOrders() ([]*ormo.InOutOrder, *errs.Error) {
 	}
 
 	// 按symbol分组本地订单
-	openOds, lock := ormo.GetOpenODs(o.Account)
-	lock.Lock()
 	odMap := make(map[string]map[bool][]*ormo.InOutOrder)
 	for _, od := range openOds {
 		if _, ok := odMap[od.Symbol]; !ok {
@@ -141,7 +287,6 @@ func (o *LiveOrderMgr) SyncLocalOrders() ([]*ormo.InOutOrder, *errs.Error) {
 		}
 		odMap[od.Symbol][od.Short] = append(odMap[od.Symbol][od.Short], od)
 	}
-	lock.Unlock()
 
 	// 对每个symbol的多空方向进行检查
 	var closedList []*ormo.InOutOrder
@@ -208,7 +353,7 @@ func (o *LiveOrderMgr) SyncLocalOrders() ([]*ormo.InOutOrder, *errs.Error) {
 					overAmt -= closeAmt
 					closeOds = append(closeOds, part.Key())
 					closedList = append(closedList, part)
-					strat.FireOdChange(o.Account, part, strat.OdChgExitFill)
+					o.ctx.FireOdChange(o.Account, part, strat.OdChgExitFill)
 				}
 				log.Warn("close extra local open orders", zap.String("pair", symbol),
 					zap.Float64("ExtraTotal", localAmt-posAmt), zap.Float64("ExtraLeft", overAmt),
@@ -244,11 +389,10 @@ For redundant positions, treat them as new orders opened by the user and create
 	     对于冗余的仓位，视为用户开的新订单，创建新订单跟踪。
 */
 func (o *LiveOrderMgr) SyncExgOrders() ([]*ormo.InOutOrder, []*ormo.InOutOrder, []*ormo.InOutOrder, *errs.Error) {
-	exchange := exg.Default
-	task := ormo.GetTask(o.Account)
+	task := o.ctx.GetTask(o.Account)
 	// Get the exchange order
 	// 获取交易所挂单
-	exOdList, err := exchange.FetchOpenOrders("", task.CreateAt, 1000, map[string]interface{}{
+	exOdList, err := o.exchange.FetchOpenOrders("", task.CreateAt, 1000, map[string]interface{}{
 		banexg.ParamAccount: o.Account,
 	})
 	if err != nil {
@@ -256,6 +400,7 @@ func (o *LiveOrderMgr) SyncExgOrders() ([]*ormo.InOutOrder, []*ormo.InOutOrder,
 	}
 	exgOdMap := make(map[string]*banexg.Order)
 	for _, od := range exOdList {
+		od.Symbol = o.pairKey(od.Symbol)
 		exgOdMap[od.ID] = od
 	}
 	sess, conn, err := ormo.Conn(orm.DbTrades, true)
@@ -265,7 +410,7 @@ func (o *LiveOrderMgr) SyncExgOrders() ([]*ormo.InOutOrder, []*ormo.InOutOrder,
 	defer conn.Close()
 	// Loading orders from the database
 	// 从数据库加载订单
-	openOds, lock := ormo.GetOpenODs(o.Account)
+	openOds, lock := o.ctx.GetOpenODs(o.Account)
 	orders, err := sess.GetOrders(ormo.GetOrdersArgs{
 		TaskID: task.ID,
 		Status: 1,
@@ -286,7 +431,7 @@ func (o *LiveOrderMgr) SyncExgOrders() ([]*ormo.InOutOrder, []*ormo.InOutOrder,
 	var lastEntMS int64
 	var openPairs = map[string]struct{}{}
 	for _, od := range orders {
-		if od.Status >= ormo.InOutStatusFullExit {
+		if od.Status >= ormo.InOutStatusFullExit || !o.hasPair(od.Symbol) {
 			continue
 		}
 		lastEntMS = max(lastEntMS, od.RealEnterMS())
@@ -307,9 +452,7 @@ func (o *LiveOrderMgr) SyncExgOrders() ([]*ormo.InOutOrder, []*ormo.InOutOrder,
 	}
 	// Get exchange positions
 	// 获取交易所仓位
-	posList, err := exchange.FetchAccountPositions(nil, map[string]interface{}{
-		banexg.ParamAccount: o.Account,
-	})
+	posList, err := o.fetchPositions(utils.KeysOfMap(openPairs))
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -362,7 +505,7 @@ func (o *LiveOrderMgr) SyncExgOrders() ([]*ormo.InOutOrder, []*ormo.InOutOrder,
 	lock.Lock()
 	for key, od := range openOds {
 		_, newHas := resMap[key]
-		if !newHas {
+		if !newHas && o.hasPair(od.Symbol) {
 			delList = append(delList, od)
 		}
 	}
@@ -382,7 +525,7 @@ func (o *LiveOrderMgr) SyncExgOrders() ([]*ormo.InOutOrder, []*ormo.InOutOrder,
 	if len(newList) > 0 {
 		log.Info(fmt.Sprintf("%s: Started tracking %v users' orders", o.Account, len(newList)))
 	}
-	err = ormo.SaveDirtyODs(orm.DbTrades, o.Account)
+	err = o.ctx.SaveDirtyODs(orm.DbTrades, o.Account)
 	if err != nil {
 		log.Error("SaveDirtyODs fail", zap.Error(err))
 	}
@@ -403,11 +546,11 @@ func (o *LiveOrderMgr) restoreInOutOrder(od *ormo.InOutOrder, exgOdMap map[strin
 	if tryOd.Enter && tryOd.OrderID == "" && tryOd.Status == ormo.OdStatusInit {
 		// The order has not been submitted to the exchange and is an entry order
 		// 订单未提交到交易所，且是入场订单
-		if isFarEnter(od) {
-			ormo.AddTriggerOd(o.Account, od)
+		if o.ctx.isFarEnter(od) {
+			o.ctx.AddTriggerOd(o.Account, od)
 		} else {
 			err = od.LocalExit(core.ExitTagForceExit, od.InitPrice, "Restart and cancel orders that haven't been filled", "")
-			strat.FireOdChange(o.Account, od, strat.OdChgExitFill)
+			o.ctx.FireOdChange(o.Account, od, strat.OdChgExitFill)
 			return err
 		}
 	} else if tryOd.OrderID != "" && tryOd.Status != ormo.OdStatusClosed {
@@ -417,7 +560,7 @@ func (o *LiveOrderMgr) restoreInOutOrder(od *ormo.InOutOrder, exgOdMap map[strin
 		if !ok {
 			// The order has been cancelled or completed. Check the exchange order
 			// 订单已取消或已成交，查询交易所订单
-			exOd, err = exg.Default.FetchOrder(od.Symbol, tryOd.OrderID, map[string]interface{}{
+			exOd, err = o.exchange.FetchOrder(core.PairSymbol(od.Symbol), tryOd.OrderID, map[string]interface{}{
 				banexg.ParamAccount: o.Account,
 			})
 			if err != nil {
@@ -444,7 +587,7 @@ func (o *LiveOrderMgr) restoreInOutOrder(od *ormo.InOutOrder, exgOdMap map[strin
 		if tryOd.Status == ormo.OdStatusClosed {
 			od.Status = ormo.InOutStatusFullExit
 			od.DirtyMain = true
-			strat.FireOdChange(o.Account, od, strat.OdChgExitFill)
+			o.ctx.FireOdChange(o.Account, od, strat.OdChgExitFill)
 			return nil
 		} else if tryOd.Status > ormo.OdStatusInit {
 			// You shouldn't go here.
@@ -474,12 +617,15 @@ func (o *LiveOrderMgr) syncPairOrders(pair, defTF string, longPos, shortPos *ban
 	if len(openOds) > 0 {
 		// There are open orders locally. Get order records from the exchange and try to restore the order status.
 		// 本地有未平仓订单，从交易所获取订单记录，尝试恢复订单状态。
-		exOrders, err = exg.Default.FetchOrders(pair, sinceMs, 0, map[string]interface{}{
+		exOrders, err = o.exchange.FetchOrders(core.PairSymbol(pair), sinceMs, 0, map[string]interface{}{
 			banexg.ParamAccount: o.Account,
 		})
 		if err != nil {
 			return openOds, err
 		}
+		for _, exod := range exOrders {
+			exod.Symbol = pair
+		}
 	}
 	// Get the exchange order before getting the connection to reduce the time taken
 	// 获取交易所订单后再获取连接，减少占用时长
@@ -520,7 +666,7 @@ func (o *LiveOrderMgr) syncPairOrders(pair, defTF string, longPos, shortPos *ban
 					// 尚未提交到交易所，直接取消
 					msg := "Cancel unsubmitted orders"
 					err = iod.LocalExit(core.ExitTagCancel, iod.Enter.Price, msg, "")
-					strat.FireOdChange(o.Account, iod, strat.OdChgExitFill)
+					o.ctx.FireOdChange(o.Account, iod, strat.OdChgExitFill)
 					if err != nil {
 						return openOds, err
 					}
@@ -533,7 +679,7 @@ func (o *LiveOrderMgr) syncPairOrders(pair, defTF string, longPos, shortPos *ban
 				if iod.Status < ormo.InOutStatusFullExit {
 					msg := "The order has no corresponding position"
 					err = iod.LocalExit(core.ExitTagFatalErr, iod.InitPrice, msg, "")
-					strat.FireOdChange(o.Account, iod, strat.OdChgExitFill)
+					o.ctx.FireOdChange(o.Account, iod, strat.OdChgExitFill)
 					if err != nil {
 						return openOds, err
 					}
@@ -554,7 +700,7 @@ func (o *LiveOrderMgr) syncPairOrders(pair, defTF string, longPos, shortPos *ban
 			if posAmt < odAmt*-0.01 {
 				msg := fmt.Sprintf("The order has no corresponding position in the exchange: %.5f", posAmt+odAmt)
 				err = iod.LocalExit(core.ExitTagFatalErr, iod.InitPrice, msg, "")
-				strat.FireOdChange(o.Account, iod, strat.OdChgExitFill)
+				o.ctx.FireOdChange(o.Account, iod, strat.OdChgExitFill)
 				if err != nil {
 					return openOds, err
 				}
@@ -606,7 +752,7 @@ func getFeeNameCost(fee *banexg.Fee, pair, odType, side string, amount, price fl
 	} else {
 		isMaker = odType != banexg.OdTypeMarket
 	}
-	fee, err := exg.Default.CalculateFee(pair, odType, side, amount, price, isMaker, nil)
+	fee, err := exg.GetPairExg(pair).CalculateFee(core.PairSymbol(pair), odType, side, amount, price, isMaker, nil)
 	if err != nil {
 		log.Error("calc fee fail getFeeNameCost", zap.Error(err))
 		return "", 0
@@ -623,7 +769,7 @@ func (o *LiveOrderMgr) applyHisOrder(sess *ormo.Queries, ods []*ormo.InOutOrder,
 	}
 	feeName, feeCost := getFeeNameCost(od.Fee, od.Symbol, od.Type, od.Side, od.Filled, od.Average)
 	price, amount, odTime := od.Average, od.Filled, od.Timestamp
-	defTF = config.GetTakeOverTF(od.Symbol, defTF)
+	defTF = config.GetTakeOverTF(o.ctx.BotCtx, od.Symbol, defTF)
 
 	if isShort == isSell {
 		// Open long or short 开多或开空
@@ -701,7 +847,7 @@ func (o *LiveOrderMgr) applyHisOrder(sess *ormo.Queries, ods []*ormo.InOutOrder,
 func (o *LiveOrderMgr) createInOutOd(exs *orm.ExSymbol, short bool, average, filled float64, odType string,
 	feeCost float64, feeName string, enterAt int64, entStatus int, entOdId string, defTF string) *ormo.InOutOrder {
 	notional := average * filled
-	leverage, _ := exg.GetLeverage(exs.Symbol, notional, o.Account)
+	leverage, _ := exg.GetLeverage(exs.PairKey(), notional, o.Account)
 	if leverage == 0 {
 		leverage = config.GetAccLeverage(o.Account)
 	}
@@ -709,16 +855,16 @@ func (o *LiveOrderMgr) createInOutOd(exs *orm.ExSymbol, short bool, average, fil
 	if entStatus == ormo.OdStatusClosed {
 		status = ormo.InOutStatusFullEnter
 	}
-	stgVer, _ := strat.Versions[config.TakeOverStrat]
+	stgVer, _ := o.ctx.Versions[config.TakeOverStrat]
 	entSide := banexg.OdSideBuy
 	if short {
 		entSide = banexg.OdSideSell
 	}
-	taskId := ormo.GetTaskID(o.Account)
+	taskId := o.ctx.GetTaskID(o.Account)
 	od := &ormo.InOutOrder{
 		IOrder: &ormo.IOrder{
 			TaskID:    taskId,
-			Symbol:    exs.Symbol,
+			Symbol:    exs.PairKey(),
 			Sid:       int64(exs.ID),
 			Timeframe: defTF,
 			Short:     short,
@@ -733,7 +879,7 @@ func (o *LiveOrderMgr) createInOutOd(exs *orm.ExSymbol, short bool, average, fil
 		},
 		Enter: &ormo.ExOrder{
 			TaskID:    taskId,
-			Symbol:    exs.Symbol,
+			Symbol:    exs.PairKey(),
 			Enter:     true,
 			OrderType: odType,
 			OrderID:   entOdId,
@@ -752,9 +898,9 @@ func (o *LiveOrderMgr) createInOutOd(exs *orm.ExSymbol, short bool, average, fil
 		DirtyEnter: true,
 	}
 	if status >= ormo.InOutStatusFullEnter {
-		strat.FireOdChange(o.Account, od, strat.OdChgEnterFill)
+		o.ctx.FireOdChange(o.Account, od, strat.OdChgEnterFill)
 	} else {
-		strat.FireOdChange(o.Account, od, strat.OdChgEnter)
+		o.ctx.FireOdChange(o.Account, od, strat.OdChgEnter)
 	}
 	return od
 }
@@ -778,7 +924,7 @@ func (o *LiveOrderMgr) createOdFromPos(pos *banexg.Position, defTF string) (*orm
 		tag = "SHORT"
 	}
 	log.Info(fmt.Sprintf("%s [Pos]%v: price:%.5f, amount:%.5f, fee: %.5f", o.Account, tag, average, filled, feeCost))
-	enterAt := btime.TimeMS()
+	enterAt := o.ctx.TimeMS()
 	entStatus := ormo.OdStatusClosed
 	iod := o.createInOutOd(exs, isShort, average, filled, entOdType, feeCost, feeName, enterAt, entStatus, "", defTF)
 	return iod, nil
@@ -793,7 +939,7 @@ func (o *LiveOrderMgr) tryFillExit(iod *ormo.InOutOrder, filled, price float64,
 	feeName string, feeCost float64) (float64, float64, *ormo.InOutOrder) {
 	if iod.Enter.Filled == 0 {
 		err := iod.LocalExit(core.ExitTagForceExit, iod.InitPrice, "not entered", "")
-		strat.FireOdChange(o.Account, iod, strat.OdChgExitFill)
+		o.ctx.FireOdChange(o.Account, iod, strat.OdChgExitFill)
 		if err != nil {
 			log.Error("local exit no enter order fail", zap.String("key", iod.Key()), zap.Error(err))
 		}
@@ -825,7 +971,7 @@ func (o *LiveOrderMgr) tryFillExit(iod *ormo.InOutOrder, filled, price float64,
 		if part.Short {
 			exitSide = banexg.OdSideBuy
 		}
-		taskId := ormo.GetTaskID(o.Account)
+		taskId := o.ctx.GetTaskID(o.Account)
 		part.Exit = &ormo.ExOrder{
 			TaskID:    taskId,
 			InoutID:   part.ID,
@@ -860,7 +1006,7 @@ func (o *LiveOrderMgr) tryFillExit(iod *ormo.InOutOrder, filled, price float64,
 	part.ExitAt = odTime
 	part.Status = ormo.InOutStatusFullExit
 	part.DirtyMain = true
-	strat.FireOdChange(o.Account, part, strat.OdChgExitFill)
+	o.ctx.FireOdChange(o.Account, part, strat.OdChgExitFill)
 	return filled, feeCost, part
 }
 
@@ -871,8 +1017,8 @@ func (o *LiveOrderMgr) ProcessOrders(sess *ormo.Queries, env *banta.BarEnv, ente
 		return ents, extOrders, err
 	}
 	for _, edit := range edits {
-		if edit.Action == ormo.OdActionLimitEnter && isFarEnter(edit.Order) {
-			ormo.AddTriggerOd(o.Account, edit.Order)
+		if edit.Action == ormo.OdActionLimitEnter && o.ctx.isFarEnter(edit.Order) {
+			o.ctx.AddTriggerOd(o.Account, edit.Order)
 			continue
 		}
 		o.queue <- &OdQItem{
@@ -886,10 +1032,10 @@ func (o *LiveOrderMgr) ProcessOrders(sess *ormo.Queries, env *banta.BarEnv, ente
 func makeAfterEnter(o *LiveOrderMgr) FuncHandleIOrder {
 	return func(order *ormo.InOutOrder) *errs.Error {
 		fields := []zap.Field{zap.String("acc", o.Account), zap.String("key", order.Key())}
-		if isFarEnter(order) {
+		if o.ctx.isFarEnter(order) {
 			// Limit orders that are difficult to execute for a long time will not be submitted to the exchange to prevent funds from being occupied.
 			// 长时间难以成交的限价单，先不提交到交易所，防止资金占用
-			ormo.AddTriggerOd(o.Account, order)
+			o.ctx.AddTriggerOd(o.Account, order)
 			log.Info("NEW Enter trigger", fields...)
 			return nil
 		}
@@ -995,7 +1141,7 @@ func (o *LiveOrderMgr) WatchMyTrades() {
 	if o.isWatchMyTrade {
 		return
 	}
-	out, err := exg.Default.WatchMyTrades(map[string]interface{}{
+	out, err := o.exchange.WatchMyTrades(map[string]interface{}{
 		banexg.ParamAccount: o.Account,
 	})
 	if err != nil {
@@ -1017,7 +1163,8 @@ func (o *LiveOrderMgr) WatchMyTrades() {
 }
 
 func (o *LiveOrderMgr) handleMyTrade(trade *banexg.MyTrade) {
-	if _, ok := core.PairsMap[trade.Symbol]; !ok {
+	trade.Symbol = o.pairKey(trade.Symbol)
+	if _, ok := o.ctx.PairsMap[trade.Symbol]; !ok {
 		// 忽略不处理的交易对
 		return
 	}
@@ -1045,7 +1192,7 @@ func (o *LiveOrderMgr) handleMyTrade(trade *banexg.MyTrade) {
 		// 检查是否是机器人下单
 		orderId := getClientOrderId(trade.ClientID)
 		if orderId > 0 {
-			openOds, lock := ormo.GetOpenODs(o.Account)
+			openOds, lock := o.ctx.GetOpenODs(o.Account)
 			lock.Lock()
 			iod, ok = openOds[orderId]
 			lock.Unlock()
@@ -1130,7 +1277,7 @@ func (o *LiveOrderMgr) TrialUnMatchesForever() {
 				return
 			}
 			var pairTrades = make(map[string][]*banexg.MyTrade)
-			expireMS := btime.TimeMS() - 1000
+			expireMS := o.ctx.TimeMS() - 1000
 			data := make(map[string]*banexg.MyTrade)
 			o.lockUnMatches.Lock()
 			for key, trade := range o.unMatchTrades {
@@ -1183,7 +1330,7 @@ func (o *LiveOrderMgr) TrialUnMatchesForever() {
 			if unHandleNum > 0 {
 				log.Warn(fmt.Sprintf("expired unmatch orders: %v", unHandleNum))
 			}
-			err := ormo.SaveDirtyODs(orm.DbTrades, o.Account)
+			err := o.ctx.SaveDirtyODs(orm.DbTrades, o.Account)
 			if err != nil {
 				log.Error("SaveDirtyODs fail", zap.Error(err))
 			}
@@ -1296,11 +1443,11 @@ func (o *LiveOrderMgr) updateByMyTrade(od *ormo.InOutOrder, trade *banexg.MyTrad
 		if err != nil {
 			return err
 		}
-		cancelTriggerOds(od)
+		o.ctx.cancelTriggerOds(od)
 		o.callBack(od, subOd.Enter)
-		strat.FireOdChange(o.Account, od, strat.OdChgExitFill)
+		o.ctx.FireOdChange(o.Account, od, strat.OdChgExitFill)
 	} else {
-		strat.FireOdChange(o.Account, od, strat.OdChgEnterFill)
+		o.ctx.FireOdChange(o.Account, od, strat.OdChgEnterFill)
 	}
 	return nil
 }
@@ -1327,7 +1474,7 @@ func (o *LiveOrderMgr) execOrderEnter(od *ormo.InOutOrder) *errs.Error {
 	var err *errs.Error
 	if od.Enter.Amount == 0 {
 		if od.QuoteCost == 0 {
-			wallets := GetWallets(o.Account)
+			wallets := o.ctx.GetPairWallets(o.Account, od.Symbol)
 			_, err = wallets.EnterOd(od)
 			if err != nil {
 				if err.Code == core.ErrLowFunds || err.Code == core.ErrInvalidCost {
@@ -1336,7 +1483,7 @@ func (o *LiveOrderMgr) execOrderEnter(od *ormo.InOutOrder) *errs.Error {
 				} else {
 					msg := err.Short()
 					err = od.LocalExit(core.ExitTagFatalErr, od.InitPrice, msg, "")
-					strat.FireOdChange(o.Account, od, strat.OdChgExitFill)
+					o.ctx.FireOdChange(o.Account, od, strat.OdChgExitFill)
 					if err != nil {
 						log.Error("local exit order fail", zap.String("key", odKey), zap.Error(err))
 					}
@@ -1344,10 +1491,10 @@ func (o *LiveOrderMgr) execOrderEnter(od *ormo.InOutOrder) *errs.Error {
 				}
 			}
 		}
-		realPrice := core.GetPrice(od.Symbol)
+		realPrice := o.ctx.GetPrice(od.Symbol)
 		// The market price should be used to calculate the quantity here, because the input price may be very different from the market price
 		// 这里应使用市价计算数量，因传入价格可能和市价相差很大
-		od.Enter.Amount, err = exg.PrecAmount(exg.Default, od.Symbol, od.QuoteCost/realPrice)
+		od.Enter.Amount, err = exg.PrecAmount(o.exchange, od.Symbol, od.QuoteCost/realPrice)
 		if err != nil {
 			forceDelOd(err)
 			return nil
@@ -1361,7 +1508,7 @@ func (o *LiveOrderMgr) execOrderEnter(od *ormo.InOutOrder) *errs.Error {
 		msg := "submit order fail, local exit"
 		log.Error(msg, zap.String("key", odKey), zap.Error(err))
 		err = od.LocalExit(core.ExitTagFatalErr, od.InitPrice, msg, "")
-		strat.FireOdChange(o.Account, od, strat.OdChgExitFill)
+		o.ctx.FireOdChange(o.Account, od, strat.OdChgExitFill)
 		if err != nil {
 			log.Error("local exit order fail", zap.String("key", odKey), zap.Error(err))
 		}
@@ -1376,9 +1523,11 @@ func (o *LiveOrderMgr) tryExitEnter(od *ormo.InOutOrder) *errs.Error {
 	// May not have entered yet, or may not have fully entered
 	// 可能尚未入场，或未完全入场
 	if od.Enter.OrderID != "" {
-		order, err := exg.Default.CancelOrder(od.Enter.OrderID, od.Symbol, map[string]interface{}{
+		startAt := time.Now()
+		order, err := o.exchange.CancelOrder(od.Enter.OrderID, core.PairSymbol(od.Symbol), map[string]interface{}{
 			banexg.ParamAccount: o.Account,
 		})
+		observeSubmit(o.Account, SubmitCancel, startAt, err)
 		if err != nil {
 			log.Error("cancel order fail", zap.String("key", od.Key()), zap.String("err", err.Short()))
 		} else {
@@ -1402,8 +1551,8 @@ func (o *LiveOrderMgr) tryExitEnter(od *ormo.InOutOrder) *errs.Error {
 		if err != nil {
 			return err
 		}
-		cancelTriggerOds(od)
-		strat.FireOdChange(o.Account, od, strat.OdChgExitFill)
+		o.ctx.cancelTriggerOds(od)
+		o.ctx.FireOdChange(o.Account, od, strat.OdChgExitFill)
 		return nil
 	} else if od.Enter.Status < ormo.OdStatusClosed {
 		od.Enter.Status = ormo.OdStatusClosed
@@ -1439,12 +1588,13 @@ func (o *LiveOrderMgr) submitExgOrder(od *ormo.InOutOrder, isEnter bool) *errs.E
 		}
 	}
 	var err *errs.Error
-	exchange := exg.Default
+	exchange := o.exchange
+	symbol := core.PairSymbol(od.Symbol)
 	leverage, maxLeverage := exg.GetLeverage(od.Symbol, od.QuoteCost, o.Account)
 	if isEnter && od.Leverage > 0 && od.Leverage != leverage {
 		newLeverage := min(maxLeverage, od.Leverage)
 		if newLeverage != leverage {
-			_, err = exchange.SetLeverage(newLeverage, od.Symbol, map[string]interface{}{
+			_, err = exchange.SetLeverage(newLeverage, symbol, map[string]interface{}{
 				banexg.ParamAccount: o.Account,
 			})
 			if err != nil {
@@ -1494,8 +1644,8 @@ func (o *LiveOrderMgr) submitExgOrder(od *ormo.InOutOrder, isEnter bool) *errs.E
 			if err != nil {
 				return err
 			}
-			cancelTriggerOds(od)
-			strat.FireOdChange(o.Account, od, strat.OdChgExitFill)
+			o.ctx.cancelTriggerOds(od)
+			o.ctx.FireOdChange(o.Account, od, strat.OdChgExitFill)
 			return nil
 		}
 	}
@@ -1504,13 +1654,15 @@ func (o *LiveOrderMgr) submitExgOrder(od *ormo.InOutOrder, isEnter bool) *errs.E
 		banexg.ParamAccount:       o.Account,
 		banexg.ParamClientOrderId: od.ClientId(true),
 	}
-	if core.IsContract {
+	if o.isContract() {
 		params[banexg.ParamPositionSide] = "LONG"
 		if od.Short {
 			params[banexg.ParamPositionSide] = "SHORT"
 		}
 	}
-	res, err := exchange.CreateOrder(od.Symbol, subOd.OrderType, side, amount, price, params)
+	startAt := time.Now()
+	res, err := exchange.CreateOrder(symbol, subOd.OrderType, side, amount, price, params)
+	observeSubmit(o.Account, SubmitCreate, startAt, err)
 	if err != nil {
 		return err
 	}
@@ -1528,7 +1680,7 @@ func (o *LiveOrderMgr) submitExgOrder(od *ormo.InOutOrder, isEnter bool) *errs.E
 	} else {
 		// Close a position and cancel associated orders
 		// 平仓，取消关联订单
-		cancelTriggerOds(od)
+		o.ctx.cancelTriggerOds(od)
 	}
 	if subOd.Status == ormo.OdStatusClosed {
 		o.callBack(od, isEnter)
@@ -1544,6 +1696,7 @@ func (o *LiveOrderMgr) updateOdByExgRes(od *ormo.InOutOrder, isEnter bool, res *
 	} else {
 		od.DirtyExit = true
 	}
+	res.Symbol = o.pairKey(res.Symbol)
 	if subOd.OrderID != "" && subOd.OrderID != res.ID {
 		// If you modify the order price, order_id will change
 		// 如修改订单价格，order_id会变化
@@ -1609,19 +1762,19 @@ func (o *LiveOrderMgr) updateOdByExgRes(od *ormo.InOutOrder, isEnter bool, res *
 		if od.Status == ormo.InOutStatusFullExit {
 			err := o.finishOrder(od, nil)
 			o.callBack(od, false)
-			strat.FireOdChange(o.Account, od, strat.OdChgExitFill)
+			o.ctx.FireOdChange(o.Account, od, strat.OdChgExitFill)
 			if err != nil {
 				return err
 			}
 		} else {
-			strat.FireOdChange(o.Account, od, strat.OdChgEnterFill)
+			o.ctx.FireOdChange(o.Account, od, strat.OdChgEnterFill)
 		}
 	}
 	return o.consumeUnMatches(od, subOd)
 }
 
 func (o *LiveOrderMgr) hasNewTrades(res *banexg.Order) bool {
-	if core.IsContract {
+	if o.isContract() {
 		// 期货市场未返回trades，直接认为需要更新
 		return true
 	}
@@ -1691,7 +1844,7 @@ func (o *LiveOrderMgr) getLimitPrice(pair string, waitSecs int) (float64, float6
 	lockVolPrices.Lock()
 	cache, ok := volPrices[key]
 	lockVolPrices.Unlock()
-	if ok && cache.ExpireMS > btime.TimeMS() {
+	if ok && cache.ExpireMS > o.ctx.TimeMS() {
 		return cache.BuyPrice, cache.SellPrice
 	}
 	// Invalid or expired, need to be recalculated
@@ -1704,7 +1857,7 @@ func (o *LiveOrderMgr) getLimitPrice(pair string, waitSecs int) (float64, float6
 	// 5-minute trading volume per second * waiting seconds * 2: The final multiplication by 2 here is to prevent the trading volume from being too low
 	// 5分钟每秒成交量*等待秒数*2：这里最后乘2是以防成交量过低
 	depth := min(avgVol/30*secsFlt, lastVol/60*secsFlt)
-	book, err := exg.GetOdBook(pair)
+	book, err := exg.GetOdBook(o.ctx.BotCtx, pair)
 	var buyPrice, sellPrice float64
 	if err != nil {
 		buyPrice, sellPrice = 0, 0
@@ -1720,7 +1873,7 @@ func (o *LiveOrderMgr) getLimitPrice(pair string, waitSecs int) (float64, float6
 	volPrices[key] = &VolPrice{
 		BuyPrice:  buyPrice,
 		SellPrice: sellPrice,
-		ExpireMS:  btime.TimeMS() + expMS,
+		ExpireMS:  o.ctx.TimeMS() + expMS,
 	}
 	lockVolPrices.Unlock()
 	return buyPrice, sellPrice
@@ -1746,7 +1899,7 @@ func getPairMinsVol(pair string, num int) (float64, float64, *errs.Error) {
 		if err != nil {
 			return 0, 0, err
 		}
-		_, bars, err := orm.AutoFetchOHLCV(exg.Default, exs, "1m", 0, 0, num, false, nil)
+		_, bars, err := orm.AutoFetchOHLCV(exg.GetPairExg(pair), exs, "1m", 0, 0, num, false, nil)
 		if err != nil {
 			return 0, 0, err
 		} else if len(bars) == 0 {
@@ -1767,29 +1920,29 @@ func getPairMinsVol(pair string, num int) (float64, float64, *errs.Error) {
 	return avg, last, err
 }
 
-func isFarEnter(od *ormo.InOutOrder) bool {
+func (c *Ctx) isFarEnter(od *ormo.InOutOrder) bool {
 	if od.Status > ormo.InOutStatusPartEnter || od.Enter.Price == 0 ||
 		!strings.Contains(od.Enter.OrderType, banexg.OdTypeLimit) {
 		// 跳过已完全入场，或者非限价单
 		return false
 	}
 	stopAfter := od.GetInfoInt64(ormo.OdInfoStopAfter)
-	if stopAfter == 0 || stopAfter <= btime.TimeMS() {
+	if stopAfter == 0 || stopAfter <= c.TimeMS() {
 		return false
 	}
-	return isFarLimit(od.Enter)
+	return c.isFarLimit(od.Enter)
 }
 
 /*
 Determine whether an order is a limit order that is difficult to execute for a long time
 判断一个订单是否是长时间难以成交的限价单
 */
-func isFarLimit(od *ormo.ExOrder) bool {
+func (c *Ctx) isFarLimit(od *ormo.ExOrder) bool {
 	if od.Price == 0 || !strings.Contains(od.OrderType, banexg.OdTypeLimit) {
 		// 非限价单，或没有指定价格，会很快成交
 		return false
 	}
-	secs, rate, err := getSecsByLimit(od.Symbol, od.Side, od.Price)
+	secs, rate, err := c.getSecsByLimit(od.Symbol, od.Side, od.Price)
 	if err != nil {
 		log.Error("getSecsByLimit for isFarLimit fail", zap.String("pair", od.Symbol),
 			zap.String("side", od.Side), zap.Float64("price", od.Price), zap.Error(err))
@@ -1808,14 +1961,14 @@ Only for real trading
 检查是否有可触发的限价单，如有，提交到交易所，应被每分钟调用
 仅实盘使用
 */
-func VerifyTriggerOds() {
+func (c *Ctx) VerifyTriggerOds() {
 	for account := range config.Accounts {
-		verifyAccountTriggerOds(account)
+		c.verifyAccountTriggerOds(account)
 	}
 }
 
-func verifyAccountTriggerOds(account string) {
-	triggerOds, lock := ormo.GetTriggerODs(account)
+func (c *Ctx) verifyAccountTriggerOds(account string) {
+	triggerOds, lock := c.GetTriggerODs(account)
 	var resOds []*ormo.InOutOrder
 	var copyTriggers = make(map[string]map[int64]*ormo.InOutOrder)
 	lock.Lock()
@@ -1825,12 +1978,16 @@ func verifyAccountTriggerOds(account string) {
 	lock.Unlock()
 	var zeros []string
 	var fails []string
-	odMgr := GetLiveOdMgr(account)
 	var saves []*ormo.InOutOrder
 	for pair, ods := range copyTriggers {
 		if len(ods) == 0 {
 			continue
 		}
+		odMgr := c.GetPairLiveOdMgr(account, pair)
+		if odMgr == nil {
+			log.Error("no LiveOrderMgr for trigger orders", zap.String("acc", account), zap.String("pair", pair))
+			continue
+		}
 		var secsVol float64
 		var book *banexg.OrderBook
 		// Calculate the past 50 minutes, average volume, and last minute volume
@@ -1839,7 +1996,7 @@ func verifyAccountTriggerOds(account string) {
 		if err == nil {
 			secsVol = max(avgVol, lastVol) / 60
 			if secsVol > 0 {
-				book, err = exg.GetOdBook(pair)
+				book, err = exg.GetOdBook(c.BotCtx, pair)
 			} else {
 				zeros = append(zeros, pair)
 			}
@@ -1873,7 +2030,7 @@ func verifyAccountTriggerOds(account string) {
 				resOds = append(resOds, od)
 			} else {
 				stopAfter := od.GetInfoInt64(ormo.OdInfoStopAfter)
-				if stopAfter <= btime.TimeMS() {
+				if stopAfter <= c.TimeMS() {
 					cancelTimeoutEnter(odMgr, od)
 					saves = append(saves, od)
 				} else {
@@ -1899,6 +2056,7 @@ func verifyAccountTriggerOds(account string) {
 		if od.Exit != nil {
 			tag = ormo.OdActionExit
 		}
+		odMgr := c.GetPairLiveOdMgr(account, od.Symbol)
 		odMgr.queue <- &OdQItem{
 			Order:  od,
 			Action: tag,
@@ -1914,7 +2072,7 @@ getSecsByLimit
 Based on the target price, calculate the approximate waiting time for the transaction.
 根据目标价格，计算大概成交需要等待的时长。
 */
-func getSecsByLimit(pair, side string, price float64) (int, float64, *errs.Error) {
+func (c *Ctx) getSecsByLimit(pair, side string, price float64) (int, float64, *errs.Error) {
 	avgVol, lastVol, err := getPairMinsVol(pair, 50)
 	if err != nil {
 		return 0, 1, err
@@ -1923,7 +2081,7 @@ func getSecsByLimit(pair, side string, price float64) (int, float64, *errs.Error
 	if secsVol == 0 {
 		return 0, 1, nil
 	}
-	book, err := exg.GetOdBook(pair)
+	book, err := exg.GetOdBook(c.BotCtx, pair)
 	if err != nil {
 		return 0, 1, err
 	}
@@ -1952,9 +2110,11 @@ func cancelTimeoutEnter(odMgr *LiveOrderMgr, od *ormo.InOutOrder) {
 	lock := od.Lock()
 	defer lock.Unlock()
 	if od.Enter.OrderID != "" {
-		res, err := exg.Default.CancelOrder(od.Enter.OrderID, od.Symbol, map[string]interface{}{
+		startAt := time.Now()
+		res, err := odMgr.exchange.CancelOrder(od.Enter.OrderID, core.PairSymbol(od.Symbol), map[string]interface{}{
 			banexg.ParamAccount: odMgr.Account,
 		})
+		observeSubmit(odMgr.Account, SubmitCancel, startAt, err)
 		if err != nil {
 			log.Error("cancel old limit enters fail", zap.String("key", od.Key()), zap.Error(err))
 		} else {
@@ -1968,7 +2128,7 @@ func cancelTimeoutEnter(odMgr *LiveOrderMgr, od *ormo.InOutOrder) {
 		// Not yet filled, exit directly
 		// 尚未入场，直接退出
 		err := od.LocalExit(core.ExitTagForceExit, od.InitPrice, "reach StopEnterBars", "")
-		strat.FireOdChange(odMgr.Account, od, strat.OdChgExitFill)
+		odMgr.ctx.FireOdChange(odMgr.Account, od, strat.OdChgExitFill)
 		if err != nil {
 			log.Error("local exit for StopEnterBars fail", zap.String("key", od.Key()), zap.Error(err))
 		}
@@ -1979,7 +2139,7 @@ func cancelTimeoutEnter(odMgr *LiveOrderMgr, od *ormo.InOutOrder) {
 		od.Status = ormo.InOutStatusFullEnter
 		od.DirtyMain = true
 		od.DirtyEnter = true
-		strat.FireOdChange(odMgr.Account, od, strat.OdChgEnterFill)
+		odMgr.ctx.FireOdChange(odMgr.Account, od, strat.OdChgEnterFill)
 	}
 }
 
@@ -1988,14 +2148,17 @@ func (o *LiveOrderMgr) editLimitOd(od *ormo.InOutOrder, action string) *errs.Err
 	if action == ormo.OdActionLimitExit {
 		subOd = od.Exit
 	}
-	exchange := exg.Default
+	exchange := o.exchange
+	symbol := core.PairSymbol(od.Symbol)
 	args := map[string]interface{}{
 		banexg.ParamAccount: o.Account,
 	}
-	if core.Market != banexg.MarketLinear && core.Market != banexg.MarketInverse {
+	if o.market != banexg.MarketLinear && o.market != banexg.MarketInverse {
 		// Spot, Margin, Options. Cancel the old order first, then create a new order
 		// 现货，保证金，期权。先取消旧订单，再创建新订单
-		_, err := exchange.CancelOrder(subOd.OrderID, od.Symbol, args)
+		startAt := time.Now()
+		_, err := exchange.CancelOrder(subOd.OrderID, symbol, args)
+		observeSubmit(o.Account, SubmitCancel, startAt, err)
 		if err != nil {
 			return err
 		}
@@ -2010,7 +2173,9 @@ func (o *LiveOrderMgr) editLimitOd(od *ormo.InOutOrder, action string) *errs.Err
 	}
 	// Only U-based & coin-based, modify order
 	// 只有U本位 & 币本位，修改订单
-	res, err := exchange.EditOrder(od.Symbol, subOd.OrderID, subOd.Side, subOd.Amount, subOd.Price, args)
+	startAt := time.Now()
+	res, err := exchange.EditOrder(symbol, subOd.OrderID, subOd.Side, subOd.Amount, subOd.Price, args)
+	observeSubmit(o.Account, SubmitEdit, startAt, err)
 	if err != nil {
 		return err
 	}
@@ -2027,13 +2192,21 @@ func (o *LiveOrderMgr) editTriggerOd(od *ormo.InOutOrder, prefix string) {
 	}
 	tg.SaveOld()
 	od.DirtyInfo = true
-	if tg.Price <= 0 {
+	native := prefix == ormo.OdActionStopLoss && o.canNativeTrail(tg)
+	if tg.IsTrailing() && !native && tg.Price <= 0 {
+		// Trailing stop tracked locally is not activated yet, submit after activated
+		// 本地跟踪的止损尚未激活，激活后再提交
+		return
+	}
+	if tg.Price <= 0 && !native {
 		// Stop loss/take profit is not set, or needs to be cancelled
 		// 未设置止损/止盈，或需要撤销
 		if tg.OrderId != "" {
-			_, err := exg.Default.CancelOrder(tg.OrderId, od.Symbol, map[string]interface{}{
+			startAt := time.Now()
+			_, err := o.exchange.CancelOrder(tg.OrderId, core.PairSymbol(od.Symbol), map[string]interface{}{
 				banexg.ParamAccount: o.Account,
 			})
+			observeSubmit(o.Account, SubmitCancel, startAt, err)
 			if err != nil {
 				log.Error("cancel old trigger fail", zap.String("key", od.Key()), zap.Error(err))
 			}
@@ -2046,7 +2219,7 @@ func (o *LiveOrderMgr) editTriggerOd(od *ormo.InOutOrder, prefix string) {
 		banexg.ParamAccount:       o.Account,
 		banexg.ParamClientOrderId: od.ClientId(true),
 	}
-	if core.IsContract {
+	if o.isContract() {
 		params[banexg.ParamPositionSide] = "LONG"
 		if od.Short {
 			params[banexg.ParamPositionSide] = "SHORT"
@@ -2060,7 +2233,16 @@ func (o *LiveOrderMgr) editTriggerOd(od *ormo.InOutOrder, prefix string) {
 	}
 	// 这里不应设置ClosePosition仓位止盈止损，否则多策略或多个订单止盈止损会互相覆盖
 	// 双向持仓无需设置ReduceOnly
-	if prefix == ormo.OdActionStopLoss {
+	if native {
+		// Trailing stop tracked by exchange, callbackRate is in percent
+		// 由交易所跟踪止损，callbackRate单位为百分比
+		odType = banexg.OdTypeTrailingStopMarket
+		price = 0
+		params[banexg.ParamCallbackRate] = math.Round(tg.Callback*1000) / 10
+		if tg.Activate > 0 {
+			params["activationPrice"] = tg.Activate
+		}
+	} else if prefix == ormo.OdActionStopLoss {
 		params[banexg.ParamStopLossPrice] = tg.Price
 	} else if prefix == ormo.OdActionTakeProfit {
 		params[banexg.ParamTakeProfitPrice] = tg.Price
@@ -2079,7 +2261,9 @@ func (o *LiveOrderMgr) editTriggerOd(od *ormo.InOutOrder, prefix string) {
 	log.Debug("set trigger", zap.String("acc", o.Account), zap.String("key", od.Key()),
 		zap.Float64("amt", od.Enter.Amount), zap.Float64("qmt", amt),
 		zap.Float64("price", od.Enter.Average))
-	res, err := exg.Default.CreateOrder(od.Symbol, odType, side, amt, price, params)
+	startAt := time.Now()
+	res, err := o.exchange.CreateOrder(core.PairSymbol(od.Symbol), odType, side, amt, price, params)
+	observeSubmit(o.Account, SubmitCreate, startAt, err)
 	if err != nil {
 		if err.BizCode == -2021 {
 			// Stop loss and stop profit are executed immediately, and the position is closed at the market price
@@ -2108,36 +2292,54 @@ func (o *LiveOrderMgr) editTriggerOd(od *ormo.InOutOrder, prefix string) {
 	orderId := tg.OrderId
 	if res != nil {
 		tg.OrderId = res.ID
+		tg.Native = native
 		od.DirtyInfo = true
 	}
 	if orderId != "" && (res == nil || res.Status == "open") {
-		_, err = exg.Default.CancelOrder(orderId, od.Symbol, map[string]interface{}{
+		startAt := time.Now()
+		_, err = o.exchange.CancelOrder(orderId, core.PairSymbol(od.Symbol), map[string]interface{}{
 			banexg.ParamAccount: o.Account,
 		})
+		observeSubmit(o.Account, SubmitCancel, startAt, err)
 		if err != nil {
 			log.Error("cancel old trigger fail", zap.String("key", od.Key()), zap.Error(err))
 		}
 	}
 }
 
+/*
+canNativeTrail
+Whether the trailing stop can be submitted as the exchange's native trailing order, otherwise it's tracked locally
+跟踪止损是否可作为交易所原生跟踪单提交，否则在本地跟踪
+*/
+func (o *LiveOrderMgr) canNativeTrail(tg *ormo.TriggerState) bool {
+	// binance only accepts callbackRate in [0.1, 10] percent, and market order after triggered
+	// 币安仅接受[0.1, 10]百分比的callbackRate，且触发后为市价单
+	return o.nativeTrail && tg.Callback >= 0.001 && tg.Callback <= 0.1 && tg.Limit == 0
+}
+
 /*
 cancelTriggerOds
 Cancel the associated order of the order. When the order is closed, the associated stop loss order and take profit order will not be automatically exited, and this method needs to be called to exit
 取消订单的关联订单。订单在平仓时，关联的止损单止盈单不会自动退出，需要调用此方法退出
 */
-func cancelTriggerOds(od *ormo.InOutOrder) {
+func (c *Ctx) cancelTriggerOds(od *ormo.InOutOrder) {
 	sl := od.GetStopLoss()
 	tp := od.GetTakeProfit()
 	if sl == nil && tp == nil {
 		return
 	}
 	odKey := od.Key()
+	account := c.GetTaskAcc(od.TaskID)
 	args := map[string]interface{}{
-		banexg.ParamAccount: ormo.GetTaskAcc(od.TaskID),
+		banexg.ParamAccount: account,
 	}
+	exchange, symbol := exg.GetPairExg(od.Symbol), core.PairSymbol(od.Symbol)
 	var logFields []zap.Field
 	if sl != nil && sl.OrderId != "" {
-		_, err := exg.Default.CancelOrder(sl.OrderId, od.Symbol, args)
+		startAt := time.Now()
+		_, err := exchange.CancelOrder(sl.OrderId, symbol, args)
+		observeSubmit(account, SubmitCancel, startAt, err)
 		if err != nil {
 			log.Warn("cancel stopLoss fail", zap.String("key", odKey), zap.String("err", err.Short()))
 		} else {
@@ -2147,7 +2349,9 @@ func cancelTriggerOds(od *ormo.InOutOrder) {
 		od.DirtyInfo = true
 	}
 	if tp != nil && tp.OrderId != "" {
-		_, err := exg.Default.CancelOrder(tp.OrderId, od.Symbol, args)
+		startAt := time.Now()
+		_, err := exchange.CancelOrder(tp.OrderId, symbol, args)
+		observeSubmit(account, SubmitCancel, startAt, err)
 		if err != nil {
 			log.Warn("cancel takeProfit fail", zap.String("key", odKey), zap.String("err", err.Short()))
 		} else {
@@ -2185,10 +2389,10 @@ func (o *LiveOrderMgr) finishOrder(od *ormo.InOutOrder, sess *ormo.Queries) *err
 }
 
 func (o *LiveOrderMgr) WatchLeverages() {
-	if !core.IsContract || o.isWatchAccConfig {
+	if !o.isContract() || o.isWatchAccConfig {
 		return
 	}
-	out, err := exg.Default.WatchAccountConfig(map[string]interface{}{
+	out, err := o.exchange.WatchAccountConfig(map[string]interface{}{
 		banexg.ParamAccount: o.Account,
 	})
 	if err != nil {
@@ -2211,17 +2415,17 @@ CheckFatalStop
 Check if the global stop loss is triggered. This method should be called regularly via cron
 检查是否触发全局止损，此方法应通过cron定期调用
 */
-func MakeCheckFatalStop(maxIntv int) func() {
+func (c *Ctx) MakeCheckFatalStop(maxIntv int) func() {
 	return func() {
 		for account := range config.Accounts {
-			checkAccFatalStop(account, maxIntv)
+			c.checkAccFatalStop(account, maxIntv)
 		}
 	}
 }
 
-func checkAccFatalStop(account string, maxIntv int) {
-	stopUntil, _ := core.NoEnterUntil[account]
-	if stopUntil >= btime.TimeMS() {
+func (c *Ctx) checkAccFatalStop(account string, maxIntv int) {
+	stopUntil, _ := c.NoEnterUntil[account]
+	if stopUntil >= c.TimeMS() {
 		return
 	}
 	sess, conn, err := ormo.Conn(orm.DbTrades, false)
@@ -2230,8 +2434,8 @@ func checkAccFatalStop(account string, maxIntv int) {
 		return
 	}
 	defer conn.Close()
-	minTimeMS := btime.TimeMS() - int64(maxIntv)*60000
-	taskId := ormo.GetTaskID(account)
+	minTimeMS := c.TimeMS() - int64(maxIntv)*60000
+	taskId := c.GetTaskID(account)
 	orders, err := sess.GetOrders(ormo.GetOrdersArgs{
 		TaskID:     taskId,
 		Status:     2,
@@ -2241,32 +2445,54 @@ func checkAccFatalStop(account string, maxIntv int) {
 		log.Error("get cur his orders fail", zap.Error(err))
 		return
 	}
-	wallets := GetWallets(account)
-	for backMins, rate := range config.FatalStop {
-		lossRate := calcFatalLoss(wallets, orders, backMins)
-		if lossRate >= rate {
+	c.ApplyFatalStop(account, orders, core.StartAt)
+}
+
+/*
+ApplyFatalStop
+Check the loss of closed orders in each fatal_stop window, forbid entering for fatal_stop_hours when triggered.
+Shared by the live cron and backtest, windows are checked from short to long.
+startMS limits the window start to bot start time in live, 0 for backtest.
+Returns the triggered window in minutes and the loss rate, 0 when not triggered.
+检查每个fatal_stop窗口内已平仓订单的亏损，触发时禁止开单fatal_stop_hours小时。实盘定时任务和回测共用，窗口从短到长检查。
+startMS在实盘中限制窗口开始于机器人启动时间，回测传0。返回触发的窗口分钟数和亏损比例，未触发时返回0
+*/
+func (c *Ctx) ApplyFatalStop(account string, orders []*ormo.InOutOrder, startMS int64) (int, float64) {
+	curMS := c.TimeMS()
+	stopUntil, _ := c.NoEnterUntil[account]
+	if stopUntil >= curMS {
+		return 0, 0
+	}
+	totalLegal := c.AccTotalLegal(account, false)
+	backList := utils.KeysOfMap(config.FatalStop)
+	slices.Sort(backList)
+	for _, backMins := range backList {
+		minTimeMS := curMS - int64(backMins)*60000
+		if startMS > 0 {
+			minTimeMS = min(minTimeMS, startMS)
+		}
+		lossRate := calcFatalLoss(totalLegal, orders, minTimeMS)
+		if lossRate >= config.FatalStop[backMins] {
 			lossPct := int(lossRate * 100)
-			core.NoEnterUntil[account] = btime.TimeMS() + int64(config.FatalStopHours)*3600*1000
+			c.NoEnterUntil[account] = curMS + int64(config.FatalStopHours)*3600*1000
 			log.Error(fmt.Sprintf("%v: Loss of %v%% in %v minutes, prohibition of placing orders for %v hours!", account,
 				lossPct, backMins, config.FatalStopHours))
-			break
+			return backMins, lossRate
 		}
 	}
+	return 0, 0
 }
 
 /*
 calcFatalLoss
-Calculate the percentage of account balance loss in the last n minutes at the system level
-计算系统级别最近n分钟内，账户余额损失百分比
+Calculate the percentage of account balance loss by orders closed since minTimeMS at the system level
+计算系统级别自minTimeMS以来平仓的订单导致的账户余额损失百分比
 */
-func calcFatalLoss(wallets *BanWallets, orders []*ormo.InOutOrder, backMins int) float64 {
-	minTimeMS := btime.TimeMS() - int64(backMins)*60000
-	minTimeMS = min(minTimeMS, core.StartAt)
+func calcFatalLoss(totalLegal float64, orders []*ormo.InOutOrder, minTimeMS int64) float64 {
 	sumProfit := float64(0)
-	for i := len(orders) - 1; i >= 0; i-- {
-		od := orders[i]
-		if od.Enter.CreateAt < minTimeMS {
-			break
+	for _, od := range orders {
+		if od.ExitAt < minTimeMS {
+			continue
 		}
 		sumProfit += od.Profit
 	}
@@ -2274,7 +2500,6 @@ func calcFatalLoss(wallets *BanWallets, orders []*ormo.InOutOrder, backMins int)
 		return 0
 	}
 	lossVal := math.Abs(sumProfit)
-	totalLegal := wallets.TotalLegal(nil, false)
 	return lossVal / (lossVal + totalLegal)
 }
 
@@ -2305,19 +2530,26 @@ func (o *LiveOrderMgr) CleanUp() *errs.Error {
 	return nil
 }
 
-func StartLiveOdMgr() {
+/*
+StartLiveOdMgr
+Start watching and consuming of LiveOrderMgr of all accounts, started ones are skipped, so it can be called again after InitLiveOrderMgr
+启动所有账户LiveOrderMgr的监听和消费，已启动的跳过，因此可在InitLiveOrderMgr后再次调用
+*/
+func (c *Ctx) StartLiveOdMgr() {
 	if !core.EnvReal {
 		panic("StartLiveOdMgr for FakeEnv is forbidden:" + core.RunEnv)
 	}
+	c.liveOdStarted = true
 	for account := range config.Accounts {
-		odMgr := GetLiveOdMgr(account)
-		// Monitor account order flow 监听账户订单流
-		odMgr.WatchMyTrades()
-		// Track user orders 跟踪用户下单
-		odMgr.TrialUnMatchesForever()
-		// Consumption order queue 消费订单队列
-		odMgr.ConsumeOrderQueue()
-		// Monitor leverage changes 监听杠杆倍数变化
-		odMgr.WatchLeverages()
+		for _, odMgr := range c.GetLiveOdMgrs(account) {
+			// Monitor account order flow 监听账户订单流
+			odMgr.WatchMyTrades()
+			// Track user orders 跟踪用户下单
+			odMgr.TrialUnMatchesForever()
+			// Consumption order queue 消费订单队列
+			odMgr.ConsumeOrderQueue()
+			// Monitor leverage changes 监听杠杆倍数变化
+			odMgr.WatchLeverages()
+		}
 	}
 }
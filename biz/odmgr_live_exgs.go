@@ -2,7 +2,6 @@ package biz
 
 import (
 	"github.com/banbox/banbot/config"
-	"github.com/banbox/banbot/core"
 	"github.com/banbox/banbot/orm"
 	"github.com/banbox/banbot/orm/ormo"
 	"github.com/banbox/banexg"
@@ -18,7 +17,7 @@ func bnbExitByMyOrder(o *LiveOrderMgr) FuncHandleMyOrder {
 		}
 		isShort := od.PositionSide == banexg.PosSideShort
 		var openOds []*ormo.InOutOrder
-		accOpenOds, lock := ormo.GetOpenODs(o.Account)
+		accOpenOds, lock := o.ctx.GetOpenODs(o.Account)
 		lock.Lock()
 		for _, iod := range accOpenOds {
 			if iod.Short != isShort || iod.Symbol != od.Symbol || iod.Enter.Side == od.Side {
@@ -92,7 +91,7 @@ func (o *LiveOrderMgr) makeInOutOd(sess *ormo.Queries, pair string, short bool,
 		log.Error("get exSymbol fail", zap.Error(err))
 		return nil
 	}
-	defTF := config.GetTakeOverTF(pair, "")
+	defTF := config.GetTakeOverTF(o.ctx.BotCtx, pair, "")
 	if defTF != "" {
 		log.Error("no strat job found for trade", zap.String("pair", pair),
 			zap.String("id", entOdId))
@@ -105,7 +104,7 @@ func (o *LiveOrderMgr) makeInOutOd(sess *ormo.Queries, pair string, short bool,
 		log.Error("save third order fail", zap.String("key", iod.Key()), zap.Error(err))
 		return nil
 	}
-	openOds, lock := ormo.GetOpenODs(o.Account)
+	openOds, lock := o.ctx.GetOpenODs(o.Account)
 	lock.Lock()
 	openOds[iod.ID] = iod
 	lock.Unlock()
@@ -119,7 +118,7 @@ func bnbTraceExgOrder(o *LiveOrderMgr) FuncHandleMyOrder {
 			return false
 		}
 		isShort := od.PositionSide == banexg.PosSideShort
-		if core.IsContract {
+		if o.isContract() {
 			if !isShort && od.Side == banexg.OdSideSell || isShort && od.Side == banexg.OdSideBuy {
 				// Ignore closed orders 忽略平仓的订单
 				return false
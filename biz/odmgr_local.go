@@ -1,7 +1,6 @@
 package biz
 
 import (
-	"github.com/banbox/banbot/btime"
 	"github.com/banbox/banbot/config"
 	"github.com/banbox/banbot/core"
 	"github.com/banbox/banbot/exg"
@@ -19,25 +18,33 @@ import (
 
 type LocalOrderMgr struct {
 	OrderMgr
-	showLog  bool
-	zeroAmts map[string]int
+	showLog   bool
+	zeroAmts  map[string]int
+	fundRates map[int32][]*orm.FundingRate // funding rate history of contracts, loaded lazily 合约资金费率历史，延迟加载
+	slippage  SlippageModel                // slippage model for market fills, nil if disabled 市价成交的滑点模型，未启用为nil
+	tickPairs map[string]bool              // pairs filled by trades instead of bars 使用逐笔成交而非K线撮合的品种
 }
 
 type FnOdCb = func(od *ormo.InOutOrder, isEnter bool)
 
-func InitLocalOrderMgr(callBack FnOdCb, showLog bool) {
+func (c *Ctx) InitLocalOrderMgr(callBack FnOdCb, showLog bool) {
 	for account := range config.Accounts {
-		mgr, ok := accOdMgrs[account]
+		mgr, ok := c.accOdMgrs[account]
 		if !ok {
 			mgr = &LocalOrderMgr{
 				OrderMgr: OrderMgr{
+					ctx:      c,
 					callBack: callBack,
 					Account:  account,
+					risk:     NewRiskMgr(c, account),
 				},
-				showLog:  showLog,
-				zeroAmts: make(map[string]int),
+				showLog:   showLog,
+				zeroAmts:  make(map[string]int),
+				fundRates: make(map[int32][]*orm.FundingRate),
+				slippage:  NewSlippageModel(c.BotCtx, config.BTSlippage),
+				tickPairs: make(map[string]bool),
 			}
-			accOdMgrs[account] = mgr
+			c.accOdMgrs[account] = mgr
 		}
 	}
 }
@@ -48,7 +55,15 @@ func (o *LocalOrderMgr) ProcessOrders(sess *ormo.Queries, env *banta.BarEnv, ent
 }
 
 func (o *LocalOrderMgr) UpdateByBar(allOpens []*ormo.InOutOrder, bar *orm.InfoKline) *errs.Error {
-	if len(allOpens) == 0 || core.EnvReal {
+	if core.EnvReal {
+		return nil
+	}
+	if o.slippage != nil {
+		o.slippage.OnBar(bar)
+	}
+	// before any order is filled or exited in this bar 在此bar的任何订单成交或退出前
+	o.rollRiskDay()
+	if len(allOpens) == 0 {
 		return nil
 	}
 	// Simulate order entry and exit, which are usually executed at the beginning of the bar
@@ -59,20 +74,26 @@ func (o *LocalOrderMgr) UpdateByBar(allOpens []*ormo.InOutOrder, bar *orm.InfoKl
 			curOrders = append(curOrders, od)
 		}
 	}
-	if len(curOrders) == 0 && !core.CheckWallets {
+	if len(curOrders) == 0 && !o.ctx.CheckWallets {
 		return nil
 	}
-	_, err := o.fillPendingOrders(curOrders, bar)
-	if err != nil {
-		return err
+	// Funding settled before this bar is applied to positions held at the bar open
+	// 本bar开始前结算的资金费，应用到bar开始时持有的仓位
+	o.applyFundFees(curOrders, bar.Time, bar.Open)
+	if !o.tickPairs[bar.Symbol] {
+		_, err := o.fillPendingOrders(curOrders, bar)
+		if err != nil {
+			return err
+		}
 	}
+	o.applyFundFees(curOrders, bar.Time+int64(utils.TFToSecs(bar.TimeFrame))*1000-1, bar.Close)
 	// Update all orders to profit at the end of the bar
 	// 更新所有订单在bar结束时利润
-	err = o.OrderMgr.UpdateByBar(curOrders, bar)
+	err := o.OrderMgr.UpdateByBar(curOrders, bar)
 	if err != nil {
 		return err
 	}
-	if core.IsContract && core.CheckWallets {
+	if exg.IsContractPair(bar.Symbol) && o.ctx.CheckWallets {
 		// Update all order margins and wallet status of this pricing currency for the contract
 		// 为合约更新此定价币的所有订单保证金和钱包情况
 		_, _, code, _ := core.SplitSymbol(bar.Symbol)
@@ -83,12 +104,233 @@ func (o *LocalOrderMgr) UpdateByBar(allOpens []*ormo.InOutOrder, bar *orm.InfoKl
 				orders = append(orders, od)
 			}
 		}
-		wallets := GetWallets(o.Account)
+		wallets := o.ctx.GetWallets(o.Account)
 		err = wallets.UpdateOds(orders, code)
 	}
 	return err
 }
 
+/*
+UpdateByTrades
+Fill pending orders, stop loss and take profit of pair trade by trade, used in tick backtest.
+Pending orders of pairs fed by trades are no longer filled by bars.
+逐笔使用成交记录撮合pair的挂单、止损和止盈，用于逐笔回测。由成交记录驱动的品种不再使用K线撮合挂单。
+*/
+func (o *LocalOrderMgr) UpdateByTrades(allOpens []*ormo.InOutOrder, pair string, trades []*banexg.Trade) *errs.Error {
+	if core.EnvReal || len(trades) == 0 {
+		return nil
+	}
+	o.tickPairs[pair] = true
+	var curOrders []*ormo.InOutOrder
+	for _, od := range allOpens {
+		if od.Symbol == pair {
+			curOrders = append(curOrders, od)
+		}
+	}
+	if len(curOrders) == 0 {
+		return nil
+	}
+	lastMS := o.ctx.TimeMS()
+	defer func() {
+		o.ctx.CurTimeMS = max(lastMS, o.ctx.CurTimeMS)
+	}()
+	for _, tr := range trades {
+		if tr.Price <= 0 {
+			continue
+		}
+		o.ctx.CurTimeMS = tr.Timestamp
+		for _, od := range curOrders {
+			if od.Status >= ormo.InOutStatusFullExit {
+				continue
+			}
+			err := o.fillByTrade(od, tr)
+			if err != nil {
+				return err
+			}
+		}
+		o.cancelExpiredEnters(curOrders)
+	}
+	return nil
+}
+
+/*
+fillByTrade
+Try to fill the pending entry/exit, or the triggers of the order with a single trade
+尝试使用单笔成交撮合订单的入场/出场挂单，或止损止盈
+*/
+func (o *LocalOrderMgr) fillByTrade(od *ormo.InOutOrder, tr *banexg.Trade) *errs.Error {
+	var exOrder *ormo.ExOrder
+	if od.ExitTag != "" && od.Exit != nil && od.Exit.Status < ormo.OdStatusClosed {
+		if od.Enter.Status < ormo.OdStatusClosed && od.Enter.Filled > 0 {
+			o.finishPartEnter(od)
+		}
+		exOrder = od.Exit
+	} else if od.Enter.Status < ormo.OdStatusClosed {
+		exOrder = od.Enter
+	} else {
+		if od.ExitTag == "" {
+			return o.tradeFillTriggers(od, tr)
+		}
+		return nil
+	}
+	odType := config.OrderType
+	if exOrder.OrderType != "" {
+		odType = exOrder.OrderType
+	}
+	isBuy := exOrder.Side == banexg.OdSideBuy
+	price := tr.Price
+	volCap, slipCost := 0.0, 0.0
+	if odType == banexg.OdTypeLimit && exOrder.Price > 0 {
+		if isBuy && tr.Price > exOrder.Price || !isBuy && tr.Price < exOrder.Price {
+			return nil
+		}
+		price = exOrder.Price
+		if exOrder.Enter && config.BTFillVolRate > 0 {
+			// Limit the amount filled by the trade amount
+			// 按此笔成交数量限制本次成交数量
+			volCap = tr.Amount * config.BTFillVolRate
+			if volCap <= 0 {
+				return nil
+			}
+		}
+	} else {
+		price, slipCost = o.slipPrice(od, exOrder, tr.Price)
+	}
+	fillMS := tr.Timestamp + int64(config.BTNetCost*1000)
+	oldFilled := exOrder.Filled
+	var err *errs.Error
+	if exOrder.Enter {
+		err = o.fillPendingEnter(od, price, fillMS, volCap)
+	} else {
+		err = o.fillPendingExit(od, price, fillMS)
+	}
+	if err == nil {
+		addFillSlipCost(od, exOrder, oldFilled, slipCost)
+	}
+	return err
+}
+
+/*
+tradeFillTriggers
+Check and fill the stop loss and take profit of the entered order with a single trade
+使用单笔成交检查并撮合已入场订单的止损和止盈
+*/
+func (o *LocalOrderMgr) tradeFillTriggers(od *ormo.InOutOrder, tr *banexg.Trade) *errs.Error {
+	sl := od.GetStopLoss()
+	tp := od.GetTakeProfit()
+	if sl == nil && tp == nil {
+		return nil
+	}
+	if sl != nil && !sl.Hit {
+		if sl.IsTrailing() && sl.UpdateTrail(tr.Price, od.Short) {
+			od.DirtyInfo = true
+		}
+		if sl.Price > 0 {
+			sl.Hit = od.Short && tr.Price >= sl.Price || !od.Short && tr.Price <= sl.Price
+		}
+	}
+	if tp != nil && !tp.Hit && tp.Price > 0 {
+		tp.Hit = od.Short && tr.Price <= tp.Price || !od.Short && tr.Price >= tp.Price
+	}
+	var tg *ormo.TriggerState
+	isStopLoss := sl != nil && sl.Hit
+	if isStopLoss {
+		tg = sl
+	} else if tp != nil && tp.Hit {
+		tg = tp
+	} else {
+		return nil
+	}
+	od.DirtyInfo = true
+	exitSide := banexg.OdSideSell
+	if od.Short {
+		exitSide = banexg.OdSideBuy
+	}
+	odType := banexg.OdTypeMarket
+	var fillPrice, slipCost float64
+	if tg.Limit > 0 {
+		// Limit order after triggered, wait until the trade price reaches the limit
+		// 触发后挂限价单，等待成交价达到限价
+		if exitSide == banexg.OdSideBuy && tr.Price > tg.Limit || exitSide == banexg.OdSideSell && tr.Price < tg.Limit {
+			return nil
+		}
+		odType = banexg.OdTypeLimit
+		fillPrice = tg.Limit
+	} else {
+		exitAmt := od.Enter.Filled
+		if tg.Rate > 0 && tg.Rate <= 0.99 {
+			exitAmt *= tg.Rate
+		}
+		fillPrice, slipCost = slipPrice(o.slippage, od.Symbol, exitSide, tr.Price, exitAmt)
+	}
+	exitTag := triggerExitTag(od, isStopLoss, fillPrice)
+	exitAt := tr.Timestamp + int64(config.BTNetCost*1000)
+	return o.exitByTrigger(od, isStopLoss, tg.Rate, exitTag, fillPrice, odType, slipCost, exitAt)
+}
+
+/*
+applyFundFees
+Apply funding fees settled in (lastSettle, untilMS] to open contract orders. Only for backtest.
+Long positions pay positive rates and short positions receive them.
+为未平仓的合约订单应用(lastSettle, untilMS]内结算的资金费。仅用于回测。
+多头支付正费率，空头收取正费率。
+*/
+func (o *LocalOrderMgr) applyFundFees(orders []*ormo.InOutOrder, untilMS int64, price float64) {
+	if !core.IsContract && !exg.IsMultiMarket() || !core.BackTestMode || price <= 0 {
+		return
+	}
+	wallets := o.ctx.GetWallets(o.Account)
+	for _, od := range orders {
+		if !exg.IsContractPair(od.Symbol) {
+			continue
+		}
+		if od.Status < ormo.InOutStatusPartEnter || od.Status >= ormo.InOutStatusFullExit {
+			continue
+		}
+		holdAmt := od.Enter.Filled
+		if od.Exit != nil {
+			holdAmt -= od.Exit.Filled
+		}
+		if holdAmt <= 0 {
+			continue
+		}
+		rates := o.getFundRates(od)
+		lastMS := max(od.GetInfoInt64(ormo.OdInfoFundAt), od.Enter.UpdateAt, od.EnterAt)
+		for _, r := range rates {
+			if r.Time <= lastMS {
+				continue
+			} else if r.Time > untilMS {
+				break
+			}
+			fee := holdAmt * price * r.Rate
+			if od.Short {
+				fee = -fee
+			}
+			od.AddFundFee(fee, r.Time)
+			_, quote, _, _ := core.SplitSymbol(od.Symbol)
+			wallets.CostFundFee(quote, fee)
+		}
+	}
+}
+
+func (o *LocalOrderMgr) getFundRates(od *ormo.InOutOrder) []*orm.FundingRate {
+	sid := int32(od.Sid)
+	rates, ok := o.fundRates[sid]
+	if ok {
+		return rates
+	}
+	exs := orm.GetSymbolByID(sid)
+	if exs != nil {
+		var err *errs.Error
+		rates, err = orm.AutoFetchFundingRates(exg.GetPairExg(exs.PairKey()), exs, config.TimeRange.StartMS, config.TimeRange.EndMS)
+		if err != nil {
+			log.Warn("load funding rates fail", zap.String("pair", od.Symbol), zap.Error(err))
+		}
+	}
+	o.fundRates[sid] = rates
+	return rates
+}
+
 /*
 fillPendingOrders
 Fills orders waiting for exchange response. Cannot be used for real trading; can be used for backtesting, simulated real trading, etc.
@@ -102,6 +344,11 @@ func (o *LocalOrderMgr) fillPendingOrders(orders []*ormo.InOutOrder, bar *orm.In
 		}
 		var exOrder *ormo.ExOrder
 		if od.ExitTag != "" && od.Exit != nil && od.Exit.Status < ormo.OdStatusClosed {
+			if od.Enter.Status < ormo.OdStatusClosed && od.Enter.Filled > 0 {
+				// Partially filled entry should be finished before exit
+				// 部分成交的入场单，退出前先结束入场
+				o.finishPartEnter(od)
+			}
 			exOrder = od.Exit
 		} else if od.Enter.Status < ormo.OdStatusClosed {
 			exOrder = od.Enter
@@ -121,10 +368,11 @@ func (o *LocalOrderMgr) fillPendingOrders(orders []*ormo.InOutOrder, bar *orm.In
 		}
 		price := exOrder.Price
 		odTFSecs := utils.TFToSecs(od.Timeframe)
-		fillMS := btime.TimeMS() - int64((float64(odTFSecs)-config.BTNetCost)*1000)
+		fillMS := o.ctx.TimeMS() - int64((float64(odTFSecs)-config.BTNetCost)*1000)
 		fillBarRate := 0.0
+		volCap, slipCost := 0.0, 0.0
 		if bar == nil {
-			price = core.GetPrice(od.Symbol)
+			price, slipCost = o.slipPrice(od, exOrder, o.ctx.GetPrice(od.Symbol))
 		} else if odType == banexg.OdTypeLimit && exOrder.Price > 0 {
 			if exOrder.Side == banexg.OdSideBuy {
 				if price < bar.Low {
@@ -146,15 +394,24 @@ func (o *LocalOrderMgr) fillPendingOrders(orders []*ormo.InOutOrder, bar *orm.In
 			odIsBuy := exOrder.Side == banexg.OdSideBuy
 			fillBarRate = simMarketRate(&bar.Kline, exOrder.Price, odIsBuy, false, 0)
 			fillMS = bar.Time + int64(float64(odTFSecs)*fillBarRate)*1000
+			if exOrder.Enter && config.BTFillVolRate > 0 {
+				// Limit the amount filled on this bar by its volume
+				// 按此bar成交量限制本次成交数量
+				volCap = bar.Volume * config.BTFillVolRate
+				if volCap <= 0 {
+					continue
+				}
+			}
 		} else {
 			// 按网络延迟，模拟成交价格，和开盘价接近According to the network delay, the simulated transaction price is close to the opening price
 			rate := config.BTNetCost / float64(odTFSecs)
-			price = simMarketPrice(&bar.Kline, rate)
+			price, slipCost = o.slipPrice(od, exOrder, simMarketPrice(&bar.Kline, rate))
 		}
+		oldFilled := exOrder.Filled
 		var err *errs.Error
 		if exOrder.Enter {
-			err = o.fillPendingEnter(od, price, fillMS)
-			if err == nil {
+			err = o.fillPendingEnter(od, price, fillMS, volCap)
+			if err == nil && od.Enter.Status == ormo.OdStatusClosed {
 				// 入场后可能立刻触发止损/止盈
 				err = o.tryFillTriggers(od, &bar.Kline, fillBarRate)
 			}
@@ -164,44 +421,100 @@ func (o *LocalOrderMgr) fillPendingOrders(orders []*ormo.InOutOrder, bar *orm.In
 		if err != nil {
 			return 0, err
 		}
+		addFillSlipCost(od, exOrder, oldFilled, slipCost)
 		affectNum += 1
 	}
-	// Forced liquidation of limit entry orders that have not been executed within a timeout period
-	// 强制平仓超时未成交的限价入场单
-	curMS := btime.TimeMS()
+	o.cancelExpiredEnters(orders)
+	return affectNum, nil
+}
+
+/*
+cancelExpiredEnters
+Forced liquidation of limit entry orders that have not been executed within a timeout period
+强制平仓超时未成交的限价入场单
+*/
+func (o *LocalOrderMgr) cancelExpiredEnters(orders []*ormo.InOutOrder) {
+	curMS := o.ctx.TimeMS()
 	for _, od := range orders {
-		if od.Status > ormo.InOutStatusInit || od.Enter.Price == 0 ||
-			!strings.Contains(od.Enter.OrderType, banexg.OdTypeLimit) {
+		if od.Status > ormo.InOutStatusPartEnter || od.Enter.Status == ormo.OdStatusClosed ||
+			od.Enter.Price == 0 || !strings.Contains(od.Enter.OrderType, banexg.OdTypeLimit) {
 			// Skip entered and non-limit orders
 			// 跳过已入场的以及非限价单
 			continue
 		}
 		stopAfter := od.GetInfoInt64(ormo.OdInfoStopAfter)
 		if stopAfter > 0 && stopAfter <= curMS {
+			if od.Enter.Filled > 0 {
+				// Partially filled, cancel the remaining and keep the filled part
+				// 部分成交，取消剩余部分，保留已成交部分
+				o.finishPartEnter(od)
+				continue
+			}
 			err := od.LocalExit(core.ExitTagEntExp, od.InitPrice, "reach StopEnterBars", "")
-			strat.FireOdChange(o.Account, od, strat.OdChgExitFill)
+			o.ctx.FireOdChange(o.Account, od, strat.OdChgExitFill)
 			if err != nil {
 				log.Error("local exit for StopEnterBars fail", zap.String("key", od.Key()), zap.Error(err))
 			}
 		}
 	}
-	return affectNum, nil
 }
 
-func (o *LocalOrderMgr) fillPendingEnter(od *ormo.InOutOrder, price float64, fillMS int64) *errs.Error {
-	wallets := GetWallets(o.Account)
+/*
+slipPrice
+Apply the slippage model to the market fill price of exOrder, returns the new price and the slippage cost.
+The cost should be recorded by addFillSlipCost after the fill is applied.
+对exOrder的市价成交价格应用滑点模型，返回新价格和滑点成本。成本应在成交后通过addFillSlipCost记录。
+*/
+func (o *LocalOrderMgr) slipPrice(od *ormo.InOutOrder, exOrder *ormo.ExOrder, price float64) (float64, float64) {
+	if o.slippage == nil || price <= 0 {
+		return price, 0
+	}
+	amount := exOrder.Amount - exOrder.Filled
+	if exOrder.Amount == 0 {
+		// entry amount is not calculated yet
+		// 入场数量尚未计算
+		amount = od.GetInfoFloat64(ormo.OdInfoLegalCost) / price
+	}
+	return slipPrice(o.slippage, od.Symbol, exOrder.Side, price, amount)
+}
+
+/*
+addFillSlipCost
+Record the slippage cost only when exOrder is really filled, canceled or skipped fills have no cost
+仅在exOrder确实成交时记录滑点成本，取消或跳过的成交没有成本
+*/
+func addFillSlipCost(od *ormo.InOutOrder, exOrder *ormo.ExOrder, oldFilled, cost float64) {
+	if cost > 0 && exOrder.Filled > oldFilled {
+		od.AddSlipCost(cost)
+	}
+}
+
+/*
+fillPendingEnter
+Fill the entry order at price. When volCap > 0, at most volCap is filled this time, the remaining is kept
+pending on ExOrder.Filled and filled in later bars.
+以price成交入场单。volCap>0时本次最多成交volCap，剩余部分保留在ExOrder.Filled中，后续bar继续成交。
+*/
+func (o *LocalOrderMgr) fillPendingEnter(od *ormo.InOutOrder, price float64, fillMS int64, volCap float64) *errs.Error {
+	wallets := o.ctx.GetWallets(o.Account)
+	exOrder := od.Enter
+	if exOrder.Filled > 0 {
+		// Already partially filled, funds were locked on first fill
+		// 已部分成交，首次成交时已锁定资金
+		return o.addEnterFill(od, price, volCap)
+	}
 	_, err := wallets.EnterOd(od)
 	if err != nil {
 		if err.Code == core.ErrLowFunds {
 			err = od.LocalExit(core.ExitTagForceExit, od.InitPrice, err.Error(), "")
-			strat.FireOdChange(o.Account, od, strat.OdChgExitFill)
+			o.ctx.FireOdChange(o.Account, od, strat.OdChgExitFill)
 			o.onLowFunds()
 			return err
 		}
 		return err
 	}
-	exchange := exg.Default
-	market, err := exchange.GetMarket(od.Symbol)
+	exchange := exg.GetPairExg(od.Symbol)
+	market, err := exchange.GetMarket(core.PairSymbol(od.Symbol))
 	if err != nil {
 		return err
 	}
@@ -209,9 +522,8 @@ func (o *LocalOrderMgr) fillPendingEnter(od *ormo.InOutOrder, price float64, fil
 	if err != nil {
 		return err
 	}
-	exOrder := od.Enter
 	if exOrder.Amount == 0 {
-		if od.Short && !core.IsContract {
+		if od.Short && !market.Contract {
 			// Spot short order, quantity must be given
 			// 现货空单，必须给定数量
 			return errs.NewMsg(core.ErrInvalidCost, "EnterAmount is required")
@@ -231,7 +543,7 @@ func (o *LocalOrderMgr) fillPendingEnter(od *ormo.InOutOrder, price float64, fil
 			err = od.LocalExit(core.ExitTagFatalErr, od.InitPrice, err.Error(), "")
 			_, quote, _, _ := core.SplitSymbol(od.Symbol)
 			wallets.Cancel(od.Key(), quote, 0, true)
-			strat.FireOdChange(o.Account, od, strat.OdChgExitFill)
+			o.ctx.FireOdChange(o.Account, od, strat.OdChgExitFill)
 			return err
 		}
 	}
@@ -243,14 +555,12 @@ func (o *LocalOrderMgr) fillPendingEnter(od *ormo.InOutOrder, price float64, fil
 	if exOrder.CreateAt == 0 {
 		exOrder.CreateAt = updateTime
 	}
-	if exOrder.OrderType == banexg.OdTypeLimit && updateTime-od.EnterAt < 60000 {
+	if exOrder.OrderType == banexg.OdTypeLimit && updateTime-od.EnterAt < 60000 && (volCap <= 0 || volCap >= exOrder.Amount) {
 		// 以限价单入场，但很快成交的话，认为是市价单成交If the limit order is filled quickly, it will be considered a market order.
 		exOrder.OrderType = banexg.OdTypeMarket
 	}
-	if exOrder.Filled == 0 {
-		// 将EnterAt更新为实际入场时间
-		// Update Enter.UpdateAt to the actual entry time
-		od.Enter.UpdateAt = updateTime
+	if volCap > 0 && volCap < exOrder.Amount {
+		return o.addEnterFill(od, entPrice, volCap)
 	}
 	exOrder.Filled = exOrder.Amount
 	exOrder.Average = entPrice
@@ -264,12 +574,76 @@ func (o *LocalOrderMgr) fillPendingEnter(od *ormo.InOutOrder, price float64, fil
 	od.DirtyEnter = true
 	od.DirtyMain = true
 	o.callBack(od, true)
-	strat.FireOdChange(o.Account, od, strat.OdChgEnterFill)
+	o.ctx.FireOdChange(o.Account, od, strat.OdChgEnterFill)
 	return nil
 }
 
+/*
+addEnterFill
+Add a partial fill of at most volCap for the entry order. The wallet is confirmed once the order is fully filled.
+Every fill price is rounded to the market precision before averaging.
+为入场单增加最多volCap的部分成交，完全成交后再确认钱包。每次成交价格在计算均价前都按市场精度取整。
+*/
+func (o *LocalOrderMgr) addEnterFill(od *ormo.InOutOrder, price float64, volCap float64) *errs.Error {
+	exchange := exg.GetPairExg(od.Symbol)
+	market, err := exchange.GetMarket(core.PairSymbol(od.Symbol))
+	if err != nil {
+		return err
+	}
+	price, err = exchange.PrecPrice(market, price)
+	if err != nil {
+		return err
+	}
+	exOrder := od.Enter
+	fillAmt := exOrder.Amount - exOrder.Filled
+	if volCap > 0 && volCap < fillAmt {
+		fillAmt = volCap
+	}
+	filled := exOrder.Filled + fillAmt
+	exOrder.Average = (exOrder.Average*exOrder.Filled + price*fillAmt) / filled
+	exOrder.Filled = filled
+	err = od.UpdateFee(exOrder.Average, true, false)
+	if err != nil {
+		return err
+	}
+	od.DirtyEnter = true
+	od.DirtyMain = true
+	if exOrder.Amount-filled <= exOrder.Amount*0.0001 {
+		exOrder.Filled = exOrder.Amount
+		exOrder.Status = ormo.OdStatusClosed
+		o.ctx.GetWallets(o.Account).ConfirmOdEnter(od, exOrder.Average)
+		od.Status = ormo.InOutStatusFullEnter
+		o.callBack(od, true)
+	} else {
+		exOrder.Status = ormo.OdStatusPartOK
+		od.Status = ormo.InOutStatusPartEnter
+	}
+	o.ctx.FireOdChange(o.Account, od, strat.OdChgEnterFill)
+	return nil
+}
+
+/*
+finishPartEnter
+Cancel the unfilled part of a partially filled entry order, keeping the filled amount as the position.
+取消部分成交入场单的未成交部分，已成交数量作为持仓。
+*/
+func (o *LocalOrderMgr) finishPartEnter(od *ormo.InOutOrder) {
+	exOrder := od.Enter
+	od.QuoteCost *= exOrder.Filled / exOrder.Amount
+	exOrder.Amount = exOrder.Filled
+	exOrder.Status = ormo.OdStatusClosed
+	// remaining locked funds are returned to available
+	// 剩余锁定的资金归还到可用余额
+	o.ctx.GetWallets(o.Account).ConfirmOdEnter(od, exOrder.Average)
+	od.Status = ormo.InOutStatusFullEnter
+	od.DirtyEnter = true
+	od.DirtyMain = true
+	o.callBack(od, true)
+	o.ctx.FireOdChange(o.Account, od, strat.OdChgEnterFill)
+}
+
 func (o *LocalOrderMgr) fillPendingExit(od *ormo.InOutOrder, price float64, fillMS int64) *errs.Error {
-	wallets := GetWallets(o.Account)
+	wallets := o.ctx.GetWallets(o.Account)
 	exOrder := od.Exit
 	wallets.ExitOd(od, exOrder.Amount)
 	if exOrder.Filled == 0 {
@@ -291,7 +665,7 @@ func (o *LocalOrderMgr) fillPendingExit(od *ormo.InOutOrder, price float64, fill
 	_ = o.finishOrder(od, nil)
 	wallets.ConfirmOdExit(od, price)
 	o.callBack(od, false)
-	strat.FireOdChange(o.Account, od, strat.OdChgExitFill)
+	o.ctx.FireOdChange(o.Account, od, strat.OdChgExitFill)
 	return nil
 }
 
@@ -301,12 +675,24 @@ func (o *LocalOrderMgr) tryFillTriggers(od *ormo.InOutOrder, bar *banexg.Kline,
 	if sl == nil && tp == nil {
 		return nil
 	}
+	slAfterRate := afterRate
 	if sl != nil && !sl.Hit {
-		// 空单止损，最高价超过止损价触发
-		// Short order stop loss, triggered when the highest price exceeds the stop loss price
-		// 多单止损，最低价跌破止损价触发
-		// Stop loss for long orders, triggered when the lowest price falls below the stop loss price
-		sl.Hit = od.Short && bar.High >= sl.Price || !od.Short && bar.Low <= sl.Price
+		if sl.IsTrailing() {
+			// Trailing stop, simulate the price path in bar to update and check the stop price
+			// 跟踪止损，模拟bar内价格路径更新并检查止损价格
+			var hitRate float64
+			sl.Hit, hitRate = simTrailStop(sl, bar, od.Short, afterRate)
+			if sl.Hit {
+				slAfterRate = hitRate
+			}
+			od.DirtyInfo = true
+		} else {
+			// 空单止损，最高价超过止损价触发
+			// Short order stop loss, triggered when the highest price exceeds the stop loss price
+			// 多单止损，最低价跌破止损价触发
+			// Stop loss for long orders, triggered when the lowest price falls below the stop loss price
+			sl.Hit = od.Short && bar.High >= sl.Price || !od.Short && bar.Low <= sl.Price
+		}
 	}
 	if tp != nil && !tp.Hit {
 		// 空单止盈，最低价跌破止盈价触发
@@ -328,54 +714,91 @@ func (o *LocalOrderMgr) tryFillTriggers(od *ormo.InOutOrder, bar *banexg.Kline,
 		// 触发止损，计算执行价格
 		trigPrice = sl.Price
 		amtRate = sl.Rate
+		afterRate = slAfterRate
 		fillPrice = getExcPrice(od, bar, sl.Price, sl.Limit, afterRate, tfSecs)
-		if sl.Tag != "" {
-			exitTag = sl.Tag
-		} else {
-			exitTag = core.ExitTagStopLoss
-			od.UpdateProfits(fillPrice)
-			if od.ProfitRate >= 0 {
-				exitTag = core.ExitTagSLTake
-			}
-		}
+		exitTag = triggerExitTag(od, true, fillPrice)
 	} else if tp != nil && tp.Hit {
 		// Trigger take profit and calculate execution price
 		// 触发止盈，计算执行价格
 		trigPrice = tp.Price
 		amtRate = tp.Rate
 		fillPrice = getExcPrice(od, bar, tp.Price, tp.Limit, afterRate, tfSecs)
-		if tp.Tag != "" {
-			exitTag = tp.Tag
-		} else {
-			exitTag = core.ExitTagTakeProfit
-		}
+		exitTag = triggerExitTag(od, false, fillPrice)
 	} else {
 		return nil
 	}
 	if fillPrice < 0 {
 		return nil
 	}
-	curMS := btime.TimeMS()
+	curMS := o.ctx.TimeMS()
 	// The time when the simulation is triggered
 	// 模拟触发时的时间
 	var rate = config.BTNetCost / tfSecs
 	odType := banexg.OdTypeMarket
+	var slipCost float64
 	if fillPrice > 0 {
 		odType = banexg.OdTypeLimit
 		rate += simMarketRate(bar, fillPrice, od.Short, true, afterRate)
 	} else {
 		// Trigger time + network delay
 		// 触发时间+网络延迟
-		rate += simMarketRate(bar, trigPrice, od.Short, true, afterRate)
+		if sl != nil && sl.Hit && sl.IsTrailing() {
+			rate += afterRate
+		} else {
+			rate += simMarketRate(bar, trigPrice, od.Short, true, afterRate)
+		}
 		// Stop loss at market price and sell immediately
 		// 市价止损，立刻卖出
 		fillPrice = simMarketPrice(bar, rate)
+		exitAmt := od.Enter.Filled
+		if amtRate > 0 && amtRate <= 0.99 {
+			exitAmt *= amtRate
+		}
+		exitSide := banexg.OdSideSell
+		if od.Short {
+			exitSide = banexg.OdSideBuy
+		}
+		fillPrice, slipCost = slipPrice(o.slippage, od.Symbol, exitSide, fillPrice, exitAmt)
 	}
+	cutSecs := tfSecs * (1 - rate)
+	exitAt := curMS - int64(cutSecs*1000)
+	return o.exitByTrigger(od, sl != nil && sl.Hit, amtRate, exitTag, fillPrice, odType, slipCost, exitAt)
+}
+
+/*
+triggerExitTag
+Get the exit tag when the stop loss or take profit is triggered
+获取止损或止盈触发时的退出标签
+*/
+func triggerExitTag(od *ormo.InOutOrder, isStopLoss bool, fillPrice float64) string {
+	if isStopLoss {
+		if sl := od.GetStopLoss(); sl != nil && sl.Tag != "" {
+			return sl.Tag
+		}
+		od.UpdateProfits(fillPrice)
+		if od.ProfitRate >= 0 {
+			return core.ExitTagSLTake
+		}
+		return core.ExitTagStopLoss
+	}
+	if tp := od.GetTakeProfit(); tp != nil && tp.Tag != "" {
+		return tp.Tag
+	}
+	return core.ExitTagTakeProfit
+}
+
+/*
+exitByTrigger
+Exit the order (or the amtRate part of it) at fillPrice after a stop loss or take profit is triggered
+止损或止盈触发后，以fillPrice退出订单（或其amtRate部分）
+*/
+func (o *LocalOrderMgr) exitByTrigger(od *ormo.InOutOrder, isStopLoss bool, amtRate float64, exitTag string,
+	fillPrice float64, odType string, slipCost float64, exitAt int64) *errs.Error {
 	if amtRate > 0 && amtRate <= 0.99 {
 		// Partial withdrawal
 		// 部分退出
 		part := o.CutOrder(od, amtRate, 0)
-		if sl != nil && sl.Hit {
+		if isStopLoss {
 			od.SetStopLoss(nil)
 		} else {
 			od.SetTakeProfit(nil)
@@ -387,8 +810,10 @@ func (o *LocalOrderMgr) tryFillTriggers(od *ormo.InOutOrder, bar *banexg.Kline,
 		od = part
 	}
 	err := od.LocalExit(exitTag, fillPrice, "", odType)
-	cutSecs := tfSecs * (1 - rate)
-	od.ExitAt = curMS - int64(cutSecs*1000)
+	if err == nil {
+		od.AddSlipCost(slipCost)
+	}
+	od.ExitAt = exitAt
 	od.DirtyMain = true
 	if od.Exit != nil {
 		od.Exit.UpdateAt = od.ExitAt
@@ -396,28 +821,27 @@ func (o *LocalOrderMgr) tryFillTriggers(od *ormo.InOutOrder, bar *banexg.Kline,
 		od.DirtyExit = true
 	}
 	_ = od.Save(nil)
-	wallets := GetWallets(o.Account)
+	wallets := o.ctx.GetWallets(o.Account)
 	wallets.ExitOd(od, od.Exit.Amount)
 	_ = o.finishOrder(od, nil)
 	wallets.ConfirmOdExit(od, od.Exit.Price)
 	o.callBack(od, false)
-	strat.FireOdChange(o.Account, od, strat.OdChgExitFill)
+	o.ctx.FireOdChange(o.Account, od, strat.OdChgExitFill)
 	return err
 }
 
 func (o *LocalOrderMgr) onLowFunds() {
 	// If the balance is insufficient and there are no orders entered, the backtest will be terminated early.
 	// 如果余额不足，且没有入场的订单，则提前终止回测
-	openNum := ormo.OpenNum(o.Account, ormo.InOutStatusPartEnter)
+	openNum := o.ctx.OpenNum(o.Account, ormo.InOutStatusPartEnter)
 	if openNum > 0 {
 		return
 	}
-	wallets := GetWallets(o.Account)
+	wallets := o.ctx.GetWallets(o.Account)
 	value := wallets.TotalLegal(nil, false)
 	if value < core.MinStakeAmount {
 		log.Warn("wallet low funds, no open orders, stop backTest..")
-		core.StopAll()
-		core.BotRunning = false
+		o.ctx.BotRunning = false
 	}
 }
 
@@ -454,9 +878,9 @@ func (o *LocalOrderMgr) ExitAndFill(sess *ormo.Queries, orders []*ormo.InOutOrde
 			return err
 		}
 	}
-	timeMS := btime.TimeMS()
+	timeMS := o.ctx.TimeMS()
 	for _, od := range orders {
-		price := core.GetPrice(od.Symbol)
+		price := o.ctx.GetPrice(od.Symbol)
 		err := o.fillPendingExit(od, price, timeMS)
 		if err != nil {
 			return err
@@ -475,7 +899,7 @@ func (o *LocalOrderMgr) CleanUp() *errs.Error {
 	if err != nil {
 		return err
 	}
-	openOds, lock := ormo.GetOpenODs(o.Account)
+	openOds, lock := o.ctx.GetOpenODs(o.Account)
 	lock.Lock()
 	openOdList := utils.ValsOfMap(openOds)
 	lock.Unlock()
@@ -501,7 +925,7 @@ func (o *LocalOrderMgr) CleanUp() *errs.Error {
 	}
 	// Reset Unrealized P&L
 	// 重置未实现盈亏
-	wallets := GetWallets(o.Account)
+	wallets := o.ctx.GetWallets(o.Account)
 	for _, item := range wallets.Items {
 		item.lock.Lock()
 		item.UnrealizedPOL = 0
@@ -510,14 +934,14 @@ func (o *LocalOrderMgr) CleanUp() *errs.Error {
 	}
 	// Filter unfilled orders
 	// 过滤未入场订单
-	var validOds = make([]*ormo.InOutOrder, 0, len(ormo.HistODs))
-	for _, od := range ormo.HistODs {
+	var validOds = make([]*ormo.InOutOrder, 0, len(o.ctx.HistODs))
+	for _, od := range o.ctx.HistODs {
 		if od.Enter == nil || od.Enter.Filled == 0 {
 			continue
 		}
 		validOds = append(validOds, od)
 	}
-	ormo.HistODs = validOds
+	o.ctx.HistODs = validOds
 	return nil
 }
 
@@ -580,6 +1004,69 @@ func simMarketPrice(bar *banexg.Kline, rate float64) float64 {
 	return start*(1-posRate) + end*posRate
 }
 
+/*
+barPricePath
+Returns the turning points of the simulated price path in bar, and the time rate of each point.
+Same as simMarketPrice: open->low->high->close for rising bar, open->high->low->close for falling bar.
+返回bar内模拟价格路径的拐点，及每个点的时间比率。
+与simMarketPrice相同：阳线为开盘->最低->最高->收盘，阴线为开盘->最高->最低->收盘
+*/
+func barPricePath(bar *banexg.Kline) ([]float64, []float64) {
+	var prices []float64
+	var a, b, c float64
+	if bar.Open <= bar.Close {
+		prices = []float64{bar.Open, bar.Low, bar.High, bar.Close}
+		a, b, c = bar.Open-bar.Low, bar.High-bar.Low, bar.High-bar.Close
+	} else {
+		prices = []float64{bar.Open, bar.High, bar.Low, bar.Close}
+		a, b, c = bar.High-bar.Open, bar.High-bar.Low, bar.Close-bar.Low
+	}
+	totalLen := a + b + c
+	if totalLen == 0 {
+		return []float64{bar.Open, bar.Close}, []float64{0, 1}
+	}
+	return prices, []float64{0, a / totalLen, (a + b) / totalLen, 1}
+}
+
+/*
+simTrailStop
+Move the trailing stop along the simulated price path after afterRate in bar, and check whether it's hit.
+Returns whether hit and the time rate in bar when hit.
+沿bar内afterRate之后的模拟价格路径移动跟踪止损，并检查是否触发。返回是否触发，以及触发时在bar内的时间比率
+*/
+func simTrailStop(tg *ormo.TriggerState, bar *banexg.Kline, short bool, afterRate float64) (bool, float64) {
+	dirt := 1.0
+	if short {
+		dirt = -1.0
+	}
+	prices, rates := barPricePath(bar)
+	last, lastRate := bar.Open, 0.0
+	if afterRate > 0 {
+		last, lastRate = simMarketPrice(bar, afterRate), afterRate
+	}
+	tg.UpdateTrail(last, short)
+	if tg.Price > 0 && (last-tg.Price)*dirt <= 0 {
+		return true, lastRate
+	}
+	for i, price := range prices {
+		if rates[i] <= lastRate {
+			continue
+		}
+		if (price-last)*dirt < 0 {
+			// Price moves against the position, check whether the stop price is crossed
+			// 价格向亏损方向移动，检查是否穿过止损价格
+			if tg.Price > 0 && (price-tg.Price)*dirt <= 0 {
+				hitRate := lastRate + (rates[i]-lastRate)*(last-tg.Price)/(last-price)
+				return true, hitRate
+			}
+		} else {
+			tg.UpdateTrail(price, short)
+		}
+		last, lastRate = price, rates[i]
+	}
+	return false, 0
+}
+
 func simMarketRate(bar *banexg.Kline, price float64, isBuy, isTrigger bool, minRate float64) float64 {
 	if isTrigger {
 		// For the order that triggers the price, it is not a pending order. If it is judged that it is not within the bar range, it is considered to be completed immediately.
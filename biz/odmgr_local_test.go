@@ -0,0 +1,400 @@
+package biz
+
+import (
+	"math"
+	"testing"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/exg"
+	"github.com/banbox/banbot/orm"
+	"github.com/banbox/banbot/orm/ormo"
+	"github.com/banbox/banbot/strat"
+	"github.com/banbox/banexg"
+)
+
+const testPair = "ETH/USDT:USDT"
+
+/*
+setupLocalMgr
+Prepare a mock linear market, a file kline store and wallets for LocalOrderMgr tests, without network or database.
+为LocalOrderMgr测试准备模拟的U本位合约市场、文件K线存储和钱包，无需网络和数据库
+*/
+func setupLocalMgr(t *testing.T) (*LocalOrderMgr, *orm.ExSymbol) {
+	oldDb, oldExg, oldDefault := config.Database, config.Exchange, exg.Default
+	oldMarket, oldContract, oldBT := core.Market, core.IsContract, core.BackTestMode
+	oldVolRate := config.BTFillVolRate
+	t.Cleanup(func() {
+		config.Database, config.Exchange, exg.Default = oldDb, oldExg, oldDefault
+		core.Market, core.IsContract, core.BackTestMode = oldMarket, oldContract, oldBT
+		config.BTFillVolRate = oldVolRate
+	})
+	config.Database = &config.DatabaseConfig{KlineStore: "file", KlineDir: t.TempDir()}
+	config.Exchange = &config.ExchangeConfig{Name: "binance"}
+	core.Market, core.IsContract, core.BackTestMode = banexg.MarketLinear, true, true
+	exg.Default = nil
+	if err := orm.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	client, err := exg.GetWith("binance", banexg.MarketLinear, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exg.Default = client
+	client.GetExg().Markets = banexg.MarketMap{
+		testPair: {ID: "ETHUSDT", Symbol: testPair, Base: "ETH", Quote: "USDT", Settle: "USDT", Type: banexg.MarketLinear,
+			Swap: true, Contract: true, Linear: true, Active: true, Taker: 0.0005, Maker: 0.0002, ContractSize: 1,
+			Precision: &banexg.Precision{Amount: 0.001, Price: 0.01, ModeAmount: banexg.PrecModeTickSize,
+				ModePrice: banexg.PrecModeTickSize}},
+	}
+	exs := &orm.ExSymbol{Exchange: "binance", Market: banexg.MarketLinear, Symbol: testPair}
+	if err = orm.EnsureSymbols([]*orm.ExSymbol{exs}); err != nil {
+		t.Fatal(err)
+	}
+	c := NewRunCtx()
+	c.SetPrices(map[string]float64{testPair: 100, "USDT": 1})
+	c.GetWallets(config.DefAcc).SetWallets(map[string]float64{"USDT": 10000})
+	mgr := &LocalOrderMgr{
+		OrderMgr:  OrderMgr{ctx: c, callBack: func(od *ormo.InOutOrder, isEnter bool) {}, Account: config.DefAcc},
+		zeroAmts:  make(map[string]int),
+		fundRates: make(map[int32][]*orm.FundingRate),
+		tickPairs: make(map[string]bool),
+	}
+	return mgr, exs
+}
+
+// newTestOrder pending entry order of amount at price, a market order when price is 0 以price挂单入场amount的订单，price为0时为市价单
+func newTestOrder(c *Ctx, exs *orm.ExSymbol, short bool, price, amount float64, enterAt int64) *ormo.InOutOrder {
+	side, odType := banexg.OdSideBuy, banexg.OdTypeLimit
+	if short {
+		side = banexg.OdSideSell
+	}
+	if price == 0 {
+		odType = banexg.OdTypeMarket
+	}
+	od := &ormo.InOutOrder{
+		IOrder: &ormo.IOrder{Sid: int64(exs.ID), Symbol: exs.Symbol, Timeframe: "1m", Short: short, Leverage: 1,
+			EnterTag: "test", InitPrice: 100, Status: ormo.InOutStatusInit, EnterAt: enterAt},
+		Enter: &ormo.ExOrder{Enter: true, Side: side, OrderType: odType, Price: price, Amount: amount},
+		Info:  map[string]interface{}{},
+	}
+	od.SetCtx(c.OdCtx())
+	return od
+}
+
+// listenOdChange count order change events by type 按类型统计订单变化事件
+func listenOdChange(c *Ctx) map[int]int {
+	evts := make(map[int]int)
+	c.AddOdSub(config.DefAcc, func(acc string, od *ormo.InOutOrder, evt int) {
+		evts[evt] += 1
+	})
+	return evts
+}
+
+func testBar(timeMS int64, open, high, low, close, vol float64) *orm.InfoKline {
+	return &orm.InfoKline{PairTFKline: &banexg.PairTFKline{Symbol: testPair, TimeFrame: "1m",
+		Kline: banexg.Kline{Time: timeMS, Open: open, High: high, Low: low, Close: close, Volume: vol}}}
+}
+
+func TestVolCappedEnter(t *testing.T) {
+	mgr, exs := setupLocalMgr(t)
+	config.BTFillVolRate = 0.1
+	evts := listenOdChange(mgr.ctx)
+	startMS := int64(1700000000000)
+	type barFill struct {
+		bar     *orm.InfoKline
+		filled  float64
+		average float64
+		status  int64
+	}
+	cases := []struct {
+		name      string
+		stopAfter int64 // OdInfoStopAfter, 0 for none
+		fills     []barFill
+		fillEvts  int
+		exitTag   string
+	}{
+		{
+			name: "capped by volume and carried to next bar",
+			fills: []barFill{
+				// open above the limit, filled at limit price 开盘价高于限价，以限价成交
+				{testBar(startMS, 101, 102, 99, 100, 40), 4, 100, ormo.InOutStatusPartEnter},
+				// low above the limit, no fill 最低价高于限价，不成交
+				{testBar(startMS+60000, 101, 102, 100.5, 101, 100), 4, 100, ormo.InOutStatusPartEnter},
+				// open below the limit, filled at open, remaining 6 is less than cap 8 开盘低于限价以开盘价成交，剩余6小于上限8
+				{testBar(startMS+120000, 98, 99, 97, 98, 80), 10, 98.8, ormo.InOutStatusFullEnter},
+			},
+			fillEvts: 2,
+		},
+		{
+			name: "later fills are rounded to price precision",
+			fills: []barFill{
+				{testBar(startMS, 101, 102, 99, 100, 40), 4, 100, ormo.InOutStatusPartEnter},
+				// open 98.006 is filled at 98.01 开盘价98.006以98.01成交
+				{testBar(startMS+60000, 98.006, 99, 97, 98, 80), 10, 98.806, ormo.InOutStatusFullEnter},
+			},
+			fillEvts: 2,
+		},
+		{
+			name:      "expired after partial fill keeps the filled part",
+			stopAfter: startMS + 90000,
+			fills: []barFill{
+				{testBar(startMS, 100, 101, 99, 100, 30), 3, 100, ormo.InOutStatusPartEnter},
+				{testBar(startMS+120000, 101, 102, 100.5, 101, 100), 3, 100, ormo.InOutStatusFullEnter},
+			},
+			fillEvts: 2,
+		},
+		{
+			name:      "expired without fill exits the order",
+			stopAfter: startMS + 90000,
+			fills: []barFill{
+				{testBar(startMS, 101, 102, 100.5, 101, 30), 0, 0, ormo.InOutStatusInit},
+				{testBar(startMS+120000, 101, 102, 100.5, 101, 100), 0, 0, ormo.InOutStatusFullExit},
+			},
+			exitTag: core.ExitTagEntExp,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clear(evts)
+			od := newTestOrder(mgr.ctx, exs, false, 100, 10, startMS)
+			if c.stopAfter > 0 {
+				od.SetInfo(ormo.OdInfoStopAfter, c.stopAfter)
+			}
+			for i, f := range c.fills {
+				mgr.ctx.CurTimeMS = f.bar.Time + 60000
+				if _, err := mgr.fillPendingOrders([]*ormo.InOutOrder{od}, f.bar); err != nil {
+					t.Fatal(err)
+				}
+				if od.Enter.Filled != f.filled || math.Abs(od.Enter.Average-f.average) > 1e-9 || od.Status != f.status {
+					t.Fatalf("bar %d: filled %v avg %v status %v, expect %v %v %v", i, od.Enter.Filled,
+						od.Enter.Average, od.Status, f.filled, f.average, f.status)
+				}
+			}
+			if evts[strat.OdChgEnterFill] != c.fillEvts {
+				t.Errorf("expect %d enter fill events, got %d", c.fillEvts, evts[strat.OdChgEnterFill])
+			}
+			if od.ExitTag != c.exitTag {
+				t.Errorf("expect exit tag %q, got %q", c.exitTag, od.ExitTag)
+			}
+			if od.Status == ormo.InOutStatusFullEnter && od.Enter.Amount != od.Enter.Filled {
+				t.Errorf("entered amount should be the filled part, got %v/%v", od.Enter.Filled, od.Enter.Amount)
+			}
+		})
+	}
+}
+
+func TestSlipCostOnFill(t *testing.T) {
+	mgr, exs := setupLocalMgr(t)
+	mgr.slippage = NewSlippageModel(core.DefCtx, &config.SlippageConfig{Model: core.SlipFixed, Bps: 10})
+	bar := testBar(1700000000000, 100, 101, 99, 100, 1000)
+	mgr.ctx.CurTimeMS = bar.Time + 60000
+	// 1000 legal cost exceeds the 50 USDT balance, the order is exited without a fill 1000的成本超出50余额，订单未成交即退出
+	oldRate := config.MinOpenRate
+	config.MinOpenRate = 0.5
+	defer func() {
+		config.MinOpenRate = oldRate
+	}()
+	mgr.ctx.GetWallets(config.DefAcc).SetWallets(map[string]float64{"USDT": 50})
+	od := newTestOrder(mgr.ctx, exs, false, 0, 10, bar.Time)
+	if _, err := mgr.fillPendingOrders([]*ormo.InOutOrder{od}, bar); err != nil {
+		t.Fatal(err)
+	}
+	if od.Status != ormo.InOutStatusFullExit || od.GetInfoFloat64(ormo.OdInfoSlipCost) != 0 {
+		t.Errorf("failed fill should have no slippage cost, status %v cost %v", od.Status,
+			od.GetInfoFloat64(ormo.OdInfoSlipCost))
+	}
+	mgr.ctx.GetWallets(config.DefAcc).SetWallets(map[string]float64{"USDT": 10000})
+	od = newTestOrder(mgr.ctx, exs, false, 0, 10, bar.Time)
+	if _, err := mgr.fillPendingOrders([]*ormo.InOutOrder{od}, bar); err != nil {
+		t.Fatal(err)
+	}
+	// 10 bps of 10*100 10个基点
+	if od.Status != ormo.InOutStatusFullEnter || math.Abs(od.GetInfoFloat64(ormo.OdInfoSlipCost)-1) > 1e-6 {
+		t.Errorf("filled order should record slippage cost, status %v cost %v", od.Status,
+			od.GetInfoFloat64(ormo.OdInfoSlipCost))
+	}
+}
+
+func TestApplyFundFees(t *testing.T) {
+	mgr, exs := setupLocalMgr(t)
+	hourMS := int64(3600000)
+	startMS := int64(1700000000000)
+	oldRange := config.TimeRange
+	config.TimeRange = &config.TimeTuple{StartMS: startMS, EndMS: startMS + 24*hourMS}
+	defer func() {
+		config.TimeRange = oldRange
+	}()
+	// the stored series covers TimeRange, so nothing is downloaded 已存储的序列覆盖TimeRange，无需下载
+	rates := []*orm.FundingRate{
+		{Time: startMS, Rate: 0.003},
+		{Time: startMS + 8*hourMS, Rate: 0.001},
+		{Time: startMS + 16*hourMS, Rate: -0.0005},
+		{Time: startMS + 24*hourMS - 1, Rate: 0.002},
+	}
+	sess, conn, err := orm.Conn(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sess.SetFundingRates(exs.ID, rates)
+	conn.Release()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallets := mgr.ctx.GetWallets(config.DefAcc)
+	newHold := func(short bool) *ormo.InOutOrder {
+		od := newTestOrder(mgr.ctx, exs, short, 100, 10, startMS+hourMS)
+		od.Status = ormo.InOutStatusFullEnter
+		od.Enter.Filled = 10
+		od.Enter.Average = 100
+		od.Enter.Status = ormo.OdStatusClosed
+		return od
+	}
+	long, short := newHold(false), newHold(true)
+	orders := []*ormo.InOutOrder{long, short}
+	cases := []struct {
+		name    string
+		untilMS int64
+		longFee float64 // accumulated fee of long order, short is the opposite 多单累计资金费，空单相反
+	}{
+		// the rate at startMS is settled before entry 开始时的费率在入场前结算
+		{"before first settle", startMS + 7*hourMS, 0},
+		// 10*100*(0.001-0.0005)
+		{"across two intervals", startMS + 17*hourMS, 0.5},
+		{"no double count", startMS + 17*hourMS, 0.5},
+		{"last interval", startMS + 24*hourMS, 2.5},
+	}
+	oldAva := wallets.Get("USDT").Available
+	for _, c := range cases {
+		mgr.applyFundFees(orders, c.untilMS, 100)
+		longFee, shortFee := long.GetInfoFloat64(ormo.OdInfoFundFee), short.GetInfoFloat64(ormo.OdInfoFundFee)
+		if math.Abs(longFee-c.longFee) > 1e-9 || math.Abs(shortFee+c.longFee) > 1e-9 {
+			t.Errorf("%s: long fee %v, short fee %v, expect %v", c.name, longFee, shortFee, c.longFee)
+		}
+		// long pays what short receives 多单支付的等于空单收取的
+		if chg := wallets.Get("USDT").Available - oldAva; math.Abs(chg) > 1e-9 {
+			t.Errorf("%s: wallet should be unchanged, got %v", c.name, chg)
+		}
+	}
+	od := newHold(false)
+	mgr.applyFundFees([]*ormo.InOutOrder{od}, startMS+17*hourMS, 100)
+	if chg := wallets.Get("USDT").Available - oldAva; math.Abs(chg+0.5) > 1e-9 {
+		t.Errorf("long funding should be paid from wallet, got %v", chg)
+	}
+	mgr.applyFundFees([]*ormo.InOutOrder{long}, startMS+24*hourMS, 100)
+	if long.GetInfoInt64(ormo.OdInfoFundAt) != startMS+24*hourMS-1 {
+		t.Errorf("fund time should be the last settle, got %v", long.GetInfoInt64(ormo.OdInfoFundAt))
+	}
+	// funding is deducted from the profit 资金费从利润中扣除
+	long.UpdateProfits(100)
+	if math.Abs(long.Profit+2.5+long.Enter.Fee) > 1e-9 {
+		t.Errorf("profit should deduct funding fee, got %v", long.Profit)
+	}
+
+	// a missing funding rate series charges nothing 缺失资金费率序列时不收费
+	mgr.fundRates[exs.ID] = nil
+	od = newHold(false)
+	mgr.applyFundFees([]*ormo.InOutOrder{od}, startMS+24*hourMS, 100)
+	if od.GetInfoFloat64(ormo.OdInfoFundFee) != 0 || od.GetInfoInt64(ormo.OdInfoFundAt) != 0 {
+		t.Errorf("missing rates should charge nothing, got %v", od.GetInfoFloat64(ormo.OdInfoFundFee))
+	}
+}
+
+func TestUpdateByTrades(t *testing.T) {
+	mgr, exs := setupLocalMgr(t)
+	startMS := int64(1700000000000)
+	trade := func(secs int64, price, amount float64) *banexg.Trade {
+		return &banexg.Trade{Symbol: testPair, Timestamp: startMS + secs*1000, Price: price, Amount: amount}
+	}
+	cases := []struct {
+		name    string
+		price   float64 // entry limit price, 0 for market 入场限价，0为市价
+		volRate float64
+		sl, tp  *ormo.ExitTrigger
+		trades  []*banexg.Trade
+		filled  float64
+		average float64
+		status  int64
+		exitTag string
+		exitAvg float64
+	}{
+		{
+			name:  "limit filled when price crosses in batch",
+			price: 100,
+			// first trade below the limit fills at the limit price 第一笔低于限价的成交以限价成交
+			trades:  []*banexg.Trade{trade(1, 101, 5), trade(2, 100.5, 5), trade(3, 99.8, 5), trade(4, 99, 5)},
+			filled:  10,
+			average: 100,
+			status:  ormo.InOutStatusFullEnter,
+		},
+		{
+			name:    "limit not reached",
+			price:   100,
+			trades:  []*banexg.Trade{trade(1, 101, 5), trade(2, 100.01, 5)},
+			status:  ormo.InOutStatusInit,
+			filled:  0,
+			average: 0,
+		},
+		{
+			name:    "partial quantity capped by trade amount",
+			price:   100,
+			volRate: 0.5,
+			// caps: 2, skipped above the limit, 3 各笔上限：2、高于限价跳过、3
+			trades:  []*banexg.Trade{trade(1, 99.9, 4), trade(2, 100.2, 50), trade(3, 99.5, 6)},
+			filled:  5,
+			average: 100,
+			status:  ormo.InOutStatusPartEnter,
+		},
+		{
+			name:    "stop loss by trade after market entry",
+			sl:      &ormo.ExitTrigger{Price: 95},
+			tp:      &ormo.ExitTrigger{Price: 110},
+			trades:  []*banexg.Trade{trade(1, 100, 1), trade(2, 96, 1), trade(3, 94.9, 1), trade(4, 90, 1)},
+			filled:  10,
+			average: 100,
+			status:  ormo.InOutStatusFullExit,
+			exitTag: core.ExitTagStopLoss,
+			exitAvg: 94.9,
+		},
+		{
+			name:    "take profit with limit waits for the limit price",
+			tp:      &ormo.ExitTrigger{Price: 105, Limit: 106},
+			trades:  []*banexg.Trade{trade(1, 100, 1), trade(2, 105.5, 1), trade(3, 105.9, 1), trade(4, 106.2, 1)},
+			filled:  10,
+			average: 100,
+			status:  ormo.InOutStatusFullExit,
+			exitTag: core.ExitTagTakeProfit,
+			exitAvg: 106,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config.BTFillVolRate = c.volRate
+			mgr.ctx.GetWallets(config.DefAcc).SetWallets(map[string]float64{"USDT": 10000})
+			od := newTestOrder(mgr.ctx, exs, false, c.price, 10, startMS)
+			if c.sl != nil {
+				od.SetStopLoss(c.sl)
+			}
+			if c.tp != nil {
+				od.SetTakeProfit(c.tp)
+			}
+			mgr.ctx.CurTimeMS = startMS
+			if err := mgr.UpdateByTrades([]*ormo.InOutOrder{od}, testPair, c.trades); err != nil {
+				t.Fatal(err)
+			}
+			if od.Enter.Filled != c.filled || math.Abs(od.Enter.Average-c.average) > 1e-9 || od.Status != c.status {
+				t.Fatalf("filled %v avg %v status %v, expect %v %v %v", od.Enter.Filled, od.Enter.Average,
+					od.Status, c.filled, c.average, c.status)
+			}
+			if od.ExitTag != c.exitTag {
+				t.Errorf("expect exit tag %q, got %q", c.exitTag, od.ExitTag)
+			}
+			if c.exitTag != "" && math.Abs(od.Exit.Average-c.exitAvg) > 1e-9 {
+				t.Errorf("expect exit at %v, got %v", c.exitAvg, od.Exit.Average)
+			}
+		})
+	}
+	if !mgr.tickPairs[testPair] {
+		t.Errorf("pair fed by trades should be marked")
+	}
+}
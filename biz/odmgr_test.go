@@ -2,8 +2,10 @@ package biz
 
 import (
 	"fmt"
+	"github.com/banbox/banbot/core"
 	"github.com/banbox/banbot/exg"
 	"github.com/banbox/banbot/orm"
+	"github.com/banbox/banbot/orm/ormo"
 	"github.com/banbox/banexg"
 	"math"
 	"testing"
@@ -45,7 +47,7 @@ func TestCalcSecsForPrice(t *testing.T) {
 	if secsVol == 0 {
 		panic(err)
 	}
-	book, err := exg.GetOdBook(pair)
+	book, err := exg.GetOdBook(core.DefCtx, pair)
 	if err != nil {
 		panic(err)
 	}
@@ -63,3 +65,30 @@ func TestCalcSecsForPrice(t *testing.T) {
 			side, price, minWaitSecs, rate*100)
 	}
 }
+
+func TestSimTrailStop(t *testing.T) {
+	// rising bar: open->low->high->close, trail 2% from high 110 gives stop 107.8, hit on the way to close
+	bar := &banexg.Kline{Open: 100, High: 110, Low: 99, Close: 105}
+	tg := &ormo.TriggerState{ExitTrigger: &ormo.ExitTrigger{Callback: 0.02}}
+	hit, rate := simTrailStop(tg, bar, false, 0)
+	if !hit || math.Abs(tg.Price-107.8) > 1e-9 || tg.Best != 110 {
+		t.Errorf("long trail: hit %v price %v best %v", hit, tg.Price, tg.Best)
+	}
+	// path length 1+11+5=17, hit after 12+2.2
+	if math.Abs(rate-14.2/17) > 1e-9 {
+		t.Errorf("long trail hit rate %v", rate)
+	}
+	// not activated until price reaches 120
+	tg = &ormo.TriggerState{ExitTrigger: &ormo.ExitTrigger{Distance: 1, Activate: 120}}
+	hit, _ = simTrailStop(tg, bar, false, 0)
+	if hit || tg.Active || tg.Price != 0 {
+		t.Errorf("inactive trail should not hit, price %v", tg.Price)
+	}
+	// short: falling bar open->high->low->close, best low 90, stop 91.8, close at 93 hits
+	bar = &banexg.Kline{Open: 100, High: 101, Low: 90, Close: 93}
+	tg = &ormo.TriggerState{ExitTrigger: &ormo.ExitTrigger{Callback: 0.02}}
+	hit, _ = simTrailStop(tg, bar, true, 0)
+	if !hit || math.Abs(tg.Price-91.8) > 1e-9 {
+		t.Errorf("short trail: hit %v price %v", hit, tg.Price)
+	}
+}
@@ -0,0 +1,187 @@
+package biz
+
+import (
+	"strings"
+
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/orm"
+	"github.com/banbox/banbot/orm/ormo"
+	"github.com/banbox/banbot/strat"
+	"github.com/banbox/banexg"
+	"github.com/banbox/banexg/errs"
+	"github.com/banbox/banexg/log"
+	"github.com/banbox/banta"
+	"go.uber.org/zap"
+)
+
+/*
+liveOdRouter
+Order manager of account trading on several exchanges/markets in live, dispatch calls to the LiveOrderMgr of
+the exchange/market by pair key.
+实盘中在多个交易所/市场交易的账户的订单管理器，按品种键将调用分发到对应交易所/市场的LiveOrderMgr
+*/
+type liveOdRouter struct {
+	ctx     *Ctx
+	Account string
+}
+
+func (r *liveOdRouter) pairMgr(pair string) (*LiveOrderMgr, *errs.Error) {
+	mgr := r.ctx.GetPairLiveOdMgr(r.Account, pair)
+	if mgr == nil {
+		return nil, errs.NewMsg(core.ErrRunTime, "no LiveOrderMgr for %s in %s", pair, r.Account)
+	}
+	return mgr, nil
+}
+
+// groupOrders group orders by LiveOrderMgr of their pairs 按品种所属的LiveOrderMgr分组订单
+func (r *liveOdRouter) groupOrders(orders []*ormo.InOutOrder) (map[*LiveOrderMgr][]*ormo.InOutOrder, *errs.Error) {
+	res := make(map[*LiveOrderMgr][]*ormo.InOutOrder)
+	for _, od := range orders {
+		mgr, err := r.pairMgr(od.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		res[mgr] = append(res[mgr], od)
+	}
+	return res, nil
+}
+
+func (r *liveOdRouter) ProcessOrders(sess *ormo.Queries, env *banta.BarEnv, enters []*strat.EnterReq,
+	exits []*strat.ExitReq, edits []*ormo.InOutEdit) ([]*ormo.InOutOrder, []*ormo.InOutOrder, *errs.Error) {
+	mgr, err := r.pairMgr(env.Symbol)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mgr.ProcessOrders(sess, env, enters, exits, edits)
+}
+
+func (r *liveOdRouter) RelayOrders(sess *ormo.Queries, orders []*ormo.InOutOrder) *errs.Error {
+	groups, err := r.groupOrders(orders)
+	if err != nil {
+		return err
+	}
+	for mgr, ods := range groups {
+		err = mgr.RelayOrders(sess, ods)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *liveOdRouter) EnterOrder(sess *ormo.Queries, env *banta.BarEnv, req *strat.EnterReq, doCheck bool) (*ormo.InOutOrder, *errs.Error) {
+	mgr, err := r.pairMgr(env.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.EnterOrder(sess, env, req, doCheck)
+}
+
+/*
+ExitOpenOrders
+pairs are split by exchange/market, all LiveOrderMgr are called when pairs is empty
+pairs按交易所/市场拆分，pairs为空时调用所有LiveOrderMgr
+*/
+func (r *liveOdRouter) ExitOpenOrders(sess *ormo.Queries, pairs string, req *strat.ExitReq) ([]*ormo.InOutOrder, *errs.Error) {
+	if req.OrderID > 0 {
+		openOds, lock := r.ctx.GetOpenODs(r.Account)
+		lock.Lock()
+		od, ok := openOds[req.OrderID]
+		lock.Unlock()
+		if !ok {
+			return nil, errs.NewMsg(errs.CodeParamInvalid, "req orderId not found: %d", req.OrderID)
+		}
+		mgr, err := r.pairMgr(od.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		return mgr.ExitOpenOrders(sess, pairs, req)
+	}
+	var mgrPairs = make(map[*LiveOrderMgr][]string)
+	if pairs == "" {
+		for _, mgr := range r.ctx.GetLiveOdMgrs(r.Account) {
+			mgrPairs[mgr] = nil
+		}
+	} else {
+		for _, p := range strings.Split(pairs, ",") {
+			if p == "" {
+				continue
+			}
+			mgr, err := r.pairMgr(p)
+			if err != nil {
+				return nil, err
+			}
+			mgrPairs[mgr] = append(mgrPairs[mgr], p)
+		}
+	}
+	var res []*ormo.InOutOrder
+	for mgr, items := range mgrPairs {
+		ods, err := mgr.ExitOpenOrders(sess, strings.Join(items, ","), req)
+		res = append(res, ods...)
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+func (r *liveOdRouter) ExitOrder(sess *ormo.Queries, od *ormo.InOutOrder, req *strat.ExitReq) (*ormo.InOutOrder, *errs.Error) {
+	mgr, err := r.pairMgr(od.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.ExitOrder(sess, od, req)
+}
+
+func (r *liveOdRouter) UpdateByBar(allOpens []*ormo.InOutOrder, bar *orm.InfoKline) *errs.Error {
+	mgr, err := r.pairMgr(bar.Symbol)
+	if err != nil {
+		return err
+	}
+	return mgr.UpdateByBar(allOpens, bar)
+}
+
+func (r *liveOdRouter) UpdateByTrades(allOpens []*ormo.InOutOrder, pair string, trades []*banexg.Trade) *errs.Error {
+	mgr, err := r.pairMgr(pair)
+	if err != nil {
+		return err
+	}
+	return mgr.UpdateByTrades(allOpens, pair, trades)
+}
+
+func (r *liveOdRouter) ExitAndFill(sess *ormo.Queries, orders []*ormo.InOutOrder, req *strat.ExitReq) *errs.Error {
+	groups, err := r.groupOrders(orders)
+	if err != nil {
+		return err
+	}
+	for mgr, ods := range groups {
+		err = mgr.ExitAndFill(sess, ods, req)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *liveOdRouter) OnEnvEnd(bar *banexg.PairTFKline, adj *orm.AdjInfo) *errs.Error {
+	mgr, err := r.pairMgr(bar.Symbol)
+	if err != nil {
+		return err
+	}
+	return mgr.OnEnvEnd(bar, adj)
+}
+
+func (r *liveOdRouter) CleanUp() *errs.Error {
+	var err *errs.Error
+	for _, mgr := range r.ctx.GetLiveOdMgrs(r.Account) {
+		curErr := mgr.CleanUp()
+		if curErr != nil {
+			if err != nil {
+				log.Error("clean LiveOrderMgr fail", zap.String("acc", r.Account), zap.Error(curErr))
+			} else {
+				err = curErr
+			}
+		}
+	}
+	return err
+}
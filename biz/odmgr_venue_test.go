@@ -0,0 +1,87 @@
+package biz
+
+import (
+	"testing"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/orm/ormo"
+)
+
+func setVenueEnv(t *testing.T) *Ctx {
+	oldReal, oldExg, oldMarket := core.EnvReal, core.ExgName, core.Market
+	core.EnvReal, core.ExgName, core.Market = true, "binance", "linear"
+	t.Cleanup(func() {
+		core.EnvReal, core.ExgName, core.Market = oldReal, oldExg, oldMarket
+	})
+	return NewRunCtx()
+}
+
+func TestLiveOdRouter(t *testing.T) {
+	ctx := setVenueEnv(t)
+	acc := "venue"
+	newMgr := func(market string) *LiveOrderMgr {
+		return &LiveOrderMgr{OrderMgr: OrderMgr{Account: acc, exgName: "binance", market: market}}
+	}
+	linear, spot := newMgr("linear"), newMgr("spot")
+	ctx.accLiveOdMgrs[acc] = map[string]*LiveOrderMgr{
+		"binance.spot":   spot,
+		"binance.linear": linear,
+	}
+	mgrs := ctx.GetLiveOdMgrs(acc)
+	if len(mgrs) != 2 || mgrs[0] != linear || mgrs[1] != spot {
+		t.Fatalf("default market should be the first of %d mgrs", len(mgrs))
+	}
+	spotKey := core.PairKey("binance", "spot", "BTC/USDT")
+	cases := []struct {
+		pair string
+		want *LiveOrderMgr
+	}{
+		{"BTC/USDT", linear},
+		{spotKey, spot},
+		{"BTC/USDT@okx.spot", nil},
+	}
+	for _, c := range cases {
+		if got := ctx.GetPairLiveOdMgr(acc, c.pair); got != c.want {
+			t.Errorf("GetPairLiveOdMgr(%s) got wrong mgr", c.pair)
+		}
+		if c.want != nil && (!c.want.hasPair(c.pair) || linear.hasPair(c.pair) == spot.hasPair(c.pair)) {
+			t.Errorf("%s should be handled by one mgr only", c.pair)
+		}
+	}
+	newOd := func(id int64, pair string) *ormo.InOutOrder {
+		return &ormo.InOutOrder{IOrder: &ormo.IOrder{ID: id, Symbol: pair}}
+	}
+	router := &liveOdRouter{ctx: ctx, Account: acc}
+	groups, err := router.groupOrders([]*ormo.InOutOrder{newOd(1, "BTC/USDT"), newOd(2, spotKey), newOd(3, spotKey)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups[linear]) != 1 || len(groups[spot]) != 2 {
+		t.Errorf("orders should be grouped by exchange/market, got %d/%d", len(groups[linear]), len(groups[spot]))
+	}
+	_, err = router.groupOrders([]*ormo.InOutOrder{newOd(4, "BTC/USDT@okx.spot")})
+	if err == nil {
+		t.Errorf("orders without LiveOrderMgr should fail")
+	}
+}
+
+func TestGetPairWallets(t *testing.T) {
+	ctx := setVenueEnv(t)
+	acc := "venue"
+	def := ctx.GetWallets(acc)
+	if ctx.GetPairWallets(acc, "BTC/USDT") != def || def.VenueMarket() != "linear" {
+		t.Errorf("default market should use wallets of GetWallets")
+	}
+	spot := ctx.GetPairWallets(acc, core.PairKey("binance", "spot", "BTC/USDT"))
+	if spot == def || spot.Market != "spot" || spot.VenueMarket() != "spot" || spot.Account != acc {
+		t.Errorf("spot should have its own wallets")
+	}
+	if ctx.GetPairWallets(acc, core.PairKey("binance", "spot", "ETH/USDT")) != spot {
+		t.Errorf("pairs of same exchange/market should share wallets")
+	}
+	core.EnvReal = false
+	if ctx.GetPairWallets(acc, core.PairKey("binance", "spot", "BTC/USDT")) != ctx.GetWallets(config.DefAcc) {
+		t.Errorf("wallets should be shared when not real trading")
+	}
+}
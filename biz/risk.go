@@ -0,0 +1,307 @@
+package biz
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/orm"
+	"github.com/banbox/banbot/orm/ormo"
+	"github.com/banbox/banbot/strat"
+	"github.com/banbox/banbot/utils"
+	"github.com/banbox/banexg/log"
+	utils2 "github.com/banbox/banexg/utils"
+	"go.uber.org/zap"
+	"gonum.org/v1/gonum/mat"
+)
+
+const dayMSecs = int64(86400000)
+
+/*
+RiskMgr
+Portfolio level risk checks of an account before order entry: notional exposure caps for gross/net, each currency
+and each correlated cluster, and a daily loss limit. Rejections are recorded by Ctx.AddAccFailOpens.
+账户开单前的组合级风控检查：总/净、每个币种、每个相关簇的名义敞口上限，以及每日亏损限制。拒绝会通过Ctx.AddAccFailOpens记录。
+*/
+type RiskMgr struct {
+	ctx       *Ctx
+	Account   string
+	Cfg       *config.RiskConfig
+	dayStart  int64   // start of current UTC day 当前UTC日开始时间
+	dayEquity float64 // equity when the day begins 当日开始时的权益
+	corrAt    int64   // time of last correlation calculation 上次计算相关性的时间
+	corrIdx   map[string]int
+	corrTried map[string]bool // pairs tried in last correlation calculation 上次计算相关性时尝试的标的
+	corrMat   *mat.SymDense
+}
+
+// riskExposure signed notional of positions, long is positive 持仓的带符号名义价值，多头为正
+type riskExposure struct {
+	gross    float64
+	net      float64
+	currency map[string]float64
+	pairs    map[string]float64
+}
+
+/*
+NewRiskMgr
+Returns nil if risk is not configured for the account
+账户未配置risk时返回nil
+*/
+func NewRiskMgr(c *Ctx, account string) *RiskMgr {
+	cfg := config.GetAccRisk(account)
+	if cfg == nil {
+		return nil
+	}
+	cp := *cfg
+	cfg = &cp
+	if cfg.CorrMin <= 0 {
+		cfg.CorrMin = 0.7
+	}
+	if cfg.CorrTF == "" {
+		cfg.CorrTF = "1h"
+	}
+	if cfg.CorrBack <= 0 {
+		cfg.CorrBack = 100
+	}
+	if cfg.CorrHours <= 0 {
+		cfg.CorrHours = 24
+	}
+	return &RiskMgr{ctx: c, Account: account, Cfg: cfg}
+}
+
+/*
+CheckEnters
+Filter out enters which exceed the daily loss limit or exposure caps
+过滤超出每日亏损限制或敞口上限的开单请求
+*/
+func (r *RiskMgr) CheckEnters(symbol string, enters []*strat.EnterReq) []*strat.EnterReq {
+	equity := r.ctx.AccTotalLegal(r.Account, true)
+	if equity <= 0 || len(enters) == 0 {
+		return enters
+	}
+	curMS := r.ctx.TimeMS()
+	if !r.checkDailyLoss(curMS, equity) {
+		r.ctx.AddAccFailOpens(r.Account, strat.FailOpenDailyLoss, len(enters))
+		return nil
+	}
+	cfg := r.Cfg
+	if cfg.MaxGross <= 0 && cfg.MaxNet <= 0 && cfg.MaxCurrency <= 0 && cfg.MaxCluster <= 0 {
+		return enters
+	}
+	exp := r.getExposure()
+	var cluster []string
+	if cfg.MaxCluster > 0 {
+		cluster = r.correlated(curMS, symbol, exp.pairs)
+	}
+	price := r.ctx.GetPrice(symbol)
+	base, quote, _, _ := core.SplitSymbol(symbol)
+	res := make([]*strat.EnterReq, 0, len(enters))
+	for _, req := range enters {
+		cost := req.LegalCost
+		if cost == 0 {
+			cost = req.Amount * price
+		}
+		chg := cost
+		if req.Short {
+			chg = -cost
+		}
+		tag := ""
+		if cfg.MaxGross > 0 && exp.gross+cost > cfg.MaxGross*equity {
+			tag = strat.FailOpenGrossLimit
+		} else if cfg.MaxNet > 0 && overCap(exp.net, chg, cfg.MaxNet*equity) {
+			tag = strat.FailOpenNetLimit
+		} else if cfg.MaxCurrency > 0 && (r.currencyOver(exp, base, chg, equity) || r.currencyOver(exp, quote, -chg, equity)) {
+			tag = strat.FailOpenCurrencyLimit
+		} else if cfg.MaxCluster > 0 {
+			var clusterNet float64
+			for _, p := range cluster {
+				clusterNet += exp.pairs[p]
+			}
+			if overCap(clusterNet, chg, cfg.MaxCluster*equity) {
+				tag = strat.FailOpenClusterLimit
+			}
+		}
+		if tag != "" {
+			if core.LiveMode {
+				log.Warn("enter rejected by risk", zap.String("acc", r.Account), zap.String("pair", symbol),
+					zap.String("strat", req.StratName), zap.String("tag", tag), zap.Float64("cost", cost))
+			}
+			r.ctx.AddAccFailOpen(r.Account, tag)
+			continue
+		}
+		exp.add(symbol, base, quote, chg)
+		res = append(res, req)
+	}
+	return res
+}
+
+// overCap whether abs(val+chg) exceeds limit and chg increases the exposure 变化后绝对值超出上限且敞口增大
+func overCap(val, chg, limit float64) bool {
+	after := math.Abs(val + chg)
+	return after > limit && after > math.Abs(val)
+}
+
+func (r *RiskMgr) currencyOver(exp *riskExposure, code string, chg, equity float64) bool {
+	if _, ok := config.StakeCurrencyMap[code]; ok {
+		return false
+	}
+	return overCap(exp.currency[code], chg, r.Cfg.MaxCurrency*equity)
+}
+
+/*
+RollDay
+Sample the equity as the baseline of daily loss when a new UTC day begins. Called on each bar before orders are
+updated, so losses of exits later in the day are measured from the day start.
+新的UTC日开始时采样权益作为每日亏损的基准。每个bar更新订单前调用，使当日后续平仓的亏损从当日开始计算。
+*/
+func (r *RiskMgr) RollDay(curMS int64) {
+	if r.Cfg.DailyLoss <= 0 {
+		return
+	}
+	dayStart := utils2.AlignTfMSecs(curMS, dayMSecs)
+	if dayStart == r.dayStart {
+		return
+	}
+	equity := r.ctx.AccTotalLegal(r.Account, true)
+	if equity > 0 {
+		r.dayStart = dayStart
+		r.dayEquity = equity
+	}
+}
+
+/*
+checkDailyLoss
+Returns false and forbid entering until next UTC day if equity dropped by DailyLoss from the day start.
+The day start equity is sampled by RollDay, or here if no bar arrived in this day yet.
+权益较当日开始下跌DailyLoss时返回false，并禁止开单直到UTC次日。当日开始权益由RollDay采样，当日尚无bar时在此采样。
+*/
+func (r *RiskMgr) checkDailyLoss(curMS int64, equity float64) bool {
+	if r.Cfg.DailyLoss <= 0 {
+		return true
+	}
+	dayStart := utils2.AlignTfMSecs(curMS, dayMSecs)
+	if dayStart != r.dayStart {
+		r.dayStart = dayStart
+		r.dayEquity = equity
+		return true
+	}
+	lossRate := 1 - equity/r.dayEquity
+	if lossRate < r.Cfg.DailyLoss {
+		return true
+	}
+	r.ctx.NoEnterUntil[r.Account] = dayStart + dayMSecs
+	log.Warn(fmt.Sprintf("%v: daily loss %.1f%% >= %.1f%%, forbid enter until next day", r.Account,
+		lossRate*100, r.Cfg.DailyLoss*100))
+	return false
+}
+
+func (r *RiskMgr) getExposure() *riskExposure {
+	res := &riskExposure{currency: make(map[string]float64), pairs: make(map[string]float64)}
+	openOds, lock := r.ctx.GetOpenODs(r.Account)
+	lock.Lock()
+	defer lock.Unlock()
+	for _, od := range openOds {
+		var cost float64
+		if amt := od.HoldAmount(); amt > 0 {
+			price := r.ctx.GetPrice(od.Symbol)
+			if price == 0 {
+				price = od.InitPrice
+			}
+			cost = amt * price
+		} else if od.Status < ormo.InOutStatusPartEnter {
+			// pending entry 等待入场
+			cost = od.GetInfoFloat64(ormo.OdInfoLegalCost)
+			if cost == 0 {
+				cost = od.Enter.Amount * od.InitPrice
+			}
+		}
+		if od.Short {
+			cost = -cost
+		}
+		base, quote, _, _ := core.SplitSymbol(od.Symbol)
+		res.add(od.Symbol, base, quote, cost)
+	}
+	return res
+}
+
+func (e *riskExposure) add(symbol, base, quote string, chg float64) {
+	e.gross += math.Abs(chg)
+	e.net += chg
+	e.currency[base] += chg
+	e.currency[quote] -= chg
+	e.pairs[symbol] += chg
+}
+
+/*
+correlated
+Pairs whose correlation with symbol >= CorrMin, including symbol itself. The correlation matrix of traded pairs is
+refreshed every CorrHours by utils.CalcCorrMat.
+与symbol相关系数不低于CorrMin的标的，包含symbol自身。交易标的的相关矩阵每CorrHours通过utils.CalcCorrMat刷新。
+*/
+func (r *RiskMgr) correlated(curMS int64, symbol string, holds map[string]float64) []string {
+	if curMS-r.corrAt >= int64(r.Cfg.CorrHours)*3600000 || !r.corrTried[symbol] {
+		r.calcCorr(curMS, symbol, holds)
+	}
+	res := []string{symbol}
+	idx, ok := r.corrIdx[symbol]
+	if !ok || r.corrMat == nil {
+		return res
+	}
+	for pair, i := range r.corrIdx {
+		if pair != symbol && r.corrMat.At(idx, i) >= r.Cfg.CorrMin {
+			res = append(res, pair)
+		}
+	}
+	return res
+}
+
+func (r *RiskMgr) calcCorr(curMS int64, symbol string, holds map[string]float64) {
+	r.corrAt = curMS
+	pairs := slices.Clone(r.ctx.Pairs)
+	for pair := range holds {
+		pairs = append(pairs, pair)
+	}
+	pairs = append(pairs, symbol)
+	pairs, _ = utils.UniqueItems(pairs)
+	r.corrTried = make(map[string]bool)
+	names := make([]string, 0, len(pairs))
+	dataArr := make([][]float64, 0, len(pairs))
+	back := r.Cfg.CorrBack
+	for _, pair := range pairs {
+		r.corrTried[pair] = true
+		exs, err := orm.GetExSymbolCur(pair)
+		if err != nil {
+			continue
+		}
+		_, klines, err := orm.GetOHLCV(exs, r.Cfg.CorrTF, 0, curMS, back, false)
+		if err != nil || len(klines) < back {
+			continue
+		}
+		prices := make([]float64, 0, len(klines))
+		for _, b := range klines {
+			prices = append(prices, b.Close)
+		}
+		if len(prices) > back {
+			prices = prices[len(prices)-back:]
+		}
+		names = append(names, pair)
+		dataArr = append(dataArr, prices)
+	}
+	r.corrIdx = make(map[string]int)
+	r.corrMat = nil
+	if len(names) <= 1 {
+		return
+	}
+	corrMat, _, err_ := utils.CalcCorrMat(back, dataArr, true)
+	if err_ != nil {
+		log.Warn("calc correlation for risk fail", zap.String("acc", r.Account), zap.Error(err_))
+		return
+	}
+	for i, name := range names {
+		r.corrIdx[name] = i
+	}
+	r.corrMat = corrMat
+}
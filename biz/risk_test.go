@@ -0,0 +1,121 @@
+package biz
+
+import (
+	"testing"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/orm/ormo"
+	"github.com/banbox/banbot/strat"
+)
+
+func TestRiskMgr(t *testing.T) {
+	acc := config.DefAcc
+	c := NewRunCtx()
+	c.SetPrices(map[string]float64{"BTC/USDT": 10000, "ETH/USDT": 1000})
+	wallets := c.GetWallets(acc)
+	wallets.Items["USDT"] = &ItemWallet{Coin: "USDT", Available: 1000}
+	openOds, lock := c.GetOpenODs(acc)
+	lock.Lock()
+	openOds[1] = &ormo.InOutOrder{
+		IOrder: &ormo.IOrder{ID: 1, Symbol: "BTC/USDT", Status: ormo.InOutStatusFullEnter, InitPrice: 10000},
+		Enter:  &ormo.ExOrder{Filled: 0.02},
+	}
+	lock.Unlock()
+
+	// gross cap 500, 200 held 总敞口上限500，已持有200
+	r := &RiskMgr{ctx: c, Account: acc, Cfg: &config.RiskConfig{MaxGross: 0.5, MaxNet: 0.3}}
+	enters := []*strat.EnterReq{{Tag: "a", LegalCost: 200, Short: true}, {Tag: "b", LegalCost: 200}}
+	res := r.CheckEnters("ETH/USDT", enters)
+	if len(res) != 1 || res[0].Tag != "a" {
+		t.Fatalf("only the short order should pass gross cap, got %v", len(res))
+	}
+	// passed enters are not held yet, net is 200 of the BTC long, long 200 makes it exceed net cap 300
+	// 已通过的开单尚未持有，净敞口为BTC多单的200，再开多200超出300上限
+	failNum := c.GetAccFailOpens()[acc][strat.FailOpenNetLimit]
+	res = r.CheckEnters("ETH/USDT", []*strat.EnterReq{{Tag: "c", LegalCost: 200}})
+	if len(res) != 0 || c.GetAccFailOpens()[acc][strat.FailOpenNetLimit] != failNum+1 {
+		t.Errorf("long 200 should be rejected by net cap")
+	}
+	// reducing exposure is allowed even when over cap 超出上限时允许减少敞口
+	r.Cfg = &config.RiskConfig{MaxCurrency: 0.1}
+	res = r.CheckEnters("BTC/USDT", []*strat.EnterReq{{Tag: "d", LegalCost: 100, Short: true}})
+	if len(res) != 1 {
+		t.Errorf("short BTC should reduce currency exposure")
+	}
+	res = r.CheckEnters("BTC/USDT", []*strat.EnterReq{{Tag: "e", LegalCost: 100}})
+	if len(res) != 0 {
+		t.Errorf("long BTC should exceed currency cap")
+	}
+
+	oldBT := core.BackTestMode
+	defer func() {
+		core.BackTestMode = oldBT
+	}()
+	core.BackTestMode = true
+	dayStart := int64(1700006400000)
+	r.Cfg = &config.RiskConfig{DailyLoss: 0.05}
+	// baseline is sampled when the day rolls over 基准在跨日时采样
+	r.RollDay(dayStart + 60000)
+	if r.dayStart != dayStart || r.dayEquity != 1000 {
+		t.Fatalf("day start equity should be 1000, got %v", r.dayEquity)
+	}
+	// an exit loses 30 before the first enter of the day 当日首次开单前平仓亏损30
+	wallets.Items["USDT"].Available = 970
+	r.RollDay(dayStart + 3600000)
+	c.CurTimeMS = dayStart + 7200000
+	if res = r.CheckEnters("ETH/USDT", []*strat.EnterReq{{Tag: "f", LegalCost: 100}}); len(res) != 1 {
+		t.Fatalf("loss of 3%% should pass")
+	}
+	if r.dayEquity != 1000 {
+		t.Errorf("baseline should keep the day start equity, got %v", r.dayEquity)
+	}
+	// 6% from the day start, but only 3.1% from the first enter 较当日开始亏损6%，较首次开单仅3.1%
+	wallets.Items["USDT"].Available = 940
+	if res = r.CheckEnters("ETH/USDT", []*strat.EnterReq{{Tag: "g", LegalCost: 100}}); len(res) != 0 {
+		t.Errorf("enter should be rejected after daily loss")
+	}
+	if c.NoEnterUntil[acc] != dayStart+dayMSecs {
+		t.Errorf("should forbid enter until next day, got %v", c.NoEnterUntil[acc])
+	}
+	// next day takes a new baseline 次日重新采样基准
+	r.RollDay(dayStart + dayMSecs)
+	if r.dayStart != dayStart+dayMSecs || r.dayEquity != 940 {
+		t.Errorf("next day baseline should be 940, got %v", r.dayEquity)
+	}
+}
+
+func TestApplyFatalStop(t *testing.T) {
+	acc := config.DefAcc
+	c := NewRunCtx()
+	c.SetPrices(map[string]float64{"USDT": 1})
+	wallets := c.GetWallets(acc)
+	wallets.Items["USDT"] = &ItemWallet{Coin: "USDT", Available: 900}
+	oldStop, oldHours := config.FatalStop, config.FatalStopHours
+	config.FatalStop = map[int]float64{60: 0.05, 1440: 0.2}
+	config.FatalStopHours = 8
+	defer func() {
+		config.FatalStop, config.FatalStopHours = oldStop, oldHours
+	}()
+	curMS := c.TimeMS()
+	orders := []*ormo.InOutOrder{
+		{IOrder: &ormo.IOrder{ID: 1, ExitAt: curMS - 7200000, Profit: -200}},
+		{IOrder: &ormo.IOrder{ID: 2, ExitAt: curMS - 600000, Profit: 20}},
+	}
+	// hour: +20; day: 180/(180+900) < 0.2 一小时盈利，一天亏损未达阈值
+	if mins, _ := c.ApplyFatalStop(acc, orders, 0); mins != 0 {
+		t.Fatalf("should not trigger, got %v", mins)
+	}
+	// hour: 60/(60+900) >= 0.05 一小时亏损达到阈值
+	orders = append(orders, &ormo.InOutOrder{IOrder: &ormo.IOrder{ID: 3, ExitAt: curMS - 300000, Profit: -80}})
+	mins, lossRate := c.ApplyFatalStop(acc, orders, 0)
+	if mins != 60 || lossRate < 0.06 || lossRate > 0.07 {
+		t.Fatalf("should trigger 60 mins window, got %v %v", mins, lossRate)
+	}
+	if c.NoEnterUntil[acc] < curMS+8*3600000 {
+		t.Errorf("should forbid enter for 8 hours, got %v", c.NoEnterUntil[acc])
+	}
+	if mins, _ = c.ApplyFatalStop(acc, orders, 0); mins != 0 {
+		t.Errorf("should skip when entering is forbidden")
+	}
+}
@@ -0,0 +1,160 @@
+package biz
+
+import (
+	"math"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/orm"
+	"github.com/banbox/banexg"
+)
+
+/*
+SlippageModel
+Calculate slippage for simulated market fills in backtest.
+计算回测中模拟市价成交的滑点。
+*/
+type SlippageModel interface {
+	Name() string
+	// OnBar is called for every bar of traded symbols, models can track volumes here 每个交易品种的bar都会调用，可在此跟踪成交量
+	OnBar(bar *orm.InfoKline)
+	// Slip returns the non-negative slippage rate for a market order of amount at price 返回以price成交amount数量的市价单的非负滑点比率
+	Slip(symbol string, isBuy bool, price, amount float64) float64
+}
+
+/*
+NewSlippageModel
+Create the slippage model from config, returns nil if disabled
+根据配置创建滑点模型，未启用时返回nil
+*/
+func NewSlippageModel(c *core.BotCtx, cfg *config.SlippageConfig) SlippageModel {
+	if cfg == nil {
+		return nil
+	}
+	switch cfg.Model {
+	case core.SlipFixed:
+		return &FixedSlippage{Bps: cfg.Bps}
+	case core.SlipSpread:
+		return &SpreadSlippage{Bps: cfg.Bps, ctx: c}
+	case core.SlipSqrt:
+		impact, volBars := cfg.Impact, cfg.VolBars
+		if impact <= 0 {
+			impact = 0.1
+		}
+		if volBars <= 0 {
+			volBars = 20
+		}
+		return &SqrtSlippage{Impact: impact, VolBars: volBars, avgVols: make(map[string]float64)}
+	default:
+		return nil
+	}
+}
+
+// FixedSlippage A fixed slippage in bps 固定基点的滑点
+type FixedSlippage struct {
+	Bps float64
+}
+
+func (s *FixedSlippage) Name() string {
+	return core.SlipFixed
+}
+
+func (s *FixedSlippage) OnBar(_ *orm.InfoKline) {}
+
+func (s *FixedSlippage) Slip(_ string, _ bool, _, _ float64) float64 {
+	return s.Bps / 10000
+}
+
+/*
+SpreadSlippage
+Walk the stored order book snapshot from mid price. Bps is used when no snapshot is available.
+从中间价开始遍历已存储的订单簿快照计算滑点。无快照时使用Bps
+*/
+type SpreadSlippage struct {
+	Bps float64
+	ctx *core.BotCtx // context holding the order books 持有订单簿的上下文
+}
+
+func (s *SpreadSlippage) Name() string {
+	return core.SlipSpread
+}
+
+func (s *SpreadSlippage) OnBar(_ *orm.InfoKline) {}
+
+func (s *SpreadSlippage) Slip(symbol string, isBuy bool, _, amount float64) float64 {
+	book, _ := s.ctx.OdBooks[symbol]
+	if book == nil || book.Asks == nil || book.Bids == nil {
+		return s.Bps / 10000
+	}
+	bestAsk, _ := book.Asks.Level(0)
+	bestBid, _ := book.Bids.Level(0)
+	if bestAsk <= 0 || bestBid <= 0 {
+		return s.Bps / 10000
+	}
+	midPrice := (bestAsk + bestBid) / 2
+	side := book.Bids
+	if isBuy {
+		side = book.Asks
+	}
+	avgPrice, _, _ := side.AvgPrice(amount)
+	if avgPrice <= 0 {
+		return s.Bps / 10000
+	}
+	return math.Abs(avgPrice-midPrice) / midPrice
+}
+
+/*
+SqrtSlippage
+Square-root market impact: Impact * sqrt(amount / average bar volume)
+平方根市场冲击：Impact * sqrt(数量 / 平均bar成交量)
+*/
+type SqrtSlippage struct {
+	Impact  float64
+	VolBars int
+	avgVols map[string]float64 // EMA of bar volume for each symbol 每个品种bar成交量的EMA
+}
+
+func (s *SqrtSlippage) Name() string {
+	return core.SlipSqrt
+}
+
+func (s *SqrtSlippage) OnBar(bar *orm.InfoKline) {
+	avgVol, ok := s.avgVols[bar.Symbol]
+	if !ok {
+		s.avgVols[bar.Symbol] = bar.Volume
+		return
+	}
+	alpha := 2 / float64(s.VolBars+1)
+	s.avgVols[bar.Symbol] = avgVol*(1-alpha) + bar.Volume*alpha
+}
+
+func (s *SqrtSlippage) Slip(symbol string, _ bool, _, amount float64) float64 {
+	avgVol, _ := s.avgVols[symbol]
+	if avgVol <= 0 || amount <= 0 {
+		return 0
+	}
+	// Limit the max slippage to avoid negative prices
+	// 限制最大滑点，避免出现负价格
+	return min(0.5, s.Impact*math.Sqrt(amount/avgVol))
+}
+
+/*
+slipPrice
+Returns the price after slippage and the slippage cost in quote
+返回滑点后的价格，以及以报价币计的滑点成本
+*/
+func slipPrice(model SlippageModel, symbol string, side string, price, amount float64) (float64, float64) {
+	if model == nil || price <= 0 || amount <= 0 {
+		return price, 0
+	}
+	isBuy := side == banexg.OdSideBuy
+	rate := model.Slip(symbol, isBuy, price, amount)
+	if rate <= 0 {
+		return price, 0
+	}
+	newPrice := price * (1 - rate)
+	if isBuy {
+		newPrice = price * (1 + rate)
+	}
+	return newPrice, math.Abs(newPrice-price) * amount
+}
@@ -0,0 +1,39 @@
+package biz
+
+import (
+	"math"
+	"testing"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/orm"
+	"github.com/banbox/banexg"
+)
+
+func TestSlippageModels(t *testing.T) {
+	fixed := NewSlippageModel(core.DefCtx, &config.SlippageConfig{Model: core.SlipFixed, Bps: 10})
+	price, cost := slipPrice(fixed, "BTC/USDT", banexg.OdSideBuy, 100, 2)
+	if math.Abs(price-100.1) > 1e-9 || math.Abs(cost-0.2) > 1e-9 {
+		t.Errorf("fixed buy: price %v cost %v", price, cost)
+	}
+	price, _ = slipPrice(fixed, "BTC/USDT", banexg.OdSideSell, 100, 2)
+	if math.Abs(price-99.9) > 1e-9 {
+		t.Errorf("fixed sell: price %v", price)
+	}
+
+	sqrtM := NewSlippageModel(core.DefCtx, &config.SlippageConfig{Model: core.SlipSqrt, Impact: 0.1, VolBars: 3})
+	if rate := sqrtM.Slip("ETH/USDT", true, 100, 1); rate != 0 {
+		t.Errorf("sqrt without volume should be 0, got %v", rate)
+	}
+	for i := 0; i < 5; i++ {
+		sqrtM.OnBar(&orm.InfoKline{PairTFKline: &banexg.PairTFKline{Symbol: "ETH/USDT",
+			Kline: banexg.Kline{Volume: 100}}})
+	}
+	if rate := sqrtM.Slip("ETH/USDT", true, 100, 25); math.Abs(rate-0.05) > 1e-9 {
+		t.Errorf("sqrt rate should be 0.05, got %v", rate)
+	}
+
+	if NewSlippageModel(core.DefCtx, &config.SlippageConfig{}) != nil {
+		t.Error("empty model should be disabled")
+	}
+}
@@ -1,7 +1,6 @@
 package biz
 
 import (
-	"github.com/banbox/banbot/btime"
 	"github.com/banbox/banbot/config"
 	"github.com/banbox/banbot/core"
 	"github.com/banbox/banbot/exg"
@@ -57,7 +56,8 @@ func TestStratRun(t *testing.T) {
 	if stagy == nil {
 		panic("load strategy fail")
 	}
-	accJobs := strat.GetJobs(config.DefAcc)
+	c := DefCtx
+	accJobs := c.GetJobs(config.DefAcc)
 	for _, symbol := range pairs {
 		exs, err := orm.GetExSymbolCur(symbol)
 		if err != nil {
@@ -73,7 +73,7 @@ func TestStratRun(t *testing.T) {
 			MaxCache:   core.NumTaCache,
 			Data:       map[string]interface{}{"sid": int64(exs.ID)},
 		}
-		strat.Envs[envKey] = env
+		c.Envs[envKey] = env
 		job := &strat.StratJob{
 			Strat:         stagy,
 			Env:           env,
@@ -92,7 +92,7 @@ func TestStratRun(t *testing.T) {
 		}
 		jobs[job.Strat.Name] = job
 	}
-	curTime := utils2.AlignTfMSecs(btime.TimeMS(), tfMSecs) - tfMSecs*int64(barNum)
+	curTime := utils2.AlignTfMSecs(c.TimeMS(), tfMSecs) - tfMSecs*int64(barNum)
 	norBar := banexg.Kline{Time: curTime, Open: 0.1, High: 0.1, Low: 0.1, Close: 0.1, Volume: 0.1}
 	for i := 0; i < barNum; i++ {
 		curTime += tfMSecs
@@ -108,9 +108,9 @@ func TestStratRun(t *testing.T) {
 		for _, pair := range pairs {
 			bar.Symbol = pair
 			envKey := strings.Join([]string{pair, tf}, "_")
-			env, _ := strat.Envs[envKey]
+			env, _ := c.Envs[envKey]
 			env.OnBar(bar.Time, bar.Open, bar.High, bar.Low, bar.Close, bar.Volume, 0)
-			core.SetBarPrice(pair, bar.Close)
+			c.SetBarPrice(pair, bar.Close)
 			jobs, _ := accJobs[envKey]
 			for _, job := range jobs {
 				job.InitBar(nil)
@@ -23,6 +23,7 @@ import (
 	"github.com/banbox/banbot/exg"
 	"github.com/banbox/banbot/goods"
 	"github.com/banbox/banbot/orm"
+	"github.com/banbox/banbot/strat"
 	"github.com/banbox/banbot/utils"
 	"github.com/banbox/banexg"
 	"github.com/banbox/banexg/errs"
@@ -384,6 +385,84 @@ func LoadCalendars(args *config.CmdArgs) *errs.Error {
 	return nil
 }
 
+/*
+LoadFundingRates
+Load funding rate history of perpetual contracts from csv. Each row: symbol,time,rate
+time can be 10/13-digit timestamp or date time string.
+从csv加载永续合约资金费率历史。每行格式：symbol,time,rate，时间可以是10/13位时间戳或日期时间字符串
+*/
+func LoadFundingRates(args *config.CmdArgs) *errs.Error {
+	err := SetupComs(args)
+	if err != nil {
+		return err
+	}
+	if args.InPath == "" {
+		return errs.NewMsg(errs.CodeParamRequired, "--in is required")
+	}
+	if !core.IsContract {
+		return errs.NewMsg(errs.CodeParamInvalid, "funding rates only valid for contract market")
+	}
+	rows, err := utils.ReadCSV(args.InPath)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	sess, conn, err := orm.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	symRates := make(map[string][]*orm.FundingRate)
+	symList := make([]string, 0, 8)
+	for i, row := range rows {
+		if len(row) < 3 {
+			continue
+		}
+		timeMS, err_ := btime.ParseTimeMS(row[1])
+		if err_ != nil {
+			if i == 0 {
+				// skip header
+				continue
+			}
+			return errs.New(errs.CodeRunTime, err_)
+		}
+		rate, err_ := strconv.ParseFloat(strings.TrimSpace(row[2]), 64)
+		if err_ != nil {
+			return errs.New(errs.CodeRunTime, err_)
+		}
+		symbol := strings.TrimSpace(row[0])
+		arr, ok := symRates[symbol]
+		if !ok {
+			symList = append(symList, symbol)
+		}
+		symRates[symbol] = append(arr, &orm.FundingRate{Time: timeMS, Rate: rate})
+	}
+	err = orm.EnsureCurSymbols(symList)
+	if err != nil {
+		return err
+	}
+	total := 0
+	for _, symbol := range symList {
+		exs, err := orm.GetExSymbolCur(symbol)
+		if err != nil {
+			log.Error("skip unknown symbol", zap.String("symbol", symbol), zap.Error(err))
+			continue
+		}
+		arr := symRates[symbol]
+		sort.Slice(arr, func(i, j int) bool {
+			return arr[i].Time < arr[j].Time
+		})
+		err = sess.SetFundingRates(exs.ID, arr)
+		if err != nil {
+			log.Error("save funding rates fail", zap.String("symbol", symbol), zap.Error(err))
+			continue
+		}
+		total += len(arr)
+	}
+	log.Info("load funding rates success", zap.Int("symbols", len(symList)), zap.Int("num", total))
+	return nil
+}
+
 var adjMap = map[string]int{
 	"pre":  core.AdjFront,
 	"post": core.AdjBehind,
@@ -582,9 +661,9 @@ func ExportAdjFactors(args *config.CmdArgs) *errs.Error {
 		if err != nil {
 			return err
 		}
-		facs, err_ := sess.GetAdjFactors(ctx, exs.ID)
-		if err_ != nil {
-			return orm.NewDbErr(core.ErrDbReadFail, err_)
+		facs, err := sess.GetSidAdjFactors(exs.ID)
+		if err != nil {
+			return err
 		}
 		sort.Slice(facs, func(i, j int) bool {
 			return facs[i].StartMs < facs[j].StartMs
@@ -635,7 +714,7 @@ func CalcCorrelation(args *config.CmdArgs) *errs.Error {
 	if args.OutPath == "" {
 		return errs.NewMsg(errs.CodeParamRequired, "--out is required")
 	}
-	pairs, err := goods.RefreshPairList(false)
+	pairs, err := goods.RefreshPairList(core.DefCtx, false)
 	if err != nil {
 		return err
 	}
@@ -844,7 +923,7 @@ func RunHistKline(args *RunHistArgs) *errs.Error {
 			})
 		}
 		futures[exs.Symbol] = tfList
-		feeder, err := data.NewDBKlineFeeder(exs, onItemBar, true)
+		feeder, err := data.NewDBKlineFeeder(strat.DefCtx, exs, onItemBar, true)
 		if err != nil {
 			return err
 		}
@@ -882,7 +961,7 @@ func RunHistKline(args *RunHistArgs) *errs.Error {
 	makeFeeders := func() []data.IHistKlineFeeder {
 		return holds
 	}
-	err := data.RunHistFeeders(makeFeeders, args.VerCh, nil)
+	err := data.RunHistFeeders(strat.DefCtx, makeFeeders, args.VerCh, nil)
 	if args.OnEnvEnd != nil {
 		args.OnEnvEnd(nil, nil)
 	}
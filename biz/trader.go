@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/banbox/banbot/btime"
 	"github.com/banbox/banbot/config"
 	"github.com/banbox/banbot/core"
 	"github.com/banbox/banbot/orm"
@@ -21,11 +20,16 @@ import (
 )
 
 type Trader struct {
+	ctx *Ctx
+}
+
+func NewTrader(c *Ctx) *Trader {
+	return &Trader{ctx: c}
 }
 
 func (t *Trader) OnEnvJobs(bar *orm.InfoKline) (*ta.BarEnv, *errs.Error) {
 	envKey := strings.Join([]string{bar.Symbol, bar.TimeFrame}, "_")
-	env, ok := strat.Envs[envKey]
+	env, ok := t.ctx.Envs[envKey]
 	if !ok {
 		return nil, errs.NewMsg(core.ErrBadConfig, "env for %s/%s not found", bar.Symbol, bar.TimeFrame)
 	}
@@ -42,10 +46,10 @@ func (t *Trader) OnEnvJobs(bar *orm.InfoKline) (*ta.BarEnv, *errs.Error) {
 
 func (t *Trader) FeedKline(bar *orm.InfoKline) *errs.Error {
 	tfSecs := utils2.TFToSecs(bar.TimeFrame)
-	core.SetBarPrice(bar.Symbol, bar.Close)
+	t.ctx.SetBarPrice(bar.Symbol, bar.Close)
 	// If it exceeds 1 minute and half of the period, the bar is considered delayed and orders cannot be placed.
 	// 超过1分钟且周期的一半，认为bar延迟，不可下单
-	delaySecs := int((btime.TimeMS()-bar.Time)/1000) - tfSecs
+	delaySecs := int((t.ctx.TimeMS()-bar.Time)/1000) - tfSecs
 	barExpired := delaySecs >= max(60, tfSecs/2)
 	if barExpired && core.LiveMode && !bar.IsWarmUp {
 		log.Warn(fmt.Sprintf("%s/%s delay %v s, open order is disabled", bar.Symbol, bar.TimeFrame, delaySecs))
@@ -75,20 +79,20 @@ func (t *Trader) FeedKline(bar *orm.InfoKline) *errs.Error {
 func (t *Trader) onAccountKline(account string, env *ta.BarEnv, bar *orm.InfoKline, barExpired bool) *errs.Error {
 	envKey := strings.Join([]string{bar.Symbol, bar.TimeFrame}, "_")
 	// Get strategy jobs 获取交易任务
-	jobs, _ := strat.GetJobs(account)[envKey]
+	jobs, _ := t.ctx.GetJobs(account)[envKey]
 	// jobs which subscript info timeframes  辅助订阅的任务
-	infoJobs, _ := strat.GetInfoJobs(account)[envKey]
+	infoJobs, _ := t.ctx.GetInfoJobs(account)[envKey]
 	if len(jobs) == 0 && len(infoJobs) == 0 {
 		return nil
 	}
-	openOds, lock := ormo.GetOpenODs(account)
+	openOds, lock := t.ctx.GetOpenODs(account)
 	// Update orders in non-production mode 更新非生产模式的订单
 	lock.Lock()
 	allOrders := utils.ValsOfMap(openOds)
 	lock.Unlock()
-	odMgr := GetOdMgr(account)
+	odMgr := t.ctx.GetOdMgr(account)
 	var err *errs.Error
-	if !bar.IsWarmUp && len(allOrders) > 0 {
+	if !bar.IsWarmUp && (len(allOrders) > 0 || !core.EnvReal) {
 		// The order status may be modified here
 		// 这里可能修改订单状态
 		err = odMgr.UpdateByBar(allOrders, bar)
@@ -116,7 +120,7 @@ func (t *Trader) onAccountKline(account string, env *ta.BarEnv, bar *orm.InfoKli
 		if !barExpired {
 			isBatch = job.Strat.BatchInOut && job.Strat.OnBatchJobs != nil
 			if isBatch {
-				AddBatchJob(account, bar.TimeFrame, job, false)
+				t.ctx.AddBatchJob(account, bar.TimeFrame, job, false)
 			} else {
 				enters = append(enters, job.Entrys...)
 			}
@@ -139,7 +143,7 @@ func (t *Trader) onAccountKline(account string, env *ta.BarEnv, bar *orm.InfoKli
 		job.IsWarmUp = bar.IsWarmUp
 		job.Strat.OnInfoBar(job, env, bar.Symbol, bar.TimeFrame)
 		if job.Strat.BatchInfo && job.Strat.OnBatchInfos != nil {
-			AddBatchJob(account, bar.TimeFrame, job, true)
+			t.ctx.AddBatchJob(account, bar.TimeFrame, job, true)
 		}
 	}
 	// 处理订单
@@ -149,6 +153,75 @@ func (t *Trader) onAccountKline(account string, env *ta.BarEnv, bar *orm.InfoKli
 	return t.ExecOrders(odMgr, jobs, env, enters, exits, edits)
 }
 
+/*
+FeedTrades
+Feed the trades of pair in tick backtest: fill orders by trades, then invoke OnTrades of strategies
+逐笔回测中推送pair的成交记录：先用成交撮合订单，再调用策略的OnTrades
+*/
+func (t *Trader) FeedTrades(pair string, trades []*banexg.Trade) *errs.Error {
+	if len(trades) == 0 {
+		return nil
+	}
+	t.ctx.SetBarPrice(pair, trades[len(trades)-1].Price)
+	var err *errs.Error
+	for account := range config.Accounts {
+		curErr := t.onAccountTrades(account, pair, trades)
+		if curErr != nil {
+			if err != nil {
+				log.Error("onAccountTrades fail", zap.String("account", account), zap.Error(curErr))
+			} else {
+				err = curErr
+			}
+		}
+	}
+	return err
+}
+
+func (t *Trader) onAccountTrades(account, pair string, trades []*banexg.Trade) *errs.Error {
+	openOds, lock := t.ctx.GetOpenODs(account)
+	lock.Lock()
+	allOrders := utils.ValsOfMap(openOds)
+	lock.Unlock()
+	odMgr := t.ctx.GetOdMgr(account)
+	err := odMgr.UpdateByTrades(allOrders, pair, trades)
+	if err != nil {
+		return err
+	}
+	prefix := pair + "_"
+	for envKey, jobs := range t.ctx.GetJobs(account) {
+		if !strings.HasPrefix(envKey, prefix) {
+			continue
+		}
+		env, _ := t.ctx.Envs[envKey]
+		if env == nil {
+			continue
+		}
+		timeFrame := envKey[len(prefix):]
+		var curOrders []*ormo.InOutOrder
+		for _, od := range allOrders {
+			if od.Status < ormo.InOutStatusFullExit && od.Symbol == pair && od.Timeframe == timeFrame {
+				curOrders = append(curOrders, od)
+			}
+		}
+		var enters []*strat.EnterReq
+		var exits []*strat.ExitReq
+		for _, job := range jobs {
+			if job.Strat.OnTrades == nil {
+				continue
+			}
+			job.InitBar(curOrders)
+			job.Strat.OnTrades(job, trades)
+			enters = append(enters, job.Entrys...)
+			exits = append(exits, job.Exits...)
+		}
+		err = t.ExecOrders(odMgr, jobs, env, enters, exits, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (t *Trader) ExecOrders(odMgr IOrderMgr, jobs map[string]*strat.StratJob, env *ta.BarEnv,
 	enters []*strat.EnterReq, exits []*strat.ExitReq, edits []*ormo.InOutEdit) *errs.Error {
 	if len(enters)+len(exits)+len(edits) == 0 {
@@ -198,7 +271,7 @@ func (t *Trader) ExecOrders(odMgr IOrderMgr, jobs map[string]*strat.StratJob, en
 }
 
 func (t *Trader) OnEnvEnd(bar *banexg.PairTFKline, adj *orm.AdjInfo) {
-	mgrs := GetAllOdMgr()
+	mgrs := t.ctx.GetAllOdMgr()
 	for acc, mgr := range mgrs {
 		err := mgr.OnEnvEnd(bar, adj)
 		if err != nil {
@@ -206,7 +279,7 @@ func (t *Trader) OnEnvEnd(bar *banexg.PairTFKline, adj *orm.AdjInfo) {
 		}
 	}
 	envKey := strings.Join([]string{bar.Symbol, bar.TimeFrame}, "_")
-	env, ok := strat.Envs[envKey]
+	env, ok := t.ctx.Envs[envKey]
 	if ok {
 		env.Reset()
 	}
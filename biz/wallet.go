@@ -19,10 +19,6 @@ import (
 	"go.uber.org/zap"
 )
 
-var (
-	accWallets = make(map[string]*BanWallets)
-)
-
 type ItemWallet struct {
 	Coin          string             // Coin code, not pair 币代码，非交易对
 	Available     float64            // Available balance 可用余额
@@ -35,9 +31,12 @@ type ItemWallet struct {
 }
 
 type BanWallets struct {
-	Items   map[string]*ItemWallet
-	Account string
-	IsWatch bool
+	ctx      *Ctx
+	Items    map[string]*ItemWallet
+	Account  string
+	Exchange string // empty for the default exchange 默认交易所时为空
+	Market   string // empty for the default market 默认市场时为空
+	IsWatch  bool
 }
 
 /*
@@ -45,7 +44,7 @@ InitFakeWallets
 Initialize a wallet object from a configuration file
 从配置文件初始化一个钱包对象
 */
-func InitFakeWallets(symbols ...string) {
+func (c *Ctx) InitFakeWallets(symbols ...string) {
 	updates := make(map[string]float64)
 	if len(symbols) == 0 {
 		updates = config.WalletAmounts
@@ -57,26 +56,108 @@ func InitFakeWallets(symbols ...string) {
 			}
 		}
 	}
-	wallets := GetWallets(config.DefAcc)
+	wallets := c.GetWallets(config.DefAcc)
 	wallets.SetWallets(updates)
 	wallets.TryUpdateStakePctAmt()
 }
 
-func GetWallets(account string) *BanWallets {
+func (c *Ctx) GetWallets(account string) *BanWallets {
 	if !core.EnvReal {
 		account = config.DefAcc
 	}
-	val, ok := accWallets[account]
+	val, ok := c.accWallets[account]
 	if !ok {
 		val = &BanWallets{
+			ctx:     c,
 			Items:   map[string]*ItemWallet{},
 			Account: account,
 		}
-		accWallets[account] = val
+		c.accWallets[account] = val
+	}
+	return val
+}
+
+/*
+GetVenueWallets
+Return the wallets of account on exchange/market. Each exchange/market has its own balances in live trading,
+the default exchange/market is same as GetWallets. Backtest and dry run share the wallets of GetWallets.
+返回账户在交易所/市场上的钱包。实盘时每个交易所/市场有独立的余额，默认交易所/市场和GetWallets相同。
+回测和模拟交易共用GetWallets的钱包
+*/
+func (c *Ctx) GetVenueWallets(account, exgName, market string) *BanWallets {
+	if !core.EnvReal || exgName == core.ExgName && market == core.Market {
+		return c.GetWallets(account)
+	}
+	key := core.PairKey(exgName, market, account)
+	val, ok := c.accWallets[key]
+	if !ok {
+		val = &BanWallets{
+			ctx:      c,
+			Items:    map[string]*ItemWallet{},
+			Account:  account,
+			Exchange: exgName,
+			Market:   market,
+		}
+		c.accWallets[key] = val
 	}
 	return val
 }
 
+// GetPairWallets return the wallets of account for the pair key 返回账户中品种键对应的钱包
+func (c *Ctx) GetPairWallets(account, pair string) *BanWallets {
+	exgName, market, _ := core.SplitPairKey(pair)
+	return c.GetVenueWallets(account, exgName, market)
+}
+
+/*
+GetAccWallets
+Return all wallets of account, the default exchange/market is the first.
+返回账户的所有钱包，默认交易所/市场为第一个
+*/
+func (c *Ctx) GetAccWallets(account string) []*BanWallets {
+	res := []*BanWallets{c.GetWallets(account)}
+	if !core.EnvReal {
+		return res
+	}
+	for _, client := range exg.AllExgs()[1:] {
+		info := client.Info()
+		res = append(res, c.GetVenueWallets(account, info.ID, info.MarketType))
+	}
+	return res
+}
+
+// AccTotalLegal return the total legal value of all wallets of account 返回账户所有钱包的法币总价值
+func (c *Ctx) AccTotalLegal(account string, withUPol bool) float64 {
+	return c.SumAccWallets(account, func(w *BanWallets) float64 {
+		return w.TotalLegal(nil, withUPol)
+	})
+}
+
+// SumAccWallets sum the value of all wallets of account 汇总账户所有钱包的值
+func (c *Ctx) SumAccWallets(account string, getVal func(w *BanWallets) float64) float64 {
+	var total float64
+	for _, w := range c.GetAccWallets(account) {
+		total += getVal(w)
+	}
+	return total
+}
+
+// VenueMarket return the market of wallets 返回钱包的市场
+func (w *BanWallets) VenueMarket() string {
+	if w.Market == "" {
+		return core.Market
+	}
+	return w.Market
+}
+
+// VenueExg return the exchange client of wallets 返回钱包的交易所客户端
+func (w *BanWallets) VenueExg() banexg.BanExchange {
+	if w.Exchange == "" {
+		return exg.Default
+	}
+	return exg.GetPairExg(core.PairKey(w.Exchange, w.Market, ""))
+}
+
 // Total: Available+Pendings+Frozens+[UnrealizedPOL]
 func (iw *ItemWallet) Total(withUpol bool) float64 {
 	sumVal := iw.Used()
@@ -113,8 +194,8 @@ func (iw *ItemWallet) Used() float64 {
 /*
 FiatValue Get the fiat currency value of this wallet 获取此钱包的法币价值
 */
-func (iw *ItemWallet) FiatValue(withUpol bool) float64 {
-	return iw.Total(withUpol) * core.GetPrice(iw.Coin)
+func (iw *ItemWallet) FiatValue(c *core.BotCtx, withUpol bool) float64 {
+	return iw.Total(withUpol) * c.GetPrice(iw.Coin)
 }
 
 /*
@@ -429,6 +510,18 @@ func (w *BanWallets) Cancel(odKey string, symbol string, addAmount float64, from
 		zap.String("od", odKey), zap.String("coin", symbol), zap.Float64("ava", wallet.Available))
 }
 
+/*
+CostFundFee
+Settle a contract funding fee into available. fee>0 means paid, fee<0 means received.
+将合约资金费结算到可用余额。fee>0表示支付，fee<0表示收取
+*/
+func (w *BanWallets) CostFundFee(symbol string, fee float64) {
+	wallet := w.Get(symbol)
+	wallet.lock.Lock()
+	wallet.Available -= fee
+	wallet.lock.Unlock()
+}
+
 /*
 EnterOd
 Both real offer and simulation are executed, which can prevent excessive consumption during real offer.
@@ -449,7 +542,7 @@ func (w *BanWallets) EnterOd(od *ormo.InOutOrder) (float64, *errs.Error) {
 	var legalCost float64
 
 	if od.Enter.Amount != 0 {
-		legalCost = od.Enter.Amount * core.GetPrice(od.Symbol)
+		legalCost = od.Enter.Amount * w.ctx.GetPrice(od.Symbol)
 	} else {
 		legalCost = od.GetInfoFloat64(ormo.OdInfoLegalCost)
 	}
@@ -517,7 +610,7 @@ func (w *BanWallets) ConfirmOdEnter(od *ormo.InOutOrder, enterPrice float64) {
 	curFee := subOd.Fee
 
 	baseCode, quoteCode, _, _ := core.SplitSymbol(exs.Symbol)
-	if core.IsContract {
+	if banexg.IsContract(exs.Market) {
 		// Futures contracts only lock the fixed currency and do not involve the increase of base currency.
 		// 期货合约，只锁定定价币，不涉及base币的增加
 		quoteAmount /= od.Leverage
@@ -592,7 +685,9 @@ func (w *BanWallets) ConfirmOdExit(od *ormo.InOutOrder, exitPrice float64) {
 		//Here profit deducts the entry and exit handling fees. The entry handling fee has been deducted previously, so the entry handling fee needs to be added here.
 		//期货合约不涉及base币的变化。退出订单时，对锁定的定价币平仓释放
 		//这里profit扣除了入场和出场手续费，前面入场手续费已扣过了，所以这里需要加入场手续费
-		w.Cancel(odKey, quoteCode, od.Profit+od.Enter.Fee, false)
+		//Funding fees were settled into the wallet when charged, so they are also added back here.
+		//资金费在结算时已计入钱包，这里也需要加回
+		w.Cancel(odKey, quoteCode, od.Profit+od.Enter.Fee+od.GetInfoFloat64(ormo.OdInfoFundFee), false)
 	} else if od.Short {
 		//For short orders, priority is given to buying from the frozen price of the quote. If it is not converted to base, it will be converted to the available price of the quote.
 		//空单，优先从quote的frozen买，不兑换为base，再换算为quote的avaiable
@@ -668,7 +763,9 @@ func (w *BanWallets) UpdateOds(odList []*ormo.InOutOrder, currency string) *errs
 	// 计算是否爆仓
 	var totProfit float64
 	for _, od := range odList {
-		totProfit += od.Profit
+		// Funding fees are already settled in wallet, exclude them from unrealized profit
+		// 资金费已在钱包结算，从未实现盈亏中排除
+		totProfit += od.Profit + od.GetInfoFloat64(ormo.OdInfoFundFee)
 	}
 	wallet.lock.Lock()
 	wallet.UnrealizedPOL = totProfit
@@ -684,12 +781,13 @@ func (w *BanWallets) UpdateOds(odList []*ormo.InOutOrder, currency string) *errs
 		}
 	}
 
-	exchange := exg.Default
 	for _, od := range odList {
-		if od.Enter == nil || od.Enter.Filled == 0 {
+		if od.Enter == nil || od.Enter.Filled == 0 || od.Enter.Status < ormo.OdStatusClosed {
+			// Skip partially filled entries, whose funds are still pending
+			// 跳过部分成交的入场单，其资金仍处于pending
 			continue
 		}
-		curPrice := core.GetPrice(od.Symbol)
+		curPrice := w.ctx.GetPrice(od.Symbol)
 		// Calculate nominal value
 		// 计算名义价值
 		quoteValue := od.Enter.Filled * curPrice
@@ -711,7 +809,7 @@ func (w *BanWallets) UpdateOds(odList []*ormo.InOutOrder, currency string) *errs
 			// 一般来说isGood < 0时应该od.Profit < 0，但有时候价格更新了，订单利润尚未更新导致od.Profit > 0
 			// 价格走势不同，产生亏损，判断是否自动补充保证金
 			// 计算维持保证金
-			minMargin, err := exchange.CalcMaintMargin(od.Symbol, quoteValue) // 要求的最低保证金
+			minMargin, err := exg.GetPairExg(od.Symbol).CalcMaintMargin(core.PairSymbol(od.Symbol), quoteValue) // 要求的最低保证金
 			if err != nil {
 				return err
 			}
@@ -736,7 +834,7 @@ func (w *BanWallets) UpdateOds(odList []*ormo.InOutOrder, currency string) *errs
 }
 
 func (w *BanWallets) GetAmountByLegal(symbol string, legalCost float64) float64 {
-	return legalCost / core.GetPrice(symbol)
+	return legalCost / w.ctx.GetPrice(symbol)
 }
 
 func (w *BanWallets) calcLegal(itemAmt func(item *ItemWallet) float64, symbols []string) ([]float64, []string, []float64) {
@@ -758,7 +856,7 @@ func (w *BanWallets) calcLegal(itemAmt func(item *ItemWallet) float64, symbols [
 	var skips []string
 
 	for key, item := range data {
-		var price = core.GetPriceSafe(key)
+		var price = w.ctx.GetPriceSafe(key)
 		if price == -1 {
 			skips = append(skips, key)
 			continue
@@ -845,7 +943,7 @@ func (w *BanWallets) FiatValue(withUpol bool, symbols ...string) float64 {
 		if !exists {
 			continue
 		}
-		totalVal += item.FiatValue(withUpol)
+		totalVal += item.FiatValue(w.ctx.BotCtx, withUpol)
 	}
 
 	return totalVal
@@ -862,11 +960,16 @@ func (w *BanWallets) TryUpdateStakePctAmt() {
 	if config.StakePct > 0 {
 		acc, ok := config.Accounts[w.Account]
 		if ok {
-			legalValue := w.TotalLegal(nil, true)
-			if banexg.IsContract(core.Market) && config.Leverage > 1 {
-				// 对于合约市场，百分比开单应基于带杠杆的名义资产价值
-				legalValue *= config.Leverage
-			}
+			// Based on all wallets of the account in live trading
+			// 实盘时基于账户的所有钱包
+			legalValue := w.ctx.SumAccWallets(w.Account, func(it *BanWallets) float64 {
+				val := it.TotalLegal(nil, true)
+				if banexg.IsContract(it.VenueMarket()) && config.Leverage > 1 {
+					// 对于合约市场，百分比开单应基于带杠杆的名义资产价值
+					val *= config.Leverage
+				}
+				return val
+			})
 			// Round to the nearest tenth place
 			// 四舍五入到十位
 			pctAmt := math.Round(legalValue*config.StakePct/1000) * 10
@@ -875,7 +978,7 @@ func (w *BanWallets) TryUpdateStakePctAmt() {
 			} else if math.Abs(pctAmt/acc.StakePctAmt-1) >= 0.2 {
 				// Update only if total assets change by more than 20%
 				// 总资产变化超过20%才更新
-				date := btime.ToDateStr(btime.TimeMS(), core.DefaultDateFmt)
+				date := btime.ToDateStr(w.ctx.TimeMS(), core.DefaultDateFmt)
 				log.Debug("stake amount changed by stake_pct", zap.String("d", date),
 					zap.Float64("old", acc.StakePctAmt), zap.Float64("new", pctAmt))
 				acc.StakePctAmt = pctAmt
@@ -885,18 +988,19 @@ func (w *BanWallets) TryUpdateStakePctAmt() {
 }
 
 func UpdateWalletByBalances(wallets *BanWallets, item *banexg.Balances) {
-	if core.IsPriceEmpty() {
+	if wallets.ctx.IsPriceEmpty() {
 		// A one-time refresh if a price is requested when all prices are not loaded
 		// 所有价格都未加载时，如果请求价格，则一次性刷新
-		res, err := exg.Default.FetchTickerPrice("", nil)
+		res, err := wallets.VenueExg().FetchTickerPrice("", nil)
 		if err != nil {
 			log.Error("load ticker prices fail", zap.Error(err))
 		} else {
-			core.SetPrices(res)
+			wallets.ctx.SetPrices(res)
 		}
 	}
 	var items []*banexg.Asset
 	var skips []string
+	isContract := banexg.IsContract(wallets.VenueMarket())
 	for coin, it := range item.Assets {
 		if it.Total == 0 {
 			continue
@@ -918,7 +1022,7 @@ func UpdateWalletByBalances(wallets *BanWallets, item *banexg.Balances) {
 			wallets.Items[coin] = record
 		}
 		record.lock.Lock()
-		if core.IsContract {
+		if isContract {
 			record.Pendings["*"] = it.Used
 			record.Frozens["*"] = 0
 		} else {
@@ -926,7 +1030,7 @@ func UpdateWalletByBalances(wallets *BanWallets, item *banexg.Balances) {
 			record.Frozens["*"] = it.Used
 		}
 		record.lock.Unlock()
-		coinPrice := core.GetPriceSafe(coin)
+		coinPrice := wallets.ctx.GetPriceSafe(coin)
 		if coinPrice == -1 {
 			skips = append(skips, coin)
 			continue
@@ -953,7 +1057,8 @@ func UpdateWalletByBalances(wallets *BanWallets, item *banexg.Balances) {
 		log.Info(fmt.Sprintf("update balance skips: %s", strings.Join(skips, "  ")))
 	}
 	if len(msgList) > 0 {
-		log.Debug(fmt.Sprintf("update balances %s: %s", wallets.Account, strings.Join(msgList, "  ")))
+		log.Debug(fmt.Sprintf("update balances %s: %s", core.PairKey(wallets.Exchange, wallets.Market, wallets.Account),
+			strings.Join(msgList, "  ")))
 	}
 }
 
@@ -961,27 +1066,28 @@ func UpdateWalletByBalances(wallets *BanWallets, item *banexg.Balances) {
 WatchLiveBalances
 币安推送的余额经常不够及时导致不准确，推荐定期主动拉取更新
 */
-func WatchLiveBalances() {
+func (c *Ctx) WatchLiveBalances() {
 	for account := range config.Accounts {
-		wallets := GetWallets(account)
-		if wallets.IsWatch {
-			continue
-		}
-		out, err := exg.Default.WatchBalance(map[string]interface{}{
-			banexg.ParamAccount: account,
-		})
-		if err != nil {
-			log.Error("watch balance err", zap.Error(err))
-			return
-		}
-		wallets.IsWatch = true
-		go func() {
-			defer func() {
-				wallets.IsWatch = false
-			}()
-			for item := range out {
-				UpdateWalletByBalances(wallets, item)
+		for _, wallets := range c.GetAccWallets(account) {
+			if wallets.IsWatch {
+				continue
+			}
+			out, err := wallets.VenueExg().WatchBalance(map[string]interface{}{
+				banexg.ParamAccount: account,
+			})
+			if err != nil {
+				log.Error("watch balance err", zap.String("market", wallets.VenueMarket()), zap.Error(err))
+				return
 			}
-		}()
+			wallets.IsWatch = true
+			go func() {
+				defer func() {
+					wallets.IsWatch = false
+				}()
+				for item := range out {
+					UpdateWalletByBalances(wallets, item)
+				}
+			}()
+		}
 	}
 }
@@ -10,7 +10,6 @@ import (
 )
 
 var (
-	CurTimeMS    = int64(0)
 	UTCLocale, _ = time.LoadLocation("UTC")
 	LocShow      *time.Location // 用于显示的时区
 )
@@ -39,15 +38,12 @@ func UTCStamp() int64 {
 
 /*
 Time
-Get the current 10-digit second-level timestamp
-获取当前10位秒级时间戳
+Get the current 10-digit second-level timestamp of core.DefCtx
+获取core.DefCtx的当前10位秒级时间戳
 */
 func Time() float64 {
 	if core.BackTestMode {
-		if CurTimeMS == 0 {
-			CurTimeMS = UTCStamp()
-		}
-		return float64(CurTimeMS) * 0.001
+		return core.DefCtx.Time()
 	} else {
 		return UTCTime()
 	}
@@ -55,18 +51,11 @@ func Time() float64 {
 
 /*
 TimeMS
-Get the current 13-digit millisecond timestamp
-获取当前13位毫秒时间戳
+Get the current 13-digit millisecond timestamp of core.DefCtx
+获取core.DefCtx的当前13位毫秒时间戳
 */
 func TimeMS() int64 {
-	if core.BackTestMode {
-		if CurTimeMS == 0 {
-			CurTimeMS = UTCStamp()
-		}
-		return CurTimeMS
-	} else {
-		return UTCStamp()
-	}
+	return core.DefCtx.TimeMS()
 }
 
 func MSToTime(timeMSecs int64) *time.Time {
@@ -78,10 +67,7 @@ func MSToTime(timeMSecs int64) *time.Time {
 
 func Now() *time.Time {
 	if core.BackTestMode {
-		if CurTimeMS == 0 {
-			CurTimeMS = UTCStamp()
-		}
-		return MSToTime(CurTimeMS)
+		return core.DefCtx.Now()
 	}
 	res := bntp.Now().In(UTCLocale)
 	return &res
@@ -262,6 +248,6 @@ update LastBarMs/wait interval from spider
 */
 func SetPairMs(pair string, barMS, waitMS int64) {
 	core.PairCopiedMs[pair] = [2]int64{barMS, waitMS}
-	core.LastBarMs = max(core.LastBarMs, barMS)
+	core.DefCtx.LastBarMs = max(core.DefCtx.LastBarMs, barMS)
 	core.LastCopiedMs = TimeMS()
 }
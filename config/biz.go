@@ -5,6 +5,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -187,6 +188,15 @@ func (c *Config) Apply(args *CmdArgs) error {
 }
 
 func ApplyConfig(args *CmdArgs, c *Config) *errs.Error {
+	appliedRaw = nil
+	if core.LiveMode {
+		// only live trading supports reload, snapshot before applying 只有实盘支持重载，应用前保存快照
+		raw, err_ := configToMap(c)
+		if err_ != nil {
+			return err_
+		}
+		appliedRaw = raw
+	}
 	Loaded = true
 	Name = c.Name
 	Args = args
@@ -254,6 +264,27 @@ func ApplyConfig(args *CmdArgs, c *Config) *errs.Error {
 	if BTNetCost == 0 {
 		BTNetCost = 15
 	}
+	BTFillVolRate = c.BTFillVolRate
+	BTSlippage = c.BTSlippage
+	if BTSlippage != nil && BTSlippage.Model != "" {
+		if _, ok := core.SlippageModels[BTSlippage.Model]; !ok {
+			return errs.NewMsg(core.ErrBadConfig, "invalid bt_slippage.model: %s", BTSlippage.Model)
+		}
+	}
+	BTTickDir = c.BTTickDir
+	if BTTickDir != "" {
+		BTTickDir = ParsePath(BTTickDir)
+	}
+	OdBookStore = c.OdBookStore
+	if OdBookStore != nil && OdBookStore.Dir != "" {
+		OdBookStore.Dir = ParsePath(OdBookStore.Dir)
+		if OdBookStore.Interval <= 0 {
+			OdBookStore.Interval = 1000
+		}
+		if OdBookStore.Depth <= 0 {
+			OdBookStore.Depth = 20
+		}
+	}
 	RelaySimUnFinish = c.RelaySimUnFinish
 	NTPLangCode = c.NTPLangCode
 	if NTPLangCode == "" {
@@ -289,6 +320,7 @@ func ApplyConfig(args *CmdArgs, c *Config) *errs.Error {
 		c.FatalStopHours = 8
 	}
 	FatalStopHours = c.FatalStopHours
+	Risk = c.Risk
 	TimeRange = c.TimeRange
 	RunTimeframes = c.RunTimeframes
 	WatchJobs = c.WatchJobs
@@ -304,13 +336,11 @@ func ApplyConfig(args *CmdArgs, c *Config) *errs.Error {
 		c.StratPerf.Validate()
 	}
 	StratPerf = c.StratPerf
-	ApplyPairPolicy(c.Pairs, c.RunPolicy)
-	if c.PairMgr == nil {
-		c.PairMgr = &PairMgrConfig{}
+	for _, pol := range c.RunPolicy {
+		if pol.Sizer != nil && pol.Sizer.Name != "" && !core.PositionSizers[pol.Sizer.Name] {
+			return errs.NewMsg(core.ErrBadConfig, "invalid sizer for %s: %s", pol.Name, pol.Sizer.Name)
+		}
 	}
-	PairMgr = c.PairMgr
-	PairFilters = c.PairFilters
-	Accounts = c.Accounts
 	Exchange = c.Exchange
 	if Exchange == nil {
 		Exchange = &ExchangeConfig{
@@ -318,7 +348,20 @@ func ApplyConfig(args *CmdArgs, c *Config) *errs.Error {
 			Items: make(map[string]map[string]interface{}),
 		}
 	}
-	err := initExgAccs(args)
+	err := checkPolicyExgs(c.RunPolicy)
+	if err != nil {
+		return err
+	}
+	// pairs of policies are keyed by exchange/market, so Exchange must be set before 策略品种按交易所/市场生成键，需先设置Exchange
+	ApplyPairPolicy(c.Pairs, c.RunPolicy)
+	StratHosts = c.StratHosts
+	if c.PairMgr == nil {
+		c.PairMgr = &PairMgrConfig{}
+	}
+	PairMgr = c.PairMgr
+	PairFilters = c.PairFilters
+	Accounts = c.Accounts
+	err = initExgAccs(args)
 	if err != nil {
 		return err
 	}
@@ -333,6 +376,30 @@ func ApplyConfig(args *CmdArgs, c *Config) *errs.Error {
 	return nil
 }
 
+/*
+checkPolicyExgs
+Policies trading on an exchange/market other than the default must give pairs. Real trading only supports
+binance, same as the default exchange.
+在非默认交易所/市场交易的策略必须指定pairs。实盘仅支持binance，与默认交易所相同。
+*/
+func checkPolicyExgs(policies []*RunPolicyConfig) *errs.Error {
+	for _, pol := range policies {
+		exgName, market := pol.ExgMarket()
+		if exgName == Exchange.Name && market == core.Market {
+			continue
+		}
+		if len(pol.Pairs) == 0 {
+			return errs.NewMsg(core.ErrBadConfig, "run_policy %s: pairs is required when exchange/market is set",
+				pol.ID())
+		}
+		if core.EnvReal && exgName != "binance" {
+			return errs.NewMsg(core.ErrBadConfig, "run_policy %s: real trading is not supported for %s",
+				pol.ID(), exgName)
+		}
+	}
+	return nil
+}
+
 func ApplyPairPolicy(pairs []string, policies []*RunPolicyConfig) {
 	staticPairs, fixPairs := initPolicies(policies)
 	if len(pairs) > 0 {
@@ -359,6 +426,7 @@ func initPolicies(policyList []*RunPolicyConfig) (bool, []string) {
 			pol.PairParams = make(map[string]map[string]float64)
 		}
 		pol.defs = make(map[string]*core.Param)
+		pol.initPairKeys()
 		if len(pol.Pairs) > 0 {
 			polPairs = append(polPairs, pol.Pairs...)
 		} else {
@@ -369,8 +437,8 @@ func initPolicies(policyList []*RunPolicyConfig) (bool, []string) {
 	return staticPairs, polPairs
 }
 
-func GetTakeOverTF(pair, defTF string) string {
-	if pairMap, ok := core.StgPairTfs[TakeOverStrat]; ok {
+func GetTakeOverTF(c *core.BotCtx, pair, defTF string) string {
+	if pairMap, ok := c.StgPairTfs[TakeOverStrat]; ok {
 		if tf, ok := pairMap[pair]; ok {
 			return tf
 		}
@@ -485,6 +553,19 @@ func GetStakeAmount(accName string) float64 {
 	return amount
 }
 
+/*
+GetAccRisk
+Risk config of account, use root risk if the account has none
+账户的风控配置，账户未配置时使用根risk
+*/
+func GetAccRisk(accName string) *RiskConfig {
+	acc, ok := Accounts[accName]
+	if ok && acc.Risk != nil {
+		return acc.Risk
+	}
+	return Risk
+}
+
 func (c *Config) DumpYaml() ([]byte, *errs.Error) {
 	data, err_ := core.MarshalYaml(c)
 	if err_ != nil {
@@ -589,6 +670,10 @@ func (c *Config) Clone() *Config {
 		MinOpenRate:      c.MinOpenRate,
 		LowCostAction:    c.LowCostAction,
 		BTNetCost:        c.BTNetCost,
+		BTFillVolRate:    c.BTFillVolRate,
+		BTSlippage:       c.BTSlippage,
+		BTTickDir:        c.BTTickDir,
+		OdBookStore:      c.OdBookStore,
 		RelaySimUnFinish: c.RelaySimUnFinish,
 		OrderBarMax:      c.OrderBarMax,
 		MaxOpenOrders:    c.MaxOpenOrders,
@@ -598,6 +683,7 @@ func (c *Config) Clone() *Config {
 		StakeCurrency:    c.StakeCurrency,
 		FatalStop:        c.FatalStop,
 		FatalStopHours:   c.FatalStopHours,
+		Risk:             c.Risk,
 		TimeRangeRaw:     c.TimeRangeRaw,
 		TimeStart:        c.TimeStart,
 		TimeEnd:          c.TimeEnd,
@@ -606,6 +692,7 @@ func (c *Config) Clone() *Config {
 		KlineSource:      c.KlineSource,
 		WatchJobs:        c.WatchJobs,
 		RunPolicy:        c.RunPolicy,
+		StratHosts:       c.StratHosts,
 		StratPerf:        c.StratPerf,
 		Pairs:            c.Pairs,
 		PairMgr:          c.PairMgr,
@@ -624,6 +711,7 @@ database.url
 exchange.account_*.*.(api_key|api_secret)
 rpc_channels.*.*secret
 api_server.jwt_secret_key
+api_server.metrics_token
 api_server.users[*].pwd
 */
 func (c *Config) Desensitize() *Config {
@@ -643,6 +731,7 @@ func (c *Config) Desensitize() *Config {
 			Retention:   c.Database.Retention,
 			MaxPoolSize: c.Database.MaxPoolSize,
 			AutoCreate:  c.Database.AutoCreate,
+			KlineStore:  c.Database.KlineStore,
 		}
 	}
 
@@ -713,6 +802,51 @@ func (c *RunPolicyConfig) ID() string {
 	}
 }
 
+/*
+ExgMarket
+Return the exchange and market this policy trades on, empty fields fall back to `exchange.name` and `market_type`.
+返回此策略交易的交易所和市场，为空时使用`exchange.name`和`market_type`
+*/
+func (c *RunPolicyConfig) ExgMarket() (string, string) {
+	exgName, market := c.Exchange, c.Market
+	if exgName == "" && Exchange != nil {
+		exgName = Exchange.Name
+	}
+	if market == "" {
+		market = core.Market
+	}
+	return exgName, market
+}
+
+/*
+PairKey
+Return the pair key of symbol on the exchange/market of this policy, see core.PairKey. Keys are kept as is.
+返回此策略交易所/市场上品种的键，见core.PairKey。已经是键的保持不变。
+*/
+func (c *RunPolicyConfig) PairKey(symbol string) string {
+	if strings.Contains(symbol, "@") {
+		return symbol
+	}
+	exgName, market := c.ExgMarket()
+	if Exchange == nil || exgName == Exchange.Name && market == core.Market {
+		return symbol
+	}
+	return core.PairKey(exgName, market, symbol)
+}
+
+// initPairKeys convert pairs and pair_params to pair keys 将pairs和pair_params转为品种键
+func (c *RunPolicyConfig) initPairKeys() {
+	for i, p := range c.Pairs {
+		c.Pairs[i] = c.PairKey(p)
+	}
+	for p, params := range c.PairParams {
+		if key := c.PairKey(p); key != p {
+			delete(c.PairParams, p)
+			c.PairParams[key] = params
+		}
+	}
+}
+
 func (c *RunPolicyConfig) Key() string {
 	tfStr := strings.Join(c.RunTimeframes, "|")
 	pairStr := strings.Join(c.Pairs, "|")
@@ -812,6 +946,12 @@ func (c *RunPolicyConfig) ToYaml() string {
 	if c.Dirt != "" {
 		b.WriteString(fmt.Sprintf("    dirt: %s\n", c.Dirt))
 	}
+	if c.Exchange != "" {
+		b.WriteString(fmt.Sprintf("    exchange: %s\n", c.Exchange))
+	}
+	if c.Market != "" {
+		b.WriteString(fmt.Sprintf("    market: %s\n", c.Market))
+	}
 	if len(c.RunTimeframes) > 0 {
 		b.WriteString(fmt.Sprintf("    run_timeframes: [ %s ]\n", strings.Join(c.RunTimeframes, ", ")))
 	}
@@ -831,13 +971,18 @@ func (c *RunPolicyConfig) ToYaml() string {
 func (c *RunPolicyConfig) Clone() *RunPolicyConfig {
 	res := &RunPolicyConfig{
 		Name:          c.Name,
+		Exchange:      c.Exchange,
+		Market:        c.Market,
 		Filters:       c.Filters,
 		RunTimeframes: c.RunTimeframes,
 		MaxPair:       c.MaxPair,
 		MaxOpen:       c.MaxOpen,
 		MaxSimulOpen:  c.MaxSimulOpen,
+		OrderBarMax:   c.OrderBarMax,
+		StakeRate:     c.StakeRate,
 		Dirt:          c.Dirt,
 		StratPerf:     c.StratPerf,
+		Sizer:         c.Sizer,
 		Pairs:         c.Pairs,
 		Params:        make(map[string]float64),
 		PairParams:    make(map[string]map[string]float64),
@@ -877,6 +1022,31 @@ func (c *RunPolicyConfig) PairDup(pair string) (*RunPolicyConfig, bool) {
 	return res, isDiff
 }
 
+/*
+SameStrat
+Whether the strategy built from c is the same as o. pairs, filters, max_pair and pair_params only affect which
+pairs to run, they are ignored; compare the result of PairDup for pair specific params.
+由c创建的策略是否与o相同。pairs、filters、max_pair和pair_params只影响运行哪些品种，不参与比较；品种专有参数应比较PairDup的结果。
+*/
+func (c *RunPolicyConfig) SameStrat(o *RunPolicyConfig) bool {
+	if c == o {
+		return true
+	}
+	if c == nil || o == nil {
+		return false
+	}
+	return reflect.DeepEqual(c.stratPart(), o.stratPart())
+}
+
+func (c *RunPolicyConfig) stratPart() *RunPolicyConfig {
+	res := c.Clone()
+	res.Filters, res.MaxPair, res.Pairs, res.PairParams, res.defs = nil, 0, nil, nil, nil
+	if len(res.RunTimeframes) == 0 {
+		res.RunTimeframes = nil
+	}
+	return res
+}
+
 func (a *AccountConfig) GetApiSecret() *ApiSecretConfig {
 	if a == nil || len(a.Exchanges) == 0 {
 		return &ApiSecretConfig{}
@@ -892,7 +1062,7 @@ func (a *AccountConfig) GetApiSecret() *ApiSecretConfig {
 	return &ApiSecretConfig{}
 }
 
-func LoadPerfs(inDir string) {
+func LoadPerfs(c *core.BotCtx, inDir string) {
 	if StratPerf == nil || !StratPerf.Enable {
 		return
 	}
@@ -919,7 +1089,7 @@ func LoadPerfs(inDir string) {
 			log.Error(fmt.Sprintf("decode %s fail", strat), zap.Error(err_))
 			continue
 		}
-		core.StratPerfSta[strat] = sta
+		c.StratPerfSta[strat] = sta
 		perfVal, ok := cfg["perf"]
 		if ok && perfVal != nil {
 			var perf = map[string]string{}
@@ -933,7 +1103,7 @@ func LoadPerfs(inDir string) {
 				num, _ := strconv.Atoi(arr[0])
 				profit, _ := strconv.ParseFloat(arr[1], 64)
 				score, _ := strconv.ParseFloat(arr[2], 64)
-				core.JobPerfs[fmt.Sprintf("%s_%s", strat, pairTf)] = &core.JobPerf{
+				c.JobPerfs[fmt.Sprintf("%s_%s", strat, pairTf)] = &core.JobPerf{
 					Num:       num,
 					TotProfit: profit,
 					Score:     score,
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banexg"
 	"gopkg.in/yaml.v3"
 	"testing"
 )
@@ -20,3 +22,84 @@ func TestLoadConfig(t *testing.T) {
 	}
 	fmt.Println("result: \n", string(data))
 }
+
+func TestDiffConfig(t *testing.T) {
+	old := map[string]interface{}{
+		"stake_amount": 10,
+		"leverage":     2,
+		"pairs":        []interface{}{"BTC/USDT"},
+		"pairmgr":      map[string]interface{}{"cron": "0 0 * * * *", "limit": 10},
+	}
+	cur := map[string]interface{}{
+		"stake_amount": 20,
+		"leverage":     3,
+		"pairs":        []interface{}{"BTC/USDT"},
+		"pairmgr":      map[string]interface{}{"cron": "0 30 * * * *", "limit": 10},
+		"pairlists":    []interface{}{map[string]interface{}{"name": "VolumePairList"}},
+	}
+	res := DiffConfig(old, cur)
+	if fmt.Sprint(res.Applied) != "[pairlists stake_amount]" {
+		t.Errorf("bad applied: %v", res.Applied)
+	}
+	if fmt.Sprint(res.Restart) != "[leverage pairmgr.cron]" {
+		t.Errorf("bad restart: %v", res.Restart)
+	}
+}
+
+func TestCheckPolicyExgs(t *testing.T) {
+	oldExg, oldMarket, oldLive, oldEnv := Exchange, core.Market, core.LiveMode, core.RunEnv
+	defer func() {
+		Exchange, core.Market, core.LiveMode = oldExg, oldMarket, oldLive
+		core.SetRunEnv(oldEnv)
+	}()
+	Exchange = &ExchangeConfig{Name: "binance"}
+	core.Market = banexg.MarketLinear
+	core.LiveMode = true
+	core.SetRunEnv(core.RunEnvProd)
+	pols := []*RunPolicyConfig{
+		{Name: "a"},
+		{Name: "b", Market: banexg.MarketSpot, Pairs: []string{"BTC/USDT"}},
+	}
+	if err := checkPolicyExgs(pols); err != nil {
+		t.Errorf("real trading should allow other markets: %v", err)
+	}
+	noPairs := []*RunPolicyConfig{{Name: "c", Market: banexg.MarketSpot}}
+	if err := checkPolicyExgs(noPairs); err == nil || err.Code != core.ErrBadConfig {
+		t.Errorf("pairs should be required for other markets, got %v", err)
+	}
+	okx := []*RunPolicyConfig{{Name: "d", Exchange: "okx", Pairs: []string{"BTC/USDT:USDT"}}}
+	if err := checkPolicyExgs(okx); err == nil || err.Code != core.ErrBadConfig {
+		t.Errorf("real trading should reject exchanges without live order manager, got %v", err)
+	}
+	core.SetRunEnv(core.RunEnvDryRun)
+	if err := checkPolicyExgs(okx); err != nil {
+		t.Errorf("dry run should allow other exchanges: %v", err)
+	}
+}
+
+func TestPolicyPairKeys(t *testing.T) {
+	oldExg, oldName, oldMarket, oldPols := Exchange, core.ExgName, core.Market, RunPolicy
+	defer func() {
+		Exchange, core.ExgName, core.Market, RunPolicy = oldExg, oldName, oldMarket, oldPols
+	}()
+	Exchange = &ExchangeConfig{Name: "binance"}
+	core.ExgName, core.Market = "binance", banexg.MarketSpot
+	spot := &RunPolicyConfig{Name: "a", Pairs: []string{"BTC/USDT"}}
+	perp := &RunPolicyConfig{Name: "b", Market: banexg.MarketLinear, Pairs: []string{"BTC/USDT:USDT"},
+		PairParams: map[string]map[string]float64{"BTC/USDT:USDT": {"atr": 10}}}
+	okx := &RunPolicyConfig{Name: "c", Exchange: "okx", Pairs: []string{"BTC/USDT", "ETH/USDT@bybit.spot"}}
+	ApplyPairPolicy(nil, []*RunPolicyConfig{spot, perp, okx})
+	if spot.Pairs[0] != "BTC/USDT" {
+		t.Errorf("default market pairs should be kept, got %v", spot.Pairs)
+	}
+	if perp.Pairs[0] != "BTC/USDT:USDT@binance.linear" || perp.PairParams["BTC/USDT:USDT@binance.linear"] == nil {
+		t.Errorf("pairs and pair_params should be keyed by market, got %v %v", perp.Pairs, perp.PairParams)
+	}
+	if okx.Pairs[0] != "BTC/USDT@okx.spot" || okx.Pairs[1] != "ETH/USDT@bybit.spot" {
+		t.Errorf("same symbol on other exchange should be another pair, got %v", okx.Pairs)
+	}
+	exgName, market, symbol := core.SplitPairKey(okx.Pairs[0])
+	if exgName != "okx" || market != banexg.MarketSpot || symbol != "BTC/USDT" {
+		t.Errorf("bad split of pair key: %v %v %v", exgName, market, symbol)
+	}
+}
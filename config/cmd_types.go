@@ -42,7 +42,11 @@ type CmdArgs struct {
 	ExgReal       string
 	OptRounds     int     // Hyperparameter optimization single task execution round 超参数优化单任务执行轮次
 	Concur        int     // Hyperparameter optimization of multi-process concurrency 超参数优化多进程并发数量
-	Sampler       string  // Hyperparameter optimization methods 超参数优化的方法: tpe/bayes/random/cmaes/ipop-cmaes/bipop-cmaes
+	Sampler       string  // Hyperparameter optimization methods 超参数优化的方法: tpe/bayes/random/cmaes/ipop-cmaes/bipop-cmaes/nsga2
+	Objectives    string  // Comma separated objectives for multi-objective optimization 多目标优化的目标，逗号分隔: profit,drawdown,sharpe...
+	Resume        string  // Name of the optimize study to continue 要继续的超参数优化研究名称
+	Pruner        string  // Stop unpromising trials early 提前停止没有希望的轮次: median/halving
+	Seed          int64   // Random seed of samplers, -1 means 0 or the seed of resumed study 采样器随机种子，-1表示0或继续的研究的种子
 	EachPairs     bool    // Execute target by target 逐个标的执行
 	ReviewPeriod  string  // During continuous parameter adjustment and backtesting, the period of parameter adjustment review 持续调参回测时，调参回顾的周期
 	RunPeriod     string  // During continuous parameter adjustment and backtesting, the effective running period after parameter adjustment 持续调参回测时，调参后有效运行周期
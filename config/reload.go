@@ -0,0 +1,163 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banexg/errs"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ReloadKeys top level keys which can be applied to a running bot without restart 运行中机器人无需重启即可生效的顶层配置
+	ReloadKeys = map[string]bool{
+		"run_policy":    true,
+		"pairs":         true,
+		"pairmgr":       true,
+		"pairlists":     true,
+		"stake_amount":  true,
+		"stake_pct":     true,
+		"max_stake_amt": true,
+	}
+	// yaml map of the applied config in live mode, used for diff on reload 实盘模式下已应用配置的yaml映射，重载时用于比较
+	appliedRaw map[string]interface{}
+)
+
+// ReloadResult changed top level keys of config 配置中变化的顶层键
+type ReloadResult struct {
+	Applied []string `json:"applied"` // applied without restart 已即时生效
+	Restart []string `json:"restart"` // need restart to take effect 需重启才能生效
+}
+
+func configToMap(c *Config) (map[string]interface{}, *errs.Error) {
+	data, err := c.DumpYaml()
+	if err != nil {
+		return nil, err
+	}
+	var res = make(map[string]interface{})
+	if err_ := yaml.Unmarshal(data, &res); err_ != nil {
+		return nil, errs.New(errs.CodeUnmarshalFail, err_)
+	}
+	return res, nil
+}
+
+/*
+DiffConfig
+Compare the top level keys of two config maps, and group the changed keys by whether they can be reloaded.
+`pairmgr.cron` is scheduled at startup, its change requires restart.
+比较两个配置映射的顶层键，按是否可重载对变化的键分组。`pairmgr.cron`在启动时调度，修改需要重启。
+*/
+func DiffConfig(old, new map[string]interface{}) *ReloadResult {
+	res := &ReloadResult{}
+	keys := make(map[string]bool)
+	for k := range old {
+		keys[k] = true
+	}
+	for k := range new {
+		keys[k] = true
+	}
+	for k := range keys {
+		if reflect.DeepEqual(old[k], new[k]) {
+			continue
+		}
+		if !ReloadKeys[k] {
+			res.Restart = append(res.Restart, k)
+			continue
+		}
+		if k == "pairmgr" {
+			oldMgr, _ := old[k].(map[string]interface{})
+			newMgr, _ := new[k].(map[string]interface{})
+			if oldMgr["cron"] != newMgr["cron"] {
+				res.Restart = append(res.Restart, "pairmgr.cron")
+				if reflect.DeepEqual(withoutKey(oldMgr, "cron"), withoutKey(newMgr, "cron")) {
+					continue
+				}
+			}
+		}
+		res.Applied = append(res.Applied, k)
+	}
+	slices.Sort(res.Applied)
+	slices.Sort(res.Restart)
+	return res
+}
+
+func withoutKey(data map[string]interface{}, key string) map[string]interface{} {
+	res := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		if k != key {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+/*
+ReloadConfig
+Read config files again with the startup args, apply the changed keys in ReloadKeys to global variables,
+other changed keys are only reported. Caller should refresh the pairs and jobs after this.
+使用启动参数重新读取配置文件，将ReloadKeys中变化的键应用到全局变量，其他变化的键仅报告。调用方之后应刷新品种和任务。
+*/
+func ReloadConfig() (*ReloadResult, *errs.Error) {
+	if !Loaded || Args == nil {
+		return nil, errs.NewMsg(core.ErrRunTime, "config not loaded")
+	}
+	if appliedRaw == nil {
+		return nil, errs.NewMsg(core.ErrRunTime, "reload is only supported in live mode")
+	}
+	c, err := GetConfig(Args, false)
+	if err != nil {
+		return nil, err
+	}
+	newRaw, err := configToMap(c)
+	if err != nil {
+		return nil, err
+	}
+	res := DiffConfig(appliedRaw, newRaw)
+	if len(res.Applied) == 0 {
+		return res, nil
+	}
+	err = checkPolicyExgs(c.RunPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if c.PairMgr == nil {
+		c.PairMgr = &PairMgrConfig{}
+	}
+	if PairMgr != nil {
+		// cron is scheduled at startup, keep the running one
+		// cron在启动时调度，保持运行中的值
+		c.PairMgr.Cron = PairMgr.Cron
+	}
+	PairMgr = c.PairMgr
+	PairFilters = c.PairFilters
+	ApplyPairPolicy(c.Pairs, c.RunPolicy)
+	StakeAmount = c.StakeAmount
+	StakePct = c.StakePct
+	MaxStakeAmt = c.MaxStakeAmt
+	Data.RunPolicy = c.RunPolicy
+	Data.Pairs = c.Pairs
+	Data.PairMgr = c.PairMgr
+	Data.PairFilters = c.PairFilters
+	Data.StakeAmount = c.StakeAmount
+	Data.StakePct = c.StakePct
+	Data.MaxStakeAmt = c.MaxStakeAmt
+	for _, k := range res.Applied {
+		val, ok := newRaw[k]
+		if !ok {
+			delete(appliedRaw, k)
+			continue
+		}
+		if newMgr, isMap := val.(map[string]interface{}); k == "pairmgr" && isMap {
+			// keep the running cron, so its change is reported until restart 保持运行中的cron，重启前持续报告其变化
+			oldMgr, _ := appliedRaw[k].(map[string]interface{})
+			mgr := withoutKey(newMgr, "cron")
+			if cron, has := oldMgr["cron"]; has {
+				mgr["cron"] = cron
+			}
+			val = mgr
+		}
+		appliedRaw[k] = val
+	}
+	return res, nil
+}
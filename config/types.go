@@ -24,16 +24,20 @@ var (
 	MarginAddRate    float64 // When trading contracts, if a loss occurs and the loss reaches this value of the initial margin ratio, additional margin will be required to avoid forced liquidation. 交易合约时，如出现亏损，亏损达到初始保证金比率的此值时，进行追加保证金，避免强平
 	ChargeOnBomb     bool
 	TakeOverStrat    string
-	StakeAmount      float64 // The amount of a single order, the priority is lower than StakePct 单笔开单金额，优先级低于StakePct
-	StakePct         float64 // Percentage of single bill amount 单笔开单金额百分比
-	MaxStakeAmt      float64 // Maximum bill amount for a single transaction 单笔最大开单金额
-	OpenVolRate      float64 // When opening an order without specifying a quantity, the multiple of the maximum allowed order quantity/average candle trading volume, defaults to 1 未指定数量开单时，最大允许开单数量/平均蜡烛成交量的倍数，默认1
-	MinOpenRate      float64 // When the wallet balance is less than the single amount, orders are allowed to be issued when it reaches this ratio of the single amount. 钱包余额不足单笔金额时，达到单笔金额的此比例则允许开单
-	LowCostAction    string  // Actions taken when stake amount less than the minimum amount 花费不足最小金额时的动作：ignore, keep
-	BTNetCost        float64 // Order placement delay during backtesting, simulated slippage, unit seconds 回测时下单延迟，模拟滑点，单位秒
-	RelaySimUnFinish bool    // 交易新品种时(回测/实盘)，是否从开始时间未平仓订单接力开始交易
-	NTPLangCode      string  // NTP真实时间同步所用langCode，默认none不启用
-	OrderBarMax      int     // 查找开始时间未平仓订单向前模拟最大bar数量
+	StakeAmount      float64            // The amount of a single order, the priority is lower than StakePct 单笔开单金额，优先级低于StakePct
+	StakePct         float64            // Percentage of single bill amount 单笔开单金额百分比
+	MaxStakeAmt      float64            // Maximum bill amount for a single transaction 单笔最大开单金额
+	OpenVolRate      float64            // When opening an order without specifying a quantity, the multiple of the maximum allowed order quantity/average candle trading volume, defaults to 1 未指定数量开单时，最大允许开单数量/平均蜡烛成交量的倍数，默认1
+	MinOpenRate      float64            // When the wallet balance is less than the single amount, orders are allowed to be issued when it reaches this ratio of the single amount. 钱包余额不足单笔金额时，达到单笔金额的此比例则允许开单
+	LowCostAction    string             // Actions taken when stake amount less than the minimum amount 花费不足最小金额时的动作：ignore, keep
+	BTNetCost        float64            // Order placement delay during backtesting, simulated slippage, unit seconds 回测时下单延迟，模拟滑点，单位秒
+	BTSlippage       *SlippageConfig    // Slippage model for market fills in backtest 回测时市价成交的滑点模型
+	BTFillVolRate    float64            // Max fraction of bar volume a limit order can fill in backtest, 0 to fill all at once 回测时限价单每个bar最多成交的bar成交量比例，0表示一次全部成交
+	BTTickDir        string             // Directory of trade ticks, replay ticks instead of klines in backtest when set 逐笔成交目录，设置时回测回放tick而非K线
+	OdBookStore      *OdBookStoreConfig // Order book snapshots recorded by spider and replayed in backtest 爬虫记录并在回测中回放的订单簿快照
+	RelaySimUnFinish bool               // 交易新品种时(回测/实盘)，是否从开始时间未平仓订单接力开始交易
+	NTPLangCode      string             // NTP真实时间同步所用langCode，默认none不启用
+	OrderBarMax      int                // 查找开始时间未平仓订单向前模拟最大bar数量
 	MaxOpenOrders    int
 	MaxSimulOpen     int
 	WalletAmounts    map[string]float64
@@ -42,11 +46,13 @@ var (
 	StakeCurrencyMap map[string]bool
 	FatalStop        map[int]float64
 	FatalStopHours   int
+	Risk             *RiskConfig // Portfolio exposure caps and daily loss limit 组合敞口上限和每日亏损限制
 	TimeRange        *TimeTuple
 	RunTimeframes    []string
 	KlineSource      string
 	WatchJobs        map[string][]string
 	RunPolicy        []*RunPolicyConfig
+	StratHosts       map[string]string // Out-of-process strategy hosts: name -> grpc address 进程外策略宿主：名称 -> grpc地址
 	StratPerf        *StratPerfConfig
 	Pairs            []string
 	PairMgr          *PairMgrConfig
@@ -99,6 +105,10 @@ type Config struct {
 	MinOpenRate      float64                           `yaml:"min_open_rate,omitempty" mapstructure:"min_open_rate"`
 	LowCostAction    string                            `yaml:"low_cost_action,omitempty" mapstructure:"low_cost_action"`
 	BTNetCost        float64                           `yaml:"bt_net_cost,omitempty" mapstructure:"bt_net_cost"`
+	BTFillVolRate    float64                           `yaml:"bt_fill_vol_rate,omitempty" mapstructure:"bt_fill_vol_rate"`
+	BTSlippage       *SlippageConfig                   `yaml:"bt_slippage,omitempty" mapstructure:"bt_slippage"`
+	BTTickDir        string                            `yaml:"bt_tick_dir,omitempty" mapstructure:"bt_tick_dir"`
+	OdBookStore      *OdBookStoreConfig                `yaml:"odbook_store,omitempty" mapstructure:"odbook_store"`
 	RelaySimUnFinish bool                              `yaml:"relay_sim_unfinish,omitempty" mapstructure:"relay_sim_unfinish"`
 	NTPLangCode      string                            `yaml:"ntp_lang_code,omitempty" mapstructure:"ntp_lang_code"`
 	OrderBarMax      int                               `yaml:"order_bar_max,omitempty" mapstructure:"order_bar_max"`
@@ -109,6 +119,7 @@ type Config struct {
 	StakeCurrency    []string                          `yaml:"stake_currency,omitempty,flow" mapstructure:"stake_currency"`
 	FatalStop        map[string]float64                `yaml:"fatal_stop,omitempty" mapstructure:"fatal_stop"`
 	FatalStopHours   int                               `yaml:"fatal_stop_hours,omitempty" mapstructure:"fatal_stop_hours"`
+	Risk             *RiskConfig                       `yaml:"risk,omitempty" mapstructure:"risk"`
 	TimeRangeRaw     string                            `yaml:"timerange,omitempty" mapstructure:"timerange"`
 	TimeStart        string                            `yaml:"time_start,omitempty" mapstructure:"time_start"`
 	TimeEnd          string                            `yaml:"time_end,omitempty" mapstructure:"time_end"`
@@ -117,6 +128,7 @@ type Config struct {
 	KlineSource      string                            `yaml:"kline_source,omitempty" mapstructure:"kline_source"`
 	WatchJobs        map[string][]string               `yaml:"watch_jobs,omitempty" mapstructure:"watch_jobs"`
 	RunPolicy        []*RunPolicyConfig                `yaml:"run_policy,omitempty" mapstructure:"run_policy"`
+	StratHosts       map[string]string                 `yaml:"strat_hosts,omitempty" mapstructure:"strat_hosts"`
 	StratPerf        *StratPerfConfig                  `yaml:"strat_perf,omitempty" mapstructure:"strat_perf"`
 	Pairs            []string                          `yaml:"pairs,omitempty,flow" mapstructure:"pairs"`
 	PairMgr          *PairMgrConfig                    `yaml:"pairmgr,omitempty" mapstructure:"pairmgr"`
@@ -133,6 +145,8 @@ type Config struct {
 // The strategy to run, multiple strategies can be run at the same time 运行的策略，可以多个策略同时运行
 type RunPolicyConfig struct {
 	Name          string                        `yaml:"name" mapstructure:"name"`
+	Exchange      string                        `yaml:"exchange,omitempty" mapstructure:"exchange"`
+	Market        string                        `yaml:"market,omitempty" mapstructure:"market"`
 	Filters       []*CommonPairFilter           `yaml:"filters,omitempty" mapstructure:"filters"`
 	RunTimeframes []string                      `yaml:"run_timeframes,omitempty,flow" mapstructure:"run_timeframes"`
 	MaxPair       int                           `yaml:"max_pair,omitempty" mapstructure:"max_pair"`
@@ -142,6 +156,7 @@ type RunPolicyConfig struct {
 	StakeRate     float64                       `yaml:"stake_rate,omitempty" mapstructure:"stake_rate"`
 	Dirt          string                        `yaml:"dirt,omitempty" mapstructure:"dirt"`
 	StratPerf     *StratPerfConfig              `yaml:"strat_perf,omitempty" mapstructure:"strat_perf"`
+	Sizer         *SizerConfig                  `yaml:"sizer,omitempty" mapstructure:"sizer"`
 	Pairs         []string                      `yaml:"pairs,omitempty,flow" mapstructure:"pairs"`
 	Params        map[string]float64            `yaml:"params,omitempty" mapstructure:"params"`
 	PairParams    map[string]map[string]float64 `yaml:"pair_params,omitempty" mapstructure:"pair_params"`
@@ -158,11 +173,58 @@ type StratPerfConfig struct {
 	BadWeight float64 `yaml:"bad_weight,omitempty" mapstructure:"bad_weight"`
 }
 
+// OdBookStoreConfig Where and how often order book snapshots are stored 订单簿快照的存储位置和频率
+type OdBookStoreConfig struct {
+	Dir      string `yaml:"dir" mapstructure:"dir"`                     // directory of snapshots, empty to disable 快照目录，为空不启用
+	Interval int64  `yaml:"interval,omitempty" mapstructure:"interval"` // min interval between two snapshots of a symbol in ms, default 1000 同一品种两次快照的最小间隔毫秒，默认1000
+	Depth    int    `yaml:"depth,omitempty" mapstructure:"depth"`       // max price levels saved for each side, default 20 每侧保存的最大档位数，默认20
+}
+
+// SlippageConfig Slippage model used by market fills in backtest 回测时市价成交使用的滑点模型
+type SlippageConfig struct {
+	Model   string  `yaml:"model" mapstructure:"model"`                 // fixed/spread/sqrt, empty to disable 为空不启用
+	Bps     float64 `yaml:"bps,omitempty" mapstructure:"bps"`           // fixed slippage in bps, also fallback of spread 固定滑点基点，也是spread无订单簿时的默认值
+	Impact  float64 `yaml:"impact,omitempty" mapstructure:"impact"`     // coefficient of square-root impact 平方根冲击系数
+	VolBars int     `yaml:"vol_bars,omitempty" mapstructure:"vol_bars"` // bars used for average volume, default 20 计算平均成交量的bar数，默认20
+}
+
+// SizerConfig Position sizing of a run_policy when EnterReq has no LegalCost/Amount 策略任务的仓位计算，EnterReq未指定LegalCost/Amount时使用
+type SizerConfig struct {
+	Name      string  `yaml:"name" mapstructure:"name"`                       // fixed/risk/atr/kelly, default fixed 默认fixed
+	Risk      float64 `yaml:"risk,omitempty" mapstructure:"risk"`             // rate of equity lost when stopped out for risk/atr, default 0.01 risk/atr止损时亏损的权益比例，默认0.01
+	ATRPeriod int     `yaml:"atr_period,omitempty" mapstructure:"atr_period"` // bars of ATR, default 14 ATR的bar数，默认14
+	ATRMulti  float64 `yaml:"atr_multi,omitempty" mapstructure:"atr_multi"`   // volatility stop distance in ATRs, default 2 波动止损距离的ATR倍数，默认2
+	KellyFrac float64 `yaml:"kelly_frac,omitempty" mapstructure:"kelly_frac"` // fraction of full kelly, default 0.5 全凯利的比例，默认0.5
+	Window    int     `yaml:"window,omitempty" mapstructure:"window"`         // recent closed orders for kelly stats, default 100 凯利统计的最近平仓订单数，默认100
+	MinOdNum  int     `yaml:"min_od_num,omitempty" mapstructure:"min_od_num"` // use fixed until closed orders reach this, default 30 平仓订单数达到此值前使用fixed，默认30
+	MaxRate   float64 `yaml:"max_rate,omitempty" mapstructure:"max_rate"`     // cap of cost as multiple of fixed stake, default 3 开单金额上限为fixed金额的倍数，默认3
+}
+
+/*
+RiskConfig
+Portfolio exposure caps and daily loss limit checked before order entry, exposure rates are relative to account equity,
+0 to disable each item.
+开单前检查的组合敞口上限和每日亏损限制，敞口比例相对账户权益，各项为0时不启用。
+*/
+type RiskConfig struct {
+	MaxGross    float64 `yaml:"max_gross,omitempty" mapstructure:"max_gross"`       // max sum of abs notional of all positions 所有持仓名义价值绝对值之和上限
+	MaxNet      float64 `yaml:"max_net,omitempty" mapstructure:"max_net"`           // max abs of long minus short notional 多空名义价值之差的绝对值上限
+	MaxCurrency float64 `yaml:"max_currency,omitempty" mapstructure:"max_currency"` // max abs net notional of each base/quote currency except stake currencies 除stake_currency外每个币种的净名义价值绝对值上限
+	MaxCluster  float64 `yaml:"max_cluster,omitempty" mapstructure:"max_cluster"`   // max abs net notional of pairs correlated with the entering pair 与开单标的相关的标的净名义价值绝对值上限
+	CorrMin     float64 `yaml:"corr_min,omitempty" mapstructure:"corr_min"`         // pairs with correlation >= this are in one cluster, default 0.7 相关系数不低于此值的标的为同一簇，默认0.7
+	CorrTF      string  `yaml:"corr_tf,omitempty" mapstructure:"corr_tf"`           // timeframe for correlation, default 1h 计算相关性的周期，默认1h
+	CorrBack    int     `yaml:"corr_back,omitempty" mapstructure:"corr_back"`       // bars for correlation, default 100 计算相关性的bar数，默认100
+	CorrHours   int     `yaml:"corr_hours,omitempty" mapstructure:"corr_hours"`     // hours to refresh correlation, default 24 刷新相关性的小时数，默认24
+	DailyLoss   float64 `yaml:"daily_loss,omitempty" mapstructure:"daily_loss"`     // forbid entering until next UTC day when equity drops by this rate from day start 权益较当日开始下跌此比例时，禁止开单直到UTC次日
+}
+
 type DatabaseConfig struct {
 	Url         string `yaml:"url,omitempty" mapstructure:"url"`
 	Retention   string `yaml:"retention,omitempty" mapstructure:"retention"`
 	MaxPoolSize int    `yaml:"max_pool_size,omitempty" mapstructure:"max_pool_size"`
 	AutoCreate  bool   `yaml:"auto_create" mapstructure:"auto_create"`
+	KlineStore  string `yaml:"kline_store,omitempty" mapstructure:"kline_store"` // kline store: empty for timescaledb, file K线存储：空为timescaledb，file
+	KlineDir    string `yaml:"kline_dir,omitempty" mapstructure:"kline_dir"`     // dir for file kline store, default: $BanDataDir/kstore 文件K线存储目录
 }
 
 type APIServerConfig struct {
@@ -171,6 +233,7 @@ type APIServerConfig struct {
 	Port         int           `yaml:"port" mapstructure:"port"`                               // LOCAL LISTENING PORT 本地监听端口
 	Verbosity    string        `yaml:"verbosity" mapstructure:"verbosity"`                     // Detail level 详细程度
 	JWTSecretKey string        `yaml:"jwt_secret_key,omitempty" mapstructure:"jwt_secret_key"` // Key used for password encryption 用于密码加密的密钥
+	MetricsToken string        `yaml:"metrics_token,omitempty" mapstructure:"metrics_token"`   // Bearer token for /metrics, empty for no check /metrics的Bearer令牌，为空不校验
 	CORSOrigins  []string      `yaml:"CORS_origins,flow" mapstructure:"CORS_origins"`          // When accessing banweb, you need to add the address of banweb here to allow access. banweb访问时，要这里添加banweb的地址放行
 	Users        []*UserConfig `yaml:"users" mapstructure:"users"`                             // Login user 登录用户
 }
@@ -195,11 +258,14 @@ type WeWorkChannel struct {
 }
 
 type TelegramChannel struct {
-	Enable   bool     `yaml:"enable" mapstructure:"enable"`
-	Type     string   `yaml:"type" mapstructure:"type"`
-	MsgTypes []string `yaml:"msg_types,flow" mapstructure:"msg_types"`
-	Token    string   `yaml:"token" mapstructure:"token"`
-	Channel  string   `yaml:"channel" mapstructure:"channel"`
+	Enable     bool     `yaml:"enable" mapstructure:"enable"`
+	Type       string   `yaml:"type" mapstructure:"type"`
+	MsgTypes   []string `yaml:"msg_types,flow" mapstructure:"msg_types"`
+	Token      string   `yaml:"token" mapstructure:"token"`
+	ChatID     string   `yaml:"chat_id" mapstructure:"chat_id"`
+	AllowChats []string `yaml:"allow_chats,flow" mapstructure:"allow_chats"` // chats allowed to send commands 允许发送命令的聊天
+	APIURL     string   `yaml:"api_url,omitempty" mapstructure:"api_url"`
+	Account    string   `yaml:"account,omitempty" mapstructure:"account"` // account for commands 命令操作的账户
 }
 
 /** ********************************** Symbol FILTER标的筛选器  ******************************** */
@@ -239,6 +305,7 @@ type AccountConfig struct {
 	Leverage      float64                   `yaml:"leverage,omitempty" mapstructure:"leverage"`
 	MaxPair       int                       `yaml:"max_pair,omitempty" mapstructure:"max_pair"`
 	MaxOpenOrders int                       `yaml:"max_open_orders,omitempty" mapstructure:"max_open_orders"`
+	Risk          *RiskConfig               `yaml:"risk,omitempty" mapstructure:"risk"` // override root risk 覆盖根risk配置
 	RPCChannels   []map[string]interface{}  `yaml:"rpc_channels,omitempty" mapstructure:"rpc_channels"`
 	APIServer     *AccPwdRole               `yaml:"api_server,omitempty" mapstructure:"api_server"`
 	Exchanges     map[string]*ExgApiSecrets `yaml:",inline" mapstructure:",remain"`
@@ -286,4 +353,6 @@ type ExportConfig struct {
 	Klines     []*MarketTFSymbolsRange `yaml:"klines"`
 	AdjFactors []*MarketSymbolsRange   `yaml:"adj_factors"`
 	Calendars  []*MarketRange          `yaml:"calendars"`
+	// FundingRates funding rate history of perpetual contracts 永续合约的资金费率历史
+	FundingRates []*MarketSymbolsRange `yaml:"funding_rates"`
 }
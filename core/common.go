@@ -67,11 +67,11 @@ func (p *JobPerf) GetAmount(amount float64) float64 {
 	return amount * p.Score
 }
 
-func GetPerfSta(stagy string) *PerfSta {
-	p, ok := StratPerfSta[stagy]
+func (c *BotCtx) GetPerfSta(stagy string) *PerfSta {
+	p, ok := c.StratPerfSta[stagy]
 	if !ok || p == nil {
 		p = &PerfSta{}
-		StratPerfSta[stagy] = p
+		c.StratPerfSta[stagy] = p
 	}
 	return p
 }
@@ -96,9 +96,9 @@ func (p *PerfSta) Log2(profit float64) float64 {
 	return logPft
 }
 
-func DumpPerfs(outDir string) {
+func (c *BotCtx) DumpPerfs(outDir string) {
 	perfs := make(map[string]map[string]string)
-	for key, pf := range JobPerfs {
+	for key, pf := range c.JobPerfs {
 		parts := strings.Split(key, "_")
 		data, ok := perfs[parts[0]]
 		if !ok {
@@ -109,7 +109,7 @@ func DumpPerfs(outDir string) {
 		data[cacheKey] = fmt.Sprintf("%v|%.5f|%.5f", pf.Num, pf.TotProfit, pf.Score)
 	}
 	res := make(map[string]interface{})
-	for name, sta := range StratPerfSta {
+	for name, sta := range c.StratPerfSta {
 		perf, _ := perfs[name]
 		res[name] = map[string]interface{}{
 			"od_num":     sta.OdNum,
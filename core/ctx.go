@@ -0,0 +1,101 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/banbox/banexg"
+	"github.com/banbox/banexg/bntp"
+)
+
+/*
+BotCtx
+State of one running bot: simulated clock, trading pairs, latest prices, order books and job performances.
+The live bot uses DefCtx, every backtest creates its own, so that multiple backtests can run in parallel in one process.
+一个运行中机器人的状态：模拟时钟、交易对、最新价格、订单簿和任务表现。
+实盘使用DefCtx，每个回测创建自己的上下文，以便一个进程中并行运行多个回测。
+*/
+type BotCtx struct {
+	CurTimeMS     int64                        // current 13-digit timestamp of backtest 回测的当前13位时间戳
+	Pairs         []string                     // All symbols, in the order after the targets are refreshed 所有的标的，按标的刷新后的顺序
+	PairsMap      map[string]bool              // All symbols(bool value means whether allow open order) 所有的标的(值表示是否允许开单)
+	BanPairsUntil map[string]int64             // symbols not allowed for trading before the specified timestamp 在指定时间戳前禁止交易的品种
+	NoEnterUntil  map[string]int64             // account: The 13-digit timestamp before the account is allowed to trade 禁止开单的截止13位时间戳
+	StgPairTfs    map[string]map[string]string // strategy:symbols:timeframe 策略: 标的: 周期
+	TFSecs        map[string]int               // All time frames involved 所有涉及的时间周期
+	BookPairs     map[string]bool              // Monitor the currency of the trading pair 监听交易对的币种
+	JobPerfs      map[string]*JobPerf          // stagy_pair_tf: JobPerf Record the billing amount ratio of the task 记录任务的开单金额比率
+	StratPerfSta  map[string]*PerfSta          // stagy: Job任务状态
+	OdBooks       map[string]*banexg.OrderBook // Cache all order books received from crawler 缓存所有从爬虫收到的订单簿
+	LastBarMs     int64                        // The end time of the last bar received, a 13-digit timestamp 上次收到bar的结束时间，13位时间戳
+	CheckWallets  bool                         // Should the wallet be updated? 当前是否应该更新钱包
+	BotRunning    bool                         // Is the robot running? 机器人是否正在运行
+	barPrices     map[string]float64           // Latest price of each coin from bar, only for backtesting etc. 来自bar的每个币的最新价格，仅用于回测等
+	prices        map[string]float64           // The latest order book price, only for real-time simulation or real trading 最新订单簿价格，仅用于实时模拟或实盘
+	lockPrices    sync.RWMutex
+	lockBarPrices sync.RWMutex
+}
+
+// DefCtx context of the live bot and commands without an own context 实盘和未创建上下文的命令使用的上下文
+var DefCtx = NewBotCtx()
+
+func NewBotCtx() *BotCtx {
+	c := &BotCtx{}
+	c.Reset()
+	return c
+}
+
+/*
+Reset
+Clear all state of the context, the clock is kept.
+清空上下文的所有状态，保留时钟。
+*/
+func (c *BotCtx) Reset() {
+	c.Pairs = nil
+	c.PairsMap = make(map[string]bool)
+	c.BanPairsUntil = make(map[string]int64)
+	c.NoEnterUntil = make(map[string]int64)
+	c.StgPairTfs = make(map[string]map[string]string)
+	c.TFSecs = make(map[string]int)
+	c.BookPairs = make(map[string]bool)
+	c.JobPerfs = make(map[string]*JobPerf)
+	c.StratPerfSta = make(map[string]*PerfSta)
+	c.OdBooks = make(map[string]*banexg.OrderBook)
+	c.LastBarMs = 0
+	c.CheckWallets = false
+	c.lockBarPrices.Lock()
+	c.barPrices = make(map[string]float64)
+	c.lockBarPrices.Unlock()
+	c.lockPrices.Lock()
+	c.prices = make(map[string]float64)
+	c.lockPrices.Unlock()
+}
+
+/*
+TimeMS
+Get the current 13-digit millisecond timestamp, the simulated one in backtest
+获取当前13位毫秒时间戳，回测时为模拟时间
+*/
+func (c *BotCtx) TimeMS() int64 {
+	if BackTestMode {
+		if c.CurTimeMS == 0 {
+			c.CurTimeMS = bntp.UTCStamp()
+		}
+		return c.CurTimeMS
+	}
+	return bntp.UTCStamp()
+}
+
+/*
+Time
+Get the current 10-digit second-level timestamp
+获取当前10位秒级时间戳
+*/
+func (c *BotCtx) Time() float64 {
+	return float64(c.TimeMS()) * 0.001
+}
+
+func (c *BotCtx) Now() *time.Time {
+	res := time.UnixMilli(c.TimeMS()).UTC()
+	return &res
+}
@@ -2,40 +2,27 @@ package core
 
 import (
 	"context"
-	"sync"
 
 	"github.com/banbox/banexg"
 	"github.com/robfig/cron/v3"
 )
 
 var (
-	RunMode       string                               // live / backtest / other
-	RunEnv        string                               // prod / test / dry_run
-	StartAt       int64                                // start timestamp(13 digits) 启动时间，13位时间戳
-	EnvReal       bool                                 // Whether to actually submit the order to the exchange(run_env:prod/test) 是否是提交到交易所真实订单模式run_env:prod/test
-	LiveMode      bool                                 // Whether real-time mode(real trade/dry run) 是否是实时模式：实盘+模拟运行
-	BackTestMode  bool                                 // 回测模式
-	TFSecs        map[string]int                       // All time frames involved 所有涉及的时间周期
-	ExgName       string                               // current exchange name 交易所名称
-	Market        string                               // current market name 当前市场
-	IsContract    bool                                 // Is the current market a contract market? 当前市场是否是合约市场, linear/inverse/option
-	CheckWallets  bool                                 // Should the wallet be updated? 当前是否应该更新钱包
-	ContractType  string                               // current contract type. 当前合约类型
-	StgPairTfs    = make(map[string]map[string]string) // strategy:symbols:timeframe 策略: 标的: 周期
-	Pairs         []string                             // All global symbols, in the order after the targets are refreshed 全局所有的标的，按标的刷新后的顺序
-	PairsMap      = make(map[string]bool)              // All global symbols(bool value means whether allow open order) 全局所有的标的(值表示是否允许开单)
-	BanPairsUntil = make(map[string]int64)             // symbols not allowed for trading before the specified timestamp 在指定时间戳前禁止交易的品种
-	NoEnterUntil  = make(map[string]int64)             // account: The 13-digit timestamp before the account is allowed to trade 禁止开单的截止13位时间戳
-	BookPairs     = make(map[string]bool)              // Monitor the currency of the trading pair 监听交易对的币种
-	PairCopiedMs  = map[string][2]int64{}              // The latest time that all targets received K lines from the crawler, as well as the waiting interval, are used to determine whether there are any that have not been received for a long time. 所有标的从爬虫收到K线的最新时间，以及等待间隔，用于判断是否有长期未收到的。
-	TfPairHits    = map[string]map[string]int{}        // tf[pair[hits]]The number of bars for each currency in each period within a period of time, used for timing output 一段时间内各周期各币种的bar数量，用于定时输出
-	JobPerfs      = make(map[string]*JobPerf)          // stagy_pair_tf: JobPerf Record the billing amount ratio of the task. If the winning rate is low, the billing amount should be reduced. 记录任务的开单金额比率，胜率低的要减少开单金额
-	StratPerfSta  = make(map[string]*PerfSta)          // stagy: Job任务状态
-	LastBarMs     int64                                // The end time of the last bar received, a 13-digit timestamp 上次收到bar的结束时间，13位时间戳
-	LastCopiedMs  int64                                // 上次收到爬虫进程推送k线的时间戳
-	OdBooks       = map[string]*banexg.OrderBook{}     // Cache all order books received from crawler 缓存所有从爬虫收到的订单簿
-	NumTaCache    = 1500                               // The number of historical values cached during indicator calculation, default 1500 指标计算时缓存的历史值数量，默认1500
-	Cron          = cron.New(cron.WithSeconds())       // Use cron to run tasks regularly 使用cron定时运行任务
+	RunMode      string                         // live / backtest / other
+	RunEnv       string                         // prod / test / dry_run
+	StartAt      int64                          // start timestamp(13 digits) 启动时间，13位时间戳
+	EnvReal      bool                           // Whether to actually submit the order to the exchange(run_env:prod/test) 是否是提交到交易所真实订单模式run_env:prod/test
+	LiveMode     bool                           // Whether real-time mode(real trade/dry run) 是否是实时模式：实盘+模拟运行
+	BackTestMode bool                           // 回测模式
+	ExgName      string                         // current exchange name 交易所名称
+	Market       string                         // current market name 当前市场
+	IsContract   bool                           // Is the current market a contract market? 当前市场是否是合约市场, linear/inverse/option
+	ContractType string                         // current contract type. 当前合约类型
+	PairCopiedMs = map[string][2]int64{}        // The latest time that all targets received K lines from the crawler, as well as the waiting interval, are used to determine whether there are any that have not been received for a long time. 所有标的从爬虫收到K线的最新时间，以及等待间隔，用于判断是否有长期未收到的。
+	TfPairHits   = map[string]map[string]int{}  // tf[pair[hits]]The number of bars for each currency in each period within a period of time, used for timing output 一段时间内各周期各币种的bar数量，用于定时输出
+	LastCopiedMs int64                          // 上次收到爬虫进程推送k线的时间戳
+	NumTaCache   = 1500                         // The number of historical values cached during indicator calculation, default 1500 指标计算时缓存的历史值数量，默认1500
+	Cron         = cron.New(cron.WithSeconds()) // Use cron to run tasks regularly 使用cron定时运行任务
 
 	ExitCalls []func() // CALLBACK TO STOP EXECUTION 停止执行的回调
 
@@ -114,13 +101,8 @@ const (
 )
 
 var (
-	barPrices     = make(map[string]float64) // Latest price of each coin from bar, only for backtesting etc. The key can be a trading pair or a coin code 来自bar的每个币的最新价格，仅用于回测等。键可以是交易对，也可以是币的code
-	prices        = make(map[string]float64) // The latest order book price of the trading pair is only used for real-time simulation or real trading. The key can be a trading pair or a coin code 交易对的最新订单簿价格，仅用于实时模拟或实盘。键可以是交易对，也可以是币的code
-	lockPrices    sync.RWMutex
-	lockBarPrices sync.RWMutex
-	Ctx           context.Context // Used to stop all goroutines at the same time 用于全部goroutine同时停止
-	StopAll       func()          // Stop all robot threads 停止全部机器人线程
-	BotRunning    bool            // Is the robot running? 机器人是否正在运行
+	Ctx     context.Context // Used to stop all goroutines at the same time 用于全部goroutine同时停止
+	StopAll func()          // Stop all robot threads 停止全部机器人线程
 )
 
 var (
@@ -158,3 +140,29 @@ var LowCostVals = map[string]int{
 	LowCostKeepBig: 1,
 	LowCostKeepAll: 2,
 }
+
+const (
+	SlipFixed  = "fixed"  // fixed bps 固定基点
+	SlipSpread = "spread" // from order book snapshots 基于订单簿快照
+	SlipSqrt   = "sqrt"   // square-root market impact 平方根市场冲击
+)
+
+var SlippageModels = map[string]bool{
+	SlipFixed:  true,
+	SlipSpread: true,
+	SlipSqrt:   true,
+}
+
+const (
+	SizerFixed = "fixed" // stake_amount/stake_pct, default 默认按单笔金额或百分比
+	SizerRisk  = "risk"  // fixed-fractional risk by stop loss distance 按止损距离固定比例风险
+	SizerATR   = "atr"   // volatility targeting by ATR 按ATR波动率目标
+	SizerKelly = "kelly" // capped fractional kelly from realized stats 按已实现统计的限额分数凯利
+)
+
+var PositionSizers = map[string]bool{
+	SizerFixed: true,
+	SizerRisk:  true,
+	SizerATR:   true,
+	SizerKelly: true,
+}
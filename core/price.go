@@ -6,27 +6,27 @@ import (
 	"strings"
 )
 
-func GetPriceSafe(symbol string) float64 {
+func (c *BotCtx) GetPriceSafe(symbol string) float64 {
 	if IsFiat(symbol) && !strings.Contains(symbol, "/") {
 		return 1
 	}
-	lockPrices.RLock()
-	price, ok := prices[symbol]
-	lockPrices.RUnlock()
+	c.lockPrices.RLock()
+	price, ok := c.prices[symbol]
+	c.lockPrices.RUnlock()
 	if ok {
 		return price
 	}
-	lockBarPrices.RLock()
-	price, ok = barPrices[symbol]
-	lockBarPrices.RUnlock()
+	c.lockBarPrices.RLock()
+	price, ok = c.barPrices[symbol]
+	c.lockBarPrices.RUnlock()
 	if ok {
 		return price
 	}
 	return -1
 }
 
-func GetPrice(symbol string) float64 {
-	price := GetPriceSafe(symbol)
+func (c *BotCtx) GetPrice(symbol string) float64 {
+	price := c.GetPriceSafe(symbol)
 	if price == -1 {
 		panic(fmt.Errorf("invalid symbol for price: %s", symbol))
 	}
@@ -41,36 +41,51 @@ func setDataPrice(data map[string]float64, pair string, price float64) {
 	}
 }
 
-func SetBarPrice(pair string, price float64) {
-	lockBarPrices.Lock()
-	setDataPrice(barPrices, pair, price)
-	lockBarPrices.Unlock()
+func (c *BotCtx) SetBarPrice(pair string, price float64) {
+	c.lockBarPrices.Lock()
+	setDataPrice(c.barPrices, pair, price)
+	c.lockBarPrices.Unlock()
 }
 
-func IsPriceEmpty() bool {
-	lockPrices.RLock()
-	lockBarPrices.RLock()
-	empty := len(prices) == 0 && len(barPrices) == 0
-	lockBarPrices.RUnlock()
-	lockPrices.RUnlock()
+func (c *BotCtx) IsPriceEmpty() bool {
+	c.lockPrices.RLock()
+	c.lockBarPrices.RLock()
+	empty := len(c.prices) == 0 && len(c.barPrices) == 0
+	c.lockBarPrices.RUnlock()
+	c.lockPrices.RUnlock()
 	return empty
 }
 
-func SetPrices(data map[string]float64) {
-	lockPrices.Lock()
+func (c *BotCtx) SetPrices(data map[string]float64) {
+	c.lockPrices.Lock()
 	for pair, price := range data {
-		prices[pair] = price
+		c.prices[pair] = price
 		base, quote, settle, _ := SplitSymbol(pair)
 		if IsFiat(quote) && (settle == "" || settle == quote) {
-			prices[base] = price
+			c.prices[base] = price
 		}
 	}
-	lockPrices.Unlock()
+	c.lockPrices.Unlock()
 }
 
-func IsMaker(pair, side string, price float64) bool {
-	curPrice := GetPrice(pair)
+/*
+IsMaker
+Whether an order at price would rest on the book. The best bid/ask of the order book is used when available,
+otherwise the latest price is used.
+订单是否会挂在订单簿上。有订单簿时使用买一卖一判断，否则使用最新价格
+*/
+func (c *BotCtx) IsMaker(pair, side string, price float64) bool {
 	isBuy := side == banexg.OdSideBuy
+	if book, _ := c.OdBooks[pair]; book != nil && book.Asks != nil && book.Bids != nil {
+		if isBuy {
+			if bestAsk, _ := book.Asks.Level(0); bestAsk > 0 {
+				return price < bestAsk
+			}
+		} else if bestBid, _ := book.Bids.Level(0); bestBid > 0 {
+			return price > bestBid
+		}
+	}
+	curPrice := c.GetPrice(pair)
 	isLow := price < curPrice
 	return isBuy == isLow
 }
@@ -44,16 +44,16 @@ func GroupByPairQuotes(items map[string][]string) string {
 
 /*
 PrintStratGroups
-print strategy+timeframe from `core.StgPairTfs`
-从core.StgPairTfs输出策略+时间周期的币种信息到控制台
+print strategy+timeframe from `StgPairTfs`
+从StgPairTfs输出策略+时间周期的币种信息到控制台
 */
-func PrintStratGroups() {
+func (c *BotCtx) PrintStratGroups() {
 	allows := make(map[string][]string)
 	disables := make(map[string][]string)
-	for stagy, pairMap := range StgPairTfs {
+	for stagy, pairMap := range c.StgPairTfs {
 		for pair, tf := range pairMap {
 			key := fmt.Sprintf("%s_%s", stagy, tf)
-			if ok, _ := PairsMap[pair]; ok {
+			if ok, _ := c.PairsMap[pair]; ok {
 				arr, _ := allows[key]
 				allows[key] = append(arr, pair)
 			} else {
@@ -80,6 +80,7 @@ SplitSymbol
 return Base，Quote，Settle，Identifier
 */
 func SplitSymbol(pair string) (string, string, string, string) {
+	pair = PairSymbol(pair)
 	if cache, ok := splitCache[pair]; ok {
 		return cache[0], cache[1], cache[2], cache[3]
 	}
@@ -104,3 +105,40 @@ func SplitSymbol(pair string) (string, string, string, string) {
 	cache, _ := splitCache[pair]
 	return cache[0], cache[1], cache[2], cache[3]
 }
+
+/*
+PairKey
+Return the key of symbol on exchange/market, which is used as the pair in jobs, envs, prices and orders.
+Symbols on the default exchange/market are kept as is, others are suffixed with `@exchange.market`, like
+`BTC/USDT@okx.spot`, so the same symbol on different exchanges/markets are different pairs.
+返回交易所/市场上品种的键，作为任务、指标环境、价格和订单中的品种。
+默认交易所/市场的品种保持不变，其他的添加`@exchange.market`后缀，如`BTC/USDT@okx.spot`，
+因此不同交易所/市场上的相同品种是不同的pair
+*/
+func PairKey(exgName, market, symbol string) string {
+	symbol = PairSymbol(symbol)
+	if exgName == "" || exgName == ExgName && market == Market {
+		return symbol
+	}
+	return symbol + "@" + exgName + "." + market
+}
+
+/*
+SplitPairKey
+Return exchange, market and symbol of pair key, the default exchange/market is used when there is no suffix.
+返回品种键的交易所、市场和品种，无后缀时为默认交易所/市场
+*/
+func SplitPairKey(key string) (string, string, string) {
+	symbol, venue, ok := strings.Cut(key, "@")
+	if !ok {
+		return ExgName, Market, key
+	}
+	exgName, market, _ := strings.Cut(venue, ".")
+	return exgName, market, symbol
+}
+
+// PairSymbol return the symbol of exchange from pair key 从品种键返回交易所的品种
+func PairSymbol(key string) string {
+	symbol, _, _ := strings.Cut(key, "@")
+	return symbol
+}
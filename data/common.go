@@ -195,7 +195,7 @@ func (j *PairTFCache) fillLacks(pair string, subTfSecs int, startMS, endMS int64
 	if err != nil {
 		return nil, err
 	}
-	exchange := exg.Default
+	exchange := exg.GetPairExg(pair)
 	if !exchange.HasApi(banexg.ApiFetchOHLCV, exs.Market) {
 		// Downloading K lines is currently not allowed, skip
 		// 当前不允许下载K线，跳过
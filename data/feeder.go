@@ -3,7 +3,6 @@ package data
 import (
 	"context"
 	"fmt"
-	"github.com/banbox/banbot/btime"
 	"github.com/banbox/banbot/config"
 	"github.com/banbox/banbot/core"
 	"github.com/banbox/banbot/exg"
@@ -54,6 +53,7 @@ LiveFeeder requires preheating for both new trading pairs and new cycles; HistFe
 */
 type Feeder struct {
 	*orm.ExSymbol
+	ctx      *strat.Ctx
 	States   []*PairTFCache
 	WaitBar  *banexg.Kline
 	CallBack FnPairKline
@@ -68,8 +68,9 @@ func (f *Feeder) getStates() []*PairTFCache {
 	return f.States
 }
 
+// getSymbol return the pair key, see core.PairKey 返回品种键，见core.PairKey
 func (f *Feeder) getSymbol() string {
-	return f.Symbol
+	return f.PairKey()
 }
 
 func (f *Feeder) getWaitBar() *banexg.Kline {
@@ -231,10 +232,10 @@ func (f *Feeder) addTfKlines(tf string, bars []*banexg.Kline) {
 
 func (f *Feeder) fireCallBacks(timeFrame string, tfMSecs int64, bars []*banexg.Kline, adj *orm.AdjInfo) {
 	isLive := core.LiveMode
-	pair := f.Symbol
+	pair := f.PairKey()
 	for _, bar := range bars {
 		if !isLive {
-			btime.CurTimeMS = bar.Time + tfMSecs
+			f.ctx.CurTimeMS = bar.Time + tfMSecs
 		}
 		f.CallBack(&orm.InfoKline{
 			PairTFKline: &banexg.PairTFKline{Kline: *bar, Symbol: pair, TimeFrame: timeFrame},
@@ -253,7 +254,7 @@ func (f *Feeder) fireCallBacks(timeFrame string, tfMSecs int64, bars []*banexg.K
 		hits[pair] = num + len(bars)
 		// 检查是否延迟
 		lastTime := bars[len(bars)-1].Time
-		delay := btime.TimeMS() - (lastTime + tfMSecs)
+		delay := f.ctx.TimeMS() - (lastTime + tfMSecs)
 		if delay > tfMSecs && tfMSecs >= 60000 {
 			barNum := delay / tfMSecs
 			log.Warn(fmt.Sprintf("%s/%s bar too late, delay %v bars, %v", pair, timeFrame, barNum, lastTime))
@@ -306,7 +307,7 @@ type KlineFeeder struct {
 	showLog  bool
 }
 
-func NewKlineFeeder(exs *orm.ExSymbol, callBack FnPairKline, showLog bool) (*KlineFeeder, *errs.Error) {
+func NewKlineFeeder(ctx *strat.Ctx, exs *orm.ExSymbol, callBack FnPairKline, showLog bool) (*KlineFeeder, *errs.Error) {
 	sess, conn, err := orm.Conn(nil)
 	if err != nil {
 		return nil, err
@@ -319,6 +320,7 @@ func NewKlineFeeder(exs *orm.ExSymbol, callBack FnPairKline, showLog bool) (*Kli
 	return &KlineFeeder{
 		Feeder: Feeder{
 			ExSymbol: exs,
+			ctx:      ctx,
 			CallBack: callBack,
 			tfBars:   make(map[string][]*banexg.Kline),
 			adjs:     adjs,
@@ -350,11 +352,11 @@ func (f *KlineFeeder) WarmTfs(curMS int64, tfNums map[string]int, pBar *utils.Pr
 			return 0, nil, err
 		}
 		if len(bars) == 0 && f.showLog {
-			skips[fmt.Sprintf("%s_%s", f.Symbol, tf)] = [2]int{warmNum, 0}
+			skips[fmt.Sprintf("%s_%s", f.PairKey(), tf)] = [2]int{warmNum, 0}
 			continue
 		}
 		if warmNum != len(bars) && f.showLog {
-			skips[fmt.Sprintf("%s_%s", f.Symbol, tf)] = [2]int{warmNum, len(bars)}
+			skips[fmt.Sprintf("%s_%s", f.PairKey(), tf)] = [2]int{warmNum, len(bars)}
 		}
 		curEnd := f.warmTf(tf, bars)
 		maxEndMs = max(maxEndMs, curEnd)
@@ -386,8 +388,8 @@ func (f *KlineFeeder) warmTf(tf string, bars []*banexg.Kline) int64 {
 	f.isWarmUp = true
 	tfMSecs := int64(utils2.TFToSecs(tf) * 1000)
 	lastMS := bars[len(bars)-1].Time + tfMSecs
-	envKey := strings.Join([]string{f.Symbol, tf}, "_")
-	if env, ok := strat.Envs[envKey]; ok {
+	envKey := strings.Join([]string{f.PairKey(), tf}, "_")
+	if env, ok := f.ctx.Envs[envKey]; ok {
 		env.Reset()
 	}
 	if len(f.adjs) > 0 {
@@ -590,7 +592,7 @@ type DBKlineFeeder struct {
 	offsetMS int64
 }
 
-func NewDBKlineFeeder(exs *orm.ExSymbol, callBack FnPairKline, showLog bool) (*DBKlineFeeder, *errs.Error) {
+func NewDBKlineFeeder(ctx *strat.Ctx, exs *orm.ExSymbol, callBack FnPairKline, showLog bool) (*DBKlineFeeder, *errs.Error) {
 	exchange, err := exg.GetWith(exs.Exchange, exs.Market, "")
 	if err != nil {
 		return nil, err
@@ -600,14 +602,14 @@ func NewDBKlineFeeder(exs *orm.ExSymbol, callBack FnPairKline, showLog bool) (*D
 	if err == nil {
 		tradeTimes = market.GetTradeTimes()
 	}
-	feeder, err := NewKlineFeeder(exs, callBack, showLog)
+	feeder, err := NewKlineFeeder(ctx, exs, callBack, showLog)
 	if err != nil {
 		return nil, err
 	}
 	res := &DBKlineFeeder{
 		HistKLineFeeder: HistKLineFeeder{
 			KlineFeeder: *feeder,
-			TimeRange:   config.TimeRange.Clone(),
+			TimeRange:   ctx.TimeRange().Clone(),
 			TradeTimes:  tradeTimes,
 		},
 	}
@@ -657,7 +659,7 @@ func (f *DBKlineFeeder) DownIfNeed(sess *orm.Queries, exchange banexg.BanExchang
 		}
 		defer conn.Release()
 	}
-	_, err = sess.DownOHLCV2DB(exchange, f.ExSymbol, downTf, btime.TimeMS(), f.TimeRange.EndMS, pBar)
+	_, err = sess.DownOHLCV2DB(exchange, f.ExSymbol, downTf, f.ctx.TimeMS(), f.TimeRange.EndMS, pBar)
 	return err
 }
 
@@ -683,7 +685,7 @@ func makeSetNext(f *DBKlineFeeder) func() {
 				old := f.caches[f.rowIdx-1]
 				tf := f.States[0].TimeFrame
 				f.OnEnvEnd(&banexg.PairTFKline{
-					Symbol:    f.Symbol,
+					Symbol:    f.PairKey(),
 					TimeFrame: tf,
 					Kline:     *old,
 				}, f.adj)
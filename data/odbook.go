@@ -0,0 +1,335 @@
+package data
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banexg"
+	"github.com/banbox/banexg/errs"
+	"github.com/banbox/banexg/log"
+	utils2 "github.com/banbox/banexg/utils"
+	"go.uber.org/zap"
+)
+
+/*
+Order book snapshots are stored in `<dir>/<exchange>_<market>/<symbol>/<yyyyMMdd>.bin`, each record is:
+uvarint timestamp, uvarint bid levels, uvarint ask levels, then float64 price,size pairs (bids desc, asks asc)
+订单簿快照存储在`<dir>/<exchange>_<market>/<symbol>/<yyyyMMdd>.bin`，每条记录为：
+uvarint时间戳，uvarint买盘档数，uvarint卖盘档数，然后是float64价格,数量对（买盘降序，卖盘升序）
+*/
+
+const (
+	odBookDayMSecs = int64(86400000)
+)
+
+var (
+	bookRec *OdBookRecorder
+)
+
+/*
+GetOdBookPath
+Get the snapshot file path of symbol for the day of timeMS
+获取symbol在timeMS所在日期的快照文件路径
+*/
+func GetOdBookPath(dir, exgName, market, symbol string, timeMS int64) string {
+	clean := strings.ReplaceAll(strings.ReplaceAll(symbol, "/", "_"), ":", "_")
+	dayStr := time.UnixMilli(timeMS).UTC().Format("20060102")
+	return filepath.Join(dir, fmt.Sprintf("%s_%s", exgName, market), clean, dayStr+".bin")
+}
+
+/*
+WriteOdBook
+Encode at most depth levels of each side of book to w
+将book每侧最多depth档编码写入w
+*/
+func WriteOdBook(w io.Writer, book *banexg.OrderBook, depth int) error {
+	bids := snapBookSide(book.Bids, depth)
+	asks := snapBookSide(book.Asks, depth)
+	buf := make([]byte, 0, binary.MaxVarintLen64*3+(len(bids)+len(asks))*8)
+	buf = binary.AppendUvarint(buf, uint64(book.TimeStamp))
+	buf = binary.AppendUvarint(buf, uint64(len(bids)/2))
+	buf = binary.AppendUvarint(buf, uint64(len(asks)/2))
+	for _, v := range bids {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+	}
+	for _, v := range asks {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func snapBookSide(side *banexg.OdBookSide, depth int) []float64 {
+	if side == nil {
+		return nil
+	}
+	side.Lock.Lock()
+	defer side.Lock.Unlock()
+	num := min(len(side.Price), len(side.Size))
+	if depth > 0 {
+		num = min(num, depth)
+	}
+	res := make([]float64, 0, num*2)
+	for i := 0; i < num; i++ {
+		res = append(res, side.Price[i], side.Size[i])
+	}
+	return res
+}
+
+/*
+ReadOdBook
+Decode the next snapshot from r, returns io.EOF when no more records
+从r解码下一个快照，没有更多记录时返回io.EOF
+*/
+func ReadOdBook(r *bufio.Reader, symbol string) (*banexg.OrderBook, error) {
+	timeMS, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	bidNum, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	askNum, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	readSide := func(num uint64) ([][2]float64, error) {
+		res := make([][2]float64, num)
+		var b [16]byte
+		for i := range res {
+			if _, err := io.ReadFull(r, b[:]); err != nil {
+				return nil, io.ErrUnexpectedEOF
+			}
+			res[i][0] = math.Float64frombits(binary.LittleEndian.Uint64(b[:8]))
+			res[i][1] = math.Float64frombits(binary.LittleEndian.Uint64(b[8:]))
+		}
+		return res, nil
+	}
+	bids, err := readSide(bidNum)
+	if err != nil {
+		return nil, err
+	}
+	asks, err := readSide(askNum)
+	if err != nil {
+		return nil, err
+	}
+	depth := int(max(bidNum, askNum))
+	return &banexg.OrderBook{
+		Symbol:    symbol,
+		TimeStamp: int64(timeMS),
+		Bids:      banexg.NewOdBookSide(true, depth, bids),
+		Asks:      banexg.NewOdBookSide(false, depth, asks),
+		Limit:     depth,
+	}, nil
+}
+
+/*
+OdBookRecorder
+Persist periodic order book snapshots per symbol/day in spider
+在爬虫中按品种/天持久化定期的订单簿快照
+*/
+type OdBookRecorder struct {
+	Dir    string
+	IntvMS int64
+	Depth  int
+	lastMS map[string]int64
+	files  map[string]*os.File // opened file of current day for each path key 每个品种当天打开的文件
+	lock   sync.Mutex
+}
+
+func NewOdBookRecorder(cfg *config.OdBookStoreConfig) *OdBookRecorder {
+	if cfg == nil || cfg.Dir == "" {
+		return nil
+	}
+	return &OdBookRecorder{
+		Dir:    cfg.Dir,
+		IntvMS: cfg.Interval,
+		Depth:  cfg.Depth,
+		lastMS: make(map[string]int64),
+		files:  make(map[string]*os.File),
+	}
+}
+
+/*
+Add
+Save the snapshot of book if the interval since last snapshot is reached
+距离上次快照达到间隔时保存book的快照
+*/
+func (r *OdBookRecorder) Add(exgName, market string, book *banexg.OrderBook) *errs.Error {
+	if book == nil || book.Symbol == "" || book.TimeStamp <= 0 {
+		return nil
+	}
+	key := fmt.Sprintf("%s_%s_%s", exgName, market, book.Symbol)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	lastMS, _ := r.lastMS[key]
+	if book.TimeStamp-lastMS < r.IntvMS {
+		return nil
+	}
+	r.lastMS[key] = book.TimeStamp
+	path := GetOdBookPath(r.Dir, exgName, market, book.Symbol, book.TimeStamp)
+	file, ok := r.files[key]
+	if !ok || file.Name() != path {
+		if file != nil {
+			_ = file.Close()
+		}
+		delete(r.files, key)
+		err_ := os.MkdirAll(filepath.Dir(path), 0755)
+		if err_ != nil {
+			return errs.New(errs.CodeIOWriteFail, err_)
+		}
+		file, err_ = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err_ != nil {
+			return errs.New(errs.CodeIOWriteFail, err_)
+		}
+		r.files[key] = file
+	}
+	err_ := WriteOdBook(file, book, r.Depth)
+	if err_ != nil {
+		return errs.New(errs.CodeIOWriteFail, err_)
+	}
+	return nil
+}
+
+func (r *OdBookRecorder) Close() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for key, file := range r.files {
+		_ = file.Close()
+		delete(r.files, key)
+	}
+}
+
+/*
+OdBookReplayer
+Replay stored order book snapshots into OdBooks of the context in sync with its clock in backtest
+回测时根据上下文的时钟将存储的订单簿快照回放到上下文的OdBooks
+*/
+type OdBookReplayer struct {
+	ctx     *core.BotCtx
+	Dir     string
+	ExgName string
+	Market  string
+	states  map[string]*bookReplayState
+}
+
+type bookReplayState struct {
+	dayMS int64 // start time of the opened day file 打开的日文件的开始时间
+	file  *os.File
+	rd    *bufio.Reader
+	cur   *banexg.OrderBook
+	next  *banexg.OrderBook
+}
+
+/*
+NewOdBookReplayer
+Returns nil when the order book store is not configured
+未配置订单簿存储时返回nil
+*/
+func NewOdBookReplayer(c *core.BotCtx) *OdBookReplayer {
+	cfg := config.OdBookStore
+	if cfg == nil || cfg.Dir == "" {
+		return nil
+	}
+	return &OdBookReplayer{
+		ctx:     c,
+		Dir:     cfg.Dir,
+		ExgName: core.ExgName,
+		Market:  core.Market,
+		states:  make(map[string]*bookReplayState),
+	}
+}
+
+/*
+Sync
+Set the latest snapshot not later than curMS to OdBooks of the context for the pair watching order books
+为订阅订单簿的pair设置不晚于curMS的最新快照到上下文的OdBooks
+*/
+func (r *OdBookReplayer) Sync(pair string, curMS int64) {
+	if _, ok := r.ctx.BookPairs[pair]; !ok {
+		return
+	}
+	sta, ok := r.states[pair]
+	if !ok {
+		sta = &bookReplayState{}
+		r.states[pair] = sta
+	}
+	for {
+		for sta.next != nil && sta.next.TimeStamp <= curMS {
+			sta.cur = sta.next
+			sta.next = r.readNext(pair, sta)
+		}
+		if sta.next != nil {
+			break
+		}
+		// current day is exhausted, open the day file of curMS
+		// 当天文件已读完，打开curMS所在日期的文件
+		dayMS := utils2.AlignTfMSecs(curMS, odBookDayMSecs)
+		if dayMS <= sta.dayMS {
+			break
+		}
+		r.openDay(pair, sta, dayMS)
+	}
+	if sta.cur != nil {
+		r.ctx.OdBooks[pair] = sta.cur
+	}
+}
+
+func (r *OdBookReplayer) openDay(pair string, sta *bookReplayState, dayMS int64) {
+	if sta.file != nil {
+		_ = sta.file.Close()
+		sta.file, sta.rd = nil, nil
+	}
+	sta.dayMS = dayMS
+	exgName, market, symbol := r.ExgName, r.Market, pair
+	if strings.Contains(pair, "@") {
+		exgName, market, symbol = core.SplitPairKey(pair)
+	}
+	path := GetOdBookPath(r.Dir, exgName, market, symbol, dayMS)
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn("open odBook snapshots fail", zap.String("path", path), zap.Error(err))
+		}
+		return
+	}
+	sta.file = file
+	sta.rd = bufio.NewReader(file)
+	sta.next = r.readNext(pair, sta)
+}
+
+func (r *OdBookReplayer) readNext(pair string, sta *bookReplayState) *banexg.OrderBook {
+	if sta.rd == nil {
+		return nil
+	}
+	book, err := ReadOdBook(sta.rd, pair)
+	if err != nil {
+		if err != io.EOF {
+			log.Warn("read odBook snapshot fail", zap.String("pair", pair), zap.Error(err))
+		}
+		_ = sta.file.Close()
+		sta.file, sta.rd = nil, nil
+		return nil
+	}
+	return book
+}
+
+func (r *OdBookReplayer) Close() {
+	for _, sta := range r.states {
+		if sta.file != nil {
+			_ = sta.file.Close()
+		}
+	}
+	r.states = make(map[string]*bookReplayState)
+}
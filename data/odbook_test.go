@@ -0,0 +1,60 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banexg"
+)
+
+func TestOdBookRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.OdBookStoreConfig{Dir: dir, Interval: 1000, Depth: 2}
+	rec := NewOdBookRecorder(cfg)
+	pair := "BTC/USDT:USDT"
+	dayMS := int64(1700006400000)
+	makeBook := func(timeMS int64, bid float64) *banexg.OrderBook {
+		return &banexg.OrderBook{
+			Symbol:    pair,
+			TimeStamp: timeMS,
+			Bids:      banexg.NewOdBookSide(true, 5, [][2]float64{{bid, 1}, {bid - 1, 2}, {bid - 2, 3}}),
+			Asks:      banexg.NewOdBookSide(false, 5, [][2]float64{{bid + 1, 1}, {bid + 2, 2}}),
+		}
+	}
+	for i, timeMS := range []int64{dayMS - 1000, dayMS, dayMS + 500, dayMS + 2000} {
+		if err := rec.Add("binance", "linear", makeBook(timeMS, float64(100+i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec.Close()
+
+	oldStore := config.OdBookStore
+	defer func() {
+		config.OdBookStore = oldStore
+	}()
+	config.OdBookStore = cfg
+	c := core.NewBotCtx()
+	c.BookPairs = map[string]bool{pair: true}
+	books := NewOdBookReplayer(c)
+	books.ExgName, books.Market = "binance", "linear"
+	defer books.Close()
+
+	books.Sync(pair, dayMS-500)
+	if bid, _ := c.OdBooks[pair].Bids.Level(0); bid != 100 {
+		t.Fatalf("expect bid 100, got %v", bid)
+	}
+	// the snapshot at dayMS+500 is skipped by the interval
+	books.Sync(pair, dayMS+1500)
+	book := c.OdBooks[pair]
+	if bid, _ := book.Bids.Level(0); bid != 101 || len(book.Bids.Price) != 2 {
+		t.Fatalf("expect bid 101 with 2 levels, got %v %v", bid, book.Bids.Price)
+	}
+	books.Sync(pair, dayMS+3000)
+	if ask, _ := c.OdBooks[pair].Asks.Level(0); ask != 104 {
+		t.Fatalf("expect ask 104, got %v", ask)
+	}
+	if !c.IsMaker(pair, banexg.OdSideBuy, 103.5) || c.IsMaker(pair, banexg.OdSideBuy, 104) {
+		t.Error("IsMaker should use best ask of the replayed book")
+	}
+}
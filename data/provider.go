@@ -6,11 +6,11 @@ import (
 	"sort"
 	"sync"
 
-	"github.com/banbox/banbot/btime"
 	"github.com/banbox/banbot/config"
 	"github.com/banbox/banbot/core"
 	"github.com/banbox/banbot/exg"
 	"github.com/banbox/banbot/orm"
+	"github.com/banbox/banbot/strat"
 	"github.com/banbox/banbot/utils"
 	"github.com/banbox/banexg"
 	"github.com/banbox/banexg/errs"
@@ -26,6 +26,7 @@ type IProvider interface {
 }
 
 type Provider[T IKlineFeeder] struct {
+	ctx       *strat.Ctx
 	holders   map[string]T
 	newFeeder func(pair string, tfs []string) (T, *errs.Error)
 	dirtyVers chan int
@@ -136,7 +137,7 @@ func (p *Provider[IKlineFeeder]) warmJobs(warmJobs []*WarmJob, pb *utils.StagedP
 		}
 	}
 	skipWarms := make(map[string][2]int)
-	startTime := btime.TimeMS()
+	startTime := p.ctx.TimeMS()
 	retErr := utils.ParallelRun(warmJobs, core.ConcurNum, func(_ int, job *WarmJob) *errs.Error {
 		hold := job.hold
 		if job.timeMS == 0 {
@@ -162,18 +163,55 @@ type HistProvider struct {
 	getEnd    FnGetInt64
 	maxTfSecs int
 	pBar      *utils.StagedPrg
+	books     *OdBookReplayer // replay stored order books, nil if not configured 回放已存储的订单簿，未配置为nil
 }
 
-func NewHistProvider(callBack FnPairKline, envEnd FuncEnvEnd, getEnd FnGetInt64, showLog bool, pBar *utils.StagedPrg) *HistProvider {
+/*
+NewHistProvider
+The clock, time range and bar envs of ctx are used, so multiple providers with different contexts can run in parallel.
+onTrades is optional, trade ticks are replayed for symbols found in config.BTTickDir when provided.
+Stored order books in config.OdBookStore are replayed to OdBooks of ctx before bars and trades are fed.
+使用ctx的时钟、时间范围和K线环境，不同上下文的多个提供者可并行运行。
+onTrades可选，提供时对config.BTTickDir中找到的品种回放逐笔成交。
+config.OdBookStore中存储的订单簿在推送K线和成交前回放到ctx的OdBooks。
+*/
+func NewHistProvider(ctx *strat.Ctx, callBack FnPairKline, onTrades FnPairTrades, envEnd FuncEnvEnd, getEnd FnGetInt64, showLog bool, pBar *utils.StagedPrg) *HistProvider {
+	books := NewOdBookReplayer(ctx.BotCtx)
+	if books != nil {
+		barCb := callBack
+		callBack = func(bar *orm.InfoKline) {
+			books.Sync(bar.Symbol, ctx.TimeMS())
+			barCb(bar)
+		}
+		if onTrades != nil {
+			tradeCb := onTrades
+			onTrades = func(pair string, trades []*banexg.Trade) {
+				books.Sync(pair, ctx.TimeMS())
+				tradeCb(pair, trades)
+			}
+		}
+	}
 	return &HistProvider{
 		Provider: Provider[IHistKlineFeeder]{
+			ctx:     ctx,
 			holders: make(map[string]IHistKlineFeeder),
 			newFeeder: func(pair string, tfs []string) (IHistKlineFeeder, *errs.Error) {
 				exs, err := orm.GetExSymbolCur(pair)
 				if err != nil {
 					return nil, err
 				}
-				feeder, err := NewDBKlineFeeder(exs, callBack, showLog)
+				if onTrades != nil && config.BTTickDir != "" {
+					tickFeeder, err := NewTickFeeder(ctx, exs, callBack, onTrades, showLog)
+					if err != nil {
+						return nil, err
+					}
+					if tickFeeder != nil {
+						tickFeeder.OnEnvEnd = envEnd
+						tickFeeder.SubTfs(tfs, false)
+						return tickFeeder, nil
+					}
+				}
+				feeder, err := NewDBKlineFeeder(ctx, exs, callBack, showLog)
 				if err != nil {
 					return nil, err
 				}
@@ -186,12 +224,13 @@ func NewHistProvider(callBack FnPairKline, envEnd FuncEnvEnd, getEnd FnGetInt64,
 		},
 		getEnd: getEnd,
 		pBar:   pBar,
+		books:  books,
 	}
 }
 
 func (p *HistProvider) downIfNeed() *errs.Error {
 	exchange := exg.Default
-	if !exchange.HasApi(banexg.ApiFetchOHLCV, core.Market) {
+	if !exchange.HasApi(banexg.ApiFetchOHLCV, core.Market) && !exg.IsMultiMarket() {
 		return nil
 	}
 	var err *errs.Error
@@ -209,6 +248,10 @@ func (p *HistProvider) downIfNeed() *errs.Error {
 		})
 	}
 	for _, h := range p.holders {
+		exchange = exg.GetPairExg(h.getSymbol())
+		if !exchange.HasApi(banexg.ApiFetchOHLCV, exchange.Info().MarketType) {
+			continue
+		}
 		err = h.DownIfNeed(sess, exchange, pBar)
 		if err != nil {
 			log.Error("download ohlcv fail", zap.String("pair", h.getSymbol()), zap.Error(err))
@@ -228,7 +271,7 @@ func (p *HistProvider) SubWarmPairs(items map[string]map[string]int, delOther bo
 	}
 	maxSince := int64(0)
 	holders := make(map[string]IHistKlineFeeder)
-	defSince := btime.TimeMS()
+	defSince := p.ctx.TimeMS()
 	for pair, since := range sinceMap {
 		hold, ok := p.holders[pair]
 		if !ok {
@@ -264,11 +307,11 @@ func (p *HistProvider) SubWarmPairs(items map[string]map[string]int, delOther bo
 	// Delete items that are not warmed up
 	// 删除未预热的项
 	p.holders = holders
-	btime.CurTimeMS = maxSince
+	p.ctx.CurTimeMS = maxSince
 	if p.getEnd != nil {
 		// 结束时间推迟3个bar，以便触发下次品种刷新
 		endMs := p.getEnd() + int64(p.maxTfSecs*1000*3)
-		endMs = min(endMs, config.TimeRange.EndMS)
+		endMs = min(endMs, p.ctx.TimeRange().EndMS)
 		for _, h := range holders {
 			h.SetEndMS(endMs)
 		}
@@ -288,7 +331,8 @@ func (p *HistProvider) LoopMain() *errs.Error {
 	makeFeeders := func() []IHistKlineFeeder {
 		return utils.ValsOfMap(p.holders)
 	}
-	totalMS := (config.TimeRange.EndMS - config.TimeRange.StartMS) / 1000
+	timeRange := p.ctx.TimeRange()
+	totalMS := (timeRange.EndMS - timeRange.StartMS) / 1000
 	var pBar = utils.NewPrgBar(int(totalMS), "RunHist")
 	if p.pBar != nil {
 		pBar.PrgCbs = append(pBar.PrgCbs, func(done int, total int) {
@@ -296,17 +340,14 @@ func (p *HistProvider) LoopMain() *errs.Error {
 		})
 	}
 	defer pBar.Close()
-	pBar.Last = config.TimeRange.StartMS
+	pBar.Last = timeRange.StartMS
 	if p.showLog {
 		log.Info("run data loop for backtest..")
 	}
-	coreStop := core.StopAll
-	core.StopAll = func() {
-		p.Terminate()
-		coreStop()
+	err := RunHistFeeders(p.ctx, makeFeeders, p.dirtyVers, pBar)
+	if p.books != nil {
+		p.books.Close()
 	}
-	err := RunHistFeeders(makeFeeders, p.dirtyVers, pBar)
-	core.StopAll = coreStop
 	if p.pBar != nil {
 		p.pBar.SetProgress("runBT", 1)
 	}
@@ -318,13 +359,13 @@ func (p *HistProvider) Terminate() {
 }
 
 /*
-RunHistFeeders run hist feeders for historical data
+RunHistFeeders run hist feeders for historical data, the clock of ctx is used for progress
 
 versions: When an integer greater than the previous value is received, makeFeeders will be called to re-acquire and continue running; when a negative number is received, exit immediately
 
 pBar: optional, used to display a progress bar
 */
-func RunHistFeeders(makeFeeders func() []IHistKlineFeeder, versions chan int, pBar *utils.PrgBar) *errs.Error {
+func RunHistFeeders(ctx *strat.Ctx, makeFeeders func() []IHistKlineFeeder, versions chan int, pBar *utils.PrgBar) *errs.Error {
 	var hold IHistKlineFeeder
 	var lastBarMs int64
 	var oldVer int
@@ -358,7 +399,7 @@ func RunHistFeeders(makeFeeders func() []IHistKlineFeeder, versions chan int, pB
 		if bar.Time > lastBarMs {
 			// 更新进度条
 			if pBar != nil {
-				curMS := btime.TimeMS()
+				curMS := ctx.TimeMS()
 				if pBar.Last == 0 {
 					pBar.Last = curMS
 				} else if curMS > pBar.Last {
@@ -411,6 +452,7 @@ func SortFeeders(holds []IHistKlineFeeder, hold IHistKlineFeeder, insert bool) [
 type LiveProvider struct {
 	Provider[IKlineFeeder]
 	*KLineWatcher
+	priceKeys map[string]bool // subscribed price keys of exchange/markets 已订阅价格的交易所/市场
 }
 
 func NewLiveProvider(callBack FnPairKline, envEnd FuncEnvEnd) (*LiveProvider, *errs.Error) {
@@ -420,13 +462,14 @@ func NewLiveProvider(callBack FnPairKline, envEnd FuncEnvEnd) (*LiveProvider, *e
 	}
 	provider := &LiveProvider{
 		Provider: Provider[IKlineFeeder]{
+			ctx:     strat.DefCtx,
 			holders: make(map[string]IKlineFeeder),
 			newFeeder: func(pair string, tfs []string) (IKlineFeeder, *errs.Error) {
-				exs, err := orm.GetExSymbol(exg.Default, pair)
+				exs, err := orm.GetExSymbolCur(pair)
 				if err != nil {
 					return nil, err
 				}
-				feeder, err := NewKlineFeeder(exs, callBack, true)
+				feeder, err := NewKlineFeeder(strat.DefCtx, exs, callBack, true)
 				if err != nil {
 					return nil, err
 				}
@@ -437,18 +480,67 @@ func NewLiveProvider(callBack FnPairKline, envEnd FuncEnvEnd) (*LiveProvider, *e
 			dirtyVers: make(chan int, 5),
 		},
 		KLineWatcher: watcher,
+		priceKeys:    make(map[string]bool),
 	}
 	watcher.OnKLineMsg = makeOnKlineMsg(provider)
 	// 立刻订阅实时价格
-	err = watcher.SendMsg("subscribe", []string{
-		fmt.Sprintf("price_%s_%s", core.ExgName, core.Market),
-	})
+	err = provider.subPrices(exg.AllExgs())
 	if err != nil {
 		return nil, err
 	}
 	return provider, nil
 }
 
+// subPrices subscribe realtime prices of exchange/markets 订阅交易所/市场的实时价格
+func (p *LiveProvider) subPrices(clients []banexg.BanExchange) *errs.Error {
+	var keys []string
+	for _, client := range clients {
+		info := client.Info()
+		key := fmt.Sprintf("price_%s_%s", info.ID, info.MarketType)
+		if !p.priceKeys[key] {
+			p.priceKeys[key] = true
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return p.SendMsg("subscribe", keys)
+}
+
+/*
+watchByExg
+Group jobs by the exchange/market which the pairs are traded on, and watch them from spider.
+按品种所在的交易所/市场分组任务，并从爬虫监听
+*/
+func (p *LiveProvider) watchByExg(jobType string, jobs []WatchJob) *errs.Error {
+	groups := make(map[banexg.BanExchange][]WatchJob)
+	for _, j := range jobs {
+		client := exg.GetPairExg(j.Symbol)
+		groups[client] = append(groups[client], j)
+	}
+	for client, items := range groups {
+		info := client.Info()
+		err := p.WatchJobs(info.ID, info.MarketType, jobType, items...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unWatchByExg group pairs by exchange/market and stop watching 按交易所/市场分组品种并停止监听
+func (p *LiveProvider) unWatchByExg(jobType string, pairs []string) *errs.Error {
+	for client, items := range exg.GroupPairs(pairs) {
+		info := client.Info()
+		err := p.UnWatchJobs(info.ID, info.MarketType, jobType, items)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *LiveProvider) SubWarmPairs(items map[string]map[string]int, delOther bool) *errs.Error {
 	newHolds, sinceMap, delPairs, err := p.Provider.SubWarmPairs(items, delOther, nil)
 	if err != nil {
@@ -466,23 +558,27 @@ func (p *LiveProvider) SubWarmPairs(items map[string]map[string]int, delOther bo
 				})
 			}
 		}
-		err = p.WatchJobs(core.ExgName, core.Market, "ohlcv", jobs...)
+		err = p.subPrices(exg.AllExgs())
+		if err != nil {
+			return err
+		}
+		err = p.watchByExg("ohlcv", jobs)
 		if err != nil {
 			return err
 		}
-		if len(core.BookPairs) > 0 {
-			jobs = make([]WatchJob, 0, len(core.BookPairs))
-			for pair := range core.BookPairs {
+		if len(p.ctx.BookPairs) > 0 {
+			jobs = make([]WatchJob, 0, len(p.ctx.BookPairs))
+			for pair := range p.ctx.BookPairs {
 				jobs = append(jobs, WatchJob{Symbol: pair, TimeFrame: "1m"})
 			}
-			err = p.WatchJobs(core.ExgName, core.Market, "book", jobs...)
+			err = p.watchByExg("book", jobs)
 			if err != nil {
 				return err
 			}
 		}
 	}
 	if len(delPairs) > 0 {
-		err = p.UnWatchJobs(core.ExgName, core.Market, "ohlcv", delPairs)
+		err = p.unWatchByExg("ohlcv", delPairs)
 		if err != nil {
 			return err
 		}
@@ -495,7 +591,7 @@ func (p *LiveProvider) UnSubPairs(pairs ...string) *errs.Error {
 	if len(removed) == 0 {
 		return nil
 	}
-	return p.UnWatchJobs(core.ExgName, core.Market, "ohlcv", pairs)
+	return p.unWatchByExg("ohlcv", pairs)
 }
 
 func (p *LiveProvider) LoopMain() *errs.Error {
@@ -504,7 +600,7 @@ func (p *LiveProvider) LoopMain() *errs.Error {
 
 func makeOnKlineMsg(p *LiveProvider) func(msg *KLineMsg) {
 	return func(msg *KLineMsg) {
-		if msg.ExgName != core.ExgName || msg.Market != core.Market {
+		if !exg.IsUsedMarket(msg.ExgName, msg.Market) {
 			return
 		}
 		hold, ok := p.holders[msg.Pair]
@@ -545,7 +641,7 @@ func makeOnKlineMsg(p *LiveProvider) func(msg *KLineMsg) {
 		}
 		// The frequency of updates is relatively low, or the proportion of the required cycle is large, and the approximate completion is considered complete
 		// 更新频率相对不高，或占需要的周期比率较大，近似完成认为完成
-		endLackSecs := int((lastBar.Time + tfMSecs - btime.TimeMS()) / 1000)
+		endLackSecs := int((lastBar.Time + tfMSecs - p.ctx.TimeMS()) / 1000)
 		if endLackSecs*2 < msg.Interval {
 			// The missing time is less than half of the update interval and is considered complete.
 			// 缺少的时间不足更新间隔的一半，认为完成。
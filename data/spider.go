@@ -3,6 +3,7 @@ package data
 import (
 	"fmt"
 	"github.com/banbox/banbot/btime"
+	"github.com/banbox/banbot/config"
 	"github.com/banbox/banbot/exg"
 	"github.com/banbox/banbot/orm"
 	"github.com/banbox/banbot/utils"
@@ -343,6 +344,12 @@ func (m *Miner) watchOdBooks(pairs []string) {
 			m.watchOdBooks(utils.KeysOfMap(m.BookPairs))
 		}()
 		for book := range out {
+			if bookRec != nil {
+				err = bookRec.Add(m.ExgName, m.Market, book)
+				if err != nil {
+					log.Error("record odBook fail", zap.String("pair", book.Symbol), zap.Error(err))
+				}
+			}
 			err = m.spider.Broadcast(&utils.IOMsg{
 				Action: prefix + book.Symbol,
 				Data:   book,
@@ -568,6 +575,7 @@ func RunSpider(addr string) *errs.Error {
 	}
 	server.InitConn = makeInitConn(Spider)
 	go consumeWriteQ(5)
+	bookRec = NewOdBookRecorder(config.OdBookStore)
 	sess, conn, err := orm.Conn(nil)
 	if err != nil {
 		return err
@@ -3,7 +3,6 @@ package data
 import (
 	"context"
 	"fmt"
-	"github.com/banbox/banbot/btime"
 	"github.com/banbox/banbot/config"
 	"github.com/banbox/banbot/core"
 	"github.com/banbox/banbot/exg"
@@ -91,7 +90,7 @@ delete from khole where sid=%v;`, sid, sid, sid, sid, sid, sid, sid, sid))
 	core.SetRunMode(core.RunModeBackTest)
 	tfMSecs := int64(utils2.TFToSecs(timeFrame) * 1000)
 	for i, bar := range arr {
-		btime.CurTimeMS = bar.Time + tfMSecs
+		core.DefCtx.CurTimeMS = bar.Time + tfMSecs
 		sess, conn, err = orm.Conn(nil)
 		if err != nil {
 			panic(err)
@@ -0,0 +1,368 @@
+package data
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/exg"
+	"github.com/banbox/banbot/orm"
+	"github.com/banbox/banbot/strat"
+	"github.com/banbox/banbot/utils"
+	"github.com/banbox/banexg"
+	"github.com/banbox/banexg/errs"
+	"github.com/banbox/banexg/log"
+	utils2 "github.com/banbox/banexg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// TickBatchMSecs Trades in the same interval are replayed together as one batch 同一区间内的逐笔成交作为一批一起回放
+	TickBatchMSecs = int64(1000)
+)
+
+type FnPairTrades = func(pair string, trades []*banexg.Trade)
+
+/*
+TickFeeder
+Replay trade ticks chronologically for backtest. Bars of subscribed timeframes are built from ticks on the fly,
+and trades are passed to OnTrades in batches of TickBatchMSecs.
+
+Tick files are zip archives under config.BTTickDir (e.g. the output of RunFormatTick), sorted by file name.
+Each archive contains csv files named by the exchange symbol ID, rows can be:
+
+	formatted tick: InstrumentID,Time,LastPrice,Volume(cumulative),BidPrice1,BidVolume1,AskPrice1,AskVolume1,...
+	plain trade: Time,Price,Amount[,Side]
+
+逐笔回放成交数据用于回测。订阅周期的K线由tick实时构建，成交按TickBatchMSecs批量传给OnTrades。
+
+	tick文件是config.BTTickDir下的zip压缩包（如RunFormatTick的输出），按文件名排序。
+	每个压缩包内的csv文件以交易所品种ID命名，行可以是：
+	格式化tick：InstrumentID,Time,LastPrice,Volume(累计),BidPrice1,BidVolume1,AskPrice1,AskVolume1,...
+	普通成交：Time,Price,Amount[,Side]
+*/
+type TickFeeder struct {
+	HistKLineFeeder
+	OnTrades FnPairTrades
+	marketID string
+	files    []string        // zip files containing ticks of this symbol 包含此品种tick的zip文件
+	fileIdx  int             // index of next file to load 下一个要加载的文件索引
+	trades   []*banexg.Trade // loaded trades of current file 当前文件已加载的成交
+	tradeIdx int
+	batch    []*banexg.Trade // trades of current batch 当前批次的成交
+	batchBar *banexg.Kline   // 1s bar built from current batch 当前批次构建的1s K线
+	sinceMS  int64
+}
+
+/*
+FindTickFiles
+Returns zip files under dir which contain ticks of marketID, sorted by name
+返回dir下包含marketID的tick的zip文件，按名称排序
+*/
+func FindTickFiles(dir, marketID string) ([]string, *errs.Error) {
+	names, err := FindPathNames(dir, ".zip")
+	if err != nil || len(names) < 2 {
+		return nil, err
+	}
+	var res []string
+	for _, name := range names[1:] {
+		path := filepath.Join(names[0], name)
+		r, err_ := zip.OpenReader(path)
+		if err_ != nil {
+			return nil, errs.New(errs.CodeIOReadFail, err_)
+		}
+		for _, f := range r.File {
+			if isTickEntry(f.Name, marketID) {
+				res = append(res, path)
+				break
+			}
+		}
+		_ = r.Close()
+	}
+	return res, nil
+}
+
+func isTickEntry(name, marketID string) bool {
+	if !strings.HasSuffix(name, ".csv") {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSuffix(filepath.Base(name), ".csv"), marketID)
+}
+
+/*
+NewTickFeeder
+Create a tick feeder for exs, returns nil if no tick files found
+为exs创建tick反馈器，未找到tick文件时返回nil
+*/
+func NewTickFeeder(ctx *strat.Ctx, exs *orm.ExSymbol, callBack FnPairKline, onTrades FnPairTrades, showLog bool) (*TickFeeder, *errs.Error) {
+	exchange, err := exg.GetWith(exs.Exchange, exs.Market, "")
+	if err != nil {
+		return nil, err
+	}
+	market, err := exchange.GetMarket(exs.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	files, err := FindTickFiles(config.BTTickDir, market.ID)
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	feeder, err := NewKlineFeeder(ctx, exs, callBack, showLog)
+	if err != nil {
+		return nil, err
+	}
+	res := &TickFeeder{
+		HistKLineFeeder: HistKLineFeeder{
+			KlineFeeder: *feeder,
+			TimeRange:   ctx.TimeRange().Clone(),
+			TradeTimes:  market.GetTradeTimes(),
+		},
+		OnTrades: onTrades,
+		marketID: market.ID,
+		files:    files,
+	}
+	res.setNext = res.nextBatch
+	return res, nil
+}
+
+func (f *TickFeeder) SetSeek(since int64) {
+	if since == 0 {
+		since = core.MSMinStamp
+	}
+	f.sinceMS = since
+	f.fileIdx = 0
+	f.trades = nil
+	f.tradeIdx = 0
+	f.nextMS = 0
+	f.rowIdx = 0
+	f.nextBatch()
+}
+
+/*
+DownIfNeed
+Bars are built from ticks, no need to download
+K线从tick构建，无需下载
+*/
+func (f *TickFeeder) DownIfNeed(_ *orm.Queries, _ banexg.BanExchange, pBar *utils.PrgBar) *errs.Error {
+	if pBar != nil {
+		pBar.Add(core.StepTotal)
+	}
+	return nil
+}
+
+func (f *TickFeeder) GetBar() *banexg.Kline {
+	if f.rowIdx < 0 {
+		return nil
+	}
+	return f.batchBar
+}
+
+/*
+RunBar
+Replay trades of current batch, then update bars of all timeframes
+回放当前批次的成交，然后更新所有周期的K线
+*/
+func (f *TickFeeder) RunBar(bar *banexg.Kline) *errs.Error {
+	trades := f.batch
+	if len(trades) == 0 {
+		return nil
+	}
+	lastMS := trades[len(trades)-1].Timestamp
+	f.ctx.CurTimeMS = lastMS
+	if f.OnTrades != nil {
+		f.OnTrades(f.PairKey(), trades)
+	}
+	_, err := f.onNewBars(TickBatchMSecs, []*banexg.Kline{bar})
+	// bar callbacks set time to the bar end, which may be earlier than the last trade
+	// K线回调会将时间设为bar结束时间，可能早于最后一笔成交
+	f.ctx.CurTimeMS = max(f.ctx.CurTimeMS, lastMS)
+	return err
+}
+
+func (f *TickFeeder) WarmTfs(curMS int64, tfNums map[string]int, pBar *utils.PrgBar) (int64, map[string][2]int, *errs.Error) {
+	return f.KlineFeeder.WarmTfs(curMS, tfNums, pBar)
+}
+
+/*
+nextBatch
+Move to the next batch of trades, set rowIdx to -1 when finished
+移动到下一批成交，结束时设置rowIdx为-1
+*/
+func (f *TickFeeder) nextBatch() {
+	f.batch = nil
+	f.batchBar = nil
+	endMS := f.TimeRange.EndMS
+	for {
+		if f.tradeIdx >= len(f.trades) {
+			if !f.loadNextFile() {
+				break
+			}
+			continue
+		}
+		tr := f.trades[f.tradeIdx]
+		if endMS > 0 && tr.Timestamp >= endMS {
+			f.fileIdx = len(f.files)
+			f.trades = nil
+			break
+		}
+		batchMS := utils2.AlignTfMSecs(tr.Timestamp, TickBatchMSecs)
+		if f.batchBar != nil && batchMS != f.batchBar.Time {
+			break
+		}
+		f.tradeIdx += 1
+		if f.batchBar == nil {
+			f.batchBar = &banexg.Kline{Time: batchMS, Open: tr.Price, High: tr.Price, Low: tr.Price}
+		}
+		f.batchBar.High = max(f.batchBar.High, tr.Price)
+		f.batchBar.Low = min(f.batchBar.Low, tr.Price)
+		f.batchBar.Close = tr.Price
+		f.batchBar.Volume += tr.Amount
+		f.batch = append(f.batch, tr)
+	}
+	if f.batchBar == nil {
+		f.rowIdx = -1
+		f.nextMS = math.MaxInt64
+		return
+	}
+	f.rowIdx = 0
+	f.nextMS = f.batchBar.Time + TickBatchMSecs
+}
+
+/*
+loadNextFile
+Load trades of the next file in time range, returns false if no more files
+加载时间范围内下一个文件的成交，没有更多文件时返回false
+*/
+func (f *TickFeeder) loadNextFile() bool {
+	for f.fileIdx < len(f.files) {
+		path := f.files[f.fileIdx]
+		f.fileIdx += 1
+		trades, err := readTickTrades(path, f.marketID)
+		if err != nil {
+			log.Error("read tick file fail", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		start := 0
+		for start < len(trades) && trades[start].Timestamp < f.sinceMS {
+			start += 1
+		}
+		if start >= len(trades) {
+			continue
+		}
+		for _, tr := range trades[start:] {
+			tr.Symbol = f.PairKey()
+		}
+		f.trades = trades[start:]
+		f.tradeIdx = 0
+		return true
+	}
+	f.trades = nil
+	f.tradeIdx = 0
+	return false
+}
+
+/*
+readTickTrades
+Read trades of marketID from a tick zip file, sorted by time. When the same symbol exists in several entries,
+the one with the highest volume is used.
+从tick zip文件读取marketID的成交，按时间排序。同一品种存在于多个文件时，使用成交量最高的
+*/
+func readTickTrades(path, marketID string) ([]*banexg.Trade, *errs.Error) {
+	r, err_ := zip.OpenReader(path)
+	if err_ != nil {
+		return nil, errs.New(errs.CodeIOReadFail, err_)
+	}
+	defer r.Close()
+	var best []*banexg.Trade
+	var bestVol float64
+	for _, file := range r.File {
+		if !isTickEntry(file.Name, marketID) {
+			continue
+		}
+		fReader, err_ := file.Open()
+		if err_ != nil {
+			return nil, errs.New(errs.CodeIOReadFail, err_)
+		}
+		rows, err_ := csv.NewReader(fReader).ReadAll()
+		_ = fReader.Close()
+		if err_ != nil {
+			return nil, errs.New(errs.CodeIOReadFail, err_)
+		}
+		trades := parseTickRows(rows)
+		var sumVol float64
+		for _, tr := range trades {
+			sumVol += tr.Amount
+		}
+		if sumVol > bestVol {
+			best, bestVol = trades, sumVol
+		}
+	}
+	return best, nil
+}
+
+/*
+parseTickRows
+Convert csv rows to trades. Formatted tick rows carry cumulative volume, which is diffed to get the amount of each
+trade; ticks without new volume are skipped.
+将csv行转为成交。格式化tick行的成交量是累计值，差分得到每笔成交数量；无新增成交量的tick被跳过
+*/
+func parseTickRows(rows [][]string) []*banexg.Trade {
+	res := make([]*banexg.Trade, 0, len(rows))
+	var lastVol, lastPrice float64
+	var lastMS int64
+	for _, row := range rows {
+		if len(row) >= 8 {
+			// InstrumentID,Time,LastPrice,Volume,BidPrice1,BidVolume1,AskPrice1,AskVolume1,...
+			timeMS, _ := strconv.ParseInt(row[1], 10, 64)
+			price, _ := strconv.ParseFloat(row[2], 64)
+			volume, _ := strconv.ParseFloat(row[3], 64)
+			if timeMS == 0 || price <= 0 || timeMS < lastMS {
+				continue
+			}
+			amount := volume - lastVol
+			if volume < lastVol {
+				// cumulative volume reset for a new session
+				// 新交易时段累计成交量归0
+				amount = volume
+			}
+			lastVol, lastMS = volume, timeMS
+			if amount <= 0 {
+				continue
+			}
+			bid1, _ := strconv.ParseFloat(row[4], 64)
+			ask1, _ := strconv.ParseFloat(row[6], 64)
+			// infer the taker side from best bid/ask, or the price change when inside the spread
+			// 根据买一卖一推断主动方向，在价差内时根据价格变化判断
+			side := banexg.OdSideBuy
+			if bid1 > 0 && price <= bid1 {
+				side = banexg.OdSideSell
+			} else if (ask1 <= 0 || price < ask1) && price < lastPrice {
+				side = banexg.OdSideSell
+			}
+			lastPrice = price
+			res = append(res, &banexg.Trade{Timestamp: timeMS, Price: price, Amount: amount,
+				Cost: price * amount, Side: side})
+		} else if len(row) >= 3 {
+			// Time,Price,Amount[,Side]
+			timeMS, _ := strconv.ParseInt(row[0], 10, 64)
+			price, _ := strconv.ParseFloat(row[1], 64)
+			amount, _ := strconv.ParseFloat(row[2], 64)
+			if timeMS == 0 || price <= 0 || amount <= 0 || timeMS < lastMS {
+				continue
+			}
+			lastMS = timeMS
+			side := banexg.OdSideBuy
+			if len(row) >= 4 && strings.EqualFold(row[3], banexg.OdSideSell) {
+				side = banexg.OdSideSell
+			}
+			res = append(res, &banexg.Trade{Timestamp: timeMS, Price: price, Amount: amount,
+				Cost: price * amount, Side: side})
+		}
+	}
+	return res
+}
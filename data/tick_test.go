@@ -0,0 +1,30 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/banbox/banexg"
+)
+
+func TestParseTickRows(t *testing.T) {
+	rows := [][]string{
+		{"rb2410", "1700000000000", "3500", "10", "3499", "5", "3501", "6"},
+		{"rb2410", "1700000000500", "3499", "14", "3499", "5", "3501", "6"},
+		{"rb2410", "1700000001000", "3500", "14", "3499", "5", "3501", "6"},
+		{"rb2410", "1700000001500", "3501", "20", "3499", "5", "3501", "6"},
+	}
+	trades := parseTickRows(rows)
+	if len(trades) != 3 {
+		t.Fatalf("expect 3 trades, got %v", len(trades))
+	}
+	if trades[1].Amount != 4 || trades[1].Side != banexg.OdSideSell {
+		t.Errorf("bad trade 1: %+v", trades[1])
+	}
+	if trades[2].Amount != 6 || trades[2].Side != banexg.OdSideBuy {
+		t.Errorf("bad trade 2: %+v", trades[2])
+	}
+	plain := parseTickRows([][]string{{"1700000000000", "100", "0.5", "sell"}, {"1700000000100", "101", "1"}})
+	if len(plain) != 2 || plain[0].Side != banexg.OdSideSell || plain[1].Side != banexg.OdSideBuy {
+		t.Errorf("bad plain trades: %+v", plain)
+	}
+}
@@ -104,10 +104,12 @@ func (w *KLineWatcher) WatchJobs(exgName, marketType, jobType string, jobs ...Wa
 		}
 		tfSecs := utils2.TFToSecs(j.TimeFrame)
 		minTfSecs = min(minTfSecs, tfSecs)
+		// spider uses symbols of exchange 爬虫使用交易所的品种
+		symbol := core.PairSymbol(j.Symbol)
 		for _, p := range prefixs {
-			tags = append(tags, p+"_"+j.Symbol)
+			tags = append(tags, p+"_"+symbol)
 		}
-		pairs = append(pairs, j.Symbol)
+		pairs = append(pairs, symbol)
 		w.jobs[jobKey] = &PairTFCache{TimeFrame: j.TimeFrame, TFSecs: tfSecs, NextMS: j.Since,
 			AlignOffMS: int64(exg.GetAlignOff(exgID, tfSecs) * 1000)}
 		// 尽早启动延迟监听
@@ -141,7 +143,7 @@ func (w *KLineWatcher) UnWatchJobs(exgName, marketType, jobType string, pairs []
 	tags := make([]string, 0, len(prefixs)*len(pairs))
 	for _, pair := range pairs {
 		for _, prefix := range prefixs {
-			tags = append(tags, fmt.Sprintf("%s_%s", prefix, pair))
+			tags = append(tags, fmt.Sprintf("%s_%s", prefix, core.PairSymbol(pair)))
 		}
 		jobKey := fmt.Sprintf("%s_%s", pair, jobType)
 		delete(w.jobs, jobKey)
@@ -156,7 +158,8 @@ func (w *KLineWatcher) onSpiderBar(key string, data []byte) {
 		return
 	}
 	parts := strings.Split(key, "_")
-	msgType, exgName, market, pair := parts[0], parts[1], parts[2], parts[3]
+	msgType, exgName, market := parts[0], parts[1], parts[2]
+	pair := core.PairKey(exgName, market, parts[3])
 	job, ok := w.jobs[fmt.Sprintf("%s_%s", pair, msgType)]
 	if !ok {
 		// 未监听，忽略
@@ -220,7 +223,7 @@ func (w *KLineWatcher) onSpiderBar(key string, data []byte) {
 func (w *KLineWatcher) onPriceUpdate(key string, data []byte) {
 	parts := strings.Split(key, "_")
 	exgName, market := parts[1], parts[2]
-	if exgName != core.ExgName || market != core.Market {
+	if !exg.IsUsedMarket(exgName, market) {
 		return
 	}
 	var msg map[string]float64
@@ -229,7 +232,14 @@ func (w *KLineWatcher) onPriceUpdate(key string, data []byte) {
 		log.Warn("onPriceUpdate receive invalid msg", zap.String("raw", string(data)), zap.Error(err))
 		return
 	}
-	core.SetPrices(msg)
+	if exgName != core.ExgName || market != core.Market {
+		prices := make(map[string]float64, len(msg))
+		for symbol, price := range msg {
+			prices[core.PairKey(exgName, market, symbol)] = price
+		}
+		msg = prices
+	}
+	core.DefCtx.SetPrices(msg)
 }
 
 func (w *KLineWatcher) onTrades(key string, data []byte) {
@@ -250,10 +260,11 @@ func (w *KLineWatcher) onTrades(key string, data []byte) {
 
 func (w *KLineWatcher) onBook(key string, data []byte) {
 	parts := strings.Split(key, "_")
-	msgType, exgName, market, pair := parts[0], parts[1], parts[2], parts[3]
-	if exgName != core.ExgName || market != core.Market {
+	msgType, exgName, market := parts[0], parts[1], parts[2]
+	if !exg.IsUsedMarket(exgName, market) {
 		return
 	}
+	pair := core.PairKey(exgName, market, parts[3])
 	_, ok := w.jobs[fmt.Sprintf("%s_%s", pair, msgType)]
 	if !ok {
 		// 未监听，忽略
@@ -269,5 +280,5 @@ func (w *KLineWatcher) onBook(key string, data []byte) {
 	if book.Symbol == "" {
 		return
 	}
-	core.OdBooks[pair] = &book
+	core.DefCtx.OdBooks[pair] = &book
 }
@@ -83,10 +83,11 @@ func init() {
 		Help: "start the spider",
 	})
 	AddCmdJob(&CmdJob{
-		Name:    "optimize",
-		Run:     opt.RunOptimize,
-		Options: []string{"out", "opt_rounds", "sampler", "picker", "each_pairs", "concur"},
-		Help:    "run hyper parameters optimization",
+		Name: "optimize",
+		Run:  opt.RunOptimize,
+		Options: []string{"out", "opt_rounds", "sampler", "objectives", "resume", "seed", "pruner", "picker",
+			"each_pairs", "concur", "prg"},
+		Help: "run hyper parameters optimization",
 	})
 	AddCmdJob(&CmdJob{
 		Name:    "init",
@@ -97,8 +98,8 @@ func init() {
 	AddCmdJob(&CmdJob{
 		Name: "bt_opt",
 		Run:  opt.RunBTOverOpt,
-		Options: []string{"review_period", "run_period", "opt_rounds", "sampler", "picker", "each_pairs",
-			"concur", "alpha", "pair_picker"},
+		Options: []string{"review_period", "run_period", "opt_rounds", "sampler", "objectives", "seed", "pruner", "picker",
+			"each_pairs", "concur", "alpha", "pair_picker"},
 		Help: "rolling backtest with hyperparameter optimization",
 	})
 	AddCmdJob(&CmdJob{
@@ -216,8 +217,8 @@ func init() {
 		Name:   "test_pickers",
 		Parent: "tool",
 		Run:    opt.RunRollBTPicker,
-		Options: []string{"review_period", "run_period", "opt_rounds", "sampler", "each_pairs", "concur",
-			"picker", "pair_picker"},
+		Options: []string{"review_period", "run_period", "opt_rounds", "sampler", "objectives", "seed", "pruner", "each_pairs",
+			"concur", "picker", "pair_picker"},
 		Help: "test pickers in roll backtest",
 	})
 	AddCmdJob(&CmdJob{
@@ -227,6 +228,13 @@ func init() {
 		Options: []string{"in"},
 		Help:    "load calenders",
 	})
+	AddCmdJob(&CmdJob{
+		Name:    "load_funding",
+		Parent:  "tool",
+		Run:     biz.LoadFundingRates,
+		Options: []string{"in"},
+		Help:    "load funding rates of perpetual contracts from csv",
+	})
 	AddCmdJob(&CmdJob{
 		Name:    "data_server",
 		Parent:  "tool",
@@ -260,6 +268,12 @@ func init() {
 		RunRaw: opt.CompareExgBTOrders,
 		Help:   "compare exchange orders with backtest",
 	})
+	AddCmdJob(&CmdJob{
+		Name:   "monte_carlo",
+		Parent: "tool",
+		RunRaw: opt.RunMonteCarlo,
+		Help:   "monte carlo robustness analysis of backtest orders",
+	})
 	AddCmdJob(&CmdJob{
 		Name:   "list_strats",
 		Parent: "tool",
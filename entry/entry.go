@@ -15,6 +15,7 @@ import (
 	"github.com/banbox/banbot/opt"
 	"github.com/banbox/banbot/orm"
 	"github.com/banbox/banbot/utils"
+	"github.com/banbox/banexg"
 	"github.com/banbox/banexg/errs"
 	"github.com/banbox/banexg/log"
 	"go.uber.org/zap"
@@ -53,9 +54,7 @@ func RunBackTest(args *config.CmdArgs) *errs.Error {
 }
 
 func runBackTest(outDir string, prgOut string) string {
-	core.BotRunning = true
-	biz.ResetVars()
-	b := opt.NewBackTest(false, outDir)
+	b := opt.NewBackTest(opt.NewBTContext(nil), false, outDir)
 	if prgOut != "" {
 		lastSave := btime.UTCStamp()
 		b.PBar.AddTrigger("", func(task string, rate float64) {
@@ -77,7 +76,7 @@ func RunTrade(args *config.CmdArgs) *errs.Error {
 	if err != nil {
 		return err
 	}
-	core.BotRunning = true
+	core.DefCtx.BotRunning = true
 	core.StartAt = btime.UTCStamp()
 	t := live.NewCryptoTrader()
 	return t.Run()
@@ -88,7 +87,7 @@ func RunDownData(args *config.CmdArgs) *errs.Error {
 	if err != nil {
 		return err
 	}
-	pairs, err := goods.RefreshPairList(false)
+	pairs, err := goods.RefreshPairList(core.DefCtx, false)
 	if err != nil {
 		return err
 	}
@@ -97,19 +96,39 @@ func RunDownData(args *config.CmdArgs) *errs.Error {
 		return nil
 	}
 	log.Info("start down kline for pairs", zap.Int("num", len(pairs)), zap.Strings("tfs", args.TimeFrames))
-	exsMap := make(map[int32]*orm.ExSymbol)
+	// pairs are grouped by the exchange/market they are traded on 按品种所在的交易所/市场分组
+	exsMaps := make(map[banexg.BanExchange]map[int32]*orm.ExSymbol)
 	for _, pair := range pairs {
 		exs, err := orm.GetExSymbolCur(pair)
 		if err != nil {
 			return err
 		}
+		exchange := exg.GetPairExg(pair)
+		exsMap, ok := exsMaps[exchange]
+		if !ok {
+			exsMap = make(map[int32]*orm.ExSymbol)
+			exsMaps[exchange] = exsMap
+		}
 		exsMap[exs.ID] = exs
 	}
 	startMs, endMs := config.TimeRange.StartMS, config.TimeRange.EndMS
-	for _, tf := range args.TimeFrames {
-		err = orm.BulkDownOHLCV(exg.Default, exsMap, tf, startMs, endMs, 0, nil)
-		if err != nil {
-			return err
+	for exchange, exsMap := range exsMaps {
+		for _, tf := range args.TimeFrames {
+			err = orm.BulkDownOHLCV(exchange, exsMap, tf, startMs, endMs, 0, nil)
+			if err != nil {
+				return err
+			}
+		}
+		if !banexg.IsContract(exchange.Info().MarketType) {
+			continue
+		}
+		// download funding rates for perpetual contracts, used to accrue funding fees in backtest
+		// 为永续合约下载资金费率，用于回测时计算资金费
+		for _, exs := range exsMap {
+			_, err = orm.AutoFetchFundingRates(exchange, exs, startMs, endMs)
+			if err != nil {
+				log.Warn("down funding rates fail", zap.String("pair", exs.Symbol), zap.Error(err))
+			}
 		}
 	}
 	return nil
@@ -245,7 +245,15 @@ func bindSubFlags(args *config.CmdArgs, cmd *flag.FlagSet, opts ...string) error
 		case "opt_rounds":
 			cmd.IntVar(&args.OptRounds, "opt-rounds", 30, "rounds num for single optimize job")
 		case "sampler":
-			cmd.StringVar(&args.Sampler, "sampler", "bayes", "hyper optimize method, tpe/bayes/random/cmaes/ipop-cmaes/bipop-cmaes")
+			cmd.StringVar(&args.Sampler, "sampler", "bayes", "hyper optimize method, tpe/bayes/random/cmaes/ipop-cmaes/bipop-cmaes/nsga2")
+		case "objectives":
+			cmd.StringVar(&args.Objectives, "objectives", "", "objectives for pareto front: score/profit/drawdown/sharpe/sortino/orders")
+		case "resume":
+			cmd.StringVar(&args.Resume, "resume", "", "continue the optimize study of name, default study name is the -out file name")
+		case "pruner":
+			cmd.StringVar(&args.Pruner, "pruner", "", "stop unpromising optimize trials early: median/halving")
+		case "seed":
+			cmd.Int64Var(&args.Seed, "seed", -1, "random seed of hyper optimize samplers, default 0 or the seed of resumed study")
 		case "picker":
 			cmd.StringVar(&args.Picker, "picker", "good3", "Method for selecting targets from multiple hyperparameter optimization results")
 		case "alpha":
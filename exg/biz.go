@@ -1,7 +1,6 @@
 package exg
 
 import (
-	"github.com/banbox/banbot/btime"
 	"github.com/banbox/banbot/config"
 	"github.com/banbox/banbot/core"
 	"github.com/banbox/banbot/utils"
@@ -9,7 +8,11 @@ import (
 	"github.com/banbox/banexg/bex"
 	"github.com/banbox/banexg/bntp"
 	"github.com/banbox/banexg/errs"
+	"github.com/banbox/banexg/log"
 	"github.com/go-viper/mapstructure/v2"
+	"go.uber.org/zap"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -27,11 +30,14 @@ func Setup() *errs.Error {
 	var err *errs.Error
 	Default, err = GetWith(exgCfg.Name, core.Market, core.ContractType)
 	core.IsContract = banexg.IsContract(core.Market)
-	return err
+	if err != nil {
+		return err
+	}
+	return LoadPolicyExgs(config.RunPolicy)
 }
 
 func create(name, market, contractType string) (banexg.BanExchange, *errs.Error) {
-	var exgOpts, _ = config.Exchange.Items[config.Exchange.Name]
+	var exgOpts, _ = config.Exchange.Items[name]
 	var options = map[string]interface{}{}
 	for key, val := range exgOpts {
 		key = utils.SnakeToCamel(key)
@@ -103,14 +109,128 @@ func GetWith(name, market, contractType string) (banexg.BanExchange, *errs.Error
 	return client, err
 }
 
+/*
+LoadPolicyExgs
+Create clients for the exchanges/markets declared by run_policy items(pols) other than the default.
+Pairs of such policies are keyed by core.PairKey, so the same symbol can be traded on several exchanges/markets.
+Called at startup and when jobs are refreshed.
+为run_policy中声明的非默认交易所/市场创建客户端。此类策略的品种使用core.PairKey作为键，因此同一品种可在多个交易所/市场交易。
+启动和刷新任务时调用。
+*/
+func LoadPolicyExgs(pols []*config.RunPolicyConfig) *errs.Error {
+	items := make(map[string]banexg.BanExchange)
+	for _, pol := range pols {
+		exgName, market := pol.ExgMarket()
+		if exgName == config.Exchange.Name && market == core.Market {
+			continue
+		}
+		if len(pol.Pairs) == 0 {
+			return errs.NewMsg(core.ErrBadConfig, "run_policy %s: pairs is required when exchange/market is set", pol.ID())
+		}
+		venue := exgName + "." + market
+		if _, ok := items[venue]; ok {
+			continue
+		}
+		client, err := GetWith(exgName, market, "")
+		if err != nil {
+			return err
+		}
+		items[venue] = client
+	}
+	pairExgLock.Lock()
+	venueExgs = items
+	pairExgLock.Unlock()
+	return nil
+}
+
+/*
+GetPairExg
+Return the client which the pair key is traded on, Default for pairs without exchange/market suffix.
+返回品种键所在交易所的客户端，无交易所/市场后缀的品种返回Default
+*/
+func GetPairExg(pair string) banexg.BanExchange {
+	if !strings.Contains(pair, "@") {
+		return Default
+	}
+	exgName, market, _ := core.SplitPairKey(pair)
+	pairExgLock.RLock()
+	client, ok := venueExgs[exgName+"."+market]
+	pairExgLock.RUnlock()
+	if ok {
+		return client
+	}
+	client, err := GetWith(exgName, market, "")
+	if err != nil {
+		log.Error("get exchange of pair fail", zap.String("pair", pair), zap.Error(err))
+		return Default
+	}
+	return client
+}
+
+// IsContractPair whether the pair is traded on contract market 品种是否在合约市场交易
+func IsContractPair(pair string) bool {
+	client := GetPairExg(pair)
+	if client == nil {
+		return core.IsContract
+	}
+	return banexg.IsContract(client.Info().MarketType)
+}
+
+// IsMultiMarket whether some pairs are traded on non-default exchange/market 是否有品种在非默认交易所/市场交易
+func IsMultiMarket() bool {
+	pairExgLock.RLock()
+	defer pairExgLock.RUnlock()
+	return len(venueExgs) > 0
+}
+
+/*
+GroupPairs
+Group pair keys by the client they are traded on.
+按品种所在的客户端分组品种键
+*/
+func GroupPairs(pairs []string) map[banexg.BanExchange][]string {
+	res := make(map[banexg.BanExchange][]string)
+	for _, pair := range pairs {
+		client := GetPairExg(pair)
+		res[client] = append(res[client], pair)
+	}
+	return res
+}
+
+// AllExgs Default and other clients used by run_policy 默认客户端和run_policy使用的其他客户端
+func AllExgs() []banexg.BanExchange {
+	res := []banexg.BanExchange{Default}
+	pairExgLock.RLock()
+	defer pairExgLock.RUnlock()
+	venues := utils.KeysOfMap(venueExgs)
+	slices.Sort(venues)
+	for _, venue := range venues {
+		if client := venueExgs[venue]; !slices.Contains(res, client) {
+			res = append(res, client)
+		}
+	}
+	return res
+}
+
+// IsUsedMarket whether the exchange and market is used by this bot 此机器人是否使用了此交易所和市场
+func IsUsedMarket(exgName, market string) bool {
+	if exgName == core.ExgName && market == core.Market {
+		return true
+	}
+	pairExgLock.RLock()
+	defer pairExgLock.RUnlock()
+	_, ok := venueExgs[exgName+"."+market]
+	return ok
+}
+
 func precNum(exchange banexg.BanExchange, symbol string, num float64, source string) (float64, *errs.Error) {
 	if exchange == nil {
-		if Default == nil {
+		exchange = GetPairExg(symbol)
+		if exchange == nil {
 			return 0, errs.NewMsg(core.ErrExgNotInit, "exchange not loaded")
 		}
-		exchange = Default
 	}
-	market, err := exchange.GetMarket(symbol)
+	market, err := exchange.GetMarket(core.PairSymbol(symbol))
 	if err != nil {
 		return 0, err
 	}
@@ -142,18 +262,18 @@ func PrecAmount(exchange banexg.BanExchange, symbol string, amount float64) (flo
 }
 
 func GetLeverage(symbol string, notional float64, account string) (float64, float64) {
-	return Default.GetLeverage(symbol, notional, account)
+	return GetPairExg(symbol).GetLeverage(core.PairSymbol(symbol), notional, account)
 }
 
-func GetOdBook(pair string) (*banexg.OrderBook, *errs.Error) {
-	book, ok := core.OdBooks[pair]
-	if !ok || book == nil || book.TimeStamp+config.OdBookTtl < btime.TimeMS() {
+func GetOdBook(c *core.BotCtx, pair string) (*banexg.OrderBook, *errs.Error) {
+	book, ok := c.OdBooks[pair]
+	if !ok || book == nil || book.TimeStamp+config.OdBookTtl < c.TimeMS() {
 		var err *errs.Error
-		book, err = Default.FetchOrderBook(pair, 1000, nil)
+		book, err = GetPairExg(pair).FetchOrderBook(core.PairSymbol(pair), 1000, nil)
 		if err != nil {
 			return nil, err
 		}
-		core.OdBooks[pair] = book
+		c.OdBooks[pair] = book
 	}
 	return book, nil
 }
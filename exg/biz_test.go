@@ -0,0 +1,52 @@
+package exg
+
+import (
+	"testing"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banexg"
+	"github.com/banbox/banexg/errs"
+)
+
+func TestLoadPolicyExgs(t *testing.T) {
+	config.Exchange = &config.ExchangeConfig{Name: "binance"}
+	core.ExgName = "binance"
+	core.Market = banexg.MarketLinear
+	var err *errs.Error
+	Default, err = GetWith("binance", banexg.MarketLinear, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		venueExgs = map[string]banexg.BanExchange{}
+	}()
+	// same symbol can be traded on several markets 同一品种可在多个市场交易
+	spotKey := core.PairKey("binance", banexg.MarketSpot, "BTC/USDT")
+	pols := []*config.RunPolicyConfig{
+		{Name: "hedge", Pairs: []string{"BTC/USDT:USDT", "BTC/USDT"}},
+		{Name: "hedge", Market: banexg.MarketSpot, Pairs: []string{spotKey}},
+	}
+	if err = LoadPolicyExgs(pols); err != nil {
+		t.Fatal(err)
+	}
+	if spotKey != "BTC/USDT@binance.spot" || !IsMultiMarket() || IsContractPair(spotKey) ||
+		!IsContractPair("BTC/USDT:USDT") {
+		t.Errorf("pair markets invalid")
+	}
+	if GetPairExg("BTC/USDT") != Default || GetPairExg(spotKey) == Default || len(AllExgs()) != 2 {
+		t.Errorf("default client invalid")
+	}
+	if !IsUsedMarket("binance", banexg.MarketSpot) || IsUsedMarket("binance", banexg.MarketInverse) {
+		t.Errorf("used markets invalid")
+	}
+	groups := GroupPairs([]string{spotKey, "BTC/USDT:USDT", "ETH/USDT:USDT"})
+	if len(groups) != 2 || len(groups[Default]) != 2 || len(groups[GetPairExg(spotKey)]) != 1 {
+		t.Errorf("groups invalid: %v", groups)
+	}
+	// pairs are required for non-default market 非默认市场必须提供pairs
+	pols = []*config.RunPolicyConfig{{Name: "hedge", Market: banexg.MarketSpot}}
+	if LoadPolicyExgs(pols) == nil {
+		t.Errorf("expect error for empty pairs")
+	}
+}
@@ -14,3 +14,8 @@ var AllowExgIds = map[string]bool{
 	"bybit":   true,
 	"china":   true,
 }
+
+// venueExgs clients of exchange.market other than Default, set by run_policy exchange/market
+// Default以外的交易所.市场的客户端，由run_policy的exchange/market设置
+var venueExgs = map[string]banexg.BanExchange{}
+var pairExgLock sync.RWMutex
@@ -2,6 +2,8 @@ package goods
 
 import (
 	"fmt"
+	"sync"
+
 	"github.com/banbox/banbot/btime"
 	"github.com/banbox/banbot/config"
 	"github.com/banbox/banbot/core"
@@ -16,6 +18,7 @@ var (
 	pairProducer IProducer
 	filters      = make([]IFilter, 0, 10)
 	ShowLog      = true
+	lockRefresh  sync.Mutex // filters are shared by all contexts 过滤器被所有上下文共享
 )
 
 func Setup() *errs.Error {
@@ -35,6 +38,34 @@ func Setup() *errs.Error {
 	return nil
 }
 
+type FuncMakeFilter = func(base BaseFilter) IFilter
+
+// FilterMake constructors of pair filters by name 按名称注册的标的过滤器构造函数
+var FilterMake = map[string]FuncMakeFilter{
+	"AgeFilter":          func(b BaseFilter) IFilter { return &AgeFilter{BaseFilter: b} },
+	"VolumePairList":     func(b BaseFilter) IFilter { return &VolumePairFilter{BaseFilter: b} },
+	"PriceFilter":        func(b BaseFilter) IFilter { return &PriceFilter{BaseFilter: b} },
+	"RateOfChangeFilter": func(b BaseFilter) IFilter { return &RateOfChangeFilter{BaseFilter: b} },
+	"VolatilityFilter":   func(b BaseFilter) IFilter { return &VolatilityFilter{BaseFilter: b} },
+	"SpreadFilter":       func(b BaseFilter) IFilter { return &SpreadFilter{BaseFilter: b} },
+	"OffsetFilter":       func(b BaseFilter) IFilter { return &OffsetFilter{BaseFilter: b} },
+	"ShuffleFilter":      func(b BaseFilter) IFilter { return &ShuffleFilter{BaseFilter: b} },
+	"CorrelationFilter":  func(b BaseFilter) IFilter { return &CorrelationFilter{BaseFilter: b} },
+	"ExprFilter":         func(b BaseFilter) IFilter { return &ExprFilter{BaseFilter: b} },
+}
+
+/*
+AddFilterGroup
+Register custom pair filters, which can be used as `group:name` in pairlists and run_policy.filters.
+The first filter of pairlists must implement IProducer.
+注册自定义标的过滤器，可在pairlists和run_policy.filters中以`group:name`使用。pairlists的第一个过滤器必须实现IProducer。
+*/
+func AddFilterGroup(group string, items map[string]FuncMakeFilter) {
+	for k, v := range items {
+		FilterMake[group+":"+k] = v
+	}
+}
+
 func GetPairFilters(items []*config.CommonPairFilter, withInvalid bool) ([]IFilter, *errs.Error) {
 	fts := make([]IFilter, 0, len(items))
 	// 未启用定期刷新，则允许成交量为空的品种
@@ -42,28 +73,11 @@ func GetPairFilters(items []*config.CommonPairFilter, withInvalid bool) ([]IFilt
 	for _, cfg := range items {
 		var output IFilter
 		var base = BaseFilter{Name: cfg.Name, AllowEmpty: allowEmpty}
-		switch cfg.Name {
-		case "AgeFilter":
-			output = &AgeFilter{BaseFilter: base}
-		case "VolumePairList":
-			output = &VolumePairFilter{BaseFilter: base}
-		case "PriceFilter":
-			output = &PriceFilter{BaseFilter: base}
-		case "RateOfChangeFilter":
-			output = &RateOfChangeFilter{BaseFilter: base}
-		case "VolatilityFilter":
-			output = &VolatilityFilter{BaseFilter: base}
-		case "SpreadFilter":
-			output = &SpreadFilter{BaseFilter: base}
-		case "OffsetFilter":
-			output = &OffsetFilter{BaseFilter: base}
-		case "ShuffleFilter":
-			output = &ShuffleFilter{BaseFilter: base}
-		case "CorrelationFilter":
-			output = &CorrelationFilter{BaseFilter: base}
-		default:
+		makeFn, ok := FilterMake[cfg.Name]
+		if !ok {
 			return nil, errs.NewMsg(errs.CodeParamInvalid, "unknown symbol filter: %s", cfg.Name)
 		}
+		output = makeFn(base)
 		err_ := mapstructure.Decode(cfg.Items, &output)
 		if err_ != nil {
 			return nil, errs.New(errs.CodeUnmarshalFail, err_)
@@ -75,17 +89,31 @@ func GetPairFilters(items []*config.CommonPairFilter, withInvalid bool) ([]IFilt
 	return fts, nil
 }
 
+/*
+RunFilter
+Run the filter with the context, FilterCtx is used if flt implements ICtxFilter.
+使用上下文执行过滤器，如果flt实现了ICtxFilter则使用FilterCtx
+*/
+func RunFilter(c *core.BotCtx, flt IFilter, pairs []string, timeMS int64) ([]string, *errs.Error) {
+	if cf, ok := flt.(ICtxFilter); ok {
+		return cf.FilterCtx(c, pairs, timeMS)
+	}
+	return flt.Filter(pairs, timeMS)
+}
+
 /*
 RefreshPairList
 
 刷新交易品种，如果alignStart=true，则计算当前时间前一个cron的触发时间对应的交易品种
-更新core.Pairs和core.PairsMap
+更新c.Pairs和c.PairsMap
 */
-func RefreshPairList(alignStart bool) ([]string, *errs.Error) {
+func RefreshPairList(c *core.BotCtx, alignStart bool) ([]string, *errs.Error) {
+	lockRefresh.Lock()
+	defer lockRefresh.Unlock()
 	var pairs []string
 	var allowFilter = false
 	var err *errs.Error
-	curTime := btime.TimeMS()
+	curTime := c.TimeMS()
 	if alignStart && config.PairMgr.Cron != "" {
 		schedule, err_ := utils.NewCronScheduler(config.PairMgr.Cron)
 		if err_ != nil {
@@ -121,7 +149,7 @@ func RefreshPairList(alignStart bool) ([]string, *errs.Error) {
 				continue
 			}
 			oldNum := len(pairs)
-			pairs, err = flt.Filter(pairs, curTime)
+			pairs, err = RunFilter(c, flt, pairs, curTime)
 			if err != nil {
 				return nil, err
 			}
@@ -143,16 +171,16 @@ func RefreshPairList(alignStart bool) ([]string, *errs.Error) {
 		pairs = pairs[:mgrCfg.Limit]
 	}
 
-	core.Pairs = nil
-	core.PairsMap = make(map[string]bool)
+	c.Pairs = nil
+	c.PairsMap = make(map[string]bool)
 	for _, p := range pairs {
-		core.Pairs = append(core.Pairs, p)
-		core.PairsMap[p] = true
+		c.Pairs = append(c.Pairs, p)
+		c.PairsMap[p] = true
 	}
 
-	for pair := range core.BanPairsUntil {
-		if _, ok := core.PairsMap[pair]; !ok {
-			delete(core.BanPairsUntil, pair)
+	for pair := range c.BanPairsUntil {
+		if _, ok := c.PairsMap[pair]; !ok {
+			delete(c.BanPairsUntil, pair)
 		}
 	}
 	return pairs, nil
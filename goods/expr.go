@@ -0,0 +1,414 @@
+package goods
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/banbox/banexg"
+	"github.com/banbox/banexg/errs"
+	utils2 "github.com/banbox/banexg/utils"
+	ta "github.com/banbox/banta"
+)
+
+/*
+exprNode
+AST of a filter expression, e.g. `ATR(14)/close > 0.02 and ROC(close, 20) > 0`.
+Supported: numbers, open/high/low/close/volume, banta indicators, + - * / > >= < <= == != and(&&) or(||) not(!) and ().
+Comparisons and logical operators return 1 or 0.
+过滤表达式的语法树，支持数字、open/high/low/close/volume、banta指标、算术比较逻辑运算符和括号。比较和逻辑运算返回1或0。
+*/
+type exprNode struct {
+	op   string // num, var, call, neg, not, or binary operator 节点类型或二元运算符
+	val  float64
+	name string
+	args []*exprNode
+}
+
+type exprIndFunc struct {
+	src  bool // whether the first arg is a series, default close 第一个参数是否为序列，默认close
+	call func(e *ta.BarEnv, src *ta.Series, period int) *ta.Series
+}
+
+func withSrc(fn func(*ta.Series, int) *ta.Series) exprIndFunc {
+	return exprIndFunc{src: true, call: func(_ *ta.BarEnv, src *ta.Series, period int) *ta.Series {
+		return fn(src, period)
+	}}
+}
+
+func withEnv(fn func(*ta.BarEnv, int) *ta.Series) exprIndFunc {
+	return exprIndFunc{call: func(e *ta.BarEnv, _ *ta.Series, period int) *ta.Series {
+		return fn(e, period)
+	}}
+}
+
+// exprFuncs indicators can be used in expression, name is case-insensitive 表达式中可用的指标，名称不区分大小写
+var exprFuncs = map[string]exprIndFunc{
+	"SMA":         withSrc(ta.SMA),
+	"EMA":         withSrc(ta.EMA),
+	"RMA":         withSrc(ta.RMA),
+	"WMA":         withSrc(ta.WMA),
+	"HMA":         withSrc(ta.HMA),
+	"SUM":         withSrc(ta.Sum),
+	"RSI":         withSrc(ta.RSI),
+	"ROC":         withSrc(ta.ROC),
+	"HIGHEST":     withSrc(ta.Highest),
+	"LOWEST":      withSrc(ta.Lowest),
+	"ER":          withSrc(ta.ER),
+	"KAMA":        withSrc(ta.KAMA),
+	"CCI":         withSrc(ta.CCI),
+	"LINREG":      withSrc(ta.LinReg),
+	"CTI":         withSrc(ta.CTI),
+	"CMO":         withSrc(ta.CMO),
+	"PERCENTRANK": withSrc(ta.PercentRank),
+	"STDDEV": withSrc(func(s *ta.Series, period int) *ta.Series {
+		res, _ := ta.StdDev(s, period)
+		return res
+	}),
+	"ATR": withEnv(func(e *ta.BarEnv, period int) *ta.Series {
+		return ta.ATR(e.High, e.Low, e.Close, period)
+	}),
+	"ADX": withEnv(func(e *ta.BarEnv, period int) *ta.Series {
+		return ta.ADX(e.High, e.Low, e.Close, period)
+	}),
+	"MFI":   withEnv(ta.MFI),
+	"CMF":   withEnv(ta.CMF),
+	"WILLR": withEnv(ta.WillR),
+	"CHOP":  withEnv(ta.CHOP),
+}
+
+var binaryPrecs = map[string]int{
+	"or": 1, "and": 2,
+	"==": 3, "!=": 3, ">": 3, ">=": 3, "<": 3, "<=": 3,
+	"+": 4, "-": 4, "*": 5, "/": 5,
+}
+
+type exprParser struct {
+	text   string
+	tokens []string
+	pos    int
+}
+
+/*
+parseExpr
+Parse the filter expression and check names of variables and indicators
+解析过滤表达式，并检查变量和指标名称
+*/
+func parseExpr(text string) (*exprNode, *errs.Error) {
+	tokens, err := tokenizeExpr(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{text: text, tokens: tokens}
+	res, err := p.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.fail("unexpected %s", p.tokens[p.pos])
+	}
+	return res, nil
+}
+
+func tokenizeExpr(text string) ([]string, *errs.Error) {
+	var res []string
+	chars := []rune(text)
+	for i := 0; i < len(chars); {
+		c := chars[i]
+		start := i
+		switch {
+		case unicode.IsSpace(c):
+			i++
+			continue
+		case unicode.IsDigit(c) || c == '.':
+			for i < len(chars) && (unicode.IsDigit(chars[i]) || chars[i] == '.') {
+				i++
+			}
+		case unicode.IsLetter(c) || c == '_':
+			for i < len(chars) && (unicode.IsLetter(chars[i]) || unicode.IsDigit(chars[i]) || chars[i] == '_') {
+				i++
+			}
+		case strings.ContainsRune("><=!&|", c):
+			i++
+			if i < len(chars) && strings.ContainsRune("=&|", chars[i]) {
+				i++
+			}
+		case strings.ContainsRune("+-*/(),", c):
+			i++
+		default:
+			return nil, errs.NewMsg(errs.CodeParamInvalid, "invalid char '%c' in expr: %s", c, text)
+		}
+		tok := string(chars[start:i])
+		switch strings.ToLower(tok) {
+		case "&&", "and":
+			tok = "and"
+		case "||", "or":
+			tok = "or"
+		case "!", "not":
+			tok = "not"
+		case "=", "&", "|":
+			return nil, errs.NewMsg(errs.CodeParamInvalid, "invalid operator '%s' in expr: %s", tok, text)
+		}
+		res = append(res, tok)
+	}
+	return res, nil
+}
+
+func (p *exprParser) fail(msg string, args ...interface{}) *errs.Error {
+	return errs.NewMsg(errs.CodeParamInvalid, "%s in expr: %s", fmt.Sprintf(msg, args...), p.text)
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) expect(tok string) *errs.Error {
+	if p.peek() != tok {
+		return p.fail("expect '%s'", tok)
+	}
+	p.pos++
+	return nil
+}
+
+// parseBinary precedence climbing for left-associative binary operators 左结合二元运算符的优先级爬升解析
+func (p *exprParser) parseBinary(minPrec int) (*exprNode, *errs.Error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		prec, ok := binaryPrecs[op]
+		if !ok || prec < minPrec {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &exprNode{op: op, args: []*exprNode{left, right}}
+	}
+}
+
+func (p *exprParser) parseUnary() (*exprNode, *errs.Error) {
+	tok := p.peek()
+	if tok == "-" || tok == "not" {
+		p.pos++
+		arg, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		op := "neg"
+		if tok == "not" {
+			op = "not"
+		}
+		return &exprNode{op: op, args: []*exprNode{arg}}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (*exprNode, *errs.Error) {
+	tok := p.peek()
+	if tok == "" {
+		return nil, p.fail("unexpected end")
+	}
+	p.pos++
+	if tok == "(" {
+		res, err := p.parseBinary(1)
+		if err != nil {
+			return nil, err
+		}
+		return res, p.expect(")")
+	}
+	c := rune(tok[0])
+	if unicode.IsDigit(c) || c == '.' {
+		val, err_ := strconv.ParseFloat(tok, 64)
+		if err_ != nil {
+			return nil, p.fail("invalid number %s", tok)
+		}
+		return &exprNode{op: "num", val: val}, nil
+	}
+	if !unicode.IsLetter(c) && c != '_' {
+		return nil, p.fail("unexpected %s", tok)
+	}
+	if p.peek() != "(" {
+		name := strings.ToLower(tok)
+		switch name {
+		case "open", "high", "low", "close", "volume":
+			return &exprNode{op: "var", name: name}, nil
+		}
+		return nil, p.fail("unknown variable %s", tok)
+	}
+	p.pos++
+	name := strings.ToUpper(tok)
+	fn, ok := exprFuncs[name]
+	if !ok {
+		return nil, p.fail("unknown indicator %s", tok)
+	}
+	var args []*exprNode
+	for p.peek() != ")" {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseBinary(1)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.pos++
+	// the last arg is period, series arg defaults to close 最后一个参数是周期，序列参数默认为close
+	if len(args) == 0 || args[len(args)-1].op != "num" {
+		return nil, p.fail("%s requires a number period as the last arg", name)
+	}
+	if fn.src && len(args) == 1 {
+		args = append([]*exprNode{{op: "var", name: "close"}}, args...)
+	}
+	if fn.src && len(args) != 2 || !fn.src && len(args) != 1 {
+		return nil, p.fail("bad arg num for %s", name)
+	}
+	return &exprNode{op: "call", name: name, args: args}, nil
+}
+
+/*
+bind
+Returns a function which should be called once after each env.OnBar, to get the series of this node
+返回一个函数，应在每次env.OnBar后调用一次，获取此节点的序列
+*/
+func (n *exprNode) bind(e *ta.BarEnv) func() *ta.Series {
+	switch n.op {
+	case "num":
+		res := e.NewSeries(nil)
+		return func() *ta.Series {
+			return res.Append(n.val)
+		}
+	case "var":
+		return func() *ta.Series {
+			switch n.name {
+			case "open":
+				return e.Open
+			case "high":
+				return e.High
+			case "low":
+				return e.Low
+			case "volume":
+				return e.Volume
+			default:
+				return e.Close
+			}
+		}
+	case "call":
+		fn := exprFuncs[n.name]
+		period := int(n.args[len(n.args)-1].val)
+		var src func() *ta.Series
+		if fn.src {
+			src = n.args[0].bind(e)
+		}
+		return func() *ta.Series {
+			var srcSer *ta.Series
+			if src != nil {
+				srcSer = src()
+			}
+			return fn.call(e, srcSer, period)
+		}
+	}
+	args := make([]func() *ta.Series, 0, len(n.args))
+	for _, a := range n.args {
+		args = append(args, a.bind(e))
+	}
+	res := e.NewSeries(nil)
+	return func() *ta.Series {
+		a := args[0]().Get(0)
+		if len(args) == 1 {
+			if n.op == "not" {
+				return res.Append(boolVal(!isTrue(a)))
+			}
+			return res.Append(-a)
+		}
+		return res.Append(calcBinary(n.op, a, args[1]().Get(0)))
+	}
+}
+
+func calcBinary(op string, a, b float64) float64 {
+	switch op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		return a / b
+	case ">":
+		return boolVal(a > b)
+	case ">=":
+		return boolVal(a >= b)
+	case "<":
+		return boolVal(a < b)
+	case "<=":
+		return boolVal(a <= b)
+	case "==":
+		return boolVal(a == b)
+	case "!=":
+		return boolVal(a != b)
+	case "and":
+		return boolVal(isTrue(a) && isTrue(b))
+	case "or":
+		return boolVal(isTrue(a) || isTrue(b))
+	}
+	return math.NaN()
+}
+
+func isTrue(v float64) bool {
+	return v != 0 && !math.IsNaN(v)
+}
+
+func boolVal(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+/*
+evalExprs
+Evaluate expressions over klines bar by bar, returns the values of the last bar. nil node gets NaN
+在K线上逐bar计算表达式，返回最后一个bar的值。nil节点返回NaN
+*/
+func evalExprs(timeFrame string, klines []*banexg.Kline, nodes ...*exprNode) []float64 {
+	res := make([]float64, len(nodes))
+	for i := range res {
+		res[i] = math.NaN()
+	}
+	if len(klines) == 0 {
+		return res
+	}
+	env := &ta.BarEnv{
+		TimeFrame: timeFrame,
+		TFMSecs:   int64(utils2.TFToSecs(timeFrame)) * 1000,
+		MaxCache:  len(klines),
+	}
+	calcs := make([]func() *ta.Series, len(nodes))
+	for i, n := range nodes {
+		if n != nil {
+			calcs[i] = n.bind(env)
+		}
+	}
+	for _, k := range klines {
+		_ = env.OnBar(k.Time, k.Open, k.High, k.Low, k.Close, k.Volume, k.Info)
+		for i, calc := range calcs {
+			if calc != nil {
+				res[i] = calc().Get(0)
+			}
+		}
+	}
+	return res
+}
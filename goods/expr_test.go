@@ -0,0 +1,43 @@
+package goods
+
+import (
+	"math"
+	"testing"
+
+	"github.com/banbox/banexg"
+)
+
+func TestEvalExprs(t *testing.T) {
+	var klines []*banexg.Kline
+	for i := 0; i < 30; i++ {
+		price := float64(100 + i)
+		klines = append(klines, &banexg.Kline{Time: int64(i) * 86400000, Open: price, High: price + 2,
+			Low: price - 2, Close: price, Volume: 1000})
+	}
+	cases := []struct {
+		expr string
+		want float64
+	}{
+		{"close", 129},
+		{"-(high - low) * 2 + 1", -7},
+		{"ATR(14)/close > 0.02 and not close < 100", 1},
+		{"ROC(20) > 0 || volume == 0", 1},
+		{"sma(close - open, 5)", 0},
+		{"Highest(high, 10) - Lowest(low, 10)", 13},
+	}
+	for _, c := range cases {
+		node, err := parseExpr(c.expr)
+		if err != nil {
+			t.Fatalf("parse %s fail: %v", c.expr, err)
+		}
+		res := evalExprs("1d", klines, node)
+		if math.Abs(res[0]-c.want) > 1e-9 {
+			t.Errorf("%s should be %v, got %v", c.expr, c.want, res[0])
+		}
+	}
+	for _, expr := range []string{"close >", "foo > 1", "UNKNOWN(3)", "SMA(close)", "ATR(high, 14)", "close = 1"} {
+		if _, err := parseExpr(expr); err == nil {
+			t.Errorf("%s should fail", expr)
+		}
+	}
+}
@@ -30,32 +30,37 @@ func (f *BaseFilter) GetName() string {
 }
 
 func (f *AgeFilter) Filter(symbols []string, timeMS int64) ([]string, *errs.Error) {
+	return f.FilterCtx(core.DefCtx, symbols, timeMS)
+}
+
+func (f *AgeFilter) FilterCtx(c *core.BotCtx, symbols []string, timeMS int64) ([]string, *errs.Error) {
 	if f.Min == 0 && f.Max == 0 {
 		return symbols, nil
 	}
 	dayMs := int64(utils2.TFToSecs("1d") * 1000)
 	result := make([]string, 0, len(symbols))
-	exsMap := orm.GetExSymbols(core.ExgName, core.Market)
 	sess, conn, err := orm.Conn(nil)
 	if err != nil {
 		return nil, err
 	}
 	defer conn.Release()
-	pairMap := make(map[string]*orm.ExSymbol)
-	for _, exs := range exsMap {
-		pairMap[exs.Symbol] = exs
-	}
 	careMap := make(map[int32]*orm.ExSymbol)
-	for _, p := range symbols {
-		if exs, ok := pairMap[p]; ok {
-			careMap[exs.ID] = exs
-		} else {
-			return nil, errs.NewMsg(errs.CodeNoMarketForPair, "unknown %v", p)
+	for exchange, pairs := range exg.GroupPairs(symbols) {
+		exInfo := exchange.Info()
+		pairMap := orm.GetExSymbolMap(exInfo.ID, exInfo.MarketType)
+		exgCares := make(map[int32]*orm.ExSymbol)
+		for _, p := range pairs {
+			if exs, ok := pairMap[core.PairSymbol(p)]; ok {
+				exgCares[exs.ID] = exs
+				careMap[exs.ID] = exs
+			} else {
+				return nil, errs.NewMsg(errs.CodeNoMarketForPair, "unknown %v", p)
+			}
+		}
+		err = orm.EnsureListDates(sess, exchange, exgCares, nil)
+		if err != nil {
+			return nil, err
 		}
-	}
-	err = orm.EnsureListDates(sess, exg.Default, careMap, nil)
-	if err != nil {
-		return nil, err
 	}
 	minStartMS := timeMS - dayMs*int64(f.Min)
 	valids := make(map[string]bool)
@@ -66,12 +71,12 @@ func (f *AgeFilter) Filter(symbols []string, timeMS int64) ([]string, *errs.Erro
 				continue
 			} else if f.Min > 0 && days < f.Min {
 				if f.AllowEmpty {
-					core.BanPairsUntil[exs.Symbol] = minStartMS
+					c.BanPairsUntil[exs.PairKey()] = minStartMS
 				} else {
 					continue
 				}
 			}
-			valids[exs.Symbol] = true
+			valids[exs.PairKey()] = true
 		} else {
 			log.Info("listMs is empty", zap.String("key", exs.Symbol))
 		}
@@ -145,10 +150,11 @@ func getSymbolVols(symbols []string, tf string, num int, endMS int64) ([]SymbolV
 			symbolVols = append(symbolVols, SymbolVol{symbol, vol, price})
 		}
 	}
-	exchange := exg.Default
-	err := orm.FastBulkOHLCV(exchange, symbols, tf, 0, endMS, num, callBack)
-	if err != nil {
-		return nil, err
+	for exchange, items := range exg.GroupPairs(symbols) {
+		err := orm.FastBulkOHLCV(exchange, items, tf, 0, endMS, num, callBack)
+		if err != nil {
+			return nil, err
+		}
 	}
 	if len(symbolVols) == 0 {
 		msg := fmt.Sprintf("No data found for %d pairs at %v", len(symbols), endMS)
@@ -160,7 +166,6 @@ func getSymbolVols(symbols []string, tf string, num int, endMS int64) ([]SymbolV
 func filterByMinCost(symbols []SymbolVol) ([]string, map[string]float64) {
 	res := make([]string, 0, len(symbols))
 	skip := make(map[string]float64)
-	exchange := exg.Default
 	accCost := float64(0)
 	for name := range config.Accounts {
 		curCost := config.GetStakeAmount(name)
@@ -169,7 +174,7 @@ func filterByMinCost(symbols []SymbolVol) ([]string, map[string]float64) {
 		}
 	}
 	for _, item := range symbols {
-		mar, err := exchange.GetMarket(item.Symbol)
+		mar, err := exg.GetPairExg(item.Symbol).GetMarket(core.PairSymbol(item.Symbol))
 		if err != nil {
 			if ShowLog {
 				log.Warn("no market found", zap.String("symbol", item.Symbol))
@@ -229,9 +234,9 @@ func (f *PriceFilter) Filter(symbols []string, timeMS int64) ([]string, *errs.Er
 }
 
 func (f *PriceFilter) validatePrice(symbol string, price float64) bool {
-	exchange := exg.Default
+	exchange := exg.GetPairExg(symbol)
 	if f.Precision > 0 {
-		pip, err := exchange.PriceOnePip(symbol)
+		pip, err := exchange.PriceOnePip(core.PairSymbol(symbol))
 		if err != nil {
 			log.Error("get one pip of price fail", zap.String("symbol", symbol))
 			return false
@@ -244,7 +249,7 @@ func (f *PriceFilter) validatePrice(symbol string, price float64) bool {
 	}
 
 	if f.MaxUnitValue > 0 {
-		market, err := exchange.GetMarket(symbol)
+		market, err := exchange.GetMarket(core.PairSymbol(symbol))
 		if err != nil {
 			log.Error("PriceFilter drop, market not exist", zap.String("pair", symbol))
 			return false
@@ -312,9 +317,11 @@ func filterByOHLCV(symbols []string, timeFrame string, endMS int64, limit int, a
 			has[pair] = struct{}{}
 		}
 	}
-	err := orm.FastBulkOHLCV(exg.Default, symbols, timeFrame, 0, endMS, limit, handle)
-	if err != nil {
-		return nil, err
+	for exchange, items := range exg.GroupPairs(symbols) {
+		err := orm.FastBulkOHLCV(exchange, items, timeFrame, 0, endMS, limit, handle)
+		if err != nil {
+			return nil, err
+		}
 	}
 	var res = make([]string, 0, len(has))
 	for _, pair := range symbols {
@@ -495,3 +502,67 @@ func (f *ShuffleFilter) Filter(symbols []string, timeMS int64) ([]string, *errs.
 	})
 	return symbols, nil
 }
+
+func (f *ExprFilter) Filter(symbols []string, timeMS int64) ([]string, *errs.Error) {
+	if f.Expr == "" && f.Sort == "" {
+		return symbols, nil
+	}
+	if f.Timeframe == "" {
+		f.Timeframe = "1d"
+	}
+	if f.BackNum <= 0 {
+		f.BackNum = 100
+	}
+	var err *errs.Error
+	if f.expr == nil && f.Expr != "" {
+		if f.expr, err = parseExpr(f.Expr); err != nil {
+			return nil, err
+		}
+	}
+	if f.sort == nil && f.Sort != "" {
+		if f.sort, err = parseExpr(f.Sort); err != nil {
+			return nil, err
+		}
+	}
+	var sortVals = make(map[string]float64)
+	res, err := filterByOHLCV(symbols, f.Timeframe, timeMS, f.BackNum, core.AdjFront, func(s string, klines []*banexg.Kline) bool {
+		if len(klines) == 0 {
+			sortVals[s] = math.NaN()
+			return f.AllowEmpty
+		}
+		vals := evalExprs(f.Timeframe, klines, f.expr, f.sort)
+		if f.expr != nil && !isTrue(vals[0]) {
+			log.Info("ExprFilter drop", zap.String("pair", s), zap.Float64("v", vals[0]))
+			return false
+		}
+		sortVals[s] = vals[1]
+		return true
+	})
+	if err != nil || f.sort == nil {
+		return res, err
+	}
+	// pairs without value are placed last 无值的标的排在最后
+	sort.SliceStable(res, func(i, j int) bool {
+		a, b := sortVals[res[i]], sortVals[res[j]]
+		if math.IsNaN(b) {
+			return !math.IsNaN(a)
+		} else if math.IsNaN(a) {
+			return false
+		}
+		if f.Order == "asc" {
+			return a < b
+		}
+		return a > b
+	})
+	topN := f.TopN
+	if f.TopRate > 0 {
+		rateNum := int(math.Round(float64(len(res)) * f.TopRate))
+		if topN == 0 || topN > rateNum {
+			topN = rateNum
+		}
+	}
+	if topN > 0 && topN < len(res) {
+		res = res[:topN]
+	}
+	return res, nil
+}
@@ -1,6 +1,7 @@
 package goods
 
 import (
+	"github.com/banbox/banbot/core"
 	"github.com/banbox/banexg/errs"
 )
 
@@ -10,6 +11,15 @@ type IFilter interface {
 	Filter(pairs []string, timeMS int64) ([]string, *errs.Error)
 }
 
+/*
+ICtxFilter
+Filters which record state like BanPairsUntil into the bot context should implement this, FilterCtx is called instead of Filter when running.
+需要向机器人上下文记录状态(如BanPairsUntil)的过滤器应实现此接口，运行时将调用FilterCtx代替Filter。
+*/
+type ICtxFilter interface {
+	FilterCtx(c *core.BotCtx, pairs []string, timeMS int64) ([]string, *errs.Error)
+}
+
 type IProducer interface {
 	IFilter
 	GenSymbols(timeMS int64) ([]string, *errs.Error)
@@ -98,3 +108,24 @@ type ShuffleFilter struct {
 	BaseFilter
 	Seed int `yaml:"seed" mapstructure:"seed,omitempty"`
 }
+
+/*
+ExprFilter
+Filter and rank pairs by expressions over banta indicators computed on Timeframe klines of latest BackNum bars.
+Expr keeps pairs whose value of the last bar is nonzero, e.g. `ATR(14)/close > 0.02`;
+Sort ranks pairs by the value of the last bar, e.g. `ROC(20)`.
+基于最近BackNum根Timeframe周期K线计算的banta指标表达式过滤和排序标的。
+Expr保留最后一根bar值非零的标的，如`ATR(14)/close > 0.02`；Sort按最后一根bar的值排序，如`ROC(20)`。
+*/
+type ExprFilter struct {
+	BaseFilter
+	Timeframe string  `yaml:"timeframe" mapstructure:"timeframe,omitempty"` // default 1d 默认1d
+	BackNum   int     `yaml:"back_num" mapstructure:"back_num,omitempty"`   // default 100 默认100
+	Expr      string  `yaml:"expr" mapstructure:"expr,omitempty"`
+	Sort      string  `yaml:"sort" mapstructure:"sort,omitempty"`
+	Order     string  `yaml:"order" mapstructure:"order,omitempty"` // desc/asc, default desc 默认desc
+	TopN      int     `yaml:"top_n" mapstructure:"top_n,omitempty"`
+	TopRate   float64 `yaml:"top_rate" mapstructure:"top_rate,omitempty"`
+	expr      *exprNode
+	sort      *exprNode
+}
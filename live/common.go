@@ -33,8 +33,10 @@ func CronRefreshPairs(dp data.IProvider) {
 			if curMS-lastRefreshMS < config.MinPairCronGapMS {
 				return
 			}
+			refreshLock.Lock()
+			defer refreshLock.Unlock()
 			lastRefreshMS = curMS
-			err := opt.RefreshPairJobs(dp, true, false, nil)
+			err := opt.RefreshPairJobs(biz.DefCtx, dp, true, false, nil)
 			if err != nil {
 				log.Error("RefreshPairJobs fail", zap.Error(err))
 			}
@@ -48,7 +50,9 @@ func CronRefreshPairs(dp data.IProvider) {
 func CronLoadMarkets() {
 	// 2小时更新一次市场行情
 	_, err := core.Cron.AddFunc("30 3 */2 * * *", func() {
-		_, _ = orm.LoadMarkets(exg.Default, true)
+		for _, exchange := range exg.AllExgs() {
+			_, _ = orm.LoadMarkets(exchange, true)
+		}
 	})
 	if err != nil {
 		log.Error("add CronLoadMarkets fail", zap.Error(err))
@@ -67,7 +71,7 @@ func CronFatalLossCheck() {
 	}
 	cronStr := fmt.Sprintf("35 */%v * * * *", min(5, minIntv))
 	maxIntv := slices.Max(checkIntvs)
-	_, err := core.Cron.AddFunc(cronStr, biz.MakeCheckFatalStop(maxIntv))
+	_, err := core.Cron.AddFunc(cronStr, biz.DefCtx.MakeCheckFatalStop(maxIntv))
 	if err != nil {
 		log.Error("add CronFatalLossCheck fail", zap.Error(err))
 	}
@@ -143,7 +147,7 @@ func CronKlineSummary() {
 func CronCheckTriggerOds() {
 	// Check every minute 15 seconds to see if the limit order submission is triggered
 	// 在每分钟的15s检查是否触发限价单提交
-	_, err_ := core.Cron.AddFunc("15 * * * * *", biz.VerifyTriggerOds)
+	_, err_ := core.Cron.AddFunc("15 * * * * *", biz.DefCtx.VerifyTriggerOds)
 	if err_ != nil {
 		log.Error("add VerifyTriggerOds fail", zap.Error(err_))
 	}
@@ -167,31 +171,34 @@ func StartLoopBalancePositions() {
 
 func updateBalancePos() {
 	for account := range config.Accounts {
-		odList, lock := ormo.GetOpenODs(account)
+		odList, lock := ormo.DefCtx.GetOpenODs(account)
 		lock.Lock()
 		odNum := len(odList)
 		lock.Unlock()
 		if odNum == 0 {
 			continue
 		}
-		odMgr := biz.GetLiveOdMgr(account)
-		_, err := odMgr.SyncLocalOrders()
-		if err != nil {
-			log.Error("SyncLocalOrders fail", zap.String("acc", account), zap.Error(err))
+		for _, odMgr := range biz.DefCtx.GetLiveOdMgrs(account) {
+			_, err := odMgr.SyncLocalOrders()
+			if err != nil {
+				log.Error("SyncLocalOrders fail", zap.String("acc", account), zap.Error(err))
+			}
 		}
 		updateAccBalance(account)
 	}
 }
 
 func updateAccBalance(account string) {
-	wallet := biz.GetWallets(account)
-	rsp, err := exg.Default.FetchBalance(map[string]interface{}{
-		banexg.ParamAccount: account,
-	})
-	if err != nil {
-		log.Error("UpdateBalance fail", zap.String("acc", account), zap.Error(err))
-	} else {
-		biz.UpdateWalletByBalances(wallet, rsp)
+	for _, wallet := range biz.DefCtx.GetAccWallets(account) {
+		rsp, err := wallet.VenueExg().FetchBalance(map[string]interface{}{
+			banexg.ParamAccount: account,
+		})
+		if err != nil {
+			log.Error("UpdateBalance fail", zap.String("acc", account), zap.String("market", wallet.VenueMarket()),
+				zap.Error(err))
+		} else {
+			biz.UpdateWalletByBalances(wallet, rsp)
+		}
 	}
 }
 
@@ -214,7 +221,7 @@ func sendOrderMsg(od *ormo.InOutOrder, isEnter bool) {
 	if subOd.Status != ormo.OdStatusClosed || filled == 0 {
 		return
 	}
-	account := ormo.GetTaskAcc(od.TaskID)
+	account := ormo.DefCtx.GetTaskAcc(od.TaskID)
 	rpc.SendMsg(map[string]interface{}{
 		"type":          msgType,
 		"account":       account,
@@ -16,28 +16,32 @@ import (
 	"github.com/banbox/banbot/rpc"
 	"github.com/banbox/banbot/strat"
 	"github.com/banbox/banbot/web"
+	weblive "github.com/banbox/banbot/web/live"
 	"github.com/banbox/banexg/errs"
 	"github.com/banbox/banexg/log"
 	"go.uber.org/zap"
 )
 
 type CryptoTrader struct {
-	biz.Trader
+	*biz.Trader
 	dp *data.LiveProvider
 }
 
 func NewCryptoTrader() *CryptoTrader {
-	return &CryptoTrader{}
+	return &CryptoTrader{Trader: biz.NewTrader(biz.DefCtx)}
 }
 
 func (t *CryptoTrader) Init() *errs.Error {
-	config.LoadPerfs(config.GetDataDir())
+	config.LoadPerfs(core.DefCtx, config.GetDataDir())
 	dp, err := data.NewLiveProvider(t.FeedKLine, t.OnEnvEnd)
 	if err != nil {
 		return err
 	}
 	t.dp = dp
-	err = ormo.InitTask(true, config.GetDataDir())
+	weblive.SpiderConnected = func() bool {
+		return !dp.IsClosed()
+	}
+	err = ormo.DefCtx.InitTask(true, config.GetDataDir())
 	if err != nil {
 		return err
 	}
@@ -61,7 +65,7 @@ func (t *CryptoTrader) Init() *errs.Error {
 	if err != nil {
 		return err
 	}
-	err = opt.RefreshPairJobs(dp, true, true, nil)
+	err = opt.RefreshPairJobs(biz.DefCtx, dp, true, true, nil)
 	lastRefreshMS = btime.TimeMS()
 	// add exit callback
 	core.ExitCalls = append(core.ExitCalls, exitCleanUp)
@@ -70,17 +74,22 @@ func (t *CryptoTrader) Init() *errs.Error {
 
 func (t *CryptoTrader) initOdMgr() *errs.Error {
 	if !core.EnvReal {
-		biz.InitLocalOrderMgr(t.orderCB, true)
+		biz.DefCtx.InitLocalOrderMgr(t.orderCB, true)
 		return nil
 	}
-	biz.InitLiveOrderMgr(t.orderCB)
+	biz.DefCtx.InitLiveOrderMgr(t.orderCB)
 	for account := range config.Accounts {
-		odMgr := biz.GetLiveOdMgr(account)
-		oldList, newList, delList, err := odMgr.SyncExgOrders()
-		if err != nil {
-			return err
+		var oldList, newList, delList []*ormo.InOutOrder
+		for _, odMgr := range biz.DefCtx.GetLiveOdMgrs(account) {
+			olds, news, dels, err := odMgr.SyncExgOrders()
+			if err != nil {
+				return err
+			}
+			oldList = append(oldList, olds...)
+			newList = append(newList, news...)
+			delList = append(delList, dels...)
 		}
-		openOds, lock := ormo.GetOpenODs(account)
+		openOds, lock := ormo.DefCtx.GetOpenODs(account)
 		lock.Lock()
 		msg := fmt.Sprintf("orders: %d restored, %d deleted, %d added, %d opened", len(oldList), len(delList), len(newList), len(openOds))
 		lock.Unlock()
@@ -118,7 +127,7 @@ func (t *CryptoTrader) FeedKLine(bar *orm.InfoKline) {
 
 func delayExecBatch() {
 	time.AfterFunc(time.Millisecond*core.DelayBatchMS, func() {
-		waitNum := biz.TryFireBatches(btime.UTCStamp())
+		waitNum := biz.DefCtx.TryFireBatches(btime.UTCStamp())
 		if waitNum > 0 {
 			// There are TF cycles that have not yet been completed, and they are postponed for a few seconds to trigger again
 			// 有尚未完成的tf周期，推迟几秒再次触发
@@ -134,10 +143,13 @@ func (t *CryptoTrader) orderCB(od *ormo.InOutOrder, isEnter bool) {
 func (t *CryptoTrader) startJobs() {
 	// Listen to account order flow, process user orders, and consume order queues
 	// 监听账户订单流、处理用户下单、消费订单队列
-	biz.StartLiveOdMgr()
+	biz.DefCtx.StartLiveOdMgr()
 	// Refresh trading pairs regularly
 	// 定期刷新交易对
 	CronRefreshPairs(t.dp)
+	// Reload config by SIGHUP or api
+	// 通过SIGHUP或api重新加载配置
+	listenReload(t.dp)
 	// Refresh the market regularly
 	// 定时刷新市场行情
 	CronLoadMarkets()
@@ -160,18 +172,20 @@ func (t *CryptoTrader) startJobs() {
 }
 
 func exitCleanUp() {
-	err := biz.CleanUpOdMgr()
+	err := biz.DefCtx.CleanUpOdMgr()
 	if err != nil {
 		log.Error("clean odMgr fail", zap.Error(err))
 	}
-	strat.ExitStratJobs()
+	strat.DefCtx.ExitStratJobs()
 	core.Cron.Stop()
-	err = exg.Default.Close()
-	if err != nil {
-		log.Error("close exg fail", zap.Error(err))
+	for _, exchange := range exg.AllExgs() {
+		err = exchange.Close()
+		if err != nil {
+			log.Error("close exg fail", zap.String("exg", exchange.Info().ID), zap.Error(err))
+		}
 	}
 	for account := range config.Accounts {
-		openOds, lock := ormo.GetOpenODs(account)
+		openOds, lock := ormo.DefCtx.GetOpenODs(account)
 		lock.Lock()
 		openNum := len(openOds)
 		lock.Unlock()
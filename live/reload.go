@@ -0,0 +1,97 @@
+package live
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/banbox/banbot/biz"
+	"github.com/banbox/banbot/btime"
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/data"
+	"github.com/banbox/banbot/opt"
+	"github.com/banbox/banbot/rpc"
+	"github.com/banbox/banbot/strat"
+	weblive "github.com/banbox/banbot/web/live"
+	"github.com/banbox/banexg/errs"
+	"github.com/banbox/banexg/log"
+	"go.uber.org/zap"
+)
+
+// Prevent concurrent refresh of pairs and jobs 防止并发刷新品种和任务
+var refreshLock sync.Mutex
+
+/*
+ReloadConfig
+Reload config files, apply changed run_policy, pairs, pairmgr, pairlists and stake settings without restart.
+Jobs are refreshed by RefreshPairJobs: new jobs are warmed up, removed jobs are handled by pairmgr.pos_on_rotation,
+running jobs whose policy changed get a rebuilt strategy and keep their orders.
+重新加载配置文件，无需重启即可应用变化的run_policy、pairs、pairmgr、pairlists和开单金额设置。
+通过RefreshPairJobs刷新任务：新任务会预热，移除的任务按pairmgr.pos_on_rotation处理，策略配置变化的运行中任务会重建策略并保留订单。
+*/
+func ReloadConfig(dp data.IProvider) (*config.ReloadResult, *errs.Error) {
+	refreshLock.Lock()
+	defer refreshLock.Unlock()
+	oldPct := config.StakePct
+	res, err := config.ReloadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Applied) > 0 {
+		if config.StakePct != oldPct {
+			// recalculate the stake amount by new stake_pct 按新的stake_pct重新计算开单金额
+			for account, acc := range config.Accounts {
+				acc.StakePctAmt = 0
+				biz.DefCtx.GetWallets(account).TryUpdateStakePctAmt()
+			}
+		}
+		// stake settings take effect at next entry, others need to refresh jobs
+		// 开单金额设置在下次入场时生效，其他需刷新任务
+		needRefresh := slices.ContainsFunc(res.Applied, func(k string) bool {
+			return !strings.HasPrefix(k, "stake_") && k != "max_stake_amt"
+		})
+		if needRefresh {
+			if slices.Contains(res.Applied, "run_policy") {
+				// policy filters are cached by policy id 策略的品种过滤器按策略ID缓存
+				strat.DefCtx.ResetPolFilters()
+			}
+			err = opt.RefreshPairJobs(biz.DefCtx, dp, true, false, nil)
+			if err != nil {
+				return res, err
+			}
+			lastRefreshMS = btime.TimeMS()
+		}
+	}
+	log.Info("config reloaded", zap.Strings("applied", res.Applied), zap.Strings("restart", res.Restart))
+	msg := fmt.Sprintf("config reloaded, applied: %v", res.Applied)
+	if len(res.Restart) > 0 {
+		msg += fmt.Sprintf(", need restart: %v", res.Restart)
+	}
+	rpc.SendMsg(map[string]interface{}{
+		"type":   rpc.MsgTypeStatus,
+		"status": msg,
+	})
+	return res, nil
+}
+
+// listenReload reload config when receiving SIGHUP 收到SIGHUP时重新加载配置
+func listenReload(dp data.IProvider) {
+	weblive.ReloadConfig = func() (*config.ReloadResult, *errs.Error) {
+		return ReloadConfig(dp)
+	}
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+	go func() {
+		for range sigChan {
+			log.Info("receive SIGHUP, reloading config")
+			_, err := ReloadConfig(dp)
+			if err != nil {
+				log.Error("reload config fail", zap.Error(err))
+			}
+		}
+	}()
+}
@@ -62,7 +62,6 @@ func RunTradeClose(args []string) error {
 }
 
 func closeOrdersByPos(accMap map[string]bool, pairMap map[string]bool) error {
-	exchange := exg.Default
 	odType := banexg.OdTypeMarket
 	closeNum := 0
 	for account := range config.Accounts {
@@ -71,34 +70,37 @@ func closeOrdersByPos(accMap map[string]bool, pairMap map[string]bool) error {
 				continue
 			}
 		}
-		posList, err := exchange.FetchAccountPositions(nil, map[string]interface{}{
-			banexg.ParamAccount: account,
-		})
-		if err != nil {
-			return err
-		}
-		log.Info("fetch account pos", zap.String("acc", account), zap.Int("num", len(posList)))
-		exitPos := make([]*banexg.Position, 0, len(posList))
-		for _, pos := range posList {
-			if _, ok := pairMap[pos.Symbol]; !ok && len(pairMap) > 0 {
+		for _, exchange := range exg.AllExgs() {
+			info := exchange.Info()
+			isContract := banexg.IsContract(info.MarketType)
+			if !isContract {
+				// spot has no position 现货没有持仓
+				log.Info("skip market without position", zap.String("exg", info.ID), zap.String("market", info.MarketType))
 				continue
 			}
-			exitPos = append(exitPos, pos)
-		}
-		if len(exitPos) > 0 {
-			for _, pos := range exitPos {
+			posList, err := exchange.FetchAccountPositions(nil, map[string]interface{}{
+				banexg.ParamAccount: account,
+			})
+			if err != nil {
+				return err
+			}
+			log.Info("fetch account pos", zap.String("acc", account), zap.String("market", info.MarketType),
+				zap.Int("num", len(posList)))
+			for _, pos := range posList {
+				pair := core.PairKey(info.ID, info.MarketType, pos.Symbol)
+				if _, ok := pairMap[pair]; !ok && len(pairMap) > 0 {
+					continue
+				}
 				isShort := pos.Side == banexg.PosSideShort
 				exitSide := banexg.OdSideSell
 				params := map[string]interface{}{
 					banexg.ParamAccount:       account,
 					banexg.ParamClientOrderId: fmt.Sprintf("bancli_%v", rand.Intn(1000)),
 				}
-				if core.IsContract {
-					params[banexg.ParamPositionSide] = "LONG"
-					if isShort {
-						params[banexg.ParamPositionSide] = "SHORT"
-						exitSide = banexg.OdSideBuy
-					}
+				params[banexg.ParamPositionSide] = "LONG"
+				if isShort {
+					params[banexg.ParamPositionSide] = "SHORT"
+					exitSide = banexg.OdSideBuy
 				}
 				closeNum += 1
 				res, err := exchange.CreateOrder(pos.Symbol, odType, exitSide, pos.Contracts, 0, params)
@@ -106,11 +108,11 @@ func closeOrdersByPos(accMap map[string]bool, pairMap map[string]bool) error {
 					return err
 				}
 				if res.Status == "filled" {
-					log.Info("close pos ok", zap.String("acc", account), zap.String("pair", pos.Symbol),
+					log.Info("close pos ok", zap.String("acc", account), zap.String("pair", pair),
 						zap.String("side", pos.Side), zap.Float64("price", res.Average),
 						zap.Float64("amount", res.Filled))
 				} else {
-					log.Warn("close fail", zap.String("acc", account), zap.String("pair", pos.Symbol),
+					log.Warn("close fail", zap.String("acc", account), zap.String("pair", pair),
 						zap.String("side", pos.Side), zap.String("status", res.Status))
 				}
 			}
@@ -121,11 +123,11 @@ func closeOrdersByPos(accMap map[string]bool, pairMap map[string]bool) error {
 }
 
 func closeOrdersByLocal(accMap map[string]bool, pairMap map[string]bool, stratMap map[string]bool) error {
-	err := ormo.InitTask(true, config.GetDataDir())
+	err := ormo.DefCtx.InitTask(true, config.GetDataDir())
 	if err != nil {
 		return err
 	}
-	biz.InitLiveOrderMgr(sendOrderMsg)
+	biz.DefCtx.InitLiveOrderMgr(sendOrderMsg)
 	sess, conn, err := ormo.Conn(orm.DbTrades, true)
 	if err != nil {
 		return err
@@ -138,12 +140,18 @@ func closeOrdersByLocal(accMap map[string]bool, pairMap map[string]bool, stratMa
 				continue
 			}
 		}
-		odMgr := biz.GetLiveOdMgr(account)
-		oldList, newList, delList, err := odMgr.SyncExgOrders()
-		if err != nil {
-			return err
+		var oldList, newList, delList []*ormo.InOutOrder
+		for _, liveMgr := range biz.DefCtx.GetLiveOdMgrs(account) {
+			olds, news, dels, err := liveMgr.SyncExgOrders()
+			if err != nil {
+				return err
+			}
+			oldList = append(oldList, olds...)
+			newList = append(newList, news...)
+			delList = append(delList, dels...)
 		}
-		openOds, lock := ormo.GetOpenODs(account)
+		odMgr := biz.DefCtx.GetOdMgr(account)
+		openOds, lock := ormo.DefCtx.GetOpenODs(account)
 		var exitOds []*ormo.InOutOrder
 		lock.Lock()
 		msg := fmt.Sprintf("orders: %d restored, %d deleted, %d added, %d opened", len(oldList), len(delList), len(newList), len(openOds))
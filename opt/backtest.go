@@ -12,8 +12,10 @@ import (
 	"github.com/banbox/banbot/orm/ormo"
 	"github.com/banbox/banbot/strat"
 	"github.com/banbox/banbot/utils"
+	"github.com/banbox/banexg"
 	"github.com/banbox/banexg/errs"
 	"github.com/banbox/banexg/log"
+	utils2 "github.com/banbox/banexg/utils"
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 	"math"
@@ -27,10 +29,13 @@ const (
 )
 
 type BackTestLite struct {
-	biz.Trader
+	*biz.Trader
 	*BTResult
-	dp    *data.HistProvider
-	isOpt bool // whether is hyper optimization
+	ctx         *biz.Ctx // state of this backtest 此回测的状态
+	dp          *data.HistProvider
+	isOpt       bool  // whether is hyper optimization
+	fatalIntv   int64 // interval of fatal_stop check in ms, -1 to disable fatal_stop检查间隔毫秒，-1禁用
+	nextFatalMS int64 // time of next fatal_stop check 下次fatal_stop检查时间
 }
 
 type BackTest struct {
@@ -39,47 +44,55 @@ type BackTest struct {
 	PBar        *utils.StagedPrg
 	nextRefresh int64 // The time of the next refresh of the trading pair 下一次刷新交易对的时间
 	schedule    cron.Schedule
+	// OnReport receive the profit percent at each plot checkpoint, return true to stop this backtest early
+	// OnReport 在每个绘图检查点接收收益百分比，返回true时提前停止此回测
+	OnReport func(step int, value float64) bool
+	PrunedAt int // progress percent of time range when stopped by OnReport 被OnReport停止时的时间进度百分比
+	lastStep int
 }
 
 /*
 NewBackTestLite 创建一个临时内部回测，仅用于寻找回测未平仓订单来接力
 Create a temporary internal backtest, solely for the purpose of finding backtest open orders to relay.
 */
-func NewBackTestLite(isOpt bool, onBar data.FnPairKline, getEnd data.FnGetInt64, pBar *utils.StagedPrg) *BackTestLite {
+func NewBackTestLite(ctx *biz.Ctx, isOpt bool, onBar data.FnPairKline, getEnd data.FnGetInt64, pBar *utils.StagedPrg) *BackTestLite {
 	b := &BackTestLite{
+		Trader:   biz.NewTrader(ctx),
 		BTResult: NewBTResult(),
+		ctx:      ctx,
 		isOpt:    isOpt,
 	}
-	biz.InitFakeWallets()
-	wallets := biz.GetWallets(config.DefAcc)
+	ctx.InitFakeWallets()
+	wallets := ctx.GetWallets(config.DefAcc)
 	b.TotalInvest = wallets.TotalLegal(nil, false)
 	if onBar == nil {
 		onBar = func(bar *orm.InfoKline) {
 			b.FeedKLine(bar)
 		}
 	}
-	b.dp = data.NewHistProvider(onBar, b.OnEnvEnd, getEnd, !isOpt, pBar)
-	biz.InitLocalOrderMgr(b.orderCB, !isOpt)
+	b.dp = data.NewHistProvider(ctx.Ctx, onBar, b.FeedTrades, b.OnEnvEnd, getEnd, !isOpt, pBar)
+	ctx.InitLocalOrderMgr(b.orderCB, !isOpt)
 	return b
 }
 
 func (b *BackTestLite) FeedKLine(bar *orm.InfoKline) bool {
 	b.BarNum += 1
-	curTime := btime.TimeMS()
+	curTime := b.ctx.TimeMS()
 	if !bar.IsWarmUp {
-		if curTime > strat.LastBatchMS {
+		if curTime > b.ctx.LastBatchMS {
 			// Enter the next timeframe and trigger the batch entry callback
 			// 进入下一个时间帧，触发批量入场回调
-			waitNum := biz.TryFireBatches(curTime)
+			waitNum := b.ctx.TryFireBatches(curTime)
 			if waitNum > 0 {
 				panic(fmt.Sprintf("batch job exec fail, wait: %v", waitNum))
 			}
-			strat.LastBatchMS = curTime
+			b.ctx.LastBatchMS = curTime
 		}
 		if curTime > b.lastTime {
 			b.lastTime = curTime
 			b.TimeNum += 1
-			core.CheckWallets = true
+			b.ctx.CheckWallets = true
+			b.checkFatalStop(curTime)
 		}
 	}
 	err := b.Trader.FeedKline(bar)
@@ -91,37 +104,94 @@ func (b *BackTestLite) FeedKLine(bar *orm.InfoKline) bool {
 		}
 		return false
 	}
-	if !core.BotRunning {
+	if !b.ctx.BotRunning {
 		b.dp.Terminate()
 		return false
 	}
 	return true
 }
 
+/*
+FeedTrades
+Feed trades of pair in tick backtest
+逐笔回测中推送pair的成交记录
+*/
+func (b *BackTestLite) FeedTrades(pair string, trades []*banexg.Trade) {
+	err := b.Trader.FeedTrades(pair, trades)
+	if err != nil {
+		if err.Code == core.ErrLiquidation {
+			b.onLiquidation(pair)
+		} else {
+			log.Error("FeedTrades fail", zap.String("p", pair), zap.Error(err))
+		}
+		return
+	}
+	if !b.ctx.BotRunning {
+		b.dp.Terminate()
+	}
+}
+
+/*
+checkFatalStop
+Simulate the fatal_stop cron of live trading with closed orders in HistODs, checked at the same interval.
+用HistODs中的已平仓订单模拟实盘的fatal_stop定时检查，检查间隔与实盘相同
+*/
+func (b *BackTestLite) checkFatalStop(curMS int64) {
+	if b.fatalIntv == 0 {
+		b.fatalIntv = -1
+		checkIntvs := utils.KeysOfMap(config.FatalStop)
+		if len(checkIntvs) > 0 && slices.Min(checkIntvs) >= 1 {
+			b.fatalIntv = int64(min(5, slices.Min(checkIntvs))) * 60000
+		}
+	}
+	if b.fatalIntv < 0 || curMS < b.nextFatalMS {
+		return
+	}
+	b.nextFatalMS = utils2.AlignTfMSecs(curMS, b.fatalIntv) + b.fatalIntv
+	minTimeMS := curMS - int64(slices.Max(utils.KeysOfMap(config.FatalStop)))*60000
+	var orders []*ormo.InOutOrder
+	for i := len(b.ctx.HistODs) - 1; i >= 0; i-- {
+		od := b.ctx.HistODs[i]
+		if od.ExitAt < minTimeMS {
+			break
+		}
+		orders = append(orders, od)
+	}
+	backMins, lossRate := b.ctx.ApplyFatalStop(config.DefAcc, orders, 0)
+	if backMins > 0 {
+		b.FatalStops = append(b.FatalStops, &FatalStopHit{
+			TimeMS:  curMS,
+			Minutes: backMins,
+			LossPct: math.Round(lossRate*10000) / 100,
+			UntilMS: b.ctx.NoEnterUntil[config.DefAcc],
+		})
+	}
+}
+
 func (b *BackTestLite) onLiquidation(symbol string) {
-	date := btime.ToDateStr(btime.TimeMS(), "")
+	date := btime.ToDateStr(b.ctx.TimeMS(), "")
 	if config.ChargeOnBomb {
-		wallets := biz.GetWallets(config.DefAcc)
+		wallets := b.ctx.GetWallets(config.DefAcc)
 		oldVal := wallets.TotalLegal(nil, false)
-		biz.InitFakeWallets(symbol)
+		b.ctx.InitFakeWallets(symbol)
 		newVal := wallets.TotalLegal(nil, false)
 		b.TotalInvest += newVal - oldVal
 		log.Warn(fmt.Sprintf("wallet %s BOMB at %s, reset wallet and continue..", symbol, date))
 	} else {
 		log.Warn(fmt.Sprintf("wallet %s BOMB at %s, exit", symbol, date))
-		core.StopAll()
+		b.ctx.BotRunning = false
 		b.dp.Terminate()
 	}
 }
 
 func (b *BackTestLite) orderCB(order *ormo.InOutOrder, isEnter bool) {
 	if isEnter {
-		openNum := ormo.OpenNum(config.DefAcc, ormo.InOutStatusPartEnter)
+		openNum := b.ctx.OpenNum(config.DefAcc, ormo.InOutStatusPartEnter)
 		if openNum > b.MaxOpenOrders {
 			b.MaxOpenOrders = openNum
 		}
 	} else {
-		wallets := biz.GetWallets(config.DefAcc)
+		wallets := b.ctx.GetWallets(config.DefAcc)
 		// 更新单笔开单金额
 		wallets.TryUpdateStakePctAmt()
 		if config.DrawBalanceOver > 0 {
@@ -133,7 +203,12 @@ func (b *BackTestLite) orderCB(order *ormo.InOutOrder, isEnter bool) {
 	}
 }
 
-func NewBackTest(isOpt bool, outDir string) *BackTest {
+/*
+NewBackTest
+Create a backtest running in ctx, which can be created by NewBTContext
+创建一个在ctx中运行的回测，ctx可通过NewBTContext创建
+*/
+func NewBackTest(ctx *biz.Ctx, isOpt bool, outDir string) *BackTest {
 	stages := []string{"init", "listMs", "loadPairs", "tfScores", "loadJobs", "warmJobs", "downKline", "runBT"}
 	stgWeis := []float64{1, 1, 2, 2, 1, 2, 10, 10}
 	b := &BackTest{
@@ -143,9 +218,9 @@ func NewBackTest(isOpt bool, outDir string) *BackTest {
 		if b.nextRefresh > 0 {
 			return b.nextRefresh
 		}
-		return config.TimeRange.EndMS
+		return ctx.TimeRange().EndMS
 	}
-	b.BackTestLite = NewBackTestLite(isOpt, b.FeedKLine, getEnd, b.PBar)
+	b.BackTestLite = NewBackTestLite(ctx, isOpt, b.FeedKLine, getEnd, b.PBar)
 	if outDir == "" && !isOpt {
 		hash, err := config.Data.HashCode()
 		if err != nil {
@@ -154,12 +229,12 @@ func NewBackTest(isOpt bool, outDir string) *BackTest {
 		outDir = fmt.Sprintf("%s/backtest/%s", config.GetDataDir(), hash)
 	}
 	b.OutDir = config.ParsePath(outDir)
-	config.LoadPerfs(config.GetDataDir())
+	config.LoadPerfs(ctx.BotCtx, config.GetDataDir())
 	return b
 }
 
 func (b *BackTest) Init() *errs.Error {
-	btime.CurTimeMS = config.TimeRange.StartMS
+	b.ctx.CurTimeMS = b.ctx.TimeRange().StartMS
 	b.MinReal = math.MaxFloat64
 	if b.OutDir != "" {
 		err_ := os.MkdirAll(b.OutDir, 0755)
@@ -167,7 +242,7 @@ func (b *BackTest) Init() *errs.Error {
 			return errs.New(core.ErrIOWriteFail, err_)
 		}
 	}
-	err := ormo.InitTask(!b.isOpt, b.OutDir)
+	err := b.ctx.InitTask(!b.isOpt, b.OutDir)
 	if err != nil {
 		return err
 	}
@@ -182,21 +257,21 @@ func (b *BackTest) Init() *errs.Error {
 	}
 	b.PBar.SetProgress("listMs", 1)
 	// 交易对初始化
-	err = RefreshPairJobs(b.dp, !b.isOpt, true, b.PBar)
+	err = RefreshPairJobs(b.ctx, b.dp, !b.isOpt, true, b.PBar)
 	return err
 }
 
 func (b *BackTest) FeedKLine(bar *orm.InfoKline) {
-	curTime := btime.TimeMS()
+	curTime := b.ctx.TimeMS()
 	ok := b.BackTestLite.FeedKLine(bar)
-	if !bar.IsWarmUp && core.CheckWallets {
-		core.CheckWallets = false
+	if !bar.IsWarmUp && b.ctx.CheckWallets {
+		b.ctx.CheckWallets = false
 		b.logState(bar.Time, curTime)
 	}
 	if ok && b.nextRefresh > 0 && bar.Time >= b.nextRefresh {
 		// 刷新交易对
 		b.nextRefresh = b.schedule.Next(time.UnixMilli(bar.Time)).UnixMilli()
-		err := RefreshPairJobs(b.dp, !b.isOpt, false, nil)
+		err := RefreshPairJobs(b.ctx, b.dp, !b.isOpt, false, nil)
 		dateStr := btime.ToDateStr(curTime, "")
 		if err != nil {
 			log.Error("RefreshPairJobs", zap.String("date", dateStr), zap.Error(err))
@@ -232,22 +307,22 @@ func (b *BackTest) Run() {
 		return
 	}
 	btCost := btime.UTCTime() - btStart
-	err = biz.GetOdMgr(config.DefAcc).CleanUp()
-	strat.ExitStratJobs()
+	err = b.ctx.GetOdMgr(config.DefAcc).CleanUp()
+	b.ctx.ExitStratJobs()
 	if err != nil {
 		log.Error("backtest clean orders fail", zap.Error(err))
 		return
 	}
-	b.logPlot(biz.GetWallets(config.DefAcc), btime.TimeMS(), -1, -1)
+	b.logPlot(b.ctx.GetWallets(config.DefAcc), b.ctx.TimeMS(), -1, -1)
 	if !b.isOpt {
 		log.Info(fmt.Sprintf("Complete! cost: %.1fs, avg: %.1f bar/s", btCost, float64(b.BarNum)/btCost))
-		failOpens := strat.DumpAccFailOpens()
+		failOpens := b.ctx.DumpAccFailOpens()
 		if failOpens != "" {
 			log.Info("fail open tag nums:\n" + failOpens)
 		}
-		b.printBtResult()
+		b.printBtResult(b.ctx)
 	} else {
-		b.Collect()
+		b.Collect(b.ctx)
 	}
 }
 
@@ -277,7 +352,7 @@ func (b *BackTest) logState(startMS, timeMS int64) {
 		b.StartMS = startMS
 	}
 	b.EndMS = timeMS
-	wallets := biz.GetWallets(config.DefAcc)
+	wallets := b.ctx.GetWallets(config.DefAcc)
 	totalLegal := wallets.TotalLegal(nil, true)
 	b.MinReal = min(b.MinReal, totalLegal)
 	if totalLegal >= b.MaxReal {
@@ -288,9 +363,9 @@ func (b *BackTest) logState(startMS, timeMS int64) {
 		b.MaxDrawDownVal = max(b.MaxDrawDownVal, b.MaxReal-totalLegal)
 		maxOccupy := b.MaxReal - wallets.AvaLegal(nil)
 		b.MaxFundOccup = max(b.MaxFundOccup, maxOccupy)
-		b.MaxOccupForPair = max(b.MaxOccupForPair, maxOccupy/float64(len(core.Pairs)))
+		b.MaxOccupForPair = max(b.MaxOccupForPair, maxOccupy/float64(len(b.ctx.Pairs)))
 	}
-	odNum := ormo.OpenNum(config.DefAcc, ormo.InOutStatusPartEnter)
+	odNum := b.ctx.OpenNum(config.DefAcc, ormo.InOutStatusPartEnter)
 	if b.TimeNum%b.PlotEvery != 0 {
 		if odNum > b.Plots.tmpOdNum {
 			b.Plots.tmpOdNum = odNum
@@ -316,6 +391,7 @@ func (b *BackTest) logState(startMS, timeMS int64) {
 			Available:     make([]float64, 0, newNum),
 			UnrealizedPOL: make([]float64, 0, newNum),
 			WithDraw:      make([]float64, 0, newNum),
+			NoEnter:       make([]int, 0, newNum),
 		}
 		for i := 0; i < oldNum; i += splStep {
 			plots.Labels = append(plots.Labels, b.Plots.Labels[i])
@@ -326,20 +402,49 @@ func (b *BackTest) logState(startMS, timeMS int64) {
 			plots.Profit = append(plots.Profit, b.Plots.Profit[i])
 			plots.UnrealizedPOL = append(plots.UnrealizedPOL, b.Plots.UnrealizedPOL[i])
 			plots.WithDraw = append(plots.WithDraw, b.Plots.WithDraw[i])
+			plots.NoEnter = append(plots.NoEnter, slices.Max(b.Plots.NoEnter[i:i+splStep]))
 		}
 		b.Plots = plots
 		return
 	}
 	// 这里应使用bar的开始时间，避免多个时间周期运行时，大部分CheckMS未更新
 	b.logPlot(wallets, startMS, odNum, totalLegal)
+	if b.OnReport != nil {
+		b.reportStep(startMS, totalLegal)
+	}
+}
+
+/*
+reportStep
+Report the profit percent to OnReport when the progress percent increases, stop the backtest if required.
+Only BotRunning of this backtest's context is reset, so other trials are not affected.
+进度百分比增加时向OnReport报告收益百分比，需要时停止回测。
+只重置此回测上下文的BotRunning，不影响其他轮次。
+*/
+func (b *BackTest) reportStep(timeMS int64, totalLegal float64) {
+	timeRange := b.ctx.TimeRange()
+	totalMS := timeRange.EndMS - timeRange.StartMS
+	if totalMS <= 0 || b.TotalInvest <= 0 || b.PrunedAt > 0 {
+		return
+	}
+	step := int((timeMS - timeRange.StartMS) * 100 / totalMS)
+	if step <= b.lastStep || step >= 100 {
+		return
+	}
+	b.lastStep = step
+	value := (totalLegal - b.TotalInvest) * 100 / b.TotalInvest
+	if b.OnReport(step, value) {
+		b.PrunedAt = step
+		b.ctx.BotRunning = false
+	}
 }
 
 func (b *BackTest) logPlot(wallets *biz.BanWallets, timeMS int64, odNum int, totalLegal float64) {
 	if odNum < 0 {
-		odNum = ormo.OpenNum(config.DefAcc, ormo.InOutStatusPartEnter)
+		odNum = b.ctx.OpenNum(config.DefAcc, ormo.InOutStatusPartEnter)
 	}
 	jobNum := 0
-	jobMap := strat.GetJobs(wallets.Account)
+	jobMap := b.ctx.GetJobs(wallets.Account)
 	for _, jobs := range jobMap {
 		for _, j := range jobs {
 			if j.CheckMS+j.Env.TFMSecs >= timeMS {
@@ -354,8 +459,8 @@ func (b *BackTest) logPlot(wallets *biz.BanWallets, timeMS int64, odNum int, tot
 	profitLegal := wallets.UnrealizedPOLLegal(nil)
 	drawLegal := wallets.GetWithdrawLegal(nil)
 	curDate := btime.ToDateStr(timeMS, "")
-	b.donePftLegal += ormo.LegalDoneProfits(b.histOdOff)
-	b.histOdOff = len(ormo.HistODs)
+	b.donePftLegal += b.ctx.LegalDoneProfits(b.histOdOff)
+	b.histOdOff = len(b.ctx.HistODs)
 	b.Plots.Labels = append(b.Plots.Labels, curDate)
 	b.Plots.OdNum = append(b.Plots.OdNum, odNum)
 	b.Plots.JobNum = append(b.Plots.JobNum, jobNum)
@@ -364,6 +469,11 @@ func (b *BackTest) logPlot(wallets *biz.BanWallets, timeMS int64, odNum int, tot
 	b.Plots.Profit = append(b.Plots.Profit, b.donePftLegal)
 	b.Plots.UnrealizedPOL = append(b.Plots.UnrealizedPOL, profitLegal)
 	b.Plots.WithDraw = append(b.Plots.WithDraw, drawLegal)
+	noEnter := 0
+	if b.ctx.NoEnterUntil[wallets.Account] > timeMS {
+		noEnter = 1
+	}
+	b.Plots.NoEnter = append(b.Plots.NoEnter, noEnter)
 }
 
 func (b *BackTest) cronDumpBtStatus() {
@@ -376,7 +486,7 @@ func (b *BackTest) cronDumpBtStatus() {
 		}
 		b.lastDumpMs = curTime
 		log.Info("dump backTest status to files...")
-		b.printBtResult()
+		b.printBtResult(b.ctx)
 	})
 	if err_ != nil {
 		log.Error("add Dump BackTest Status fail", zap.Error(err_))
@@ -390,7 +500,7 @@ func (b *BackTest) initRefreshCron() *errs.Error {
 		if err_ != nil {
 			return errs.New(core.ErrBadConfig, err_)
 		}
-		baseMS := config.TimeRange.StartMS
+		baseMS := b.ctx.TimeRange().StartMS
 		for {
 			baseTime := time.UnixMilli(baseMS)
 			b.nextRefresh = b.schedule.Next(baseTime).UnixMilli()
@@ -403,27 +513,32 @@ func (b *BackTest) initRefreshCron() *errs.Error {
 	return nil
 }
 
-func RefreshPairJobs(dp data.IProvider, showLog, isFirst bool, pBar *utils.StagedPrg) *errs.Error {
-	pairs, pairTfScores, err := biz.RefreshPairs(showLog, isFirst, pBar)
+/*
+RefreshPairJobs
+Refresh pairs and jobs of ctx, relay simulated open orders for new pairs and warm up them.
+刷新ctx的交易对和任务，为新品种接力模拟的未平仓订单并预热。
+*/
+func RefreshPairJobs(ctx *biz.Ctx, dp data.IProvider, showLog, isFirst bool, pBar *utils.StagedPrg) *errs.Error {
+	pairs, pairTfScores, err := ctx.RefreshPairs(showLog, isFirst, pBar)
 	if err != nil {
 		return err
 	}
 	// store the currently running jobs and mark them as prohibited from running
 	// 获取旧的已运行一段时间的任务（在刷新任务前运行），标记为禁止运行
-	forbidJobs := strat.GetJobKeys()
+	forbidJobs := ctx.GetJobKeys()
 	// 刷新交易任务
-	warms, err := biz.RefreshJobs(pairs, pairTfScores, showLog, pBar)
+	warms, err := ctx.RefreshJobs(pairs, pairTfScores, showLog, pBar)
 	if err != nil {
 		return err
 	}
 	if isFirst {
 		// 监听订单状态变化，触发策略的OnOrderChange
-		biz.InitOdSubs()
+		ctx.InitOdSubs()
 	}
 	// relay the simulate open position orders for new symbols at this time
 	// 接力入场新品种的截止此时模拟持仓订单
 	backMode := core.RunMode
-	err = relayUnFinishOrders(pairTfScores, forbidJobs, isFirst)
+	err = relayUnFinishOrders(ctx, pairTfScores, forbidJobs, isFirst)
 	core.SetRunMode(backMode)
 	core.SetRunEnv(core.RunEnv)
 	if err != nil {
@@ -437,50 +552,60 @@ func RefreshPairJobs(dp data.IProvider, showLog, isFirst bool, pBar *utils.Stage
 获取模拟回测的未完成订单，接力入场；
 应在RefreshJobs之后再调用，否则入场订单可能被视为旧的平仓掉
 */
-func relayUnFinishOrders(pairTfScores map[string]map[string]float64, forbidJobs map[string]map[string]bool, isFirst bool) *errs.Error {
+func relayUnFinishOrders(ctx *biz.Ctx, pairTfScores map[string]map[string]float64, forbidJobs map[string]map[string]bool, isFirst bool) *errs.Error {
 	if !config.RelaySimUnFinish {
 		return nil
 	}
-	// Backup global state
-	// 备份全局状态
-	backUp := biz.BackupVars()
-	timeRange := config.TimeRange.Clone()
-	backTime := btime.CurTimeMS
-	simEndMs := backTime
-	if core.LiveMode {
-		simEndMs = btime.TimeMS()
+	relayOpens, relayDones, err := simRelayOrders(ctx, pairTfScores, forbidJobs)
+	if err != nil {
+		return err
 	}
-	backPols := config.RunPolicy
+	return syncSimOrders(ctx, isFirst, relayOpens, relayDones)
+}
+
+/*
+simRelayOrders
+Backtest each relay group of ctx in a new context, return the unfinished and finished orders keyed by KeyAlign.
+The state of ctx is not changed.
+在新的上下文中回测ctx的每个接力组，返回以KeyAlign为键的未完成和已完成订单。不改变ctx的状态。
+*/
+func simRelayOrders(ctx *biz.Ctx, pairTfScores map[string]map[string]float64, forbidJobs map[string]map[string]bool) (
+	map[string]*ormo.InOutOrder, map[string]*ormo.InOutOrder, *errs.Error) {
+	simEndMs := ctx.TimeMS()
 	backRunMode := core.RunMode
 	core.SetRunMode(core.RunModeBackTest)
 	core.SetRunEnv(core.RunEnv)
+	defer func() {
+		core.SetRunMode(backRunMode)
+		core.SetRunEnv(core.RunEnv)
+	}()
 	// Divide into multiple groups based on the subscription period according to the strategy
 	// 按策略订阅周期划分为多个组
-	groups := strat.RelayPolicyGroups()
+	groups := ctx.RelayPolicyGroups()
 	// pair_tf_stratID
 	var relayOpens = make(map[string]*ormo.InOutOrder)
 	var relayDones = make(map[string]*ormo.InOutOrder)
 	for _, gp := range groups {
-		// Reset global variables, backtest for time range, and search for open orders
-		// 重置全局变量，回测过去一段时间，查找未平仓订单
-		biz.ResetVars()
-		strat.ForbidJobs = forbidJobs
-		btime.CurTimeMS = gp.StartMS
-		config.TimeRange = &config.TimeTuple{
+		// backtest for time range in a new context, and search for open orders
+		// 在新的上下文中回测过去一段时间，查找未平仓订单
+		sub := NewBTContext(gp.Policies)
+		sub.SetTimeRange(&config.TimeTuple{
 			StartMS: gp.StartMS,
 			EndMS:   simEndMs,
-		}
-		err := ormo.InitTask(false, "")
+		})
+		sub.CurTimeMS = gp.StartMS
+		sub.ForbidJobs = forbidJobs
+		sub.Pairs = ctx.Pairs
+		sub.PairsMap = ctx.PairsMap
+		err := sub.InitTask(false, "")
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
-		lite := NewBackTestLite(true, nil, nil, nil)
-		// set policy to run
-		// 重新加载策略任务
-		config.RunPolicy = gp.Policies
-		warms, _, err := strat.LoadStratJobs(core.Pairs, pairTfScores)
+		lite := NewBackTestLite(sub, true, nil, nil, nil)
+		// 加载策略任务
+		warms, _, err := sub.LoadStratJobs(sub.Pairs, pairTfScores)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		if len(warms) == 0 {
 			// 没有需要预回测的任务
@@ -488,15 +613,15 @@ func relayUnFinishOrders(pairTfScores map[string]map[string]float64, forbidJobs
 		}
 		err = lite.dp.SubWarmPairs(warms, true)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		err = lite.dp.LoopMain()
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		// Record the last unfinished orders
 		// 记录最后的未完成订单
-		odMap, lock := ormo.GetOpenODs(config.DefAcc)
+		odMap, lock := sub.GetOpenODs(config.DefAcc)
 		lock.Lock()
 		for _, od := range odMap {
 			if od.Status >= ormo.InOutStatusPartEnter && od.ExitTag == "" {
@@ -506,22 +631,14 @@ func relayUnFinishOrders(pairTfScores map[string]map[string]float64, forbidJobs
 			}
 		}
 		lock.Unlock()
-		for _, od := range ormo.HistODs {
+		for _, od := range sub.HistODs {
 			relayDones[od.KeyAlign()] = od
 		}
 	}
-	config.RunPolicy = backPols
-	config.TimeRange = timeRange
-	btime.CurTimeMS = backTime
-	// Restore global variables and relay open orders for entry
-	// 恢复全局变量，接力入场未平仓订单
-	biz.RestoreVars(backUp)
-	core.SetRunMode(backRunMode)
-	core.SetRunEnv(core.RunEnv)
-	return syncSimOrders(isFirst, relayOpens, relayDones)
+	return relayOpens, relayDones, nil
 }
 
-func syncSimOrders(isFirst bool, relayOpens, relayDones map[string]*ormo.InOutOrder) *errs.Error {
+func syncSimOrders(ctx *biz.Ctx, isFirst bool, relayOpens, relayDones map[string]*ormo.InOutOrder) *errs.Error {
 	if isFirst {
 		// 如果是初次执行，检查打开的订单是否已在测试期间平仓，是则自动平仓
 		// 主要针对实盘隔一段时间后重启有未平仓订单场景，需检查订单是否应在机器人停止期间平仓
@@ -536,8 +653,8 @@ func syncSimOrders(isFirst bool, relayOpens, relayDones map[string]*ormo.InOutOr
 		}
 		closeNums := make(map[string]int)
 		for acc := range config.Accounts {
-			odMgr := biz.GetOdMgr(acc)
-			odMap, lock := ormo.GetOpenODs(acc)
+			odMgr := ctx.GetOdMgr(acc)
+			odMap, lock := ctx.GetOpenODs(acc)
 			var exitOds []*ormo.InOutOrder
 			lock.Lock()
 			for _, od := range odMap {
@@ -576,10 +693,10 @@ func syncSimOrders(isFirst bool, relayOpens, relayDones map[string]*ormo.InOutOr
 		defer conn.Close()
 	}
 	for acc := range config.Accounts {
-		odMgr := biz.GetOdMgr(acc)
-		jobs := strat.GetJobs(acc)
+		odMgr := ctx.GetOdMgr(acc)
+		jobs := ctx.GetJobs(acc)
 		allowOds := make([]*ormo.InOutOrder, 0, len(relayOpens))
-		odMap, lock := ormo.GetOpenODs(acc)
+		odMap, lock := ctx.GetOpenODs(acc)
 		curKeyMap := make(map[string]*ormo.InOutOrder)
 		lock.Lock()
 		for _, od := range odMap {
@@ -44,20 +44,14 @@ var (
 	DefCalcOptBest = "good3"
 )
 
-type OptGroup struct {
-	Items []*OptInfo
-	Score float64
-	Name  string
-	Pair  string
-	TFStr string
-}
-
 type OptInfo struct {
 	Dirt   string
 	ID     string
 	Score  float64
 	Params map[string]float64
 	Ints   map[string]bool
+	Objs   []float64 // values of multi objectives, larger is better 多目标的值，越大越好
+	Pruned int       // progress percent when pruned, 0 for finished 被剪枝时的进度百分比，完成的为0
 	*BTResult
 }
 
@@ -104,15 +98,33 @@ func calcBestBy(items []*OptInfo, name string) *OptInfo {
 	return res
 }
 
-func (o *OptInfo) runGetBtResult(pol *config.RunPolicyConfig) {
+/*
+runGetBtResult
+Backtest pols with params of this trial applied to pol, save the result
+将此轮的参数应用到pol后回测pols，保存结果
+*/
+func (o *OptInfo) runGetBtResult(pol *config.RunPolicyConfig, pols []*config.RunPolicyConfig) {
 	for k, v := range o.Params {
 		pol.Params[k] = v
 	}
-	bt, loss := runBTOnce()
+	bt, loss := runBTOnce(pols, nil)
 	o.Score = -loss
 	o.BTResult = bt.BTResult
 }
 
+// loadDetail load the full BTResult dumped in detailDir, keep the current if fail 加载detailDir中保存的完整BTResult，失败时保留当前的
+func (o *OptInfo) loadDetail(detailDir string) {
+	if o.ID == "" {
+		return
+	}
+	res, err := parseBtResult(filepath.Join(detailDir, o.ID+".json"))
+	if err != nil {
+		log.Warn("parse BtResult fail", zap.String("id", o.ID), zap.String("err", err.Short()))
+		return
+	}
+	o.BTResult = res
+}
+
 func (o *OptInfo) ToPol(name, dirt, tfStr, pairStr string) *config.RunPolicyConfig {
 	if o.Dirt == "" {
 		o.Dirt = dirt
@@ -139,6 +151,8 @@ func newRollBtOpt(args *config.CmdArgs) (*rollBtOpt, *errs.Error) {
 		return nil, err
 	}
 	dateRange := config.TimeRange.Clone()
+	// windows are applied to the global time range used by optimize and backtest 窗口作用于优化和回测使用的全局时间范围
+	config.TimeRange = dateRange
 	allStartMs, allEndMs := dateRange.StartMS, dateRange.EndMS
 	runMSecs := int64(utils2.TFToSecs(args.RunPeriod)) * 1000
 	reviewMSecs := int64(utils2.TFToSecs(args.ReviewPeriod)) * 1000
@@ -166,25 +180,28 @@ func newRollBtOpt(args *config.CmdArgs) (*rollBtOpt, *errs.Error) {
 	}, nil
 }
 
-func (t *rollBtOpt) next(pairPicker string) (string, *errs.Error) {
+/*
+next
+Optimize the review window before curMs, return the run_policy config.
+The study of a window optimized before is continued, so finished trials are not run again.
+对curMs之前的回顾窗口调优，返回run_policy配置。之前已调优的窗口会继续其研究，已完成的轮次不会再次运行。
+*/
+func (t *rollBtOpt) next() (string, *errs.Error) {
 	t.dateRange.StartMS = t.curMs - t.reviewMSecs
 	t.dateRange.EndMS = t.curMs
 	fname := fmt.Sprintf("opt_%v.log", t.dateRange.StartMS/1000)
 	t.args.OutPath = filepath.Join(t.outDir, fname)
-	polStr, err := pickFromExists(t.args.OutPath, t.args.Picker, pairPicker)
+	trialNum, err := countStudyTrials(t.args.OutPath)
 	if err != nil {
 		return "", err
 	}
-	if polStr == "" {
-		config.RunPolicy = t.initPols
-		polStr, err = runOptimize(t.args, 0)
-		if err != nil {
-			return "", err
-		}
-	} else {
-		log.Info("use hyperopt cache", zap.String("path", fname))
+	t.args.Resume = ""
+	if trialNum > 0 {
+		log.Info("use hyperopt cache", zap.String("path", fname), zap.Int("trials", trialNum))
+		t.args.Resume = DefStudyName(t.args.OutPath)
 	}
-	return polStr, nil
+	config.RunPolicy = t.initPols
+	return runOptimize(t.args, 0)
 }
 
 func (t *rollBtOpt) dumpConfig() *errs.Error {
@@ -238,6 +255,18 @@ func (o *OptInfo) ToLine() string {
 	return fmt.Sprintf("loss: %7.2f \t%s \t%s, id: %v", -o.Score, text, o.BriefLine(), o.ID)
 }
 
+/*
+optLine
+Line in optimize log, pruned trials are commented out so they are ignored when collecting
+优化日志中的行，被剪枝的轮次作为注释，收集时忽略
+*/
+func (o *OptInfo) optLine() string {
+	if o.Pruned > 0 {
+		return fmt.Sprintf("# pruned at %d%%: %s", o.Pruned, o.ToLine())
+	}
+	return o.ToLine()
+}
+
 /*
 AvgGoodDesc
 For profitable groups, cut the specified range in descending order of scores and take the average of the parameters
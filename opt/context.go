@@ -0,0 +1,29 @@
+package opt
+
+import (
+	"github.com/banbox/banbot/biz"
+	"github.com/banbox/banbot/config"
+)
+
+/*
+NewBTContext
+Create an empty context for a backtest running pols, nil pols means using config.RunPolicy.
+The simulated clock, run policies, time range, orders, wallets and jobs of a backtest are all kept in its context,
+so multiple backtests can run in parallel goroutines, sharing the read-only klines and exchange markets.
+Pass cloned policies when the caller keeps changing them from other goroutines.
+
+为运行pols的回测创建一个空上下文，pols为nil时使用config.RunPolicy。
+回测的模拟时钟、运行策略、时间范围、订单、钱包和任务都保存在其上下文中，因此多个回测可在不同协程中并行运行，共享只读的K线和交易所市场。
+调用方在其他协程中会修改策略时，应传入克隆的策略。
+*/
+func NewBTContext(pols []*config.RunPolicyConfig) *biz.Ctx {
+	c := biz.NewRunCtx()
+	if pols != nil {
+		c.SetRunPolicy(pols)
+	}
+	if tr := c.TimeRange(); tr != nil {
+		c.CurTimeMS = tr.StartMS
+	}
+	c.BotRunning = true
+	return c
+}
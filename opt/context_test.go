@@ -0,0 +1,68 @@
+package opt
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	ta "github.com/banbox/banta"
+)
+
+func TestBTContextIsolation(t *testing.T) {
+	oldTime := core.DefCtx.CurTimeMS
+	pols := []*config.RunPolicyConfig{{Name: "ctx_a"}}
+	ctxA, ctxB := NewBTContext(pols), NewBTContext(nil)
+	ctxA.Envs["BTC/USDT_1m"] = &ta.BarEnv{}
+	ctxA.NoEnterUntil["BTC/USDT"] = 5
+	ctxA.CurTimeMS = 2000
+	ctxA.SetTimeRange(&config.TimeTuple{StartMS: 1000, EndMS: 3000})
+	if len(ctxB.Envs) != 0 || len(ctxB.NoEnterUntil) != 0 || ctxB.CurTimeMS == 2000 {
+		t.Error("context B should not see state of A")
+	}
+	if ctxA.RunPolicy()[0].Name != "ctx_a" || ctxA.TimeRange().StartMS != 1000 {
+		t.Errorf("context A lost policies or time range")
+	}
+	if ctxB.TimeRange() != config.TimeRange {
+		t.Errorf("context B should use config.TimeRange")
+	}
+	if core.DefCtx.CurTimeMS != oldTime || len(core.DefCtx.NoEnterUntil) != 0 {
+		t.Errorf("default context changed: time %v, until %v", core.DefCtx.CurTimeMS, core.DefCtx.NoEnterUntil)
+	}
+}
+
+func TestBTContextConcurrent(t *testing.T) {
+	names := []string{"ctx_a", "ctx_b", "ctx_c"}
+	var wg sync.WaitGroup
+	errs := make([]error, len(names))
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			ctx := NewBTContext([]*config.RunPolicyConfig{{Name: name}})
+			for step := 1; step <= 200; step++ {
+				if ctx.RunPolicy()[0].Name != name || len(ctx.Envs) != step-1 ||
+					ctx.NoEnterUntil[name] != int64(step-1) || len(ctx.NoEnterUntil) > 1 {
+					errs[i] = fmt.Errorf("%s step %d got envs %d, until %v", name, step, len(ctx.Envs),
+						ctx.NoEnterUntil)
+					return
+				}
+				ctx.Envs[fmt.Sprintf("%s_%d", name, step)] = &ta.BarEnv{}
+				ctx.NoEnterUntil[name] = int64(step)
+				ctx.CurTimeMS = int64(i*1000000 + step)
+				ctx.AddAccFailOpen(config.DefAcc, name)
+			}
+			if ctx.CurTimeMS != int64(i*1000000+200) || ctx.GetAccFailOpens()[config.DefAcc][name] != 200 {
+				errs[i] = fmt.Errorf("%s result mixed: time %d, fails %v", name, ctx.CurTimeMS,
+					ctx.GetAccFailOpens())
+			}
+		}(i, name)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -1,19 +1,19 @@
 package opt
 
 import (
-	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/fs"
 	"math/rand"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"slices"
 	"sort"
 	"strconv"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/anyongjin/go-bayesopt"
 	"github.com/banbox/banbot/biz"
@@ -47,6 +47,7 @@ func RunBTOverOpt(args *config.CmdArgs) *errs.Error {
 	var allHisOds []*ormo.InOutOrder
 	var lastWal map[string]float64
 	var lastRes *BTResult
+	wf := &WalkForward{}
 	lastPols := config.RunPolicy
 	pbar := utils.NewPrgBar(int((t.allEndMs-t.curMs)/1000), "BtOpt")
 	defer pbar.Close()
@@ -54,11 +55,10 @@ func RunBTOverOpt(args *config.CmdArgs) *errs.Error {
 	for t.curMs < t.allEndMs {
 		pbar.Add(int(t.runMSecs / 1000))
 		config.RunPolicy = backPols
-		polStr, err := t.next(args.PairPicker)
+		polStr, err := t.next()
 		if err != nil {
 			return err
 		}
-		biz.ResetVars()
 		polList, err := parseRunPolicies(polStr)
 		if err != nil {
 			return err
@@ -71,33 +71,67 @@ func RunBTOverOpt(args *config.CmdArgs) *errs.Error {
 		}
 		applyOptPolicies(lastPols, polList, args.Alpha)
 		lastPols = config.RunPolicy
-		wallets := biz.GetWallets(config.DefAcc)
-		core.BotRunning = true
-		t.dateRange.StartMS = t.curMs
-		t.dateRange.EndMS = t.curMs + t.runMSecs
+		win := &WFWindow{
+			ISStart:  t.curMs - t.reviewMSecs,
+			ISEnd:    t.curMs,
+			OOSStart: t.curMs,
+			OOSEnd:   t.curMs + t.runMSecs,
+			Params:   dumpPolicyParams(config.RunPolicy),
+		}
+		win.IS = runInSample(lastPols, win.ISStart, win.ISEnd)
+		ctx := NewBTContext(lastPols)
+		ctx.SetTimeRange(&config.TimeTuple{StartMS: win.OOSStart, EndMS: win.OOSEnd})
 		outDir := filepath.Join(t.outDir, args.Picker)
-		bt := NewBackTest(false, outDir)
+		bt := NewBackTest(ctx, false, outDir)
+		wallets := ctx.GetWallets(config.DefAcc)
+		initLegal := walletsLegal(ctx.BotCtx, config.WalletAmounts)
 		if lastWal != nil {
 			wallets.SetWallets(lastWal)
+			initLegal = walletsLegal(ctx.BotCtx, lastWal)
 		}
 		if lastRes != nil {
 			bt.BTResult = lastRes
 		}
-		ormo.HistODs = allHisOds
+		ctx.HistODs = allHisOds
+		prevOdNum := len(allHisOds)
 		bt.Run()
 		lastRes = bt.BTResult
-		allHisOds = ormo.HistODs
+		allHisOds = ctx.HistODs
 		lastWal = wallets.DumpAvas()
+		win.OOS = calcWFMetrics(ctx.BotCtx, allHisOds[min(prevOdNum, len(allHisOds)):], initLegal)
+		wf.AddWindow(win)
 		t.curMs += t.runMSecs
 	}
 	err = t.dumpConfig()
 	if err != nil {
 		return err
 	}
+	if lastRes != nil && len(wf.Windows) > 0 {
+		log.Info("Walk Forward Reports:\n" + wf.Text())
+		lastRes.WalkForward = wf
+		lastRes.dumpDetail("")
+		err = wf.Dump(lastRes.OutDir)
+		if err != nil {
+			return err
+		}
+	}
 	log.Info("Rolling Optimization Backtesting finished", zap.String("at", t.outDir))
 	return nil
 }
 
+/*
+runInSample
+Backtest run policies over the in-sample window, to compare with out-of-sample performance
+在样本内窗口回测运行策略，用于和样本外表现对比
+*/
+func runInSample(pols []*config.RunPolicyConfig, startMS, endMS int64) *WFMetrics {
+	ctx := NewBTContext(pols)
+	ctx.SetTimeRange(&config.TimeTuple{StartMS: startMS, EndMS: endMS})
+	bt := NewBackTest(ctx, true, "")
+	bt.Run()
+	return calcWFMetrics(ctx.BotCtx, ctx.HistODs, walletsLegal(ctx.BotCtx, config.WalletAmounts))
+}
+
 func RunRollBTPicker(args *config.CmdArgs) *errs.Error {
 	t, err := newRollBtOpt(args)
 	if err != nil || t == nil {
@@ -125,11 +159,10 @@ func RunRollBTPicker(args *config.CmdArgs) *errs.Error {
 		for i, picker := range pickers {
 			t.args.Picker = picker
 			config.RunPolicy = backPols
-			polStr, err := t.next(args.PairPicker)
+			polStr, err := t.next()
 			if err != nil {
 				return err
 			}
-			biz.ResetVars()
 			polList, err := parseRunPolicies(polStr)
 			if err != nil {
 				return err
@@ -140,11 +173,9 @@ func RunRollBTPicker(args *config.CmdArgs) *errs.Error {
 				t.curMs += t.runMSecs
 				continue
 			}
-			config.RunPolicy = polList
-			core.BotRunning = true
-			t.dateRange.StartMS = t.curMs
-			t.dateRange.EndMS = t.curMs + t.runMSecs
-			bt := NewBackTest(true, "")
+			ctx := NewBTContext(polList)
+			ctx.SetTimeRange(&config.TimeTuple{StartMS: t.curMs, EndMS: t.curMs + t.runMSecs})
+			bt := NewBackTest(ctx, true, "")
 			bt.Run()
 			score := bt.Score()
 			scores = append(scores, score)
@@ -207,17 +238,6 @@ func btOptHash(args *config.CmdArgs) string {
 	return res[:10]
 }
 
-func pickFromExists(path string, picker, pairPicker string) (string, *errs.Error) {
-	paths, err_ := utils.GetFilesWithPrefix(path)
-	if err_ != nil {
-		return "", errs.New(errs.CodeIOReadFail, err_)
-	}
-	if len(paths) == 0 {
-		return "", nil
-	}
-	return collectOptLog(paths, 0, picker, pairPicker)
-}
-
 /*
 applyOptPolicies
 Update strategy group parameters using EMA to avoid significant differences in parameters before and after rolling backtesting
@@ -253,6 +273,62 @@ func applyOptPolicies(olds, pols []*config.RunPolicyConfig, alpha float64) {
 	config.RunPolicy = res
 }
 
+// OptTrialPrefix prefix of trial lines in stdout when `-prg` is set 设置`-prg`时标准输出中每轮结果行的前缀
+const OptTrialPrefix = "optTrial: "
+
+var optPrg *optProgress // progress output of the running optimize 当前运行的超参数优化的进度输出
+
+/*
+optProgress
+Print each finished trial and the progress to stdout for `-prg`, used by web ui to show optimize tasks
+为`-prg`输出每轮结果和进度到标准输出，用于web界面展示超参数优化任务
+*/
+type optProgress struct {
+	prefix string
+	total  int
+	done   int
+	lock   sync.Mutex
+}
+
+func newOptProgress(args *config.CmdArgs, pols []*config.RunPolicyConfig, allPairs []string) *optProgress {
+	total := 0
+	for _, pol := range pols {
+		num := 1
+		if pol.Dirt == "any" {
+			// long, short, both 多、空、双向
+			num = 3
+		}
+		if args.EachPairs {
+			if len(pol.Pairs) > 0 {
+				num *= len(pol.Pairs)
+			} else {
+				num *= len(allPairs)
+			}
+		}
+		total += num * args.OptRounds
+	}
+	return &optProgress{prefix: args.PrgOut, total: max(1, total)}
+}
+
+func (p *optProgress) addTrial(pol string, o *OptInfo) {
+	data, err_ := json.Marshal(newTrialItem(pol, o))
+	if err_ != nil {
+		log.Warn("marshal trial fail", zap.Error(err_))
+		return
+	}
+	p.forward(OptTrialPrefix + string(data))
+}
+
+// forward print a trial line and update progress 输出一轮结果行并更新进度
+func (p *optProgress) forward(line string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.done += 1
+	// re-tuning one side of unbalanced long/short is not counted in total 多空不均衡时重新微调一侧的轮次不计入总数
+	rate := min(0.99, float64(p.done)/float64(p.total))
+	fmt.Printf("%s\n%s: %v\n", line, p.prefix, rate)
+}
+
 func RunOptimize(args *config.CmdArgs) *errs.Error {
 	if args.OutPath == "" {
 		log.Warn("-out is required")
@@ -272,93 +348,119 @@ func RunOptimize(args *config.CmdArgs) *errs.Error {
 	return nil
 }
 
+/*
+runOptimize
+Optimize all policy groups of config.RunPolicy, groups run in parallel goroutines when `-concur` > 1,
+every backtest runs in its own context. Return the best run_policy config of all groups.
+对config.RunPolicy的所有策略组调优，`-concur`大于1时各组在并行的协程中运行，每个回测在自己的上下文中运行。
+返回所有组的最佳run_policy配置。
+*/
 func runOptimize(args *config.CmdArgs, minScore float64) (string, *errs.Error) {
 	var err *errs.Error
-	btime.CurTimeMS = config.TimeRange.StartMS
+	curStudy, err = openOptStudy(args)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		curStudy.store.Close()
+		curStudy = nil
+	}()
 	// 列举所有标的
 	allPairs := config.Pairs
 	if len(allPairs) == 0 {
 		goods.ShowLog = false
-		allPairs, err = goods.RefreshPairList(false)
+		allPairs, err = goods.RefreshPairList(NewBTContext(nil).BotCtx, false)
 		if err != nil {
 			return "", err
 		}
 	}
-	var logOuts []string
 	groups := config.RunPolicy
+	if args.PrgOut != "" {
+		optPrg = newOptProgress(args, groups, allPairs)
+		defer func() {
+			optPrg = nil
+		}()
+	}
+	file, err_ := os.Create(args.OutPath)
+	if err_ != nil {
+		return "", errs.New(errs.CodeIOWriteFail, err_)
+	}
+	var res []*GroupScore
 	if len(groups) <= 1 || args.Concur <= 1 {
-		logOuts = append(logOuts, args.OutPath)
-		file, err_ := os.Create(args.OutPath)
-		if err_ != nil {
-			return "", errs.New(errs.CodeIOWriteFail, err_)
-		}
 		for _, gp := range groups {
 			// Bayesian optimization is carried out separately for each strategy, long and short, to find the best parameters
 			// 针对每个策略、多空单独进行贝叶斯优化，寻找最佳参数
-			err = optAndPrint(gp.Clone(), args, allPairs, file)
+			var items []*GroupScore
+			items, err = optAndPrint(gp.Clone(), args, allPairs, file)
 			if err != nil {
-				file.Close()
-				return "", err
+				break
 			}
+			res = append(res, items...)
 		}
-		file.Close()
-		sortOptLogs(args.OutPath)
 	} else {
-		// Multi-process execution to improve speed.
-		// 多进程执行，提高速度。
-		log.Warn("running optimize", zap.Int("num", len(groups)), zap.Int("rounds", args.OptRounds))
-		var cmds = []string{"optimize", "-opt-rounds"}
-		cmds = append(cmds, strconv.Itoa(args.OptRounds), "-sampler", args.Sampler)
-		if args.EachPairs {
-			cmds = append(cmds, "-each-pairs")
-		}
-		for _, p := range args.Configs {
-			cmds = append(cmds, "-config", p)
-		}
-		if args.Picker != "" {
-			cmds = append(cmds, "-picker", args.Picker)
-		}
-		startStr := strconv.FormatInt(config.TimeRange.StartMS/1000, 10)
-		endStr := strconv.FormatInt(config.TimeRange.EndMS/1000, 10)
+		// Run groups in parallel goroutines to improve speed.
+		// 在并行的协程中运行各组，提高速度。
+		log.Warn("running optimize", zap.Int("num", len(groups)), zap.Int("rounds", args.OptRounds),
+			zap.Int("concur", args.Concur))
+		var lock sync.Mutex
 		err = utils.ParallelRun(groups, args.Concur, func(i int, pol *config.RunPolicyConfig) *errs.Error {
-			time.Sleep(time.Millisecond * time.Duration(1000*rand.Float64()+100*float64(i)))
-			iStr := strconv.Itoa(i + 1)
-			cfgFile, err_ := os.CreateTemp("", "ban_opt"+iStr)
-			if err_ != nil {
-				log.Warn("write temp config fail", zap.Error(err_))
-				return nil
+			// write to file when the group finished, avoid lines of groups mixed
+			// 组完成时再写入文件，避免各组的行混在一起
+			var b strings.Builder
+			items, err := optAndPrint(pol.Clone(), args, allPairs, &b)
+			lock.Lock()
+			defer lock.Unlock()
+			file.WriteString(b.String())
+			res = append(res, items...)
+			return err
+		})
+	}
+	file.Close()
+	if err != nil {
+		return "", err
+	}
+	sortOptLogs(args.OutPath)
+	return dumpOptGroups(res, minScore, args.PairPicker), nil
+}
+
+/*
+dumpOptGroups
+Output the run_policy config of groups in reverse score order, groups below minScore are skipped.
+pairs of each policy are selected by pairSel from its backtest result if given.
+按分数倒序输出各组的run_policy配置，跳过低于minScore的组。
+给定pairSel时，从每个策略的回测结果中选择品种。
+*/
+func dumpOptGroups(res []*GroupScore, minScore float64, pairSel string) string {
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Score > res[j].Score
+	})
+	var b strings.Builder
+	b.WriteString("run_policy:\n")
+	for _, gp := range res {
+		if gp.Score < minScore {
+			break
+		}
+		b.WriteString(fmt.Sprintf("\n  # score: %.2f\n", gp.Score))
+		for i, p := range gp.Items {
+			tfStr := strings.Join(p.RunTimeframes, ", ")
+			b.WriteString(fmt.Sprintf("  - name: %s\n    run_timeframes: [ %s ]\n", p.Name, tfStr))
+			pairs := p.Pairs
+			if pairSel != "" && i < len(gp.Bests) && gp.Bests[i] != nil && gp.Bests[i].BTResult != nil {
+				if picked := selectPairs(gp.Bests[i].BTResult, pairSel); len(picked) > 0 {
+					pairs = picked
+				}
 			}
-			defer os.Remove(cfgFile.Name())
-			cfgFile.WriteString(fmt.Sprintf("time_start: \"%s\"\n", startStr))
-			cfgFile.WriteString(fmt.Sprintf("time_end: \"%s\"\n", endStr))
-			cfgFile.WriteString("run_policy:\n")
-			cfgFile.WriteString(pol.ToYaml())
-			cfgFile.Close()
-			curCmds := append(cmds, "-config", cfgFile.Name())
-			outPath := args.OutPath + "." + iStr
-			curCmds = append(curCmds, "-out", outPath)
-			logOuts = append(logOuts, outPath)
-			log.Warn("runing: " + strings.Join(curCmds, " "))
-			var out bytes.Buffer
-			excPath, err_ := os.Executable()
-			if err_ != nil {
-				return errs.New(errs.CodeRunTime, err_)
+			if len(pairs) > 0 {
+				b.WriteString(fmt.Sprintf("    pairs: [%s]\n", strings.Join(pairs, ", ")))
 			}
-			cmd := exec.Command(excPath, curCmds...)
-			cmd.Stdout = &out
-			cmd.Stderr = &out
-			err_ = cmd.Run()
-			fmt.Println(out.String())
-			if err_ != nil {
-				return errs.New(errs.CodeRunTime, err_)
+			if p.Dirt != "" {
+				b.WriteString(fmt.Sprintf("    dirt: %s\n", p.Dirt))
 			}
-			return nil
-		})
-		if err != nil {
-			return "", err
+			paramStr := utils.MapToStr(p.Params, true, 2)
+			b.WriteString(fmt.Sprintf("    params: {%s}\n", paramStr))
 		}
 	}
-	return collectOptLog(logOuts, minScore, args.Picker, args.PairPicker)
+	return b.String()
 }
 
 func sortOptLogs(path string) {
@@ -412,10 +514,13 @@ func sortOptLogs(path string) {
 
 /*
 optAndPrint optimize for raw policy group;
-write one or multiple optimize result to file.
+write one or multiple optimize result to file, and return them.
 */
-func optAndPrint(pol *config.RunPolicyConfig, args *config.CmdArgs, allPairs []string, file *os.File) *errs.Error {
+func optAndPrint(pol *config.RunPolicyConfig, args *config.CmdArgs, allPairs []string, file io.StringWriter) ([]*GroupScore, *errs.Error) {
 	file.WriteString(fmt.Sprintf("# run hyper optimize: %v, rounds: %v\n", args.Sampler, args.OptRounds))
+	if curStudy != nil {
+		file.WriteString(fmt.Sprintf("# study: %v, seed: %v\n", curStudy.name, curStudy.seed))
+	}
 	startDt := btime.ToDateStr(config.TimeRange.StartMS, "")
 	endDt := btime.ToDateStr(config.TimeRange.EndMS, "")
 	file.WriteString(fmt.Sprintf("# date range: %v - %v\n", startDt, endDt))
@@ -428,7 +533,7 @@ func optAndPrint(pol *config.RunPolicyConfig, args *config.CmdArgs, allPairs []s
 		res = make([]*GroupScore, 0, len(pairs))
 		for _, p := range pairs {
 			pol.Pairs = []string{p}
-			item := optForGroup(pol, args.Sampler, args.Picker, args.OptRounds, file)
+			item := optForGroup(pol, args, file)
 			if item != nil {
 				res = append(res, item)
 			}
@@ -437,7 +542,7 @@ func optAndPrint(pol *config.RunPolicyConfig, args *config.CmdArgs, allPairs []s
 			return res[i].Score > res[j].Score
 		})
 	} else {
-		item := optForGroup(pol, args.Sampler, args.Picker, args.OptRounds, file)
+		item := optForGroup(pol, args, file)
 		if item != nil {
 			res = append(res, item)
 		}
@@ -451,7 +556,7 @@ func optAndPrint(pol *config.RunPolicyConfig, args *config.CmdArgs, allPairs []s
 			file.WriteString(p.ToYaml())
 		}
 	}
-	return nil
+	return res, nil
 }
 
 /*
@@ -459,7 +564,7 @@ optForGroup
 Optimize the hyperparameters of a policy and automatically search for the best combination of long, short, and both.
 对某个策略超参数调优，自动搜索long/short/both的最佳组合。
 */
-func optForGroup(pol *config.RunPolicyConfig, method, picker string, rounds int, flog *os.File) *GroupScore {
+func optForGroup(pol *config.RunPolicyConfig, args *config.CmdArgs, flog io.StringWriter) *GroupScore {
 	groups := make([]*config.RunPolicyConfig, 0, 3)
 	var long, short, both *config.RunPolicyConfig
 	if pol.Dirt == "any" {
@@ -476,9 +581,9 @@ func optForGroup(pol *config.RunPolicyConfig, method, picker string, rounds int,
 	var bestOdNum = 0
 	var bestScore = -999.0
 	var bestPols []*config.RunPolicyConfig
+	var bests = make(map[*config.RunPolicyConfig]*OptInfo)
 	for _, p := range groups {
-		config.RunPolicy = []*config.RunPolicyConfig{p}
-		optForPol(p, method, picker, rounds, flog)
+		bests[p] = optForPol(p, []*config.RunPolicyConfig{p}, args, flog)
 		if p.Score > bestScore {
 			bestOdNum = p.MaxOpen
 			bestScore = p.Score
@@ -486,7 +591,7 @@ func optForGroup(pol *config.RunPolicyConfig, method, picker string, rounds int,
 		}
 	}
 	if len(groups) == 1 {
-		return &GroupScore{groups, bestScore}
+		return newGroupScore(groups, bests, bestScore)
 	}
 	// long,short,both分别评估
 	minScore := min(long.Score, short.Score)
@@ -494,8 +599,7 @@ func optForGroup(pol *config.RunPolicyConfig, method, picker string, rounds int,
 	if minScore > 0 && maxScore > 0 {
 		// 检查组合的是否优于long/short/both
 		flog.WriteString("\n========== union long/short ============\n")
-		config.RunPolicy = []*config.RunPolicyConfig{long, short}
-		bt, loss := runBTOnce()
+		bt, loss := runBTOnce([]*config.RunPolicyConfig{long, short}, nil)
 		line := fmt.Sprintf("loss: %5.2f \t%v\n", loss, bt.BriefLine())
 		flog.WriteString(line)
 		log.Warn(line)
@@ -511,38 +615,48 @@ func optForGroup(pol *config.RunPolicyConfig, method, picker string, rounds int,
 	if minScore < 0 || maxScore > minScore*5 {
 		// The long and short returns are seriously unbalanced, the parameters with high fixed returns remain unchanged, and the parameters with low returns are fine-tuned to find the best score of the combination
 		// 多空收益严重不均衡，固定收益高的参数不变，微调收益低的参数，寻找组合最佳分数
-		config.RunPolicy = []*config.RunPolicyConfig{long, short}
+		unionPols := []*config.RunPolicyConfig{long, short}
 		var unionScore float64
 		if long.Score > short.Score {
-			optForPol(short, method, picker, rounds, flog)
+			bests[short] = optForPol(short, unionPols, args, flog)
 			unionScore = short.Score
 		} else {
-			optForPol(long, method, picker, rounds, flog)
+			bests[long] = optForPol(long, unionPols, args, flog)
 			unionScore = long.Score
 		}
 		if unionScore > bestScore {
-			return &GroupScore{[]*config.RunPolicyConfig{long, short}, unionScore}
+			return newGroupScore(unionPols, bests, unionScore)
 		}
 	}
 	if len(bestPols) > 0 {
-		return &GroupScore{bestPols, bestScore}
+		return newGroupScore(bestPols, bests, bestScore)
 	}
 	return nil
 }
 
 type GroupScore struct {
 	Items []*config.RunPolicyConfig
+	Bests []*OptInfo // best trial of each item, nil if not tuned 每项的最佳轮次，未调优时为nil
 	Score float64
 }
 
+func newGroupScore(items []*config.RunPolicyConfig, bests map[*config.RunPolicyConfig]*OptInfo, score float64) *GroupScore {
+	res := &GroupScore{Items: items, Score: score, Bests: make([]*OptInfo, len(items))}
+	for i, p := range items {
+		res.Bests[i] = bests[p]
+	}
+	return res
+}
+
 /*
 optForPol
 Optimize policy tasks and support bayes, tpe, and cames
-Before calling this method, you need to set 'config. RunPolicy`
+pols are all policies to backtest, including pol. return the best trial, nil if pol has no hyper params.
 对策略任务执行优化，支持bayes/tpe/cames等
-调用此方法前需要设置 `config.RunPolicy`
+pols是要回测的所有策略，包含pol。返回最佳轮次，pol没有超参数时返回nil
 */
-func optForPol(pol *config.RunPolicyConfig, method, picker string, rounds int, flog *os.File) {
+func optForPol(pol *config.RunPolicyConfig, pols []*config.RunPolicyConfig, args *config.CmdArgs, flog io.StringWriter) *OptInfo {
+	method, picker, rounds := args.Sampler, args.Picker, args.OptRounds
 	title := pol.Key()
 	// 重置PairParams，避免影响传入参数
 	pol.PairParams = make(map[string]map[string]float64)
@@ -551,16 +665,64 @@ func optForPol(pol *config.RunPolicyConfig, method, picker string, rounds int, f
 	params := pol.HyperParams()
 	if len(params) == 0 {
 		log.Warn("no hyper params, skip optimize", zap.String("strat", title))
-		return
+		return nil
 	}
-	detailDir := filepath.Join(filepath.Dir(flog.Name()), "detail")
+	detailDir := filepath.Join(filepath.Dir(args.OutPath), "detail")
 	err_ := utils.EnsureDir(detailDir, 0755)
 	if err_ != nil {
 		log.Warn("create detail dir fail", zap.String("path", detailDir), zap.Error(err_))
-		return
+		return nil
+	}
+	objs, err := ParseObjectives(args.Objectives)
+	if err != nil {
+		log.Warn("invalid objectives", zap.Error(err))
+		return nil
+	}
+	if len(objs) == 0 && method == "nsga2" {
+		objs, _ = ParseObjectives(DefOptObjectives)
 	}
 	flog.WriteString(fmt.Sprintf("\n============== %s =============\n", title))
 	var resList = make([]*OptInfo, 0, rounds)
+	var olds []*OptInfo
+	var seed int64
+	// tuning one side while other policies fixed is a different trial set
+	// 固定其他策略微调某一侧时是不同的结果集
+	trialKey := title
+	for _, p := range pols {
+		if p != pol {
+			trialKey += "," + p.Key()
+		}
+	}
+	if curStudy != nil {
+		seed = curStudy.seed
+		olds, err = curStudy.store.GetTrials(curStudy.name, trialKey)
+		if err != nil {
+			log.Warn("load study trials fail", zap.String("study", curStudy.name), zap.Error(err))
+		}
+	}
+	for _, o := range olds {
+		if len(objs) > 0 && o.BTResult != nil {
+			o.Objs = calcObjectives(o.BTResult, objs)
+		}
+		if o.Pruned > 0 {
+			o.Objs = nil
+		}
+		flog.WriteString(o.optLine() + "\n")
+		resList = append(resList, o)
+	}
+	if len(olds) > 0 {
+		log.Warn("resume optimize", zap.String("job", title), zap.Int("done", len(olds)),
+			zap.Int("left", max(0, rounds-len(olds))))
+	}
+	pruner, err := NewTrialPruner(args.Pruner)
+	if err != nil {
+		log.Warn("invalid pruner", zap.Error(err))
+		return nil
+	}
+	var onReport func(step int, value float64) bool
+	if pruner != nil {
+		onReport = pruner.Report
+	}
 	runOptJob := func(data map[string]float64) (float64, *errs.Error) {
 		jobId := utils.RandomStr(6)
 		ints := make(map[string]bool)
@@ -568,30 +730,88 @@ func optForPol(pol *config.RunPolicyConfig, method, picker string, rounds int, f
 			pol.Params[k] = v
 			ints[k] = pol.IsInt(k)
 		}
-		bt, loss := runBTOnce()
-		o := &OptInfo{Score: -loss, Params: data, Ints: ints, BTResult: bt.BTResult, ID: jobId}
-		line := o.ToLine()
+		bt, loss := runBTOnce(pols, onReport)
+		if pruner != nil {
+			pruner.Finish()
+		}
+		o := &OptInfo{Score: -loss, Params: data, Ints: ints, BTResult: bt.BTResult, ID: jobId, Pruned: bt.PrunedAt}
+		if o.Pruned > 0 {
+			// the partial result is not comparable, report no better than the worst finished trial
+			// 部分回测结果不可比，报告不优于最差的已完成轮次
+			for _, it := range resList {
+				if it.Pruned == 0 {
+					loss = max(loss, -it.Score)
+				}
+			}
+			if len(objs) > 0 {
+				o.Objs = calcObjectives(nil, objs)
+			}
+		} else if len(objs) > 0 {
+			o.Objs = calcObjectives(bt.BTResult, objs)
+		}
+		line := o.optLine()
 		flog.WriteString(line + "\n")
 		log.Warn(line)
 		bt.dumpDetail(filepath.Join(detailDir, jobId+".json"))
 		o.BTResult.DelBigObjects()
 		resList = append(resList, o)
+		if curStudy != nil {
+			if err := curStudy.store.AddTrial(curStudy.name, trialKey, o); err != nil {
+				log.Warn("save study trial fail", zap.String("study", curStudy.name), zap.Error(err))
+			}
+		}
+		if optPrg != nil {
+			optPrg.addTrial(trialKey, o)
+		}
 		return loss, nil
 	}
-	var err *errs.Error
-	if method == "bayes" {
-		err = runBayes(rounds, params, runOptJob)
+	// bayes replays olds to follow the same sequence as an uninterrupted run; others continue the remaining
+	// rounds, offset seed to avoid repeating sampled points
+	// bayes重放olds以与未中断时顺序相同；其他方法继续剩余轮次，偏移种子避免重复已采样的点
+	leftNum, leftSeed := rounds-len(olds), seed+int64(len(olds))
+	if leftNum <= 0 {
+		// all rounds finished in resumed study 继续的研究中所有轮次已完成
+	} else if method == "bayes" {
+		err = runBayes(rounds, params, seed, olds, runOptJob)
+	} else if method == "nsga2" {
+		err = runNSGA2(leftNum, params, leftSeed, olds, func(data map[string]float64) ([]float64, *errs.Error) {
+			_, err := runOptJob(data)
+			if err != nil {
+				return nil, err
+			}
+			return resList[len(resList)-1].Objs, nil
+		})
 	} else {
-		err = runGOptuna(method, rounds, params, runOptJob)
+		err = runGOptuna(method, leftNum, params, leftSeed, olds, runOptJob)
 	}
-	best := calcBestBy(resList, picker)
+	pickFrom := make([]*OptInfo, 0, len(resList))
+	for _, o := range resList {
+		if o.Pruned == 0 {
+			pickFrom = append(pickFrom, o)
+		}
+	}
+	if len(pickFrom) == 0 {
+		pickFrom = resList
+	}
+	if len(objs) > 0 {
+		// pick from the non-dominated results 从非支配的结果中挑选
+		writeParetoFront(flog, objs, pickFrom)
+		if front := ParetoFront(pickFrom); len(front) > 0 {
+			pickFrom = front
+		}
+	}
+	best := calcBestBy(pickFrom, picker)
 	if best.BTResult == nil {
 		best.ID = utils.RandomStr(6)
-		best.runGetBtResult(pol)
+		best.runGetBtResult(pol, pols)
 		best.dumpDetail(filepath.Join(detailDir, best.ID+".json"))
 		line := fmt.Sprintf("[%s] %s", picker, best.ToLine())
 		flog.WriteString(line + "\n")
 		log.Warn(line)
+	} else if len(best.PairGrps) == 0 && best.ID != "" {
+		// trials of resumed study only have brief results, load the detail for picking pairs
+		// 继续的研究中的轮次只有摘要结果，加载详情用于选择品种
+		best.loadDetail(detailDir)
 	}
 	if err != nil {
 		log.Error("optimize fail", zap.String("job", title), zap.Error(err))
@@ -599,21 +819,25 @@ func optForPol(pol *config.RunPolicyConfig, method, picker string, rounds int, f
 	pol.Params = best.Params
 	pol.Score = best.Score
 	pol.MaxOpen = best.OrderNum
+	return best
 }
 
-func runBTOnce() (*BackTest, float64) {
-	core.BotRunning = true
-	biz.ResetVars()
-	bt := NewBackTest(true, "")
+/*
+runBTOnce
+Run a backtest of pols in a new context for hyper optimization, onReport is optional for pruning
+在新的上下文中为超参数优化运行一次pols的回测，onReport可选，用于剪枝
+*/
+func runBTOnce(pols []*config.RunPolicyConfig, onReport func(step int, value float64) bool) (*BackTest, float64) {
+	bt := NewBackTest(NewBTContext(pols), true, "")
+	bt.OnReport = onReport
 	bt.Run()
 	var loss = -bt.Score()
 	return bt, loss
 }
 
-func runGOptuna(name string, rounds int, params []*core.Param, loop FuncOptTask) *errs.Error {
+func runGOptuna(name string, rounds int, params []*core.Param, seed int64, olds []*OptInfo, loop FuncOptTask) *errs.Error {
 	var sampler goptuna.Sampler
 	var options []goptuna.StudyOption
-	var seed = int64(0)
 	if name == "random" {
 		sampler = goptuna.NewRandomSampler(goptuna.RandomSamplerOptionSeed(seed))
 	} else if name == "cmaes" {
@@ -631,7 +855,7 @@ func runGOptuna(name string, rounds int, params []*core.Param, loop FuncOptTask)
 			cmaes.SamplerOptionBIPop(2))
 		options = append(options, goptuna.StudyOptionRelativeSampler(rs))
 	} else if name == "tpe" {
-		sampler = tpe.NewSampler()
+		sampler = tpe.NewSampler(tpe.SamplerOptionSeed(seed))
 	} else {
 		panic("invalid sampler")
 	}
@@ -640,6 +864,10 @@ func runGOptuna(name string, rounds int, params []*core.Param, loop FuncOptTask)
 	if err_ != nil {
 		return errs.New(errs.CodeRunTime, err_)
 	}
+	err_ = addGOptunaTrials(study, params, olds)
+	if err_ != nil {
+		return errs.New(errs.CodeRunTime, err_)
+	}
 	err_ = study.Optimize(func(trial goptuna.Trial) (float64, error) {
 		var data = make(map[string]float64)
 		for _, p := range params {
@@ -667,14 +895,32 @@ func runGOptuna(name string, rounds int, params []*core.Param, loop FuncOptTask)
 	return nil
 }
 
-func runBayes(rounds int, params []*core.Param, loop FuncOptTask) *errs.Error {
+// seedParam samples from rng instead of the global math/rand 从rng采样而非全局math/rand
+type seedParam struct {
+	bayesopt.UniformParam
+	rng *rand.Rand
+}
+
+func (p seedParam) Sample() float64 {
+	return p.rng.Float64()*(p.Max-p.Min) + p.Min
+}
+
+/*
+runBayes
+Run bayes optimization for rounds (including olds) with a sampler seeded by seed.
+Earlier trials in olds are replayed in order instead of running backtests, so a resumed study samples
+the same points as an uninterrupted one.
+使用seed作为种子的采样器运行rounds轮(包含olds)贝叶斯优化。
+olds中已有的轮次按顺序重放而不运行回测，因此继续的研究与未中断时采样相同的点。
+*/
+func runBayes(rounds int, params []*core.Param, seed int64, olds []*OptInfo, loop FuncOptTask) *errs.Error {
+	rng := rand.New(rand.NewSource(seed))
 	bysParams := make([]bayesopt.Param, 0, len(params))
 	for _, p := range params {
 		minVal, maxVal := p.OptSpace()
-		bysParams = append(bysParams, bayesopt.UniformParam{
-			Name: p.Name,
-			Min:  minVal,
-			Max:  maxVal,
+		bysParams = append(bysParams, seedParam{
+			UniformParam: bayesopt.UniformParam{Name: p.Name, Min: minVal, Max: maxVal},
+			rng:          rng,
 		})
 	}
 	options := []bayesopt.OptimizerOption{
@@ -683,21 +929,46 @@ func runBayes(rounds int, params []*core.Param, loop FuncOptTask) *errs.Error {
 		bayesopt.WithRandomRounds(rounds / 2),
 	}
 	opt := bayesopt.New(bysParams, options...)
-	_, _, err_ := opt.Optimize(func(m map[bayesopt.Param]float64) float64 {
+	for i := 0; ; i++ {
+		x, _, err_ := opt.Next()
+		if err_ != nil {
+			return errs.New(errs.CodeRunTime, err_)
+		}
+		if x == nil {
+			break
+		}
 		var data = make(map[string]float64)
-		for k, v := range m {
+		for k, v := range x {
 			data[k.GetName()] = v
 		}
 		for _, p := range params {
 			data[p.Name], _ = p.ToRegular(data[p.Name])
 		}
-		score, _ := loop(data)
-		return score
-	})
-	if err_ != nil {
-		return errs.New(errs.CodeRunTime, err_)
+		var loss float64
+		if i < len(olds) {
+			old := olds[i]
+			for j, p := range params {
+				if val, ok := old.Params[p.Name]; ok && val != data[p.Name] {
+					// sampled by another seed, use the params of the trial 由其他种子采样，使用该轮次的参数
+					x[bysParams[j]] = toOptSpace(p, val)
+				}
+			}
+			// same as runOptJob: pruned trial is no better than the worst finished one
+			// 与runOptJob相同：剪枝的轮次不优于最差的已完成轮次
+			loss = -old.Score
+			if old.Pruned > 0 {
+				for _, it := range olds[:i] {
+					if it.Pruned == 0 {
+						loss = max(loss, -it.Score)
+					}
+				}
+			}
+		} else {
+			loss, _ = loop(data)
+		}
+		opt.Log(x, loss)
 	}
-	err_ = opt.ExplorationErr()
+	err_ := opt.ExplorationErr()
 	if err_ != nil {
 		log.Warn("bayes early stop", zap.String("err", err_.Error()))
 	}
@@ -706,10 +977,10 @@ func runBayes(rounds int, params []*core.Param, loop FuncOptTask) *errs.Error {
 
 /*
 CollectOptLog
-Collect and analyze the logs generated by RunOptimize
-Sorts all policy tasks in reverse score order of output.
-收集分析RunOptimize生成的日志
-将所有策略任务按分数倒序排列输出。
+Collect the studies saved by RunOptimize, pick the best of each policy group by `-picker`,
+and print them in reverse score order. `-in` is an optimize log or a directory containing them.
+收集RunOptimize保存的研究，按`-picker`挑选每个策略组的最佳结果，并按分数倒序输出。
+`-in`为优化日志或包含优化日志的目录。
 */
 func CollectOptLog(args *config.CmdArgs) *errs.Error {
 	if args.InPath == "" {
@@ -720,272 +991,137 @@ func CollectOptLog(args *config.CmdArgs) *errs.Error {
 	if err_ != nil {
 		return errs.New(errs.CodeIOReadFail, err_)
 	}
-	if !info.IsDir() {
+	paths := make([]string, 0)
+	if info.IsDir() {
+		filepath.WalkDir(args.InPath, func(path string, d fs.DirEntry, err error) error {
+			if strings.HasSuffix(path, ".log") {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+	} else {
 		sortOptLogs(args.InPath)
-		return nil
+		paths = append(paths, args.InPath)
 	}
 	core.SetRunMode(core.RunModeBackTest)
 	err := biz.SetupComsExg(args)
 	if err != nil {
 		return err
 	}
-	paths := make([]string, 0)
-	filepath.WalkDir(args.InPath, func(path string, d fs.DirEntry, err error) error {
-		if strings.HasSuffix(path, ".log") {
-			paths = append(paths, path)
+	var res []*GroupScore
+	for _, path := range paths {
+		items, err := collectStudy(path, args.Picker)
+		if err != nil {
+			return err
 		}
-		return nil
-	})
-	res, err := collectOptLog(paths, 0, args.Picker, args.PairPicker)
-	if err != nil {
-		return err
+		res = append(res, items...)
 	}
-	fmt.Print(res)
+	fmt.Print(dumpOptGroups(res, 0, args.PairPicker))
 	return nil
 }
 
-func collectOptLog(paths []string, minScore float64, picker, pairSel string) (string, *errs.Error) {
-	res := make([]*OptGroup, 0)
-	detailDir := ""
-	for _, path := range paths {
-		var name, pair, dirt, tfStr string
-		inUnion := false
-		var items []*OptInfo
-		var long, short, both, union, longMain, shortMain *OptInfo
-		var pickerMap = make(map[string]*OptInfo)
-		fdata, err_ := os.ReadFile(path)
-		if err_ != nil {
-			return "", errs.New(errs.CodeIOReadFail, err_)
+/*
+collectStudy
+Pick the best policies of each group from the study of the optimize log, same as optForGroup.
+The union of long and short without tuning is not saved as trials, so it's not compared here.
+从优化日志对应的研究中挑选每组的最佳策略，与optForGroup相同。
+未调优的多空组合没有保存为轮次，这里不参与比较。
+*/
+func collectStudy(outPath, picker string) ([]*GroupScore, *errs.Error) {
+	dbPath := GetStudyDbPath(outPath)
+	if !utils.Exists(dbPath) {
+		return nil, nil
+	}
+	store, err := OpenStudyStore(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	trials, err := store.ListTrials(DefStudyName(outPath))
+	if err != nil || len(trials) == 0 {
+		return nil, err
+	}
+	detailDir := filepath.Join(filepath.Dir(outPath), "detail")
+	// group trials by the tuned policy and fixed policies 按调优策略和固定策略分组
+	var keys []string
+	var keyTrials = make(map[string][]*OptInfo)
+	for _, t := range trials {
+		if t.Pruned > 0 {
+			continue
 		}
-		if detailDir == "" {
-			detailDir = filepath.Join(filepath.Dir(path), "detail")
-			_ = utils.EnsureDir(detailDir, 0755)
+		o := &OptInfo{ID: t.ID, Score: t.Score, Params: t.Params}
+		if t.Result != nil {
+			o.BTResult = t.Result.toResult()
 		}
-		saveGroup := func() {
-			if name == "" {
-				return
-			}
-			var oneSide = long
-			if short != nil && (oneSide == nil || short.Score > oneSide.Score) {
-				oneSide = short
-			}
-			var twoSide = both
-			if union != nil && (twoSide == nil || union.Score > twoSide.Score) {
-				twoSide = union
-			}
-			sideMain := 0
-			if longMain != nil && (twoSide == nil || longMain.Score > twoSide.Score) {
-				twoSide = longMain
-				sideMain = 1
-			}
-			if shortMain != nil && (twoSide == nil || shortMain.Score > twoSide.Score) {
-				twoSide = shortMain
-				sideMain = 2
-			}
-			var pols []*OptInfo
-			var bestScore float64
-			var useOne = true
-			if twoSide == nil {
-				useOne = true
-			} else if oneSide == nil {
-				useOne = false
-			} else {
-				scoreRate := twoSide.Score / oneSide.Score
-				odNumRate := float64(twoSide.OrderNum) / float64(oneSide.OrderNum)
-				if scoreRate > 1.25 || scoreRate > 1.1 && odNumRate < 1.5 {
-					useOne = false
-				}
-			}
-			if !useOne {
-				bestScore = twoSide.Score
-				if twoSide.Dirt == "long" {
-					long.Params = twoSide.Params
-				} else if twoSide.Dirt == "short" {
-					short.Params = twoSide.Params
-				}
-				if twoSide.Dirt == "" {
-					pols = []*OptInfo{twoSide}
-				} else if sideMain == 1 {
-					pols = []*OptInfo{long, longMain}
-				} else if sideMain == 2 {
-					pols = []*OptInfo{short, shortMain}
-				} else {
-					pols = []*OptInfo{long, short}
-				}
-			} else {
-				bestScore = oneSide.Score
-				pols = []*OptInfo{oneSide}
-			}
-			res = append(res, &OptGroup{
-				Items: pols,
-				Score: bestScore,
-				Name:  name,
-				Pair:  pair,
-				TFStr: tfStr,
-			})
-			long, short, both, union, longMain, shortMain = nil, nil, nil, nil, nil, nil
-			name, pair, dirt, tfStr = "", "", "", ""
-			inUnion = false
-			items = nil
-			pickerMap = make(map[string]*OptInfo)
+		if _, ok := keyTrials[t.Pol]; !ok {
+			keys = append(keys, t.Pol)
 		}
-		lines := strings.Split(string(fdata), "\n")
-		outs := make([]string, 0, len(lines))
-		outs = append(outs, lines[:2]...)
-		for _, line := range lines[2:] {
-			outs = append(outs, line)
-			if strings.HasPrefix(line, "loss:") {
-				opt := parseOptLine(line)
-				items = append(items, opt)
-			} else if strings.HasPrefix(line, "[") && strings.Contains(line, "loss:") {
-				// 保存指定picker的结果
-				start := strings.Index(line, "loss:")
-				pkEnd := strings.IndexRune(line, ']')
-				pickerMap[line[1:pkEnd]] = parseOptLine(line[start:])
-			} else if line == "" {
-				// end section, calc best
-				var best *OptInfo
-				if old, has := pickerMap[picker]; has {
-					best = old
-				} else {
-					best = calcBestBy(items, picker)
-				}
-				if best != nil {
-					needRun := best.BTResult == nil || best.ID == ""
-					if inUnion {
-						union = best
-						union.Dirt = "union"
-						inUnion = false
-						if needRun {
-							config.RunPolicy = []*config.RunPolicyConfig{
-								long.ToPol(name, dirt, tfStr, pair),
-								short.ToPol(name, dirt, tfStr, pair),
-							}
-						}
-					} else if dirt == "long" {
-						if long == nil {
-							long = best
-							if needRun {
-								config.RunPolicy = []*config.RunPolicyConfig{
-									long.ToPol(name, dirt, tfStr, pair),
-								}
-							}
-						} else {
-							shortMain = best
-							if needRun {
-								config.RunPolicy = []*config.RunPolicyConfig{
-									short.ToPol(name, dirt, tfStr, pair),
-									shortMain.ToPol(name, dirt, tfStr, pair),
-								}
-							}
-						}
-					} else if dirt == "short" {
-						if short == nil {
-							short = best
-							if needRun {
-								config.RunPolicy = []*config.RunPolicyConfig{
-									short.ToPol(name, dirt, tfStr, pair)}
-							}
-						} else {
-							longMain = best
-							if needRun {
-								config.RunPolicy = []*config.RunPolicyConfig{
-									long.ToPol(name, dirt, tfStr, pair),
-									longMain.ToPol(name, dirt, tfStr, pair)}
-							}
-						}
-					} else {
-						both = best
-						if needRun {
-							config.RunPolicy = []*config.RunPolicyConfig{both.ToPol(name, dirt, tfStr, pair)}
-						}
-					}
-					var dumpPath string
-					if best.ID != "" {
-						dumpPath = filepath.Join(detailDir, best.ID+".json")
-						btRes, err := parseBtResult(dumpPath)
-						if err != nil {
-							log.Warn("parse BtResult fail", zap.String("err", err.Short()))
-							needRun = true
-						} else {
-							best.BTResult = btRes
-						}
-					}
-					if needRun {
-						if best.BTResult == nil || len(best.PairGrps) == 0 {
-							best.ID = utils.RandomStr(6)
-							dumpPath = filepath.Join(detailDir, best.ID+".json")
-							best.runGetBtResult(config.RunPolicy[len(config.RunPolicy)-1])
-							best.dumpDetail(dumpPath)
-						}
-						l := fmt.Sprintf("[%s] %s", picker, best.ToLine())
-						idx := len(outs) - 1
-						outs = append(outs[:idx], []string{l, outs[idx]}...)
-					}
-				}
-				items = nil
-				if len(pickerMap) > 0 {
-					pickerMap = make(map[string]*OptInfo)
-				}
-				continue
-			}
-			if strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "# ") {
-				// 跳过输出的配置、注释信息
-				continue
-			}
-			if strings.HasPrefix(line, "==============") && strings.HasSuffix(line, "=============") {
-				n, d, t, p := parseSectionTitle(strings.Split(line, " ")[1])
-				if p != pair || n != name || t != tfStr {
-					saveGroup()
-				}
-				name, dirt, tfStr, pair = n, d, t, p
-				inUnion = false
-			} else if strings.HasPrefix(line, "========== union") {
-				inUnion = true
-			}
+		keyTrials[t.Pol] = append(keyTrials[t.Pol], o)
+	}
+	type polBest struct {
+		pol  *config.RunPolicyConfig
+		best *OptInfo
+	}
+	// best of policies tuned alone: polKey: polBest 单独调优的策略的最佳结果
+	var alones = make(map[string]*polBest)
+	var bests = make(map[string]*OptInfo)
+	var secKeys []string
+	var secs = make(map[string][]*GroupScore)
+	var unions = make(map[string][]string)
+	for _, key := range keys {
+		best := calcBestBy(keyTrials[key], picker)
+		if len(best.PairGrps) == 0 {
+			best.loadDetail(detailDir)
 		}
-		if len(outs) > len(lines) {
-			err := utils.WriteFile(path, []byte(strings.Join(outs, "\n")))
-			if err != nil {
-				log.Warn("write fail", zap.String("p", path), zap.Error(err))
-			}
+		bests[key] = best
+		polKey, _, isUnion := strings.Cut(key, ",")
+		name, dirt, tfStr, pairStr := parseSectionTitle(polKey)
+		secKey := fmt.Sprintf("%s/%s/%s", name, tfStr, pairStr)
+		if _, ok := secs[secKey]; !ok {
+			secKeys = append(secKeys, secKey)
+			secs[secKey] = nil
 		}
-		saveGroup()
-	}
-	sort.Slice(res, func(i, j int) bool {
-		return res[i].Score > res[j].Score
-	})
-	var b strings.Builder
-	b.WriteString("run_policy:\n")
-	for _, gp := range res {
-		if gp.Score < minScore {
-			break
+		if isUnion {
+			unions[secKey] = append(unions[secKey], key)
+			continue
 		}
-		b.WriteString(fmt.Sprintf("\n  # score: %.2f\n", gp.Score))
-		for _, p := range gp.Items {
-			tfStr := strings.ReplaceAll(gp.TFStr, "|", ", ")
-			b.WriteString(fmt.Sprintf("  - name: %s\n    run_timeframes: [ %s ]\n", gp.Name, tfStr))
-			var pairStr string
-			if pairSel != "" {
-				pairs := selectPairs(p.BTResult, pairSel)
-				if len(pairs) > 0 {
-					pairStr = strings.Join(pairs, ", ")
-				}
+		pol := best.ToPol(name, dirt, tfStr, pairStr)
+		alones[polKey] = &polBest{pol: pol, best: best}
+		item := &GroupScore{Items: []*config.RunPolicyConfig{pol}, Bests: []*OptInfo{best}, Score: best.Score}
+		secs[secKey] = append(secs[secKey], item)
+	}
+	res := make([]*GroupScore, 0, len(secKeys))
+	for _, secKey := range secKeys {
+		var sel *GroupScore
+		for _, gp := range secs[secKey] {
+			if sel == nil || gp.Score > sel.Score {
+				sel = gp
 			}
-			if pairStr == "" && gp.Pair != "" {
-				pairStr = strings.ReplaceAll(gp.Pair, "|", ", ")
+		}
+		// one side tuned with the other side fixed 固定一侧参数，调优另一侧
+		for _, key := range unions[secKey] {
+			polKey, fixKey, _ := strings.Cut(key, ",")
+			fixed, ok := alones[fixKey]
+			if !ok {
+				continue
 			}
-			if pairStr != "" {
-				b.WriteString(fmt.Sprintf("    pairs: [%s]\n", pairStr))
+			best := bests[key]
+			if sel != nil && best.Score <= sel.Score {
+				continue
 			}
-			if p.Dirt != "" {
-				b.WriteString(fmt.Sprintf("    dirt: %s\n", p.Dirt))
+			name, dirt, tfStr, pairStr := parseSectionTitle(polKey)
+			pol := best.ToPol(name, dirt, tfStr, pairStr)
+			sel = &GroupScore{
+				Items: []*config.RunPolicyConfig{fixed.pol, pol},
+				Bests: []*OptInfo{fixed.best, best},
+				Score: best.Score,
 			}
-			paramStr := utils.MapToStr(p.Params, true, 2)
-			b.WriteString(fmt.Sprintf("    params: {%s}\n", paramStr))
+		}
+		if sel != nil {
+			res = append(res, sel)
 		}
 	}
-	return b.String(), nil
+	return res, nil
 }
 
 /*
@@ -993,7 +1129,8 @@ Parse the header and return: policy name, direction, tfStr, pairStr
 解析标题，返回：策略名，方向，tfStr，pairStr
 */
 func parseSectionTitle(title string) (string, string, string, string) {
-	arr := strings.Split(title, "/")
+	// pairs may contain "/" 品种中可能包含"/"
+	arr := strings.SplitN(title, "/", 3)
 	name, dirt := parsePolID(arr[0])
 	return name, dirt, arr[1], arr[2]
 }
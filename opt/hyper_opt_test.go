@@ -1,7 +1,50 @@
 package opt
 
-import "testing"
+import (
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banexg/errs"
+	"math"
+	"reflect"
+	"testing"
+)
 
 func TestSortOptLogs(t *testing.T) {
 	sortOptLogs("E:\\trade\\go\\bandata\\backtest\\opt_bearMacd.log")
 }
+
+func TestRunBayesSeed(t *testing.T) {
+	params := []*core.Param{
+		{Name: "x", Min: 0, Max: 2, VType: core.VTypeUniform},
+		{Name: "y", Min: -1, Max: 1, VType: core.VTypeUniform},
+	}
+	run := func(rounds int, seed int64, olds []*OptInfo) []*OptInfo {
+		var res []*OptInfo
+		err := runBayes(rounds, params, seed, olds, func(data map[string]float64) (float64, *errs.Error) {
+			loss := math.Pow(data["x"]-0.7, 2) + math.Pow(data["y"]+0.2, 2)
+			res = append(res, &OptInfo{Params: data, Score: -loss})
+			return loss, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	// bayes rounds are slow, use 2 random rounds and 2 bayes rounds 贝叶斯轮次较慢，使用2个随机轮次和2个贝叶斯轮次
+	trials := run(4, 7, nil)
+	if len(trials) != 4 {
+		t.Fatalf("expect 4 trials, got %v", len(trials))
+	}
+	if again := run(4, 7, nil); !reflect.DeepEqual(trials, again) {
+		t.Errorf("same seed should produce same trials")
+	}
+	if other := run(2, 8, nil); reflect.DeepEqual(trials[0].Params, other[0].Params) {
+		t.Errorf("different seed should produce different trials")
+	}
+	// resume in random and bayes rounds, should continue as uninterrupted run 在随机和贝叶斯轮次中继续，应与未中断时相同
+	for _, done := range []int{1, 3} {
+		left := run(4, 7, trials[:done])
+		if !reflect.DeepEqual(trials[done:], left) {
+			t.Errorf("resume from %v should continue the same trials", done)
+		}
+	}
+}
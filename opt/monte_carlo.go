@@ -0,0 +1,377 @@
+package opt
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/banbox/banbot/orm/ormo"
+	"github.com/banbox/banbot/utils"
+	"github.com/banbox/banexg/errs"
+	"github.com/banbox/banexg/log"
+	utils2 "github.com/banbox/banexg/utils"
+	"github.com/olekukonko/tablewriter"
+	"go.uber.org/zap"
+)
+
+const (
+	MCShuffle   = "shuffle"   // shuffle order of trades 打乱交易顺序
+	MCBootstrap = "bootstrap" // resample trades with replacement 有放回地重采样交易
+	MCSkip      = "skip"      // skip trades randomly 随机跳过交易
+	MCSlippage  = "slippage"  // add random slippage to trades 为交易添加随机滑点
+)
+
+var MCMethods = []string{MCShuffle, MCBootstrap, MCSkip, MCSlippage}
+
+// mcPercents percentiles shown in the distribution charts 分布图中展示的百分位
+var mcPercents = []float64{0, 5, 10, 20, 30, 40, 50, 60, 70, 80, 90, 95, 100}
+
+type MCArgs struct {
+	Runs        int     `json:"runs"`        // simulations for each method 每种方法的模拟次数
+	SkipRate    float64 `json:"skipRate"`    // probability of skipping a trade 跳过一笔交易的概率
+	SlipPct     float64 `json:"slipPct"`     // max slippage percent of enter cost for each side 每侧最大滑点占入场成本的百分比
+	Seed        int64   `json:"seed"`        // random seed 随机种子
+	InitBalance float64 `json:"initBalance"` // use totalInvest of backtest when 0 为0时使用回测的总投资
+}
+
+// MCStat distribution of a metric over simulations 一个指标在多次模拟中的分布
+type MCStat struct {
+	Mean float64 `json:"mean"`
+	P5   float64 `json:"p5"`
+	P25  float64 `json:"p25"`
+	P50  float64 `json:"p50"`
+	P75  float64 `json:"p75"`
+	P95  float64 `json:"p95"`
+}
+
+// MCPath metrics of one equity path 一条资金曲线的指标
+type MCPath struct {
+	FinalBalance   float64 `json:"finalBalance"`
+	MaxDrawDownPct float64 `json:"maxDrawDownPct"`
+	RecoveryDays   float64 `json:"recoveryDays"` // longest time from a peak until recovered 从高点到恢复的最长时间
+}
+
+type MCMethodRes struct {
+	Method         string    `json:"method"`
+	FinalBalance   *MCStat   `json:"finalBalance"`
+	MaxDrawDownPct *MCStat   `json:"maxDrawDownPct"`
+	RecoveryDays   *MCStat   `json:"recoveryDays"`
+	LossProb       float64   `json:"lossProb"` // probability of final balance below initial 最终余额低于初始的概率
+	balances       []float64 // sorted samples 排序后的样本
+	drawDowns      []float64
+	recoveries     []float64
+}
+
+/*
+MonteCarloRes
+Robustness of a backtest by resampling its closed orders
+通过对已平仓订单重采样评估回测的稳健性
+*/
+type MonteCarloRes struct {
+	Args        *MCArgs        `json:"args"`
+	InitBalance float64        `json:"initBalance"`
+	OrderNum    int            `json:"orderNum"`
+	Origin      *MCPath        `json:"origin"`
+	Methods     []*MCMethodRes `json:"methods"`
+}
+
+/*
+calcMCPath
+calculate metrics of an equity path, time to recovery is measured in trades and converted to days by the average trade interval
+计算资金曲线的指标，恢复时间以交易笔数计，并按平均交易间隔换算为天数
+*/
+func calcMCPath(profits []float64, initBalance, tradeDays float64) *MCPath {
+	res := &MCPath{FinalBalance: initBalance}
+	if len(profits) == 0 {
+		return res
+	}
+	// leading zero makes the initial balance the first peak 前置0使初始余额成为第一个高点
+	ddPct, _, _, _, _, _ := utils.CalcMaxDrawDown(append([]float64{0}, profits...), initBalance)
+	res.MaxDrawDownPct = utils.NanInfTo(ddPct*100, 0)
+	peak, peakIdx, longest := initBalance, 0, 0
+	equity := initBalance
+	for i, p := range profits {
+		equity += p
+		if equity >= peak {
+			longest = max(longest, i+1-peakIdx)
+			peak, peakIdx = equity, i+1
+		}
+	}
+	// drawdown not recovered until the end 直到结束仍未恢复的回撤
+	longest = max(longest, len(profits)-peakIdx)
+	res.FinalBalance = equity
+	res.RecoveryDays = float64(longest) * tradeDays
+	return res
+}
+
+// percentile of sorted values with linear interpolation 对已排序数据线性插值计算百分位
+func percentile(sorted []float64, pct float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := pct / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := min(lo+1, len(sorted)-1)
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+func newMCStat(sorted []float64) *MCStat {
+	sum := float64(0)
+	for _, v := range sorted {
+		sum += v
+	}
+	res := &MCStat{
+		P5:  percentile(sorted, 5),
+		P25: percentile(sorted, 25),
+		P50: percentile(sorted, 50),
+		P75: percentile(sorted, 75),
+		P95: percentile(sorted, 95),
+	}
+	if len(sorted) > 0 {
+		res.Mean = sum / float64(len(sorted))
+	}
+	return res
+}
+
+// sampleProfits generate profits of a simulated path 生成一次模拟路径的利润序列
+func sampleProfits(method string, profits, costs []float64, args *MCArgs, rnd *rand.Rand, out []float64) []float64 {
+	out = out[:0]
+	switch method {
+	case MCShuffle:
+		out = append(out, profits...)
+		rnd.Shuffle(len(out), func(i, j int) {
+			out[i], out[j] = out[j], out[i]
+		})
+	case MCBootstrap:
+		for range profits {
+			out = append(out, profits[rnd.Intn(len(profits))])
+		}
+	case MCSkip:
+		for _, p := range profits {
+			if rnd.Float64() >= args.SkipRate {
+				out = append(out, p)
+			}
+		}
+	case MCSlippage:
+		for i, p := range profits {
+			// enter and exit both have slippage 入场和出场都有滑点
+			slip := (rnd.Float64() + rnd.Float64()) * args.SlipPct / 100
+			out = append(out, p-costs[i]*slip)
+		}
+	}
+	return out
+}
+
+/*
+MonteCarlo
+run Monte Carlo simulations over closed orders with each method in MCMethods
+使用MCMethods中的每种方法对已平仓订单进行蒙特卡洛模拟
+*/
+func MonteCarlo(orders []*ormo.InOutOrder, args *MCArgs) (*MonteCarloRes, *errs.Error) {
+	if args.InitBalance <= 0 {
+		return nil, errs.NewMsg(errs.CodeParamRequired, "init balance is required for monte carlo")
+	}
+	if args.Runs <= 0 {
+		args.Runs = 1000
+	}
+	ods := make([]*ormo.InOutOrder, 0, len(orders))
+	for _, od := range orders {
+		if od.Exit != nil && od.Enter != nil {
+			ods = append(ods, od)
+		}
+	}
+	if len(ods) == 0 {
+		return nil, errs.NewMsg(errs.CodeParamInvalid, "no closed orders for monte carlo")
+	}
+	slices.SortFunc(ods, func(a, b *ormo.InOutOrder) int {
+		return int(a.RealExitMS() - b.RealExitMS())
+	})
+	profits := make([]float64, len(ods))
+	costs := make([]float64, len(ods))
+	for i, od := range ods {
+		profits[i] = od.Profit
+		costs[i] = od.EnterCost()
+	}
+	// average days between trades 交易之间的平均天数
+	spanMS := ods[len(ods)-1].RealExitMS() - ods[0].RealEnterMS()
+	tradeDays := float64(max(spanMS, 0)) / float64(utils2.SecsDay*1000) / float64(len(ods))
+	res := &MonteCarloRes{
+		Args:        args,
+		InitBalance: args.InitBalance,
+		OrderNum:    len(ods),
+		Origin:      calcMCPath(profits, args.InitBalance, tradeDays),
+	}
+	rnd := rand.New(rand.NewSource(args.Seed))
+	buf := make([]float64, 0, len(profits))
+	for _, method := range MCMethods {
+		item := &MCMethodRes{
+			Method:     method,
+			balances:   make([]float64, 0, args.Runs),
+			drawDowns:  make([]float64, 0, args.Runs),
+			recoveries: make([]float64, 0, args.Runs),
+		}
+		lossNum := 0
+		for i := 0; i < args.Runs; i++ {
+			buf = sampleProfits(method, profits, costs, args, rnd, buf)
+			path := calcMCPath(buf, args.InitBalance, tradeDays)
+			item.balances = append(item.balances, path.FinalBalance)
+			item.drawDowns = append(item.drawDowns, path.MaxDrawDownPct)
+			item.recoveries = append(item.recoveries, path.RecoveryDays)
+			if path.FinalBalance < args.InitBalance {
+				lossNum += 1
+			}
+		}
+		slices.Sort(item.balances)
+		slices.Sort(item.drawDowns)
+		slices.Sort(item.recoveries)
+		item.FinalBalance = newMCStat(item.balances)
+		item.MaxDrawDownPct = newMCStat(item.drawDowns)
+		item.RecoveryDays = newMCStat(item.recoveries)
+		item.LossProb = float64(lossNum) * 100 / float64(args.Runs)
+		res.Methods = append(res.Methods, item)
+	}
+	return res, nil
+}
+
+// Text render confidence intervals of each method as a table 将每种方法的置信区间渲染为表格
+func (r *MonteCarloRes) Text() string {
+	var b bytes.Buffer
+	table := tablewriter.NewWriter(&b)
+	table.SetHeader([]string{"Method", "Metric", "Mean", "5%", "25%", "50%", "75%", "95%", "Origin"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetAlignment(tablewriter.ALIGN_RIGHT)
+	fmtFlt := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+	for _, m := range r.Methods {
+		rows := []struct {
+			name   string
+			sta    *MCStat
+			origin float64
+		}{
+			{"Final Balance", m.FinalBalance, r.Origin.FinalBalance},
+			{"Max DrawDown%", m.MaxDrawDownPct, r.Origin.MaxDrawDownPct},
+			{"Recovery Days", m.RecoveryDays, r.Origin.RecoveryDays},
+		}
+		for _, row := range rows {
+			s := row.sta
+			table.Append([]string{m.Method, row.name, fmtFlt(s.Mean), fmtFlt(s.P5), fmtFlt(s.P25), fmtFlt(s.P50),
+				fmtFlt(s.P75), fmtFlt(s.P95), fmtFlt(row.origin)})
+		}
+	}
+	table.Render()
+	for _, m := range r.Methods {
+		b.WriteString(fmt.Sprintf("%s loss probability: %.1f%%\n", m.Method, m.LossProb))
+	}
+	return b.String()
+}
+
+/*
+Dump
+write monte_carlo.json and percentile charts of final balance, drawdown and recovery days
+写入monte_carlo.json，以及最终余额、回撤和恢复天数的百分位图
+*/
+func (r *MonteCarloRes) Dump(outDir string) *errs.Error {
+	data, err_ := utils2.Marshal(r)
+	if err_ != nil {
+		return errs.New(errs.CodeMarshalFail, err_)
+	}
+	err_ = os.WriteFile(filepath.Join(outDir, "monte_carlo.json"), data, 0644)
+	if err_ != nil {
+		return errs.New(errs.CodeIOWriteFail, err_)
+	}
+	labels := make([]string, 0, len(mcPercents))
+	for _, pct := range mcPercents {
+		labels = append(labels, strconv.FormatFloat(pct, 'f', -1, 64)+"%")
+	}
+	charts := []struct {
+		name  string
+		title string
+		get   func(m *MCMethodRes) []float64
+	}{
+		{"mc_balance.html", "Final Balance Percentiles", func(m *MCMethodRes) []float64 { return m.balances }},
+		{"mc_drawdown.html", "Max DrawDown% Percentiles", func(m *MCMethodRes) []float64 { return m.drawDowns }},
+		{"mc_recovery.html", "Recovery Days Percentiles", func(m *MCMethodRes) []float64 { return m.recoveries }},
+	}
+	for _, c := range charts {
+		dsList := make([]*ChartDs, 0, len(r.Methods))
+		for _, m := range r.Methods {
+			sorted := c.get(m)
+			vals := make([]float64, 0, len(mcPercents))
+			for _, pct := range mcPercents {
+				vals = append(vals, percentile(sorted, pct))
+			}
+			dsList = append(dsList, &ChartDs{Label: m.Method, Data: vals})
+		}
+		err := DumpChart(filepath.Join(outDir, c.name), c.title, labels, 5, nil, dsList)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+RunMonteCarloDir
+run Monte Carlo simulations for orders.gob in a backtest directory, and write results to it
+对回测目录中的orders.gob运行蒙特卡洛模拟，并将结果写入该目录
+*/
+func RunMonteCarloDir(btDir string, args *MCArgs) (*MonteCarloRes, *errs.Error) {
+	orders, err := ormo.LoadOrdersGob(filepath.Join(btDir, "orders.gob"))
+	if err != nil {
+		return nil, err
+	}
+	if args.InitBalance <= 0 {
+		detailPath := filepath.Join(btDir, "detail.json")
+		if utils.Exists(detailPath) {
+			detail, err := parseBtResult(detailPath)
+			if err != nil {
+				return nil, err
+			}
+			args.InitBalance = detail.TotalInvest
+		}
+	}
+	res, err := MonteCarlo(orders, args)
+	if err != nil {
+		return nil, err
+	}
+	return res, res.Dump(btDir)
+}
+
+/*
+RunMonteCarlo
+tool command: Monte Carlo robustness analysis of a finished backtest
+工具命令：对已完成回测进行蒙特卡洛稳健性分析
+*/
+func RunMonteCarlo(args []string) error {
+	var btDir string
+	var mcArgs = &MCArgs{}
+	var sub = flag.NewFlagSet("monte_carlo", flag.ExitOnError)
+	sub.StringVar(&btDir, "in", "", "backtest report directory containing orders.gob")
+	sub.IntVar(&mcArgs.Runs, "runs", 1000, "simulations for each method")
+	sub.Float64Var(&mcArgs.SkipRate, "skip", 0.1, "probability of skipping a trade, 0~1")
+	sub.Float64Var(&mcArgs.SlipPct, "slip", 0.1, "max slippage percent of enter cost for each side")
+	sub.Int64Var(&mcArgs.Seed, "seed", 0, "random seed")
+	sub.Float64Var(&mcArgs.InitBalance, "init", 0, "initial balance, default: totalInvest in detail.json")
+	err_ := sub.Parse(args)
+	if err_ != nil {
+		return err_
+	}
+	if btDir == "" {
+		return errs.NewMsg(errs.CodeParamRequired, "-in is required")
+	}
+	res, err := RunMonteCarloDir(btDir, mcArgs)
+	if err != nil {
+		return err
+	}
+	log.Info("Monte Carlo Reports:\n" + res.Text())
+	log.Info("Monte Carlo Saved", zap.String("at", btDir))
+	return nil
+}
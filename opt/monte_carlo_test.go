@@ -0,0 +1,61 @@
+package opt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/banbox/banbot/orm/ormo"
+)
+
+func TestCalcMCPath(t *testing.T) {
+	// equity: 1000 -> 900 -> 950 -> 1100 -> 1050
+	path := calcMCPath([]float64{-100, 50, 150, -50}, 1000, 2)
+	if path.FinalBalance != 1050 {
+		t.Errorf("bad final balance: %v", path.FinalBalance)
+	}
+	if math.Abs(path.MaxDrawDownPct-10) > 1e-9 {
+		t.Errorf("bad drawdown: %v", path.MaxDrawDownPct)
+	}
+	// 3 trades from 1000 to recover 从1000恢复需要3笔交易
+	if path.RecoveryDays != 6 {
+		t.Errorf("bad recovery days: %v", path.RecoveryDays)
+	}
+}
+
+func TestMonteCarlo(t *testing.T) {
+	day := int64(86400000)
+	profits := []float64{120, -80, 60, -150, 200, 30, -40, 90}
+	orders := make([]*ormo.InOutOrder, 0, len(profits))
+	for i, p := range profits {
+		orders = append(orders, &ormo.InOutOrder{
+			IOrder: &ormo.IOrder{Profit: p},
+			Enter:  &ormo.ExOrder{CreateAt: int64(i) * day, Average: 100, Filled: 10},
+			Exit:   &ormo.ExOrder{CreateAt: int64(i+1) * day, Average: 100, Filled: 10},
+		})
+	}
+	res, err := MonteCarlo(orders, &MCArgs{Runs: 200, SkipRate: 0.2, SlipPct: 0.5, InitBalance: 1000, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Methods) != len(MCMethods) || res.Origin.FinalBalance != 1230 {
+		t.Fatalf("bad result: %+v", res)
+	}
+	for _, m := range res.Methods {
+		s := m.FinalBalance
+		if s.P5 > s.P50 || s.P50 > s.P95 {
+			t.Errorf("%s percentiles not ordered: %+v", m.Method, s)
+		}
+		switch m.Method {
+		case MCShuffle:
+			// shuffling keeps the final balance 打乱顺序不改变最终余额
+			if math.Abs(s.P5-1230) > 1e-6 || math.Abs(s.P95-1230) > 1e-6 {
+				t.Errorf("shuffle should keep final balance: %+v", s)
+			}
+		case MCSlippage:
+			// slippage only reduces profits, at most 1% of 1000 cost for each order 滑点只会减少利润
+			if s.P95 > 1230 || s.P5 < 1230-8*10 {
+				t.Errorf("bad slippage balance: %+v", s)
+			}
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package opt
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banexg/errs"
+)
+
+type FnOptObjective func(r *BTResult) float64
+
+type FuncMultiOptTask func(params map[string]float64) ([]float64, *errs.Error)
+
+/*
+OptObjectives
+Objectives for multi-objective hyper optimization, all are maximized.
+多目标超参数优化的目标，全部取最大值。
+*/
+var OptObjectives = map[string]FnOptObjective{
+	"score": func(r *BTResult) float64 {
+		return r.Score()
+	},
+	"profit": func(r *BTResult) float64 {
+		return r.TotProfitPct
+	},
+	"drawdown": func(r *BTResult) float64 {
+		// smaller drawdown is better 回撤越小越好
+		return -r.ShowDrawDownPct
+	},
+	"sharpe": func(r *BTResult) float64 {
+		return r.SharpeRatio
+	},
+	"sortino": func(r *BTResult) float64 {
+		return r.SortinoRatio
+	},
+	"orders": func(r *BTResult) float64 {
+		return float64(r.OrderNum)
+	},
+}
+
+const (
+	DefOptObjectives = "profit,drawdown,sharpe"
+	paretoLinePrefix = "# pareto: "
+)
+
+/*
+ParseObjectives
+Parse comma separated objective names, returns nil if text is empty
+解析逗号分隔的目标名称，text为空时返回nil
+*/
+func ParseObjectives(text string) ([]string, *errs.Error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	var res []string
+	for _, name := range strings.Split(text, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := OptObjectives[name]; !ok {
+			return nil, errs.NewMsg(core.ErrBadConfig, "unknown objective: %s", name)
+		}
+		res = append(res, name)
+	}
+	return res, nil
+}
+
+func calcObjectives(r *BTResult, names []string) []float64 {
+	res := make([]float64, len(names))
+	if r == nil {
+		for i := range res {
+			res[i] = math.Inf(-1)
+		}
+		return res
+	}
+	for i, name := range names {
+		res[i] = OptObjectives[name](r)
+		if math.IsNaN(res[i]) {
+			res[i] = math.Inf(-1)
+		}
+	}
+	return res
+}
+
+// dominates whether a is not worse than b in all objectives and better in at least one a在所有目标上不差于b且至少一个更好
+func dominates(a, b []float64) bool {
+	better := false
+	for i := range a {
+		if a[i] < b[i] {
+			return false
+		} else if a[i] > b[i] {
+			better = true
+		}
+	}
+	return better
+}
+
+/*
+paretoFronts
+Fast non-dominated sort, returns indexes of points for each front, the first is the Pareto front
+快速非支配排序，返回每层前沿的点索引，第一层为帕累托前沿
+*/
+func paretoFronts(points [][]float64) [][]int {
+	num := len(points)
+	domBy := make([]int, num)
+	doms := make([][]int, num)
+	var fronts [][]int
+	var cur []int
+	for i := 0; i < num; i++ {
+		for j := 0; j < num; j++ {
+			if i == j {
+				continue
+			}
+			if dominates(points[i], points[j]) {
+				doms[i] = append(doms[i], j)
+			} else if dominates(points[j], points[i]) {
+				domBy[i] += 1
+			}
+		}
+		if domBy[i] == 0 {
+			cur = append(cur, i)
+		}
+	}
+	for len(cur) > 0 {
+		fronts = append(fronts, cur)
+		var next []int
+		for _, i := range cur {
+			for _, j := range doms[i] {
+				domBy[j] -= 1
+				if domBy[j] == 0 {
+					next = append(next, j)
+				}
+			}
+		}
+		cur = next
+	}
+	return fronts
+}
+
+/*
+crowdingDists
+Crowding distance of points in a front, boundary points are +Inf
+计算前沿中各点的拥挤距离，边界点为+Inf
+*/
+func crowdingDists(points [][]float64, front []int) map[int]float64 {
+	res := make(map[int]float64, len(front))
+	for _, i := range front {
+		res[i] = 0
+	}
+	if len(front) == 0 {
+		return res
+	}
+	objNum := len(points[front[0]])
+	idxs := append([]int{}, front...)
+	for m := 0; m < objNum; m++ {
+		sort.Slice(idxs, func(a, b int) bool {
+			return points[idxs[a]][m] < points[idxs[b]][m]
+		})
+		lo, hi := points[idxs[0]][m], points[idxs[len(idxs)-1]][m]
+		res[idxs[0]] = math.Inf(1)
+		res[idxs[len(idxs)-1]] = math.Inf(1)
+		if hi-lo <= 0 || math.IsInf(hi-lo, 0) {
+			continue
+		}
+		for k := 1; k < len(idxs)-1; k++ {
+			res[idxs[k]] += (points[idxs[k+1]][m] - points[idxs[k-1]][m]) / (hi - lo)
+		}
+	}
+	return res
+}
+
+/*
+ParetoFront
+Return the non-dominated items by Objs, sorted by score desc
+返回按Objs非支配的项，按分数降序
+*/
+func ParetoFront(items []*OptInfo) []*OptInfo {
+	var valids []*OptInfo
+	points := make([][]float64, 0, len(items))
+	for _, it := range items {
+		if len(it.Objs) == 0 {
+			continue
+		}
+		valids = append(valids, it)
+		points = append(points, it.Objs)
+	}
+	fronts := paretoFronts(points)
+	if len(fronts) == 0 {
+		return nil
+	}
+	res := make([]*OptInfo, 0, len(fronts[0]))
+	for _, i := range fronts[0] {
+		res = append(res, valids[i])
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Score > res[j].Score
+	})
+	return res
+}
+
+func writeParetoFront(flog io.StringWriter, objs []string, items []*OptInfo) {
+	front := ParetoFront(items)
+	if len(front) == 0 {
+		return
+	}
+	flog.WriteString(fmt.Sprintf("# pareto front (%s): %d\n", strings.Join(objs, ", "), len(front)))
+	for _, it := range front {
+		flog.WriteString(paretoLinePrefix + it.ToLine() + "\n")
+	}
+}
+
+/*
+ParseParetoLines
+Parse the Pareto front of each section from the optimize log, the key is the section title
+从超参数优化日志中解析每个部分的帕累托前沿，键为部分标题
+*/
+func ParseParetoLines(content string) map[string][]*OptInfo {
+	res := make(map[string][]*OptInfo)
+	title := ""
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "==============") || strings.HasPrefix(line, "========== union") {
+			title = strings.Trim(line, "= ")
+		} else if strings.HasPrefix(line, paretoLinePrefix) {
+			item := parseOptLine(line[len(paretoLinePrefix):])
+			if item != nil {
+				res[title] = append(res[title], item)
+			}
+		}
+	}
+	return res
+}
+
+type nsgaIndividual struct {
+	genes []float64
+	objs  []float64
+	rank  int
+	crowd float64
+}
+
+/*
+runNSGA2
+NSGA-II multi-objective optimization: tournament selection by rank and crowding distance,
+simulated binary crossover and polynomial mutation. All objectives are maximized.
+olds are finished trials of a resumed study, used as the initial population.
+NSGA-II多目标优化：按等级和拥挤距离锦标赛选择，模拟二进制交叉和多项式变异。所有目标取最大值。
+olds是继续的研究中已完成的结果，用作初始种群。
+*/
+func runNSGA2(rounds int, params []*core.Param, seed int64, olds []*OptInfo, loop FuncMultiOptTask) *errs.Error {
+	if rounds <= 0 || len(params) == 0 {
+		return nil
+	}
+	rng := rand.New(rand.NewSource(seed))
+	popSize := min(rounds, max(8, min(40, rounds/5)))
+	lows := make([]float64, len(params))
+	highs := make([]float64, len(params))
+	for i, p := range params {
+		lows[i], highs[i] = p.OptSpace()
+	}
+	toData := func(genes []float64) (map[string]float64, bool) {
+		data := make(map[string]float64, len(params))
+		allValid := true
+		for i, p := range params {
+			val, valid := p.ToRegular(genes[i])
+			allValid = allValid && valid
+			data[p.Name] = val
+		}
+		return data, allValid
+	}
+	randGenes := func() []float64 {
+		var genes []float64
+		for i := 0; i < 100; i++ {
+			genes = make([]float64, len(params))
+			for j := range genes {
+				genes[j] = lows[j] + rng.Float64()*(highs[j]-lows[j])
+			}
+			if _, valid := toData(genes); valid {
+				break
+			}
+		}
+		return genes
+	}
+	used := 0
+	evaluate := func(genes []float64) (*nsgaIndividual, *errs.Error) {
+		data, valid := toData(genes)
+		if !valid {
+			genes = randGenes()
+			data, _ = toData(genes)
+		}
+		used += 1
+		objs, err := loop(data)
+		if err != nil {
+			return nil, err
+		}
+		return &nsgaIndividual{genes: genes, objs: objs}, nil
+	}
+	pop := make([]*nsgaIndividual, 0, popSize)
+	for _, o := range olds {
+		if len(o.Objs) == 0 {
+			continue
+		}
+		genes := make([]float64, len(params))
+		for i, p := range params {
+			genes[i] = toOptSpace(p, o.Params[p.Name])
+		}
+		pop = append(pop, &nsgaIndividual{genes: genes, objs: o.Objs})
+	}
+	for len(pop) < popSize && used < rounds {
+		ind, err := evaluate(randGenes())
+		if err != nil {
+			return err
+		}
+		pop = append(pop, ind)
+	}
+	pop = nsgaSelect(pop, popSize)
+	tournament := func() *nsgaIndividual {
+		a, b := pop[rng.Intn(len(pop))], pop[rng.Intn(len(pop))]
+		if a.rank < b.rank || a.rank == b.rank && a.crowd > b.crowd {
+			return a
+		}
+		return b
+	}
+	for used < rounds {
+		children := make([]*nsgaIndividual, 0, popSize)
+		for len(children) < popSize && used < rounds {
+			genes := sbxCrossover(rng, tournament().genes, tournament().genes, lows, highs)
+			polyMutate(rng, genes, lows, highs)
+			ind, err := evaluate(genes)
+			if err != nil {
+				return err
+			}
+			children = append(children, ind)
+		}
+		pop = nsgaSelect(append(pop, children...), popSize)
+	}
+	return nil
+}
+
+/*
+nsgaSelect
+Keep size individuals by non-dominated rank, then by crowding distance, and update their rank and crowd
+按非支配等级保留size个个体，同级按拥挤距离，并更新其rank和crowd
+*/
+func nsgaSelect(items []*nsgaIndividual, size int) []*nsgaIndividual {
+	points := make([][]float64, len(items))
+	for i, it := range items {
+		points[i] = it.objs
+	}
+	res := make([]*nsgaIndividual, 0, size)
+	for rank, front := range paretoFronts(points) {
+		dists := crowdingDists(points, front)
+		for _, i := range front {
+			items[i].rank = rank
+			items[i].crowd = dists[i]
+		}
+		if len(res)+len(front) > size {
+			sort.Slice(front, func(a, b int) bool {
+				return dists[front[a]] > dists[front[b]]
+			})
+			front = front[:size-len(res)]
+		}
+		for _, i := range front {
+			res = append(res, items[i])
+		}
+		if len(res) >= size {
+			break
+		}
+	}
+	return res
+}
+
+func sbxCrossover(rng *rand.Rand, a, b, lows, highs []float64) []float64 {
+	const eta = 15.0
+	res := make([]float64, len(a))
+	for i := range a {
+		if rng.Float64() > 0.9 || math.Abs(a[i]-b[i]) < 1e-12 {
+			res[i] = a[i]
+			continue
+		}
+		u := rng.Float64()
+		var beta float64
+		if u <= 0.5 {
+			beta = math.Pow(2*u, 1/(eta+1))
+		} else {
+			beta = math.Pow(1/(2*(1-u)), 1/(eta+1))
+		}
+		val := 0.5 * ((1+beta)*a[i] + (1-beta)*b[i])
+		if rng.Float64() < 0.5 {
+			val = 0.5 * ((1-beta)*a[i] + (1+beta)*b[i])
+		}
+		res[i] = min(highs[i], max(lows[i], val))
+	}
+	return res
+}
+
+func polyMutate(rng *rand.Rand, genes, lows, highs []float64) {
+	const eta = 20.0
+	prob := 1 / float64(len(genes))
+	for i := range genes {
+		span := highs[i] - lows[i]
+		if span <= 0 || rng.Float64() > prob {
+			continue
+		}
+		u := rng.Float64()
+		var delta float64
+		if u < 0.5 {
+			delta = math.Pow(2*u, 1/(eta+1)) - 1
+		} else {
+			delta = 1 - math.Pow(2*(1-u), 1/(eta+1))
+		}
+		genes[i] = min(highs[i], max(lows[i], genes[i]+delta*span))
+	}
+}
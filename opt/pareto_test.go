@@ -0,0 +1,49 @@
+package opt
+
+import (
+	"testing"
+
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banexg/errs"
+)
+
+func TestParetoFronts(t *testing.T) {
+	points := [][]float64{{1, 5}, {2, 4}, {1, 4}, {3, 1}, {0, 0}}
+	fronts := paretoFronts(points)
+	if len(fronts) != 3 || len(fronts[0]) != 3 {
+		t.Fatalf("bad fronts: %v", fronts)
+	}
+	items := make([]*OptInfo, len(points))
+	for i, p := range points {
+		items[i] = &OptInfo{Score: p[0], Objs: p}
+	}
+	front := ParetoFront(items)
+	if len(front) != 3 || front[0].Score != 3 {
+		t.Errorf("bad pareto front: %v", front)
+	}
+}
+
+func TestRunNSGA2(t *testing.T) {
+	params := []*core.Param{
+		{Name: "x", Min: 0, Max: 2, VType: core.VTypeUniform},
+	}
+	var evals int
+	var objs [][]float64
+	err := runNSGA2(60, params, 1, nil, func(data map[string]float64) ([]float64, *errs.Error) {
+		evals += 1
+		x := data["x"]
+		// maximize -x^2 and -(x-2)^2, the pareto set is x in [0, 2]
+		res := []float64{-x * x, -(x - 2) * (x - 2)}
+		objs = append(objs, res)
+		return res, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evals != 60 {
+		t.Errorf("expect 60 evals, got %v", evals)
+	}
+	if len(paretoFronts(objs)[0]) < 10 {
+		t.Errorf("pareto front too small")
+	}
+}
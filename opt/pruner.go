@@ -0,0 +1,168 @@
+package opt
+
+import (
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banexg/errs"
+)
+
+/*
+TrialPruner
+Stop unpromising hyper optimization trials early by intermediate values reported during backtest.
+step is the progress percent of the backtest time range (1-100), value is larger the better.
+根据回测中报告的中间值，提前停止没有希望的超参数优化轮次。
+step是回测时间范围的进度百分比(1-100)，value越大越好。
+*/
+type TrialPruner interface {
+	// Report record the value of current trial at step, returns true if the trial should be pruned 记录当前轮次在step的值，应剪枝时返回true
+	Report(step int, value float64) bool
+	// Finish current trial is finished or pruned, start the next 当前轮次已完成或被剪枝，开始下一轮
+	Finish()
+}
+
+type FnNewPruner func() TrialPruner
+
+var Pruners = map[string]FnNewPruner{
+	"median": func() TrialPruner {
+		return &MedianPruner{StartupTrials: 5, WarmupSteps: 10}
+	},
+	"halving": func() TrialPruner {
+		return &HalvingPruner{MinStep: 10, Reduction: 3, MinTrials: 3}
+	},
+}
+
+/*
+NewTrialPruner
+Returns nil if name is empty
+name为空时返回nil
+*/
+func NewTrialPruner(name string) (TrialPruner, *errs.Error) {
+	if name == "" {
+		return nil, nil
+	}
+	fn, ok := Pruners[name]
+	if !ok {
+		return nil, errs.NewMsg(core.ErrBadConfig, "unknown pruner: %s", name)
+	}
+	return fn(), nil
+}
+
+type stepVal struct {
+	step int
+	val  float64
+}
+
+// trialCurves intermediate values of finished trials and the current trial 已完成轮次和当前轮次的中间值
+type trialCurves struct {
+	hist [][]stepVal
+	cur  []stepVal
+}
+
+func (c *trialCurves) add(step int, value float64) {
+	if math.IsNaN(value) {
+		value = math.Inf(-1)
+	}
+	c.cur = append(c.cur, stepVal{step, value})
+}
+
+func (c *trialCurves) Finish() {
+	if len(c.cur) > 0 {
+		c.hist = append(c.hist, c.cur)
+	}
+	c.cur = nil
+}
+
+/*
+valuesAt
+The latest value not later than step of each finished trial which has reached step
+每个已到达step的已完成轮次在不晚于step的最新值
+*/
+func (c *trialCurves) valuesAt(step int) []float64 {
+	res := make([]float64, 0, len(c.hist))
+	for _, curve := range c.hist {
+		if curve[len(curve)-1].step < step {
+			// pruned before step 在step之前被剪枝
+			continue
+		}
+		idx := sort.Search(len(curve), func(i int) bool {
+			return curve[i].step > step
+		})
+		if idx > 0 {
+			res = append(res, curve[idx-1].val)
+		}
+	}
+	return res
+}
+
+/*
+MedianPruner
+Prune if the value is worse than the median of previous trials at the same step
+值比之前轮次在同一进度的中位数差时剪枝
+*/
+type MedianPruner struct {
+	trialCurves
+	StartupTrials int // no pruning until these trials are finished 完成这些轮次之前不剪枝
+	WarmupSteps   int // no pruning before this step 此进度之前不剪枝
+}
+
+func (p *MedianPruner) Report(step int, value float64) bool {
+	p.add(step, value)
+	if len(p.hist) < p.StartupTrials || step < p.WarmupSteps {
+		return false
+	}
+	vals := p.valuesAt(step)
+	if len(vals) < p.StartupTrials {
+		return false
+	}
+	slices.Sort(vals)
+	mid := len(vals) / 2
+	median := vals[mid]
+	if len(vals)%2 == 0 {
+		median = (vals[mid-1] + vals[mid]) / 2
+	}
+	return value < median
+}
+
+/*
+HalvingPruner
+Successive halving: rungs are at MinStep * Reduction^k, at each rung only the top 1/Reduction of trials
+reaching the rung continue.
+逐次减半：在MinStep * Reduction^k处设置梯级，每个梯级只有到达的前1/Reduction轮次继续。
+*/
+type HalvingPruner struct {
+	trialCurves
+	MinStep   int
+	Reduction int
+	MinTrials int // no pruning until these trials reached the rung 到达梯级的轮次少于此数时不剪枝
+	nextRung  int
+}
+
+func (p *HalvingPruner) Report(step int, value float64) bool {
+	p.add(step, value)
+	if p.nextRung == 0 {
+		p.nextRung = p.MinStep
+	}
+	if step < p.nextRung {
+		return false
+	}
+	rung := p.nextRung
+	for p.nextRung <= step {
+		p.nextRung *= p.Reduction
+	}
+	vals := p.valuesAt(rung)
+	if len(vals) < p.MinTrials {
+		return false
+	}
+	// keep if value is in the top 1/Reduction 值在前1/Reduction时保留
+	sort.Sort(sort.Reverse(sort.Float64Slice(vals)))
+	keepNum := max(1, len(vals)/p.Reduction)
+	return value < vals[keepNum-1]
+}
+
+func (p *HalvingPruner) Finish() {
+	p.trialCurves.Finish()
+	p.nextRung = 0
+}
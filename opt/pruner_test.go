@@ -0,0 +1,50 @@
+package opt
+
+import "testing"
+
+// runCurve report a linear curve value=slope*step until pruned, returns the pruned step or 0
+func runCurve(p TrialPruner, slope float64) int {
+	defer p.Finish()
+	for step := 1; step < 100; step++ {
+		if p.Report(step, slope*float64(step)) {
+			return step
+		}
+	}
+	return 0
+}
+
+func TestMedianPruner(t *testing.T) {
+	p, err := NewTrialPruner("median")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, slope := range []float64{1, 2, 3, 4, 5} {
+		if step := runCurve(p, slope); step != 0 {
+			t.Fatalf("startup trial pruned at %d", step)
+		}
+	}
+	if step := runCurve(p, 0.5); step != 10 {
+		t.Errorf("bad trial should be pruned at warmup step, got %d", step)
+	}
+	if step := runCurve(p, 4); step != 0 {
+		t.Errorf("good trial should not be pruned, got %d", step)
+	}
+}
+
+func TestHalvingPruner(t *testing.T) {
+	p := &HalvingPruner{MinStep: 10, Reduction: 3, MinTrials: 3}
+	for _, slope := range []float64{1, 2, 3} {
+		if step := runCurve(p, slope); step != 0 {
+			t.Fatalf("startup trial pruned at %d", step)
+		}
+	}
+	if step := runCurve(p, 2.5); step != 10 {
+		t.Errorf("trial not in top 1/3 should be pruned at rung 10, got %d", step)
+	}
+	if step := runCurve(p, 3.5); step != 0 {
+		t.Errorf("best trial should not be pruned, got %d", step)
+	}
+	if _, err := NewTrialPruner("unknown"); err == nil {
+		t.Error("unknown pruner should fail")
+	}
+}
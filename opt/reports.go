@@ -30,42 +30,55 @@ import (
 )
 
 type BTResult struct {
-	MaxOpenOrders   int        `json:"maxOpenOrders"`
-	MinReal         float64    `json:"minReal"`
-	MaxReal         float64    `json:"maxReal"`         // Maximum Assets 最大资产
-	MaxDrawDownPct  float64    `json:"maxDrawDownPct"`  // Maximum drawdown percentage 最大回撤百分比
-	ShowDrawDownPct float64    `json:"showDrawDownPct"` // Displays the maximum drawdown percentage 显示最大回撤百分比
-	MaxDrawDownVal  float64    `json:"maxDrawDownVal"`  // Maximum drawdown percentage 最大回撤金额
-	ShowDrawDownVal float64    `json:"showDrawDownVal"` // Displays the maximum drawdown percentage 显示最大回撤金额
-	MaxFundOccup    float64    `json:"maxFundOccup"`
-	MaxOccupForPair float64    `json:"maxOccupForPair"`
-	BarNum          int        `json:"barNum"`
-	TimeNum         int        `json:"timeNum"`
-	OrderNum        int        `json:"orderNum"`
-	lastTime        int64      // 上次bar的时间戳
-	histOdOff       int        // 计算已完成订单利润的偏移
-	donePftLegal    float64    // 已完成订单利润
-	Plots           *PlotData  `json:"plots"`
-	CreateMS        int64      `json:"createMS"`
-	StartMS         int64      `json:"startMS"`
-	EndMS           int64      `json:"endMS"`
-	PlotEvery       int        `json:"plotEvery"`
-	TotalInvest     float64    `json:"totalInvest"`
-	OutDir          string     `json:"outDir"`
-	PairGrps        []*RowItem `json:"pairGrps"`
-	DateGrps        []*RowItem `json:"dateGrps"`
-	EnterGrps       []*RowItem `json:"enterGrps"`
-	ExitGrps        []*RowItem `json:"exitGrps"`
-	ProfitGrps      []*RowItem `json:"profitGrps"`
-	TotProfit       float64    `json:"totProfit"`
-	TotCost         float64    `json:"totCost"`
-	TotFee          float64    `json:"totFee"`
-	TotProfitPct    float64    `json:"totProfitPct"`
-	WinRatePct      float64    `json:"winRatePct"`
-	FinBalance      float64    `json:"finBalance"`
-	FinWithdraw     float64    `json:"finWithdraw"`
-	SharpeRatio     float64    `json:"sharpeRatio"`
-	SortinoRatio    float64    `json:"sortinoRatio"`
+	MaxOpenOrders   int             `json:"maxOpenOrders"`
+	MinReal         float64         `json:"minReal"`
+	MaxReal         float64         `json:"maxReal"`         // Maximum Assets 最大资产
+	MaxDrawDownPct  float64         `json:"maxDrawDownPct"`  // Maximum drawdown percentage 最大回撤百分比
+	ShowDrawDownPct float64         `json:"showDrawDownPct"` // Displays the maximum drawdown percentage 显示最大回撤百分比
+	MaxDrawDownVal  float64         `json:"maxDrawDownVal"`  // Maximum drawdown percentage 最大回撤金额
+	ShowDrawDownVal float64         `json:"showDrawDownVal"` // Displays the maximum drawdown percentage 显示最大回撤金额
+	MaxFundOccup    float64         `json:"maxFundOccup"`
+	MaxOccupForPair float64         `json:"maxOccupForPair"`
+	BarNum          int             `json:"barNum"`
+	TimeNum         int             `json:"timeNum"`
+	OrderNum        int             `json:"orderNum"`
+	lastTime        int64           // 上次bar的时间戳
+	histOdOff       int             // 计算已完成订单利润的偏移
+	donePftLegal    float64         // 已完成订单利润
+	Plots           *PlotData       `json:"plots"`
+	CreateMS        int64           `json:"createMS"`
+	StartMS         int64           `json:"startMS"`
+	EndMS           int64           `json:"endMS"`
+	PlotEvery       int             `json:"plotEvery"`
+	TotalInvest     float64         `json:"totalInvest"`
+	OutDir          string          `json:"outDir"`
+	PairGrps        []*RowItem      `json:"pairGrps"`
+	DateGrps        []*RowItem      `json:"dateGrps"`
+	EnterGrps       []*RowItem      `json:"enterGrps"`
+	ExitGrps        []*RowItem      `json:"exitGrps"`
+	ProfitGrps      []*RowItem      `json:"profitGrps"`
+	TotProfit       float64         `json:"totProfit"`
+	TotCost         float64         `json:"totCost"`
+	TotFee          float64         `json:"totFee"`
+	TotFundFee      float64         `json:"totFundFee"` // net funding fee of contracts, positive means paid 合约净资金费，正数表示支付
+	SlipModel       string          `json:"slipModel"`  // slippage model of market fills 市价成交的滑点模型
+	TotSlipCost     float64         `json:"totSlipCost"`
+	TotProfitPct    float64         `json:"totProfitPct"`
+	WinRatePct      float64         `json:"winRatePct"`
+	FinBalance      float64         `json:"finBalance"`
+	FinWithdraw     float64         `json:"finWithdraw"`
+	SharpeRatio     float64         `json:"sharpeRatio"`
+	SortinoRatio    float64         `json:"sortinoRatio"`
+	FatalStops      []*FatalStopHit `json:"fatalStops"`            // triggered fatal_stop 触发的fatal_stop
+	WalkForward     *WalkForward    `json:"walkForward,omitempty"` // windows of RunBTOverOpt 滚动调参回测的窗口
+}
+
+// FatalStopHit a fatal_stop triggered in backtest 回测中触发的一次fatal_stop
+type FatalStopHit struct {
+	TimeMS  int64   `json:"timeMS"`
+	Minutes int     `json:"minutes"` // loss window in minutes 亏损窗口分钟数
+	LossPct float64 `json:"lossPct"`
+	UntilMS int64   `json:"untilMS"` // entering is forbidden until this time 禁止开单截止时间
 }
 
 type PlotData struct {
@@ -77,6 +90,7 @@ type PlotData struct {
 	Profit        []float64 `json:"profit"`
 	UnrealizedPOL []float64 `json:"unrealizedPOL"`
 	WithDraw      []float64 `json:"withDraw"`
+	NoEnter       []int     `json:"noEnter"` // 1 when entering is forbidden by fatal_stop or risk 被fatal_stop或风控禁止开单时为1
 	tmpOdNum      int
 }
 
@@ -110,13 +124,13 @@ func NewBTResult() *BTResult {
 	return res
 }
 
-func (r *BTResult) printBtResult() {
+func (r *BTResult) printBtResult(ctx *biz.Ctx) {
 	if config.StratPerf != nil && config.StratPerf.Enable {
-		core.DumpPerfs(r.OutDir)
+		ctx.DumpPerfs(r.OutDir)
 	}
 
-	r.Collect()
-	orders := ormo.HistODs
+	r.Collect(ctx)
+	orders := ctx.HistODs
 	var b strings.Builder
 	var tblText string
 	if len(orders) > 0 {
@@ -148,37 +162,39 @@ func (r *BTResult) printBtResult() {
 	log.Info("BackTest Reports:\n" + b.String())
 	log.Info("BackTest Saved", zap.String("at", r.OutDir))
 
-	r.dumpBtFiles()
+	r.dumpBtFiles(ctx)
 }
 
-func (r *BTResult) dumpBtFiles() {
+func (r *BTResult) dumpBtFiles(ctx *biz.Ctx) {
 	csvPath := fmt.Sprintf("%s/orders.csv", r.OutDir)
-	err_ := DumpOrdersCSV(ormo.HistODs, csvPath)
+	err_ := DumpOrdersCSV(ctx.HistODs, csvPath)
 	if err_ != nil {
 		log.Error("dump orders.csv fail", zap.Error(err_))
 	}
 
-	err := ormo.DumpOrdersGob(filepath.Join(r.OutDir, "orders.gob"))
+	err := ormo.DumpOrdersGob(filepath.Join(r.OutDir, "orders.gob"), ctx.HistODs)
 	if err != nil {
 		log.Warn("dump orders.gob fail", zap.Error(err))
 	}
 
 	r.dumpConfig()
 
-	r.dumpStrategy()
+	r.dumpStrategy(ctx.BotCtx)
 
-	r.dumpStratOutputs()
+	r.dumpStratOutputs(ctx.Ctx)
 
-	r.dumpGraph()
+	r.dumpGraph(ctx.HistODs)
 
 	r.dumpDetail("")
 }
 
-func (r *BTResult) Collect() {
-	orders := ormo.HistODs
+func (r *BTResult) Collect(ctx *biz.Ctx) {
+	orders := ctx.HistODs
 	r.OrderNum = len(orders)
 	sumProfit := float64(0)
 	sumFee := float64(0)
+	sumFundFee := float64(0)
+	sumSlipCost := float64(0)
 	sumCost := float64(0)
 	winCount := float64(0)
 	for _, od := range orders {
@@ -187,6 +203,8 @@ func (r *BTResult) Collect() {
 		if od.Exit != nil {
 			sumFee += od.Exit.Fee
 		}
+		sumFundFee += od.GetInfoFloat64(ormo.OdInfoFundFee)
+		sumSlipCost += od.GetInfoFloat64(ormo.OdInfoSlipCost)
 		sumCost += od.EnterCost() / od.Leverage
 		if od.Profit > 0 {
 			winCount += 1
@@ -195,6 +213,11 @@ func (r *BTResult) Collect() {
 	r.TotProfit = sumProfit
 	r.TotCost = utils.NanInfTo(sumCost, 0)
 	r.TotFee = sumFee
+	r.TotFundFee = sumFundFee
+	r.TotSlipCost = sumSlipCost
+	if config.BTSlippage != nil {
+		r.SlipModel = config.BTSlippage.Model
+	}
 	r.TotProfitPct = r.TotProfit * 100 / r.TotalInvest
 	if r.MinReal > r.MaxReal {
 		r.MinReal = r.MaxReal
@@ -206,13 +229,13 @@ func (r *BTResult) Collect() {
 	r.ShowDrawDownVal = ddVal
 	if len(orders) > 0 {
 		r.WinRatePct = winCount * 100 / float64(len(orders))
-		r.groupByPairs(orders)
+		r.groupByPairs(ctx.BotCtx, orders)
 		r.groupByDates(orders)
 		r.groupByProfits(orders)
-		r.groupByEnters(orders)
-		r.groupByExits(orders)
+		r.groupByEnters(ctx.BotCtx, orders)
+		r.groupByExits(ctx.BotCtx, orders)
 	}
-	wallets := biz.GetWallets(config.DefAcc)
+	wallets := ctx.GetWallets(config.DefAcc)
 	r.FinBalance = wallets.AvaLegal(nil)
 	r.FinWithdraw = wallets.GetWithdrawLegal(nil)
 	err := r.calcMeasures(30)
@@ -240,6 +263,16 @@ func (r *BTResult) textMetrics(orders []*ormo.InOutOrder) string {
 	totProfitPct := strconv.FormatFloat(r.TotProfitPct, 'f', 1, 64)
 	table.Append([]string{"Total Profit %", totProfitPct + "%"})
 	table.Append([]string{"Total Fee", strconv.FormatFloat(r.TotFee, 'f', 2, 64)})
+	if core.IsContract || r.TotFundFee != 0 {
+		table.Append([]string{"Total Funding", strconv.FormatFloat(r.TotFundFee, 'f', 2, 64)})
+	}
+	if len(r.FatalStops) > 0 {
+		table.Append([]string{"Fatal Stops", strconv.Itoa(len(r.FatalStops))})
+	}
+	if r.SlipModel != "" {
+		table.Append([]string{"Slippage Model", r.SlipModel})
+		table.Append([]string{"Total Slippage", strconv.FormatFloat(r.TotSlipCost, 'f', 2, 64)})
+	}
 	avfProfit := strconv.FormatFloat(r.TotProfitPct*100/float64(len(orders)), 'f', 2, 64)
 	table.Append([]string{"Avg Profit %%", avfProfit + "%%"})
 	table.Append([]string{"Total Cost", strconv.FormatFloat(r.TotCost, 'f', 2, 64)})
@@ -273,8 +306,8 @@ func (r *BTResult) textMetrics(orders []*ormo.InOutOrder) string {
 	return b.String()
 }
 
-func (r *BTResult) groupByPairs(orders []*ormo.InOutOrder) {
-	groups := groupItems(orders, true, func(od *ormo.InOutOrder, i int) string {
+func (r *BTResult) groupByPairs(c *core.BotCtx, orders []*ormo.InOutOrder) {
+	groups := groupItems(c, orders, true, func(od *ormo.InOutOrder, i int) string {
 		return od.Symbol
 	})
 	sort.Slice(groups, func(i, j int) bool {
@@ -287,8 +320,8 @@ func textGroupPairs(r *BTResult) string {
 	return printGroups(r.PairGrps, "Pair", true, nil, nil)
 }
 
-func (r *BTResult) groupByEnters(orders []*ormo.InOutOrder) {
-	groups := groupItems(orders, true, func(od *ormo.InOutOrder, i int) string {
+func (r *BTResult) groupByEnters(c *core.BotCtx, orders []*ormo.InOutOrder) {
+	groups := groupItems(c, orders, true, func(od *ormo.InOutOrder, i int) string {
 		return fmt.Sprintf("%s:%s", od.Strategy, od.EnterTag)
 	})
 	sort.Slice(groups, func(i, j int) bool {
@@ -301,8 +334,8 @@ func textGroupEntTags(r *BTResult) string {
 	return printGroups(r.EnterGrps, "Enter Tag", true, nil, nil)
 }
 
-func (r *BTResult) groupByExits(orders []*ormo.InOutOrder) {
-	groups := groupItems(orders, true, func(od *ormo.InOutOrder, i int) string {
+func (r *BTResult) groupByExits(c *core.BotCtx, orders []*ormo.InOutOrder) {
+	groups := groupItems(c, orders, true, func(od *ormo.InOutOrder, i int) string {
 		return fmt.Sprintf("%s:%s", od.Strategy, od.ExitTag)
 	})
 	sort.Slice(groups, func(i, j int) bool {
@@ -337,7 +370,7 @@ func (r *BTResult) groupByProfits(orders []*ormo.InOutOrder) {
 		maxPct := strconv.FormatFloat(slices.Max(gp.Items)*100, 'f', 2, 64)
 		grpTitles = append(grpTitles, fmt.Sprintf("%s ~ %s%%", minPct, maxPct))
 	}
-	groups := groupItems(orders, false, func(od *ormo.InOutOrder, i int) string {
+	groups := groupItems(nil, orders, false, func(od *ormo.InOutOrder, i int) string {
 		return grpTitles[res.RowGIds[i]]
 	})
 	sort.Slice(groups, func(i, j int) bool {
@@ -376,7 +409,7 @@ func (r *BTResult) groupByDates(orders []*ormo.InOutOrder) {
 		bestTFSecs = utils2.TFToSecs(bestTF)
 	}
 	tfUnit := bestTF[1]
-	groups := groupItems(orders, false, func(od *ormo.InOutOrder, i int) string {
+	groups := groupItems(nil, orders, false, func(od *ormo.InOutOrder, i int) string {
 		entMS := od.RealEnterMS()
 		if tfUnit == 'Y' {
 			return btime.ToDateStrLoc(entMS, "2006")
@@ -431,7 +464,12 @@ func makeEnterExits(orders []*ormo.InOutOrder) []string {
 	}
 }
 
-func groupItems(orders []*ormo.InOutOrder, measure bool, getTag func(od *ormo.InOutOrder, i int) string) []*RowItem {
+/*
+groupItems
+Group orders by getTag, c is used to calculate sharpe/sortino when measure is true
+按getTag对订单分组，measure为true时使用c计算sharpe/sortino
+*/
+func groupItems(c *core.BotCtx, orders []*ormo.InOutOrder, measure bool, getTag func(od *ormo.InOutOrder, i int) string) []*RowItem {
 	if len(orders) == 0 {
 		return nil
 	}
@@ -473,7 +511,7 @@ func groupItems(orders []*ormo.InOutOrder, measure bool, getTag func(od *ormo.In
 	if measure {
 		// 分30份采样计算指标，太大的话会导致指标偏小
 		for _, gp := range groups {
-			sharpe, sortino, err := measurePerformance(gp.Orders)
+			sharpe, sortino, err := measurePerformance(c, gp.Orders)
 			if err != nil {
 				log.Warn("calc measure fail", zap.Error(err))
 			} else {
@@ -527,17 +565,14 @@ func printGroups(groups []*RowItem, title string, measure bool, extHeads []strin
 	return b.String()
 }
 
-func measurePerformance(ods []*ormo.InOutOrder) (float64, float64, *errs.Error) {
+func measurePerformance(c *core.BotCtx, ods []*ormo.InOutOrder) (float64, float64, *errs.Error) {
 	if len(ods) == 0 {
 		return 0, 0, nil
 	}
 	sort.Slice(ods, func(i, j int) bool {
 		return ods[i].RealExitMS() < ods[j].RealExitMS()
 	})
-	initStake := float64(0)
-	for key, val := range config.WalletAmounts {
-		initStake += val * core.GetPriceSafe(key)
-	}
+	initStake := walletsLegal(c, config.WalletAmounts)
 	dayTfMsecs := int64(utils2.TFToSecs("1d") * 1000)
 	startMS := utils2.AlignTfMSecs(ods[0].RealEnterMS(), dayTfMsecs)
 	endMS := utils2.AlignTfMSecs(ods[len(ods)-1].RealEnterMS(), dayTfMsecs) + dayTfMsecs
@@ -645,7 +680,7 @@ func DumpOrdersCSV(orders []*ormo.InOutOrder, outPath string) error {
 	defer writer.Flush()
 	heads := []string{"sid", "symbol", "timeframe", "direction", "leverage", "entAt", "entTag", "entPrice",
 		"entAmount", "entCost", "entFee", "exitAt", "exitTag", "exitPrice", "exitAmount", "exitGot",
-		"exitFee", "maxPftRate", "maxDrawDown", "profitRate", "profit", "strategy"}
+		"exitFee", "fundFee", "maxPftRate", "maxDrawDown", "profitRate", "profit", "strategy"}
 	if err_ = writer.Write(heads); err_ != nil {
 		return err_
 	}
@@ -670,11 +705,12 @@ func DumpOrdersCSV(orders []*ormo.InOutOrder, outPath string) error {
 		if od.Exit != nil {
 			row[13], row[14], row[15], row[16] = calcExOrder(od.Exit)
 		}
-		row[17] = strconv.FormatFloat(od.MaxPftRate, 'f', 4, 64)
-		row[18] = strconv.FormatFloat(od.MaxDrawDown, 'f', 4, 64)
-		row[19] = strconv.FormatFloat(od.ProfitRate, 'f', 4, 64)
-		row[20] = strconv.FormatFloat(od.Profit, 'f', 8, 64)
-		row[21] = od.Strategy
+		row[17] = strconv.FormatFloat(od.GetInfoFloat64(ormo.OdInfoFundFee), 'f', 8, 64)
+		row[18] = strconv.FormatFloat(od.MaxPftRate, 'f', 4, 64)
+		row[19] = strconv.FormatFloat(od.MaxDrawDown, 'f', 4, 64)
+		row[20] = strconv.FormatFloat(od.ProfitRate, 'f', 4, 64)
+		row[21] = strconv.FormatFloat(od.Profit, 'f', 8, 64)
+		row[22] = od.Strategy
 		if err_ = writer.Write(row); err_ != nil {
 			return err_
 		}
@@ -709,13 +745,13 @@ func (r *BTResult) dumpConfig() {
 	}
 }
 
-func (r *BTResult) dumpStrategy() {
+func (r *BTResult) dumpStrategy(c *core.BotCtx) {
 	stratDir := config.GetStratDir()
 	if stratDir == "" {
 		log.Info("env `BanStratDir` not configured, skip backup strategy")
 		return
 	}
-	for name := range core.StgPairTfs {
+	for name := range c.StgPairTfs {
 		dname := strings.Split(name, ":")[0]
 		curDir, err_ := utils.FindSubPath(stratDir, dname, 3)
 		if err_ != nil {
@@ -730,9 +766,9 @@ func (r *BTResult) dumpStrategy() {
 	}
 }
 
-func (r *BTResult) dumpStratOutputs() {
+func (r *BTResult) dumpStratOutputs(c *strat.Ctx) {
 	groups := make(map[string][]string)
-	for _, items := range strat.PairStrats {
+	for _, items := range c.PairStrats {
 		for _, stgy := range items {
 			if len(stgy.Outputs) == 0 {
 				continue
@@ -761,7 +797,7 @@ func (r *BTResult) dumpStratOutputs() {
 	}
 }
 
-func (r *BTResult) dumpGraph() {
+func (r *BTResult) dumpGraph(orders []*ormo.InOutOrder) {
 	odNum := make([]float64, 0, len(r.Plots.OdNum))
 	for _, v := range r.Plots.OdNum {
 		odNum = append(odNum, float64(v))
@@ -770,6 +806,10 @@ func (r *BTResult) dumpGraph() {
 	for _, v := range r.Plots.JobNum {
 		jobNum = append(jobNum, float64(v))
 	}
+	noEnter := make([]float64, 0, len(r.Plots.NoEnter))
+	for _, v := range r.Plots.NoEnter {
+		noEnter = append(noEnter, float64(v))
+	}
 	outPath := fmt.Sprintf("%s/assets.html", r.OutDir)
 	title := "Real-time Assets/Balances/Unrealized P&L/Withdrawals/Concurrent Orders"
 	tplPath := fmt.Sprintf("%s/lines.html", config.GetDataDir())
@@ -782,6 +822,7 @@ func (r *BTResult) dumpGraph() {
 		{Label: "Withdraw", Data: r.Plots.WithDraw, Hidden: true},
 		{Label: "OrderNum", Data: odNum, YAxisID: "yRight", Hidden: true},
 		{Label: "JobNum", Data: jobNum, YAxisID: "yRight", Hidden: true},
+		{Label: "NoEnter", Data: noEnter, YAxisID: "yRight", Hidden: len(r.FatalStops) == 0},
 	})
 	if err != nil {
 		log.Error("save assets.html fail", zap.Error(err))
@@ -789,7 +830,7 @@ func (r *BTResult) dumpGraph() {
 	// Draw cumulative profit curves for each entry tag separately
 	// 为入场标签分别绘制累计利润曲线
 	outPath = fmt.Sprintf("%s/enters.html", r.OutDir)
-	err = DumpEnterTagCumProfits(outPath, ormo.HistODs, 600)
+	err = DumpEnterTagCumProfits(outPath, orders, 600)
 	if err != nil {
 		log.Error("DumpEnterTagCumProfits fail", zap.Error(err))
 	}
@@ -1026,13 +1067,13 @@ func CalcGroupCumProfits(odList []*ormo.InOutOrder, genKey func(o *ormo.InOutOrd
 	tfMSecs := int64(utils2.TFToSecs(tf) * 1000)
 	startMS := utils2.AlignTfMSecs(minTimeMS, tfMSecs)
 	endMS := utils2.AlignTfMSecs(maxTimeMS, tfMSecs) + tfMSecs
-	exsMap := orm.GetExSymbolMap(core.ExgName, core.Market)
 	var result []*ChartDs
 	maxXNum := 0
 	for key, pairMap := range groups {
 		var glbRets []float64
-		for pair, orders := range pairMap {
-			exs := exsMap[pair]
+		for _, orders := range pairMap {
+			// pairs may come from different markets, find by sid 品种可能来自不同市场，按sid查找
+			exs := orm.GetSymbolByID(int32(orders[0].Sid))
 			_, bars, err := orm.GetOHLCV(exs, tf, startMS, endMS, 0, false)
 			if err != nil {
 				return nil, nil, err
@@ -61,22 +61,20 @@ func RunSimBT(args *config.CmdArgs) *errs.Error {
 		log.Info("run section", zap.Int64("start", sec.StartMS), zap.Int64("end", sec.EndMS))
 
 		// 更新配置
-		config.TimeRange.StartMS = sec.StartMS
-		config.TimeRange.EndMS = sec.EndMS
 		config.Pairs = utils.KeysOfMap(sec.PairMap)
 
 		// 执行回测
-		core.BotRunning = true
-		biz.ResetVars()
-		bt := NewBackTest(true, "")
+		ctx := NewBTContext(nil)
+		ctx.SetTimeRange(&config.TimeTuple{StartMS: sec.StartMS, EndMS: sec.EndMS})
+		bt := NewBackTest(ctx, true, "")
 		bt.Run()
 
 		// 收集订单
-		allOrders = append(allOrders, ormo.HistODs...)
+		allOrders = append(allOrders, ctx.HistODs...)
 
 		// 输出进度
 		log.Info("finished", zap.Int("current", i+1), zap.Int("total", len(sections)),
-			zap.Int("orders", len(ormo.HistODs)))
+			zap.Int("orders", len(ctx.HistODs)))
 	}
 
 	// 保存订单
@@ -177,7 +175,7 @@ func saveOrders(orders []*ormo.InOutOrder, outPath string) *errs.Error {
 	}
 
 	// 保存为GOB
-	err2 := ormo.DumpOrdersGob(outPath + ".gob")
+	err2 := ormo.DumpOrdersGob(outPath+".gob", orders)
 	if err2 != nil {
 		return err2
 	}
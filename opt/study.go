@@ -0,0 +1,464 @@
+package opt
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/banbox/banbot/btime"
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/utils"
+	"github.com/banbox/banexg/errs"
+	"github.com/banbox/banexg/log"
+	"github.com/c-bata/goptuna"
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
+)
+
+/*
+Trials of hyper optimization are persisted into `studies.db` (sqlite) in the directory of the optimize log,
+so an interrupted run can be continued by `optimize -resume <study>`.
+超参数优化的每轮结果持久化到优化日志所在目录的`studies.db`(sqlite)中，
+中断的任务可通过`optimize -resume <study>`继续。
+*/
+
+const (
+	studyDbName = "studies.db"
+	ddlStudy    = `
+CREATE TABLE IF NOT EXISTS study
+(
+    name       TEXT PRIMARY KEY,
+    sampler    TEXT    NOT NULL,
+    seed       INTEGER NOT NULL,
+    objectives TEXT    NOT NULL,
+    create_at  INTEGER NOT NULL,
+    update_at  INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS trial
+(
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    study     TEXT    NOT NULL,
+    pol       TEXT    NOT NULL, -- RunPolicyConfig.Key() of the tuned policy and other fixed policies
+    job_id    TEXT    NOT NULL,
+    params    TEXT    NOT NULL,
+    ints      TEXT    NOT NULL,
+    score     REAL    NOT NULL,
+    pruned    INTEGER NOT NULL, -- progress percent when pruned, 0 for finished
+    result    TEXT    NOT NULL, -- json of trialBrief
+    create_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_trial_study_pol ON trial (study, pol);
+`
+)
+
+var (
+	curStudy *studyRun // study of the running optimize 当前运行的超参数优化研究
+)
+
+type studyRun struct {
+	store *StudyStore
+	name  string
+	seed  int64
+}
+
+/*
+trialBrief
+Summary of BTResult saved for each trial, NaN/Inf are replaced since json can't encode them
+每轮保存的BTResult摘要，json不支持NaN/Inf，已替换
+*/
+type trialBrief struct {
+	OrderNum        int     `json:"orderNum"`
+	MaxOpenOrders   int     `json:"maxOpenOrders"`
+	TotProfit       float64 `json:"totProfit"`
+	TotProfitPct    float64 `json:"totProfitPct"`
+	TotFee          float64 `json:"totFee"`
+	WinRatePct      float64 `json:"winRatePct"`
+	MaxDrawDownPct  float64 `json:"maxDrawDownPct"`
+	ShowDrawDownPct float64 `json:"showDrawDownPct"`
+	FinBalance      float64 `json:"finBalance"`
+	SharpeRatio     float64 `json:"sharpeRatio"`
+	SortinoRatio    float64 `json:"sortinoRatio"`
+}
+
+func finiteNum(v float64) float64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	return max(-math.MaxFloat64, min(math.MaxFloat64, v))
+}
+
+func newTrialBrief(r *BTResult) *trialBrief {
+	return &trialBrief{
+		OrderNum:        r.OrderNum,
+		MaxOpenOrders:   r.MaxOpenOrders,
+		TotProfit:       finiteNum(r.TotProfit),
+		TotProfitPct:    finiteNum(r.TotProfitPct),
+		TotFee:          finiteNum(r.TotFee),
+		WinRatePct:      finiteNum(r.WinRatePct),
+		MaxDrawDownPct:  finiteNum(r.MaxDrawDownPct),
+		ShowDrawDownPct: finiteNum(r.ShowDrawDownPct),
+		FinBalance:      finiteNum(r.FinBalance),
+		SharpeRatio:     finiteNum(r.SharpeRatio),
+		SortinoRatio:    finiteNum(r.SortinoRatio),
+	}
+}
+
+func (b *trialBrief) toResult() *BTResult {
+	return &BTResult{
+		OrderNum:        b.OrderNum,
+		MaxOpenOrders:   b.MaxOpenOrders,
+		TotProfit:       b.TotProfit,
+		TotProfitPct:    b.TotProfitPct,
+		TotFee:          b.TotFee,
+		WinRatePct:      b.WinRatePct,
+		MaxDrawDownPct:  b.MaxDrawDownPct,
+		ShowDrawDownPct: b.ShowDrawDownPct,
+		FinBalance:      b.FinBalance,
+		SharpeRatio:     b.SharpeRatio,
+		SortinoRatio:    b.SortinoRatio,
+	}
+}
+
+type OptStudy struct {
+	Name       string
+	Sampler    string
+	Seed       int64
+	Objectives string
+	CreateAt   int64
+	UpdateAt   int64
+}
+
+/*
+StudyStore
+Local sqlite store of optimize studies and their trials
+本地sqlite存储的超参数优化研究及其每轮结果
+*/
+type StudyStore struct {
+	Path string
+	db   *sql.DB
+}
+
+// GetStudyDbPath the study store path for optimize log file 优化日志对应的研究存储路径
+func GetStudyDbPath(outPath string) string {
+	return filepath.Join(filepath.Dir(outPath), studyDbName)
+}
+
+// DefStudyName default study name from the optimize log path 从优化日志路径生成默认研究名
+func DefStudyName(outPath string) string {
+	name := filepath.Base(outPath)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+func OpenStudyStore(path string) (*StudyStore, *errs.Error) {
+	// multiple optimize processes may write the same file 多个优化进程可能同时写入同一文件
+	connStr := fmt.Sprintf("file:%s?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)", path)
+	db, err_ := sql.Open("sqlite", connStr)
+	if err_ != nil {
+		return nil, errs.New(core.ErrDbConnFail, err_)
+	}
+	if _, err_ = db.Exec(ddlStudy); err_ != nil {
+		_ = db.Close()
+		return nil, errs.New(core.ErrDbExecFail, err_)
+	}
+	return &StudyStore{Path: path, db: db}, nil
+}
+
+/*
+openOptStudy
+Open the study store for optimize; create the study (or clear it) for new runs,
+load the existing one for `-resume`, and resolve the sampler seed.
+为超参数优化打开研究存储；新任务时创建(或清空)研究，`-resume`时加载已有研究，并确定采样器种子。
+*/
+func openOptStudy(args *config.CmdArgs) (*studyRun, *errs.Error) {
+	store, err := OpenStudyStore(GetStudyDbPath(args.OutPath))
+	if err != nil {
+		return nil, err
+	}
+	res := &studyRun{store: store, name: args.Resume, seed: args.Seed}
+	if res.name != "" {
+		st, err := store.GetStudy(res.name)
+		if err != nil || st == nil {
+			store.Close()
+			if err == nil {
+				err = errs.NewMsg(errs.CodeParamInvalid, "study not found: %s in %s", res.name, store.Path)
+			}
+			return nil, err
+		}
+		if res.seed < 0 {
+			res.seed = st.Seed
+		}
+		if st.Sampler != args.Sampler {
+			log.Warn("sampler differs from study", zap.String("study", st.Sampler),
+				zap.String("cur", args.Sampler))
+		}
+		return res, nil
+	}
+	res.name = DefStudyName(args.OutPath)
+	res.seed = max(0, res.seed)
+	err = store.ResetStudy(&OptStudy{
+		Name:       res.name,
+		Sampler:    args.Sampler,
+		Seed:       res.seed,
+		Objectives: args.Objectives,
+	})
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *StudyStore) Close() {
+	_ = s.db.Close()
+}
+
+/*
+GetStudy
+returns nil if the study not exists
+研究不存在时返回nil
+*/
+func (s *StudyStore) GetStudy(name string) (*OptStudy, *errs.Error) {
+	row := s.db.QueryRow(`select name, sampler, seed, objectives, create_at, update_at from study where name=?`, name)
+	var res OptStudy
+	err_ := row.Scan(&res.Name, &res.Sampler, &res.Seed, &res.Objectives, &res.CreateAt, &res.UpdateAt)
+	if err_ == sql.ErrNoRows {
+		return nil, nil
+	} else if err_ != nil {
+		return nil, errs.New(core.ErrDbReadFail, err_)
+	}
+	return &res, nil
+}
+
+/*
+ResetStudy
+Create the study, or clear all trials of it if already exists
+创建研究，已存在时清空其所有结果
+*/
+func (s *StudyStore) ResetStudy(st *OptStudy) *errs.Error {
+	curMS := btime.UTCStamp()
+	st.CreateAt, st.UpdateAt = curMS, curMS
+	_, err_ := s.db.Exec(`delete from trial where study=?`, st.Name)
+	if err_ != nil {
+		return errs.New(core.ErrDbExecFail, err_)
+	}
+	_, err_ = s.db.Exec(`insert or replace into study (name, sampler, seed, objectives, create_at, update_at)
+values (?, ?, ?, ?, ?, ?)`, st.Name, st.Sampler, st.Seed, st.Objectives, st.CreateAt, st.UpdateAt)
+	if err_ != nil {
+		return errs.New(core.ErrDbExecFail, err_)
+	}
+	return nil
+}
+
+// TrialNum count trials of study 统计研究的轮次数量
+func (s *StudyStore) TrialNum(study string) (int, *errs.Error) {
+	var num int
+	err_ := s.db.QueryRow(`select count(*) from trial where study=?`, study).Scan(&num)
+	if err_ != nil {
+		return 0, errs.New(core.ErrDbReadFail, err_)
+	}
+	return num, nil
+}
+
+/*
+countStudyTrials
+Count trials of the default study of the optimize log, 0 if the study store not exists
+统计优化日志的默认研究的轮次数量，研究存储不存在时为0
+*/
+func countStudyTrials(outPath string) (int, *errs.Error) {
+	dbPath := GetStudyDbPath(outPath)
+	if !utils.Exists(dbPath) {
+		return 0, nil
+	}
+	store, err := OpenStudyStore(dbPath)
+	if err != nil {
+		return 0, err
+	}
+	defer store.Close()
+	return store.TrialNum(DefStudyName(outPath))
+}
+
+// AddTrial save a finished trial of pol 保存pol的一轮已完成的结果
+func (s *StudyStore) AddTrial(study, pol string, o *OptInfo) *errs.Error {
+	params, _ := json.Marshal(o.Params)
+	ints, _ := json.Marshal(o.Ints)
+	var result []byte
+	if o.BTResult != nil {
+		result, _ = json.Marshal(newTrialBrief(o.BTResult))
+	}
+	curMS := btime.UTCStamp()
+	_, err_ := s.db.Exec(`insert into trial (study, pol, job_id, params, ints, score, pruned, result, create_at)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?)`, study, pol, o.ID, string(params), string(ints), finiteNum(o.Score),
+		o.Pruned, string(result), curMS)
+	if err_ != nil {
+		return errs.New(core.ErrDbExecFail, err_)
+	}
+	_, err_ = s.db.Exec(`update study set update_at=? where name=?`, curMS, study)
+	if err_ != nil {
+		return errs.New(core.ErrDbExecFail, err_)
+	}
+	return nil
+}
+
+/*
+GetTrials
+List finished trials of pol in insert order, BTResult only contains the summary fields
+按插入顺序列出pol已完成的结果，BTResult只包含摘要字段
+*/
+func (s *StudyStore) GetTrials(study, pol string) ([]*OptInfo, *errs.Error) {
+	rows, err_ := s.db.Query(`select job_id, params, ints, score, pruned, result from trial
+where study=? and pol=? order by id`, study, pol)
+	if err_ != nil {
+		return nil, errs.New(core.ErrDbReadFail, err_)
+	}
+	defer rows.Close()
+	var res []*OptInfo
+	for rows.Next() {
+		var params, ints, result string
+		o := &OptInfo{}
+		err_ = rows.Scan(&o.ID, &params, &ints, &o.Score, &o.Pruned, &result)
+		if err_ != nil {
+			return nil, errs.New(core.ErrDbReadFail, err_)
+		}
+		_ = json.Unmarshal([]byte(params), &o.Params)
+		_ = json.Unmarshal([]byte(ints), &o.Ints)
+		if result != "" {
+			var brief trialBrief
+			if json.Unmarshal([]byte(result), &brief) == nil {
+				o.BTResult = brief.toResult()
+			}
+		}
+		res = append(res, o)
+	}
+	if err_ = rows.Err(); err_ != nil {
+		return nil, errs.New(core.ErrDbReadFail, err_)
+	}
+	return res, nil
+}
+
+/*
+TrialItem
+A trial of study for display, Pol is the key of tuned policy followed by other fixed policies
+用于展示的一轮结果，Pol为调优策略的key，后跟其他固定策略
+*/
+type TrialItem struct {
+	Pol      string             `json:"pol"`
+	ID       string             `json:"id"`
+	Score    float64            `json:"score"`
+	Pruned   int                `json:"pruned"`
+	Params   map[string]float64 `json:"params"`
+	Result   *trialBrief        `json:"result"`
+	CreateAt int64              `json:"createAt"`
+}
+
+func newTrialItem(pol string, o *OptInfo) *TrialItem {
+	res := &TrialItem{Pol: pol, ID: o.ID, Score: finiteNum(o.Score), Pruned: o.Pruned, Params: o.Params,
+		CreateAt: btime.UTCStamp()}
+	if o.BTResult != nil {
+		res.Result = newTrialBrief(o.BTResult)
+	}
+	return res
+}
+
+/*
+ToPolicy
+Build the tuned run_policy with params of this trial
+用此轮结果的参数构建调优的run_policy
+*/
+func (t *TrialItem) ToPolicy() *config.RunPolicyConfig {
+	key, _, _ := strings.Cut(t.Pol, ",")
+	name, dirt, tfStr, pairStr := parseSectionTitle(key)
+	o := &OptInfo{Score: t.Score, Params: t.Params}
+	return o.ToPol(name, dirt, tfStr, pairStr)
+}
+
+// ListTrials list all trials of study in insert order 按插入顺序列出研究的所有结果
+func (s *StudyStore) ListTrials(study string) ([]*TrialItem, *errs.Error) {
+	rows, err_ := s.db.Query(`select pol, job_id, params, score, pruned, result, create_at from trial
+where study=? order by id`, study)
+	if err_ != nil {
+		return nil, errs.New(core.ErrDbReadFail, err_)
+	}
+	defer rows.Close()
+	var res []*TrialItem
+	for rows.Next() {
+		var params, result string
+		it := &TrialItem{}
+		err_ = rows.Scan(&it.Pol, &it.ID, &params, &it.Score, &it.Pruned, &result, &it.CreateAt)
+		if err_ != nil {
+			return nil, errs.New(core.ErrDbReadFail, err_)
+		}
+		_ = json.Unmarshal([]byte(params), &it.Params)
+		if result != "" {
+			var brief trialBrief
+			if json.Unmarshal([]byte(result), &brief) == nil {
+				it.Result = &brief
+			}
+		}
+		res = append(res, it)
+	}
+	if err_ = rows.Err(); err_ != nil {
+		return nil, errs.New(core.ErrDbReadFail, err_)
+	}
+	return res, nil
+}
+
+/*
+toOptSpace
+Inverse of Param.ToRegular, find the value in OptSpace by bisection since ToRegular is monotonic
+Param.ToRegular的逆运算，ToRegular单调递增，故用二分法在OptSpace中查找
+*/
+func toOptSpace(p *core.Param, val float64) float64 {
+	lo, hi := p.OptSpace()
+	if p.VType != core.VTypeNorm {
+		return min(hi, max(lo, val))
+	}
+	for i := 0; i < 60; i++ {
+		mid := (lo + hi) / 2
+		if reg, _ := p.ToRegular(mid); reg < val {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+/*
+addGOptunaTrials
+Add finished trials into goptuna study, so samplers continue from the history
+将已完成的结果添加到goptuna研究中，使采样器基于历史继续
+*/
+func addGOptunaTrials(study *goptuna.Study, params []*core.Param, olds []*OptInfo) error {
+	for _, o := range olds {
+		if o.Pruned > 0 {
+			continue
+		}
+		trialID, err := study.Storage.CreateNewTrial(study.ID)
+		if err != nil {
+			return err
+		}
+		for _, p := range params {
+			val, ok := o.Params[p.Name]
+			if !ok {
+				continue
+			}
+			low, high := p.OptSpace()
+			dist := goptuna.UniformDistribution{Low: low, High: high}
+			err = study.Storage.SetTrialParam(trialID, p.Name, toOptSpace(p, val), dist)
+			if err != nil {
+				return err
+			}
+		}
+		if err = study.Storage.SetTrialValue(trialID, -o.Score); err != nil {
+			return err
+		}
+		if err = study.Storage.SetTrialState(trialID, goptuna.TrialStateComplete); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package opt
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/banbox/banbot/core"
+)
+
+func TestStudyStore(t *testing.T) {
+	store, err := OpenStudyStore(filepath.Join(t.TempDir(), studyDbName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	err = store.ResetStudy(&OptStudy{Name: "demo", Sampler: "tpe", Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := &BTResult{OrderNum: 12, TotProfitPct: 35.5, ShowDrawDownPct: 8, SharpeRatio: math.NaN()}
+	for i := 0; i < 3; i++ {
+		o := &OptInfo{ID: "job" + string(rune('a'+i)), Score: float64(i), Params: map[string]float64{"x": float64(i)},
+			BTResult: res}
+		if err = store.AddTrial("demo", "pol", o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st, err := store.GetStudy("demo")
+	if err != nil || st == nil || st.Seed != 7 {
+		t.Fatalf("bad study: %v %v", st, err)
+	}
+	trials, err := store.GetTrials("demo", "pol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trials) != 3 || trials[2].Params["x"] != 2 || trials[1].ID != "jobb" {
+		t.Fatalf("bad trials: %v", trials)
+	}
+	if r := trials[0].BTResult; r == nil || r.OrderNum != 12 || r.TotProfitPct != 35.5 || r.SharpeRatio != 0 {
+		t.Errorf("bad trial result: %+v", r)
+	}
+	// reset clears trials 重置后清空结果
+	if err = store.ResetStudy(&OptStudy{Name: "demo", Sampler: "tpe"}); err != nil {
+		t.Fatal(err)
+	}
+	trials, _ = store.GetTrials("demo", "pol")
+	if len(trials) != 0 {
+		t.Errorf("trials should be cleared, got %d", len(trials))
+	}
+}
+
+func TestToOptSpace(t *testing.T) {
+	params := []*core.Param{core.PNorm(10, 50), core.PUniform(0, 1)}
+	for _, p := range params {
+		lo, hi := p.OptSpace()
+		for _, x := range []float64{lo, (lo*3 + hi) / 4, (lo + hi) / 2, hi} {
+			val, _ := p.ToRegular(x)
+			back, _ := p.ToRegular(toOptSpace(p, val))
+			if math.Abs(back-val) > 1e-6*max(1, math.Abs(val)) {
+				t.Errorf("toOptSpace not inverse: %v -> %v -> %v", x, val, back)
+			}
+		}
+	}
+}
+
+func TestListTrials(t *testing.T) {
+	store, err := OpenStudyStore(filepath.Join(t.TempDir(), studyDbName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	pol := "ma:l/1h/BTC/USDT|ETH/USDT,ma:s/1h/BTC/USDT|ETH/USDT"
+	o := &OptInfo{ID: "job1", Score: 2.5, Params: map[string]float64{"x": 3}, BTResult: &BTResult{OrderNum: 5}}
+	if err = store.AddTrial("demo", pol, o); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.AddTrial("other", pol, o); err != nil {
+		t.Fatal(err)
+	}
+	items, err := store.ListTrials("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Pol != pol || items[0].Result == nil || items[0].Result.OrderNum != 5 {
+		t.Fatalf("bad items: %+v", items)
+	}
+	// the tuned policy is the first one, pairs contain "/" 调优的策略为第一个，品种包含"/"
+	res := items[0].ToPolicy()
+	if res.Name != "ma" || res.Dirt != "long" || len(res.Pairs) != 2 || res.Pairs[1] != "ETH/USDT" ||
+		res.Params["x"] != 3 {
+		t.Errorf("bad policy: %+v", res)
+	}
+}
@@ -0,0 +1,245 @@
+package opt
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/banbox/banbot/btime"
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/orm/ormo"
+	"github.com/banbox/banbot/utils"
+	"github.com/banbox/banexg/errs"
+	"github.com/olekukonko/tablewriter"
+)
+
+// WFMetrics performance of orders in a walk-forward window 滚动窗口内订单的表现
+type WFMetrics struct {
+	ProfitPct   float64 `json:"profitPct"`
+	DrawDownPct float64 `json:"drawDownPct"` // max drawdown of closed profits 已平仓利润的最大回撤
+	Sharpe      float64 `json:"sharpe"`
+	OrderNum    int     `json:"orderNum"`
+	WinRatePct  float64 `json:"winRatePct"`
+}
+
+/*
+WFWindow
+One window of walk-forward analysis: parameters are optimized in-sample(IS) and then run out-of-sample(OOS)
+滚动前进分析的一个窗口：在样本内(IS)优化参数，然后在样本外(OOS)运行
+*/
+type WFWindow struct {
+	ISStart  int64                         `json:"isStart"`
+	ISEnd    int64                         `json:"isEnd"`
+	OOSStart int64                         `json:"oosStart"`
+	OOSEnd   int64                         `json:"oosEnd"`
+	IS       *WFMetrics                    `json:"is"`
+	OOS      *WFMetrics                    `json:"oos"`
+	Params   map[string]map[string]float64 `json:"params"` // params of each run_policy 每个run_policy的参数
+	Drift    float64                       `json:"drift"`  // mean change percent of params from last window 参数相比上个窗口的平均变化百分比
+	WFE      float64                       `json:"wfe"`    // walk-forward efficiency 滚动前进效率
+}
+
+/*
+WalkForward
+Report of RunBTOverOpt, WFE is the annualized OOS profit divided by the annualized IS profit
+RunBTOverOpt的报告，WFE为样本外年化收益除以样本内年化收益
+*/
+type WalkForward struct {
+	Windows      []*WFWindow `json:"windows"`
+	ISProfitPct  float64     `json:"isProfitPct"`
+	OOSProfitPct float64     `json:"oosProfitPct"`
+	AvgDrift     float64     `json:"avgDrift"`
+	WFE          float64     `json:"wfe"`
+}
+
+// walletsLegal legal value of wallet amounts by prices of c 按c的价格计算钱包数量的法币价值
+func walletsLegal(c *core.BotCtx, amounts map[string]float64) float64 {
+	total := float64(0)
+	for key, val := range amounts {
+		total += val * c.GetPriceSafe(key)
+	}
+	return total
+}
+
+/*
+calcWFMetrics
+calculate metrics from closed orders of a window backtested in c, initLegal is the wallet value at the window start
+从在c中回测的窗口内已平仓订单计算指标，initLegal是窗口开始时的钱包价值
+*/
+func calcWFMetrics(c *core.BotCtx, ods []*ormo.InOutOrder, initLegal float64) *WFMetrics {
+	res := &WFMetrics{OrderNum: len(ods)}
+	if len(ods) == 0 || initLegal <= 0 {
+		return res
+	}
+	ods = slices.Clone(ods)
+	slices.SortFunc(ods, func(a, b *ormo.InOutOrder) int {
+		return int(a.RealExitMS() - b.RealExitMS())
+	})
+	profits := make([]float64, 0, len(ods))
+	sumProfit, winNum := float64(0), 0
+	for _, od := range ods {
+		profits = append(profits, od.Profit)
+		sumProfit += od.Profit
+		if od.Profit > 0 {
+			winNum += 1
+		}
+	}
+	res.ProfitPct = sumProfit * 100 / initLegal
+	res.WinRatePct = float64(winNum) * 100 / float64(len(ods))
+	ddPct, _, _, _, _, _ := utils.CalcMaxDrawDown(profits, initLegal)
+	res.DrawDownPct = utils.NanInfTo(ddPct*100, 0)
+	sharpe, _, err := measurePerformance(c, ods)
+	if err == nil {
+		res.Sharpe = utils.NanInfTo(sharpe, 0)
+	}
+	return res
+}
+
+// dumpPolicyParams copy params of run policies, keyed by policy 复制运行策略的参数，以策略为键
+func dumpPolicyParams(pols []*config.RunPolicyConfig) map[string]map[string]float64 {
+	res := make(map[string]map[string]float64, len(pols))
+	for _, p := range pols {
+		params := make(map[string]float64, len(p.Params))
+		for k, v := range p.Params {
+			params[k] = v
+		}
+		res[p.Key()] = params
+	}
+	return res
+}
+
+/*
+calcParamDrift
+mean change percent of params existing in both windows
+两个窗口都存在的参数的平均变化百分比
+*/
+func calcParamDrift(olds, news map[string]map[string]float64) float64 {
+	sumPct, num := float64(0), 0
+	for key, params := range news {
+		oldParams, ok := olds[key]
+		if !ok {
+			continue
+		}
+		for k, v := range params {
+			oldV, ok := oldParams[k]
+			if !ok {
+				continue
+			}
+			num += 1
+			base := max(math.Abs(oldV), math.Abs(v))
+			if base > 0 {
+				sumPct += math.Abs(v-oldV) * 100 / base
+			}
+		}
+	}
+	if num == 0 {
+		return 0
+	}
+	return sumPct / float64(num)
+}
+
+/*
+calcWFE
+annualized OOS profit divided by annualized IS profit, 0 when IS is not profitable
+样本外年化收益除以样本内年化收益，样本内未盈利时为0
+*/
+func calcWFE(isPct float64, isMS int64, oosPct float64, oosMS int64) float64 {
+	if isPct <= 0 || isMS <= 0 || oosMS <= 0 {
+		return 0
+	}
+	return (oosPct / float64(oosMS)) / (isPct / float64(isMS))
+}
+
+// AddWindow add a finished window and update the summary 添加一个已完成窗口并更新汇总
+func (w *WalkForward) AddWindow(win *WFWindow) {
+	if len(w.Windows) > 0 {
+		win.Drift = calcParamDrift(w.Windows[len(w.Windows)-1].Params, win.Params)
+	}
+	win.WFE = calcWFE(win.IS.ProfitPct, win.ISEnd-win.ISStart, win.OOS.ProfitPct, win.OOSEnd-win.OOSStart)
+	w.Windows = append(w.Windows, win)
+	var isMS, oosMS int64
+	w.ISProfitPct, w.OOSProfitPct, w.AvgDrift = 0, 0, 0
+	for i, it := range w.Windows {
+		w.ISProfitPct += it.IS.ProfitPct
+		w.OOSProfitPct += it.OOS.ProfitPct
+		isMS += it.ISEnd - it.ISStart
+		oosMS += it.OOSEnd - it.OOSStart
+		if i > 0 {
+			w.AvgDrift += it.Drift
+		}
+	}
+	if len(w.Windows) > 1 {
+		w.AvgDrift /= float64(len(w.Windows) - 1)
+	}
+	w.WFE = calcWFE(w.ISProfitPct, isMS, w.OOSProfitPct, oosMS)
+}
+
+func (w *WalkForward) rows() [][]string {
+	rows := [][]string{{"IS Range", "OOS Range", "IS Profit%", "OOS Profit%", "IS DrawDown%", "OOS DrawDown%",
+		"IS Sharpe", "OOS Sharpe", "IS Orders", "OOS Orders", "Drift%", "WFE"}}
+	fmtFlt := func(v float64, prec int) string {
+		return strconv.FormatFloat(v, 'f', prec, 64)
+	}
+	fmtRange := func(start, end int64) string {
+		return btime.ToDateStr(start, core.DateFmt) + "~" + btime.ToDateStr(end, core.DateFmt)
+	}
+	for _, it := range w.Windows {
+		rows = append(rows, []string{
+			fmtRange(it.ISStart, it.ISEnd), fmtRange(it.OOSStart, it.OOSEnd),
+			fmtFlt(it.IS.ProfitPct, 1), fmtFlt(it.OOS.ProfitPct, 1),
+			fmtFlt(it.IS.DrawDownPct, 1), fmtFlt(it.OOS.DrawDownPct, 1),
+			fmtFlt(it.IS.Sharpe, 2), fmtFlt(it.OOS.Sharpe, 2),
+			strconv.Itoa(it.IS.OrderNum), strconv.Itoa(it.OOS.OrderNum),
+			fmtFlt(it.Drift, 1), fmtFlt(it.WFE, 2),
+		})
+	}
+	return rows
+}
+
+// Text render windows as a table, with the summary at the bottom 将窗口渲染为表格，底部为汇总
+func (w *WalkForward) Text() string {
+	var b bytes.Buffer
+	rows := w.rows()
+	table := tablewriter.NewWriter(&b)
+	table.SetHeader(rows[0])
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetAlignment(tablewriter.ALIGN_RIGHT)
+	table.AppendBulk(rows[1:])
+	table.Render()
+	b.WriteString(fmt.Sprintf("IS Profit: %.1f%%, OOS Profit: %.1f%%, Avg Drift: %.1f%%, WFE: %.2f\n",
+		w.ISProfitPct, w.OOSProfitPct, w.AvgDrift, w.WFE))
+	return b.String()
+}
+
+/*
+Dump
+write walk_forward.csv and walk_forward.html to the backtest directory
+写入walk_forward.csv和walk_forward.html到回测目录
+*/
+func (w *WalkForward) Dump(outDir string) *errs.Error {
+	err := utils.WriteCsvFile(filepath.Join(outDir, "walk_forward.csv"), w.rows(), false)
+	if err != nil {
+		return err
+	}
+	labels := make([]string, 0, len(w.Windows))
+	isPfts := make([]float64, 0, len(w.Windows))
+	oosPfts := make([]float64, 0, len(w.Windows))
+	drifts := make([]float64, 0, len(w.Windows))
+	for _, it := range w.Windows {
+		labels = append(labels, btime.ToDateStr(it.OOSStart, core.DateFmt))
+		isPfts = append(isPfts, it.IS.ProfitPct)
+		oosPfts = append(oosPfts, it.OOS.ProfitPct)
+		drifts = append(drifts, it.Drift)
+	}
+	title := fmt.Sprintf("Walk Forward, WFE: %.2f", w.WFE)
+	return DumpChart(filepath.Join(outDir, "walk_forward.html"), title, labels, 5, nil, []*ChartDs{
+		{Label: "IS Profit%", Data: isPfts},
+		{Label: "OOS Profit%", Data: oosPfts},
+		{Label: "Drift%", Data: drifts, YAxisID: "yRight"},
+	})
+}
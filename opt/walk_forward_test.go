@@ -0,0 +1,43 @@
+package opt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWalkForward(t *testing.T) {
+	day := int64(86400000)
+	wf := &WalkForward{}
+	wf.AddWindow(&WFWindow{
+		ISStart: 0, ISEnd: 30 * day, OOSStart: 30 * day, OOSEnd: 40 * day,
+		IS:     &WFMetrics{ProfitPct: 30},
+		OOS:    &WFMetrics{ProfitPct: 5},
+		Params: map[string]map[string]float64{"ma": {"a": 10, "b": 0}},
+	})
+	wf.AddWindow(&WFWindow{
+		ISStart: 10 * day, ISEnd: 40 * day, OOSStart: 40 * day, OOSEnd: 50 * day,
+		IS:     &WFMetrics{ProfitPct: -6},
+		OOS:    &WFMetrics{ProfitPct: 1},
+		Params: map[string]map[string]float64{"ma": {"a": 8, "b": 0}, "rsi": {"c": 3}},
+	})
+	first, second := wf.Windows[0], wf.Windows[1]
+	// 5%/10d vs 30%/30d
+	if math.Abs(first.WFE-0.5) > 1e-9 || first.Drift != 0 {
+		t.Errorf("bad first window: %+v", first)
+	}
+	// IS is not profitable 样本内未盈利
+	if second.WFE != 0 {
+		t.Errorf("WFE should be 0 for losing IS, got %v", second.WFE)
+	}
+	// a: 20% change, b: no change, c: not in last window
+	if math.Abs(second.Drift-10) > 1e-9 || wf.AvgDrift != second.Drift {
+		t.Errorf("bad drift: %v, avg: %v", second.Drift, wf.AvgDrift)
+	}
+	// 6%/20d vs 24%/60d
+	if wf.ISProfitPct != 24 || wf.OOSProfitPct != 6 || math.Abs(wf.WFE-0.75) > 1e-9 {
+		t.Errorf("bad summary: %+v", wf)
+	}
+	if len(wf.rows()) != 3 {
+		t.Errorf("bad rows: %v", wf.rows())
+	}
+}
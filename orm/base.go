@@ -71,7 +71,21 @@ func Setup() *errs.Error {
 		pool.Close()
 		pool = nil
 	}
-	var err2 *errs.Error
+	useStore, err2 := setupKStore()
+	if err2 != nil {
+		return err2
+	}
+	if useStore {
+		// kline store has no pending insert jobs 使用K线存储时没有未完成的插入任务
+		err2 = LoadAllExSymbols()
+		if err2 != nil {
+			return err2
+		}
+		if exg.Default != nil {
+			_, err2 = LoadMarkets(exg.Default, false)
+		}
+		return err2
+	}
 	pool, err2 = pgConnPool()
 	if err2 != nil {
 		return err2
@@ -186,6 +200,11 @@ func createPgDb(dbUrl string) *errs.Error {
 }
 
 func Conn(ctx context.Context) (*Queries, *pgxpool.Conn, *errs.Error) {
+	if kStore != nil {
+		// queries are routed to kline store, a zero conn is safe to release
+		// 查询被路由到K线存储，零值conn可安全释放
+		return New(nil), &pgxpool.Conn{}, nil
+	}
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -273,6 +292,9 @@ func (t *Tx) Close(ctx context.Context, commit bool) *errs.Error {
 }
 
 func (q *Queries) NewTx(ctx context.Context) (*Tx, *Queries, *errs.Error) {
+	if kStore != nil {
+		return &Tx{closed: true}, q, nil
+	}
 	if ctx == nil {
 		ctx = context.Background()
 	}
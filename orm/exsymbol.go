@@ -22,11 +22,13 @@ var (
 	symbolLock   sync.Mutex
 	tryListIds   = make(map[int32]bool)
 	tryListLock  sync.Mutex
+	initExgs     = make(map[banexg.BanExchange]bool) // clients inited by InitPairExgs 已由InitPairExgs初始化的客户端
+	initExgLock  sync.Mutex
 )
 
 func (q *Queries) LoadExgSymbols(exgName string) *errs.Error {
 	ctx := context.Background()
-	exsList, err := q.ListSymbols(ctx, exgName)
+	exsList, err := q.listSymbols(ctx, exgName)
 	if err != nil {
 		return NewDbErr(core.ErrDbReadFail, err)
 	}
@@ -74,11 +76,35 @@ func GetSymbolByID(id int32) *ExSymbol {
 	return item
 }
 
+// GetExSymbolCur get ExSymbol from the exchange which the symbol is traded on 从品种所在的交易所获取ExSymbol
 func GetExSymbolCur(symbol string) (*ExSymbol, *errs.Error) {
-	return GetExSymbol(exg.Default, symbol)
+	return GetExSymbol(exg.GetPairExg(symbol), symbol)
 }
 
+/*
+InitPairExgs
+Load markets and symbols of the clients bound by run_policy exchange/market, inited clients are skipped.
+加载run_policy的exchange/market绑定的客户端的市场和品种，跳过已初始化的客户端
+*/
+func InitPairExgs() *errs.Error {
+	initExgLock.Lock()
+	defer initExgLock.Unlock()
+	for _, client := range exg.AllExgs()[1:] {
+		if initExgs[client] {
+			continue
+		}
+		err := InitExg(client)
+		if err != nil {
+			return err
+		}
+		initExgs[client] = true
+	}
+	return nil
+}
+
+// GetExSymbol symbol can be a pair key of core.PairKey 品种可以是core.PairKey的品种键
 func GetExSymbol(exchange banexg.BanExchange, symbol string) (*ExSymbol, *errs.Error) {
+	symbol = core.PairSymbol(symbol)
 	market, err := exchange.GetMarket(symbol)
 	// It is not immediately exited here, it may be delisted, and it is returned empty, but there is historical data, you can try to get it from the cache below
 	// 这里不立即退出，可能退市了这里返回空，但有历史数据，可尝试从下面缓存获取
@@ -145,7 +171,7 @@ func EnsureExgSymbols(exchange banexg.BanExchange) *errs.Error {
 			}
 			defer conn.Release()
 			for _, exs := range editList {
-				err_ := sess.SetListMS(context.Background(), SetListMSParams{
+				err_ := sess.setListMS(context.Background(), SetListMSParams{
 					ID:       exs.ID,
 					ListMs:   exs.ListMs,
 					DelistMs: exs.DelistMs,
@@ -244,7 +270,7 @@ func EnsureSymbols(symbols []*ExSymbol, exchanges ...string) *errs.Error {
 		argList = append(argList, AddSymbolsParams{Exchange: item.Exchange, ExgReal: item.ExgReal,
 			Market: item.Market, Symbol: item.Symbol})
 	}
-	_, err_ := sess.AddSymbols(context.Background(), argList)
+	_, err_ := sess.addSymbols(context.Background(), argList)
 	if err_ != nil {
 		errMsg := err_.Error()
 		if strings.Contains(errMsg, "SQLSTATE 22001") {
@@ -278,7 +304,7 @@ func LoadAllExSymbols() *errs.Error {
 	}
 	defer conn.Release()
 	ctx := context.Background()
-	exgList, err_ := sess.ListExchanges(ctx)
+	exgList, err_ := sess.listExchanges(ctx)
 	if err_ != nil {
 		return NewDbErr(core.ErrDbReadFail, err)
 	}
@@ -304,6 +330,11 @@ func (s *ExSymbol) GetValidStart(startMS int64) int64 {
 	return max(s.ListMs, startMS)
 }
 
+// PairKey the pair key used by jobs and orders, see core.PairKey 任务和订单使用的品种键，见core.PairKey
+func (s *ExSymbol) PairKey() string {
+	return core.PairKey(s.Exchange, s.Market, s.Symbol)
+}
+
 func (s *ExSymbol) ToShort() string {
 	slashArr := strings.Split(s.Symbol, "/")
 	if len(slashArr) == 1 {
@@ -357,7 +388,7 @@ func InitListDates() *errs.Error {
 			changed = true
 		}
 		if changed {
-			err_ := sess.SetListMS(context.Background(), SetListMSParams{
+			err_ := sess.setListMS(context.Background(), SetListMSParams{
 				ID:       exs.ID,
 				ListMs:   exs.ListMs,
 				DelistMs: exs.DelistMs,
@@ -423,7 +454,7 @@ func EnsureListDates(sess *Queries, exchange banexg.BanExchange, exsMap map[int3
 		}
 		if len(klines) > 0 {
 			exs.ListMs = klines[0].Time
-			err_ := sess.SetListMS(context.Background(), SetListMSParams{
+			err_ := sess.setListMS(context.Background(), SetListMSParams{
 				ID:       exs.ID,
 				ListMs:   exs.ListMs,
 				DelistMs: exs.DelistMs,
@@ -0,0 +1,186 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/banbox/banbot/btime"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banexg"
+	"github.com/banbox/banexg/errs"
+	"github.com/banbox/banexg/log"
+	"go.uber.org/zap"
+)
+
+const (
+	// FundGapMSecs Minimum interval between two funding settlements, used to skip needless downloads 两次资金费结算的最小间隔，用于跳过无需下载的区间
+	FundGapMSecs = int64(3600000)
+)
+
+/*
+GetFundingRates
+Get the funding rate history of the specified symbol in [startMS, endMS), sorted by time
+获取指定品种在[startMS, endMS)内的资金费率历史，按时间升序
+*/
+func (q *Queries) GetFundingRates(sid int32, startMS, endMS int64) ([]*FundingRate, *errs.Error) {
+	if kStore != nil {
+		return kStore.GetFundingRates(sid, startMS, endMS)
+	}
+	sql := "select id,sid,time,rate from funding_rates where sid=$1 "
+	sqlParams := []interface{}{sid}
+	if startMS > 0 {
+		sql += fmt.Sprintf("and time >= $%v ", len(sqlParams)+1)
+		sqlParams = append(sqlParams, startMS)
+	}
+	if endMS > 0 {
+		sql += fmt.Sprintf("and time < $%v ", len(sqlParams)+1)
+		sqlParams = append(sqlParams, endMS)
+	}
+	sql += "order by time"
+	rows, err_ := q.db.Query(context.Background(), sql, sqlParams...)
+	if err_ != nil {
+		return nil, NewDbErr(core.ErrDbReadFail, err_)
+	}
+	defer rows.Close()
+	result := make([]*FundingRate, 0)
+	for rows.Next() {
+		var it FundingRate
+		err_ = rows.Scan(&it.ID, &it.Sid, &it.Time, &it.Rate)
+		if err_ != nil {
+			return result, NewDbErr(core.ErrDbReadFail, err_)
+		}
+		result = append(result, &it)
+	}
+	return result, nil
+}
+
+/*
+GetFundingRange
+Returns the earliest and latest funding timestamps stored for sid, 0 if none
+返回sid已存储的最早和最晚资金费时间戳，无数据时返回0
+*/
+func (q *Queries) GetFundingRange(sid int32) (int64, int64, *errs.Error) {
+	if kStore != nil {
+		items, err := kStore.GetFundingRates(sid, 0, 0)
+		if err != nil || len(items) == 0 {
+			return 0, 0, err
+		}
+		return items[0].Time, items[len(items)-1].Time, nil
+	}
+	sql := "select coalesce(min(time), 0), coalesce(max(time), 0) from funding_rates where sid=$1"
+	var startMS, endMS int64
+	err_ := q.db.QueryRow(context.Background(), sql, sid).Scan(&startMS, &endMS)
+	if err_ != nil {
+		return 0, 0, NewDbErr(core.ErrDbReadFail, err_)
+	}
+	return startMS, endMS, nil
+}
+
+/*
+SetFundingRates
+Save funding rates of a symbol. Existing records in the covered time range are replaced.
+保存品种的资金费率，覆盖时间范围内已有的记录会被替换
+*/
+func (q *Queries) SetFundingRates(sid int32, items []*FundingRate) *errs.Error {
+	if len(items) == 0 {
+		return nil
+	}
+	if kStore != nil {
+		return kStore.SetFundingRates(sid, items)
+	}
+	ctx := context.Background()
+	startMS, endMS := items[0].Time, items[len(items)-1].Time+1
+	err_ := q.DelFundingRates(ctx, DelFundingRatesParams{Sid: sid, Time: startMS, Time_2: endMS})
+	if err_ != nil {
+		return NewDbErr(core.ErrDbExecFail, err_)
+	}
+	adds := make([]AddFundingRatesParams, 0, len(items))
+	var lastMS int64
+	for _, it := range items {
+		if it.Time <= lastMS {
+			// skip unsorted or duplicate items
+			// 跳过无序或重复的记录
+			continue
+		}
+		lastMS = it.Time
+		adds = append(adds, AddFundingRatesParams{Sid: sid, Time: it.Time, Rate: it.Rate})
+	}
+	_, err_ = q.AddFundingRates(ctx, adds)
+	if err_ != nil {
+		return NewDbErr(core.ErrDbExecFail, err_)
+	}
+	return nil
+}
+
+/*
+DownFundingRates
+Download funding rate history in [startMS, endMS) from exchange and save to db. Returns the number saved.
+从交易所下载[startMS, endMS)内的资金费率历史并保存到数据库，返回保存的数量
+*/
+func (q *Queries) DownFundingRates(exchange banexg.BanExchange, exs *ExSymbol, startMS, endMS int64) (int, *errs.Error) {
+	if !banexg.IsContract(exs.Market) {
+		return 0, nil
+	}
+	startMS = exs.GetValidStart(startMS)
+	if endMS == 0 {
+		endMS = btime.UTCStamp()
+	}
+	if startMS >= endMS {
+		return 0, nil
+	}
+	rates, err := exchange.FetchFundingRateHistory(exs.Symbol, startMS, 0, map[string]interface{}{
+		banexg.ParamUntil: endMS,
+	})
+	if err != nil {
+		return 0, err
+	}
+	items := make([]*FundingRate, 0, len(rates))
+	for _, r := range rates {
+		if r.Timestamp < startMS || r.Timestamp >= endMS {
+			continue
+		}
+		items = append(items, &FundingRate{Sid: exs.ID, Time: r.Timestamp, Rate: r.FundingRate})
+	}
+	return len(items), q.SetFundingRates(exs.ID, items)
+}
+
+/*
+AutoFetchFundingRates
+Get funding rates in [startMS, endMS). Missing head/tail ranges are downloaded from the exchange first.
+获取[startMS, endMS)内的资金费率，缺失的首尾区间先从交易所下载
+*/
+func AutoFetchFundingRates(exchange banexg.BanExchange, exs *ExSymbol, startMS, endMS int64) ([]*FundingRate, *errs.Error) {
+	sess, conn, err := Conn(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+	if exchange != nil && banexg.IsContract(exs.Market) {
+		oldStart, oldEnd, err := sess.GetFundingRange(exs.ID)
+		if err != nil {
+			return nil, err
+		}
+		var ranges [][2]int64
+		if oldStart == 0 {
+			ranges = append(ranges, [2]int64{startMS, endMS})
+		} else {
+			if oldStart-exs.GetValidStart(startMS) > FundGapMSecs {
+				ranges = append(ranges, [2]int64{startMS, oldStart})
+			}
+			if endMS-oldEnd > FundGapMSecs {
+				ranges = append(ranges, [2]int64{oldEnd + 1, endMS})
+			}
+		}
+		for _, rg := range ranges {
+			num, err := sess.DownFundingRates(exchange, exs, rg[0], rg[1])
+			if err != nil {
+				log.Warn("down funding rates fail", zap.String("symbol", exs.Symbol), zap.Error(err))
+				break
+			}
+			if num > 0 {
+				log.Debug("down funding rates", zap.String("symbol", exs.Symbol), zap.Int("num", num))
+			}
+		}
+	}
+	return sess.GetFundingRates(exs.ID, startMS, endMS)
+}
@@ -0,0 +1,56 @@
+package orm
+
+import (
+	"testing"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banexg"
+)
+
+func TestFundingRates(t *testing.T) {
+	store, err := NewFileKStore(&config.DatabaseConfig{KlineDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStore := kStore
+	kStore = store
+	defer func() {
+		kStore = oldStore
+	}()
+	hourMS := int64(3600000)
+	q := New(nil)
+	items := make([]*FundingRate, 0, 4)
+	for i := int64(0); i < 4; i++ {
+		items = append(items, &FundingRate{Time: i * 8 * hourMS, Rate: 0.0001})
+	}
+	if err = q.SetFundingRates(5, items); err != nil {
+		t.Fatal(err)
+	}
+	// replace the middle range 替换中间区间
+	err = q.SetFundingRates(5, []*FundingRate{{Time: 8 * hourMS, Rate: -0.0002}, {Time: 16 * hourMS, Rate: 0.0003}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// [startMS, endMS) 左闭右开
+	res, err := q.GetFundingRates(5, 8*hourMS, 24*hourMS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[0].Rate != -0.0002 || res[1].Rate != 0.0003 {
+		t.Errorf("rates in range invalid: %v", res)
+	}
+	start, end, err := q.GetFundingRange(5)
+	if err != nil || start != 0 || end != 24*hourMS {
+		t.Errorf("funding range invalid: %v %v %v", start, end, err)
+	}
+	// a missing series returns empty without download when exchange is nil 缺失序列在exchange为nil时不下载，返回空
+	exs := &ExSymbol{ID: 6, Exchange: "binance", Market: banexg.MarketLinear, Symbol: "ETH/USDT:USDT"}
+	res, err = AutoFetchFundingRates(nil, exs, 0, 24*hourMS)
+	if err != nil || len(res) != 0 {
+		t.Errorf("missing series should be empty: %v %v", res, err)
+	}
+	start, end, err = q.GetFundingRange(6)
+	if err != nil || start != 0 || end != 0 {
+		t.Errorf("missing series range should be 0: %v %v %v", start, end, err)
+	}
+}
@@ -268,7 +268,7 @@ func downOHLCV2DBRange(sess *Queries, exchange banexg.BanExchange, exs *ExSymbol
 	}()
 
 	wg.Wait()
-	err_ := sess.DelInsKline(context.Background(), insId)
+	err_ := sess.delInsKline(context.Background(), insId)
 	if err_ != nil {
 		log.Warn("DelInsKline fail", zap.Int32("id", insId), zap.Error(err_))
 	}
@@ -393,7 +393,7 @@ func (q *Queries) GetAdjs(sid int32) ([]*AdjInfo, *errs.Error) {
 		return cache, nil
 	}
 	ctx := context.Background()
-	rows, err_ := q.GetAdjFactors(ctx, sid)
+	rows, err_ := q.getAdjFactors(ctx, sid)
 	if err_ != nil {
 		return nil, NewDbErr(core.ErrDbReadFail, err_)
 	}
@@ -692,7 +692,7 @@ func FastBulkOHLCV(exchange banexg.BanExchange, symbols []string, timeFrame stri
 				if !ok {
 					return
 				}
-				handler(exs.Symbol, timeFrame, klines, nil)
+				handler(exs.PairKey(), timeFrame, klines, nil)
 			}
 			err = sess.QueryOHLCVBatch(sidArr, timeFrame, startMS, endMS, limit, bulkHandler)
 			if err != nil {
@@ -709,7 +709,7 @@ func FastBulkOHLCV(exchange banexg.BanExchange, symbols []string, timeFrame stri
 		if err != nil {
 			return err
 		}
-		handler(exs.Symbol, timeFrame, kline, adjs)
+		handler(exs.PairKey(), timeFrame, kline, adjs)
 	}
 	return nil
 }
@@ -767,6 +767,9 @@ func (q *Queries) getCalendars(name string, startMS, stopMS int64, fields string
 }
 
 func (q *Queries) GetCalendars(name string, startMS, stopMS int64) ([][2]int64, *errs.Error) {
+	if kStore != nil {
+		return kStore.GetCalendars(name, startMS, stopMS)
+	}
 	rows, err_ := q.getCalendars(name, startMS, stopMS, "start_ms,stop_ms")
 	if err_ != nil {
 		return nil, NewDbErr(core.ErrDbReadFail, err_)
@@ -788,6 +791,9 @@ func (q *Queries) SetCalendars(name string, items [][2]int64) *errs.Error {
 	if len(items) == 0 {
 		return nil
 	}
+	if kStore != nil {
+		return kStore.SetCalendars(name, items)
+	}
 	startMS, stopMS := items[0][0], items[len(items)-1][1]
 	rows, err_ := q.getCalendars(name, startMS, stopMS, "id,start_ms,stop_ms")
 	if err_ != nil {
@@ -910,7 +916,7 @@ func (q *Queries) DelKData(exs *ExSymbol, tfList []string, startMS, endMS int64)
 			}
 			if realStart > 0 && realEnd > 0 {
 				ctx := context.Background()
-				_, err_ := q.AddKInfo(ctx, AddKInfoParams{Sid: exs.ID, Timeframe: tf, Start: realStart, Stop: realEnd})
+				_, err_ := q.addKInfo(ctx, AddKInfoParams{Sid: exs.ID, Timeframe: tf, Start: realStart, Stop: realEnd})
 				if err_ != nil {
 					return NewDbErr(core.ErrDbExecFail, err_)
 				}
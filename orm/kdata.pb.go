@@ -411,19 +411,81 @@ func (x *KHoleBlock) GetHoles() []int64 {
 	return nil
 }
 
+// FundingRateBlock represents funding rate history of a perpetual contract
+type FundingRateBlock struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sid           int32                  `protobuf:"varint,1,opt,name=sid,proto3" json:"sid,omitempty"`            // symbol id
+	Times         []int64                `protobuf:"varint,2,rep,packed,name=times,proto3" json:"times,omitempty"` // settlement timestamps in milliseconds
+	Rates         []float64              `protobuf:"fixed64,3,rep,packed,name=rates,proto3" json:"rates,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FundingRateBlock) Reset() {
+	*x = FundingRateBlock{}
+	mi := &file_kdata_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FundingRateBlock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FundingRateBlock) ProtoMessage() {}
+
+func (x *FundingRateBlock) ProtoReflect() protoreflect.Message {
+	mi := &file_kdata_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FundingRateBlock.ProtoReflect.Descriptor instead.
+func (*FundingRateBlock) Descriptor() ([]byte, []int) {
+	return file_kdata_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *FundingRateBlock) GetSid() int32 {
+	if x != nil {
+		return x.Sid
+	}
+	return 0
+}
+
+func (x *FundingRateBlock) GetTimes() []int64 {
+	if x != nil {
+		return x.Times
+	}
+	return nil
+}
+
+func (x *FundingRateBlock) GetRates() []float64 {
+	if x != nil {
+		return x.Rates
+	}
+	return nil
+}
+
 type EXInfo struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Symbols       []*ExSymbolBlock       `protobuf:"bytes,1,rep,name=symbols,proto3" json:"symbols,omitempty"`
 	KHoles        []*KHoleBlock          `protobuf:"bytes,2,rep,name=kHoles,proto3" json:"kHoles,omitempty"`
 	AdjFactors    []*AdjFactorBlock      `protobuf:"bytes,3,rep,name=adjFactors,proto3" json:"adjFactors,omitempty"`
 	Calendars     []*CalendarBlock       `protobuf:"bytes,4,rep,name=calendars,proto3" json:"calendars,omitempty"`
+	FundingRates  []*FundingRateBlock    `protobuf:"bytes,5,rep,name=fundingRates,proto3" json:"fundingRates,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *EXInfo) Reset() {
 	*x = EXInfo{}
-	mi := &file_kdata_proto_msgTypes[5]
+	mi := &file_kdata_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -435,7 +497,7 @@ func (x *EXInfo) String() string {
 func (*EXInfo) ProtoMessage() {}
 
 func (x *EXInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_kdata_proto_msgTypes[5]
+	mi := &file_kdata_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -448,7 +510,7 @@ func (x *EXInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EXInfo.ProtoReflect.Descriptor instead.
 func (*EXInfo) Descriptor() ([]byte, []int) {
-	return file_kdata_proto_rawDescGZIP(), []int{5}
+	return file_kdata_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *EXInfo) GetSymbols() []*ExSymbolBlock {
@@ -479,6 +541,13 @@ func (x *EXInfo) GetCalendars() []*CalendarBlock {
 	return nil
 }
 
+func (x *EXInfo) GetFundingRates() []*FundingRateBlock {
+	if x != nil {
+		return x.FundingRates
+	}
+	return nil
+}
+
 var File_kdata_proto protoreflect.FileDescriptor
 
 var file_kdata_proto_rawDesc = []byte{
@@ -525,20 +594,29 @@ var file_kdata_proto_rawDesc = []byte{
 	0x69, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x18,
 	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x66, 0x72, 0x61, 0x6d, 0x65,
 	0x12, 0x14, 0x0a, 0x05, 0x68, 0x6f, 0x6c, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x03, 0x52,
-	0x05, 0x68, 0x6f, 0x6c, 0x65, 0x73, 0x22, 0xc6, 0x01, 0x0a, 0x06, 0x45, 0x58, 0x49, 0x6e, 0x66,
-	0x6f, 0x12, 0x2c, 0x0a, 0x07, 0x73, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03,
-	0x28, 0x0b, 0x32, 0x12, 0x2e, 0x6f, 0x72, 0x6d, 0x2e, 0x45, 0x78, 0x53, 0x79, 0x6d, 0x62, 0x6f,
-	0x6c, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x07, 0x73, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x73, 0x12,
-	0x27, 0x0a, 0x06, 0x6b, 0x48, 0x6f, 0x6c, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
-	0x0f, 0x2e, 0x6f, 0x72, 0x6d, 0x2e, 0x4b, 0x48, 0x6f, 0x6c, 0x65, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
-	0x52, 0x06, 0x6b, 0x48, 0x6f, 0x6c, 0x65, 0x73, 0x12, 0x33, 0x0a, 0x0a, 0x61, 0x64, 0x6a, 0x46,
-	0x61, 0x63, 0x74, 0x6f, 0x72, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x6f,
-	0x72, 0x6d, 0x2e, 0x41, 0x64, 0x6a, 0x46, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x42, 0x6c, 0x6f, 0x63,
-	0x6b, 0x52, 0x0a, 0x61, 0x64, 0x6a, 0x46, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x73, 0x12, 0x30, 0x0a,
-	0x09, 0x63, 0x61, 0x6c, 0x65, 0x6e, 0x64, 0x61, 0x72, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b,
-	0x32, 0x12, 0x2e, 0x6f, 0x72, 0x6d, 0x2e, 0x43, 0x61, 0x6c, 0x65, 0x6e, 0x64, 0x61, 0x72, 0x42,
-	0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x09, 0x63, 0x61, 0x6c, 0x65, 0x6e, 0x64, 0x61, 0x72, 0x73, 0x42,
-	0x03, 0x5a, 0x01, 0x2e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x05, 0x68, 0x6f, 0x6c, 0x65, 0x73, 0x22, 0x50, 0x0a, 0x10, 0x46, 0x75, 0x6e, 0x64, 0x69, 0x6e,
+	0x67, 0x52, 0x61, 0x74, 0x65, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x73, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05,
+	0x74, 0x69, 0x6d, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x03, 0x52, 0x05, 0x74, 0x69, 0x6d,
+	0x65, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x61, 0x74, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28,
+	0x01, 0x52, 0x05, 0x72, 0x61, 0x74, 0x65, 0x73, 0x22, 0x81, 0x02, 0x0a, 0x06, 0x45, 0x58, 0x49,
+	0x6e, 0x66, 0x6f, 0x12, 0x2c, 0x0a, 0x07, 0x73, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x6f, 0x72, 0x6d, 0x2e, 0x45, 0x78, 0x53, 0x79, 0x6d,
+	0x62, 0x6f, 0x6c, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x07, 0x73, 0x79, 0x6d, 0x62, 0x6f, 0x6c,
+	0x73, 0x12, 0x27, 0x0a, 0x06, 0x6b, 0x48, 0x6f, 0x6c, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x0f, 0x2e, 0x6f, 0x72, 0x6d, 0x2e, 0x4b, 0x48, 0x6f, 0x6c, 0x65, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x52, 0x06, 0x6b, 0x48, 0x6f, 0x6c, 0x65, 0x73, 0x12, 0x33, 0x0a, 0x0a, 0x61, 0x64,
+	0x6a, 0x46, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13,
+	0x2e, 0x6f, 0x72, 0x6d, 0x2e, 0x41, 0x64, 0x6a, 0x46, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x52, 0x0a, 0x61, 0x64, 0x6a, 0x46, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x73, 0x12,
+	0x30, 0x0a, 0x09, 0x63, 0x61, 0x6c, 0x65, 0x6e, 0x64, 0x61, 0x72, 0x73, 0x18, 0x04, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x12, 0x2e, 0x6f, 0x72, 0x6d, 0x2e, 0x43, 0x61, 0x6c, 0x65, 0x6e, 0x64, 0x61,
+	0x72, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x09, 0x63, 0x61, 0x6c, 0x65, 0x6e, 0x64, 0x61, 0x72,
+	0x73, 0x12, 0x39, 0x0a, 0x0c, 0x66, 0x75, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x52, 0x61, 0x74, 0x65,
+	0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x6f, 0x72, 0x6d, 0x2e, 0x46, 0x75,
+	0x6e, 0x64, 0x69, 0x6e, 0x67, 0x52, 0x61, 0x74, 0x65, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x0c,
+	0x66, 0x75, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x52, 0x61, 0x74, 0x65, 0x73, 0x42, 0x03, 0x5a, 0x01,
+	0x2e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -553,25 +631,27 @@ func file_kdata_proto_rawDescGZIP() []byte {
 	return file_kdata_proto_rawDescData
 }
 
-var file_kdata_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_kdata_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_kdata_proto_goTypes = []any{
-	(*KlineBlock)(nil),     // 0: orm.KlineBlock
-	(*AdjFactorBlock)(nil), // 1: orm.AdjFactorBlock
-	(*CalendarBlock)(nil),  // 2: orm.CalendarBlock
-	(*ExSymbolBlock)(nil),  // 3: orm.ExSymbolBlock
-	(*KHoleBlock)(nil),     // 4: orm.KHoleBlock
-	(*EXInfo)(nil),         // 5: orm.EXInfo
+	(*KlineBlock)(nil),       // 0: orm.KlineBlock
+	(*AdjFactorBlock)(nil),   // 1: orm.AdjFactorBlock
+	(*CalendarBlock)(nil),    // 2: orm.CalendarBlock
+	(*ExSymbolBlock)(nil),    // 3: orm.ExSymbolBlock
+	(*KHoleBlock)(nil),       // 4: orm.KHoleBlock
+	(*FundingRateBlock)(nil), // 5: orm.FundingRateBlock
+	(*EXInfo)(nil),           // 6: orm.EXInfo
 }
 var file_kdata_proto_depIdxs = []int32{
 	3, // 0: orm.EXInfo.symbols:type_name -> orm.ExSymbolBlock
 	4, // 1: orm.EXInfo.kHoles:type_name -> orm.KHoleBlock
 	1, // 2: orm.EXInfo.adjFactors:type_name -> orm.AdjFactorBlock
 	2, // 3: orm.EXInfo.calendars:type_name -> orm.CalendarBlock
-	4, // [4:4] is the sub-list for method output_type
-	4, // [4:4] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	5, // 4: orm.EXInfo.fundingRates:type_name -> orm.FundingRateBlock
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_kdata_proto_init() }
@@ -585,7 +665,7 @@ func file_kdata_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_kdata_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
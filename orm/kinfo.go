@@ -25,6 +25,9 @@ func (q *Queries) FindKHoles(args FindKHolesArgs) ([]*KHole, int64, *errs.Error)
 	if args.Sid == 0 {
 		return nil, 0, errs.NewMsg(core.ErrDbReadFail, "sid is required")
 	}
+	if kStore != nil {
+		return findStoreKHoles(args)
+	}
 
 	// 构建WHERE条件
 	whereClause := fmt.Sprintf("where sid=$%v ", len(sqlParams)+1)
@@ -97,8 +97,20 @@ select time,open,high,low,close,volume,info from $tbl
 where sid=%d and time >= %v and time < %v
 order by time`, sid, startMs, finishEndMS)
 	}
-	subTF, rows, err_ := queryHyper(q, timeframe, dctSql, limit)
-	klines, err_ := mapToKlines(rows, err_)
+	var subTF string
+	var klines []*banexg.Kline
+	var err_ error
+	if kStore != nil {
+		var storeStart = startMs
+		if revRead {
+			storeStart = 0
+		}
+		subTF, klines, err_ = queryStoreKLines(sid, timeframe, storeStart, finishEndMS, limit, revRead)
+	} else {
+		var rows pgx.Rows
+		subTF, rows, err_ = queryHyper(q, timeframe, dctSql, limit)
+		klines, err_ = mapToKlines(rows, err_)
+	}
 	if err_ != nil {
 		return nil, NewDbErr(core.ErrDbReadFail, err_)
 	}
@@ -162,11 +174,19 @@ func (q *Queries) QueryOHLCVBatch(sids []int32, timeframe string, startMs, endMs
 select time,open,high,low,close,volume,info,sid from $tbl
 where time >= %v and time < %v and sid in (%v)
 order by sid,time`, startMs, finishEndMS, sidText)
-	subTF, rows, err_ := queryHyper(q, timeframe, dctSql, 0)
-	arrs, err_ := mapToItems(rows, err_, func() (*KlineSid, []any) {
-		var i KlineSid
-		return &i, []any{&i.Time, &i.Open, &i.High, &i.Low, &i.Close, &i.Volume, &i.Info, &i.Sid}
-	})
+	var subTF string
+	var arrs []*KlineSid
+	var err_ error
+	if kStore != nil {
+		subTF, arrs, err_ = queryStoreBatch(sids, timeframe, startMs, finishEndMS)
+	} else {
+		var rows pgx.Rows
+		subTF, rows, err_ = queryHyper(q, timeframe, dctSql, 0)
+		arrs, err_ = mapToItems(rows, err_, func() (*KlineSid, []any) {
+			var i KlineSid
+			return &i, []any{&i.Time, &i.Open, &i.High, &i.Low, &i.Close, &i.Volume, &i.Info, &i.Sid}
+		})
+	}
 	if err_ != nil {
 		return NewDbErr(core.ErrDbReadFail, err_)
 	}
@@ -218,6 +238,17 @@ order by sid,time`, startMs, finishEndMS, sidText)
 }
 
 func (q *Queries) getKLineTimes(sid int32, timeframe string, startMs, endMs int64) ([]int64, *errs.Error) {
+	if kStore != nil {
+		klines, err := kStore.QueryKLines(sid, timeframe, startMs, endMs, 0, false)
+		if err != nil {
+			return nil, err
+		}
+		resList := make([]int64, len(klines))
+		for i, k := range klines {
+			resList[i] = k.Time
+		}
+		return resList, nil
+	}
 	tblName := "kline_" + timeframe
 	dctSql := fmt.Sprintf(`
 select time from %s
@@ -282,6 +313,10 @@ func getSubTf(timeFrame string) (string, string, int) {
 }
 
 func (q *Queries) PurgeKlineUn() *errs.Error {
+	if kStore != nil {
+		delStoreUnFinish(0, "")
+		return nil
+	}
 	sql := "delete from kline_un"
 	return q.Exec(sql)
 }
@@ -308,13 +343,22 @@ func getUnFinish(sess *Queries, sid int32, timeFrame string, startMS, endMS int6
 		// Collect data from completed sub cycles
 		// 从已完成的子周期中归集数据
 		fromTF, _, _ = getSubTf(timeFrame)
-		aggFrom := "kline_" + fromTF
-		sql := fmt.Sprintf(`select time,open,high,low,close,volume,info from %s
+		var klines []*banexg.Kline
+		if kStore != nil {
+			var err *errs.Error
+			klines, err = kStore.QueryKLines(sid, fromTF, startMS, endMS, 0, false)
+			if err != nil {
+				return nil, 0, err
+			}
+		} else {
+			aggFrom := "kline_" + fromTF
+			sql := fmt.Sprintf(`select time,open,high,low,close,volume,info from %s
 where sid=%d and time >= %v and time < %v`, aggFrom, sid, startMS, endMS)
-		rows, err_ := sess.db.Query(ctx, sql)
-		klines, err_ := mapToKlines(rows, err_)
-		if err_ != nil {
-			return nil, 0, err_
+			rows, err_ := sess.db.Query(ctx, sql)
+			klines, err_ = mapToKlines(rows, err_)
+			if err_ != nil {
+				return nil, 0, err_
+			}
 		}
 		offMS := GetAlignOff(sid, tfMSecs)
 		bigKlines, _ = utils.BuildOHLCV(klines, tfMSecs, 0, nil, 0, offMS)
@@ -324,13 +368,18 @@ where sid=%d and time >= %v and time < %v`, aggFrom, sid, startMS, endMS)
 	}
 	// Querying data from unfinished cycles/sub cycles
 	// 从未完成的周期/子周期中查询数据
-	sql := fmt.Sprintf(`SELECT start_ms,open,high,low,close,volume,info,stop_ms FROM kline_un
-						where sid=%d and timeframe='%s' and start_ms >= %d
-						limit 1`, sid, fromTF, startMS)
-	row := sess.db.QueryRow(ctx, sql)
 	var unbar = &banexg.Kline{}
 	var unToMS = int64(0)
-	err_ := row.Scan(&unbar.Time, &unbar.Open, &unbar.High, &unbar.Low, &unbar.Close, &unbar.Volume, &unbar.Info, &unToMS)
+	var err_ error
+	if kStore != nil {
+		unbar, unToMS, err_ = getStoreUnFinish(sid, fromTF, startMS)
+	} else {
+		sql := fmt.Sprintf(`SELECT start_ms,open,high,low,close,volume,info,stop_ms FROM kline_un
+						where sid=%d and timeframe='%s' and start_ms >= %d
+						limit 1`, sid, fromTF, startMS)
+		row := sess.db.QueryRow(ctx, sql)
+		err_ = row.Scan(&unbar.Time, &unbar.Open, &unbar.High, &unbar.Low, &unbar.Close, &unbar.Volume, &unbar.Info, &unToMS)
+	}
 	if err_ != nil {
 		return nil, 0, err_
 	} else if unbar.Volume > 0 {
@@ -406,6 +455,10 @@ func calcUnFinish(sid int32, timeFrame, subTF string, startMS, endMS int64, arr
 }
 
 func updateUnFinish(sess *Queries, agg *KlineAgg, sid int32, subTF string, startMS, endMS int64, klines []*banexg.Kline) *errs.Error {
+	if kStore != nil {
+		updateStoreUnFinish(agg, sid, subTF, startMS, endMS, klines)
+		return nil
+	}
 	tfMSecs := int64(utils2.TFToSecs(agg.TimeFrame) * 1000)
 	finished := endMS%tfMSecs == 0
 	whereSql := fmt.Sprintf("where sid=%v and timeframe='%v';", sid, agg.TimeFrame)
@@ -516,6 +569,9 @@ func (q *Queries) InsertKLines(timeFrame string, sid int32, arr []*banexg.Kline,
 	startMS, endMS := arr[0].Time, arr[arrLen-1].Time+tfMSecs
 	log.Debug("insert klines", zap.String("tf", timeFrame), zap.Int32("sid", sid),
 		zap.Int("num", arrLen), zap.Int64("start", startMS), zap.Int64("end", endMS))
+	if kStore != nil {
+		return kStore.InsertKLines(sid, timeFrame, arr)
+	}
 	tblName := "kline_" + timeFrame
 	var adds = make([]*KlineSid, arrLen)
 	for i, v := range arr {
@@ -574,7 +630,7 @@ func (q *Queries) InsertKLinesAuto(timeFrame string, sid int32, arr []*banexg.Kl
 		return num, err
 	}
 	err = q.UpdateKRange(sid, timeFrame, startMS, endMS, arr, aggBig)
-	_ = q.DelInsKline(context.Background(), insId)
+	_ = q.delInsKline(context.Background(), insId)
 	return num, err
 }
 
@@ -614,6 +670,9 @@ Calculate the effective range of the specified period K-line within the specifie
 计算指定周期K线在指定范围内，有效区间。
 */
 func (q *Queries) CalcKLineRange(sid int32, timeFrame string, start, end int64) (int64, int64, *errs.Error) {
+	if kStore != nil {
+		return kStore.CalcKLineRange(sid, timeFrame, start, end)
+	}
 	tblName := "kline_" + timeFrame
 	sql := fmt.Sprintf("select min(time),max(time) from %s where sid=%v", tblName, sid)
 	if start > 0 {
@@ -641,6 +700,9 @@ func (q *Queries) CalcKLineRange(sid int32, timeFrame string, start, end int64)
 }
 
 func (q *Queries) CalcKLineRanges(timeFrame string, sids map[int32]bool) (map[int32][2]int64, *errs.Error) {
+	if kStore != nil {
+		return storeKLineRanges(timeFrame, sids)
+	}
 	tblName := "kline_" + timeFrame
 	if len(sids) > 0 {
 		var b strings.Builder
@@ -688,7 +750,7 @@ func (q *Queries) updateKLineRange(sid int32, timeFrame string, startMS, endMS i
 	realStart, realEnd, err := q.CalcKLineRange(sid, timeFrame, 0, 0)
 	if err != nil {
 		if startMS > 0 && endMS > 0 {
-			_, err_ = q.AddKInfo(ctx, AddKInfoParams{Sid: sid, Timeframe: timeFrame, Start: startMS, Stop: endMS})
+			_, err_ = q.addKInfo(ctx, AddKInfoParams{Sid: sid, Timeframe: timeFrame, Start: startMS, Stop: endMS})
 			if err_ != nil {
 				return NewDbErr(core.ErrDbExecFail, err_)
 			}
@@ -708,15 +770,15 @@ func (q *Queries) updateKLineRange(sid int32, timeFrame string, startMS, endMS i
 	}
 	oldStart, oldEnd := q.GetKlineRange(sid, timeFrame)
 	if oldStart == 0 && oldEnd == 0 {
-		_, err_ = q.AddKInfo(ctx, AddKInfoParams{Sid: sid, Timeframe: timeFrame, Start: realStart, Stop: realEnd})
+		_, err_ = q.addKInfo(ctx, AddKInfoParams{Sid: sid, Timeframe: timeFrame, Start: realStart, Stop: realEnd})
 	} else {
-		err_ = q.SetKInfo(ctx, SetKInfoParams{Sid: sid, Timeframe: timeFrame, Start: realStart, Stop: realEnd})
+		err_ = q.setKInfo(ctx, SetKInfoParams{Sid: sid, Timeframe: timeFrame, Start: realStart, Stop: realEnd})
 	}
 	if err_ != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err_, &pgErr) {
 			if pgErr.Code == "23505" {
-				err_ = q.SetKInfo(ctx, SetKInfoParams{Sid: sid, Timeframe: timeFrame, Start: realStart, Stop: realEnd})
+				err_ = q.setKInfo(ctx, SetKInfoParams{Sid: sid, Timeframe: timeFrame, Start: realStart, Stop: realEnd})
 			}
 		}
 		if err_ != nil {
@@ -834,7 +896,7 @@ func (q *Queries) updateKHoles(sid int32, timeFrame string, startMS, endMS int64
 	// Query the recorded kholes and merge them
 	// 查询已记录的khole，进行合并
 	ctx := context.Background()
-	resHoles, err_ := q.GetKHoles(ctx, GetKHolesParams{Sid: sid, Timeframe: timeFrame, Start: startMS, Stop: endMS})
+	resHoles, err_ := q.getKHoles(ctx, GetKHolesParams{Sid: sid, Timeframe: timeFrame, Start: startMS, Stop: endMS})
 	if err_ != nil {
 		return NewDbErr(core.ErrDbReadFail, err_)
 	}
@@ -898,14 +960,14 @@ func (q *Queries) updateKHoles(sid int32, timeFrame string, startMS, endMS int64
 		if h.ID == 0 {
 			adds = append(adds, AddKHolesParams{Sid: h.Sid, Timeframe: h.Timeframe, Start: h.Start, Stop: h.Stop, NoData: h.NoData})
 		} else {
-			err_ = q.SetKHole(ctx, SetKHoleParams{ID: h.ID, Start: h.Start, Stop: h.Stop, NoData: h.NoData})
+			err_ = q.setKHole(ctx, SetKHoleParams{ID: h.ID, Start: h.Start, Stop: h.Stop, NoData: h.NoData})
 			if err_ != nil {
 				return NewDbErr(core.ErrDbExecFail, err_)
 			}
 		}
 	}
 	if len(adds) > 0 {
-		_, err_ = q.AddKHoles(ctx, adds)
+		_, err_ = q.addKHoles(ctx, adds)
 		if err_ != nil {
 			return NewDbErr(core.ErrDbExecFail, err_)
 		}
@@ -992,18 +1054,25 @@ func (q *Queries) refreshAgg(item *KlineAgg, sid int32, orgStartMS, orgEndMS int
 	if aggFrom == "" {
 		aggFrom = item.AggFrom
 	}
-	tblName := "kline_" + aggFrom
-	sql := fmt.Sprintf(`
+	if kStore != nil {
+		err := refreshStoreAgg(item, sid, aggFrom, aggStart, endMS)
+		if err != nil {
+			return err
+		}
+	} else {
+		tblName := "kline_" + aggFrom
+		sql := fmt.Sprintf(`
 select sid,"time"/%d*%d as atime,%s
 from %s where sid=%d and time>=%v and time<%v
 GROUP BY sid, 2 
 ORDER BY sid, 2`, tfMSecs, tfMSecs, aggFields, tblName, sid, aggStart, endMS)
-	finalSql := fmt.Sprintf(`
+		finalSql := fmt.Sprintf(`
 insert into %s (sid, time, open, high, low, close, volume, info)
 %s %s`, item.Table, sql, klineInsConflict)
-	_, err_ := q.db.Exec(context.Background(), finalSql)
-	if err_ != nil {
-		return NewDbErr(core.ErrDbReadFail, err_)
+		_, err_ := q.db.Exec(context.Background(), finalSql)
+		if err_ != nil {
+			return NewDbErr(core.ErrDbReadFail, err_)
+		}
 	}
 	// Update the effective range of intervals
 	// 更新有效区间范围
@@ -1026,6 +1095,10 @@ func NewKlineAgg(TimeFrame, Table, AggFrom, AggStart, AggEnd, AggEvery, CpsBefor
 }
 
 func (q *Queries) GetKlineNum(sid int32, timeFrame string, start, end int64) int {
+	if kStore != nil {
+		klines, _ := kStore.QueryKLines(sid, timeFrame, start, end, 0, false)
+		return len(klines)
+	}
 	sql := fmt.Sprintf("select count(0) from kline_%s where sid=%v and time>=%v and time<%v",
 		timeFrame, sid, start, end)
 	row := q.db.QueryRow(context.Background(), sql)
@@ -1066,11 +1139,17 @@ func GetDownTF(timeFrame string) (string, *errs.Error) {
 }
 
 func (q *Queries) DelKInfo(sid int32, timeFrame string) *errs.Error {
+	if kStore != nil {
+		return kStore.DelKInfo(sid, timeFrame)
+	}
 	sql := fmt.Sprintf("delete from kinfo where sid=%v and timeframe=$1", sid)
 	return q.Exec(sql, timeFrame)
 }
 
 func (q *Queries) DelKLines(sid int32, timeFrame string, startMS, endMS int64) *errs.Error {
+	if kStore != nil {
+		return kStore.DelKLines(sid, timeFrame, startMS, endMS)
+	}
 	sql := fmt.Sprintf("delete from kline_%s where sid=%v", timeFrame, sid)
 	if startMS > 0 {
 		sql += fmt.Sprintf(" and time >= %v", startMS)
@@ -1082,6 +1161,10 @@ func (q *Queries) DelKLines(sid int32, timeFrame string, startMS, endMS int64) *
 }
 
 func (q *Queries) GetKlineRange(sid int32, timeFrame string) (int64, int64) {
+	if kStore != nil {
+		res := q.GetKlineRanges([]int32{sid}, timeFrame)[sid]
+		return res[0], res[1]
+	}
 	sql := fmt.Sprintf("select start,stop from kinfo where sid=%v and timeframe=$1 limit 1", sid)
 	row := q.db.QueryRow(context.Background(), sql, timeFrame)
 	var start, stop int64
@@ -1090,6 +1173,21 @@ func (q *Queries) GetKlineRange(sid int32, timeFrame string) (int64, int64) {
 }
 
 func (q *Queries) GetKlineRanges(sidList []int32, timeFrame string) map[int32][2]int64 {
+	if kStore != nil {
+		res := make(map[int32][2]int64)
+		for _, sid := range sidList {
+			infos, err := kStore.ListKInfos(sid)
+			if err != nil {
+				continue
+			}
+			for _, info := range infos {
+				if info.Timeframe == timeFrame {
+					res[sid] = [2]int64{info.Start, info.Stop}
+				}
+			}
+		}
+		return res
+	}
 	var texts = make([]string, len(sidList))
 	for i, sid := range sidList {
 		texts[i] = fmt.Sprintf("%v", sid)
@@ -1115,6 +1213,9 @@ func (q *Queries) GetKlineRanges(sidList []int32, timeFrame string) map[int32][2
 }
 
 func (q *Queries) DelFactors(sid int32, startMS, endMS int64) *errs.Error {
+	if kStore != nil {
+		return kStore.DelAdjFactors(sid, startMS, endMS, true)
+	}
 	sql := fmt.Sprintf("delete from adj_factors where sid=%v or sub_id=%v", sid, sid)
 	if startMS > 0 {
 		sql += fmt.Sprintf(" and start_ms >= %v", startMS)
@@ -1126,11 +1227,28 @@ func (q *Queries) DelFactors(sid int32, startMS, endMS int64) *errs.Error {
 }
 
 func (q *Queries) DelKLineUn(sid int32, timeFrame string) *errs.Error {
+	if kStore != nil {
+		delStoreUnFinish(sid, timeFrame)
+		return nil
+	}
 	sql := fmt.Sprintf("delete from kline_un where sid=%v and timeframe=$1", sid)
 	return q.Exec(sql, timeFrame)
 }
 
 func (q *Queries) DelKHoles(sid int32, timeFrame string, startMS, endMS int64) *errs.Error {
+	if kStore != nil {
+		holes, err := kStore.ListKHoles(sid)
+		if err != nil {
+			return err
+		}
+		var ids []int64
+		for _, h := range holes {
+			if h.Timeframe == timeFrame && (startMS <= 0 || h.Start >= startMS) && (endMS <= 0 || h.Stop <= endMS) {
+				ids = append(ids, h.ID)
+			}
+		}
+		return q.DelKHoleIDs(ids...)
+	}
 	sql := fmt.Sprintf("delete from khole where sid=%v and timeframe=$1", sid)
 	if startMS > 0 {
 		sql += fmt.Sprintf(" and start >= %v", startMS)
@@ -1145,6 +1263,9 @@ func (q *Queries) DelKHoleIDs(ids ...int64) *errs.Error {
 	if len(ids) == 0 {
 		return nil
 	}
+	if kStore != nil {
+		return kStore.DelKHoles(ids...)
+	}
 	var builder strings.Builder
 	builder.WriteString("delete from khole where id in (")
 	arr := make([]string, len(ids))
@@ -1180,6 +1301,10 @@ FixKInfoZeros
 修复kinfo表中start=0或stop=0的记录。通过查询实际K线数据范围来更新正确的start和stop值。
 */
 func (q *Queries) FixKInfoZeros() *errs.Error {
+	if kStore != nil {
+		// kinfo of kline store is always set with the real range 使用K线存储时kinfo总是按实际区间设置
+		return nil
+	}
 	// 查询所有stop=0或start=0的记录
 	sql := `SELECT sid, timeframe FROM kinfo WHERE stop = 0 or start = 0`
 	rows, err_ := q.db.Query(context.Background(), sql)
@@ -1327,7 +1452,7 @@ type KHoleExt struct {
 func tryFillHoles(sess *Queries, sids map[int32]bool, prg utils.PrgCB) *errs.Error {
 	ctx := context.Background()
 	sidList := utils2.KeysOfMap(sids)
-	holes, err_ := sess.ListKHoles(ctx, sidList)
+	holes, err_ := sess.listKHoles(ctx, sidList)
 	if err_ != nil {
 		return NewDbErr(core.ErrDbReadFail, err_)
 	}
@@ -1445,7 +1570,7 @@ func tryFillHoles(sess *Queries, sids map[int32]bool, prg utils.PrgCB) *errs.Err
 		if start != row.Start || stop != row.Stop {
 			// 此区间被更新
 			editNum += 1
-			err_ = sess.SetKHole(ctx, SetKHoleParams{ID: row.ID, Start: start, Stop: stop})
+			err_ = sess.setKHole(ctx, SetKHoleParams{ID: row.ID, Start: start, Stop: stop})
 			if err_ != nil {
 				return NewDbErr(core.ErrDbExecFail, err_)
 			}
@@ -1471,7 +1596,7 @@ func tryFillHoles(sess *Queries, sids map[int32]bool, prg utils.PrgCB) *errs.Err
 				NoData:    true,
 			}
 		}
-		_, err_ = sess.AddKHoles(ctx, items)
+		_, err_ = sess.addKHoles(ctx, items)
 		if err_ != nil {
 			return NewDbErr(core.ErrDbExecFail, err_)
 		}
@@ -1487,7 +1612,7 @@ type KInfoExt struct {
 }
 
 func syncKlineInfos(sess *Queries, sids map[int32]bool, prg utils.PrgCB) *errs.Error {
-	infos, err_ := sess.ListKInfos(context.Background())
+	infos, err_ := sess.listKInfos(context.Background())
 	if err_ != nil {
 		return NewDbErr(core.ErrDbExecFail, err_)
 	}
@@ -1575,14 +1700,14 @@ func (q *Queries) syncKlineSid(sid int32, tfMap map[string]*KInfoExt, calcs map[
 			newStart, newEnd := ranges[0], ranges[1]
 			err_ = nil
 			if oldStart == 0 && oldEnd == 0 {
-				_, err_ = q.AddKInfo(ctx, AddKInfoParams{
+				_, err_ = q.addKInfo(ctx, AddKInfoParams{
 					Sid:       sid,
 					Timeframe: agg.TimeFrame,
 					Start:     newStart,
 					Stop:      newEnd,
 				})
 			} else if newStart != oldStart || oldEnd != newEnd {
-				err_ = q.SetKInfo(ctx, SetKInfoParams{
+				err_ = q.setKInfo(ctx, SetKInfoParams{
 					Sid:       sid,
 					Timeframe: agg.TimeFrame,
 					Start:     newStart,
@@ -1647,6 +1772,9 @@ Update unfinished insertion tasks and call them when the robot starts,
 更新未完成的插入任务，在机器人启动时调用，
 */
 func (q *Queries) UpdatePendingIns() *errs.Error {
+	if kStore != nil {
+		return nil
+	}
 	if utils.HasBanConn() {
 		lockVal, err := utils.GetNetLock("UpdatePendingIns", 10)
 		if err != nil {
@@ -1685,6 +1813,10 @@ func (q *Queries) UpdatePendingIns() *errs.Error {
 }
 
 func (q *Queries) AddInsJob(add AddInsKlineParams) (int32, *errs.Error) {
+	if kStore != nil {
+		// insert of a series is serialized by kline store 同一序列的插入由K线存储串行化
+		return 1, nil
+	}
 	ctx := context.Background()
 	ins, err_ := q.GetInsKline(ctx, add.Sid)
 	if err_ != nil && !errors.Is(err_, pgx.ErrNoRows) {
@@ -1840,7 +1972,7 @@ func saveAdjFactors(data map[int64]map[int32]*banexg.Kline, pCode string, pExs *
 	// Delete the old main continuous contract compounding factor
 	// 删除旧的主力连续合约复权因子
 	ctx := context.Background()
-	err_ := sess.DelAdjFactors(ctx, exs.ID)
+	err_ := sess.delAdjFactors(ctx, exs.ID)
 	if err_ != nil {
 		return NewDbErr(core.ErrDbExecFail, err_)
 	}
@@ -1903,7 +2035,7 @@ func saveAdjFactors(data map[int64]map[int32]*banexg.Kline, pCode string, pExs *
 	}
 	outPath := filepath.Join(outDir, exs.Symbol+"_adjs.txt")
 	_ = utils2.WriteFile(outPath, []byte(strings.Join(lines, "\n")))
-	_, err_ = sess.AddAdjFactors(ctx, adds)
+	_, err_ = sess.addAdjFactors(ctx, adds)
 	if err_ != nil {
 		return NewDbErr(core.ErrDbExecFail, err_)
 	}
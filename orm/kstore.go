@@ -0,0 +1,463 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/utils"
+	"github.com/banbox/banexg"
+	"github.com/banbox/banexg/errs"
+	"github.com/banbox/banexg/log"
+	utils2 "github.com/banbox/banexg/utils"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+/*
+KlineStore
+Storage of klines and related data, used instead of timescaledb when `database.kline_store` is set.
+Only saved timeframes (1m,5m,15m,1h,1d) are passed in, aggregation, kinfo range and khole calculation are
+done by Queries, so all download/aggregation/export/import/backtest paths work the same for each store.
+K线及相关数据的存储，设置`database.kline_store`时替代timescaledb。
+只会传入保存的周期(1m,5m,15m,1h,1d)，聚合、kinfo区间和khole计算由Queries完成，故下载/聚合/导出/导入/回测对每种存储都相同。
+*/
+type KlineStore interface {
+	// QueryKLines bars in [startMS, endMS) ordered by time, latest `limit` bars when rev is true 返回[startMS, endMS)内按时间升序的bar，rev为true时取最新的limit个
+	QueryKLines(sid int32, timeFrame string, startMS, endMS int64, limit int, rev bool) ([]*banexg.Kline, *errs.Error)
+	// InsertKLines insert bars, replace existing bars with the same time 插入bar，替换已有的相同时间的bar
+	InsertKLines(sid int32, timeFrame string, arr []*banexg.Kline) (int64, *errs.Error)
+	DelKLines(sid int32, timeFrame string, startMS, endMS int64) *errs.Error
+	// CalcKLineRange the real range [start, stop) of bars, 0 if no data 计算bar的实际区间，无数据时为0
+	CalcKLineRange(sid int32, timeFrame string, startMS, endMS int64) (int64, int64, *errs.Error)
+	// KLineSids all sids with bars of timeFrame 有此周期bar的所有sid
+	KLineSids(timeFrame string) ([]int32, *errs.Error)
+
+	// ListKInfos kinfo of sid, all when sid is 0 sid的kinfo，sid为0时返回全部
+	ListKInfos(sid int32) ([]*KInfo, *errs.Error)
+	SetKInfo(arg SetKInfoParams) *errs.Error
+	DelKInfo(sid int32, timeFrame string) *errs.Error
+	ListKHoles(sids ...int32) ([]*KHole, *errs.Error)
+	AddKHoles(args []AddKHolesParams) *errs.Error
+	SetKHole(arg SetKHoleParams) *errs.Error
+	DelKHoles(ids ...int64) *errs.Error
+
+	// GetAdjFactors factors of sid ordered by start_ms 按start_ms排序的sid复权因子
+	GetAdjFactors(sid int32) ([]*AdjFactor, *errs.Error)
+	AddAdjFactors(args []AddAdjFactorsParams) *errs.Error
+	// DelAdjFactors delete factors with start_ms in [startMS, endMS), also match sub_id when withSub 删除start_ms在区间内的因子，withSub时也匹配sub_id
+	DelAdjFactors(sid int32, startMS, endMS int64, withSub bool) *errs.Error
+	GetFundingRates(sid int32, startMS, endMS int64) ([]*FundingRate, *errs.Error)
+	// SetFundingRates replace funding rates in the time range of items 替换items时间范围内的资金费率
+	SetFundingRates(sid int32, items []*FundingRate) *errs.Error
+
+	// ListSymbols symbols of exchange, all when exchange is empty 交易所的标的，exchange为空时返回全部
+	ListSymbols(exchange string) ([]*ExSymbol, *errs.Error)
+	AddSymbols(args []AddSymbolsParams) *errs.Error
+	SetListMS(arg SetListMSParams) *errs.Error
+	GetCalendars(name string, startMS, stopMS int64) ([][2]int64, *errs.Error)
+	// SetCalendars save trade days, overlapped old items are merged 保存交易日，重叠的旧记录被合并
+	SetCalendars(name string, items [][2]int64) *errs.Error
+	Close() *errs.Error
+}
+
+type FuncNewKlineStore = func(cfg *config.DatabaseConfig) (KlineStore, *errs.Error)
+
+var (
+	// KlineStores available values of `database.kline_store`, empty for timescaledb 可用的`database.kline_store`值，空为timescaledb
+	KlineStores = map[string]FuncNewKlineStore{
+		"file": NewFileKStore,
+	}
+	kStore KlineStore
+
+	// unfinished bars are kept in memory for kline stores 使用K线存储时，未完成bar保存在内存中
+	storeUnBars = make(map[string]*KlineUn)
+	storeUnLock sync.Mutex
+)
+
+/*
+setupKStore
+Init the kline store if `database.kline_store` is set, returns false for timescaledb
+如果设置了`database.kline_store`则初始化K线存储，timescaledb时返回false
+*/
+func setupKStore() (bool, *errs.Error) {
+	if kStore != nil {
+		_ = kStore.Close()
+		kStore = nil
+	}
+	dbCfg := config.Database
+	if dbCfg == nil || dbCfg.KlineStore == "" {
+		return false, nil
+	}
+	newFn, ok := KlineStores[dbCfg.KlineStore]
+	if !ok {
+		return false, errs.NewMsg(core.ErrBadConfig, "unknown database.kline_store: %s", dbCfg.KlineStore)
+	}
+	var err *errs.Error
+	kStore, err = newFn(dbCfg)
+	return true, err
+}
+
+// GetKlineStore current kline store, nil for timescaledb 当前K线存储，timescaledb时为nil
+func GetKlineStore() KlineStore {
+	return kStore
+}
+
+/*
+queryStoreKLines
+Query bars from kline store in the same order as queryHyper, timeframes not saved are returned as sub timeframe.
+从K线存储查询bar，顺序与queryHyper一致，未保存的周期返回子周期bar
+*/
+func queryStoreKLines(sid int32, timeFrame string, startMS, endMS int64, limit int, rev bool) (string, []*banexg.Kline, error) {
+	subTF, fromTF := "", timeFrame
+	if _, ok := aggMap[timeFrame]; !ok {
+		var rate int
+		subTF, _, rate = getSubTf(timeFrame)
+		fromTF = subTF
+		if limit > 0 && rate > 1 {
+			limit = rate * (limit + 1)
+		}
+	}
+	if fromTF == "" {
+		return "", nil, fmt.Errorf("unsupported timeframe: %s", timeFrame)
+	}
+	klines, err := kStore.QueryKLines(sid, fromTF, startMS, endMS, limit, rev)
+	if err != nil {
+		return "", nil, err
+	}
+	if rev {
+		// same as `order by time desc` 与`order by time desc`一致
+		utils.ReverseArr(klines)
+	}
+	return subTF, klines, nil
+}
+
+func queryStoreBatch(sids []int32, timeFrame string, startMS, endMS int64) (string, []*KlineSid, error) {
+	var res []*KlineSid
+	var subTF string
+	for _, sid := range slices.Sorted(slices.Values(sids)) {
+		tf, klines, err := queryStoreKLines(sid, timeFrame, startMS, endMS, 0, false)
+		if err != nil {
+			return "", nil, err
+		}
+		subTF = tf
+		for _, k := range klines {
+			res = append(res, &KlineSid{Kline: *k, Sid: sid})
+		}
+	}
+	return subTF, res, nil
+}
+
+func storeKLineRanges(timeFrame string, sids map[int32]bool) (map[int32][2]int64, *errs.Error) {
+	var sidList []int32
+	if len(sids) > 0 {
+		sidList = utils2.KeysOfMap(sids)
+	} else {
+		var err *errs.Error
+		sidList, err = kStore.KLineSids(timeFrame)
+		if err != nil {
+			return nil, err
+		}
+	}
+	res := make(map[int32][2]int64)
+	for _, sid := range sidList {
+		start, stop, err := kStore.CalcKLineRange(sid, timeFrame, 0, 0)
+		if err != nil {
+			return res, err
+		}
+		if stop > 0 {
+			res[sid] = [2]int64{start, stop}
+		}
+	}
+	return res, nil
+}
+
+/*
+refreshStoreAgg
+Aggregate bars of aggFrom in [startMS, endMS) into item.TimeFrame, same as the insert-select sql of refreshAgg
+将aggFrom在[startMS, endMS)内的bar聚合到item.TimeFrame，与refreshAgg的insert-select语句一致
+*/
+func refreshStoreAgg(item *KlineAgg, sid int32, aggFrom string, startMS, endMS int64) *errs.Error {
+	klines, err := kStore.QueryKLines(sid, aggFrom, startMS, endMS, 0, false)
+	if err != nil || len(klines) == 0 {
+		return err
+	}
+	fromTfMSecs := int64(utils2.TFToSecs(aggFrom) * 1000)
+	bigs, _ := utils.BuildOHLCV(klines, item.MSecs, 0, nil, fromTfMSecs, 0)
+	_, err = kStore.InsertKLines(sid, item.TimeFrame, bigs)
+	return err
+}
+
+func storeUnKey(sid int32, timeFrame string) string {
+	return fmt.Sprintf("%d_%s", sid, timeFrame)
+}
+
+/*
+getStoreUnFinish
+Query unfinished bar from memory, same as the kline_un query in getUnFinish
+从内存查询未完成bar，与getUnFinish中kline_un的查询一致
+*/
+func getStoreUnFinish(sid int32, timeFrame string, startMS int64) (*banexg.Kline, int64, error) {
+	storeUnLock.Lock()
+	defer storeUnLock.Unlock()
+	un, ok := storeUnBars[storeUnKey(sid, timeFrame)]
+	if !ok || un.StartMs < startMS {
+		return nil, 0, pgx.ErrNoRows
+	}
+	return &banexg.Kline{Time: un.StartMs, Open: un.Open, High: un.High, Low: un.Low, Close: un.Close,
+		Volume: un.Volume, Info: un.Info}, un.StopMs, nil
+}
+
+// updateStoreUnFinish same as updateUnFinish, but save in memory 与updateUnFinish一致，但保存在内存中
+func updateStoreUnFinish(agg *KlineAgg, sid int32, subTF string, startMS, endMS int64, klines []*banexg.Kline) {
+	tfMSecs := int64(utils2.TFToSecs(agg.TimeFrame) * 1000)
+	key := storeUnKey(sid, agg.TimeFrame)
+	storeUnLock.Lock()
+	defer storeUnLock.Unlock()
+	if endMS%tfMSecs == 0 {
+		delete(storeUnBars, key)
+		return
+	}
+	if len(klines) == 0 {
+		log.Warn("skip unFinish for empty", zap.Int64("s", startMS), zap.Int64("e", endMS))
+		return
+	}
+	sub := calcUnFinish(sid, agg.TimeFrame, subTF, startMS, endMS, klines)
+	if sub == nil {
+		delete(storeUnBars, key)
+		return
+	}
+	barStartMS := utils2.AlignTfMSecs(startMS, tfMSecs)
+	barEndMS := utils2.AlignTfMSecs(endMS, tfMSecs)
+	if old, ok := storeUnBars[key]; ok && barStartMS == barEndMS && old.StopMs == startMS {
+		old.High = max(old.High, sub.High)
+		old.Low = min(old.Low, sub.Low)
+		old.Close = sub.Close
+		old.Volume += sub.Volume
+		old.StopMs = endMS
+		return
+	}
+	sub.StopMs = endMS
+	storeUnBars[key] = sub
+}
+
+func delStoreUnFinish(sid int32, timeFrame string) {
+	storeUnLock.Lock()
+	if sid == 0 {
+		storeUnBars = make(map[string]*KlineUn)
+	} else {
+		delete(storeUnBars, storeUnKey(sid, timeFrame))
+	}
+	storeUnLock.Unlock()
+}
+
+func findStoreKHoles(args FindKHolesArgs) ([]*KHole, int64, *errs.Error) {
+	holes, err := kStore.ListKHoles(args.Sid)
+	if err != nil {
+		return nil, 0, err
+	}
+	holes = slices.DeleteFunc(holes, func(h *KHole) bool {
+		return args.TimeFrame != "" && h.Timeframe != args.TimeFrame || args.Start > 0 && h.Start < args.Start ||
+			args.Stop > 0 && h.Stop > args.Stop
+	})
+	slices.SortFunc(holes, func(a, b *KHole) int {
+		return compareInt(b.Start, a.Start)
+	})
+	total := int64(len(holes))
+	limit := 100
+	if args.Limit > 0 {
+		limit = args.Limit
+	}
+	start := min(max(args.Offset, 0), len(holes))
+	return holes[start:min(start+limit, len(holes))], total, nil
+}
+
+/** ********************************** route sqlc queries to kline store ******************************** */
+
+func (q *Queries) addKInfo(ctx context.Context, arg AddKInfoParams) (*KInfo, error) {
+	if kStore != nil {
+		err := kStore.SetKInfo(SetKInfoParams(arg))
+		if err != nil {
+			return nil, err
+		}
+		return &KInfo{Sid: arg.Sid, Timeframe: arg.Timeframe, Start: arg.Start, Stop: arg.Stop}, nil
+	}
+	return q.AddKInfo(ctx, arg)
+}
+
+func (q *Queries) setKInfo(ctx context.Context, arg SetKInfoParams) error {
+	if kStore != nil {
+		return errOrNil(kStore.SetKInfo(arg))
+	}
+	return q.SetKInfo(ctx, arg)
+}
+
+func (q *Queries) listKInfos(ctx context.Context) ([]*KInfo, error) {
+	if kStore != nil {
+		return valOrErr(kStore.ListKInfos(0))
+	}
+	return q.ListKInfos(ctx)
+}
+
+/*
+GetKInfos
+Get the kline ranges of all timeframes for sid
+获取sid所有周期的K线区间
+*/
+func (q *Queries) GetKInfos(sid int32) ([]*KInfo, *errs.Error) {
+	if kStore != nil {
+		return kStore.ListKInfos(sid)
+	}
+	res, err_ := q.FindKInfos(context.Background(), sid)
+	if err_ != nil {
+		return nil, NewDbErr(core.ErrDbReadFail, err_)
+	}
+	return res, nil
+}
+
+func (q *Queries) delInsKline(ctx context.Context, id int32) error {
+	if kStore != nil {
+		return nil
+	}
+	return q.DelInsKline(ctx, id)
+}
+
+func (q *Queries) getKHoles(ctx context.Context, arg GetKHolesParams) ([]*KHole, error) {
+	if kStore != nil {
+		holes, err := kStore.ListKHoles(arg.Sid)
+		if err != nil {
+			return nil, err
+		}
+		return slices.DeleteFunc(holes, func(h *KHole) bool {
+			return h.Timeframe != arg.Timeframe || h.Start < arg.Start || h.Stop > arg.Stop
+		}), nil
+	}
+	return q.GetKHoles(ctx, arg)
+}
+
+func (q *Queries) listKHoles(ctx context.Context, sids []int32) ([]*KHole, error) {
+	if kStore != nil {
+		return valOrErr(kStore.ListKHoles(sids...))
+	}
+	return q.ListKHoles(ctx, sids)
+}
+
+func (q *Queries) addKHoles(ctx context.Context, args []AddKHolesParams) (int64, error) {
+	if kStore != nil {
+		return int64(len(args)), errOrNil(kStore.AddKHoles(args))
+	}
+	return q.AddKHoles(ctx, args)
+}
+
+func (q *Queries) setKHole(ctx context.Context, arg SetKHoleParams) error {
+	if kStore != nil {
+		return errOrNil(kStore.SetKHole(arg))
+	}
+	return q.SetKHole(ctx, arg)
+}
+
+func (q *Queries) getAdjFactors(ctx context.Context, sid int32) ([]*AdjFactor, error) {
+	if kStore != nil {
+		return valOrErr(kStore.GetAdjFactors(sid))
+	}
+	return q.GetAdjFactors(ctx, sid)
+}
+
+/*
+GetSidAdjFactors
+Get the adjustment factors of sid ordered by start_ms
+获取sid按start_ms排序的复权因子
+*/
+func (q *Queries) GetSidAdjFactors(sid int32) ([]*AdjFactor, *errs.Error) {
+	res, err_ := q.getAdjFactors(context.Background(), sid)
+	if err_ != nil {
+		return nil, NewDbErr(core.ErrDbReadFail, err_)
+	}
+	return res, nil
+}
+
+func (q *Queries) addAdjFactors(ctx context.Context, args []AddAdjFactorsParams) (int64, error) {
+	if kStore != nil {
+		return int64(len(args)), errOrNil(kStore.AddAdjFactors(args))
+	}
+	return q.AddAdjFactors(ctx, args)
+}
+
+func (q *Queries) delAdjFactors(ctx context.Context, sid int32) error {
+	if kStore != nil {
+		return errOrNil(kStore.DelAdjFactors(sid, 0, 0, false))
+	}
+	return q.DelAdjFactors(ctx, sid)
+}
+
+func (q *Queries) listSymbols(ctx context.Context, exchange string) ([]*ExSymbol, error) {
+	if kStore != nil {
+		return valOrErr(kStore.ListSymbols(exchange))
+	}
+	return q.ListSymbols(ctx, exchange)
+}
+
+func (q *Queries) listExchanges(ctx context.Context) ([]string, error) {
+	if kStore != nil {
+		items, err := kStore.ListSymbols("")
+		if err != nil {
+			return nil, err
+		}
+		res := make([]string, 0, 4)
+		for _, exs := range items {
+			if !slices.Contains(res, exs.Exchange) {
+				res = append(res, exs.Exchange)
+			}
+		}
+		return res, nil
+	}
+	return q.ListExchanges(ctx)
+}
+
+func (q *Queries) addSymbols(ctx context.Context, args []AddSymbolsParams) (int64, error) {
+	if kStore != nil {
+		return int64(len(args)), errOrNil(kStore.AddSymbols(args))
+	}
+	return q.AddSymbols(ctx, args)
+}
+
+func (q *Queries) setListMS(ctx context.Context, arg SetListMSParams) error {
+	if kStore != nil {
+		return errOrNil(kStore.SetListMS(arg))
+	}
+	return q.SetListMS(ctx, arg)
+}
+
+func (q *Queries) addCalendars(ctx context.Context, args []AddCalendarsParams) (int64, error) {
+	if kStore != nil {
+		groups := make(map[string][][2]int64)
+		for _, a := range args {
+			groups[a.Name] = append(groups[a.Name], [2]int64{a.StartMs, a.StopMs})
+		}
+		for name, items := range groups {
+			slices.SortFunc(items, func(a, b [2]int64) int {
+				return compareInt(a[0], b[0])
+			})
+			if err := kStore.SetCalendars(name, items); err != nil {
+				return 0, err
+			}
+		}
+		return int64(len(args)), nil
+	}
+	return q.AddCalendars(ctx, args)
+}
+
+// errOrNil avoid non-nil error interface holding a nil *errs.Error 避免error接口持有nil的*errs.Error
+func errOrNil(err *errs.Error) error {
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func valOrErr[T any](val T, err *errs.Error) (T, error) {
+	return val, errOrNil(err)
+}
@@ -0,0 +1,886 @@
+package orm
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banexg"
+	"github.com/banbox/banexg/errs"
+	"github.com/banbox/banexg/log"
+	utils2 "github.com/banbox/banexg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	fileBarSize    = 56      // bytes of a bar: time + 6 float64 columns 单个bar字节数：time + 6个float64列
+	fileBlockBars  = 2000    // max bars of a block 单个数据块最大bar数
+	fileMinGarbage = 1 << 20 // compact only when garbage exceeds this size 垃圾超过此大小时才压缩
+)
+
+/*
+FileKStore
+Embedded kline store without database. Each sid has a directory, bars of each timeframe are saved in an append-only
+data file `{tf}.dat` as columnar blocks (time, open, high, low, close, volume, info), `{tf}.idx` records the range and
+offset of valid blocks ordered by time. Replaced blocks become garbage, and are compacted when larger than valid data.
+kinfo, kholes, adj factors and funding rates of a sid are saved in `{sid}/meta.json`, symbols and calendars
+in the root `meta.json`.
+无需数据库的嵌入式K线存储。每个sid一个目录，每个周期的bar以列式数据块保存在只追加的数据文件`{tf}.dat`中
+（time, open, high, low, close, volume, info），`{tf}.idx`按时间顺序记录有效数据块的范围和偏移。被替换的数据块成为垃圾，
+超过有效数据时进行压缩。sid的kinfo、kholes、复权因子和资金费率保存在`{sid}/meta.json`，标的和交易日历保存在根目录`meta.json`。
+*/
+type FileKStore struct {
+	dir    string
+	lock   sync.Mutex
+	root   *fileRootMeta
+	sids   map[int32]*fileSidMeta
+	series map[string]*fileSeries
+}
+
+type fileRootMeta struct {
+	Symbols   []*ExSymbol           `json:"symbols"`
+	Calendars map[string][][2]int64 `json:"calendars"`
+}
+
+type fileSidMeta struct {
+	KInfos   map[string][2]int64 `json:"kinfos"`
+	Holes    []*KHole            `json:"holes"`
+	Adjs     []*AdjFactor        `json:"adjs"`
+	Fundings []*FundingRate      `json:"fundings"`
+	NextID   int64               `json:"next_id"`
+}
+
+// fileSeries bars of a sid and timeframe 一个sid和周期的bar数据
+type fileSeries struct {
+	lock   sync.Mutex
+	path   string // path without ext 不含扩展名的路径
+	blocks []*fileBlock
+	size   int64 // size of data file 数据文件大小
+	loaded bool
+}
+
+type fileBlock struct {
+	Off   int64 `json:"off"`
+	Num   int   `json:"num"`
+	Start int64 `json:"start"` // time of first bar 第一个bar时间
+	End   int64 `json:"end"`   // time of last bar 最后一个bar时间
+}
+
+func NewFileKStore(cfg *config.DatabaseConfig) (KlineStore, *errs.Error) {
+	dir := cfg.KlineDir
+	if dir == "" {
+		dir = filepath.Join(config.GetDataDir(), "kstore")
+	}
+	dir = config.ParsePath(dir)
+	if err_ := os.MkdirAll(dir, 0755); err_ != nil {
+		return nil, errs.New(core.ErrIOWriteFail, err_)
+	}
+	s := &FileKStore{
+		dir:    dir,
+		root:   &fileRootMeta{Calendars: make(map[string][][2]int64)},
+		sids:   make(map[int32]*fileSidMeta),
+		series: make(map[string]*fileSeries),
+	}
+	if err := readJSON(filepath.Join(dir, "meta.json"), s.root); err != nil {
+		return nil, err
+	}
+	if s.root.Calendars == nil {
+		s.root.Calendars = make(map[string][][2]int64)
+	}
+	log.Info("use file kline store", zap.String("dir", dir))
+	return s, nil
+}
+
+func (s *FileKStore) getSeries(sid int32, timeFrame string) (*fileSeries, *errs.Error) {
+	key := fmt.Sprintf("%d_%s", sid, timeFrame)
+	s.lock.Lock()
+	ser, ok := s.series[key]
+	if !ok {
+		ser = &fileSeries{path: filepath.Join(s.dir, strconv.Itoa(int(sid)), timeFrame)}
+		s.series[key] = ser
+	}
+	s.lock.Unlock()
+	ser.lock.Lock()
+	defer ser.lock.Unlock()
+	if !ser.loaded {
+		if err := readJSON(ser.path+".idx", &ser.blocks); err != nil {
+			return nil, err
+		}
+		if stat, err_ := os.Stat(ser.path + ".dat"); err_ == nil {
+			ser.size = stat.Size()
+		}
+		ser.loaded = true
+	}
+	return ser, nil
+}
+
+func (s *FileKStore) QueryKLines(sid int32, timeFrame string, startMS, endMS int64, limit int, rev bool) ([]*banexg.Kline, *errs.Error) {
+	ser, err := s.getSeries(sid, timeFrame)
+	if err != nil {
+		return nil, err
+	}
+	ser.lock.Lock()
+	defer ser.lock.Unlock()
+	if endMS <= 0 {
+		endMS = math.MaxInt64
+	}
+	res := make([]*banexg.Kline, 0, max(limit, 16))
+	if rev {
+		for i := len(ser.blocks) - 1; i >= 0 && (limit <= 0 || len(res) < limit); i-- {
+			b := ser.blocks[i]
+			if b.Start >= endMS {
+				continue
+			}
+			if b.End < startMS {
+				break
+			}
+			bars, err := ser.readBlock(b)
+			if err != nil {
+				return nil, err
+			}
+			for j := len(bars) - 1; j >= 0 && (limit <= 0 || len(res) < limit); j-- {
+				if t := bars[j].Time; t >= startMS && t < endMS {
+					res = append(res, bars[j])
+				}
+			}
+		}
+		slices.Reverse(res)
+		return res, nil
+	}
+	for _, b := range ser.blocks {
+		if b.End < startMS {
+			continue
+		}
+		if b.Start >= endMS || limit > 0 && len(res) >= limit {
+			break
+		}
+		bars, err := ser.readBlock(b)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range bars {
+			if k.Time >= startMS && k.Time < endMS {
+				res = append(res, k)
+				if limit > 0 && len(res) >= limit {
+					break
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+/*
+InsertKLines
+Bars with the same time are replaced. Non-overlapping bars are appended as new blocks, blocks overlapping with
+arr are merged and appended, the small block before arr is merged in place when it's at the end of file.
+相同时间的bar会被替换。不重叠的bar作为新块追加，与arr重叠的块合并后追加，arr前的小块位于文件末尾时原地合并。
+*/
+func (s *FileKStore) InsertKLines(sid int32, timeFrame string, arr []*banexg.Kline) (int64, *errs.Error) {
+	if len(arr) == 0 {
+		return 0, nil
+	}
+	ser, err := s.getSeries(sid, timeFrame)
+	if err != nil {
+		return 0, err
+	}
+	ser.lock.Lock()
+	defer ser.lock.Unlock()
+	if err_ := os.MkdirAll(filepath.Dir(ser.path), 0755); err_ != nil {
+		return 0, errs.New(core.ErrIOWriteFail, err_)
+	}
+	bars := slices.Clone(arr)
+	slices.SortStableFunc(bars, func(a, b *banexg.Kline) int {
+		return compareInt(a.Time, b.Time)
+	})
+	start, end := bars[0].Time, bars[len(bars)-1].Time
+	var olds []*banexg.Kline
+	keeps := make([]*fileBlock, 0, len(ser.blocks))
+	var drops []*fileBlock
+	for i, b := range ser.blocks {
+		overlap := b.End >= start && b.Start <= end
+		// merge small block just before arr 合并arr之前紧邻的小块
+		isPrev := b.End < start && (i+1 == len(ser.blocks) || ser.blocks[i+1].Start > end) &&
+			b.Num+len(bars) <= fileBlockBars
+		if !overlap && !isPrev {
+			keeps = append(keeps, b)
+			continue
+		}
+		items, err := ser.readBlock(b)
+		if err != nil {
+			return 0, err
+		}
+		olds = append(olds, items...)
+		drops = append(drops, b)
+	}
+	merged := mergeBars(olds, bars)
+	ser.blocks = keeps
+	if len(drops) == 1 && drops[0].Off+int64(drops[0].Num*fileBarSize) == ser.size {
+		// rewrite the last block of file in place 原地重写文件最后一个块
+		ser.size = drops[0].Off
+	}
+	if err = ser.appendBars(merged); err != nil {
+		return 0, err
+	}
+	return int64(len(arr)), ser.flush()
+}
+
+func (s *FileKStore) DelKLines(sid int32, timeFrame string, startMS, endMS int64) *errs.Error {
+	ser, err := s.getSeries(sid, timeFrame)
+	if err != nil {
+		return err
+	}
+	ser.lock.Lock()
+	defer ser.lock.Unlock()
+	if endMS <= 0 {
+		endMS = math.MaxInt64
+	}
+	keeps := make([]*fileBlock, 0, len(ser.blocks))
+	var rests []*banexg.Kline
+	for _, b := range ser.blocks {
+		if b.End < startMS || b.Start >= endMS {
+			keeps = append(keeps, b)
+			continue
+		}
+		if b.Start >= startMS && b.End < endMS {
+			continue
+		}
+		items, err := ser.readBlock(b)
+		if err != nil {
+			return err
+		}
+		for _, k := range items {
+			if k.Time < startMS || k.Time >= endMS {
+				rests = append(rests, k)
+			}
+		}
+	}
+	ser.blocks = keeps
+	if err = ser.appendBars(rests); err != nil {
+		return err
+	}
+	return ser.flush()
+}
+
+func (s *FileKStore) CalcKLineRange(sid int32, timeFrame string, startMS, endMS int64) (int64, int64, *errs.Error) {
+	first, err := s.QueryKLines(sid, timeFrame, startMS, endMS, 1, false)
+	if err != nil || len(first) == 0 {
+		return 0, 0, err
+	}
+	last, err := s.QueryKLines(sid, timeFrame, startMS, endMS, 1, true)
+	if err != nil || len(last) == 0 {
+		return 0, 0, err
+	}
+	return first[0].Time, last[0].Time + int64(utils2.TFToSecs(timeFrame)*1000), nil
+}
+
+func (s *FileKStore) KLineSids(timeFrame string) ([]int32, *errs.Error) {
+	entries, err_ := os.ReadDir(s.dir)
+	if err_ != nil {
+		return nil, errs.New(core.ErrIOReadFail, err_)
+	}
+	res := make([]int32, 0, len(entries))
+	for _, e := range entries {
+		sid, err_ := strconv.Atoi(e.Name())
+		if !e.IsDir() || err_ != nil {
+			continue
+		}
+		if _, err_ = os.Stat(filepath.Join(s.dir, e.Name(), timeFrame+".idx")); err_ == nil {
+			res = append(res, int32(sid))
+		}
+	}
+	return res, nil
+}
+
+// readBlock read bars of a block, caller should hold the lock 读取块中的bar，调用方应持有锁
+func (ser *fileSeries) readBlock(b *fileBlock) ([]*banexg.Kline, *errs.Error) {
+	file, err_ := os.Open(ser.path + ".dat")
+	if err_ != nil {
+		return nil, errs.New(core.ErrIOReadFail, err_)
+	}
+	defer file.Close()
+	data := make([]byte, b.Num*fileBarSize)
+	if _, err_ = file.ReadAt(data, b.Off); err_ != nil && err_ != io.EOF {
+		return nil, errs.New(core.ErrIOReadFail, err_)
+	}
+	return decodeBars(data, b.Num), nil
+}
+
+// appendBars write bars as new blocks at ser.size 将bar作为新块写入到ser.size处
+func (ser *fileSeries) appendBars(bars []*banexg.Kline) *errs.Error {
+	if len(bars) == 0 {
+		return nil
+	}
+	file, err_ := os.OpenFile(ser.path+".dat", os.O_RDWR|os.O_CREATE, 0644)
+	if err_ != nil {
+		return errs.New(core.ErrIOWriteFail, err_)
+	}
+	defer file.Close()
+	if err_ = file.Truncate(ser.size); err_ != nil {
+		return errs.New(core.ErrIOWriteFail, err_)
+	}
+	for i := 0; i < len(bars); i += fileBlockBars {
+		chunk := bars[i:min(i+fileBlockBars, len(bars))]
+		if _, err_ = file.WriteAt(encodeBars(chunk), ser.size); err_ != nil {
+			return errs.New(core.ErrIOWriteFail, err_)
+		}
+		ser.blocks = append(ser.blocks, &fileBlock{
+			Off:   ser.size,
+			Num:   len(chunk),
+			Start: chunk[0].Time,
+			End:   chunk[len(chunk)-1].Time,
+		})
+		ser.size += int64(len(chunk) * fileBarSize)
+	}
+	slices.SortFunc(ser.blocks, func(a, b *fileBlock) int {
+		return compareInt(a.Start, b.Start)
+	})
+	return nil
+}
+
+// flush compact data file if needed and save index 按需压缩数据文件并保存索引
+func (ser *fileSeries) flush() *errs.Error {
+	if len(ser.blocks) == 0 {
+		ser.size = 0
+		for _, ext := range []string{".dat", ".idx"} {
+			if err_ := os.Remove(ser.path + ext); err_ != nil && !os.IsNotExist(err_) {
+				return errs.New(core.ErrIOWriteFail, err_)
+			}
+		}
+		return nil
+	}
+	validSize := int64(0)
+	for _, b := range ser.blocks {
+		validSize += int64(b.Num * fileBarSize)
+	}
+	if garbage := ser.size - validSize; garbage > fileMinGarbage && garbage > validSize {
+		if err := ser.compact(); err != nil {
+			return err
+		}
+	}
+	return writeJSON(ser.path+".idx", ser.blocks)
+}
+
+// compact rewrite valid blocks in time order 按时间顺序重写有效块
+func (ser *fileSeries) compact() *errs.Error {
+	tmpPath := ser.path + ".dat.tmp"
+	file, err_ := os.Create(tmpPath)
+	if err_ != nil {
+		return errs.New(core.ErrIOWriteFail, err_)
+	}
+	newBlocks := make([]*fileBlock, 0, len(ser.blocks))
+	off := int64(0)
+	for _, b := range ser.blocks {
+		bars, err := ser.readBlock(b)
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+		if _, err_ = file.Write(encodeBars(bars)); err_ != nil {
+			_ = file.Close()
+			return errs.New(core.ErrIOWriteFail, err_)
+		}
+		newBlocks = append(newBlocks, &fileBlock{Off: off, Num: b.Num, Start: b.Start, End: b.End})
+		off += int64(b.Num * fileBarSize)
+	}
+	if err_ = file.Close(); err_ != nil {
+		return errs.New(core.ErrIOWriteFail, err_)
+	}
+	if err_ = os.Rename(tmpPath, ser.path+".dat"); err_ != nil {
+		return errs.New(core.ErrIOWriteFail, err_)
+	}
+	ser.blocks = newBlocks
+	ser.size = off
+	return nil
+}
+
+// encodeBars encode bars to columns: times, opens, highs, lows, closes, volumes, infos 按列编码bar
+func encodeBars(bars []*banexg.Kline) []byte {
+	num := len(bars)
+	data := make([]byte, num*fileBarSize)
+	for i, k := range bars {
+		binary.LittleEndian.PutUint64(data[i*8:], uint64(k.Time))
+		cols := [6]float64{k.Open, k.High, k.Low, k.Close, k.Volume, k.Info}
+		for c, v := range cols {
+			binary.LittleEndian.PutUint64(data[((c+1)*num+i)*8:], math.Float64bits(v))
+		}
+	}
+	return data
+}
+
+func decodeBars(data []byte, num int) []*banexg.Kline {
+	res := make([]*banexg.Kline, num)
+	col := func(c, i int) float64 {
+		return math.Float64frombits(binary.LittleEndian.Uint64(data[(c*num+i)*8:]))
+	}
+	for i := range res {
+		res[i] = &banexg.Kline{
+			Time:   int64(binary.LittleEndian.Uint64(data[i*8:])),
+			Open:   col(1, i),
+			High:   col(2, i),
+			Low:    col(3, i),
+			Close:  col(4, i),
+			Volume: col(5, i),
+			Info:   col(6, i),
+		}
+	}
+	return res
+}
+
+// mergeBars merge two sorted bars, bars in adds replace olds with same time 合并两个有序bar列表，adds替换相同时间的olds
+func mergeBars(olds, adds []*banexg.Kline) []*banexg.Kline {
+	res := make([]*banexg.Kline, 0, len(olds)+len(adds))
+	i, j := 0, 0
+	for i < len(olds) || j < len(adds) {
+		var k *banexg.Kline
+		if j >= len(adds) || i < len(olds) && olds[i].Time < adds[j].Time {
+			k = olds[i]
+			i++
+		} else {
+			if i < len(olds) && olds[i].Time == adds[j].Time {
+				i++
+			}
+			k = adds[j]
+			j++
+		}
+		if n := len(res); n > 0 && res[n-1].Time == k.Time {
+			res[n-1] = k
+		} else {
+			res = append(res, k)
+		}
+	}
+	return res
+}
+
+func compareInt(a, b int64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+/** ********************************** kinfo, khole, adj factors, fundings ******************************** */
+
+// getSid load meta of sid, caller should hold s.lock 加载sid元数据，调用方应持有s.lock
+func (s *FileKStore) getSid(sid int32) (*fileSidMeta, *errs.Error) {
+	if m, ok := s.sids[sid]; ok {
+		return m, nil
+	}
+	m := &fileSidMeta{}
+	if err := readJSON(filepath.Join(s.dir, strconv.Itoa(int(sid)), "meta.json"), m); err != nil {
+		return nil, err
+	}
+	if m.KInfos == nil {
+		m.KInfos = make(map[string][2]int64)
+	}
+	s.sids[sid] = m
+	return m, nil
+}
+
+func (s *FileKStore) saveSid(sid int32) *errs.Error {
+	dir := filepath.Join(s.dir, strconv.Itoa(int(sid)))
+	if err_ := os.MkdirAll(dir, 0755); err_ != nil {
+		return errs.New(core.ErrIOWriteFail, err_)
+	}
+	return writeJSON(filepath.Join(dir, "meta.json"), s.sids[sid])
+}
+
+// allSids load meta of all sids, caller should hold s.lock 加载全部sid的元数据，调用方应持有s.lock
+func (s *FileKStore) allSids() ([]int32, *errs.Error) {
+	entries, err_ := os.ReadDir(s.dir)
+	if err_ != nil {
+		return nil, errs.New(core.ErrIOReadFail, err_)
+	}
+	res := make([]int32, 0, len(entries))
+	for _, e := range entries {
+		sid, err_ := strconv.Atoi(e.Name())
+		if !e.IsDir() || err_ != nil {
+			continue
+		}
+		if _, err := s.getSid(int32(sid)); err != nil {
+			return nil, err
+		}
+		res = append(res, int32(sid))
+	}
+	return res, nil
+}
+
+func (s *FileKStore) ListKInfos(sid int32) ([]*KInfo, *errs.Error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sids := []int32{sid}
+	if sid == 0 {
+		var err *errs.Error
+		if sids, err = s.allSids(); err != nil {
+			return nil, err
+		}
+	}
+	var res []*KInfo
+	for _, id := range sids {
+		m, err := s.getSid(id)
+		if err != nil {
+			return nil, err
+		}
+		for tf, rg := range m.KInfos {
+			res = append(res, &KInfo{Sid: id, Timeframe: tf, Start: rg[0], Stop: rg[1]})
+		}
+	}
+	return res, nil
+}
+
+func (s *FileKStore) SetKInfo(arg SetKInfoParams) *errs.Error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	m, err := s.getSid(arg.Sid)
+	if err != nil {
+		return err
+	}
+	m.KInfos[arg.Timeframe] = [2]int64{arg.Start, arg.Stop}
+	return s.saveSid(arg.Sid)
+}
+
+func (s *FileKStore) DelKInfo(sid int32, timeFrame string) *errs.Error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	m, err := s.getSid(sid)
+	if err != nil {
+		return err
+	}
+	if _, ok := m.KInfos[timeFrame]; !ok {
+		return nil
+	}
+	delete(m.KInfos, timeFrame)
+	return s.saveSid(sid)
+}
+
+func (s *FileKStore) ListKHoles(sids ...int32) ([]*KHole, *errs.Error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var res []*KHole
+	for _, sid := range sids {
+		m, err := s.getSid(sid)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range m.Holes {
+			item := *h
+			res = append(res, &item)
+		}
+	}
+	return res, nil
+}
+
+func (s *FileKStore) AddKHoles(args []AddKHolesParams) *errs.Error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	dirty := make(map[int32]bool)
+	for _, a := range args {
+		m, err := s.getSid(a.Sid)
+		if err != nil {
+			return err
+		}
+		m.NextID += 1
+		// sid in high 32 bits, so the owner can be found by id 高32位为sid，可通过id找到所属sid
+		id := int64(a.Sid)<<32 | m.NextID
+		m.Holes = append(m.Holes, &KHole{ID: id, Sid: a.Sid, Timeframe: a.Timeframe, Start: a.Start,
+			Stop: a.Stop, NoData: a.NoData})
+		dirty[a.Sid] = true
+	}
+	for sid := range dirty {
+		if err := s.saveSid(sid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FileKStore) SetKHole(arg SetKHoleParams) *errs.Error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sid := int32(arg.ID >> 32)
+	m, err := s.getSid(sid)
+	if err != nil {
+		return err
+	}
+	for _, h := range m.Holes {
+		if h.ID == arg.ID {
+			h.Start, h.Stop, h.NoData = arg.Start, arg.Stop, arg.NoData
+			return s.saveSid(sid)
+		}
+	}
+	return nil
+}
+
+func (s *FileKStore) DelKHoles(ids ...int64) *errs.Error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	idMap := make(map[int32]map[int64]bool)
+	for _, id := range ids {
+		sid := int32(id >> 32)
+		if _, ok := idMap[sid]; !ok {
+			idMap[sid] = make(map[int64]bool)
+		}
+		idMap[sid][id] = true
+	}
+	for sid, dels := range idMap {
+		m, err := s.getSid(sid)
+		if err != nil {
+			return err
+		}
+		m.Holes = slices.DeleteFunc(m.Holes, func(h *KHole) bool {
+			return dels[h.ID]
+		})
+		if err = s.saveSid(sid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FileKStore) GetAdjFactors(sid int32) ([]*AdjFactor, *errs.Error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	m, err := s.getSid(sid)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*AdjFactor, 0, len(m.Adjs))
+	for _, f := range m.Adjs {
+		item := *f
+		res = append(res, &item)
+	}
+	slices.SortStableFunc(res, func(a, b *AdjFactor) int {
+		return compareInt(a.StartMs, b.StartMs)
+	})
+	return res, nil
+}
+
+func (s *FileKStore) AddAdjFactors(args []AddAdjFactorsParams) *errs.Error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	dirty := make(map[int32]bool)
+	for _, a := range args {
+		m, err := s.getSid(a.Sid)
+		if err != nil {
+			return err
+		}
+		m.NextID += 1
+		m.Adjs = append(m.Adjs, &AdjFactor{ID: int32(m.NextID), Sid: a.Sid, SubID: a.SubID, StartMs: a.StartMs,
+			Factor: a.Factor})
+		dirty[a.Sid] = true
+	}
+	for sid := range dirty {
+		if err := s.saveSid(sid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FileKStore) DelAdjFactors(sid int32, startMS, endMS int64, withSub bool) *errs.Error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sids := []int32{sid}
+	if withSub {
+		var err *errs.Error
+		if sids, err = s.allSids(); err != nil {
+			return err
+		}
+	}
+	if endMS <= 0 {
+		endMS = math.MaxInt64
+	}
+	for _, id := range sids {
+		m, err := s.getSid(id)
+		if err != nil {
+			return err
+		}
+		oldNum := len(m.Adjs)
+		m.Adjs = slices.DeleteFunc(m.Adjs, func(f *AdjFactor) bool {
+			return (f.Sid == sid || withSub && f.SubID == sid) && f.StartMs >= startMS && f.StartMs < endMS
+		})
+		if len(m.Adjs) != oldNum {
+			if err = s.saveSid(id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *FileKStore) GetFundingRates(sid int32, startMS, endMS int64) ([]*FundingRate, *errs.Error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	m, err := s.getSid(sid)
+	if err != nil {
+		return nil, err
+	}
+	if endMS <= 0 {
+		endMS = math.MaxInt64
+	}
+	res := make([]*FundingRate, 0, len(m.Fundings))
+	for _, f := range m.Fundings {
+		if f.Time >= startMS && f.Time < endMS {
+			item := *f
+			res = append(res, &item)
+		}
+	}
+	return res, nil
+}
+
+func (s *FileKStore) SetFundingRates(sid int32, items []*FundingRate) *errs.Error {
+	if len(items) == 0 {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	m, err := s.getSid(sid)
+	if err != nil {
+		return err
+	}
+	startMS, endMS := items[0].Time, items[len(items)-1].Time+1
+	m.Fundings = slices.DeleteFunc(m.Fundings, func(f *FundingRate) bool {
+		return f.Time >= startMS && f.Time < endMS
+	})
+	for _, f := range items {
+		m.NextID += 1
+		m.Fundings = append(m.Fundings, &FundingRate{ID: m.NextID, Sid: sid, Time: f.Time, Rate: f.Rate})
+	}
+	slices.SortStableFunc(m.Fundings, func(a, b *FundingRate) int {
+		return compareInt(a.Time, b.Time)
+	})
+	return s.saveSid(sid)
+}
+
+/** ********************************** symbols, calendars ******************************** */
+
+func (s *FileKStore) ListSymbols(exchange string) ([]*ExSymbol, *errs.Error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	res := make([]*ExSymbol, 0, len(s.root.Symbols))
+	for _, exs := range s.root.Symbols {
+		if exchange == "" || exs.Exchange == exchange {
+			item := *exs
+			res = append(res, &item)
+		}
+	}
+	return res, nil
+}
+
+func (s *FileKStore) AddSymbols(args []AddSymbolsParams) *errs.Error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	maxID := int32(0)
+	keys := make(map[string]bool)
+	for _, exs := range s.root.Symbols {
+		maxID = max(maxID, exs.ID)
+		keys[exs.Exchange+":"+exs.Market+":"+exs.Symbol] = true
+	}
+	for _, a := range args {
+		key := a.Exchange + ":" + a.Market + ":" + a.Symbol
+		if keys[key] {
+			continue
+		}
+		keys[key] = true
+		maxID += 1
+		s.root.Symbols = append(s.root.Symbols, &ExSymbol{ID: maxID, Exchange: a.Exchange, ExgReal: a.ExgReal,
+			Market: a.Market, Symbol: a.Symbol})
+	}
+	return writeJSON(filepath.Join(s.dir, "meta.json"), s.root)
+}
+
+func (s *FileKStore) SetListMS(arg SetListMSParams) *errs.Error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, exs := range s.root.Symbols {
+		if exs.ID == arg.ID {
+			exs.ListMs, exs.DelistMs = arg.ListMs, arg.DelistMs
+			return writeJSON(filepath.Join(s.dir, "meta.json"), s.root)
+		}
+	}
+	return nil
+}
+
+func (s *FileKStore) GetCalendars(name string, startMS, stopMS int64) ([][2]int64, *errs.Error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	res := make([][2]int64, 0)
+	for _, it := range s.root.Calendars[name] {
+		if (startMS <= 0 || it[1] > startMS) && (stopMS <= 0 || it[0] < stopMS) {
+			res = append(res, it)
+		}
+	}
+	return res, nil
+}
+
+func (s *FileKStore) SetCalendars(name string, items [][2]int64) *errs.Error {
+	if len(items) == 0 {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	startMS, stopMS := items[0][0], items[len(items)-1][1]
+	olds := s.root.Calendars[name]
+	res := make([][2]int64, 0, len(olds)+len(items))
+	for _, it := range olds {
+		if it[1] > startMS && it[0] < stopMS {
+			// replaced by items, keep the outer bounds 被items替换，保留外侧边界
+			items[0][0] = min(items[0][0], it[0])
+			items[len(items)-1][1] = max(items[len(items)-1][1], it[1])
+			continue
+		}
+		res = append(res, it)
+	}
+	res = append(res, items...)
+	slices.SortFunc(res, func(a, b [2]int64) int {
+		return compareInt(a[0], b[0])
+	})
+	s.root.Calendars[name] = res
+	return writeJSON(filepath.Join(s.dir, "meta.json"), s.root)
+}
+
+func (s *FileKStore) Close() *errs.Error {
+	return nil
+}
+
+// readJSON read json file into out, missing file is ignored 读取json文件到out，文件不存在时忽略
+func readJSON(path string, out interface{}) *errs.Error {
+	data, err_ := os.ReadFile(path)
+	if err_ != nil {
+		if os.IsNotExist(err_) {
+			return nil
+		}
+		return errs.New(core.ErrIOReadFail, err_)
+	}
+	if err_ = json.Unmarshal(data, out); err_ != nil {
+		return errs.NewFull(errs.CodeUnmarshalFail, err_, "read %s fail", path)
+	}
+	return nil
+}
+
+// writeJSON write json to a temp file and rename, avoid broken file 写入临时文件后重命名，避免文件损坏
+func writeJSON(path string, data interface{}) *errs.Error {
+	content, err_ := json.Marshal(data)
+	if err_ != nil {
+		return errs.New(errs.CodeMarshalFail, err_)
+	}
+	tmpPath := path + ".tmp"
+	if err_ = os.WriteFile(tmpPath, content, 0644); err_ != nil {
+		return errs.New(core.ErrIOWriteFail, err_)
+	}
+	if err_ = os.Rename(tmpPath, path); err_ != nil {
+		return errs.New(core.ErrIOWriteFail, err_)
+	}
+	return nil
+}
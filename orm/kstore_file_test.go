@@ -0,0 +1,159 @@
+package orm
+
+import (
+	"testing"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banexg"
+)
+
+func makeBars(startMS, tfMSecs int64, num int, price float64) []*banexg.Kline {
+	res := make([]*banexg.Kline, num)
+	for i := range res {
+		p := price + float64(i)
+		res[i] = &banexg.Kline{Time: startMS + int64(i)*tfMSecs, Open: p, High: p + 1, Low: p - 1, Close: p,
+			Volume: 10, Info: 1}
+	}
+	return res
+}
+
+func TestFileKStoreKLines(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileKStore(&config.DatabaseConfig{KlineDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tfMSecs := int64(60000)
+	// two separate ranges, then fill the gap with overlap 两个分离区间，然后用重叠数据填充空隙
+	if _, err = store.InsertKLines(1, "1m", makeBars(0, tfMSecs, 3000, 100)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.InsertKLines(1, "1m", makeBars(4000*tfMSecs, tfMSecs, 100, 100)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.InsertKLines(1, "1m", makeBars(2990*tfMSecs, tfMSecs, 1010, 500)); err != nil {
+		t.Fatal(err)
+	}
+	bars, err := store.QueryKLines(1, "1m", 0, 0, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bars) != 4100 {
+		t.Fatalf("expect 4100 bars, got %d", len(bars))
+	}
+	for i, b := range bars {
+		if b.Time != int64(i)*tfMSecs {
+			t.Fatalf("bar %d time invalid: %d", i, b.Time)
+		}
+	}
+	if bars[2990].Open != 500 || bars[2989].Open != 2989+100 {
+		t.Errorf("overlapped bars should be replaced, got %v %v", bars[2989].Open, bars[2990].Open)
+	}
+	last, err := store.QueryKLines(1, "1m", 0, 4050*tfMSecs, 3, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(last) != 3 || last[2].Time != 4049*tfMSecs {
+		t.Errorf("reverse query invalid: %v", last)
+	}
+	start, stop, err := store.CalcKLineRange(1, "1m", 0, 0)
+	if err != nil || start != 0 || stop != 4100*tfMSecs {
+		t.Errorf("range invalid: %v %v %v", start, stop, err)
+	}
+	if err = store.DelKLines(1, "1m", 100*tfMSecs, 4000*tfMSecs); err != nil {
+		t.Fatal(err)
+	}
+	// reopen to check the saved index 重新打开以检查保存的索引
+	store, err = NewFileKStore(&config.DatabaseConfig{KlineDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bars, err = store.QueryKLines(1, "1m", 0, 0, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bars) != 200 || bars[99].Time != 99*tfMSecs || bars[100].Time != 4000*tfMSecs {
+		t.Errorf("bars after delete invalid, num: %d", len(bars))
+	}
+	sids, err := store.KLineSids("1m")
+	if err != nil || len(sids) != 1 || sids[0] != 1 {
+		t.Errorf("sids invalid: %v %v", sids, err)
+	}
+}
+
+func TestFileKStoreMeta(t *testing.T) {
+	store, err := NewFileKStore(&config.DatabaseConfig{KlineDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.AddKHoles([]AddKHolesParams{
+		{Sid: 2, Timeframe: "1m", Start: 100, Stop: 200, NoData: true},
+		{Sid: 2, Timeframe: "1h", Start: 300, Stop: 400},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	holes, err := store.ListKHoles(2)
+	if err != nil || len(holes) != 2 {
+		t.Fatalf("holes invalid: %v %v", holes, err)
+	}
+	if err = store.SetKHole(SetKHoleParams{ID: holes[0].ID, Start: 120, Stop: 200}); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.DelKHoles(holes[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	holes, _ = store.ListKHoles(2)
+	if len(holes) != 1 || holes[0].Start != 120 || holes[0].NoData {
+		t.Errorf("holes after edit invalid: %v", holes)
+	}
+	err = store.AddSymbols([]AddSymbolsParams{
+		{Exchange: "binance", Market: "linear", Symbol: "BTC/USDT:USDT"},
+		{Exchange: "binance", Market: "linear", Symbol: "BTC/USDT:USDT"},
+		{Exchange: "china", Market: "linear", Symbol: "rb888"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, _ := store.ListSymbols("binance")
+	if len(items) != 1 || items[0].ID != 1 {
+		t.Errorf("symbols invalid: %v", items)
+	}
+	if err = store.SetCalendars("SHFE", [][2]int64{{0, 10}, {20, 30}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.SetCalendars("SHFE", [][2]int64{{25, 35}}); err != nil {
+		t.Fatal(err)
+	}
+	cals, _ := store.GetCalendars("SHFE", 0, 0)
+	if len(cals) != 2 || cals[1] != [2]int64{20, 35} {
+		t.Errorf("calendars invalid: %v", cals)
+	}
+}
+
+func TestRefreshStoreAgg(t *testing.T) {
+	store, err := NewFileKStore(&config.DatabaseConfig{KlineDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStore := kStore
+	kStore = store
+	defer func() {
+		kStore = oldStore
+	}()
+	base := int64(1700000100000) // aligned to 5m 按5m对齐
+	if _, err = store.InsertKLines(3, "1m", makeBars(base, 60000, 12, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if err = refreshStoreAgg(aggMap["5m"], 3, "1m", base, base+600000); err != nil {
+		t.Fatal(err)
+	}
+	bars, _ := store.QueryKLines(3, "5m", 0, 0, 0, false)
+	if len(bars) != 2 {
+		t.Fatalf("expect 2 bars, got %d", len(bars))
+	}
+	b := bars[1]
+	if b.Time != base+300000 || b.Open != 15 || b.Close != 19 || b.High != 20 || b.Low != 14 || b.Volume != 50 {
+		t.Errorf("agg bar invalid: %+v", b)
+	}
+}
@@ -30,6 +30,13 @@ type ExSymbol struct {
 	DelistMs int64  `json:"delist_ms"`
 }
 
+type FundingRate struct {
+	ID   int64   `json:"id"`
+	Sid  int32   `json:"sid"`
+	Time int64   `json:"time"`
+	Rate float64 `json:"rate"`
+}
+
 type InsKline struct {
 	ID        int32  `json:"id"`
 	Sid       int32  `json:"sid"`
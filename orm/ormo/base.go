@@ -11,11 +11,6 @@ import (
 	"sync"
 )
 
-var (
-	accTasks     = make(map[string]*BotTask)
-	taskIdAccMap = make(map[int64]string)
-)
-
 func Conn(path string, write bool) (*Queries, *sql.DB, *errs.Error) {
 	db, err := orm.DbLite(orm.DbTrades, path, write)
 	if err != nil {
@@ -24,69 +19,69 @@ func Conn(path string, write bool) (*Queries, *sql.DB, *errs.Error) {
 	return New(db), db, nil
 }
 
-func GetTaskID(account string) int64 {
-	task := GetTask(account)
+func (c *Ctx) GetTaskID(account string) int64 {
+	task := c.GetTask(account)
 	if task != nil {
 		return task.ID
 	}
 	return 0
 }
 
-func GetTask(account string) *BotTask {
+func (c *Ctx) GetTask(account string) *BotTask {
 	if !core.EnvReal {
 		account = config.DefAcc
 	}
-	if task, ok := accTasks[account]; ok {
+	if task, ok := c.accTasks[account]; ok {
 		return task
 	}
 	return nil
 }
 
-func GetTaskAcc(id int64) string {
-	if acc, ok := taskIdAccMap[id]; ok {
+func (c *Ctx) GetTaskAcc(id int64) string {
+	if acc, ok := c.taskIdAccMap[id]; ok {
 		return acc
 	}
 	return ""
 }
 
-func GetOpenODs(account string) (map[int64]*InOutOrder, *sync.Mutex) {
+func (c *Ctx) GetOpenODs(account string) (map[int64]*InOutOrder, *sync.Mutex) {
 	if !core.EnvReal {
 		account = config.DefAcc
 	}
-	mOpenLock.Lock()
-	val, ok := accOpenODs[account]
+	c.mOpenLock.Lock()
+	val, ok := c.accOpenODs[account]
 	if !ok {
 		val = make(map[int64]*InOutOrder)
-		accOpenODs[account] = val
+		c.accOpenODs[account] = val
 	}
-	lock, ok2 := lockOpenMap[account]
+	lock, ok2 := c.lockOpenMap[account]
 	if !ok2 {
 		lock = &sync.Mutex{}
-		lockOpenMap[account] = lock
+		c.lockOpenMap[account] = lock
 	}
-	mOpenLock.Unlock()
+	c.mOpenLock.Unlock()
 	return val, lock
 }
 
-func GetTriggerODs(account string) (map[string]map[int64]*InOutOrder, *sync.Mutex) {
+func (c *Ctx) GetTriggerODs(account string) (map[string]map[int64]*InOutOrder, *sync.Mutex) {
 	if !core.EnvReal {
 		account = config.DefAcc
 	}
-	val, ok := accTriggerODs[account]
+	val, ok := c.accTriggerODs[account]
 	if !ok {
 		val = make(map[string]map[int64]*InOutOrder)
-		accTriggerODs[account] = val
+		c.accTriggerODs[account] = val
 	}
-	lock, ok2 := lockTriggerMap[account]
+	lock, ok2 := c.lockTriggerMap[account]
 	if !ok2 {
 		lock = &sync.Mutex{}
-		lockTriggerMap[account] = lock
+		c.lockTriggerMap[account] = lock
 	}
 	return val, lock
 }
 
-func AddTriggerOd(account string, od *InOutOrder) {
-	triggerOds, lock := GetTriggerODs(account)
+func (c *Ctx) AddTriggerOd(account string, od *InOutOrder) {
+	triggerOds, lock := c.GetTriggerODs(account)
 	lock.Lock()
 	ods, ok := triggerOds[od.Symbol]
 	if !ok {
@@ -102,9 +97,9 @@ OpenNum
 Returns the number of open orders that match the specified status
 返回符合指定状态的尚未平仓订单的数量
 */
-func OpenNum(account string, status int64) int {
+func (c *Ctx) OpenNum(account string, status int64) int {
 	openNum := 0
-	openOds, lock := GetOpenODs(account)
+	openOds, lock := c.GetOpenODs(account)
 	lock.Lock()
 	for _, od := range openOds {
 		if od.Status >= status {
@@ -120,14 +115,14 @@ SaveDirtyODs
 Find unsaved orders from open orders and save them all to the database
 从打开的订单中查找未保存的订单，全部保存到数据库
 */
-func SaveDirtyODs(path string, account string) *errs.Error {
+func (c *Ctx) SaveDirtyODs(path string, account string) *errs.Error {
 	var dirtyOds []*InOutOrder
-	mOpenLock.Lock()
-	for accKey, ods := range accOpenODs {
+	c.mOpenLock.Lock()
+	for accKey, ods := range c.accOpenODs {
 		if account != "" && accKey != account {
 			continue
 		}
-		lock, _ := lockOpenMap[accKey]
+		lock, _ := c.lockOpenMap[accKey]
 		lock.Lock()
 		for key, od := range ods {
 			if od.IsDirty() {
@@ -139,7 +134,7 @@ func SaveDirtyODs(path string, account string) *errs.Error {
 		}
 		lock.Unlock()
 	}
-	mOpenLock.Unlock()
+	c.mOpenLock.Unlock()
 	if len(dirtyOds) == 0 {
 		return nil
 	}
@@ -158,42 +153,3 @@ func SaveDirtyODs(path string, account string) *errs.Error {
 	}
 	return odErr
 }
-
-func ResetVars() {
-	accOpenODs = make(map[string]map[int64]*InOutOrder)
-	accTriggerODs = make(map[string]map[string]map[int64]*InOutOrder)
-	lockOpenMap = make(map[string]*sync.Mutex)
-	lockTriggerMap = make(map[string]*sync.Mutex)
-	lockOds = make(map[string]*sync.Mutex)
-	accTasks = make(map[string]*BotTask)
-	taskIdAccMap = make(map[int64]string)
-}
-
-// VarsBackup 存储 ormo 包相关全局变量的备份
-type VarsBackup struct {
-	AccOpenODs    map[string]map[int64]*InOutOrder
-	AccTriggerODs map[string]map[string]map[int64]*InOutOrder
-	AccTasks      map[string]*BotTask
-	TaskIdAccMap  map[int64]string
-}
-
-// BackupVars 备份所有全局变量
-func BackupVars() *VarsBackup {
-	return &VarsBackup{
-		AccOpenODs:    accOpenODs,
-		AccTriggerODs: accTriggerODs,
-		AccTasks:      accTasks,
-		TaskIdAccMap:  taskIdAccMap,
-	}
-}
-
-// RestoreVars 从备份中恢复所有全局变量
-func RestoreVars(backup *VarsBackup) {
-	if backup == nil {
-		return
-	}
-	accOpenODs = backup.AccOpenODs
-	accTriggerODs = backup.AccTriggerODs
-	accTasks = backup.AccTasks
-	taskIdAccMap = backup.TaskIdAccMap
-}
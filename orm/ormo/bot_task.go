@@ -14,14 +14,15 @@ import (
 	"strings"
 )
 
-func InitTask(showLog bool, outDir string) *errs.Error {
-	if len(accTasks) > 0 {
+func (c *Ctx) InitTask(showLog bool, outDir string) *errs.Error {
+	if len(c.accTasks) > 0 {
 		return nil
 	}
 	if !core.LiveMode {
-		accTasks[config.DefAcc] = &BotTask{ID: -1, Mode: core.RunMode, CreateAt: btime.UTCStamp(),
-			StartAt: config.TimeRange.StartMS, StopAt: config.TimeRange.EndMS}
-		taskIdAccMap[-1] = config.DefAcc
+		timeRange := c.TimeRange()
+		c.accTasks[config.DefAcc] = &BotTask{ID: -1, Mode: core.RunMode, CreateAt: btime.UTCStamp(),
+			StartAt: timeRange.StartMS, StopAt: timeRange.EndMS}
+		c.taskIdAccMap[-1] = config.DefAcc
 		if showLog {
 			log.Info("init task ok", zap.Int64("id", -1))
 		}
@@ -39,8 +40,8 @@ func InitTask(showLog bool, outDir string) *errs.Error {
 		if err != nil {
 			return err
 		}
-		accTasks[account] = task
-		taskIdAccMap[task.ID] = account
+		c.accTasks[account] = task
+		c.taskIdAccMap[task.ID] = account
 		idList = append(idList, fmt.Sprintf("%s:%v", account, task.ID))
 	}
 	if showLog {
@@ -9,12 +9,12 @@ import (
 	"math"
 )
 
-func DumpOrdersGob(path string) *errs.Error {
-	for _, od := range HistODs {
+func DumpOrdersGob(path string, orders []*InOutOrder) *errs.Error {
+	for _, od := range orders {
 		_, _ = od.GetInfoText()
 		od.Info = nil
 	}
-	return utils.EncodeGob(path, HistODs)
+	return utils.EncodeGob(path, orders)
 }
 
 func LoadOrdersGob(path string) ([]*InOutOrder, *errs.Error) {
@@ -68,6 +68,15 @@ func decodeTriggerState(data map[string]interface{}) *TriggerState {
 	if v, ok := data["order_id"].(string); ok {
 		ts.OrderId = v
 	}
+	if v, ok := data["best"].(float64); ok {
+		ts.Best = v
+	}
+	if v, ok := data["active"].(bool); ok {
+		ts.Active = v
+	}
+	if v, ok := data["native"].(bool); ok {
+		ts.Native = v
+	}
 
 	// 处理嵌套的Old字段
 	if oldData, ok := data["old"].(map[string]interface{}); ok {
@@ -91,5 +100,14 @@ func decodeTrigger(data map[string]interface{}) *ExitTrigger {
 	if v, ok := data["tag"].(string); ok {
 		ts.Tag = v
 	}
+	if v, ok := data["activate"].(float64); ok {
+		ts.Activate = v
+	}
+	if v, ok := data["callback"].(float64); ok {
+		ts.Callback = v
+	}
+	if v, ok := data["distance"].(float64); ok {
+		ts.Distance = v
+	}
 	return ts
 }
@@ -0,0 +1,96 @@
+package ormo
+
+import (
+	"sync"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+)
+
+/*
+Ctx
+Orders, tasks, run policies and time range of a running bot, based on core.BotCtx.
+正在运行的机器人的订单、任务、运行策略和时间范围，基于core.BotCtx。
+*/
+type Ctx struct {
+	*core.BotCtx
+	HistODs  []*InOutOrder // Historical orders, used as storage for backtesting. 历史订单，回测时作为存储用
+	FakeOdId int64         // Virtual order ID, used for temporary maintenance during backtesting. 虚拟订单ID，用于回测时临时维护
+
+	runPolicy      []*config.RunPolicyConfig                   // nil for config.RunPolicy 为nil时使用config.RunPolicy
+	timeRange      *config.TimeTuple                           // nil for config.TimeRange 为nil时使用config.TimeRange
+	accOpenODs     map[string]map[int64]*InOutOrder            // all open orders: accName:orderId:order 全部打开的订单
+	accTriggerODs  map[string]map[string]map[int64]*InOutOrder // Waiting for the limit order to be triggered, only for real trading . accName:pair:orders 等待触发限价单的订单，仅实盘使用；
+	lockOpenMap    map[string]*sync.Mutex                      // Access to the lock of accOpenODs 访问accOpenODs的锁
+	lockTriggerMap map[string]*sync.Mutex                      // Access to the lock of accTriggerODs 访问accTriggerODs的锁
+	lockOds        map[string]*sync.Mutex                      // Modify the lock of the order to prevent concurrent modification 修改订单的锁，防止并发修改
+	doneODs        map[int64]bool                              // 用于防止重复添加到HistODs
+	accTasks       map[string]*BotTask
+	taskIdAccMap   map[int64]string
+	mLockOds       sync.Mutex
+	mOpenLock      sync.Mutex
+}
+
+// DefCtx context of the live bot, based on core.DefCtx 实盘使用的上下文，基于core.DefCtx
+var DefCtx = NewCtx(core.DefCtx)
+
+func NewCtx(bot *core.BotCtx) *Ctx {
+	c := &Ctx{BotCtx: bot, FakeOdId: 1}
+	c.resetOrders()
+	return c
+}
+
+/*
+Reset
+Clear the state of this context and core.BotCtx, the run policies and time range are kept.
+清空此上下文和core.BotCtx的状态，保留运行策略和时间范围。
+*/
+func (c *Ctx) Reset() {
+	c.BotCtx.Reset()
+	c.resetOrders()
+}
+
+func (c *Ctx) resetOrders() {
+	c.mOpenLock.Lock()
+	c.accOpenODs = make(map[string]map[int64]*InOutOrder)
+	c.accTriggerODs = make(map[string]map[string]map[int64]*InOutOrder)
+	c.lockOpenMap = make(map[string]*sync.Mutex)
+	c.lockTriggerMap = make(map[string]*sync.Mutex)
+	c.mOpenLock.Unlock()
+	c.mLockOds.Lock()
+	c.lockOds = make(map[string]*sync.Mutex)
+	c.mLockOds.Unlock()
+	c.HistODs = make([]*InOutOrder, 0)
+	c.doneODs = make(map[int64]bool)
+	c.accTasks = make(map[string]*BotTask)
+	c.taskIdAccMap = make(map[int64]string)
+}
+
+// OdCtx return itself, used to get the order context from contexts embedding it 返回自身，用于从嵌入它的上下文中获取订单上下文
+func (c *Ctx) OdCtx() *Ctx {
+	return c
+}
+
+// RunPolicy policies to run, config.RunPolicy if not set 要运行的策略，未设置时为config.RunPolicy
+func (c *Ctx) RunPolicy() []*config.RunPolicyConfig {
+	if c.runPolicy != nil {
+		return c.runPolicy
+	}
+	return config.RunPolicy
+}
+
+func (c *Ctx) SetRunPolicy(pols []*config.RunPolicyConfig) {
+	c.runPolicy = pols
+}
+
+// TimeRange time range to run, config.TimeRange if not set 运行的时间范围，未设置时为config.TimeRange
+func (c *Ctx) TimeRange() *config.TimeTuple {
+	if c.timeRange != nil {
+		return c.timeRange
+	}
+	return config.TimeRange
+}
+
+func (c *Ctx) SetTimeRange(tr *config.TimeTuple) {
+	c.timeRange = tr
+}
@@ -1,20 +1,5 @@
 package ormo
 
-import "sync"
-
-var (
-	accOpenODs     = map[string]map[int64]*InOutOrder{}            // all open orders: accName:orderId:order 全部打开的订单
-	accTriggerODs  = map[string]map[string]map[int64]*InOutOrder{} // Waiting for the limit order to be triggered, only for real trading . accName:pair:orders 等待触发限价单的订单，仅实盘使用；
-	lockOpenMap    = map[string]*sync.Mutex{}                      // Access to the lock of accOpenODs 访问accOpenODs的锁
-	lockTriggerMap = map[string]*sync.Mutex{}                      // Access to the lock of accTriggerODs 访问accTriggerODs的锁
-	lockOds        = map[string]*sync.Mutex{}                      // Modify the lock of the order to prevent concurrent modification 修改订单的锁，防止并发修改
-	mLockOds       sync.Mutex
-	mOpenLock      sync.Mutex
-	HistODs        []*InOutOrder          // Historical orders, used as storage for backtesting. 历史订单，回测时作为存储用
-	doneODs        = make(map[int64]bool) // 用于防止重复添加到HistODs
-	FakeOdId       = int64(1)             // Virtual order ID, used for temporary maintenance during backtesting. 虚拟订单ID，用于回测时临时维护
-)
-
 const (
 	InOutStatusInit = iota
 	InOutStatusPartEnter
@@ -39,6 +24,9 @@ const (
 	OdInfoStopAfter  = "StopAfter"
 	OdInfoStopLoss   = "StopLoss"
 	OdInfoTakeProfit = "TakeProfit"
+	OdInfoFundFee    = "FundFee"  // accumulated funding fee in quote, positive means paid 累计资金费(报价币)，正数表示支付
+	OdInfoFundAt     = "FundAt"   // timestamp of last funding settlement applied 最后结算的资金费时间戳
+	OdInfoSlipCost   = "SlipCost" // accumulated slippage cost in quote of simulated fills 模拟成交的累计滑点成本(报价币)
 )
 
 const (
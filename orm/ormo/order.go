@@ -13,7 +13,6 @@ import (
 	"sync/atomic"
 	"time"
 
-	"github.com/banbox/banbot/btime"
 	"github.com/banbox/banbot/config"
 	"github.com/banbox/banbot/core"
 	"github.com/banbox/banbot/exg"
@@ -41,6 +40,7 @@ type InOutOrder struct {
 	DirtyExit  bool                   `json:"-"` // Exit has unsaved temporary changes 有未保存的临时修改
 	DirtyInfo  bool                   `json:"-"` // Info has unsaved temporary changes 有未保存的临时修改
 	idKey      string                 `json:"-"` // Key to distinguish orders 区分订单的key
+	ctx        *Ctx                   // context which the order belongs to, nil for DefCtx 订单所属的上下文，nil表示DefCtx
 }
 
 type InOutEdit struct {
@@ -48,6 +48,18 @@ type InOutEdit struct {
 	Action string
 }
 
+// Ctx return the context of the order, DefCtx if not set 返回订单所属的上下文，未设置时为DefCtx
+func (i *InOutOrder) Ctx() *Ctx {
+	if i.ctx != nil {
+		return i.ctx
+	}
+	return DefCtx
+}
+
+func (i *InOutOrder) SetCtx(c *Ctx) {
+	i.ctx = c
+}
+
 func (i *InOutOrder) SetInfo(key string, val interface{}) {
 	i.loadInfo()
 	if val == nil {
@@ -206,7 +218,7 @@ func (i *InOutOrder) UpdateProfits(price float64) {
 	if i.Exit != nil && !math.IsNaN(i.Exit.Fee) && !math.IsInf(i.Exit.Fee, 0) {
 		exitFee = i.Exit.Fee
 	}
-	i.Profit = profitVal - enterFee - exitFee
+	i.Profit = profitVal - enterFee - exitFee - i.GetInfoFloat64(OdInfoFundFee)
 	entPrice := i.InitPrice
 	if i.Enter.Average > 0 {
 		entPrice = i.Enter.Average
@@ -231,13 +243,31 @@ func (i *InOutOrder) UpdateProfits(price float64) {
 	i.DirtyMain = true
 }
 
+/*
+AddFundFee
+Accumulate a funding fee settled at timeMS. fee>0 means paid, fee<0 means received.
+累加在timeMS结算的资金费，fee>0表示支付，fee<0表示收取
+*/
+func (i *InOutOrder) AddFundFee(fee float64, timeMS int64) {
+	i.SetInfo(OdInfoFundFee, i.GetInfoFloat64(OdInfoFundFee)+fee)
+	i.SetInfo(OdInfoFundAt, timeMS)
+}
+
+// AddSlipCost Accumulate the slippage cost of a simulated fill 累加模拟成交的滑点成本
+func (i *InOutOrder) AddSlipCost(cost float64) {
+	if cost == 0 {
+		return
+	}
+	i.SetInfo(OdInfoSlipCost, i.GetInfoFloat64(OdInfoSlipCost)+cost)
+}
+
 /*
 UpdateFee
 Calculates commission for entry/exit orders. Must be called after Filled is assigned a value, otherwise the calculation is empty
 为入场/出场订单计算手续费，必须在Filled赋值后调用，否则计算为空
 */
 func (i *InOutOrder) UpdateFee(price float64, forEnter bool, isHistory bool) *errs.Error {
-	exchange := exg.Default
+	exchange := exg.GetPairExg(i.Symbol)
 	exOrder := i.Enter
 	if !forEnter {
 		exOrder = i.Exit
@@ -248,10 +278,10 @@ func (i *InOutOrder) UpdateFee(price float64, forEnter bool, isHistory bool) *er
 			// 历史已完成订单，不使用当前价格判断是否为maker，直接认为maker
 			maker = true
 		} else {
-			maker = core.IsMaker(i.Symbol, exOrder.Side, price)
+			maker = i.Ctx().IsMaker(i.Symbol, exOrder.Side, price)
 		}
 	}
-	fee, err := exchange.CalculateFee(i.Symbol, exOrder.OrderType, exOrder.Side, exOrder.Filled, price, maker, nil)
+	fee, err := exchange.CalculateFee(core.PairSymbol(i.Symbol), exOrder.OrderType, exOrder.Side, exOrder.Filled, price, maker, nil)
 	if err != nil {
 		return err
 	}
@@ -273,7 +303,7 @@ func (i *InOutOrder) CanClose() bool {
 		return true
 	}
 	tfMSecs := int64(utils2.TFToSecs(i.Timeframe) * 1000)
-	return float64(btime.TimeMS()-i.RealEnterMS()) > float64(tfMSecs)*0.9
+	return float64(i.Ctx().TimeMS()-i.RealEnterMS()) > float64(tfMSecs)*0.9
 }
 
 func (i *InOutOrder) SetExit(tag, orderType string, limit float64) {
@@ -282,7 +312,7 @@ func (i *InOutOrder) SetExit(tag, orderType string, limit float64) {
 			tag = core.ExitTagUnknown
 		}
 		i.ExitTag = tag
-		i.ExitAt = btime.TimeMS()
+		i.ExitAt = i.Ctx().TimeMS()
 		i.DirtyMain = true
 	}
 	if i.Exit == nil {
@@ -297,7 +327,7 @@ func (i *InOutOrder) SetExit(tag, orderType string, limit float64) {
 			Enter:     false,
 			OrderType: orderType,
 			Side:      odSide,
-			CreateAt:  btime.TimeMS(),
+			CreateAt:  i.Ctx().TimeMS(),
 			Price:     limit,
 			Amount:    i.Enter.Filled,
 			Status:    OdStatusInit,
@@ -324,7 +354,7 @@ When calling this function on a real drive, it will be saved to the database
 */
 func (i *InOutOrder) LocalExit(tag string, price float64, msg, odType string) *errs.Error {
 	if price == 0 {
-		newPrice := core.GetPrice(i.Symbol)
+		newPrice := i.Ctx().GetPrice(i.Symbol)
 		if newPrice > 0 {
 			price = newPrice
 		} else if i.Enter.Average > 0 {
@@ -349,7 +379,7 @@ func (i *InOutOrder) LocalExit(tag string, price float64, msg, odType string) *e
 	}
 	i.Exit.Status = OdStatusClosed
 	if i.Exit.Filled == 0 {
-		i.ExitAt = btime.TimeMS()
+		i.ExitAt = i.Ctx().TimeMS()
 	}
 	i.Exit.Filled = i.Enter.Filled
 	i.Exit.Average = i.Exit.Price
@@ -416,10 +446,19 @@ func (i *InOutOrder) CutPart(enterAmt, exitAmt float64) *InOutOrder {
 		DirtyEnter: true,
 		DirtyInfo:  true,
 		idKey:      i.idKey,
+		ctx:        i.ctx,
 	}
 	for key, val := range i.Info {
 		part.Info[key] = val
 	}
+	for _, key := range []string{OdInfoFundFee, OdInfoSlipCost} {
+		// accumulated costs are split proportionally by entry amount
+		// 累计的费用按入场数量比例拆分
+		if val := i.GetInfoFloat64(key); val != 0 {
+			part.Info[key] = val * enterRate
+			i.SetInfo(key, val*(1-enterRate))
+		}
+	}
 	// The enter.at of the original order needs to be+1 to prevent conflicts with sub orders that have been split.
 	// 原来订单的enter_at需要+1，防止和拆分的子订单冲突。
 	i.EnterAt += 1
@@ -461,15 +500,16 @@ func (i *InOutOrder) IsDirty() bool {
 
 func (i *InOutOrder) Save(sess *Queries) *errs.Error {
 	if core.LiveMode {
-		openOds, lock := GetOpenODs(GetTaskAcc(i.TaskID))
+		c := i.Ctx()
+		openOds, lock := c.GetOpenODs(c.GetTaskAcc(i.TaskID))
 		lock.Lock()
 		if i.Status < InOutStatusFullExit {
 			openOds[i.ID] = i
 		} else {
 			delete(openOds, i.ID)
-			mLockOds.Lock()
-			delete(lockOds, i.Key())
-			mLockOds.Unlock()
+			c.mLockOds.Lock()
+			delete(c.lockOds, i.Key())
+			c.mLockOds.Unlock()
 		}
 		lock.Unlock()
 		err := i.saveToDb(sess)
@@ -483,14 +523,15 @@ func (i *InOutOrder) Save(sess *Queries) *errs.Error {
 }
 
 func (i *InOutOrder) saveToMem() {
+	c := i.Ctx()
 	if i.ID == 0 {
 		if i.Status == InOutStatusDelete {
 			return
 		}
-		i.ID = FakeOdId
-		FakeOdId += 1
+		i.ID = c.FakeOdId
+		c.FakeOdId += 1
 	}
-	openOds, lock := GetOpenODs(GetTaskAcc(i.TaskID))
+	openOds, lock := c.GetOpenODs(c.GetTaskAcc(i.TaskID))
 	lock.Lock()
 	if i.Status < InOutStatusFullExit {
 		openOds[i.ID] = i
@@ -499,10 +540,10 @@ func (i *InOutOrder) saveToMem() {
 			delete(openOds, i.ID)
 		}
 		if i.Status == InOutStatusFullExit && i.Enter != nil && i.Enter.Filled > core.AmtDust {
-			if _, ok := doneODs[i.ID]; !ok {
-				doneODs[i.ID] = true
+			if _, ok := c.doneODs[i.ID]; !ok {
+				c.doneODs[i.ID] = true
 				// 切分的订单不会出现在openOds中
-				HistODs = append(HistODs, i)
+				c.HistODs = append(c.HistODs, i)
 			}
 		}
 	}
@@ -597,7 +638,7 @@ func (i *InOutOrder) GetInfoText() (string, *errs.Error) {
 func (i *InOutOrder) SetExitTrigger(key string, args *ExitTrigger) {
 	var empty *TriggerState
 	tg := utils2.GetMapVal(i.Info, key, empty)
-	if args == nil || args.Price == 0 {
+	if args == nil || args.Price == 0 && !args.IsTrailing() {
 		if tg != nil && tg.OrderId != "" {
 			tg.ExitTrigger = &ExitTrigger{}
 			i.SetInfo(key, tg)
@@ -608,11 +649,30 @@ func (i *InOutOrder) SetExitTrigger(key string, args *ExitTrigger) {
 	} else if tg == nil {
 		tg = &TriggerState{}
 	}
+	if args.IsTrailing() {
+		// The trailing price is updated in place, avoid sharing args between orders
+		// 跟踪止损价格会原地更新，避免多个订单共享args
+		args = args.Clone()
+		if tg.Best > 0 && !tg.Native {
+			// keep the stop price already trailed
+			// 保留已跟踪到的止损价格
+			trailPrice := args.TrailPrice(tg.Best, i.Short)
+			if args.Price == 0 || i.Short && trailPrice < args.Price || !i.Short && trailPrice > args.Price {
+				args.Price = trailPrice
+			}
+		}
+	} else {
+		tg.Best, tg.Active = 0, false
+	}
 	var rangeVal float64
 	if args.Limit != 0 {
 		rangeVal = math.Abs(i.InitPrice - args.Limit)
-	} else {
+	} else if args.Price != 0 {
 		rangeVal = math.Abs(i.InitPrice - args.Price)
+	} else if args.Callback > 0 {
+		rangeVal = i.InitPrice * args.Callback
+	} else {
+		rangeVal = args.Distance
 	}
 	tg.Range = rangeVal
 	tg.ExitTrigger = args
@@ -689,12 +749,13 @@ Return to modify the lock of the current order. A successful return indicates th
 */
 func (i *InOutOrder) Lock() *sync.Mutex {
 	odKey := i.Key()
-	mLockOds.Lock()
-	defer mLockOds.Unlock()
-	lock, ok := lockOds[odKey]
+	c := i.Ctx()
+	c.mLockOds.Lock()
+	defer c.mLockOds.Unlock()
+	lock, ok := c.lockOds[odKey]
 	if !ok {
 		lock = &sync.Mutex{}
-		lockOds[odKey] = lock
+		c.lockOds[odKey] = lock
 	}
 	var got = int32(0)
 	if core.LiveMode {
@@ -1135,7 +1196,8 @@ func (q *Queries) DelOrder(od *InOutOrder) *errs.Error {
 	if od == nil || od.ID == 0 {
 		return nil
 	}
-	openOds, lock := GetOpenODs(GetTaskAcc(od.TaskID))
+	c := od.Ctx()
+	openOds, lock := c.GetOpenODs(c.GetTaskAcc(od.TaskID))
 	lock.Lock()
 	delete(openOds, od.ID)
 	lock.Unlock()
@@ -1200,6 +1262,9 @@ func (s *TriggerState) SaveOld() {
 		s.Old.Price = s.Price
 		s.Old.Limit = s.Limit
 		s.Old.Rate = s.Rate
+		s.Old.Activate = s.Activate
+		s.Old.Callback = s.Callback
+		s.Old.Distance = s.Distance
 		if s.Tag != "" {
 			s.Old.Tag = s.Tag
 		}
@@ -1211,15 +1276,13 @@ func (s *TriggerState) Clone() *TriggerState {
 		return nil
 	}
 	return &TriggerState{
-		ExitTrigger: &ExitTrigger{
-			Price: s.Price,
-			Limit: s.Limit,
-			Rate:  s.Rate,
-			Tag:   s.Tag,
-		},
-		Range:   s.Rate,
-		Hit:     s.Hit,
-		OrderId: s.OrderId,
+		ExitTrigger: s.ExitTrigger.Clone(),
+		Range:       s.Rate,
+		Hit:         s.Hit,
+		OrderId:     s.OrderId,
+		Best:        s.Best,
+		Active:      s.Active,
+		Native:      s.Native,
 	}
 }
 
@@ -1230,6 +1293,9 @@ func (t *ExitTrigger) Equal(o *ExitTrigger) bool {
 	if t.Price != o.Price || t.Limit != o.Limit || t.Rate != o.Limit {
 		return false
 	}
+	if t.Activate != o.Activate || t.Callback != o.Callback || t.Distance != o.Distance {
+		return false
+	}
 	return true
 }
 
@@ -1238,25 +1304,87 @@ func (t *ExitTrigger) Clone() *ExitTrigger {
 		return nil
 	}
 	return &ExitTrigger{
-		Price: t.Price,
-		Limit: t.Limit,
-		Rate:  t.Rate,
-		Tag:   t.Tag,
+		Price:    t.Price,
+		Limit:    t.Limit,
+		Rate:     t.Rate,
+		Tag:      t.Tag,
+		Activate: t.Activate,
+		Callback: t.Callback,
+		Distance: t.Distance,
 	}
 }
 
+// IsTrailing whether this is a trailing stop 是否为跟踪止损
+func (t *ExitTrigger) IsTrailing() bool {
+	return t != nil && (t.Callback > 0 || t.Distance > 0)
+}
+
+/*
+TrailPrice
+Calculate the trailing stop price from the best price
+根据最优价格计算跟踪止损价格
+*/
+func (t *ExitTrigger) TrailPrice(best float64, short bool) float64 {
+	if best <= 0 {
+		return 0
+	}
+	if t.Callback > 0 {
+		if short {
+			return best * (1 + t.Callback)
+		}
+		return best * (1 - t.Callback)
+	}
+	if short {
+		return best + t.Distance
+	}
+	return max(0, best-t.Distance)
+}
+
+/*
+UpdateTrail
+Update the trailing stop with the latest price. The stop price only moves towards the profit side.
+Returns true if the stop price changed.
+使用最新价格更新跟踪止损。止损价格只会向盈利方向移动。止损价格变化时返回true
+*/
+func (s *TriggerState) UpdateTrail(price float64, short bool) bool {
+	if s == nil || !s.IsTrailing() || price <= 0 {
+		return false
+	}
+	dirt := 1.0
+	if short {
+		dirt = -1.0
+	}
+	if !s.Active {
+		if s.Activate > 0 && (price-s.Activate)*dirt < 0 {
+			return false
+		}
+		s.Active = true
+		s.Best = price
+	} else if (price-s.Best)*dirt > 0 {
+		s.Best = price
+	} else {
+		return false
+	}
+	newPrice := s.TrailPrice(s.Best, short)
+	if newPrice <= 0 || s.Price > 0 && (newPrice-s.Price)*dirt <= 0 {
+		return false
+	}
+	s.Price = newPrice
+	return true
+}
+
 /*
 LegalDoneProfits
 Calculate the fiat value of realized profits
 计算已实现利润的法币价值
 */
-func LegalDoneProfits(off int) float64 {
+func (c *Ctx) LegalDoneProfits(off int) float64 {
 	var total = float64(0)
 	var skips []string
-	for i := off; i < len(HistODs); i++ {
-		od := HistODs[i]
+	for i := off; i < len(c.HistODs); i++ {
+		od := c.HistODs[i]
 		_, quote, _, _ := core.SplitSymbol(od.Symbol)
-		price := core.GetPriceSafe(quote)
+		price := c.GetPriceSafe(quote)
 		if price == -1 {
 			skips = append(skips, quote)
 			continue
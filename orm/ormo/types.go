@@ -1,10 +1,13 @@
 package ormo
 
 type ExitTrigger struct {
-	Price float64 `json:"price,omitempty"` // Trigger Price 触发价格
-	Limit float64 `json:"limit,omitempty"` // Submit limit order price after triggering, otherwise market order. 触发后提交限价单价格，否则市价单
-	Rate  float64 `json:"rate,omitempty"`  // Stop-profit and stop-loss ratio, (0,1], 0 means all. 止盈止损比例，(0,1]，0表示全部
-	Tag   string  `json:"tag,omitempty"`   // Reason, used for ExitTag. 原因，用于ExitTag
+	Price    float64 `json:"price,omitempty"`    // Trigger Price 触发价格
+	Limit    float64 `json:"limit,omitempty"`    // Submit limit order price after triggering, otherwise market order. 触发后提交限价单价格，否则市价单
+	Rate     float64 `json:"rate,omitempty"`     // Stop-profit and stop-loss ratio, (0,1], 0 means all. 止盈止损比例，(0,1]，0表示全部
+	Tag      string  `json:"tag,omitempty"`      // Reason, used for ExitTag. 原因，用于ExitTag
+	Activate float64 `json:"activate,omitempty"` // Trailing stop activation price, 0 means active immediately. 跟踪止损激活价格，0表示立即激活
+	Callback float64 `json:"callback,omitempty"` // Trailing callback rate from the best price, e.g. 0.01 for 1%. 跟踪止损从最优价格的回调比率，如0.01表示1%
+	Distance float64 `json:"distance,omitempty"` // Trailing distance from the best price, used when Callback is 0. 跟踪止损距最优价格的绝对距离，Callback为0时使用
 }
 
 type TriggerState struct {
@@ -13,4 +16,7 @@ type TriggerState struct {
 	Hit     bool         `json:"hit,omitempty"`   // whether trigger price has been triggered? 是否已触发
 	OrderId string       `json:"order_id,omitempty"`
 	Old     *ExitTrigger `json:"old,omitempty"`
+	Best    float64      `json:"best,omitempty"`   // best price since trailing activated 跟踪止损激活后的最优价格
+	Active  bool         `json:"active,omitempty"` // whether the trailing stop is activated 跟踪止损是否已激活
+	Native  bool         `json:"native,omitempty"` // trailing is tracked by exchange 由交易所跟踪止损
 }
@@ -22,6 +22,12 @@ type AddCalendarsParams struct {
 	StopMs  int64  `json:"stop_ms"`
 }
 
+type AddFundingRatesParams struct {
+	Sid  int32   `json:"sid"`
+	Time int64   `json:"time"`
+	Rate float64 `json:"rate"`
+}
+
 const addInsKline = `-- name: AddInsKline :one
 insert into ins_kline ("sid", "timeframe", "start_ms", "stop_ms")
 values ($1, $2, $3, $4) RETURNING id
@@ -102,6 +108,22 @@ func (q *Queries) DelAdjFactors(ctx context.Context, sid int32) error {
 	return err
 }
 
+const delFundingRates = `-- name: DelFundingRates :exec
+delete from funding_rates
+where sid=$1 and time >= $2 and time < $3
+`
+
+type DelFundingRatesParams struct {
+	Sid    int32 `json:"sid"`
+	Time   int64 `json:"time"`
+	Time_2 int64 `json:"time_2"`
+}
+
+func (q *Queries) DelFundingRates(ctx context.Context, arg DelFundingRatesParams) error {
+	_, err := q.db.Exec(ctx, delFundingRates, arg.Sid, arg.Time, arg.Time_2)
+	return err
+}
+
 const delInsKline = `-- name: DelInsKline :exec
 delete from ins_kline
 where id=$1
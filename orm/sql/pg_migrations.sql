@@ -17,3 +17,14 @@ BEGIN
     ALTER TABLE public.exsymbol ALTER COLUMN symbol TYPE varchar(50);
     END IF;
 END $$;
+
+-- version 3
+-- 添加funding_rates表，存储永续合约资金费率历史
+CREATE TABLE IF NOT EXISTS "public"."funding_rates"
+(
+    "id"        BIGSERIAL  NOT NULL PRIMARY KEY,
+    "sid"       int4       not null,
+    "time"      int8       not null,
+    "rate"      float8     not null
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_funding_rates_sid_time" ON "public"."funding_rates" USING btree ("sid", "time");
@@ -98,3 +98,14 @@ where id=$1;
 -- name: AddInsKline :one
 insert into ins_kline ("sid", "timeframe", "start_ms", "stop_ms")
 values ($1, $2, $3, $4) RETURNING id;
+
+
+
+-- name: AddFundingRates :copyfrom
+insert into funding_rates
+(sid, time, rate)
+values ($1, $2, $3);
+
+-- name: DelFundingRates :exec
+delete from funding_rates
+where sid=$1 and time >= $2 and time < $3;
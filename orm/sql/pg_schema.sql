@@ -111,3 +111,16 @@ CREATE TABLE "public"."ins_kline"
 );
 CREATE INDEX "idx_ins_kline_sid" ON "public"."ins_kline" USING btree ("sid");
 
+
+-- ----------------------------
+-- Table structure for funding_rates
+-- ----------------------------
+DROP TABLE IF EXISTS "public"."funding_rates";
+CREATE TABLE "public"."funding_rates"
+(
+    "id"        BIGSERIAL  NOT NULL PRIMARY KEY,
+    "sid"       int4       not null,
+    "time"      int8       not null,
+    "rate"      float8     not null
+);
+CREATE UNIQUE INDEX "idx_funding_rates_sid_time" ON "public"."funding_rates" USING btree ("sid", "time");
@@ -83,7 +83,7 @@ func genExpAdjFactors(sess *Queries, items []*config.MarketSymbolsRange) ([]*Adj
 					return nil, err
 				}
 				for _, exs := range exsList {
-					facs, err_ := sess.GetAdjFactors(context.Background(), exs.ID)
+					facs, err_ := sess.getAdjFactors(context.Background(), exs.ID)
 					if err_ != nil {
 						return nil, errs.New(core.ErrDbReadFail, err_)
 					}
@@ -161,6 +161,50 @@ func genExpCalendars(sess *Queries, items []*config.MarketRange) ([]*CalendarBlo
 	return calendars, nil
 }
 
+func genExpFundingRates(sess *Queries, items []*config.MarketSymbolsRange) ([]*FundingRateBlock, *errs.Error) {
+	var result []*FundingRateBlock
+
+	for _, fCfg := range items {
+		startMS, stopMS, err_ := config.ParseTimeRange(fCfg.TimeRange)
+		if err_ != nil {
+			return nil, errs.New(errs.CodeRunTime, err_)
+		}
+
+		exchanges, markets := parseExgMarkets(fCfg.Exchange, fCfg.Market)
+		for _, exchange := range exchanges {
+			for _, market := range markets {
+				if !banexg.IsContract(market) {
+					continue
+				}
+				exsList, err := parseExSymbols(exchange, fCfg.ExgReal, market, fCfg.Symbols)
+				if err != nil {
+					return nil, err
+				}
+				for _, exs := range exsList {
+					rates, err := sess.GetFundingRates(exs.ID, startMS, stopMS)
+					if err != nil {
+						return nil, err
+					}
+					if len(rates) == 0 {
+						continue
+					}
+					block := &FundingRateBlock{
+						Sid:   exs.ID,
+						Times: make([]int64, 0, len(rates)),
+						Rates: make([]float64, 0, len(rates)),
+					}
+					for _, r := range rates {
+						block.Times = append(block.Times, r.Time)
+						block.Rates = append(block.Rates, r.Rate)
+					}
+					result = append(result, block)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
 func genExportTask(cfg *config.ExportConfig, pb *utils2.StagedPrg) (*ExportTask, *errs.Error) {
 	sess, conn, err := Conn(nil)
 	if err != nil {
@@ -178,10 +222,21 @@ func genExportTask(cfg *config.ExportConfig, pb *utils2.StagedPrg) (*ExportTask,
 		return nil, err
 	}
 
+	fundRates, err := genExpFundingRates(sess, cfg.FundingRates)
+	if err != nil {
+		return nil, err
+	}
+
 	jobs, exsBlockMap, err := genExportKlines(cfg.Klines, adjFactors)
 	if err != nil {
 		return nil, err
 	}
+	if len(fundRates) > 0 {
+		allExSymbols := GetAllExSymbols()
+		for _, f := range fundRates {
+			exsBlockMap[f.Sid] = allExSymbols[f.Sid]
+		}
+	}
 
 	symbols := make([]*ExSymbolBlock, 0, len(exsBlockMap))
 	for _, exs := range exsBlockMap {
@@ -205,10 +260,11 @@ func genExportTask(cfg *config.ExportConfig, pb *utils2.StagedPrg) (*ExportTask,
 	return &ExportTask{
 		jobs: jobs,
 		exInfo: &EXInfo{
-			Symbols:    symbols,
-			KHoles:     kHoles,
-			AdjFactors: adjFactors,
-			Calendars:  calendars,
+			Symbols:      symbols,
+			KHoles:       kHoles,
+			AdjFactors:   adjFactors,
+			Calendars:    calendars,
+			FundingRates: fundRates,
 		},
 	}, nil
 }
@@ -323,7 +379,7 @@ func genExpKHoles(sess *Queries, jobs []*ExportKlineJob, pb *utils2.StagedPrg) (
 		pBar.Add(1)
 		key := fmt.Sprintf("%d_%s", task.ID, task.TimeFrame)
 		r := rangeMap[key]
-		holes, err := sess.GetKHoles(context.Background(), GetKHolesParams{
+		holes, err := sess.getKHoles(context.Background(), GetKHolesParams{
 			Sid:       task.ID,
 			Timeframe: task.TimeFrame,
 			Start:     r.StartMS,
@@ -650,6 +706,9 @@ func ImportData(dataDir string, numWorkers int, pb *utils2.StagedPrg) *errs.Erro
 	if err = importCalendars(sess, exInfo.Calendars); err != nil {
 		return err
 	}
+	if err = importFundingRates(sess, idMap, exInfo.FundingRates); err != nil {
+		return err
+	}
 
 	// Get all .dat files in the directory
 	files, err_ := filepath.Glob(filepath.Join(dataDir, "kline*.dat"))
@@ -789,7 +848,7 @@ func ImportData(dataDir string, numWorkers int, pb *utils2.StagedPrg) *errs.Erro
 
 			if oldStart == 0 && oldEnd == 0 {
 				// 如果没有旧数据，直接添加新区间
-				_, err_ := sess.AddKInfo(context.Background(), AddKInfoParams{
+				_, err_ := sess.addKInfo(context.Background(), AddKInfoParams{
 					Sid:       sid,
 					Timeframe: tf,
 					Start:     newStart,
@@ -808,7 +867,7 @@ func ImportData(dataDir string, numWorkers int, pb *utils2.StagedPrg) *errs.Erro
 					}
 				}
 				// 区间重合，直接更新为大区间
-				err_ := sess.SetKInfo(context.Background(), SetKInfoParams{
+				err_ := sess.setKInfo(context.Background(), SetKInfoParams{
 					sid, tf, min(newStart, oldStart), max(newEnd, oldEnd),
 				})
 				if err_ != nil {
@@ -1011,7 +1070,7 @@ func importAdjFactors(sess *Queries, idMap map[int32]int32, items []*AdjFactorBl
 			})
 		}
 		if len(adds) > 0 {
-			_, err_ := sess.AddAdjFactors(context.Background(), adds)
+			_, err_ := sess.addAdjFactors(context.Background(), adds)
 			if err_ != nil {
 				return errs.New(core.ErrDbExecFail, err_)
 			}
@@ -1021,6 +1080,32 @@ func importAdjFactors(sess *Queries, idMap map[int32]int32, items []*AdjFactorBl
 	return nil
 }
 
+func importFundingRates(sess *Queries, idMap map[int32]int32, items []*FundingRateBlock) *errs.Error {
+	if len(items) == 0 {
+		return nil
+	}
+	addNum := 0
+	for _, it := range items {
+		sid, ok := idMap[it.Sid]
+		if !ok {
+			return errs.NewMsg(errs.CodeRunTime, "sid unknown: %v", it.Sid)
+		}
+		if len(it.Times) != len(it.Rates) {
+			return errs.NewMsg(errs.CodeRunTime, "funding rates len mismatch for sid: %v", it.Sid)
+		}
+		rates := make([]*FundingRate, 0, len(it.Times))
+		for i, t := range it.Times {
+			rates = append(rates, &FundingRate{Sid: sid, Time: t, Rate: it.Rates[i]})
+		}
+		if err := sess.SetFundingRates(sid, rates); err != nil {
+			return err
+		}
+		addNum += len(rates)
+	}
+	log.Info("fundingRates import ok", zap.Int("num", addNum))
+	return nil
+}
+
 func importCalendars(sess *Queries, cals []*CalendarBlock) *errs.Error {
 	if len(cals) == 0 {
 		return nil
@@ -1058,7 +1143,7 @@ func importCalendars(sess *Queries, cals []*CalendarBlock) *errs.Error {
 
 		// 添加新的日历数据
 		if len(newCalendars) > 0 {
-			_, err_ := sess.AddCalendars(context.Background(), newCalendars)
+			_, err_ := sess.addCalendars(context.Background(), newCalendars)
 			if err_ != nil {
 				return errs.New(core.ErrDbExecFail, err_)
 			}
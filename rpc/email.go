@@ -0,0 +1,212 @@
+package rpc
+
+import (
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/banbox/banbot/btime"
+	"github.com/banbox/banexg/log"
+	"github.com/go-viper/mapstructure/v2"
+	"go.uber.org/zap"
+)
+
+/*
+EmailHook
+Send messages by SMTP. Messages of digest_types are batched and sent as one digest email every digest_mins,
+other messages are sent immediately. Port 465 uses implicit TLS, others use STARTTLS when supported by server.
+通过SMTP发送消息。digest_types类型的消息会合并，每digest_mins分钟发送一封摘要邮件，其他消息立即发送。
+465端口使用隐式TLS，其他端口在服务器支持时使用STARTTLS。
+*/
+type EmailHook struct {
+	*WebHook
+	emailItem
+	digestTypes map[string]bool
+	digest      []string
+	digestLock  sync.Mutex
+	closed      bool
+	stop        chan struct{}
+	send        func(subject, body string) error
+}
+
+type emailItem struct {
+	Host       string   `mapstructure:"host"`
+	Port       int      `mapstructure:"port"` // default 587 默认587
+	Username   string   `mapstructure:"username"`
+	Password   string   `mapstructure:"password"`
+	From       string   `mapstructure:"from"` // default username 默认username
+	To         []string `mapstructure:"to"`
+	Subject    string   `mapstructure:"subject"`      // subject prefix, default banbot 标题前缀，默认banbot
+	DigestRaw  []string `mapstructure:"digest_types"` // default [entry, exit] 默认[entry, exit]
+	DigestMins int      `mapstructure:"digest_mins"`  // default 60, 0 to disable digest 默认60，0禁用摘要
+}
+
+const (
+	MsgTypeDigest = "digest"
+)
+
+func NewEmailHook(name string, item map[string]interface{}) *EmailHook {
+	hook := NewWebHook(name, item)
+	res := &EmailHook{
+		WebHook:     hook,
+		emailItem:   emailItem{Port: 587, Subject: "banbot", DigestMins: 60, DigestRaw: []string{MsgTypeEntry, MsgTypeExit}},
+		digestTypes: make(map[string]bool),
+		stop:        make(chan struct{}),
+	}
+	err_ := mapstructure.WeakDecode(item, &res.emailItem)
+	if err_ != nil {
+		panic(fmt.Sprintf("rpc_channels.%v is invalid: %v", name, err_))
+	}
+	if res.Host == "" || len(res.To) == 0 {
+		panic(name + ": `host`, `to` is required")
+	}
+	if res.From == "" {
+		res.From = res.Username
+	}
+	for _, t := range res.DigestRaw {
+		res.digestTypes[t] = true
+	}
+	res.send = res.sendMail
+	res.doSendMsgs = func(msgList []map[string]string) []map[string]string {
+		fails := []map[string]string{}
+		for _, msg := range msgList {
+			content, _ := msg["content"]
+			if content == "" {
+				log.Error("email get empty msg, skip")
+				continue
+			}
+			subject, _ := msg["title"]
+			if subject == "" {
+				subject = fmt.Sprintf("[%s] %s", res.Subject, msg["msg_type"])
+			}
+			if err := res.send(subject, content); err != nil {
+				log.Error("email send msg fail", zap.String("name", name), zap.String("subject", subject),
+					zap.Error(err))
+				fails = append(fails, msg)
+			}
+		}
+		return fails
+	}
+	return res
+}
+
+func (h *EmailHook) SendMsg(msgType string, account string, payload map[string]string) bool {
+	payload = withMsgMeta(msgType, account, payload)
+	if h.DigestMins <= 0 || !h.digestTypes[msgType] {
+		return h.WebHook.SendMsg(msgType, account, payload)
+	}
+	if !h.allowMsg(msgType, account, payload) {
+		return false
+	}
+	content, _ := payload["content"]
+	if content == "" {
+		return false
+	}
+	h.digestLock.Lock()
+	h.digest = append(h.digest, btime.ToDateStr(btime.TimeMS(), "")+"\n"+content)
+	h.digestLock.Unlock()
+	return true
+}
+
+func (h *EmailHook) ConsumeForever() {
+	if h.Disable {
+		return
+	}
+	if h.DigestMins > 0 {
+		go func() {
+			ticker := time.NewTicker(time.Duration(h.DigestMins) * time.Minute)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					h.flushDigest()
+				case <-h.stop:
+					return
+				}
+			}
+		}()
+	}
+	h.WebHook.ConsumeForever()
+}
+
+// flushDigest merge cached low priority messages into one and put it to queue 合并缓存的低优先级消息并放入队列
+func (h *EmailHook) flushDigest() {
+	h.digestLock.Lock()
+	defer h.digestLock.Unlock()
+	if h.closed || len(h.digest) == 0 {
+		return
+	}
+	payload := map[string]string{
+		"msg_type": MsgTypeDigest,
+		"title":    fmt.Sprintf("[%s] digest of %d messages", h.Subject, len(h.digest)),
+		"content":  strings.Join(h.digest, "\n\n"),
+	}
+	h.digest = nil
+	h.wg.Add(1)
+	h.Queue <- payload
+}
+
+func (h *EmailHook) CleanUp() {
+	h.flushDigest()
+	h.digestLock.Lock()
+	h.closed = true
+	h.digestLock.Unlock()
+	close(h.stop)
+	h.WebHook.CleanUp()
+}
+
+func (h *EmailHook) sendMail(subject, body string) error {
+	var b strings.Builder
+	b.WriteString("From: " + h.From + "\r\n")
+	b.WriteString("To: " + strings.Join(h.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + btime.ToTime(btime.UTCStamp()).Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	msg := []byte(b.String())
+	addr := fmt.Sprintf("%s:%d", h.Host, h.Port)
+	var auth smtp.Auth
+	if h.Username != "" {
+		auth = smtp.PlainAuth("", h.Username, h.Password, h.Host)
+	}
+	if h.Port != 465 {
+		return smtp.SendMail(addr, auth, h.From, h.To, msg)
+	}
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: h.Host})
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, h.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if auth != nil {
+		if err = c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(h.From); err != nil {
+		return err
+	}
+	for _, to := range h.To {
+		if err = c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,99 @@
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/banbox/banbot/btime"
+	"github.com/banbox/banexg/log"
+	"github.com/banbox/banexg/utils"
+	"github.com/go-viper/mapstructure/v2"
+	"go.uber.org/zap"
+)
+
+/*
+JsonHook
+POST the rendered payload with msg_type and account as json to url. If secret is set, the hex HMAC-SHA256 of
+`timestamp.body` is sent in sign_header, and the timestamp in milliseconds is sent in X-Timestamp.
+将渲染后的payload连同msg_type和account以json格式POST到url。设置secret时，`timestamp.body`的十六进制HMAC-SHA256
+通过sign_header发送，毫秒时间戳通过X-Timestamp发送。
+*/
+type JsonHook struct {
+	*WebHook
+	jsonHookItem
+}
+
+type jsonHookItem struct {
+	URL        string            `mapstructure:"url"`
+	Secret     string            `mapstructure:"secret"`
+	SignHeader string            `mapstructure:"sign_header"` // default X-Signature 默认X-Signature
+	Headers    map[string]string `mapstructure:"headers"`
+}
+
+const (
+	headerTimestamp = "X-Timestamp"
+)
+
+func NewJsonHook(name string, item map[string]interface{}) *JsonHook {
+	hook := NewWebHook(name, item)
+	res := &JsonHook{WebHook: hook}
+	err_ := mapstructure.Decode(item, &res.jsonHookItem)
+	if err_ != nil {
+		panic(fmt.Sprintf("rpc_channels.%v is invalid: %v", name, err_))
+	}
+	if res.URL == "" {
+		panic(name + ": `url` is required")
+	}
+	if res.SignHeader == "" {
+		res.SignHeader = "X-Signature"
+	}
+	if res.RetryBackoff == 0 {
+		res.RetryBackoff = 2
+	}
+	res.doSendMsgs = func(msgList []map[string]string) []map[string]string {
+		fails := []map[string]string{}
+		for _, msg := range msgList {
+			body, err_ := utils.MarshalString(msg)
+			if err_ != nil {
+				log.Error("json hook marshal req fail", zap.String("name", name), zap.Error(err_))
+				continue
+			}
+			rsp := requestWith("POST", res.URL, body, res.makeHeaders(body))
+			if rsp.Status < 200 || rsp.Status >= 300 {
+				log.Error("json hook send msg fail", zap.String("name", name), zap.Int("status", rsp.Status),
+					zap.String("rsp", rsp.Content), zap.Error(rsp.Error))
+				fails = append(fails, msg)
+			}
+		}
+		return fails
+	}
+	return res
+}
+
+func (h *JsonHook) SendMsg(msgType string, account string, payload map[string]string) bool {
+	return h.WebHook.SendMsg(msgType, account, withMsgMeta(msgType, account, payload))
+}
+
+func (h *JsonHook) makeHeaders(body string) map[string]string {
+	res := map[string]string{"Content-Type": "application/json"}
+	for k, v := range h.Headers {
+		res[http.CanonicalHeaderKey(k)] = v
+	}
+	if h.Secret != "" {
+		stamp := strconv.FormatInt(btime.UTCStamp(), 10)
+		res[headerTimestamp] = stamp
+		res[h.SignHeader] = signHmac(h.Secret, stamp+"."+body)
+	}
+	return res
+}
+
+// signHmac hex encoded HMAC-SHA256 十六进制编码的HMAC-SHA256
+func signHmac(secret, text string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(text))
+	return hex.EncodeToString(mac.Sum(nil))
+}
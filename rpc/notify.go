@@ -56,6 +56,14 @@ func initWebHooks() *errs.Error {
 		switch chlType {
 		case "wework":
 			channel = NewWeWork(name, item)
+		case "telegram":
+			channel = NewTelegram(name, item)
+		case "json":
+			channel = NewJsonHook(name, item)
+		case "email":
+			channel = NewEmailHook(name, item)
+		case "slack", "discord":
+			channel = NewSlackHook(name, item)
 		default:
 			return errs.NewMsg(core.ErrBadConfig, "RPCChannel not support: %v", chlType)
 		}
@@ -0,0 +1,74 @@
+package rpc
+
+import (
+	"strings"
+
+	"github.com/banbox/banexg/log"
+	"github.com/banbox/banexg/utils"
+	"go.uber.org/zap"
+)
+
+/*
+SlackHook
+Incoming webhook of Slack(type: slack) or Discord(type: discord), the content of payload is sent as message text.
+Slack(type: slack)或Discord(type: discord)的incoming webhook，payload的content作为消息文本发送。
+https://api.slack.com/messaging/webhooks
+https://discord.com/developers/docs/resources/webhook#execute-webhook
+*/
+type SlackHook struct {
+	*WebHook
+	url      string
+	textKey  string
+	userName string
+}
+
+const (
+	discordMaxLen = 2000
+)
+
+func NewSlackHook(name string, item map[string]interface{}) *SlackHook {
+	hook := NewWebHook(name, item)
+	res := &SlackHook{
+		WebHook:  hook,
+		url:      utils.GetMapVal(item, "url", ""),
+		textKey:  "text",
+		userName: utils.GetMapVal(item, "username", ""),
+	}
+	if res.url == "" {
+		panic(name + ": `url` is required")
+	}
+	if hook.ChlType == "discord" {
+		res.textKey = "content"
+	}
+	res.doSendMsgs = func(msgList []map[string]string) []map[string]string {
+		fails := []map[string]string{}
+		for _, msg := range msgList {
+			content, _ := msg["content"]
+			if content == "" {
+				log.Error(name+" get empty msg, skip", zap.String("type", hook.ChlType))
+				continue
+			}
+			if chars := []rune(content); res.textKey == "content" && len(chars) > discordMaxLen {
+				content = string(chars[:discordMaxLen])
+			}
+			var body = map[string]string{res.textKey: content}
+			if res.userName != "" {
+				body["username"] = res.userName
+			}
+			bodyText, err_ := utils.MarshalString(body)
+			if err_ != nil {
+				log.Error("slack marshal req fail", zap.String("content", content), zap.Error(err_))
+				continue
+			}
+			// url contains the secret token, don't log rsp.Error url包含密钥，不记录rsp.Error
+			rsp := requestJSON("POST", res.url, bodyText)
+			if rsp.Status < 200 || rsp.Status >= 300 {
+				log.Error(hook.ChlType+" send msg fail", zap.String("name", name), zap.Int("status", rsp.Status),
+					zap.String("rsp", strings.TrimSpace(rsp.Content)))
+				fails = append(fails, msg)
+			}
+		}
+		return fails
+	}
+	return res
+}
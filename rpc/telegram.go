@@ -0,0 +1,295 @@
+package rpc
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banexg/log"
+	"github.com/banbox/banexg/utils"
+	"github.com/go-viper/mapstructure/v2"
+	"go.uber.org/zap"
+)
+
+/**
+Telegram bot: send messages and receive commands from whitelisted chats
+Telegram机器人：发送消息，并接收白名单聊天的命令
+https://core.telegram.org/bots/api
+*/
+
+type Telegram struct {
+	*WebHook
+	telegramItem
+	allowChats map[string]bool
+	offset     int64
+}
+
+type telegramItem struct {
+	Token      string   `mapstructure:"token"`
+	ChatID     string   `mapstructure:"chat_id"`     // chat to send messages 发送消息的聊天ID
+	AllowChats []string `mapstructure:"allow_chats"` // chats allowed to send commands, default chat_id 允许发送命令的聊天，默认chat_id
+	APIURL     string   `mapstructure:"api_url"`     // default https://api.telegram.org 默认https://api.telegram.org
+	Account    string   `mapstructure:"account"`     // account for commands, default the first of accounts 命令操作的账户，默认accounts的第一个
+}
+
+/*
+FuncBotCmd
+Handle a command from rpc channels like telegram for account, returns the text to reply.
+Registered by the live web api, so commands share the same logic with it.
+处理来自telegram等rpc渠道针对账户的命令，返回回复的文本。由实盘web api注册，以便命令与其共用相同逻辑。
+*/
+type FuncBotCmd = func(acc string, args []string) (string, error)
+
+var (
+	BotCmds   = make(map[string]FuncBotCmd)  // command name without `/` -> handler 不含`/`的命令名
+	tgPolling = make(map[string][]*Telegram) // token -> channels of the bot, the first one polls 机器人token -> 渠道，第一个负责拉取
+	tgLock    sync.Mutex
+)
+
+const (
+	tgApiURL      = "https://api.telegram.org"
+	tgPollTimeout = 30
+)
+
+func NewTelegram(name string, item map[string]interface{}) *Telegram {
+	hook := NewWebHook(name, item)
+	res := &Telegram{
+		WebHook:    hook,
+		allowChats: make(map[string]bool),
+	}
+	err_ := mapstructure.WeakDecode(item, &res.telegramItem)
+	if err_ != nil {
+		panic(fmt.Sprintf("rpc_channels.%v is invalid: %v", name, err_))
+	}
+	if res.Token == "" || res.ChatID == "" {
+		panic(name + ": `token`, `chat_id` is required")
+	}
+	res.APIURL = strings.TrimSuffix(res.APIURL, "/")
+	if res.APIURL == "" {
+		res.APIURL = tgApiURL
+	}
+	if res.Account == "" {
+		if len(res.AccountsRaw) > 0 {
+			res.Account = res.AccountsRaw[0]
+		} else {
+			res.Account = config.DefAcc
+		}
+	}
+	chats := res.AllowChats
+	if len(chats) == 0 {
+		chats = []string{res.ChatID}
+	}
+	for _, c := range chats {
+		res.allowChats[c] = true
+	}
+	res.doSendMsgs = func(msgList []map[string]string) []map[string]string {
+		fails := []map[string]string{}
+		for _, msg := range msgList {
+			content, _ := msg["content"]
+			if content == "" {
+				log.Error("telegram get empty msg, skip")
+				continue
+			}
+			if !res.sendText(res.ChatID, content) {
+				fails = append(fails, msg)
+			}
+		}
+		return fails
+	}
+	return res
+}
+
+func (t *Telegram) ConsumeForever() {
+	if t.Disable {
+		return
+	}
+	tgLock.Lock()
+	chls := tgPolling[t.Token]
+	tgPolling[t.Token] = append(chls, t)
+	tgLock.Unlock()
+	// only one channel can poll updates for the same bot, commands are routed by account
+	// 同一个机器人只能有一个渠道拉取更新，命令按账户路由
+	if len(chls) == 0 {
+		go t.pollForever()
+	}
+	t.WebHook.ConsumeForever()
+}
+
+func (t *Telegram) CleanUp() {
+	t.WebHook.CleanUp()
+	tgLock.Lock()
+	chls := slices.DeleteFunc(tgPolling[t.Token], func(c *Telegram) bool {
+		return c == t
+	})
+	if len(chls) == 0 {
+		delete(tgPolling, t.Token)
+	} else {
+		tgPolling[t.Token] = chls
+	}
+	tgLock.Unlock()
+}
+
+// botChannels return channels sharing the bot token of t 返回与t共用机器人token的渠道
+func (t *Telegram) botChannels() []*Telegram {
+	tgLock.Lock()
+	defer tgLock.Unlock()
+	chls := slices.Clone(tgPolling[t.Token])
+	if len(chls) == 0 {
+		chls = []*Telegram{t}
+	}
+	return chls
+}
+
+type tgRsp struct {
+	Ok          bool   `json:"ok"`
+	Description string `json:"description"`
+}
+
+type tgUpdatesRsp struct {
+	tgRsp
+	Result []*tgUpdate `json:"result"`
+}
+
+type tgUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Text string `json:"text"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+}
+
+func (t *Telegram) apiURL(method string) string {
+	return fmt.Sprintf("%s/bot%s/%s", t.APIURL, t.Token, method)
+}
+
+func (t *Telegram) sendText(chatID, text string) bool {
+	body, err_ := utils.MarshalString(map[string]string{
+		"chat_id": chatID,
+		"text":    text,
+	})
+	if err_ != nil {
+		log.Error("telegram marshal req fail", zap.String("content", text), zap.Error(err_))
+		return true
+	}
+	// error of rsp contains the url with token, don't log it 响应的错误包含带token的url，不记录
+	rsp := requestJSON("POST", t.apiURL("sendMessage"), body)
+	if rsp.Status != 200 {
+		log.Error("telegram send msg net fail", zap.String("content", text), zap.Int("status", rsp.Status),
+			zap.String("rsp", rsp.Content))
+		return false
+	}
+	var res tgRsp
+	err_ = utils.UnmarshalString(rsp.Content, &res, utils.JsonNumDefault)
+	if err_ != nil || !res.Ok {
+		log.Warn("telegram send msg fail", zap.String("content", text), zap.String("body", rsp.Content))
+		return false
+	}
+	return true
+}
+
+func (t *Telegram) pollForever() {
+	for !t.Disable {
+		updates, ok := t.getUpdates()
+		if !ok {
+			if !core.Sleep(5 * time.Second) {
+				return
+			}
+			continue
+		}
+		for _, u := range updates {
+			t.offset = max(t.offset, u.UpdateID+1)
+			if u.Message == nil || u.Message.Text == "" {
+				continue
+			}
+			chatID := strconv.FormatInt(u.Message.Chat.ID, 10)
+			reply := t.handleCmd(chatID, u.Message.Text)
+			if reply != "" {
+				t.sendText(chatID, reply)
+			}
+		}
+	}
+}
+
+func (t *Telegram) getUpdates() ([]*tgUpdate, bool) {
+	args := url.Values{}
+	args.Set("offset", strconv.FormatInt(t.offset, 10))
+	args.Set("timeout", strconv.Itoa(tgPollTimeout))
+	rsp := request("GET", t.apiURL("getUpdates")+"?"+args.Encode(), "")
+	if rsp.Status != 200 {
+		log.Warn("telegram get updates fail", zap.Int("status", rsp.Status), zap.String("rsp", rsp.Content))
+		return nil, false
+	}
+	var res tgUpdatesRsp
+	err_ := utils.UnmarshalString(rsp.Content, &res, utils.JsonNumDefault)
+	if err_ != nil || !res.Ok {
+		log.Warn("telegram parse updates fail", zap.String("body", rsp.Content))
+		return nil, false
+	}
+	return res.Result, true
+}
+
+/*
+handleCmd
+Parse text like `/forceexit 12` and run the registered command, returns the reply text.
+When several accounts share the bot, the first argument can be an account like `/forceexit user2 12`, the
+account of this channel is used if omitted. The chat must be allowed by the channel of the account.
+解析如`/forceexit 12`的文本并执行注册的命令，返回回复文本。
+多个账户共用机器人时，第一个参数可以是账户如`/forceexit user2 12`，省略时使用此渠道的账户。聊天须被该账户的渠道允许。
+*/
+func (t *Telegram) handleCmd(chatID, text string) string {
+	fields := strings.Fields(text)
+	chls := t.botChannels()
+	target := t
+	if len(fields) > 1 {
+		for _, c := range chls {
+			if c.Account == fields[1] {
+				target = c
+				fields = append(fields[:1], fields[2:]...)
+				break
+			}
+		}
+	}
+	if !target.allowChats[chatID] {
+		log.Warn("telegram cmd from unknown chat, ignore", zap.String("chat", chatID),
+			zap.String("acc", target.Account), zap.String("text", text))
+		return ""
+	}
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return ""
+	}
+	// commands in group may be `/cmd@bot_name` 群组中的命令可能是`/cmd@bot_name`
+	name, _, _ := strings.Cut(strings.TrimPrefix(fields[0], "/"), "@")
+	cmd, ok := BotCmds[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(BotCmds))
+		for k := range BotCmds {
+			names = append(names, "/"+k)
+		}
+		sort.Strings(names)
+		reply := fmt.Sprintf("unknown command: %s\navailable: %s", fields[0], strings.Join(names, " "))
+		if len(chls) > 1 {
+			accs := make([]string, 0, len(chls))
+			for _, c := range chls {
+				accs = append(accs, c.Account)
+			}
+			reply += fmt.Sprintf("\naccounts: %s, default %s, e.g. /cmd %s args", strings.Join(accs, " "),
+				t.Account, accs[len(accs)-1])
+		}
+		return reply
+	}
+	log.Info("telegram run cmd", zap.String("acc", target.Account), zap.String("text", text))
+	reply, err := cmd(target.Account, fields[1:])
+	if err != nil {
+		return fmt.Sprintf("%s fail: %v", fields[0], err)
+	}
+	return reply
+}
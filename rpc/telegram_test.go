@@ -0,0 +1,179 @@
+package rpc
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/banbox/banexg/utils"
+)
+
+func TestTelegram(t *testing.T) {
+	var lock sync.Mutex
+	var sent []map[string]string
+	polled := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/bottk/sendMessage"):
+			body, _ := io.ReadAll(r.Body)
+			var msg map[string]string
+			_ = utils.UnmarshalString(string(body), &msg, utils.JsonNumDefault)
+			sent = append(sent, msg)
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		case strings.HasSuffix(r.URL.Path, "/bottk/getUpdates"):
+			if polled {
+				time.Sleep(50 * time.Millisecond)
+				_, _ = w.Write([]byte(`{"ok":true,"result":[]}`))
+				return
+			}
+			polled = true
+			_, _ = w.Write([]byte(`{"ok":true,"result":[
+{"update_id":1,"message":{"text":"/pause 2","chat":{"id":-100}}},
+{"update_id":2,"message":{"text":"/pause@bot","chat":{"id":123}}},
+{"update_id":3,"message":{"text":"/unknown","chat":{"id":123}}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	var gotAcc string
+	var gotArgs []string
+	BotCmds["pause"] = func(acc string, args []string) (string, error) {
+		gotAcc, gotArgs = acc, args
+		return "paused", nil
+	}
+	defer delete(BotCmds, "pause")
+
+	chl := NewTelegram("tg", map[string]interface{}{
+		"token":    "tk",
+		"chat_id":  123,
+		"api_url":  srv.URL + "/",
+		"accounts": []string{"user1"},
+	})
+	go chl.ConsumeForever()
+	chl.SendMsg(MsgTypeStatus, "", map[string]string{"content": "hello"})
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		lock.Lock()
+		num := len(sent)
+		lock.Unlock()
+		if num >= 3 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	chl.SetDisable(true)
+	chl.CleanUp()
+
+	lock.Lock()
+	defer lock.Unlock()
+	texts := make(map[string]string)
+	for _, m := range sent {
+		if m["chat_id"] != "123" {
+			t.Errorf("should only send to 123, got %v", m["chat_id"])
+		}
+		texts[m["text"]] = m["chat_id"]
+	}
+	if len(sent) != 3 {
+		t.Fatalf("expect 3 msgs, got %v", sent)
+	}
+	if _, ok := texts["hello"]; !ok {
+		t.Errorf("webhook msg not sent: %v", sent)
+	}
+	if _, ok := texts["paused"]; !ok {
+		t.Errorf("pause reply not sent: %v", sent)
+	}
+	if gotAcc != "user1" || len(gotArgs) != 0 {
+		t.Errorf("cmd from unknown chat should be ignored, got acc %v args %v", gotAcc, gotArgs)
+	}
+}
+
+func TestTelegramAccounts(t *testing.T) {
+	var lock sync.Mutex
+	var sent []map[string]string
+	polled := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/bottk2/sendMessage"):
+			body, _ := io.ReadAll(r.Body)
+			var msg map[string]string
+			_ = utils.UnmarshalString(string(body), &msg, utils.JsonNumDefault)
+			sent = append(sent, msg)
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		case strings.HasSuffix(r.URL.Path, "/bottk2/getUpdates"):
+			if polled {
+				time.Sleep(50 * time.Millisecond)
+				_, _ = w.Write([]byte(`{"ok":true,"result":[]}`))
+				return
+			}
+			polled = true
+			_, _ = w.Write([]byte(`{"ok":true,"result":[
+{"update_id":1,"message":{"text":"/pause user2 3","chat":{"id":123}}},
+{"update_id":2,"message":{"text":"/pause 5","chat":{"id":456}}},
+{"update_id":3,"message":{"text":"/pause user2","chat":{"id":456}}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	var calls []string
+	BotCmds["pause"] = func(acc string, args []string) (string, error) {
+		lock.Lock()
+		calls = append(calls, acc+":"+strings.Join(args, ","))
+		lock.Unlock()
+		return "paused " + acc, nil
+	}
+	defer delete(BotCmds, "pause")
+
+	chl1 := NewTelegram("tg1", map[string]interface{}{
+		"token":       "tk2",
+		"chat_id":     123,
+		"allow_chats": []string{"123", "456"},
+		"api_url":     srv.URL,
+		"account":     "user1",
+	})
+	chl2 := NewTelegram("tg2", map[string]interface{}{
+		"token":   "tk2",
+		"chat_id": 123,
+		"api_url": srv.URL,
+		"account": "user2",
+	})
+	// register both before the poller handles updates 在拉取处理更新前注册两个渠道
+	tgLock.Lock()
+	tgPolling["tk2"] = []*Telegram{chl1, chl2}
+	tgLock.Unlock()
+	go chl1.pollForever()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		lock.Lock()
+		num := len(sent)
+		lock.Unlock()
+		if num >= 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	chl1.SetDisable(true)
+	chl1.CleanUp()
+	chl2.CleanUp()
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(calls) != 2 || calls[0] != "user2:3" || calls[1] != "user1:5" {
+		t.Errorf("commands should be routed by account arg, got %v", calls)
+	}
+	if len(sent) != 2 || sent[0]["text"] != "paused user2" || sent[1]["chat_id"] != "456" {
+		t.Errorf("unexpected replies: %v", sent)
+	}
+	if _, ok := tgPolling["tk2"]; ok {
+		t.Errorf("token should be removed after all channels clean up")
+	}
+}
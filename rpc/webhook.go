@@ -30,13 +30,14 @@ type WebHook struct {
 }
 
 type webHookItem struct {
-	MsgTypesRaw []string `mapstructure:"msg_types"`
-	AccountsRaw []string `mapstructure:"accounts"`
-	Keywords    []string `mapstructure:"keywords"`
-	RetryNum    int      `mapstructure:"retry_num"`   // Retry times 重试次数
-	RetryDelay  int      `mapstructure:"retry_delay"` // Retry interval 重试间隔
-	Disable     bool     `mapstructure:"disable"`     // 是否禁用
-	ChlType     string   `mapstructure:"type"`        // Channel Type 渠道类型
+	MsgTypesRaw  []string `mapstructure:"msg_types"`
+	AccountsRaw  []string `mapstructure:"accounts"`
+	Keywords     []string `mapstructure:"keywords"`
+	RetryNum     int      `mapstructure:"retry_num"`     // Retry times 重试次数
+	RetryDelay   int      `mapstructure:"retry_delay"`   // Retry interval 重试间隔
+	RetryBackoff float64  `mapstructure:"retry_backoff"` // Multiplier of interval after each retry 每次重试后间隔的倍数
+	Disable      bool     `mapstructure:"disable"`       // 是否禁用
+	ChlType      string   `mapstructure:"type"`          // Channel Type 渠道类型
 }
 
 const (
@@ -106,6 +107,16 @@ func (h *WebHook) SetDisable(val bool) {
 }
 
 func (h *WebHook) SendMsg(msgType string, account string, payload map[string]string) bool {
+	if !h.allowMsg(msgType, account, payload) {
+		return false
+	}
+	h.Queue <- payload
+	h.wg.Add(1)
+	return true
+}
+
+// allowMsg check msg_types, accounts and keywords of channel 检查渠道的msg_types、accounts和keywords
+func (h *WebHook) allowMsg(msgType string, account string, payload map[string]string) bool {
 	if h.Disable {
 		return false
 	}
@@ -131,8 +142,6 @@ func (h *WebHook) SendMsg(msgType string, account string, payload map[string]str
 			return false
 		}
 	}
-	h.Queue <- payload
-	h.wg.Add(1)
 	return true
 }
 
@@ -177,9 +186,13 @@ func (h *WebHook) ConsumeForever() {
 
 func (h *WebHook) doSendRetry(msgList []map[string]string) {
 	attempts, totalNum := 0, len(msgList)
+	delay := float64(h.RetryDelay)
 	for len(msgList) > 0 && attempts < h.RetryNum+1 {
 		if attempts > 0 {
-			core.Sleep(time.Duration(h.RetryDelay) * time.Second)
+			core.Sleep(time.Duration(delay * float64(time.Second)))
+			if h.RetryBackoff > 1 {
+				delay *= h.RetryBackoff
+			}
 		}
 		attempts += 1
 		msgList = h.doSendMsgs(msgList)
@@ -187,7 +200,35 @@ func (h *WebHook) doSendRetry(msgList []map[string]string) {
 	h.wg.Add(0 - totalNum)
 }
 
+/*
+withMsgMeta
+Copy payload and add msg_type and account for channels which need them, payload is shared by all channels
+复制payload并添加msg_type和account，供需要的渠道使用，payload被所有渠道共享
+*/
+func withMsgMeta(msgType, account string, payload map[string]string) map[string]string {
+	res := make(map[string]string, len(payload)+2)
+	for k, v := range payload {
+		res[k] = v
+	}
+	if _, ok := res["msg_type"]; !ok {
+		res["msg_type"] = msgType
+	}
+	if _, ok := res["account"]; !ok && account != "" {
+		res["account"] = account
+	}
+	return res
+}
+
 func request(method, url, body string) *banexg.HttpRes {
+	return requestWith(method, url, body, nil)
+}
+
+// requestJSON request with json body 发送json请求体
+func requestJSON(method, url, body string) *banexg.HttpRes {
+	return requestWith(method, url, body, map[string]string{"Content-Type": "application/json"})
+}
+
+func requestWith(method, url, body string, headers map[string]string) *banexg.HttpRes {
 	if client == nil {
 		client = &http.Client{}
 	}
@@ -199,5 +240,8 @@ func request(method, url, body string) *banexg.HttpRes {
 	if err_ != nil {
 		return &banexg.HttpRes{Error: errs.New(core.ErrRunTime, err_)}
 	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
 	return utils2.DoHttp(client, req)
 }
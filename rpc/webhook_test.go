@@ -0,0 +1,145 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banexg/utils"
+)
+
+// waitFor wait until cond is true or timeout 等待直到cond为true或超时
+func waitFor(cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (r *hookRecorder) count() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.bodies)
+}
+
+type hookRecorder struct {
+	lock    sync.Mutex
+	bodies  []map[string]string
+	headers []http.Header
+	fails   int // number of requests to fail 需要失败的请求数
+}
+
+func (r *hookRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.fails > 0 {
+		r.fails -= 1
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	data, _ := io.ReadAll(req.Body)
+	var body map[string]string
+	_ = utils.UnmarshalString(string(data), &body, utils.JsonNumDefault)
+	r.bodies = append(r.bodies, body)
+	r.headers = append(r.headers, req.Header.Clone())
+}
+
+func TestJsonHook(t *testing.T) {
+	core.Ctx = context.Background()
+	rec := &hookRecorder{fails: 1}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	chl := NewJsonHook("js", map[string]interface{}{
+		"url":       srv.URL,
+		"secret":    "abc",
+		"msg_types": []string{MsgTypeEntry},
+		"keywords":  []string{"BTC"},
+		"retry_num": 1,
+		"headers":   map[string]string{"x-token": "t1"},
+	})
+	go chl.ConsumeForever()
+	chl.SendMsg(MsgTypeExit, "user1", map[string]string{"content": "BTC exit"})
+	chl.SendMsg(MsgTypeEntry, "user1", map[string]string{"content": "ETH entry"})
+	payload := map[string]string{"content": "BTC entry"}
+	chl.SendMsg(MsgTypeEntry, "user1", payload)
+	waitFor(func() bool { return rec.count() >= 1 })
+	chl.CleanUp()
+
+	if len(payload) != 1 {
+		t.Errorf("shared payload should not be modified: %v", payload)
+	}
+	if len(rec.bodies) != 1 {
+		t.Fatalf("expect 1 msg after retry, got %v", rec.bodies)
+	}
+	body, head := rec.bodies[0], rec.headers[0]
+	if body["content"] != "BTC entry" || body["msg_type"] != MsgTypeEntry || body["account"] != "user1" {
+		t.Errorf("bad body: %v", body)
+	}
+	data, _ := utils.MarshalString(body)
+	if head.Get("X-Signature") != signHmac("abc", head.Get(headerTimestamp)+"."+data) {
+		t.Errorf("bad signature: %v", head.Get("X-Signature"))
+	}
+	if head.Get("X-Token") != "t1" {
+		t.Errorf("custom header missing: %v", head)
+	}
+}
+
+func TestSlackHook(t *testing.T) {
+	rec := &hookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	for _, chlType := range []string{"slack", "discord"} {
+		chl := NewSlackHook(chlType, map[string]interface{}{"type": chlType, "url": srv.URL})
+		go chl.ConsumeForever()
+		chl.SendMsg(MsgTypeStatus, "", map[string]string{"content": "hi " + chlType})
+		num := len(rec.bodies)
+		waitFor(func() bool { return rec.count() > num })
+		chl.CleanUp()
+	}
+	if len(rec.bodies) != 2 || rec.bodies[0]["text"] != "hi slack" || rec.bodies[1]["content"] != "hi discord" {
+		t.Errorf("bad bodies: %v", rec.bodies)
+	}
+}
+
+func TestEmailDigest(t *testing.T) {
+	chl := NewEmailHook("mail", map[string]interface{}{
+		"host":         "127.0.0.1",
+		"to":           []string{"a@b.com"},
+		"digest_types": []string{MsgTypeEntry, MsgTypeExit},
+	})
+	var lock sync.Mutex
+	subjects := make(map[string]string)
+	chl.send = func(subject, body string) error {
+		lock.Lock()
+		subjects[subject] = body
+		lock.Unlock()
+		return nil
+	}
+	go chl.ConsumeForever()
+	chl.SendMsg(MsgTypeEntry, "", map[string]string{"content": "enter BTC"})
+	chl.SendMsg(MsgTypeExit, "", map[string]string{"content": "exit BTC"})
+	chl.SendMsg(MsgTypeException, "", map[string]string{"content": "boom"})
+	waitFor(func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(subjects) > 0
+	})
+	chl.CleanUp()
+
+	if subjects["[banbot] exception"] != "boom" {
+		t.Errorf("exception should be sent at once: %v", subjects)
+	}
+	digest := subjects["[banbot] digest of 2 messages"]
+	if !strings.Contains(digest, "enter BTC") || !strings.Contains(digest, "exit BTC") {
+		t.Errorf("entry and exit should be merged into digest: %v", subjects)
+	}
+	if len(subjects) != 2 {
+		t.Errorf("expect 2 emails, got %v", subjects)
+	}
+}
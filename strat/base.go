@@ -25,8 +25,8 @@ func (s *TradeStrat) GetStakeAmount(j *StratJob) float64 {
 		amount *= s.StakeRate
 	}
 	// 乘以此任务的开单倍率
-	key := core.KeyStratPairTf(j.Strat.Name, j.Symbol.Symbol, j.TimeFrame)
-	pref, _ := core.JobPerfs[key]
+	key := core.KeyStratPairTf(j.Strat.Name, j.Symbol.PairKey(), j.TimeFrame)
+	pref, _ := j.Ctx().JobPerfs[key]
 	if pref != nil {
 		amount = pref.GetAmount(amount)
 	}
@@ -84,7 +84,7 @@ func (s *StratJob) CanOpen(short bool) bool {
 	disable := false
 	if short {
 		disable = s.MaxOpenShort < 0 || s.MaxOpenShort > 0 && len(s.ShortOrders) >= s.MaxOpenShort
-		if core.Market == banexg.MarketSpot {
+		if s.Symbol.Market == banexg.MarketSpot {
 			disable = true
 		}
 	} else {
@@ -94,6 +94,22 @@ func (s *StratJob) CanOpen(short bool) bool {
 }
 
 func (s *StratJob) OpenOrder(req *EnterReq) *errs.Error {
+	err := s.CheckEnter(req)
+	if err != nil {
+		return err
+	}
+	s.Entrys = append(s.Entrys, req)
+	s.OrderNum += 1
+	return nil
+}
+
+/*
+CheckEnter
+Validate req and fill stop loss, take profit, cost and order type like OpenOrder, without adding it to Entrys.
+Used by manual orders from api.
+像OpenOrder一样校验req并填充止损、止盈、金额和订单类型，但不添加到Entrys。用于api手动开单。
+*/
+func (s *StratJob) CheckEnter(req *EnterReq) *errs.Error {
 	if req.Tag == "" {
 		return errs.NewMsg(errs.CodeParamRequired, "tag is Required")
 	}
@@ -101,7 +117,7 @@ func (s *StratJob) OpenOrder(req *EnterReq) *errs.Error {
 		req.StratName = s.Strat.Name
 	}
 	isLiveMode := core.LiveMode
-	symbol := s.Symbol.Symbol
+	symbol := s.Symbol.PairKey()
 	var dirType = core.OdDirtLong
 	if req.Short {
 		dirType = core.OdDirtShort
@@ -114,13 +130,14 @@ func (s *StratJob) OpenOrder(req *EnterReq) *errs.Error {
 				zap.String("tag", req.Tag),
 				zap.Int("dir", dirType))
 		}
-		AddAccFailOpen(s.Account, FailOpenBadDirtOrLimit)
+		s.Ctx().AddAccFailOpen(s.Account, FailOpenBadDirtOrLimit)
 		return errs.NewMsg(errs.CodeParamInvalid, "open order disabled")
 	}
 	if math.IsNaN(req.Limit+req.Amount+req.Leverage+req.CostRate+req.LegalCost) ||
 		math.IsNaN(req.StopLoss+req.StopLossVal+req.StopLossLimit+req.StopLossRate) ||
+		math.IsNaN(req.TrailActivate+req.TrailCallback+req.TrailDistance) ||
 		math.IsNaN(req.TakeProfit+req.TakeProfitVal+req.TakeProfitLimit+req.TakeProfitRate) {
-		AddAccFailOpen(s.Account, FailOpenNanNum)
+		s.Ctx().AddAccFailOpen(s.Account, FailOpenNanNum)
 		return errs.NewMsg(errs.CodeParamInvalid, "nan in EnterReq")
 	}
 	// 检查价格是否有效
@@ -128,37 +145,12 @@ func (s *StratJob) OpenOrder(req *EnterReq) *errs.Error {
 	if req.Short {
 		dirFlag = -1.0
 	}
-	enterPrice := core.GetPrice(symbol)
+	enterPrice := s.Ctx().GetPrice(symbol)
 	if req.Limit > 0 {
 		if (req.Limit-enterPrice)*dirFlag < 0 {
 			enterPrice = req.Limit
 		}
 	}
-	if req.Amount == 0 && req.LegalCost == 0 {
-		if req.CostRate == 0 {
-			req.CostRate = 1
-		}
-		req.LegalCost = s.Strat.GetStakeAmount(s) * req.CostRate
-		avgVol := s.avgVolume(5) // 最近5个蜡烛成交量
-		reqAmt := req.LegalCost / enterPrice
-		if avgVol > 0 && reqAmt/avgVol > config.OpenVolRate {
-			req.LegalCost = avgVol * config.OpenVolRate * enterPrice
-			if core.LiveMode {
-				log.Info(fmt.Sprintf("%v open amt rate: %.1f > open_vol_rate(%.1f), cut to cost: %.1f",
-					symbol, reqAmt/avgVol, config.OpenVolRate, req.LegalCost))
-			}
-		}
-		minCost := float64(core.MinStakeAmount)
-		if req.LegalCost < minCost {
-			rate := req.LegalCost / minCost
-			if config.LowCostAction == core.LowCostKeepBig && rate > 0.4 || config.LowCostAction == core.LowCostKeepAll {
-				req.LegalCost = minCost * 1.1
-			} else {
-				AddAccFailOpen(s.Account, FailOpenCostTooLess)
-				return errs.NewMsg(errs.CodeParamInvalid, "legal cost must >= %d", minCost)
-			}
-		}
-	}
 	// 检查止损
 	curSLPrice := s.LongSLPrice
 	if req.Short {
@@ -183,7 +175,7 @@ func (s *StratJob) OpenOrder(req *EnterReq) *errs.Error {
 				if req.Short {
 					rel = ">"
 				}
-				AddAccFailOpen(s.Account, FailOpenBadStopLoss)
+				s.Ctx().AddAccFailOpen(s.Account, FailOpenBadStopLoss)
 				return errs.NewMsg(errs.CodeParamInvalid, "%s stopLoss %f must %s %f for %v order",
 					symbol, curSLPrice, rel, enterPrice, dirType)
 			}
@@ -194,6 +186,19 @@ func (s *StratJob) OpenOrder(req *EnterReq) *errs.Error {
 				zap.String("pair", symbol))
 		}
 	}
+	if req.TrailCallback > 0 || req.TrailDistance > 0 {
+		if !s.ExgStopLoss {
+			req.TrailActivate, req.TrailCallback, req.TrailDistance = 0, 0, 0
+			if isLiveMode {
+				log.Warn("trailing stop disabled",
+					zap.String("strategy", s.Strat.Name),
+					zap.String("pair", symbol))
+			}
+		} else if req.TrailCallback >= 1 {
+			s.Ctx().AddAccFailOpen(s.Account, FailOpenBadStopLoss)
+			return errs.NewMsg(errs.CodeParamInvalid, "%s trailing callback %f must < 1", symbol, req.TrailCallback)
+		}
+	}
 	// 检查止盈
 	curTPPrice := s.LongTPPrice
 	if req.Short {
@@ -218,7 +223,7 @@ func (s *StratJob) OpenOrder(req *EnterReq) *errs.Error {
 				if req.Short {
 					rel = "<"
 				}
-				AddAccFailOpen(s.Account, FailOpenBadTakeProfit)
+				s.Ctx().AddAccFailOpen(s.Account, FailOpenBadTakeProfit)
 				return errs.NewMsg(errs.CodeParamInvalid, "%s takeProfit %f must %s %f for %v order",
 					symbol, curTPPrice, rel, enterPrice, dirType)
 			}
@@ -227,6 +232,31 @@ func (s *StratJob) OpenOrder(req *EnterReq) *errs.Error {
 			log.Warn("takeProfit disabled", zap.String("stagy", s.Strat.Name), zap.String("pair", symbol))
 		}
 	}
+	if req.Amount == 0 && req.LegalCost == 0 {
+		if req.CostRate == 0 {
+			req.CostRate = 1
+		}
+		req.LegalCost = s.Strat.getLegalCost(s, req, enterPrice, curSLPrice)
+		avgVol := s.avgVolume(5) // 最近5个蜡烛成交量
+		reqAmt := req.LegalCost / enterPrice
+		if avgVol > 0 && reqAmt/avgVol > config.OpenVolRate {
+			req.LegalCost = avgVol * config.OpenVolRate * enterPrice
+			if core.LiveMode {
+				log.Info(fmt.Sprintf("%v open amt rate: %.1f > open_vol_rate(%.1f), cut to cost: %.1f",
+					symbol, reqAmt/avgVol, config.OpenVolRate, req.LegalCost))
+			}
+		}
+		minCost := float64(core.MinStakeAmount)
+		if req.LegalCost < minCost {
+			rate := req.LegalCost / minCost
+			if config.LowCostAction == core.LowCostKeepBig && rate > 0.4 || config.LowCostAction == core.LowCostKeepAll {
+				req.LegalCost = minCost * 1.1
+			} else {
+				s.Ctx().AddAccFailOpen(s.Account, FailOpenCostTooLess)
+				return errs.NewMsg(errs.CodeParamInvalid, "legal cost must >= %d", minCost)
+			}
+		}
+	}
 	if req.Limit > 0 && req.OrderType == 0 {
 		req.OrderType = core.OrderTypeLimit
 	}
@@ -236,8 +266,6 @@ func (s *StratJob) OpenOrder(req *EnterReq) *errs.Error {
 			req.StopBars = s.Strat.StopEnterBars
 		}
 	}
-	s.Entrys = append(s.Entrys, req)
-	s.OrderNum += 1
 	return nil
 }
 
@@ -255,7 +283,7 @@ func (s *StratJob) CloseOrders(req *ExitReq) *errs.Error {
 	if !s.CloseShort && (dirtBoth || req.Dirt == core.OdDirtShort) || !s.CloseLong && (dirtBoth || req.Dirt == core.OdDirtLong) {
 		log.Warn("close order disabled",
 			zap.String("strategy", s.Strat.Name),
-			zap.String("pair", s.Symbol.Symbol),
+			zap.String("pair", s.Symbol.PairKey()),
 			zap.String("tag", req.Tag),
 			zap.Int("dirt", req.Dirt))
 		return errs.NewMsg(errs.CodeParamInvalid, "close order disabled")
@@ -507,17 +535,23 @@ func (s *StratJob) setAllExitTrigger(dirt float64, key string, args *ormo.ExitTr
 		} else {
 			if setPos >= size+core.AmtDust {
 				od.SetExitTrigger(key, &ormo.ExitTrigger{
-					Price: args.Price,
-					Limit: args.Limit,
-					Tag:   args.Tag,
+					Price:    args.Price,
+					Limit:    args.Limit,
+					Tag:      args.Tag,
+					Activate: args.Activate,
+					Callback: args.Callback,
+					Distance: args.Distance,
 				})
 				setPos -= size
 			} else {
 				od.SetExitTrigger(key, &ormo.ExitTrigger{
-					Price: args.Price,
-					Limit: args.Limit,
-					Rate:  setPos / size,
-					Tag:   args.Tag,
+					Price:    args.Price,
+					Limit:    args.Limit,
+					Rate:     setPos / size,
+					Tag:      args.Tag,
+					Activate: args.Activate,
+					Callback: args.Callback,
+					Distance: args.Distance,
 				})
 				setPos = 0
 			}
@@ -2,7 +2,6 @@ package strat
 
 import (
 	"fmt"
-	"github.com/banbox/banbot/btime"
 	"github.com/banbox/banbot/config"
 	"github.com/banbox/banbot/core"
 	"github.com/banbox/banbot/orm"
@@ -10,6 +9,8 @@ import (
 	"github.com/banbox/banbot/utils"
 	"github.com/banbox/banexg/errs"
 	"github.com/banbox/banexg/log"
+	"go.uber.org/zap"
+	"maps"
 	"math"
 	"slices"
 	"sort"
@@ -28,6 +29,12 @@ func New(pol *config.RunPolicyConfig) *TradeStrat {
 	var stgy *TradeStrat
 	if ok {
 		stgy = makeFn(pol)
+	} else if addr, stratName, isHost := SplitHostStrat(pol.Name); isHost {
+		var err *errs.Error
+		stgy, err = newHostStrat(addr, stratName, pol)
+		if err != nil {
+			panic(fmt.Sprintf("load strategy %s from host fail: %v", pol.Name, err))
+		}
 	} else {
 		panic("strategy not found: " + pol.Name)
 		// stgy = loadNative(pol.Name)
@@ -37,6 +44,13 @@ func New(pol *config.RunPolicyConfig) *TradeStrat {
 		stgy.MinTfScore = 0.75
 	}
 	stgy.Policy = pol
+	if pol.Sizer != nil {
+		sizer, err := NewPositionSizer(pol.Sizer)
+		if err != nil {
+			log.Error("invalid sizer, use fixed", zap.String("strat", pol.Name), zap.String("err", err.Short()))
+		}
+		stgy.Sizer = sizer
+	}
 	if pol.StakeRate > 0 {
 		stgy.StakeRate = pol.StakeRate
 	}
@@ -46,8 +60,8 @@ func New(pol *config.RunPolicyConfig) *TradeStrat {
 	return stgy
 }
 
-func Get(pair, stratID string) *TradeStrat {
-	data, _ := PairStrats[pair]
+func (c *Ctx) Get(pair, stratID string) *TradeStrat {
+	data, _ := c.PairStrats[pair]
 	if len(data) == 0 {
 		return nil
 	}
@@ -55,8 +69,8 @@ func Get(pair, stratID string) *TradeStrat {
 	return res
 }
 
-func GetStratPerf(pair, strat string) *config.StratPerfConfig {
-	stg := Get(pair, strat)
+func (c *Ctx) GetStratPerf(pair, strat string) *config.StratPerfConfig {
+	stg := c.Get(pair, strat)
 	if stg == nil {
 		return nil
 	}
@@ -146,8 +160,25 @@ func (q *ExitReq) Clone() *ExitReq {
 	return res
 }
 
+/*
+Ctx
+The context this job belongs to, DefCtx for jobs created without context
+此任务所属的上下文，未指定上下文创建的任务为DefCtx
+*/
+func (s *StratJob) Ctx() *Ctx {
+	if s.ctx != nil {
+		return s.ctx
+	}
+	return DefCtx
+}
+
+// SetCtx set the context this job belongs to 设置此任务所属的上下文
+func (s *StratJob) SetCtx(c *Ctx) {
+	s.ctx = c
+}
+
 func (s *StratJob) InitBar(curOrders []*ormo.InOutOrder) {
-	s.CheckMS = btime.TimeMS()
+	s.CheckMS = s.Ctx().TimeMS()
 	if s.IsWarmUp {
 		s.LongOrders = nil
 		s.ShortOrders = nil
@@ -156,7 +187,7 @@ func (s *StratJob) InitBar(curOrders []*ormo.InOutOrder) {
 		s.ShortOrders = nil
 		enteredNum := 0
 		for _, od := range curOrders {
-			if od.Symbol == s.Symbol.Symbol && od.Timeframe == s.TimeFrame && od.Strategy == s.Strat.Name {
+			if od.Symbol == s.Symbol.PairKey() && od.Timeframe == s.TimeFrame && od.Strategy == s.Strat.Name {
 				if od.Status >= ormo.InOutStatusPartEnter && od.Status <= ormo.InOutStatusPartExit {
 					enteredNum += 1
 				}
@@ -245,7 +276,7 @@ func (s *StratJob) CheckCustomExits(snap map[int64]*ormo.InOutSnap) ([]*ormo.InO
 	}
 	if core.LiveMode && skipSL+skipTP > 0 {
 		log.Warn(fmt.Sprintf("%s/%s triggers on exchange is disabled, stoploss: %v, takeprofit: %v",
-			s.Strat.Name, s.Symbol.Symbol, skipSL, skipTP))
+			s.Strat.Name, s.Symbol.PairKey(), skipSL, skipTP))
 	}
 	return res, nil
 }
@@ -260,6 +291,11 @@ func (s *StratJob) checkOrderExit(od *ormo.InOutOrder) (*ormo.InOutEdit, *ormo.I
 	var slEdit, tpEdit *ormo.InOutEdit
 	newSL := od.GetStopLoss()
 	newTP := od.GetTakeProfit()
+	if core.LiveMode && newSL != nil && !newSL.Native {
+		// Trailing stop not supported by exchange, update stop price with latest price
+		// 交易所不支持的跟踪止损，使用最新价格更新止损价格
+		newSL.UpdateTrail(s.Ctx().GetPrice(od.Symbol), od.Short)
+	}
 	// Check if the condition sheet needs to be modified
 	// 检查是否需要修改条件单
 	if sl != nil || newSL != nil {
@@ -278,35 +314,35 @@ func (s *StratJob) checkOrderExit(od *ormo.InOutOrder) (*ormo.InOutEdit, *ormo.I
 /*
 GetJobs 返回：pair_tf: [stratID]StratJob
 */
-func GetJobs(account string) map[string]map[string]*StratJob {
+func (c *Ctx) GetJobs(account string) map[string]map[string]*StratJob {
 	if !core.EnvReal {
 		account = config.DefAcc
 	}
-	jobs, ok := AccJobs[account]
+	jobs, ok := c.AccJobs[account]
 	if !ok {
 		jobs = map[string]map[string]*StratJob{}
-		AccJobs[account] = jobs
+		c.AccJobs[account] = jobs
 	}
 	return jobs
 }
 
-func GetInfoJobs(account string) map[string]map[string]*StratJob {
+func (c *Ctx) GetInfoJobs(account string) map[string]map[string]*StratJob {
 	if !core.EnvReal {
 		account = config.DefAcc
 	}
-	lockInfoJobs.Lock()
-	jobs, ok := AccInfoJobs[account]
+	c.lockInfoJobs.Lock()
+	jobs, ok := c.AccInfoJobs[account]
 	if !ok {
 		jobs = map[string]map[string]*StratJob{}
-		AccInfoJobs[account] = jobs
+		c.AccInfoJobs[account] = jobs
 	}
-	lockInfoJobs.Unlock()
+	c.lockInfoJobs.Unlock()
 	return jobs
 }
 
-func CalcJobScores(pair, tf, stgy string) *errs.Error {
+func (c *Ctx) CalcJobScores(pair, tf, stgy string) *errs.Error {
 	var orders []*ormo.InOutOrder
-	cfg := GetStratPerf(pair, stgy)
+	cfg := c.GetStratPerf(pair, stgy)
 	if core.LiveMode {
 		// Retrieve recent orders from the database
 		// 从数据库查询最近订单
@@ -315,7 +351,7 @@ func CalcJobScores(pair, tf, stgy string) *errs.Error {
 			return err
 		}
 		defer conn.Close()
-		taskId := ormo.GetTaskID(config.DefAcc)
+		taskId := c.GetTaskID(config.DefAcc)
 		orders, err = sess.GetOrders(ormo.GetOrdersArgs{
 			TaskID:    taskId,
 			Strategy:  stgy,
@@ -329,7 +365,7 @@ func CalcJobScores(pair, tf, stgy string) *errs.Error {
 		}
 	} else {
 		// 从HistODs查询
-		for _, od := range ormo.HistODs {
+		for _, od := range c.HistODs {
 			if od.Symbol != pair || od.Timeframe != tf || od.Strategy != stgy {
 				continue
 			}
@@ -339,7 +375,7 @@ func CalcJobScores(pair, tf, stgy string) *errs.Error {
 			orders = orders[len(orders)-cfg.MaxOdNum:]
 		}
 	}
-	sta := core.GetPerfSta(stgy)
+	sta := c.GetPerfSta(stgy)
 	sta.OdNum += 1
 	if len(orders) < cfg.MinOdNum {
 		return nil
@@ -349,21 +385,21 @@ func CalcJobScores(pair, tf, stgy string) *errs.Error {
 		totalPft += od.ProfitRate
 	}
 	var prefKey = core.KeyStratPairTf(stgy, pair, tf)
-	perf, _ := core.JobPerfs[prefKey]
+	perf, _ := c.JobPerfs[prefKey]
 	if perf == nil {
 		perf = &core.JobPerf{
 			Num:       len(orders),
 			TotProfit: totalPft,
 			Score:     1,
 		}
-		core.JobPerfs[prefKey] = perf
+		c.JobPerfs[prefKey] = perf
 	} else {
 		perf.Num = len(orders)
 	}
 	// collect all trade jobs for this strategy & calculate stake rate
 	// 收集此策略所有任务，计算开单倍率
 	var prefs []*core.JobPerf
-	for key, p := range core.JobPerfs {
+	for key, p := range c.JobPerfs {
 		if strings.HasPrefix(key, stgy) {
 			prefs = append(prefs, p)
 		}
@@ -372,7 +408,7 @@ func CalcJobScores(pair, tf, stgy string) *errs.Error {
 	if core.LiveMode {
 		// Real disk mode, immediately save to data directory
 		// 实盘模式，立刻保存到数据目录
-		core.DumpPerfs(config.GetDataDir())
+		c.DumpPerfs(config.GetDataDir())
 	}
 	return nil
 }
@@ -471,16 +507,18 @@ func CalcJobPerfs(cfg *config.StratPerfConfig, p *core.PerfSta, perfs []*core.Jo
 	}
 }
 
-func AddOdSub(acc string, cb FnOdChange) {
-	lockOdSub.Lock()
-	defer lockOdSub.Unlock()
-	subs, _ := accOdSubs[acc]
-	accOdSubs[acc] = append(subs, cb)
+func (c *Ctx) AddOdSub(acc string, cb FnOdChange) {
+	c.lockOdSub.Lock()
+	defer c.lockOdSub.Unlock()
+	subs, _ := c.accOdSubs[acc]
+	c.accOdSubs[acc] = append(subs, cb)
 }
 
-func FireOdChange(acc string, od *ormo.InOutOrder, evt int) {
-	subs, _ := accOdSubs[acc]
-	subs2, _ := accOdSubs["*"]
+func (c *Ctx) FireOdChange(acc string, od *ormo.InOutOrder, evt int) {
+	c.lockOdSub.Lock()
+	subs, _ := c.accOdSubs[acc]
+	subs2, _ := c.accOdSubs["*"]
+	c.lockOdSub.Unlock()
 	subs = append(subs, subs2...)
 	for _, cb := range subs {
 		cb(acc, od, evt)
@@ -508,8 +546,8 @@ func (w Warms) Update(pair, tf string, num int) {
 /*
 JobForbidType 0 allow; 1 forbid; 2 forbid & occupy a slot
 */
-func JobForbidType(pair, tf, stratName string) int {
-	if jobs, ok := ForbidJobs[fmt.Sprintf("%s_%s", pair, tf)]; ok {
+func (c *Ctx) JobForbidType(pair, tf, stratName string) int {
+	if jobs, ok := c.ForbidJobs[fmt.Sprintf("%s_%s", pair, tf)]; ok {
 		hold, ok2 := jobs[stratName]
 		if ok2 {
 			if hold {
@@ -521,9 +559,9 @@ func JobForbidType(pair, tf, stratName string) int {
 	return 0
 }
 
-func GetJobKeys() map[string]map[string]bool {
+func (c *Ctx) GetJobKeys() map[string]map[string]bool {
 	jobs := make(map[string]map[string]bool)
-	for _, jobsMap := range AccJobs {
+	for _, jobsMap := range c.AccJobs {
 		for pairTF, stgMap := range jobsMap {
 			idMap := make(map[string]bool)
 			for polID := range stgMap {
@@ -536,27 +574,42 @@ func GetJobKeys() map[string]map[string]bool {
 	return jobs
 }
 
-func AddAccFailOpen(acc, tag string) {
-	AddAccFailOpens(acc, tag, 1)
+func (c *Ctx) AddAccFailOpen(acc, tag string) {
+	c.AddAccFailOpens(acc, tag, 1)
 }
 
-func AddAccFailOpens(acc, tag string, num int) {
-	lockAccFailOpen.Lock()
-	tagMap, ok1 := accFailOpens[acc]
+func (c *Ctx) AddAccFailOpens(acc, tag string, num int) {
+	c.lockAccFailOpen.Lock()
+	tagMap, ok1 := c.accFailOpens[acc]
 	if !ok1 {
 		tagMap = make(map[string]int)
-		accFailOpens[acc] = tagMap
+		c.accFailOpens[acc] = tagMap
 	}
 	count, _ := tagMap[tag]
 	tagMap[tag] = count + num
-	lockAccFailOpen.Unlock()
+	c.lockAccFailOpen.Unlock()
+}
+
+/*
+GetAccFailOpens
+return a copy of failed entry counts: account -> reason -> count
+返回开单失败次数的副本：账户 -> 原因 -> 次数
+*/
+func (c *Ctx) GetAccFailOpens() map[string]map[string]int {
+	c.lockAccFailOpen.Lock()
+	res := make(map[string]map[string]int, len(c.accFailOpens))
+	for acc, tagMap := range c.accFailOpens {
+		res[acc] = maps.Clone(tagMap)
+	}
+	c.lockAccFailOpen.Unlock()
+	return res
 }
 
-func DumpAccFailOpens() string {
-	lockAccFailOpen.Lock()
+func (c *Ctx) DumpAccFailOpens() string {
+	c.lockAccFailOpen.Lock()
 	var b strings.Builder
 	isFirst := true
-	for k, v := range accFailOpens {
+	for k, v := range c.accFailOpens {
 		if isFirst {
 			isFirst = false
 		} else {
@@ -566,7 +619,7 @@ func DumpAccFailOpens() string {
 		b.WriteString(": ")
 		b.WriteString(utils.MapToStr(v, true, 0))
 	}
-	lockAccFailOpen.Unlock()
+	c.lockAccFailOpen.Unlock()
 	return b.String()
 }
 
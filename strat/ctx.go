@@ -0,0 +1,79 @@
+package strat
+
+import (
+	"sync"
+
+	"github.com/banbox/banbot/goods"
+	"github.com/banbox/banbot/orm/ormo"
+	ta "github.com/banbox/banta"
+)
+
+/*
+Ctx
+Strategy jobs of a running bot, based on ormo.Ctx.
+All stratName in the fields are RunPolicy.ID(), not the original strategy name. A ":l" or ":s" suffix means only long or only short.
+正在运行的机器人的策略任务，基于ormo.Ctx。
+字段中所有的stratName都是RunPolicy.ID()，不是原始策略名。后面添加了":l"或":s"后缀表示仅开多或仅开空
+*/
+type Ctx struct {
+	*ormo.Ctx
+	Versions    map[string]int                             // stratName: int 策略版本号
+	Envs        map[string]*ta.BarEnv                      // pair_tf: BarEnv
+	AccJobs     map[string]map[string]map[string]*StratJob // account: pair_tf: [stratID]StratJob
+	AccInfoJobs map[string]map[string]map[string]*StratJob // account: pair_tf: [stratID_pair]StratJob 额外订阅
+	PairStrats  map[string]map[string]*TradeStrat          // pair:[stratID]TradeStrat 所有的订阅策略
+	ForbidJobs  map[string]map[string]bool                 // pair_tf: [stratID] occupy
+
+	BatchTasks  map[string]*BatchMap // tf_account_strat: pair: task 每个bar周期更新（只适用于单交易所单市场）
+	LastBatchMS int64                // timeMS The timestamp of the last batch execution is only used for backtesting 上次批量执行的时间戳，仅用于回测
+
+	// AccEquity Returns the total equity of account in legal currency, set by biz 返回账户的法币总权益，由biz设置
+	AccEquity func(account string) float64
+
+	lockInfoJobs sync.Mutex
+
+	accOdSubs map[string][]FnOdChange // acc: listeners List of subscription order status change events 订阅订单状态变化事件列表
+	lockOdSub sync.Mutex
+
+	accFailOpens    map[string]map[string]int // Statistics of reasons for failed entry for accounts 各个账号开单失败原因统计
+	lockAccFailOpen sync.Mutex
+
+	polFilters map[string][]goods.IFilter // cached pair filters of policies 策略的品种过滤器缓存
+}
+
+// DefCtx context of the live bot, based on ormo.DefCtx 实盘使用的上下文，基于ormo.DefCtx
+var DefCtx = NewCtx(ormo.DefCtx)
+
+func NewCtx(od *ormo.Ctx) *Ctx {
+	c := &Ctx{Ctx: od}
+	c.clearJobs()
+	c.accOdSubs = make(map[string][]FnOdChange)
+	return c
+}
+
+/*
+Reset
+Clear the state of this context and ormo.Ctx, subscriptions of order changes are kept.
+清空此上下文和ormo.Ctx的状态，保留订单变化的订阅。
+*/
+func (c *Ctx) Reset() {
+	c.Ctx.Reset()
+	c.clearJobs()
+}
+
+func (c *Ctx) clearJobs() {
+	c.Versions = make(map[string]int)
+	c.Envs = make(map[string]*ta.BarEnv)
+	c.AccJobs = make(map[string]map[string]map[string]*StratJob)
+	c.lockInfoJobs.Lock()
+	c.AccInfoJobs = make(map[string]map[string]map[string]*StratJob)
+	c.lockInfoJobs.Unlock()
+	c.PairStrats = make(map[string]map[string]*TradeStrat)
+	c.ForbidJobs = make(map[string]map[string]bool)
+	c.BatchTasks = make(map[string]*BatchMap)
+	c.LastBatchMS = 0
+	c.lockAccFailOpen.Lock()
+	c.accFailOpens = make(map[string]map[string]int)
+	c.lockAccFailOpen.Unlock()
+	c.polFilters = make(map[string][]goods.IFilter)
+}
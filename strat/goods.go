@@ -2,7 +2,6 @@ package strat
 
 import (
 	"fmt"
-	"github.com/banbox/banbot/btime"
 	"github.com/banbox/banbot/config"
 	"github.com/banbox/banbot/core"
 	"github.com/banbox/banbot/orm"
@@ -28,15 +27,15 @@ RelayPolicyGroups 获取需要接力开单的策略分组
 将策略按最小周期划分为多个组。组内多个策略的最小周期最大差距不能超过5倍。
 用于对不同起止时间且不同周期的策略，划分不同组，提高整体效率
 */
-func RelayPolicyGroups() []*PolicyGroup {
+func (c *Ctx) RelayPolicyGroups() []*PolicyGroup {
 	tfScores := make(map[string]float64)
-	allowTfs := allAllowTFs()
+	allowTfs := c.allAllowTFs()
 	for _, tf := range allowTfs {
 		tfScores[tf] = 1
 	}
 	// 记录每个策略的最小使用周期
 	polTFs := make(map[string]int)
-	for _, pol := range config.RunPolicy {
+	for _, pol := range c.RunPolicy() {
 		stgy := New(pol)
 		tf := stgy.pickTimeFrame("", tfScores)
 		if tf == "" {
@@ -88,12 +87,12 @@ func RelayPolicyGroups() []*PolicyGroup {
 		gp[it.Str] = curGpId
 	}
 	// 计算每个分组开始时间
-	curTime := btime.TimeMS()
+	curTime := c.TimeMS()
 	polGroups := make([]*PolicyGroup, 0, curGpId+1)
 	for i := 0; i <= curGpId; i++ {
 		polGroups = append(polGroups, &PolicyGroup{StartMS: math.MaxInt64})
 	}
-	for _, pol := range config.RunPolicy {
+	for _, pol := range c.RunPolicy() {
 		stgy := New(pol)
 		tf := stgy.pickTimeFrame("", tfScores)
 		if tf == "" {
@@ -110,9 +109,9 @@ func RelayPolicyGroups() []*PolicyGroup {
 
 // CalcPairTfScores Calculate the K-line quality score of each dimension of the trading pair
 // 计算交易对各维度K线质量分数
-func CalcPairTfScores(exchange banexg.BanExchange, pairs []string) (map[string]map[string]float64, *errs.Error) {
+func (c *Ctx) CalcPairTfScores(exchange banexg.BanExchange, pairs []string) (map[string]map[string]float64, *errs.Error) {
 	pairTfScores := make(map[string]map[string]float64)
-	allowTfs := allAllowTFs()
+	allowTfs := c.allAllowTFs()
 	if len(allowTfs) == 0 {
 		return pairTfScores, errs.NewMsg(core.ErrBadConfig, "run_timeframes is required in config")
 	}
@@ -136,7 +135,7 @@ func CalcPairTfScores(exchange banexg.BanExchange, pairs []string) (map[string]m
 			tfScores = make(map[string]float64)
 			pairTfScores[pair] = tfScores
 		}
-		pipChg, err := exchange.PriceOnePip(pair)
+		pipChg, err := exchange.PriceOnePip(core.PairSymbol(pair))
 		if err != nil {
 			log.Error("PriceOnePip fail", zap.String("pair", pair), zap.Float64("pip", pipChg))
 			return
@@ -225,9 +224,9 @@ func calcKlineScore(arr []*banexg.Kline, pipChg float64, prevNum int) float64 {
 	return chgScore*0.4 + jRateScore*0.3 + overlapScore*0.3
 }
 
-func allAllowTFs() []string {
+func (c *Ctx) allAllowTFs() []string {
 	var groups = [][]string{config.RunTimeframes}
-	for _, pol := range config.RunPolicy {
+	for _, pol := range c.RunPolicy() {
 		if pol.Dirt == "any" {
 			pol.Dirt = ""
 		}
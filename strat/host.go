@@ -0,0 +1,397 @@
+package strat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/orm/ormo"
+	"github.com/banbox/banbot/strat/stratpb"
+	"github.com/banbox/banexg/errs"
+	"github.com/banbox/banexg/log"
+	ta "github.com/banbox/banta"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+/*
+Out-of-process strategies: a run_policy named `host:strategy` whose host is in config.StratHosts is run by
+calling the strategy host over gRPC (doc/strat_host.proto), so strategies can be added, upgraded and restarted
+without rebuilding the bot. Works in both backtest and live.
+进程外策略：run_policy名称为`宿主:策略`且宿主在config.StratHosts中时，通过gRPC调用策略宿主运行(doc/strat_host.proto)，
+策略可独立添加、升级和重启，无需重新编译机器人。回测和实盘均可用。
+*/
+
+const (
+	HostProtoVersion = 1 // version of strat_host.proto 协议版本
+)
+
+var (
+	HostCallTimeout = time.Second * 30
+
+	hostClients = make(map[string]stratpb.StratHostClient)
+	hostLock    sync.Mutex
+)
+
+/*
+SplitHostStrat
+Return the host address and strategy name if name is `host:strategy` and the host is configured
+name为`宿主:策略`且宿主已配置时，返回宿主地址和策略名
+*/
+func SplitHostStrat(name string) (string, string, bool) {
+	host, stratName, found := strings.Cut(name, ":")
+	if !found || stratName == "" {
+		return "", "", false
+	}
+	addr, ok := config.StratHosts[host]
+	if !ok || addr == "" {
+		return "", "", false
+	}
+	return addr, stratName, true
+}
+
+func getHostClient(addr string) (stratpb.StratHostClient, *errs.Error) {
+	hostLock.Lock()
+	defer hostLock.Unlock()
+	if cli, ok := hostClients[addr]; ok {
+		return cli, nil
+	}
+	// the connection reconnects automatically when the host restarts 宿主重启时连接会自动重连
+	conn, err_ := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err_ != nil {
+		return nil, errs.New(errs.CodeNetFail, err_)
+	}
+	cli := stratpb.NewStratHostClient(conn)
+	hostClients[addr] = cli
+	return cli, nil
+}
+
+type hostStrat struct {
+	cli   stratpb.StratHostClient
+	name  string
+	addr  string
+	pol   *config.RunPolicyConfig
+	info  *stratpb.StratInfo
+	ready map[string]bool // job keys which OnStartUp is called 已调用OnStartUp的任务
+	lock  sync.Mutex
+}
+
+/*
+newHostStrat
+Handshake with the strategy host and build the TradeStrat adapter
+与策略宿主握手，并构建TradeStrat适配器
+*/
+func newHostStrat(addr, stratName string, pol *config.RunPolicyConfig) (*TradeStrat, *errs.Error) {
+	cli, err := getHostClient(addr)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), HostCallTimeout)
+	defer cancel()
+	info, err_ := cli.Info(ctx, &stratpb.InfoReq{Version: HostProtoVersion, Name: stratName})
+	if err_ != nil {
+		return nil, errs.New(errs.CodeNetFail, err_)
+	}
+	if info.Version != HostProtoVersion {
+		return nil, errs.NewMsg(core.ErrBadConfig, "strat host protocol version mismatch, bot: %v, host %s: %v",
+			HostProtoVersion, addr, info.Version)
+	}
+	h := &hostStrat{cli: cli, name: stratName, addr: addr, pol: pol, info: info, ready: make(map[string]bool)}
+	for _, p := range info.Params {
+		param := &core.Param{Name: p.Name, VType: int(p.Vtype), Min: p.Min, Max: p.Max, Mean: p.Mean, Rate: p.Rate}
+		if p.IsInt {
+			pol.DefInt(p.Name, int(p.DefaultVal), param)
+		} else {
+			pol.Def(p.Name, p.DefaultVal, param)
+		}
+	}
+	res := &TradeStrat{
+		Version:       int(info.StratVersion),
+		WarmupNum:     int(info.WarmupNum),
+		OdBarMax:      int(info.OdBarMax),
+		MinTfScore:    info.MinTfScore,
+		WatchBook:     info.WatchBook,
+		DrawDownExit:  info.DrawDownExit,
+		StakeRate:     info.StakeRate,
+		StopEnterBars: int(info.StopEnterBars),
+		EachMaxLong:   int(info.EachMaxLong),
+		EachMaxShort:  int(info.EachMaxShort),
+		AllowTFs:      info.AllowTfs,
+		OnStartUp:     h.onStartUp,
+		OnBar:         h.onBar,
+		OnInfoBar:     h.onInfoBar,
+		OnShutDown:    h.onShutDown,
+	}
+	if len(info.PairInfos) > 0 {
+		res.OnPairInfos = h.onPairInfos
+	}
+	if info.CheckExit {
+		res.OnCheckExit = h.onCheckExit
+	}
+	if info.OrderChange {
+		res.OnOrderChange = h.onOrderChange
+	}
+	return res, nil
+}
+
+func hostJobKey(s *StratJob) string {
+	return fmt.Sprintf("%s/%s/%s/%s", s.Account, s.Symbol.PairKey(), s.TimeFrame, s.Strat.Name)
+}
+
+func (h *hostStrat) jobState(s *StratJob) *stratpb.JobState {
+	res := &stratpb.JobState{
+		Key:          hostJobKey(s),
+		Account:      s.Account,
+		Pair:         s.Symbol.PairKey(),
+		Timeframe:    s.TimeFrame,
+		IsWarmUp:     s.IsWarmUp,
+		MaxOpenLong:  int32(s.MaxOpenLong),
+		MaxOpenShort: int32(s.MaxOpenShort),
+	}
+	for _, od := range s.GetOrders(0) {
+		res.Orders = append(res.Orders, hostOrder(od))
+	}
+	return res
+}
+
+func hostOrder(od *ormo.InOutOrder) *stratpb.Order {
+	res := &stratpb.Order{
+		Id:         od.ID,
+		Symbol:     od.Symbol,
+		Timeframe:  od.Timeframe,
+		Short:      od.Short,
+		Status:     int32(od.Status),
+		EnterTag:   od.EnterTag,
+		ExitTag:    od.ExitTag,
+		EnterAt:    od.EnterAt,
+		ExitAt:     od.ExitAt,
+		Leverage:   od.Leverage,
+		ProfitRate: od.ProfitRate,
+		Profit:     od.Profit,
+	}
+	if od.Enter != nil {
+		res.EnterPrice = od.Enter.Average
+		if res.EnterPrice == 0 {
+			res.EnterPrice = od.Enter.Price
+		}
+		res.EnterAmount = od.Enter.Amount
+		res.EnterFilled = od.Enter.Filled
+	}
+	if od.Exit != nil {
+		res.ExitPrice = od.Exit.Average
+		res.ExitFilled = od.Exit.Filled
+	}
+	if sl := od.GetStopLoss(); sl != nil {
+		res.StopLoss = sl.Price
+	}
+	if tp := od.GetTakeProfit(); tp != nil {
+		res.TakeProfit = tp.Price
+	}
+	return res
+}
+
+func hostBarEnv(e *ta.BarEnv) *stratpb.BarEnv {
+	res := &stratpb.BarEnv{
+		Exchange:  e.Exchange,
+		Market:    e.MarketType,
+		Symbol:    e.Symbol,
+		Timeframe: e.TimeFrame,
+		TimeStart: e.TimeStart,
+		TimeStop:  e.TimeStop,
+		BarNum:    int32(e.BarNum),
+	}
+	if e.Close != nil && e.Close.Len() > 0 {
+		res.Open, res.High, res.Low = e.Open.Get(0), e.High.Get(0), e.Low.Get(0)
+		res.Close, res.Volume, res.Info = e.Close.Get(0), e.Volume.Get(0), e.Info.Get(0)
+	}
+	return res
+}
+
+/*
+call
+Invoke fn with timeout; if the host doesn't know the job (e.g. restarted), call OnStartUp for it and retry once.
+fn is skipped when the job can't be started up. Errors are logged and returned.
+带超时调用fn；宿主不认识此任务时(如已重启)，先为其调用OnStartUp再重试一次。任务无法启动时跳过fn。错误会被记录并返回
+*/
+func (h *hostStrat) call(s *StratJob, method string, fn func(ctx context.Context) (*stratpb.JobRsp, error)) error {
+	canInit := method != "OnStartUp" && method != "OnShutDown"
+	h.lock.Lock()
+	ready := h.ready[hostJobKey(s)]
+	h.lock.Unlock()
+	if !ready && canInit {
+		if err := h.startUp(s); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), HostCallTimeout)
+	rsp, err := fn(ctx)
+	cancel()
+	if status.Code(err) == codes.FailedPrecondition && canInit {
+		log.Warn("strat host lost job, restart it", zap.String("host", h.addr), zap.String("job", hostJobKey(s)))
+		if err = h.startUp(s); err != nil {
+			return err
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), HostCallTimeout)
+		rsp, err = fn(ctx)
+		cancel()
+	}
+	if err != nil {
+		log.Error("call strat host fail", zap.String("host", h.addr), zap.String("method", method),
+			zap.String("job", hostJobKey(s)), zap.Error(err))
+		return err
+	}
+	h.applyRsp(s, rsp)
+	return nil
+}
+
+func (h *hostStrat) applyRsp(s *StratJob, rsp *stratpb.JobRsp) {
+	if rsp == nil {
+		return
+	}
+	for _, q := range rsp.Entries {
+		err := s.OpenOrder(fromHostEnter(q))
+		if err != nil {
+			log.Warn("open order from strat host fail", zap.String("job", hostJobKey(s)), zap.Error(err))
+		}
+	}
+	for _, q := range rsp.Exits {
+		err := s.CloseOrders(fromHostExit(q))
+		if err != nil {
+			log.Warn("close order from strat host fail", zap.String("job", hostJobKey(s)), zap.Error(err))
+		}
+	}
+}
+
+func (h *hostStrat) onPairInfos(_ *StratJob) []*PairSub {
+	res := make([]*PairSub, 0, len(h.info.PairInfos))
+	for _, p := range h.info.PairInfos {
+		res = append(res, &PairSub{Pair: p.Pair, TimeFrame: p.Timeframe, WarmupNum: int(p.WarmupNum)})
+	}
+	return res
+}
+
+func (h *hostStrat) onStartUp(s *StratJob) {
+	// the error is logged by call, the job is started up again on next call 错误已由call记录，下次调用时重新启动任务
+	_ = h.startUp(s)
+}
+
+/*
+startUp
+Call OnStartUp of the host, the job is marked ready only when it succeeds
+调用宿主的OnStartUp，仅成功时标记任务为就绪
+*/
+func (h *hostStrat) startUp(s *StratJob) error {
+	params := make(map[string]float64)
+	for _, p := range h.info.Params {
+		params[p.Name] = p.DefaultVal
+	}
+	for k, v := range s.Strat.Policy.Params {
+		params[k] = v
+	}
+	if pairPms, ok := s.Strat.Policy.PairParams[s.Symbol.PairKey()]; ok {
+		for k, v := range pairPms {
+			params[k] = v
+		}
+	}
+	req := &stratpb.StartUpReq{Job: h.jobState(s), Params: params}
+	err := h.call(s, "OnStartUp", func(ctx context.Context) (*stratpb.JobRsp, error) {
+		return h.cli.OnStartUp(ctx, req)
+	})
+	if err != nil {
+		return err
+	}
+	h.lock.Lock()
+	h.ready[hostJobKey(s)] = true
+	h.lock.Unlock()
+	return nil
+}
+
+func (h *hostStrat) onBar(s *StratJob) {
+	_ = h.call(s, "OnBar", func(ctx context.Context) (*stratpb.JobRsp, error) {
+		return h.cli.OnBar(ctx, &stratpb.BarReq{Job: h.jobState(s), Env: hostBarEnv(s.Env)})
+	})
+}
+
+func (h *hostStrat) onInfoBar(s *StratJob, e *ta.BarEnv, _, _ string) {
+	_ = h.call(s, "OnInfoBar", func(ctx context.Context) (*stratpb.JobRsp, error) {
+		return h.cli.OnInfoBar(ctx, &stratpb.BarReq{Job: h.jobState(s), Env: hostBarEnv(e)})
+	})
+}
+
+func (h *hostStrat) onCheckExit(s *StratJob, od *ormo.InOutOrder) *ExitReq {
+	var res *ExitReq
+	_ = h.call(s, "OnCheckExit", func(ctx context.Context) (*stratpb.JobRsp, error) {
+		rsp, err := h.cli.OnCheckExit(ctx, &stratpb.OrderReq{Job: h.jobState(s), Order: hostOrder(od)})
+		if err == nil && rsp.Exit != nil {
+			res = fromHostExit(rsp.Exit)
+		}
+		return nil, err
+	})
+	return res
+}
+
+func (h *hostStrat) onOrderChange(s *StratJob, od *ormo.InOutOrder, chgType int) {
+	_ = h.call(s, "OnOrderChange", func(ctx context.Context) (*stratpb.JobRsp, error) {
+		req := &stratpb.OrderReq{Job: h.jobState(s), Order: hostOrder(od), ChgType: int32(chgType)}
+		return h.cli.OnOrderChange(ctx, req)
+	})
+}
+
+func (h *hostStrat) onShutDown(s *StratJob) {
+	_ = h.call(s, "OnShutDown", func(ctx context.Context) (*stratpb.JobRsp, error) {
+		return h.cli.OnShutDown(ctx, &stratpb.JobReq{Job: h.jobState(s)})
+	})
+	h.lock.Lock()
+	delete(h.ready, hostJobKey(s))
+	h.lock.Unlock()
+}
+
+func fromHostEnter(q *stratpb.EnterReq) *EnterReq {
+	return &EnterReq{
+		Tag:             q.Tag,
+		Short:           q.Short,
+		OrderType:       int(q.OrderType),
+		Limit:           q.Limit,
+		CostRate:        q.CostRate,
+		LegalCost:       q.LegalCost,
+		Leverage:        q.Leverage,
+		Amount:          q.Amount,
+		StopLossVal:     q.StopLossVal,
+		StopLoss:        q.StopLoss,
+		StopLossLimit:   q.StopLossLimit,
+		StopLossRate:    q.StopLossRate,
+		StopLossTag:     q.StopLossTag,
+		TrailActivate:   q.TrailActivate,
+		TrailCallback:   q.TrailCallback,
+		TrailDistance:   q.TrailDistance,
+		TakeProfitVal:   q.TakeProfitVal,
+		TakeProfit:      q.TakeProfit,
+		TakeProfitLimit: q.TakeProfitLimit,
+		TakeProfitRate:  q.TakeProfitRate,
+		TakeProfitTag:   q.TakeProfitTag,
+		StopBars:        int(q.StopBars),
+	}
+}
+
+func fromHostExit(q *stratpb.ExitReq) *ExitReq {
+	return &ExitReq{
+		Tag:        q.Tag,
+		EnterTag:   q.EnterTag,
+		Dirt:       int(q.Dirt),
+		OrderType:  int(q.OrderType),
+		Limit:      q.Limit,
+		ExitRate:   q.ExitRate,
+		Amount:     q.Amount,
+		OrderID:    q.OrderId,
+		UnFillOnly: q.UnFillOnly,
+		FilledOnly: q.FilledOnly,
+		Force:      q.Force,
+	}
+}
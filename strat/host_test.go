@@ -0,0 +1,132 @@
+package strat
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/orm"
+	"github.com/banbox/banbot/strat/stratpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+type testHost struct {
+	stratpb.UnimplementedStratHostServer
+	lock    sync.Mutex
+	jobs    map[string]map[string]float64
+	starts  int
+	barNums int
+	barCall int // OnBar calls including failed ones 包含失败的OnBar调用次数
+	failNum int // fail next OnStartUp calls 使接下来的OnStartUp调用失败
+}
+
+func (h *testHost) Info(_ context.Context, req *stratpb.InfoReq) (*stratpb.StratInfo, error) {
+	return &stratpb.StratInfo{
+		Version:   HostProtoVersion,
+		WarmupNum: 30,
+		AllowTfs:  []string{"1h"},
+		Params: []*stratpb.HyperParam{
+			{Name: "atr", DefaultVal: 14, Min: 5, Max: 30, IsInt: true},
+			{Name: "rate", DefaultVal: 0.5, Min: 0, Max: 1},
+		},
+	}, nil
+}
+
+func (h *testHost) OnStartUp(_ context.Context, req *stratpb.StartUpReq) (*stratpb.JobRsp, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.failNum > 0 {
+		h.failNum -= 1
+		return nil, status.Error(codes.Internal, "start up fail")
+	}
+	h.jobs[req.Job.Key] = req.Params
+	h.starts += 1
+	return &stratpb.JobRsp{}, nil
+}
+
+func (h *testHost) OnBar(_ context.Context, req *stratpb.BarReq) (*stratpb.JobRsp, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.barCall += 1
+	if _, ok := h.jobs[req.Job.Key]; !ok {
+		return nil, status.Error(codes.FailedPrecondition, "unknown job")
+	}
+	h.barNums += 1
+	return &stratpb.JobRsp{}, nil
+}
+
+func TestHostStrat(t *testing.T) {
+	lis, err_ := net.Listen("tcp", "127.0.0.1:0")
+	if err_ != nil {
+		t.Fatal(err_)
+	}
+	host := &testHost{jobs: make(map[string]map[string]float64)}
+	srv := grpc.NewServer()
+	stratpb.RegisterStratHostServer(srv, host)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	config.StratHosts = map[string]string{"py": lis.Addr().String()}
+	pol := &config.RunPolicyConfig{Name: "py:trend", Params: map[string]float64{"rate": 0.8}}
+	stgy := New(pol)
+	if stgy.WarmupNum != 30 || len(stgy.AllowTFs) != 1 || stgy.OnCheckExit != nil {
+		t.Fatalf("bad strategy meta: %+v", stgy)
+	}
+	if len(pol.HyperParams()) != 2 || !pol.IsInt("atr") {
+		t.Errorf("hyper params not registered: %v", pol.HyperParams())
+	}
+	job := &StratJob{Strat: stgy, Env: env, Symbol: &orm.ExSymbol{Symbol: "BTC/USDT"}, TimeFrame: "1h",
+		Account: "user1"}
+	stgy.OnBar(job)
+	params := host.jobs[hostJobKey(job)]
+	if host.starts != 1 || host.barNums != 1 || params["atr"] != 14 || params["rate"] != 0.8 {
+		t.Fatalf("OnStartUp should be called before OnBar, starts: %v, bars: %v, params: %v",
+			host.starts, host.barNums, params)
+	}
+	// host restarted and lost the job, should start up and retry 宿主重启丢失任务，应重新启动并重试
+	host.jobs = make(map[string]map[string]float64)
+	stgy.OnBar(job)
+	if host.starts != 2 || host.barNums != 2 {
+		t.Errorf("should retry after start up, starts: %v, bars: %v", host.starts, host.barNums)
+	}
+	// failed start up leaves the job not ready, OnBar is skipped 启动失败时任务未就绪，跳过OnBar
+	job2 := &StratJob{Strat: stgy, Env: env, Symbol: &orm.ExSymbol{Symbol: "ETH/USDT"}, TimeFrame: "1h",
+		Account: "user1"}
+	host.failNum = 1
+	stgy.OnStartUp(job2)
+	stgy.OnBar(job2)
+	if host.starts != 3 || host.barNums != 3 || host.barCall != 4 {
+		t.Errorf("should start up again before OnBar, starts: %v, bars: %v, calls: %v", host.starts,
+			host.barNums, host.barCall)
+	}
+	host.failNum = 1
+	stgy.OnBar(&StratJob{Strat: stgy, Env: env, Symbol: &orm.ExSymbol{Symbol: "SOL/USDT"}, TimeFrame: "1h",
+		Account: "user1"})
+	if host.starts != 3 || host.barCall != 4 {
+		t.Errorf("OnBar should be skipped when start up fails, starts: %v, calls: %v", host.starts, host.barCall)
+	}
+}
+
+func TestFromHostEnter(t *testing.T) {
+	q := &stratpb.EnterReq{Tag: "long", LegalCost: 100, StopLoss: 90, TrailActivate: 110, TrailCallback: 0.02,
+		TrailDistance: 1.5, StopBars: 3}
+	data, err_ := proto.Marshal(q)
+	if err_ != nil {
+		t.Fatal(err_)
+	}
+	got := &stratpb.EnterReq{}
+	if err_ = proto.Unmarshal(data, got); err_ != nil {
+		t.Fatal(err_)
+	}
+	req := fromHostEnter(got)
+	expect := &EnterReq{Tag: "long", LegalCost: 100, StopLoss: 90, TrailActivate: 110, TrailCallback: 0.02,
+		TrailDistance: 1.5, StopBars: 3}
+	if *req != *expect {
+		t.Errorf("trailing stop lost through host adapter: %+v", req)
+	}
+}
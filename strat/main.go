@@ -3,7 +3,6 @@ package strat
 import (
 	"flag"
 	"fmt"
-	"github.com/banbox/banbot/btime"
 	"github.com/banbox/banbot/config"
 	"github.com/banbox/banbot/core"
 	"github.com/banbox/banbot/goods"
@@ -22,46 +21,46 @@ import (
 /*
 LoadStratJobs Loading strategies and trading pairs 加载策略和交易对
 
-更新以下全局变量：
-Update the following global variables:
-core.TFSecs
-core.StgPairTfs
-core.BookPairs
-strat.Versions
-strat.Envs
-strat.PairStrats
-strat.AccJobs
-strat.AccInfoJobs
+更新上下文的以下字段：
+Update the following fields of the context:
+TFSecs
+StgPairTfs
+BookPairs
+c.Versions
+c.Envs
+c.PairStrats
+c.AccJobs
+c.AccInfoJobs
 
 	return：pair:timeframe:warmNum, acc:exit orders, error
 */
-func LoadStratJobs(pairs []string, tfScores map[string]map[string]float64) (map[string]map[string]int, map[string][]*ormo.InOutOrder, *errs.Error) {
+func (c *Ctx) LoadStratJobs(pairs []string, tfScores map[string]map[string]float64) (map[string]map[string]int, map[string][]*ormo.InOutOrder, *errs.Error) {
 	if len(pairs) == 0 || len(tfScores) == 0 {
 		return nil, nil, errs.NewMsg(errs.CodeParamRequired, "`pairs` and `tfScores` are required for LoadStratJobs")
 	}
 	// Set the global variables involved to null, as will be updated below
 	// 将涉及的全局变量置为空，下面会更新
-	core.TFSecs = make(map[string]int)
-	core.BookPairs = make(map[string]bool)
-	core.StgPairTfs = make(map[string]map[string]string)
-	resetJobs()
+	c.TFSecs = make(map[string]int)
+	c.BookPairs = make(map[string]bool)
+	c.StgPairTfs = make(map[string]map[string]string)
+	c.resetJobs()
 	pairTfWarms := make(Warms)
 	// 记录每个账户下，每个策略的任务数量，防止超过账户要求数量
 	accLimits, maxJobNum := newAccStratLimits()
-	for _, pol := range config.RunPolicy {
+	for _, pol := range c.RunPolicy() {
 		stgy := New(pol)
 		polID := pol.ID()
 		if stgy == nil {
 			return nil, nil, errs.NewMsg(core.ErrRunTime, "strategy %s load fail", polID)
 		}
-		Versions[stgy.Name] = stgy.Version
+		c.Versions[stgy.Name] = stgy.Version
 		stgyMaxNum := pol.MaxPair
 		if stgyMaxNum == 0 {
 			stgyMaxNum = maxJobNum
 		}
 		holdNum := 0
 		failTfScores := make(map[string]map[string]float64)
-		var curPairs, err = getPolicyPairs(pol, pairs)
+		var curPairs, err = c.getPolicyPairs(pol, pairs)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -79,13 +78,13 @@ func LoadStratJobs(pairs []string, tfScores map[string]map[string]float64) (map[
 				break
 			}
 			curStgy := stgy
-			scores, _ := tfScores[exs.Symbol]
-			tf := curStgy.pickTimeFrame(exs.Symbol, scores)
+			scores, _ := tfScores[exs.PairKey()]
+			tf := curStgy.pickTimeFrame(exs.PairKey(), scores)
 			if tf == "" {
-				failTfScores[exs.Symbol] = scores
+				failTfScores[exs.PairKey()] = scores
 				continue
 			}
-			jobType := JobForbidType(exs.Symbol, tf, polID)
+			jobType := c.JobForbidType(exs.PairKey(), tf, polID)
 			if jobType > 0 {
 				if jobType > 1 {
 					// 任务禁止，但增加占位
@@ -96,33 +95,35 @@ func LoadStratJobs(pairs []string, tfScores map[string]map[string]float64) (map[
 				}
 				continue
 			}
-			items, ok := PairStrats[exs.Symbol]
+			items, ok := c.PairStrats[exs.PairKey()]
 			if !ok {
 				items = make(map[string]*TradeStrat)
-				PairStrats[exs.Symbol] = items
+				c.PairStrats[exs.PairKey()] = items
 			}
-			if _, ok = items[polID]; ok {
+			curPol, isDiff := pol.PairDup(exs.PairKey())
+			if old, ok := items[polID]; ok && old.Policy.SameStrat(curPol) {
 				// 当前pair+stratID已有任务，跳过
-				err = markStratJob(tf, polID, exs, dirt, accLimits)
+				err = c.markStratJob(tf, polID, exs, dirt, accLimits)
 				if err != nil {
 					return nil, nil, err
 				}
 				holdNum += 1
 				continue
 			}
-			// Check for proprietary parameters of the current target and reinitialize the strategy
-			// 检查有当前标的专有参数，重新初始化策略
-			if curPol, isDiff := pol.PairDup(exs.Symbol); isDiff {
+			// Check for proprietary parameters of the current target and reinitialize the strategy,
+			// existing jobs of a policy changed by reload get the new strategy in ensureStratJob
+			// 检查有当前标的专有参数，重新初始化策略；重载修改了策略配置时，已有任务在ensureStratJob中替换为新策略
+			if isDiff {
 				curStgy = New(curPol)
 			}
 			items[polID] = curStgy
 			holdNum += 1
 			// 初始化BarEnv
-			env := initBarEnv(exs, tf)
+			env := c.initBarEnv(exs, tf)
 			// Record the data that needs to be preheated; Record subscription information
 			// 记录需要预热的数据；记录订阅信息
-			pairTfWarms.Update(exs.Symbol, tf, curStgy.WarmupNum)
-			ensureStratJob(curStgy, tf, exs, env, dirt, pairTfWarms.Update, accLimits)
+			pairTfWarms.Update(exs.PairKey(), tf, curStgy.WarmupNum)
+			c.ensureStratJob(curStgy, tf, exs, env, dirt, pairTfWarms.Update, accLimits)
 		}
 		printFailTfScores(polID, failTfScores)
 	}
@@ -134,7 +135,7 @@ func LoadStratJobs(pairs []string, tfScores map[string]map[string]float64) (map[
 	newPairs := make(map[string]bool)  // 继续监听的品种
 	pairTfs := make(Warms)
 	holdPosition := config.PairMgr.PosOnRotation != "close"
-	for acc, jobs := range AccJobs {
+	for acc, jobs := range c.AccJobs {
 		exitOds := make([]*ormo.InOutOrder, 0, 4)
 		for envKey, envJobs := range jobs {
 			resJobs := make(map[string]*StratJob)
@@ -150,7 +151,7 @@ func LoadStratJobs(pairs []string, tfScores map[string]map[string]float64) (map[
 							job.Strat.OnShutDown(job)
 						}
 						exitJobs[job] = true
-						exitPairs[job.Symbol.Symbol] = true
+						exitPairs[job.Symbol.PairKey()] = true
 						if job.EnteredNum > 0 {
 							exitOds = append(exitOds, job.LongOrders...)
 							exitOds = append(exitOds, job.ShortOrders...)
@@ -159,25 +160,25 @@ func LoadStratJobs(pairs []string, tfScores map[string]map[string]float64) (map[
 				} else {
 					// 可以继续开单
 					resJobs[name] = job
-					newPairs[job.Symbol.Symbol] = true
+					newPairs[job.Symbol.PairKey()] = true
 				}
 			}
 			if len(resJobs) > 0 {
 				jobs[envKey] = resJobs
 				arr := strings.Split(envKey, "_")
 				pair, tf := arr[0], arr[1]
-				if _, ok := core.TFSecs[tf]; !ok {
-					core.TFSecs[tf] = utils2.TFToSecs(tf)
+				if _, ok := c.TFSecs[tf]; !ok {
+					c.TFSecs[tf] = utils2.TFToSecs(tf)
 				}
 				for _, j := range resJobs {
-					subMap, ok := core.StgPairTfs[j.Strat.Name]
+					subMap, ok := c.StgPairTfs[j.Strat.Name]
 					if !ok {
 						subMap = make(map[string]string)
-						core.StgPairTfs[j.Strat.Name] = subMap
+						c.StgPairTfs[j.Strat.Name] = subMap
 					}
 					subMap[pair] = tf
 					if j.Strat.WatchBook {
-						core.BookPairs[pair] = true
+						c.BookPairs[pair] = true
 					}
 				}
 				envKeys[envKey] = true
@@ -199,13 +200,13 @@ func LoadStratJobs(pairs []string, tfScores map[string]map[string]float64) (map[
 		keys := utils2.KeysOfMap(exitPairs)
 		log.Info("exit pairs", zap.Int("num", len(keys)), zap.Strings("arr", keys))
 	}
-	for k := range core.PairsMap {
+	for k := range c.PairsMap {
 		_, ok := newPairs[k]
-		core.PairsMap[k] = ok
+		c.PairsMap[k] = ok
 	}
 	// 从AccInfoJobs中移除已取消的项
-	lockInfoJobs.Lock()
-	for acc, jobMap := range AccInfoJobs {
+	c.lockInfoJobs.Lock()
+	for acc, jobMap := range c.AccInfoJobs {
 		newJobMap := make(map[string]map[string]*StratJob)
 		for pairTf, stgMap := range jobMap {
 			newStgMap := make(map[string]*StratJob)
@@ -219,28 +220,28 @@ func LoadStratJobs(pairs []string, tfScores map[string]map[string]float64) (map[
 				envKeys[pairTf] = true
 				arr := strings.Split(pairTf, "_")
 				pair, tf := arr[0], arr[1]
-				if _, ok := core.TFSecs[tf]; !ok {
-					core.TFSecs[tf] = utils2.TFToSecs(tf)
+				if _, ok := c.TFSecs[tf]; !ok {
+					c.TFSecs[tf] = utils2.TFToSecs(tf)
 				}
 				// 确保添加到pairTfWarms中
 				pairTfs.Update(pair, tf, 0)
 			}
 		}
-		AccInfoJobs[acc] = newJobMap
+		c.AccInfoJobs[acc] = newJobMap
 	}
-	lockInfoJobs.Unlock()
+	c.lockInfoJobs.Unlock()
 	// Ensure that all pairs and TFs are recorded in the returned data to prevent them from being removed by the data subscriber
 	// 确保所有pair、tf都在返回的中有记录，防止被数据订阅端移除
-	for _, pairMap := range core.StgPairTfs {
+	for _, pairMap := range c.StgPairTfs {
 		for pair, tf := range pairMap {
 			pairTfs.Update(pair, tf, 0)
 		}
 	}
-	// Remove useless items from PairStrats
+	// Remove useless items from c.PairStrats
 	// 从PairStrats中删除无用的项
-	for pair, stgMap := range PairStrats {
+	for pair, stgMap := range c.PairStrats {
 		for name := range stgMap {
-			if pairMap, ok := core.StgPairTfs[name]; ok {
+			if pairMap, ok := c.StgPairTfs[name]; ok {
 				if _, ok = pairMap[pair]; ok {
 					continue
 				}
@@ -248,11 +249,11 @@ func LoadStratJobs(pairs []string, tfScores map[string]map[string]float64) (map[
 			delete(stgMap, name)
 		}
 	}
-	// Remove useless items from Envs
+	// Remove useless items from c.Envs
 	// 从Envs中删除无用的项
-	for envKey := range Envs {
+	for envKey := range c.Envs {
 		if _, ok := envKeys[envKey]; !ok {
-			delete(Envs, envKey)
+			delete(c.Envs, envKey)
 		}
 	}
 	// 从pairTfs中确认哪些要恢复
@@ -271,8 +272,8 @@ func LoadStratJobs(pairs []string, tfScores map[string]map[string]float64) (map[
 	return pairTfs, accExitOds, nil
 }
 
-func ExitStratJobs() {
-	for _, jobs := range AccJobs {
+func (c *Ctx) ExitStratJobs() {
+	for _, jobs := range c.AccJobs {
 		for _, items := range jobs {
 			for _, job := range items {
 				if job.Strat.OnShutDown != nil {
@@ -302,28 +303,28 @@ func printFailTfScores(stratName string, pairTfScores map[string]map[string]floa
 	log.Info(fmt.Sprintf("%v filter pairs by tfScore: \n%v", stratName, strings.Join(lines, "\n")))
 }
 
-func initBarEnv(exs *orm.ExSymbol, tf string) *ta.BarEnv {
-	envKey := strings.Join([]string{exs.Symbol, tf}, "_")
-	env, ok := Envs[envKey]
+func (c *Ctx) initBarEnv(exs *orm.ExSymbol, tf string) *ta.BarEnv {
+	envKey := strings.Join([]string{exs.PairKey(), tf}, "_")
+	env, ok := c.Envs[envKey]
 	if !ok {
 		tfMSecs := int64(utils2.TFToSecs(tf) * 1000)
 		env = &ta.BarEnv{
-			Exchange:   core.ExgName,
-			MarketType: core.Market,
-			Symbol:     exs.Symbol,
+			Exchange:   exs.Exchange,
+			MarketType: exs.Market,
+			Symbol:     exs.PairKey(),
 			TimeFrame:  tf,
 			TFMSecs:    tfMSecs,
 			MaxCache:   core.NumTaCache,
 			Data:       map[string]interface{}{"sid": int64(exs.ID)},
 		}
-		Envs[envKey] = env
+		c.Envs[envKey] = env
 	}
 	return env
 }
 
-func markStratJob(tf, stgName string, exs *orm.ExSymbol, dirt int, accLimits accStratLimits) *errs.Error {
-	envKey := strings.Join([]string{exs.Symbol, tf}, "_")
-	for acc, jobs := range AccJobs {
+func (c *Ctx) markStratJob(tf, stgName string, exs *orm.ExSymbol, dirt int, accLimits accStratLimits) *errs.Error {
+	envKey := strings.Join([]string{exs.PairKey(), tf}, "_")
+	for acc, jobs := range c.AccJobs {
 		envJobs, ok := jobs[envKey]
 		if !ok {
 			return errs.NewMsg(errs.CodeRunTime, "`envKey` for StratJob not found: %s", envKey)
@@ -345,10 +346,10 @@ func markStratJob(tf, stgName string, exs *orm.ExSymbol, dirt int, accLimits acc
 	return nil
 }
 
-func ensureStratJob(stgy *TradeStrat, tf string, exs *orm.ExSymbol, env *ta.BarEnv, dirt int,
+func (c *Ctx) ensureStratJob(stgy *TradeStrat, tf string, exs *orm.ExSymbol, env *ta.BarEnv, dirt int,
 	logWarm func(pair, tf string, num int), accLimits accStratLimits) {
-	envKey := strings.Join([]string{exs.Symbol, tf}, "_")
-	for account, jobs := range AccJobs {
+	envKey := strings.Join([]string{exs.PairKey(), tf}, "_")
+	for account, jobs := range c.AccJobs {
 		envJobs, ok := jobs[envKey]
 		if !ok {
 			envJobs = make(map[string]*StratJob)
@@ -361,6 +362,7 @@ func ensureStratJob(stgy *TradeStrat, tf string, exs *orm.ExSymbol, env *ta.BarE
 				continue
 			}
 			job = &StratJob{
+				ctx:           c,
 				Strat:         stgy,
 				Env:           env,
 				Symbol:        exs,
@@ -376,6 +378,17 @@ func ensureStratJob(stgy *TradeStrat, tf string, exs *orm.ExSymbol, env *ta.BarE
 				stgy.OnStartUp(job)
 			}
 			envJobs[stgy.Name] = job
+		} else if job.Strat != stgy {
+			// strategy rebuilt for changed policy, keep the orders and env of the job
+			// 策略配置变化后重建，保留任务的订单和环境
+			if job.Strat.OnShutDown != nil {
+				job.Strat.OnShutDown(job)
+			}
+			job.Strat = stgy
+			job.More = nil
+			if stgy.OnStartUp != nil {
+				stgy.OnStartUp(job)
+			}
 		}
 		if allowOpen {
 			job.MaxOpenShort = stgy.EachMaxShort
@@ -389,12 +402,12 @@ func ensureStratJob(stgy *TradeStrat, tf string, exs *orm.ExSymbol, env *ta.BarE
 		// Load subscription information for other targets
 		// 加载订阅其他标的信息
 		if stgy.OnPairInfos != nil {
-			infoJobs := GetInfoJobs(account)
+			infoJobs := c.GetInfoJobs(account)
 			for _, s := range stgy.OnPairInfos(job) {
 				pair := s.Pair
 				if pair == "_cur_" {
-					pair = exs.Symbol
-					initBarEnv(exs, s.TimeFrame)
+					pair = exs.PairKey()
+					c.initBarEnv(exs, s.TimeFrame)
 				} else {
 					curExs, err := orm.GetExSymbolCur(pair)
 					if err != nil {
@@ -402,7 +415,7 @@ func ensureStratJob(stgy *TradeStrat, tf string, exs *orm.ExSymbol, env *ta.BarE
 							zap.String("pair", pair))
 						continue
 					}
-					initBarEnv(curExs, s.TimeFrame)
+					c.initBarEnv(curExs, s.TimeFrame)
 				}
 				logWarm(pair, s.TimeFrame, s.WarmupNum)
 				jobKey := strings.Join([]string{pair, s.TimeFrame}, "_")
@@ -412,7 +425,7 @@ func ensureStratJob(stgy *TradeStrat, tf string, exs *orm.ExSymbol, env *ta.BarE
 					infoJobs[jobKey] = items
 				}
 				// 这里需要stratID+pair作为键，否则多个品种订阅同一个额外品种数据时，只记录了最后一个
-				items[strings.Join([]string{stgy.Name, exs.Symbol}, "_")] = job
+				items[strings.Join([]string{stgy.Name, exs.PairKey()}, "_")] = job
 			}
 		}
 	}
@@ -421,16 +434,16 @@ func ensureStratJob(stgy *TradeStrat, tf string, exs *orm.ExSymbol, env *ta.BarE
 /*
 将jobs的MaxOpenLong,MacOpenShort都置为-1，禁止开单，并更新附加订单
 */
-func resetJobs() {
+func (c *Ctx) resetJobs() {
 	for account := range config.Accounts {
-		openOds, lock := ormo.GetOpenODs(account)
+		openOds, lock := c.GetOpenODs(account)
 		odList := make([]*ormo.InOutOrder, 0, len(openOds))
 		lock.Lock()
 		for _, od := range openOds {
 			odList = append(odList, od)
 		}
 		lock.Unlock()
-		accJobs := GetJobs(account)
+		accJobs := c.GetJobs(account)
 		for _, jobs := range accJobs {
 			for _, job := range jobs {
 				job.InitBar(odList)
@@ -441,9 +454,12 @@ func resetJobs() {
 	}
 }
 
-var polFilters = make(map[string][]goods.IFilter)
+// ResetPolFilters clear the cached pair filters of policies, call it after run_policy reloaded 清空策略的品种过滤器缓存，run_policy重载后调用
+func (c *Ctx) ResetPolFilters() {
+	c.polFilters = make(map[string][]goods.IFilter)
+}
 
-func getPolicyPairs(pol *config.RunPolicyConfig, pairs []string) ([]string, *errs.Error) {
+func (c *Ctx) getPolicyPairs(pol *config.RunPolicyConfig, pairs []string) ([]string, *errs.Error) {
 	// According to pol Pair determines the subject of the transaction
 	// 根据pol.Pairs确定交易的标的
 	if len(pol.Pairs) > 0 {
@@ -456,18 +472,18 @@ func getPolicyPairs(pol *config.RunPolicyConfig, pairs []string) ([]string, *err
 		// Filter based on filters
 		// 根据filters过滤筛选
 		polID := pol.ID()
-		filters, ok := polFilters[polID]
+		filters, ok := c.polFilters[polID]
 		var err *errs.Error
 		if !ok {
 			filters, err = goods.GetPairFilters(pol.Filters, false)
 			if err != nil {
 				return nil, err
 			}
-			polFilters[polID] = filters
+			c.polFilters[polID] = filters
 		}
-		curMS := btime.TimeMS()
+		curMS := c.TimeMS()
 		for _, flt := range filters {
-			pairs, err = flt.Filter(pairs, curMS)
+			pairs, err = goods.RunFilter(c.BotCtx, flt, pairs, curMS)
 			if err != nil {
 				return nil, err
 			}
@@ -0,0 +1,118 @@
+package strat
+
+import (
+	"testing"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/exg"
+	"github.com/banbox/banbot/orm"
+	"github.com/banbox/banbot/orm/ormo"
+	"github.com/banbox/banexg"
+)
+
+func newTestCtx() *Ctx {
+	return NewCtx(ormo.NewCtx(core.NewBotCtx()))
+}
+
+// setupStratPairs register markets of pairs to a file kline store, no network or database is required
+// 将品种的市场注册到文件K线存储，无需网络或数据库
+func setupStratPairs(t *testing.T, pairs ...string) *Ctx {
+	oldDb, oldExg, oldDefault := config.Database, config.Exchange, exg.Default
+	oldMarket, oldAccs, oldMgr, oldExgName := core.Market, config.Accounts, config.PairMgr, core.ExgName
+	oldPols := config.RunPolicy
+	t.Cleanup(func() {
+		config.Database, config.Exchange, exg.Default = oldDb, oldExg, oldDefault
+		core.Market, config.Accounts, config.PairMgr, core.ExgName = oldMarket, oldAccs, oldMgr, oldExgName
+		config.RunPolicy = oldPols
+	})
+	config.Database = &config.DatabaseConfig{KlineStore: "file", KlineDir: t.TempDir()}
+	config.Exchange = &config.ExchangeConfig{Name: "binance"}
+	config.Accounts = map[string]*config.AccountConfig{config.DefAcc: {}}
+	config.PairMgr = &config.PairMgrConfig{}
+	core.Market, core.ExgName = banexg.MarketSpot, "binance"
+	exg.Default = nil
+	c := newTestCtx()
+	c.CurTimeMS = 1700000000000
+	if err := orm.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	client, err := exg.GetWith("binance", banexg.MarketSpot, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exg.Default = client
+	markets := make(banexg.MarketMap)
+	var exsList []*orm.ExSymbol
+	for _, pair := range pairs {
+		markets[pair] = &banexg.Market{Symbol: pair, Type: banexg.MarketSpot, Spot: true, Active: true}
+		exsList = append(exsList, &orm.ExSymbol{Exchange: "binance", Market: banexg.MarketSpot, Symbol: pair})
+	}
+	client.GetExg().Markets = markets
+	if err = orm.EnsureSymbols(exsList); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestReloadStratJobs(t *testing.T) {
+	pairs := []string{"BTC/USDT", "ETH/USDT"}
+	c := setupStratPairs(t, pairs...)
+	var shutdowns int
+	StratMake["reload_test"] = func(pol *config.RunPolicyConfig) *TradeStrat {
+		period := pol.DefInt("period", 10, core.PUniform(5, 30))
+		return &TradeStrat{
+			WarmupNum: period,
+			AllowTFs:  []string{"1h"},
+			OnStartUp: func(s *StratJob) {
+				s.More = period
+			},
+			OnShutDown: func(s *StratJob) {
+				shutdowns += 1
+			},
+		}
+	}
+	defer delete(StratMake, "reload_test")
+	tfScores := map[string]map[string]float64{"BTC/USDT": {"1h": 1}, "ETH/USDT": {"1h": 1}}
+	loadPolicy := func(pol *config.RunPolicyConfig) {
+		config.ApplyPairPolicy(nil, []*config.RunPolicyConfig{pol})
+		if _, _, err := c.LoadStratJobs(pairs, tfScores); err != nil {
+			t.Fatal(err)
+		}
+	}
+	getJob := func(pair string) *StratJob {
+		return c.GetJobs(config.DefAcc)[pair+"_1h"]["reload_test"]
+	}
+	loadPolicy(&config.RunPolicyConfig{Name: "reload_test", Params: map[string]float64{"period": 10},
+		PairParams: map[string]map[string]float64{"ETH/USDT": {"period": 20}}})
+	btcJob, ethJob := getJob("BTC/USDT"), getJob("ETH/USDT")
+	if btcJob == nil || ethJob == nil || btcJob.More != 10 || ethJob.More != 20 {
+		t.Fatalf("jobs not loaded: %+v %+v", btcJob, ethJob)
+	}
+	btcStgy, ethStgy := btcJob.Strat, ethJob.Strat
+	// same policy reloaded as new objects, strategies are kept 重载为新对象的相同策略，保持原策略
+	loadPolicy(&config.RunPolicyConfig{Name: "reload_test", Params: map[string]float64{"period": 10},
+		PairParams: map[string]map[string]float64{"ETH/USDT": {"period": 20}}, MaxPair: 5})
+	if getJob("BTC/USDT").Strat != btcStgy || getJob("ETH/USDT").Strat != ethStgy || shutdowns != 0 {
+		t.Errorf("unchanged policy should keep running strategies")
+	}
+	// changed param reaches the running strategy, pair params are kept 修改的参数应用到运行中的策略，品种参数保持
+	loadPolicy(&config.RunPolicyConfig{Name: "reload_test", Params: map[string]float64{"period": 15},
+		PairParams: map[string]map[string]float64{"ETH/USDT": {"period": 20}}})
+	job := getJob("BTC/USDT")
+	if job != btcJob || job.Strat == btcStgy || job.More != 15 || job.Strat.WarmupNum != 15 {
+		t.Errorf("changed param should rebuild strategy of running job, more: %v", job.More)
+	}
+	if c.Get("BTC/USDT", "reload_test") != job.Strat {
+		t.Errorf("PairStrats should hold the rebuilt strategy")
+	}
+	if getJob("ETH/USDT").Strat != ethStgy || shutdowns != 1 {
+		t.Errorf("pair params unchanged, ETH strategy should be kept, shutdowns: %v", shutdowns)
+	}
+	// changed stake_rate also rebuilds 修改stake_rate同样重建
+	loadPolicy(&config.RunPolicyConfig{Name: "reload_test", Params: map[string]float64{"period": 15},
+		PairParams: map[string]map[string]float64{"ETH/USDT": {"period": 20}}, StakeRate: 0.5})
+	if getJob("BTC/USDT").Strat.StakeRate != 0.5 || getJob("ETH/USDT").Strat.StakeRate != 0.5 {
+		t.Errorf("stake_rate should reach running strategies")
+	}
+}
@@ -0,0 +1,258 @@
+package strat
+
+import (
+	"math"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/orm"
+	"github.com/banbox/banbot/orm/ormo"
+	"github.com/banbox/banexg/errs"
+	"github.com/banbox/banexg/log"
+	"go.uber.org/zap"
+)
+
+/*
+PositionSizer
+Calculate the legal cost (notional value) of a new order whose EnterReq has no LegalCost/Amount.
+The result is multiplied by EnterReq.CostRate, and goes through the same open_vol_rate and min cost checks in
+both backtest and live.
+为未指定LegalCost/Amount的EnterReq计算开单法币金额(名义价值)。
+结果会乘以EnterReq.CostRate，并在回测和实盘中同样经过open_vol_rate和最小金额检查。
+*/
+type PositionSizer interface {
+	Name() string
+	// LegalCost price is the entry price, stopLoss is the stop loss price, 0 if not set 入场价格和止损价格，未设置止损时为0
+	LegalCost(s *StratJob, price, stopLoss float64) float64
+}
+
+/*
+NewPositionSizer
+Create the position sizer from run_policy.sizer, returns nil for the default fixed stake
+根据run_policy.sizer创建仓位计算器，默认固定金额时返回nil
+*/
+func NewPositionSizer(cfg *config.SizerConfig) (PositionSizer, *errs.Error) {
+	if cfg == nil || cfg.Name == "" || cfg.Name == core.SizerFixed {
+		return nil, nil
+	}
+	base := sizerBase{Risk: cfg.Risk, MaxRate: cfg.MaxRate}
+	if base.Risk <= 0 {
+		base.Risk = 0.01
+	}
+	if base.MaxRate <= 0 {
+		base.MaxRate = 3
+	}
+	switch cfg.Name {
+	case core.SizerRisk:
+		return &RiskSizer{sizerBase: base}, nil
+	case core.SizerATR:
+		res := &ATRSizer{sizerBase: base, Period: cfg.ATRPeriod, Multi: cfg.ATRMulti}
+		if res.Period <= 0 {
+			res.Period = 14
+		}
+		if res.Multi <= 0 {
+			res.Multi = 2
+		}
+		return res, nil
+	case core.SizerKelly:
+		res := &KellySizer{sizerBase: base, Fraction: cfg.KellyFrac, Window: cfg.Window, MinOdNum: cfg.MinOdNum}
+		if res.Fraction <= 0 {
+			res.Fraction = 0.5
+		}
+		if res.Window <= 0 {
+			res.Window = 100
+		}
+		if res.MinOdNum <= 0 {
+			res.MinOdNum = 30
+		}
+		return res, nil
+	default:
+		return nil, errs.NewMsg(core.ErrBadConfig, "invalid run_policy.sizer.name: %s", cfg.Name)
+	}
+}
+
+func (s *TradeStrat) getLegalCost(j *StratJob, req *EnterReq, price, stopLoss float64) float64 {
+	if s.Sizer == nil {
+		return s.GetStakeAmount(j) * req.CostRate
+	}
+	return s.Sizer.LegalCost(j, price, stopLoss) * req.CostRate
+}
+
+type sizerBase struct {
+	Risk    float64
+	MaxRate float64
+}
+
+/*
+byRisk
+Cost so that equity*Risk is lost when price moves by dist (rate of price), capped by MaxRate*stake.
+Falls back to the fixed stake when equity or dist is unknown.
+使价格变动dist(价格比例)时亏损equity*Risk的金额，上限为MaxRate*固定金额。权益或dist未知时使用固定金额。
+*/
+func (b *sizerBase) byRisk(s *StratJob, dist float64) float64 {
+	stake := s.Strat.GetStakeAmount(s)
+	equity := s.Ctx().accEquity(s.Account)
+	if equity <= 0 || dist <= 0 || math.IsNaN(dist) {
+		return stake
+	}
+	return min(equity*b.Risk/dist, stake*b.MaxRate)
+}
+
+func (c *Ctx) accEquity(account string) float64 {
+	if c.AccEquity == nil {
+		return 0
+	}
+	return c.AccEquity(account)
+}
+
+/*
+RiskSizer
+Fixed-fractional risk: lose Risk of equity when the stop loss of the order is hit
+固定比例风险：订单触发止损时亏损权益的Risk比例
+*/
+type RiskSizer struct {
+	sizerBase
+}
+
+func (r *RiskSizer) Name() string {
+	return core.SizerRisk
+}
+
+func (r *RiskSizer) LegalCost(s *StratJob, price, stopLoss float64) float64 {
+	if stopLoss <= 0 || price <= 0 {
+		return r.byRisk(s, 0)
+	}
+	return r.byRisk(s, math.Abs(price-stopLoss)/price)
+}
+
+/*
+ATRSizer
+Volatility targeting: lose Risk of equity when price moves Multi*ATR
+波动率目标：价格变动Multi*ATR时亏损权益的Risk比例
+*/
+type ATRSizer struct {
+	sizerBase
+	Period int
+	Multi  float64
+}
+
+func (r *ATRSizer) Name() string {
+	return core.SizerATR
+}
+
+func (r *ATRSizer) LegalCost(s *StratJob, price, _ float64) float64 {
+	atr := calcATR(s, r.Period)
+	if atr <= 0 || price <= 0 {
+		return r.byRisk(s, 0)
+	}
+	return r.byRisk(s, r.Multi*atr/price)
+}
+
+// calcATR simple average of true range of the latest period bars, 0 if not enough bars 最近period根bar真实波幅的简单平均，bar不足时返回0
+func calcATR(s *StratJob, period int) float64 {
+	e := s.Env
+	if e == nil || e.Close == nil || e.Close.Len() <= period {
+		return 0
+	}
+	var sum float64
+	for i := 0; i < period; i++ {
+		high, low, prevClose := e.High.Get(i), e.Low.Get(i), e.Close.Get(i+1)
+		sum += max(high-low, math.Abs(high-prevClose), math.Abs(low-prevClose))
+	}
+	return sum / float64(period)
+}
+
+/*
+KellySizer
+Capped fractional kelly from the realized profit rates of recent closed orders of the strategy:
+f = W/L - (1-W)/G, where W is win rate, G and L are average profit and loss rate. Cost is equity*Fraction*f,
+capped by MaxRate*stake. Use fixed stake before MinOdNum orders are closed.
+根据策略最近平仓订单的已实现收益率计算限额分数凯利：f = W/L - (1-W)/G，W为胜率，G和L为平均盈利和亏损率。
+金额为equity*Fraction*f，上限为MaxRate*固定金额。平仓订单少于MinOdNum时使用固定金额。
+*/
+type KellySizer struct {
+	sizerBase
+	Fraction float64
+	Window   int
+	MinOdNum int
+}
+
+func (r *KellySizer) Name() string {
+	return core.SizerKelly
+}
+
+func (r *KellySizer) LegalCost(s *StratJob, _, _ float64) float64 {
+	stake := s.Strat.GetStakeAmount(s)
+	equity := s.Ctx().accEquity(s.Account)
+	if equity <= 0 {
+		return stake
+	}
+	orders, err := s.Ctx().getClosedOrders(s.Account, s.Strat.Name, r.Window)
+	if err != nil {
+		log.Error("load closed orders for kelly fail", zap.String("strat", s.Strat.Name), zap.Error(err))
+		return stake
+	}
+	if len(orders) < r.MinOdNum {
+		return stake
+	}
+	f := kellyFraction(orders)
+	if f <= 0 {
+		return 0
+	}
+	return min(equity*r.Fraction*f, stake*r.MaxRate)
+}
+
+// kellyFraction f = W/L - (1-W)/G, +Inf if there is no loss 没有亏损时返回+Inf
+func kellyFraction(orders []*ormo.InOutOrder) float64 {
+	var winNum int
+	var winSum, lossSum float64
+	for _, od := range orders {
+		if od.ProfitRate > 0 {
+			winNum += 1
+			winSum += od.ProfitRate
+		} else {
+			lossSum -= od.ProfitRate
+		}
+	}
+	lossNum := len(orders) - winNum
+	if winNum == 0 {
+		return 0
+	}
+	if lossNum == 0 || lossSum == 0 {
+		return math.Inf(1)
+	}
+	winRate := float64(winNum) / float64(len(orders))
+	avgWin := winSum / float64(winNum)
+	avgLoss := lossSum / float64(lossNum)
+	return winRate/avgLoss - (1-winRate)/avgWin
+}
+
+/*
+getClosedOrders
+Recent closed orders of the strategy, from database in live mode, from HistODs in backtest
+策略最近的已平仓订单，实盘从数据库查询，回测从HistODs查询
+*/
+func (c *Ctx) getClosedOrders(account, stgy string, limit int) ([]*ormo.InOutOrder, *errs.Error) {
+	if core.LiveMode {
+		sess, conn, err := ormo.Conn(orm.DbTrades, false)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		return sess.GetOrders(ormo.GetOrdersArgs{
+			TaskID:   c.GetTaskID(account),
+			Strategy: stgy,
+			Status:   2,
+			Limit:    limit,
+		})
+	}
+	var res []*ormo.InOutOrder
+	taskID := c.GetTaskID(account)
+	for i := len(c.HistODs) - 1; i >= 0 && len(res) < limit; i-- {
+		od := c.HistODs[i]
+		if od.Strategy == stgy && od.TaskID == taskID {
+			res = append(res, od)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,91 @@
+package strat
+
+import (
+	"math"
+	"testing"
+
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/orm"
+	"github.com/banbox/banbot/orm/ormo"
+)
+
+func TestKellyFraction(t *testing.T) {
+	var orders []*ormo.InOutOrder
+	// win rate 0.6, avg win 0.1, avg loss 0.05 胜率0.6，平均盈利0.1，平均亏损0.05
+	for i := 0; i < 10; i++ {
+		rate := 0.1
+		if i >= 6 {
+			rate = -0.05
+		}
+		orders = append(orders, &ormo.InOutOrder{IOrder: &ormo.IOrder{ProfitRate: rate}})
+	}
+	f := kellyFraction(orders)
+	if math.Abs(f-(0.6/0.05-0.4/0.1)) > 1e-9 {
+		t.Errorf("bad kelly fraction: %v", f)
+	}
+	if f = kellyFraction(orders[6:]); f != 0 {
+		t.Errorf("all loss should be 0, got %v", f)
+	}
+}
+
+func TestPositionSizer(t *testing.T) {
+	config.StakeAmount = 100
+	c := newTestCtx()
+	c.AccEquity = func(account string) float64 {
+		return 10000
+	}
+	job := &StratJob{ctx: c, Strat: &TradeStrat{Name: "demo"}, Symbol: &orm.ExSymbol{Symbol: "BTC/USDT"}, TimeFrame: "1h"}
+	sizer, err := NewPositionSizer(&config.SizerConfig{Name: core.SizerRisk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// lose 1% of equity(100) when price drops 5%: cost 2000, capped by 3*stake 价格下跌5%时亏损1%权益
+	if cost := sizer.LegalCost(job, 100, 95); cost != 300 {
+		t.Errorf("risk cost should be capped to 300, got %v", cost)
+	}
+	sizer, _ = NewPositionSizer(&config.SizerConfig{Name: core.SizerRisk, MaxRate: 100})
+	if cost := sizer.LegalCost(job, 100, 95); math.Abs(cost-2000) > 1e-9 {
+		t.Errorf("risk cost should be 2000, got %v", cost)
+	}
+	if cost := sizer.LegalCost(job, 100, 0); cost != 100 {
+		t.Errorf("no stop loss should use fixed stake, got %v", cost)
+	}
+	if _, err = NewPositionSizer(&config.SizerConfig{Name: "unknown"}); err == nil {
+		t.Error("unknown sizer should fail")
+	}
+	if sizer, _ = NewPositionSizer(&config.SizerConfig{Name: core.SizerFixed}); sizer != nil {
+		t.Error("fixed sizer should be nil")
+	}
+}
+
+func TestGetClosedOrders(t *testing.T) {
+	oldLive := core.LiveMode
+	defer func() {
+		core.LiveMode = oldLive
+	}()
+	core.LiveMode = false
+	c := newTestCtx()
+	c.SetTimeRange(&config.TimeTuple{})
+	if err := c.InitTask(false, ""); err != nil {
+		t.Fatal(err)
+	}
+	taskID := c.GetTaskID(config.DefAcc)
+	// orders of other tasks or strategies should be skipped 其他任务或策略的订单应被跳过
+	c.HistODs = []*ormo.InOutOrder{
+		{IOrder: &ormo.IOrder{ID: 1, TaskID: taskID, Strategy: "demo"}},
+		{IOrder: &ormo.IOrder{ID: 2, TaskID: taskID + 100, Strategy: "demo"}},
+		{IOrder: &ormo.IOrder{ID: 3, TaskID: taskID, Strategy: "other"}},
+		{IOrder: &ormo.IOrder{ID: 4, TaskID: taskID, Strategy: "demo"}},
+	}
+	res, err := c.getClosedOrders(config.DefAcc, "demo", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[0].ID != 4 || res[1].ID != 1 {
+		t.Fatalf("expect orders 4,1 of current task, got %v", res)
+	}
+	if res, _ = c.getClosedOrders(config.DefAcc, "demo", 1); len(res) != 1 || res[0].ID != 4 {
+		t.Errorf("limit should keep latest order, got %v", res)
+	}
+}
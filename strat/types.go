@@ -32,6 +32,7 @@ type TradeStrat struct {
 	AllowTFs      []string // Allow running time period, use global configuration when not provided 允许运行的时间周期，不提供时使用全局配置
 	Outputs       []string // The content of the text file output by the strategy, where each string is one line 策略输出的文本文件内容，每个字符串是一行
 	Policy        *config.RunPolicyConfig
+	Sizer         PositionSizer // Position sizing when EnterReq has no LegalCost/Amount, nil for fixed 开单未指定金额时的仓位计算，nil使用固定金额
 
 	OnPairInfos         func(s *StratJob) []*PairSub
 	OnStartUp           func(s *StratJob)
@@ -83,6 +84,7 @@ type PairSub struct {
 }
 
 type StratJob struct {
+	ctx           *Ctx
 	Strat         *TradeStrat
 	Env           *ta.BarEnv
 	Entrys        []*EnterReq
@@ -129,12 +131,16 @@ type EnterReq struct {
 	StopLossLimit   float64 // Stop loss limit price, does not provide the use of StopLoss 止损限制价格，不提供使用StopLoss
 	StopLossRate    float64 // Stop loss exit ratio, 0 means all exits, needs to be between (0,1) 止损退出比例，0表示全部退出，需介于(0,1]之间
 	StopLossTag     string  // Reason for Stop Loss 止损原因
+	TrailActivate   float64 // Trailing stop activation price, 0 means active immediately 跟踪止损激活价格，0表示立即激活
+	TrailCallback   float64 // Trailing stop callback rate from the best price, e.g. 0.01 for 1% 跟踪止损回调比率，如0.01表示1%
+	TrailDistance   float64 // Trailing stop absolute distance from the best price 跟踪止损距最优价格的绝对距离
 	TakeProfitVal   float64 // The distance from the entry price to the take profit price is used to calculate TakeProfit 入场价格到止盈价格的距离，用于计算TakeProfit
 	TakeProfit      float64 // When the take profit trigger price is not empty, submit a take profit order on the exchange. 止盈触发价格，不为空时在交易所提交一个止盈单。
 	TakeProfitLimit float64 // Profit taking limit price, TakeProfit is not available for use 止盈限制价格，不提供使用TakeProfit
 	TakeProfitRate  float64 // Take profit exit ratio, 0 indicates full exit, needs to be between (0,1) 止盈退出比率，0表示全部退出，需介于(0,1]之间
 	TakeProfitTag   string  // Reason for profit taking 止盈原因
 	StopBars        int     // If the entry limit order exceeds how many bars and is not executed, it will be cancelled 入场限价单超过多少个bar未成交则取消
+	UserOpen        bool    // Opened manually by api, skip the bar delay check 通过api手动开单，跳过bar延迟检查
 }
 
 /*
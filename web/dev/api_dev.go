@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -74,6 +75,13 @@ func regApiDev(api fiber.Router) {
 	api.Get("/download", handleDownload)
 	api.Get("/compare_assets", getCompareAssets)
 	api.Post("/update_note", handleUpdateNote)
+	api.Get("/opt_pareto", getOptPareto)
+	api.Post("/run_optimize", handleRunOptimize)
+	api.Get("/opt_trials", getOptTrials)
+	api.Get("/opt_policy", getOptPolicy)
+	api.Post("/opt_promote", handleOptPromote)
+	api.Get("/bt_monte_carlo", getBtMonteCarlo)
+	api.Post("/run_monte_carlo", handleRunMonteCarlo)
 }
 
 func onWsDev(c *websocket.Conn) {
@@ -656,38 +664,68 @@ func handleRunBacktest(c *fiber.Ctx) error {
 		return err
 	}
 
-	// 创建临时文件存储配置
-	tmpFile, err := os.CreateTemp(os.TempDir(), "tmp_cfg_*.yml")
+	content, err := mergeTaskConfigs(args.Configs)
+	if err != nil {
+		return err
+	}
+	hashVal, err := addDevTask("backtest", content, args.DupMode, "", backtestArgs(args.Separate))
 	if err != nil {
 		return err
 	}
-	tmpPath := tmpFile.Name()
-	defer os.Remove(tmpPath)
 
-	// 写入配置内容
-	var realPath string
+	return c.JSON(fiber.Map{
+		"code": 200,
+		"data": hashVal,
+	})
+}
+
+// backtestArgs 构建回测参数
+func backtestArgs(separate bool) func(btPath string) string {
+	return func(btPath string) string {
+		btArgs := fmt.Sprintf("-out %s -prg uiPrg -no-default -config %s", btPath, btPath+"/config.yml")
+		if separate {
+			btArgs = "-separate " + btArgs
+		}
+		return btArgs
+	}
+}
+
+// mergeTaskConfigs 保存编辑的配置文件，并合并为一个配置
+func mergeTaskConfigs(configs map[string]string) (string, error) {
 	var paths []string
-	for path, text := range args.Configs {
+	for path, text := range configs {
 		if strings.TrimSpace(text) == "" {
 			continue
 		}
-		realPath, err = parsePath(path)
+		realPath, err := parsePath(path)
 		if err != nil {
-			return err
+			return "", err
 		}
 		err2 := utils.WriteFile(realPath, []byte(text))
 		if err2 != nil {
-			return err2
+			return "", err2
 		}
 		paths = append(paths, realPath)
 	}
 	skips := []string{"name", "env", "webhook", "rpc_channels", "api_server"}
-	content, err := MergeConfigPaths(paths, skips...)
+	return MergeConfigPaths(paths, skips...)
+}
+
+/*
+addDevTask
+验证配置，保存到$backtest/<hash>目录，并添加backtest/optimize任务。
+salt用于区分相同配置的不同任务参数；makeArgs根据输出目录构建命令行参数
+*/
+func addDevTask(mode, content, dupMode, salt string, makeArgs func(btPath string) string) (string, error) {
+	// 创建临时文件存储配置
+	tmpFile, err := os.CreateTemp(os.TempDir(), "tmp_cfg_*.yml")
 	if err != nil {
-		return err
+		return "", err
 	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
 	if _, err = tmpFile.WriteString(content); err != nil {
-		return err
+		return "", err
 	}
 	tmpFile.Close()
 
@@ -697,50 +735,50 @@ func handleRunBacktest(c *fiber.Ctx) error {
 		NoDefault: true,
 	}, false)
 	if err2 != nil {
-		return err2
+		return "", err2
 	}
 
 	// 检查必要的配置项
 	if len(cfg.RunPolicy) == 0 {
-		return errs.NewMsg(errs.CodeParamRequired, "run_policy is required")
+		return "", errs.NewMsg(errs.CodeParamRequired, "run_policy is required")
 	}
 	if cfg.TimeRange.StartMS == 0 || cfg.TimeRange.EndMS == 0 {
-		return errs.NewMsg(errs.CodeParamRequired, "time_range is required")
+		return "", errs.NewMsg(errs.CodeParamRequired, "time_range is required")
 	}
 	if len(cfg.WalletAmounts) == 0 {
-		return errs.NewMsg(errs.CodeParamRequired, "wallet_amounts is required")
+		return "", errs.NewMsg(errs.CodeParamRequired, "wallet_amounts is required")
 	}
 	if cfg.StakeAmount == 0 && cfg.StakePct == 0 {
-		return errs.NewMsg(errs.CodeParamRequired, "stake_amount or stake_pct is required")
+		return "", errs.NewMsg(errs.CodeParamRequired, "stake_amount or stake_pct is required")
 	}
 	if len(cfg.StakeCurrency) == 0 {
-		return errs.NewMsg(errs.CodeParamRequired, "stake_currency is required")
+		return "", errs.NewMsg(errs.CodeParamRequired, "stake_currency is required")
 	}
 	if cfg.Exchange.Name == "" {
-		return errs.NewMsg(errs.CodeParamRequired, "exchange.name is required")
+		return "", errs.NewMsg(errs.CodeParamRequired, "exchange.name is required")
 	}
-	if cfg.Database.Url == "" {
-		return errs.NewMsg(errs.CodeParamRequired, "database.url is required")
+	if cfg.Database.Url == "" && cfg.Database.KlineStore == "" {
+		return "", errs.NewMsg(errs.CodeParamRequired, "database.url is required")
 	}
 
 	// 获取配置内容并计算哈希
 	cfgData, err2 := cfg.DumpYaml()
 	if err2 != nil {
-		return err2
+		return "", err2
 	}
-	hashVal := utils.MD5(cfgData)[:10]
+	hashVal := utils.MD5(append(cfgData, salt...))[:10]
 	btPath := fmt.Sprintf("$backtest/%s", hashVal)
 	absPath := config.ParsePath(btPath)
 
 	// 创建目标目录
 	if err = os.MkdirAll(absPath, 0755); err != nil {
-		return err
+		return "", err
 	}
 
-	// 添加回测任务
+	// 添加任务
 	qu, conn, err2 := ormu.Conn()
 	if err2 != nil {
-		return err2
+		return "", err2
 	}
 	defer conn.Close()
 
@@ -748,20 +786,20 @@ func handleRunBacktest(c *fiber.Ctx) error {
 	cfgPath := filepath.Join(absPath, "config.yml")
 	if utils.Exists(cfgPath) {
 		oldTasks, err2 := qu.FindTasks(context.Background(), ormu.FindTasksParams{
-			Mode: "backtest",
+			Mode: mode,
 			Path: hashVal,
 		})
 		if err2 != nil {
-			return err2
+			return "", err2
 		}
 		var old *ormu.Task
 		if len(oldTasks) > 0 {
 			old = oldTasks[0]
 		}
 		backupPath := ""
-		if args.DupMode == "" {
-			return errs.NewMsg(errs.CodeParamRequired, "already_exist")
-		} else if args.DupMode == "backup" {
+		if dupMode == "" {
+			return "", errs.NewMsg(errs.CodeParamRequired, "already_exist")
+		} else if dupMode == "backup" {
 			backupPath = hashVal + "_bak"
 			if old != nil {
 				backupPath = hashVal + "_" + strconv.FormatInt(old.ID, 10)
@@ -769,7 +807,7 @@ func handleRunBacktest(c *fiber.Ctx) error {
 			realPath := config.ParsePath(fmt.Sprintf("$backtest/%s", backupPath))
 			err = utils.CopyDir(absPath, realPath)
 			if err != nil {
-				return err
+				return "", err
 			}
 		}
 		if old != nil {
@@ -778,24 +816,18 @@ func handleRunBacktest(c *fiber.Ctx) error {
 				Path: backupPath,
 			})
 			if err != nil {
-				return err
+				return "", err
 			}
 		}
 	}
 	if err = os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
-		return err
-	}
-
-	// 构建回测参数
-	btArgs := fmt.Sprintf("-out %s -prg uiPrg -no-default -config %s", btPath, btPath+"/config.yml")
-	if args.Separate {
-		btArgs = "-separate " + btArgs
+		return "", err
 	}
 
 	task, err := qu.AddTask(context.Background(), ormu.AddTaskParams{
-		Mode:     "backtest",
+		Mode:     mode,
 		Path:     hashVal,
-		Args:     btArgs,
+		Args:     makeArgs(btPath),
 		Config:   string(cfgData),
 		Strats:   strings.Join(cfg.Strats(), ","),
 		Periods:  strings.Join(cfg.TimeFrames(), ","),
@@ -807,14 +839,10 @@ func handleRunBacktest(c *fiber.Ctx) error {
 		Progress: 0,
 	})
 	if err != nil {
-		return err
+		return "", err
 	}
-	log.Info("add backtest", zap.Int64("id", task.ID), zap.String("hash", hashVal))
-
-	return c.JSON(fiber.Map{
-		"code": 200,
-		"data": hashVal,
-	})
+	log.Info("add "+mode, zap.Int64("id", task.ID), zap.String("hash", hashVal))
+	return hashVal, nil
 }
 
 // getBtPath 获取回测输出目录
@@ -845,6 +873,228 @@ func parseBtResult(path string) (*opt.BTResult, error) {
 	return res, nil
 }
 
+// getOptPareto 获取超参数优化日志中的帕累托前沿
+func getOptPareto(c *fiber.Ctx) error {
+	type ParetoArgs struct {
+		Path string `query:"path" validate:"required"`
+	}
+	var args = new(ParetoArgs)
+	err := base.VerifyArg(c, args, base.ArgQuery)
+	if err != nil {
+		return err
+	}
+	path, err := parsePath(args.Path)
+	if err != nil {
+		return err
+	}
+	content, err2 := utils.ReadTextFile(path)
+	if err2 != nil {
+		return err2
+	}
+	type ParetoItem struct {
+		ID       string             `json:"id"`
+		Score    float64            `json:"score"`
+		Params   map[string]float64 `json:"params"`
+		OrderNum int                `json:"orderNum"`
+		Profit   float64            `json:"profitPct"`
+		DrawDown float64            `json:"drawDownPct"`
+		Sharpe   float64            `json:"sharpe"`
+	}
+	sections := opt.ParseParetoLines(content)
+	res := make(map[string][]*ParetoItem, len(sections))
+	for title, items := range sections {
+		arr := make([]*ParetoItem, 0, len(items))
+		for _, it := range items {
+			arr = append(arr, &ParetoItem{
+				ID:       it.ID,
+				Score:    it.Score,
+				Params:   it.Params,
+				OrderNum: it.OrderNum,
+				Profit:   it.TotProfitPct,
+				DrawDown: it.ShowDrawDownPct,
+				Sharpe:   it.SharpeRatio,
+			})
+		}
+		res[title] = arr
+	}
+	return c.JSON(fiber.Map{
+		"data": res,
+	})
+}
+
+// optSamplers 支持的超参数优化采样器
+var optSamplers = []string{"bayes", "tpe", "random", "cmaes", "ipop-cmaes", "bipop-cmaes", "nsga2"}
+
+// handleRunOptimize 添加超参数优化任务
+func handleRunOptimize(c *fiber.Ctx) error {
+	type RunOptArgs struct {
+		Configs    map[string]string `json:"configs" validate:"required"`
+		DupMode    string            `json:"dupMode"`
+		Sampler    string            `json:"sampler"`
+		Rounds     int               `json:"rounds"`
+		Picker     string            `json:"picker"`
+		Concur     int               `json:"concur"`
+		Objectives string            `json:"objectives"`
+		Pruner     string            `json:"pruner"`
+		EachPairs  bool              `json:"eachPairs"`
+	}
+
+	var args = new(RunOptArgs)
+	if err := base.VerifyArg(c, args, base.ArgBody); err != nil {
+		return err
+	}
+	if args.Sampler == "" {
+		args.Sampler = "bayes"
+	}
+	if args.Picker == "" {
+		args.Picker = opt.DefCalcOptBest
+	}
+	args.Rounds = max(1, args.Rounds)
+	args.Concur = max(1, args.Concur)
+	if !slices.Contains(optSamplers, args.Sampler) {
+		return errs.NewMsg(errs.CodeParamInvalid, "invalid sampler: %s", args.Sampler)
+	}
+	if _, ok := opt.MapCalcOptBest[args.Picker]; !ok {
+		return errs.NewMsg(errs.CodeParamInvalid, "invalid picker: %s", args.Picker)
+	}
+	args.Objectives = strings.ReplaceAll(args.Objectives, " ", "")
+	if _, err := opt.ParseObjectives(args.Objectives); err != nil {
+		return err
+	}
+	if _, err := opt.NewTrialPruner(args.Pruner); err != nil {
+		return err
+	}
+
+	content, err := mergeTaskConfigs(args.Configs)
+	if err != nil {
+		return err
+	}
+	optArgs := fmt.Sprintf("-sampler %s -opt-rounds %d -picker %s -concur %d", args.Sampler, args.Rounds,
+		args.Picker, args.Concur)
+	if args.Objectives != "" {
+		optArgs += " -objectives " + args.Objectives
+	}
+	if args.Pruner != "" {
+		optArgs += " -pruner " + args.Pruner
+	}
+	if args.EachPairs {
+		optArgs += " -each-pairs"
+	}
+	hashVal, err := addDevTask("optimize", content, args.DupMode, optArgs, func(btPath string) string {
+		return fmt.Sprintf("-out %s/%s -prg uiPrg -no-default -config %s/config.yml %s", btPath, optLogName,
+			btPath, optArgs)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"code": 200,
+		"data": hashVal,
+	})
+}
+
+// getOptTrials 获取超参数优化任务的所有轮次结果
+func getOptTrials(c *fiber.Ctx) error {
+	type TrialsArgs struct {
+		TaskID int64 `query:"taskId" validate:"required"`
+	}
+	var args = new(TrialsArgs)
+	if err := base.VerifyArg(c, args, base.ArgQuery); err != nil {
+		return err
+	}
+	btDir, err := getBtPath(args.TaskID)
+	if err != nil {
+		return err
+	}
+	items, err := loadOptTrials(btDir)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"data": items,
+	})
+}
+
+// getOptTrial 查找超参数优化任务的某轮结果，返回结果和任务目录
+func getOptTrial(taskID int64, pol, id string) (*opt.TrialItem, string, error) {
+	btDir, err := getBtPath(taskID)
+	if err != nil {
+		return nil, "", err
+	}
+	items, err := loadOptTrials(btDir)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, it := range items {
+		if it.Pol == pol && it.ID == id {
+			return it, btDir, nil
+		}
+	}
+	return nil, "", errs.NewMsg(errs.CodeParamInvalid, "trial not found: %s", id)
+}
+
+// getOptPolicy 获取某轮结果参数对应的run_policy配置片段
+func getOptPolicy(c *fiber.Ctx) error {
+	type PolicyArgs struct {
+		TaskID int64  `query:"taskId" validate:"required"`
+		Pol    string `query:"pol" validate:"required"`
+		ID     string `query:"id" validate:"required"`
+	}
+	var args = new(PolicyArgs)
+	if err := base.VerifyArg(c, args, base.ArgQuery); err != nil {
+		return err
+	}
+	trial, _, err := getOptTrial(args.TaskID, args.Pol, args.ID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"data": "run_policy:\n" + trial.ToPolicy().ToYaml(),
+	})
+}
+
+// handleOptPromote 使用某轮结果的参数，基于优化任务的配置创建新的回测任务
+func handleOptPromote(c *fiber.Ctx) error {
+	type PromoteArgs struct {
+		TaskID   int64  `json:"taskId" validate:"required"`
+		Pol      string `json:"pol" validate:"required"`
+		ID       string `json:"id" validate:"required"`
+		Separate bool   `json:"separate"`
+		DupMode  string `json:"dupMode"`
+	}
+	var args = new(PromoteArgs)
+	if err := base.VerifyArg(c, args, base.ArgBody); err != nil {
+		return err
+	}
+	trial, btDir, err := getOptTrial(args.TaskID, args.Pol, args.ID)
+	if err != nil {
+		return err
+	}
+	tmpFile, err := os.CreateTemp(os.TempDir(), "tmp_pol_*.yml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err = tmpFile.WriteString("run_policy:\n" + trial.ToPolicy().ToYaml()); err != nil {
+		return err
+	}
+	tmpFile.Close()
+	// run_policy of the trial overrides the optimize config 用此轮结果的run_policy覆盖优化任务的配置
+	content, err := MergeConfigPaths([]string{filepath.Join(btDir, "config.yml"), tmpFile.Name()})
+	if err != nil {
+		return err
+	}
+	hashVal, err := addDevTask("backtest", content, args.DupMode, "", backtestArgs(args.Separate))
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"code": 200,
+		"data": hashVal,
+	})
+}
+
 // getBtDetail 获取回测详情
 func getBtDetail(c *fiber.Ctx) error {
 	type DetailArgs struct {
@@ -1099,11 +1349,14 @@ func getBtLogs(c *fiber.Ctx) error {
 	})
 }
 
+// btHtmlTypes 回测目录中可访问的HTML报告
+var btHtmlTypes = []string{"assets", "enters", "walk_forward", "mc_balance", "mc_drawdown", "mc_recovery"}
+
 // getBtHtml 获取回测HTML报告
 func getBtHtml(c *fiber.Ctx) error {
 	type HtmlArgs struct {
 		TaskID int64  `query:"task_id" validate:"required"`
-		Type   string `query:"type" validate:"required"` // assets 或 enters
+		Type   string `query:"type" validate:"required"` // assets, enters, walk_forward 或 mc_xxx
 	}
 	var args = new(HtmlArgs)
 	if err := base.VerifyArg(c, args, base.ArgQuery); err != nil {
@@ -1115,14 +1368,10 @@ func getBtHtml(c *fiber.Ctx) error {
 		return fmt.Errorf("get backtest path failed: %v", err)
 	}
 
-	var htmlPath string
-	if args.Type == "assets" {
-		htmlPath = filepath.Join(btPath, "assets.html")
-	} else if args.Type == "enters" {
-		htmlPath = filepath.Join(btPath, "enters.html")
-	} else {
+	if !slices.Contains(btHtmlTypes, args.Type) {
 		return fmt.Errorf("invalid type: %s", args.Type)
 	}
+	htmlPath := filepath.Join(btPath, args.Type+".html")
 
 	content, err := os.ReadFile(htmlPath)
 	if err != nil {
@@ -1133,6 +1382,61 @@ func getBtHtml(c *fiber.Ctx) error {
 	return c.Send(content)
 }
 
+// getBtMonteCarlo 获取回测已有的蒙特卡洛分析结果，不存在时返回null
+func getBtMonteCarlo(c *fiber.Ctx) error {
+	type MCArgs struct {
+		TaskID int64 `query:"task_id" validate:"required"`
+	}
+	var args = new(MCArgs)
+	if err := base.VerifyArg(c, args, base.ArgQuery); err != nil {
+		return err
+	}
+	btPath, err := getBtPath(args.TaskID)
+	if err != nil {
+		return err
+	}
+	var res *opt.MonteCarloRes
+	data, err := os.ReadFile(filepath.Join(btPath, "monte_carlo.json"))
+	if err == nil {
+		res = new(opt.MonteCarloRes)
+		if err = utils2.Unmarshal(data, res, utils2.JsonNumAuto); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"data": res,
+	})
+}
+
+// handleRunMonteCarlo 对回测订单运行蒙特卡洛稳健性分析
+func handleRunMonteCarlo(c *fiber.Ctx) error {
+	type RunMCArgs struct {
+		TaskID int64 `json:"taskId" validate:"required"`
+		opt.MCArgs
+	}
+	var args = new(RunMCArgs)
+	if err := base.VerifyArg(c, args, base.ArgBody); err != nil {
+		return err
+	}
+	if args.Runs > 100000 || args.SkipRate < 0 || args.SkipRate >= 1 || args.SlipPct < 0 {
+		return errs.NewMsg(errs.CodeParamInvalid, "invalid monte carlo args")
+	}
+	btPath, err := getBtPath(args.TaskID)
+	if err != nil {
+		return err
+	}
+	res, err2 := opt.RunMonteCarloDir(btPath, &args.MCArgs)
+	if err2 != nil {
+		return err2
+	}
+	return c.JSON(fiber.Map{
+		"code": 200,
+		"data": res,
+	})
+}
+
 // getBtStratTree 获取回测策略代码文件树
 func getBtStratTree(c *fiber.Ctx) error {
 	type TreeArgs struct {
@@ -1326,9 +1630,9 @@ func getSymbolInfo(c *fiber.Ctx) error {
 	}
 	defer conn.Release()
 
-	kinfos, err_ := sess.FindKInfos(context.Background(), args.ID)
-	if err_ != nil {
-		return err_
+	kinfos, err := sess.GetKInfos(args.ID)
+	if err != nil {
+		return err
 	}
 
 	// 获取复权因子
@@ -13,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/banbox/banbot/opt"
 	"github.com/banbox/banbot/orm/ormo"
 
 	utils2 "github.com/banbox/banbot/utils"
@@ -28,6 +29,9 @@ import (
 	"github.com/banbox/banexg/utils"
 )
 
+// optLogName 超参数优化任务在输出目录中的日志文件名，研究名也由此生成
+const optLogName = "optimize.log"
+
 type CmdArgs struct {
 	Port     int
 	Host     string
@@ -41,9 +45,9 @@ type CmdArgs struct {
 
 var (
 	btInfoKeyList = []string{"maxOpenOrders", "showDrawDownPct", "barNum", "maxDrawDownVal", "showDrawDownVal", "totalInvest",
-		"totProfit", "totCost", "totFee", "totProfitPct", "sortinoRatio"}
+		"totProfit", "totCost", "totFee", "totFundFee", "totSlipCost", "totProfitPct", "sortinoRatio"}
 	btInfoKeys      = make(map[string]bool)
-	maxBtTasks      = 3 // 最大并发回测任务数
+	maxBtTasks      = 3 // 最大并发回测/超参数优化任务数
 	runBtTasks      = make(map[int64]*exec.Cmd)
 	runBtTasksMutex sync.Mutex
 
@@ -74,7 +78,7 @@ func getGobOrders(path string) ([]*ormo.InOutOrder, *sync.Mutex, *errs.Error) {
 	return cacheOrders, &ordersLock, nil
 }
 
-// 执行单个回测任务
+// 执行单个回测或超参数优化任务
 func executeBtTask(task *ormu.Task) {
 	defer func() {
 		runBtTasksMutex.Lock()
@@ -90,7 +94,7 @@ func executeBtTask(task *ormu.Task) {
 	}
 
 	// 构建命令
-	cmdArgsStr := "backtest " + task.Args
+	cmdArgsStr := task.Mode + " " + task.Args
 	cmd := exec.Command(exePath, strings.Split(cmdArgsStr, " ")...)
 
 	// 添加到运行列表
@@ -144,9 +148,9 @@ func runBtCommand(cmd *exec.Cmd, task *ormu.Task) error {
 	}
 	defer stdErr.Close()
 
-	log.Info("start backtest", zap.Int64("id", task.ID), zap.String("args", task.Args))
+	log.Info("start "+task.Mode, zap.Int64("id", task.ID), zap.String("args", task.Args))
 	if err := cmd.Start(); err != nil {
-		log.Error("start backtest fail", zap.Error(err))
+		log.Error("start "+task.Mode+" fail", zap.Error(err))
 		return err
 	}
 
@@ -166,6 +170,8 @@ func runBtCommand(cmd *exec.Cmd, task *ormu.Task) error {
 				if err := handleProgress(line[len(prefix):], task.ID); err != nil {
 					log.Error("handle progress failed", zap.Error(err))
 				}
+			} else if strings.HasPrefix(line, opt.OptTrialPrefix) {
+				handleOptTrial(line[len(opt.OptTrialPrefix):], task.ID)
 			} else {
 				b.WriteString(line)
 				b.WriteString("\n")
@@ -200,10 +206,10 @@ func runBtCommand(cmd *exec.Cmd, task *ormu.Task) error {
 		"progress": 1,
 	})
 	if err != nil {
-		log.Error("run backtest failed", zap.Int64("task", task.ID), zap.String("args", task.Args),
+		log.Error("run "+task.Mode+" failed", zap.Int64("task", task.ID), zap.String("args", task.Args),
 			zap.String("path", task.Path), zap.String("output", b.String()), zap.Error(err))
 	} else {
-		log.Info("done backtest", zap.Int64("id", task.ID), zap.String("args", task.Args))
+		log.Info("done "+task.Mode, zap.Int64("id", task.ID), zap.String("args", task.Args))
 	}
 
 	return err
@@ -236,6 +242,21 @@ func handleProgress(progressStr string, taskID int64) error {
 	return updateTaskStatus(qu, taskID, int64(ormu.BtStatusRunning), prgVal)
 }
 
+// 广播超参数优化任务的一轮结果
+func handleOptTrial(trialStr string, taskID int64) {
+	var trial = make(map[string]interface{})
+	err := utils.UnmarshalString(trialStr, &trial, utils.JsonNumAuto)
+	if err != nil {
+		log.Warn("invalid opt trial", zap.String("trial", trialStr))
+		return
+	}
+	BroadcastWS("", map[string]interface{}{
+		"type":   "optTrial",
+		"taskId": taskID,
+		"trial":  trial,
+	})
+}
+
 // 更新回测任务结果
 func updateBtTaskResult(task *ormu.Task, errTask error) {
 	qu, conn, err2 := ormu.Conn()
@@ -245,7 +266,13 @@ func updateBtTaskResult(task *ormu.Task, errTask error) {
 	}
 	defer conn.Close()
 	btRoot := fmt.Sprintf("%s/backtest", config.GetDataDir())
-	taskRes, err := collectBtTask(btRoot, task.Path)
+	var taskRes *ormu.Task
+	var err error
+	if task.Mode == "optimize" {
+		taskRes, err = collectOptTask(filepath.Join(btRoot, task.Path))
+	} else {
+		taskRes, err = collectBtTask(btRoot, task.Path)
+	}
 	if err != nil {
 		var errMsg string
 		if errTask != nil {
@@ -310,8 +337,8 @@ func startBtTaskScheduler() {
 				continue
 			}
 
+			// 回测和超参数优化任务共享并发数
 			tasks, err := qu.FindTasks(context.Background(), ormu.FindTasksParams{
-				Mode:   "backtest",
 				Status: int64(ormu.BtStatusInit),
 				Limit:  1,
 			})
@@ -337,7 +364,7 @@ func startBtTaskScheduler() {
 				continue
 			}
 
-			// 启动新的回测任务
+			// 启动新的任务
 			go executeBtTask(task)
 		}
 	}()
@@ -461,7 +488,7 @@ func collectBtTask(rootDir, relPath string) (*ormu.Task, error) {
 	d["leverage"] = cfg.Leverage
 	walletTot := float64(0)
 	for code, amt := range cfg.WalletAmounts {
-		walletTot += core.GetPriceSafe(code) * amt
+		walletTot += core.DefCtx.GetPriceSafe(code) * amt
 	}
 	d["walletAmount"] = walletTot
 	d["stakeAmount"] = cfg.StakeAmount
@@ -494,6 +521,68 @@ func collectBtTask(rootDir, relPath string) (*ormu.Task, error) {
 	}, nil
 }
 
+// loadOptTrials 读取超参数优化任务目录中的所有轮次结果
+func loadOptTrials(btDir string) ([]*opt.TrialItem, error) {
+	dbPath := opt.GetStudyDbPath(filepath.Join(btDir, optLogName))
+	if !utils2.Exists(dbPath) {
+		return nil, nil
+	}
+	store, err := opt.OpenStudyStore(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	items, err := store.ListTrials(opt.DefStudyName(optLogName))
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+/*
+collectOptTask
+统计超参数优化任务的结果，使用分数最高的已完成轮次作为任务的指标
+*/
+func collectOptTask(btDir string) (*ormu.Task, error) {
+	items, err := loadOptTrials(btDir)
+	if err != nil {
+		return nil, err
+	}
+	var best *opt.TrialItem
+	for _, it := range items {
+		if it.Pruned > 0 || it.Result == nil {
+			continue
+		}
+		if best == nil || it.Score > best.Score {
+			best = it
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	infoText, err := utils.MarshalString(map[string]interface{}{
+		"trialNum":        len(items),
+		"bestId":          best.ID,
+		"bestPol":         best.Pol,
+		"bestScore":       best.Score,
+		"showDrawDownPct": best.Result.ShowDrawDownPct,
+		"sortinoRatio":    best.Result.SortinoRatio,
+		"totFee":          best.Result.TotFee,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ormu.Task{
+		Status:      ormu.BtStatusDone,
+		OrderNum:    int64(best.Result.OrderNum),
+		ProfitRate:  best.Result.TotProfitPct,
+		WinRate:     best.Result.WinRatePct,
+		MaxDrawdown: best.Result.MaxDrawDownPct,
+		Sharpe:      best.Result.SharpeRatio,
+		Info:        infoText,
+	}, nil
+}
+
 func MergeConfig(inText string, skips ...string) (string, error) {
 	dataDir := config.GetDataDir()
 	if dataDir == "" {
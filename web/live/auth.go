@@ -49,7 +49,7 @@ func postLogin(c *fiber.Ctx) error {
 		// 只返回正在运行的交易账户
 		var accRoles = make(map[string]string)
 		for acc, role := range u.AccRoles {
-			task := ormo.GetTask(acc)
+			task := ormo.DefCtx.GetTask(acc)
 			if task != nil {
 				accRoles[acc] = role
 			}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/banbox/banbot/opt"
 	"github.com/banbox/banexg/binance"
+	"maps"
 	"math"
 	"slices"
 	"sort"
@@ -14,6 +15,7 @@ import (
 	"time"
 
 	"github.com/banbox/banbot/orm/ormo"
+	"github.com/banbox/banexg/errs"
 	"github.com/banbox/banexg/log"
 	"go.uber.org/zap"
 
@@ -47,6 +49,9 @@ func regApiBiz(api fiber.Router) {
 	api.Post("/forceexit", postForceExit)
 	api.Post("/close_pos", postClosePos)
 	api.Post("/delay_entry", postDelayEntry)
+	api.Post("/open_order", postOpenOrder)
+	api.Post("/edit_order", postEditOrder)
+	api.Post("/reload_config", postReloadConfig)
 	api.Get("/config", getConfig)
 	api.Get("/stg_jobs", getStratJobs)
 	api.Get("/performance", getPerformance)
@@ -81,6 +86,19 @@ func wrapAccDb(c *fiber.Ctx, cb FnAccDbCB) error {
 	})
 }
 
+// RoleAdmin role in acc_roles which can open or edit orders manually 可手动开单或修改订单的acc_roles角色
+const RoleAdmin = "admin"
+
+func wrapAccAdmin(c *fiber.Ctx, cb FnAccCB) error {
+	return wrapAccount(c, func(acc string) error {
+		roles, _ := c.Locals("accounts").(map[string]string)
+		if role, _ := roles[acc]; role != RoleAdmin {
+			return fiber.NewError(fiber.StatusForbidden, "admin role required for account "+acc)
+		}
+		return cb(acc)
+	})
+}
+
 func getVersion(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"version": core.Version,
@@ -89,51 +107,69 @@ func getVersion(c *fiber.Ctx) error {
 
 func getBalance(c *fiber.Ctx) error {
 	return wrapAccount(c, func(account string) error {
-		wallet := biz.GetWallets(account)
+		wallets := biz.DefCtx.GetAccWallets(account)
 		return c.JSON(fiber.Map{
-			"items": walletItems(wallet),
-			"total": wallet.FiatValue(true),
+			"items": walletItems(wallets...),
+			"total": walletsFiat(wallets),
 		})
 	})
 }
 
-func walletItems(wallet *biz.BanWallets) []map[string]interface{} {
+/*
+walletItems
+Coins of wallets on exchanges/markets other than default are suffixed like pair keys: USDT@binance.spot
+非默认交易所/市场钱包的币种和品种键一样添加后缀：USDT@binance.spot
+*/
+func walletItems(wallets ...*biz.BanWallets) []map[string]interface{} {
 	items := make([]map[string]interface{}, 0)
-	for coin, item := range wallet.Items {
-		total := item.Total(true)
-		items = append(items, map[string]interface{}{
-			"symbol":     coin,
-			"total":      total,
-			"upol":       item.UnrealizedPOL,
-			"free":       item.Available,
-			"used":       item.Used(),
-			"total_fiat": total * core.GetPrice(coin),
-		})
+	for _, wallet := range wallets {
+		for coin, item := range wallet.Items {
+			total := item.Total(true)
+			items = append(items, map[string]interface{}{
+				"symbol":     core.PairKey(wallet.Exchange, wallet.Market, coin),
+				"total":      total,
+				"upol":       item.UnrealizedPOL,
+				"free":       item.Available,
+				"used":       item.Used(),
+				"total_fiat": total * core.DefCtx.GetPrice(coin),
+			})
+		}
 	}
 	return items
 }
 
+func walletsFiat(wallets []*biz.BanWallets) float64 {
+	var total float64
+	for _, wallet := range wallets {
+		total += wallet.FiatValue(true)
+	}
+	return total
+}
+
 func postRefreshWallet(c *fiber.Ctx) error {
 	return wrapAccount(c, func(account string) error {
-		wallet := biz.GetWallets(account)
-		rsp, err := exg.Default.FetchBalance(map[string]interface{}{
-			banexg.ParamAccount: account,
-		})
-		if err != nil {
-			return err
+		wallets := biz.DefCtx.GetAccWallets(account)
+		for _, wallet := range wallets {
+			rsp, err := wallet.VenueExg().FetchBalance(map[string]interface{}{
+				banexg.ParamAccount: account,
+			})
+			if err != nil {
+				return err
+			}
+			log.Info("RefreshWallet", zap.String("acc", account), zap.String("market", wallet.VenueMarket()),
+				zap.Any("rsp", rsp))
+			biz.UpdateWalletByBalances(wallet, rsp)
 		}
-		log.Info("RefreshWallet", zap.String("acc", account), zap.Any("rsp", rsp))
-		biz.UpdateWalletByBalances(wallet, rsp)
 		return c.JSON(fiber.Map{
-			"items": walletItems(wallet),
-			"total": wallet.FiatValue(true),
+			"items": walletItems(wallets...),
+			"total": walletsFiat(wallets),
 		})
 	})
 }
 
 func getTodayNum(c *fiber.Ctx) error {
 	return wrapAccount(c, func(acc string) error {
-		openOds, lock := ormo.GetOpenODs(acc)
+		openOds, lock := ormo.DefCtx.GetOpenODs(acc)
 		lock.Lock()
 		dayOpenNum := len(openOds)
 		dayOpenPft := float64(0)
@@ -146,7 +182,7 @@ func getTodayNum(c *fiber.Ctx) error {
 		tfMSecs := int64(utils2.TFToSecs("1d") * 1000)
 		nowMS := btime.UTCStamp()
 		todayStartMS := utils2.AlignTfMSecs(nowMS, tfMSecs)
-		taskId := ormo.GetTaskID(acc)
+		taskId := ormo.DefCtx.GetTaskID(acc)
 		dayDoneNum := 0
 		dayDonePft := float64(0)
 		if taskId > 0 {
@@ -186,14 +222,13 @@ func getStatistics(c *fiber.Ctx) error {
 			return err
 		}
 		defer conn.Close()
-		taskId := ormo.GetTaskID(acc)
+		taskId := ormo.DefCtx.GetTaskID(acc)
 		orders, err := sess.GetOrders(ormo.GetOrdersArgs{
 			TaskID: taskId,
 		})
 		if err != nil {
 			return err
 		}
-		wallets := biz.GetWallets(acc)
 		var totalDuration int64 // All order holding seconds 所有订单持仓秒数
 		var profitSum, profitRateSum, totalCost float64
 		var doneProfitSum, doneProfitRateSum, doneTotalCost float64
@@ -260,7 +295,7 @@ func getStatistics(c *fiber.Ctx) error {
 		}
 
 		expProfit, expRatio := utils.CalcExpectancy(dayProfits)
-		initBalance := wallets.TotalLegal(nil, true) - profitSum
+		initBalance := biz.DefCtx.AccTotalLegal(acc, true) - profitSum
 		ddPct, ddVal, _, _, _, _ := utils.CalcMaxDrawDown(dayProfits, initBalance)
 		return c.JSON(fiber.Map{
 			"doneProfitMean":    doneProfitMean,
@@ -288,10 +323,10 @@ func getStatistics(c *fiber.Ctx) error {
 			"maxDrawdownVal":    ddVal,
 			"totalCost":         totalCost,
 			"botStartMs":        core.StartAt,
-			"runTfs":            utils.KeysOfMap(core.TFSecs),
+			"runTfs":            utils.KeysOfMap(core.DefCtx.TFSecs),
 			"exchange":          core.ExgName,
 			"market":            core.Market,
-			"pairs":             core.Pairs,
+			"pairs":             core.DefCtx.Pairs,
 		})
 	})
 }
@@ -324,7 +359,7 @@ func getOrders(c *fiber.Ctx) error {
 			return err
 		}
 		defer conn.Close()
-		taskId := ormo.GetTaskID(acc)
+		taskId := ormo.DefCtx.GetTaskID(acc)
 		var symbols []string
 		if data.Symbols != "" {
 			symbols = strings.Split(data.Symbols, ",")
@@ -357,7 +392,7 @@ func getOrders(c *fiber.Ctx) error {
 			if od.ExitTag != "" && od.Exit != nil && od.Exit.Price > 0 {
 				price = od.Exit.Price
 			} else {
-				price = core.GetPriceSafe(od.Symbol)
+				price = core.DefCtx.GetPriceSafe(od.Symbol)
 				if price > 0 {
 					od.UpdateProfits(price)
 				}
@@ -419,7 +454,7 @@ func getOrders(c *fiber.Ctx) error {
 
 func postCalcProfits(c *fiber.Ctx) error {
 	return wrapAccount(c, func(acc string) error {
-		openOds, lock := ormo.GetOpenODs(acc)
+		openOds, lock := ormo.DefCtx.GetOpenODs(acc)
 		if len(openOds) == 0 {
 			return nil
 		}
@@ -436,7 +471,7 @@ func postCalcProfits(c *fiber.Ctx) error {
 		for _, it := range items {
 			prices[it.Symbol] = it.Price
 		}
-		core.SetPrices(prices)
+		core.DefCtx.SetPrices(prices)
 		fails := make(map[string]bool)
 		for _, od := range openOds {
 			if price, ok := prices[od.Symbol]; ok {
@@ -463,62 +498,74 @@ func postForceExit(c *fiber.Ctx) error {
 	}
 
 	return wrapAccount(c, func(acc string) error {
-		openOds, lock := ormo.GetOpenODs(acc)
-		lock.Lock()
-
-		var targetOrders []*ormo.InOutOrder
-		if data.OrderID == "all" {
-			targetOrders = utils2.ValsOfMap(openOds)
-		} else {
-			orderID, err := strconv.ParseInt(data.OrderID, 10, 64)
-			if err != nil {
-				lock.Unlock()
-				return fiber.NewError(fiber.StatusBadRequest, "invalid order id")
-			}
-			for _, od := range openOds {
-				if od.ID == orderID {
-					targetOrders = append(targetOrders, od)
-					break
-				}
-			}
-			if len(targetOrders) == 0 {
-				lock.Unlock()
-				return fiber.NewError(fiber.StatusNotFound, "order not found")
-			}
-		}
-		lock.Unlock()
-
-		sess, conn, err := ormo.Conn(orm.DbTrades, true)
+		closeNum, failNum, errMsg, err := forceExitOrders(acc, data.OrderID)
 		if err != nil {
 			return err
 		}
-		defer conn.Close()
-		odMgr := biz.GetLiveOdMgr(acc)
-		closeNum, failNum := 0, 0
-		var errMsg strings.Builder
-		for _, od := range targetOrders {
-			_, err2 := odMgr.ExitOrder(sess, od, &strat.ExitReq{
-				Tag:       core.ExitTagUserExit,
-				StratName: od.Strategy,
-				OrderID:   od.ID,
-				Force:     true,
-			})
-			if err2 != nil {
-				failNum += 1
-				errMsg.WriteString(fmt.Sprintf("Order %v: %v\n", od.ID, err2.Short()))
-			} else {
-				closeNum += 1
-			}
-		}
-
 		return c.JSON(fiber.Map{
 			"closeNum": closeNum,
 			"failNum":  failNum,
-			"errMsg":   errMsg.String(),
+			"errMsg":   errMsg,
 		})
 	})
 }
 
+/*
+forceExitOrders
+Force exit the open order of orderID, or all open orders if orderID is "all"
+强制平仓orderID对应的未平仓订单，orderID为"all"时平仓所有订单
+*/
+func forceExitOrders(acc, orderID string) (int, int, string, error) {
+	openOds, lock := ormo.DefCtx.GetOpenODs(acc)
+	lock.Lock()
+
+	var targetOrders []*ormo.InOutOrder
+	if orderID == "all" {
+		targetOrders = utils2.ValsOfMap(openOds)
+	} else {
+		odID, err := strconv.ParseInt(orderID, 10, 64)
+		if err != nil {
+			lock.Unlock()
+			return 0, 0, "", fiber.NewError(fiber.StatusBadRequest, "invalid order id")
+		}
+		for _, od := range openOds {
+			if od.ID == odID {
+				targetOrders = append(targetOrders, od)
+				break
+			}
+		}
+		if len(targetOrders) == 0 {
+			lock.Unlock()
+			return 0, 0, "", fiber.NewError(fiber.StatusNotFound, "order not found")
+		}
+	}
+	lock.Unlock()
+
+	sess, conn, err := ormo.Conn(orm.DbTrades, true)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	defer conn.Close()
+	odMgr := biz.DefCtx.GetOdMgr(acc)
+	closeNum, failNum := 0, 0
+	var errMsg strings.Builder
+	for _, od := range targetOrders {
+		_, err2 := odMgr.ExitOrder(sess, od, &strat.ExitReq{
+			Tag:       core.ExitTagUserExit,
+			StratName: od.Strategy,
+			OrderID:   od.ID,
+			Force:     true,
+		})
+		if err2 != nil {
+			failNum += 1
+			errMsg.WriteString(fmt.Sprintf("Order %v: %v\n", od.ID, err2.Short()))
+		} else {
+			closeNum += 1
+		}
+	}
+	return closeNum, failNum, errMsg.String(), nil
+}
+
 func postClosePos(c *fiber.Ctx) error {
 	type CloseArgs struct {
 		Symbol    string  `json:"symbol" validate:"required"`
@@ -614,16 +661,281 @@ func postDelayEntry(c *fiber.Ctx) error {
 		return err
 	}
 	return wrapAccount(c, func(acc string) error {
-		untilMS := btime.UTCStamp() + data.Secs*1000
-		core.NoEnterUntil[acc] = untilMS
 		return c.JSON(fiber.Map{
-			"allowTradeAt": untilMS,
+			"allowTradeAt": delayEntry(acc, data.Secs),
 		})
 	})
 }
 
+// delayEntry forbid entering for secs, allow immediately if secs <= 0 禁止开单secs秒，secs<=0时立即允许
+func delayEntry(acc string, secs int64) int64 {
+	untilMS := btime.UTCStamp() + max(secs, 0)*1000
+	core.DefCtx.NoEnterUntil[acc] = untilMS
+	return untilMS
+}
+
+/*
+postOpenOrder
+Open an order manually. The order belongs to a running strategy job of the pair, and is validated by
+StratJob.CheckEnter and the same checks of IOrderMgr.EnterOrder as strategy signals.
+Cost or amount is required, and it's rejected while entries of the account are disabled.
+手动开单。订单属于该标的的一个运行中的策略任务，与策略信号一样经过StratJob.CheckEnter和IOrderMgr.EnterOrder的检查。
+cost或amount必填，账户禁止开单期间拒绝。
+*/
+func postOpenOrder(c *fiber.Ctx) error {
+	type OpenArgs struct {
+		Pair       string  `json:"pair" validate:"required"`
+		Side       string  `json:"side" validate:"required,oneof=long short"`
+		Cost       float64 `json:"cost" validate:"gte=0"`
+		Amount     float64 `json:"amount" validate:"gte=0"`
+		Leverage   float64 `json:"leverage" validate:"gte=0"`
+		Limit      float64 `json:"limit" validate:"gte=0"`
+		StopLoss   float64 `json:"stopLoss" validate:"gte=0"`
+		TakeProfit float64 `json:"takeProfit" validate:"gte=0"`
+		Tag        string  `json:"tag"`
+		Strategy   string  `json:"strategy"`
+	}
+	var data = new(OpenArgs)
+	if err := base.VerifyArg(c, data, base.ArgBody); err != nil {
+		return err
+	}
+	if data.Cost == 0 && data.Amount == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "cost or amount is required")
+	}
+	if data.Tag == "" {
+		data.Tag = core.EnterTagUserOpen
+	}
+	return wrapAccAdmin(c, func(acc string) error {
+		if stopUntil, _ := core.DefCtx.NoEnterUntil[acc]; btime.UTCStamp() < stopUntil {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("entry disabled for %s until %v", acc, stopUntil))
+		}
+		job := findPairJob(acc, data.Pair, "", data.Strategy)
+		if job == nil {
+			return fiber.NewError(fiber.StatusBadRequest, "no running strategy job for "+data.Pair)
+		}
+		req := &strat.EnterReq{
+			Tag:        data.Tag,
+			StratName:  job.Strat.Name,
+			Short:      data.Side == "short",
+			Limit:      data.Limit,
+			LegalCost:  data.Cost,
+			Amount:     data.Amount,
+			Leverage:   data.Leverage,
+			StopLoss:   data.StopLoss,
+			TakeProfit: data.TakeProfit,
+			UserOpen:   true,
+		}
+		if err := job.CheckEnter(req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Short())
+		}
+		sess, conn, err := ormo.Conn(orm.DbTrades, true)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		oldFails := strat.DefCtx.GetAccFailOpens()[acc]
+		od, err := biz.DefCtx.GetOdMgr(acc).EnterOrder(sess, job.Env, req, true)
+		if err != nil {
+			return err
+		}
+		if od == nil {
+			reasons := newFailOpens(oldFails, strat.DefCtx.GetAccFailOpens()[acc])
+			return fiber.NewError(fiber.StatusBadRequest, "enter rejected: "+reasons)
+		}
+		return c.JSON(fiber.Map{"order": od})
+	})
+}
+
+/*
+newFailOpens
+Return the failed entry reasons whose count increased from old to cur, joined by comma
+返回从old到cur次数增加的开单失败原因，逗号连接
+*/
+func newFailOpens(old, cur map[string]int) string {
+	var tags []string
+	for tag, num := range cur {
+		if num > old[tag] {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return "unknown"
+	}
+	sort.Strings(tags)
+	return strings.Join(tags, ", ")
+}
+
+/*
+postEditOrder
+Update the stop loss, trailing stop, take profit of an open order, or the entry limit price of an unfilled order.
+Nil fields are unchanged, 0 removes the stop loss or take profit.
+修改未平仓订单的止损、跟踪止损、止盈，或未成交订单的入场限价。nil字段不修改，0表示删除止损或止盈。
+*/
+func postEditOrder(c *fiber.Ctx) error {
+	type EditArgs struct {
+		OrderID         int64    `json:"orderId" validate:"required"`
+		Limit           *float64 `json:"limit" validate:"omitempty,gt=0"`
+		StopLoss        *float64 `json:"stopLoss" validate:"omitempty,gte=0"`
+		StopLossLimit   *float64 `json:"stopLossLimit" validate:"omitempty,gte=0"`
+		TrailActivate   *float64 `json:"trailActivate" validate:"omitempty,gte=0"`
+		TrailCallback   *float64 `json:"trailCallback" validate:"omitempty,gte=0,lt=1"`
+		TrailDistance   *float64 `json:"trailDistance" validate:"omitempty,gte=0"`
+		TakeProfit      *float64 `json:"takeProfit" validate:"omitempty,gte=0"`
+		TakeProfitLimit *float64 `json:"takeProfitLimit" validate:"omitempty,gte=0"`
+	}
+	var data = new(EditArgs)
+	if err := base.VerifyArg(c, data, base.ArgBody); err != nil {
+		return err
+	}
+	return wrapAccAdmin(c, func(acc string) error {
+		openOds, lock := ormo.DefCtx.GetOpenODs(acc)
+		lock.Lock()
+		od, _ := openOds[data.OrderID]
+		lock.Unlock()
+		if od == nil {
+			return fiber.NewError(fiber.StatusNotFound, "order not found")
+		}
+		job := findPairJob(acc, od.Symbol, od.Timeframe, od.Strategy)
+		price := core.DefCtx.GetPrice(od.Symbol)
+		dirFlag := 1.0
+		if od.Short {
+			dirFlag = -1.0
+		}
+		var edits []*ormo.InOutEdit
+		entered := od.CanClose() && od.Status >= ormo.InOutStatusFullEnter
+		odLock := od.Lock()
+		if data.Limit != nil {
+			if err := od.SetEnterLimit(*data.Limit); err != nil {
+				odLock.Unlock()
+				return fiber.NewError(fiber.StatusBadRequest, err.Short())
+			}
+			edits = append(edits, &ormo.InOutEdit{Order: od, Action: ormo.OdActionLimitEnter})
+		}
+		if data.StopLoss != nil || data.StopLossLimit != nil || data.TrailActivate != nil ||
+			data.TrailCallback != nil || data.TrailDistance != nil {
+			var trig = &ormo.ExitTrigger{}
+			if old := od.GetStopLoss(); old != nil && old.ExitTrigger != nil {
+				trig = old.ExitTrigger.Clone()
+			}
+			setFloat(&trig.Price, data.StopLoss)
+			setFloat(&trig.Limit, data.StopLossLimit)
+			setFloat(&trig.Activate, data.TrailActivate)
+			setFloat(&trig.Callback, data.TrailCallback)
+			setFloat(&trig.Distance, data.TrailDistance)
+			if trig.Price > 0 || trig.IsTrailing() {
+				if job != nil && !job.ExgStopLoss {
+					odLock.Unlock()
+					return fiber.NewError(fiber.StatusBadRequest, "stopLoss is disabled for "+od.Strategy)
+				}
+				if trig.Price > 0 && (trig.Price-price)*dirFlag >= 0 {
+					odLock.Unlock()
+					return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid stopLoss %v for price %v", trig.Price, price))
+				}
+			}
+			od.SetStopLoss(trig)
+			if entered {
+				edits = append(edits, &ormo.InOutEdit{Order: od, Action: ormo.OdActionStopLoss})
+			}
+		}
+		if data.TakeProfit != nil || data.TakeProfitLimit != nil {
+			var trig = &ormo.ExitTrigger{}
+			if old := od.GetTakeProfit(); old != nil && old.ExitTrigger != nil {
+				trig = old.ExitTrigger.Clone()
+			}
+			setFloat(&trig.Price, data.TakeProfit)
+			setFloat(&trig.Limit, data.TakeProfitLimit)
+			if trig.Price > 0 {
+				if job != nil && !job.ExgTakeProfit {
+					odLock.Unlock()
+					return fiber.NewError(fiber.StatusBadRequest, "takeProfit is disabled for "+od.Strategy)
+				}
+				if (trig.Price-price)*dirFlag <= 0 {
+					odLock.Unlock()
+					return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid takeProfit %v for price %v", trig.Price, price))
+				}
+			}
+			od.SetTakeProfit(trig)
+			if entered {
+				edits = append(edits, &ormo.InOutEdit{Order: od, Action: ormo.OdActionTakeProfit})
+			}
+		}
+		sess, conn, err := ormo.Conn(orm.DbTrades, true)
+		if err != nil {
+			odLock.Unlock()
+			return err
+		}
+		defer conn.Close()
+		err = od.Save(sess)
+		odLock.Unlock()
+		if err != nil {
+			return err
+		}
+		if len(edits) > 0 {
+			env, _ := strat.DefCtx.Envs[od.Symbol+"_"+od.Timeframe]
+			_, _, err = biz.DefCtx.GetOdMgr(acc).ProcessOrders(sess, env, nil, nil, edits)
+			if err != nil {
+				return err
+			}
+		}
+		return c.JSON(fiber.Map{"order": od})
+	})
+}
+
+func setFloat(dst *float64, val *float64) {
+	if val != nil {
+		*dst = *val
+	}
+}
+
+/*
+findPairJob
+Find the running strategy job of pair for account, timeFrame and stratName are optional
+查找账户中标的的运行中策略任务，timeFrame和stratName可选
+*/
+func findPairJob(acc, pair, timeFrame, stratName string) *strat.StratJob {
+	jobs := strat.DefCtx.GetJobs(acc)
+	keys := utils2.KeysOfMap(jobs)
+	sort.Strings(keys)
+	for _, pairTF := range keys {
+		p, tf, _ := strings.Cut(pairTF, "_")
+		if p != pair || timeFrame != "" && tf != timeFrame {
+			continue
+		}
+		jobMap := jobs[pairTF]
+		names := utils2.KeysOfMap(jobMap)
+		sort.Strings(names)
+		for _, name := range names {
+			if stratName == "" || name == stratName {
+				return jobMap[name]
+			}
+		}
+	}
+	return nil
+}
+
+// ReloadConfig is set by the live trader 由实盘交易器设置
+var ReloadConfig func() (*config.ReloadResult, *errs.Error)
+
+/*
+postReloadConfig
+Apply changed run_policy, pairs, pairlists and stake settings without restart, return the changed keys
+无需重启应用变化的run_policy、pairs、pairlists和开单金额设置，返回变化的键
+*/
+func postReloadConfig(c *fiber.Ctx) error {
+	return wrapAccAdmin(c, func(acc string) error {
+		if ReloadConfig == nil {
+			return fiber.NewError(fiber.StatusBadRequest, "reload is not supported")
+		}
+		res, err := ReloadConfig()
+		if err != nil {
+			return err
+		}
+		return c.JSON(res)
+	})
+}
+
 func getConfig(c *fiber.Ctx) error {
-	// 因在线更新配置有很多限制，大多数配置无法即刻生效，故暂不提供在线修改
+	// 因在线更新配置有很多限制，大多数配置无法即刻生效，故暂不提供在线修改；可修改配置文件后通过/reload_config重载部分配置
 	data, err := config.DumpYaml(true)
 	if err != nil {
 		return err
@@ -640,14 +952,14 @@ func getStratJobs(c *fiber.Ctx) error {
 		OdNum    int     `json:"odNum"`
 	}
 	return wrapAccount(c, func(acc string) error {
-		jobs := strat.GetJobs(acc)
+		jobs := strat.DefCtx.GetJobs(acc)
 		items := make([]*JobItem, 0, len(jobs))
-		openOds, lock := ormo.GetOpenODs(acc)
+		openOds, lock := ormo.DefCtx.GetOpenODs(acc)
 		lock.Lock()
 		defer lock.Unlock()
 		for pairTF, jobMap := range jobs {
 			arr := strings.Split(pairTF, "_")
-			price := core.GetPriceSafe(arr[0])
+			price := core.DefCtx.GetPriceSafe(arr[0])
 			for stgName := range jobMap {
 				var odNum = 0
 				for _, od := range openOds {
@@ -667,7 +979,7 @@ func getStratJobs(c *fiber.Ctx) error {
 		}
 		return c.JSON(fiber.Map{
 			"jobs":   items,
-			"strats": strat.Versions,
+			"strats": strat.DefCtx.Versions,
 		})
 	})
 }
@@ -688,7 +1000,7 @@ func getTaskPairs(c *fiber.Ctx) error {
 		}
 		defer conn.Close()
 		ctx := context.Background()
-		taskId := ormo.GetTaskID(acc)
+		taskId := ormo.DefCtx.GetTaskID(acc)
 		if data.Stop == 0 {
 			data.Stop = math.MaxInt64
 		}
@@ -706,6 +1018,11 @@ func getTaskPairs(c *fiber.Ctx) error {
 
 func getExsMap(c *fiber.Ctx) error {
 	exsMap := orm.GetExSymbols(core.ExgName, core.Market)
+	// orders may be traded on other exchanges/markets of run_policy 订单可能在run_policy的其他交易所/市场
+	for _, client := range exg.AllExgs()[1:] {
+		info := client.Info()
+		maps.Copy(exsMap, orm.GetExSymbols(info.ID, info.MarketType))
+	}
 	return c.JSON(fiber.Map{
 		"data": exsMap,
 	})
@@ -740,7 +1057,7 @@ func getPerformance(c *fiber.Ctx) error {
 			return err
 		}
 		defer conn.Close()
-		taskId := ormo.GetTaskID(acc)
+		taskId := ormo.DefCtx.GetTaskID(acc)
 		orders, err := sess.GetOrders(ormo.GetOrdersArgs{
 			TaskID:      taskId,
 			Pairs:       data.Pairs,
@@ -868,7 +1185,7 @@ func getGroupSta(c *fiber.Ctx) error {
 			return err
 		}
 		defer conn.Close()
-		taskId := ormo.GetTaskID(acc)
+		taskId := ormo.DefCtx.GetTaskID(acc)
 		var symbols []string
 		if data.Symbol != "" {
 			symbols = strings.Split(data.Symbol, ",")
@@ -1014,7 +1331,7 @@ func getBotInfo(c *fiber.Ctx) error {
 		return err
 	}
 	return wrapAccount(c, func(acc string) error {
-		stopUntil, _ := core.NoEnterUntil[acc]
+		stopUntil, _ := core.DefCtx.NoEnterUntil[acc]
 		return c.JSON(fiber.Map{
 			"cpuPct":       percent[0],
 			"ramPct":       v.UsedPercent,
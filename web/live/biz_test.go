@@ -0,0 +1,150 @@
+package live
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/orm"
+	"github.com/banbox/banbot/orm/ormo"
+	"github.com/banbox/banbot/strat"
+	"github.com/banbox/banbot/web/base"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	testAcc    = "user1"
+	testViewer = "viewer"
+)
+
+func newOrderApp() *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: base.ErrHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("accounts", map[string]string{testAcc: RoleAdmin, testViewer: "view"})
+		return c.Next()
+	})
+	app.Post("/open_order", postOpenOrder)
+	app.Post("/edit_order", postEditOrder)
+	return app
+}
+
+func TestOrderApiArgs(t *testing.T) {
+	oldJobs, oldNoEnter := strat.DefCtx.AccJobs, core.DefCtx.NoEnterUntil
+	defer func() {
+		strat.DefCtx.AccJobs, core.DefCtx.NoEnterUntil = oldJobs, oldNoEnter
+		ormo.DefCtx.Reset()
+	}()
+	ormo.DefCtx.Reset()
+	core.DefCtx.NoEnterUntil = make(map[string]int64)
+	job := &strat.StratJob{Strat: &strat.TradeStrat{Name: "demo"}, Symbol: &orm.ExSymbol{Symbol: "BTC/USDT"},
+		TimeFrame: "1h", Account: testAcc}
+	strat.DefCtx.AccJobs = map[string]map[string]map[string]*strat.StratJob{
+		testAcc: {"BTC/USDT_1h": {"demo": job}},
+	}
+	app := newOrderApp()
+	cases := []struct {
+		name string
+		path string
+		acc  string
+		body string
+		code int
+		msg  string
+	}{
+		{"open missing side", "/open_order", testAcc, `{"pair":"BTC/USDT","cost":10}`, 400, "Side"},
+		{"open bad side", "/open_order", testAcc, `{"pair":"BTC/USDT","side":"buy","cost":10}`, 400, "oneof"},
+		{"open zero amount", "/open_order", testAcc, `{"pair":"BTC/USDT","side":"long"}`, 400, "cost or amount"},
+		{"open negative cost", "/open_order", testAcc, `{"pair":"BTC/USDT","side":"long","cost":-1}`, 400, "gte"},
+		{"open bad pair", "/open_order", testAcc, `{"pair":"ETH/USDT","side":"long","cost":10}`, 400, "no running strategy job"},
+		{"open bad strategy", "/open_order", testAcc, `{"pair":"BTC/USDT","side":"long","cost":10,"strategy":"other"}`, 400, "no running strategy job"},
+		{"open no account", "/open_order", "", `{"pair":"BTC/USDT","side":"long","cost":10}`, 400, "X-Account"},
+		{"open not admin", "/open_order", testViewer, `{"pair":"BTC/USDT","side":"long","cost":10}`, 403, "admin role"},
+		{"edit missing id", "/edit_order", testAcc, `{"stopLoss":90}`, 400, "OrderID"},
+		{"edit bad callback", "/edit_order", testAcc, `{"orderId":1,"trailCallback":1.5}`, 400, "lt"},
+		{"edit zero limit", "/edit_order", testAcc, `{"orderId":1,"limit":0}`, 400, "gt"},
+		{"edit unknown id", "/edit_order", testAcc, `{"orderId":12345,"stopLoss":90}`, 404, "order not found"},
+		{"edit not admin", "/edit_order", testViewer, `{"orderId":1,"stopLoss":90}`, 403, "admin role"},
+	}
+	for _, c := range cases {
+		code, msg := doPost(t, app, c.path, c.acc, c.body)
+		if code != c.code || !strings.Contains(msg, c.msg) {
+			t.Errorf("%s: expect %d %q, got %d %q", c.name, c.code, c.msg, code, msg)
+		}
+	}
+}
+
+func TestOrderApiDisabledAcc(t *testing.T) {
+	oldJobs, oldNoEnter := strat.DefCtx.AccJobs, core.DefCtx.NoEnterUntil
+	defer func() {
+		strat.DefCtx.AccJobs, core.DefCtx.NoEnterUntil = oldJobs, oldNoEnter
+		ormo.DefCtx.Reset()
+	}()
+	ormo.DefCtx.Reset()
+	job := &strat.StratJob{Strat: &strat.TradeStrat{Name: "demo"}, Symbol: &orm.ExSymbol{Symbol: "BTC/USDT"},
+		TimeFrame: "1h", Account: testAcc}
+	strat.DefCtx.AccJobs = map[string]map[string]map[string]*strat.StratJob{
+		testAcc: {"BTC/USDT_1h": {"demo": job}},
+	}
+	core.DefCtx.NoEnterUntil = make(map[string]int64)
+	app := newOrderApp()
+	openBody := `{"pair":"BTC/USDT","side":"long","cost":10}`
+	until := delayEntry(testAcc, 3600)
+	code, msg := doPost(t, app, "/open_order", testAcc, openBody)
+	if code != 400 || !strings.Contains(msg, "entry disabled") {
+		t.Errorf("open should be rejected while entry disabled, got %d %q", code, msg)
+	}
+	if core.DefCtx.NoEnterUntil[testAcc] != until {
+		t.Errorf("rejected open should not change allowTradeAt")
+	}
+	// editing orders is still allowed for disabled account 禁止开单的账户仍可修改订单
+	code, msg = doPost(t, app, "/edit_order", testAcc, `{"orderId":12345,"stopLoss":90}`)
+	if code != 404 {
+		t.Errorf("edit should reach order lookup for disabled account, got %d %q", code, msg)
+	}
+	// other accounts are not affected 其他账户不受影响
+	code, msg = doPost(t, app, "/open_order", testViewer, openBody)
+	if code != 403 {
+		t.Errorf("viewer should fail by role, got %d %q", code, msg)
+	}
+	// enable again, then fail by a missing job instead of disabled 重新允许后，因缺少任务失败而非禁止开单
+	delayEntry(testAcc, 0)
+	code, msg = doPost(t, app, "/open_order", testAcc, `{"pair":"ETH/USDT","side":"long","cost":10}`)
+	if code != 400 || !strings.Contains(msg, "no running strategy job") {
+		t.Errorf("open should pass disabled check after enabled, got %d %q", code, msg)
+	}
+}
+
+func TestNewFailOpens(t *testing.T) {
+	cases := []struct {
+		old    map[string]int
+		cur    map[string]int
+		expect string
+	}{
+		{nil, nil, "unknown"},
+		{nil, map[string]int{"max_open": 1}, "max_open"},
+		{map[string]int{"max_open": 3, "low_cost": 1}, map[string]int{"max_open": 3, "low_cost": 2}, "low_cost"},
+		{map[string]int{"max_open": 3}, map[string]int{"max_open": 4, "low_cost": 1}, "low_cost, max_open"},
+		{map[string]int{"max_open": 3}, map[string]int{"max_open": 3}, "unknown"},
+	}
+	for _, c := range cases {
+		if res := newFailOpens(c.old, c.cur); res != c.expect {
+			t.Errorf("newFailOpens(%v, %v) = %q, expect %q", c.old, c.cur, res, c.expect)
+		}
+	}
+}
+
+func doPost(t *testing.T, app *fiber.App, path, acc, body string) (int, string) {
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if acc != "" {
+		req.Header.Set("X-Account", acc)
+	}
+	rsp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	data, _ := io.ReadAll(rsp.Body)
+	return rsp.StatusCode, string(data)
+}
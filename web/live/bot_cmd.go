@@ -0,0 +1,122 @@
+package live
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/banbox/banbot/biz"
+	"github.com/banbox/banbot/btime"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/orm/ormo"
+	"github.com/banbox/banbot/rpc"
+	"github.com/banbox/banexg/errs"
+	utils2 "github.com/banbox/banexg/utils"
+)
+
+// pauseSecs forbid entering until /resume when /pause has no hours 暂停未指定小时数时，禁止开单直到/resume
+const pauseSecs = int64(10 * 365 * 86400)
+
+func init() {
+	rpc.BotCmds["status"] = cmdStatus
+	rpc.BotCmds["balance"] = cmdBalance
+	rpc.BotCmds["orders"] = cmdOrders
+	rpc.BotCmds["forceexit"] = cmdForceExit
+	rpc.BotCmds["pause"] = cmdPause
+	rpc.BotCmds["resume"] = cmdResume
+}
+
+func cmdStatus(acc string, _ []string) (string, error) {
+	openOds, lock := ormo.DefCtx.GetOpenODs(acc)
+	lock.Lock()
+	odNum := len(openOds)
+	var profit float64
+	for _, od := range openOds {
+		profit += od.Profit
+	}
+	lock.Unlock()
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("account: %s\nopen orders: %d\nunrealized profit: %.2f\n", acc, odNum, profit))
+	b.WriteString(fmt.Sprintf("equity: %.2f\n", walletsFiat(biz.DefCtx.GetAccWallets(acc))))
+	stopUntil, _ := core.DefCtx.NoEnterUntil[acc]
+	if stopUntil > btime.UTCStamp() {
+		b.WriteString("entry paused until " + btime.ToDateStr(stopUntil, ""))
+	} else {
+		b.WriteString("entry allowed")
+	}
+	return b.String(), nil
+}
+
+func cmdBalance(acc string, _ []string) (string, error) {
+	wallets := biz.DefCtx.GetAccWallets(acc)
+	items := walletItems(wallets...)
+	sort.Slice(items, func(i, j int) bool {
+		return items[i]["total_fiat"].(float64) > items[j]["total_fiat"].(float64)
+	})
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("total: %.2f\n", walletsFiat(wallets)))
+	for _, it := range items {
+		b.WriteString(fmt.Sprintf("%v: %.6g free: %.6g upol: %.2f\n", it["symbol"], it["total"], it["free"], it["upol"]))
+	}
+	return b.String(), nil
+}
+
+func cmdOrders(acc string, _ []string) (string, error) {
+	openOds, lock := ormo.DefCtx.GetOpenODs(acc)
+	lock.Lock()
+	orders := utils2.ValsOfMap(openOds)
+	lock.Unlock()
+	if len(orders) == 0 {
+		return "no open orders", nil
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].ID < orders[j].ID
+	})
+	var b strings.Builder
+	for _, od := range orders {
+		side := "long"
+		if od.Short {
+			side = "short"
+		}
+		b.WriteString(fmt.Sprintf("#%d %s %s %s %s price: %.6g profit: %.2f\n", od.ID, od.Symbol, od.Timeframe,
+			side, od.Strategy, od.InitPrice, od.Profit))
+	}
+	return b.String(), nil
+}
+
+func cmdForceExit(acc string, args []string) (string, error) {
+	if len(args) == 0 {
+		return "", errs.NewMsg(errs.CodeParamRequired, "usage: /forceexit <order_id|all>")
+	}
+	closeNum, failNum, errMsg, err := forceExitOrders(acc, args[0])
+	if err != nil {
+		return "", err
+	}
+	res := fmt.Sprintf("closed: %d, failed: %d", closeNum, failNum)
+	if errMsg != "" {
+		res += "\n" + errMsg
+	}
+	return res, nil
+}
+
+func cmdPause(acc string, args []string) (string, error) {
+	secs := pauseSecs
+	if len(args) > 0 {
+		hours, err_ := strconv.ParseFloat(args[0], 64)
+		if err_ != nil || hours <= 0 {
+			return "", errs.NewMsg(errs.CodeParamInvalid, "usage: /pause [hours]")
+		}
+		secs = int64(hours * 3600)
+	}
+	untilMS := delayEntry(acc, secs)
+	if secs == pauseSecs {
+		return "entry paused until /resume", nil
+	}
+	return "entry paused until " + btime.ToDateStr(untilMS, ""), nil
+}
+
+func cmdResume(acc string, _ []string) (string, error) {
+	delayEntry(acc, 0)
+	return "entry resumed", nil
+}
@@ -37,6 +37,7 @@ func StartApi() *errs.Error {
 	base.RegApiWebsocket(app.Group("/api/ws"))
 	regApiBiz(app.Group("/api/bot", AuthMiddleware(cfg.JWTSecretKey)))
 	regApiPub(app.Group("/api"))
+	app.Get("/metrics", MetricsAuth(cfg.MetricsToken), getMetrics)
 
 	// 添加静态文件服务
 	err_ := ui.ServeStatic(app)
@@ -0,0 +1,210 @@
+package live
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/banbox/banbot/biz"
+	"github.com/banbox/banbot/btime"
+	"github.com/banbox/banbot/config"
+	"github.com/banbox/banbot/core"
+	"github.com/banbox/banbot/orm/ormo"
+	"github.com/banbox/banbot/strat"
+	"github.com/banbox/banbot/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SpiderConnected is set by the live trader, returns whether connected to spider 由实盘交易器设置，返回是否连接到爬虫
+var SpiderConnected func() bool
+
+/*
+MetricsAuth
+check the bearer token of /metrics when api_server.metrics_token is set
+设置了api_server.metrics_token时，校验/metrics的Bearer令牌
+*/
+func MetricsAuth(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token != "" && c.Get(fiber.HeaderAuthorization) != "Bearer "+token {
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+		}
+		return c.Next()
+	}
+}
+
+/*
+getMetrics
+output runtime status in prometheus text exposition format
+以prometheus文本格式输出运行状态
+*/
+func getMetrics(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	return c.SendString(DumpMetrics())
+}
+
+type promWriter struct {
+	b strings.Builder
+}
+
+func (w *promWriter) header(name, typ, help string) {
+	w.b.WriteString(fmt.Sprintf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ))
+}
+
+// sample write a line with label pairs: key1, val1, key2, val2... 写一行指标，标签按键值对传入
+func (w *promWriter) sample(name string, val float64, labels ...string) {
+	w.b.WriteString(name)
+	if len(labels) > 0 {
+		w.b.WriteString("{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.b.WriteString(",")
+			}
+			w.b.WriteString(labels[i])
+			w.b.WriteString(`="`)
+			w.b.WriteString(escapeLabel(labels[i+1]))
+			w.b.WriteString(`"`)
+		}
+		w.b.WriteString("}")
+	}
+	w.b.WriteString(" ")
+	w.b.WriteString(formatMetric(val))
+	w.b.WriteString("\n")
+}
+
+func escapeLabel(val string) string {
+	val = strings.ReplaceAll(val, `\`, `\\`)
+	val = strings.ReplaceAll(val, "\n", `\n`)
+	return strings.ReplaceAll(val, `"`, `\"`)
+}
+
+func formatMetric(val float64) string {
+	if math.IsNaN(val) {
+		return "NaN"
+	} else if math.IsInf(val, 1) {
+		return "+Inf"
+	} else if math.IsInf(val, -1) {
+		return "-Inf"
+	}
+	return strconv.FormatFloat(val, 'g', -1, 64)
+}
+
+func boolMetric(val bool) float64 {
+	if val {
+		return 1
+	}
+	return 0
+}
+
+/*
+DumpMetrics
+collect metrics of accounts, orders, exchange requests, klines and spider
+收集账户、订单、交易所请求、K线和爬虫的指标
+*/
+func DumpMetrics() string {
+	w := &promWriter{}
+	curMS := btime.TimeMS()
+	accounts := utils.KeysOfMap(config.Accounts)
+	slices.Sort(accounts)
+
+	w.header("banbot_info", "gauge", "Bot name and version")
+	w.sample("banbot_info", 1, "name", config.Name, "version", core.Version, "env", core.RunEnv)
+
+	// wallets 钱包
+	w.header("banbot_account_equity", "gauge", "Total legal value of wallets including unrealized pnl")
+	for _, acc := range accounts {
+		w.sample("banbot_account_equity", biz.DefCtx.AccTotalLegal(acc, true), "account", acc)
+	}
+	w.header("banbot_account_available", "gauge", "Available legal value of wallets")
+	for _, acc := range accounts {
+		w.sample("banbot_account_available", biz.DefCtx.SumAccWallets(acc, func(w *biz.BanWallets) float64 {
+			return w.AvaLegal(nil)
+		}), "account", acc)
+	}
+	w.header("banbot_account_unrealized_pnl", "gauge", "Unrealized pnl of open positions")
+	for _, acc := range accounts {
+		w.sample("banbot_account_unrealized_pnl", biz.DefCtx.SumAccWallets(acc, func(w *biz.BanWallets) float64 {
+			return w.UnrealizedPOLLegal(nil)
+		}), "account", acc)
+	}
+
+	// open orders 未平仓订单
+	w.header("banbot_open_orders", "gauge", "Number of open orders by strategy")
+	for _, acc := range accounts {
+		openOds, lock := ormo.DefCtx.GetOpenODs(acc)
+		stgNums := make(map[string]int)
+		lock.Lock()
+		for _, od := range openOds {
+			stgNums[od.Strategy] += 1
+		}
+		lock.Unlock()
+		stgNames := utils.KeysOfMap(stgNums)
+		slices.Sort(stgNames)
+		for _, stgName := range stgNames {
+			w.sample("banbot_open_orders", float64(stgNums[stgName]), "account", acc, "strategy", stgName)
+		}
+	}
+
+	// exchange requests of LiveOrderMgr 实盘订单管理器的交易所请求
+	stats := biz.GetSubmitStats()
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Account != stats[j].Account {
+			return stats[i].Account < stats[j].Account
+		}
+		return stats[i].Action < stats[j].Action
+	})
+	w.header("banbot_order_submit_seconds", "histogram", "Latency of order requests to exchange")
+	for _, sta := range stats {
+		for i, le := range biz.SubmitBuckets {
+			w.sample("banbot_order_submit_seconds_bucket", float64(sta.Buckets[i]), "account", sta.Account,
+				"action", sta.Action, "le", formatMetric(le))
+		}
+		w.sample("banbot_order_submit_seconds_bucket", float64(sta.Count), "account", sta.Account,
+			"action", sta.Action, "le", "+Inf")
+		w.sample("banbot_order_submit_seconds_sum", sta.SumSecs, "account", sta.Account, "action", sta.Action)
+		w.sample("banbot_order_submit_seconds_count", float64(sta.Count), "account", sta.Account, "action", sta.Action)
+	}
+	w.header("banbot_order_submit_errors_total", "counter", "Failed order requests to exchange")
+	for _, sta := range stats {
+		w.sample("banbot_order_submit_errors_total", float64(sta.Errors), "account", sta.Account, "action", sta.Action)
+	}
+
+	// klines from spider 从爬虫收到的K线
+	// alert when delay exceeds 2 intervals like CronKlineDelays 可像CronKlineDelays一样在延迟超过2倍间隔时报警
+	pairs := utils.KeysOfMap(core.PairCopiedMs)
+	slices.Sort(pairs)
+	w.header("banbot_kline_delay_seconds", "gauge", "Seconds since the end of latest kline received from spider")
+	for _, pair := range pairs {
+		wait := core.PairCopiedMs[pair]
+		w.sample("banbot_kline_delay_seconds", float64(max(0, curMS-wait[0]))/1000, "pair", pair)
+	}
+	w.header("banbot_kline_interval_seconds", "gauge", "Expected interval of klines from spider")
+	for _, pair := range pairs {
+		w.sample("banbot_kline_interval_seconds", float64(core.PairCopiedMs[pair][1])/1000, "pair", pair)
+	}
+	w.header("banbot_spider_connected", "gauge", "Whether connected to spider")
+	if SpiderConnected != nil {
+		w.sample("banbot_spider_connected", boolMetric(SpiderConnected()))
+	}
+	if core.LastCopiedMs > 0 {
+		w.header("banbot_spider_last_recv_seconds", "gauge", "Seconds since the last kline pushed by spider")
+		w.sample("banbot_spider_last_recv_seconds", float64(max(0, curMS-core.LastCopiedMs))/1000)
+	}
+
+	// failed entries 开单失败
+	w.header("banbot_fail_open_total", "counter", "Number of rejected entries by reason")
+	failOpens := strat.DefCtx.GetAccFailOpens()
+	failAccs := utils.KeysOfMap(failOpens)
+	slices.Sort(failAccs)
+	for _, acc := range failAccs {
+		tagMap := failOpens[acc]
+		tags := utils.KeysOfMap(tagMap)
+		slices.Sort(tags)
+		for _, tag := range tags {
+			w.sample("banbot_fail_open_total", float64(tagMap[tag]), "account", acc, "reason", tag)
+		}
+	}
+	return w.b.String()
+}
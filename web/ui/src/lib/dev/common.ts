@@ -21,6 +21,73 @@ export interface BackTestPlots {
   real: number[];
   unrealizedPOL: number[];
   withDraw: number[];
+  noEnter?: number[];
+}
+
+export interface FatalStopHit {
+  timeMS: number;
+  minutes: number;
+  lossPct: number;
+  untilMS: number;
+}
+
+export interface WFMetrics {
+  profitPct: number;
+  drawDownPct: number;
+  sharpe: number;
+  orderNum: number;
+  winRatePct: number;
+}
+
+export interface WFWindow {
+  isStart: number;
+  isEnd: number;
+  oosStart: number;
+  oosEnd: number;
+  is: WFMetrics;
+  oos: WFMetrics;
+  params: Record<string, Record<string, number>>;
+  drift: number; // 参数相比上个窗口的平均变化百分比
+  wfe: number; // 滚动前进效率
+}
+
+export interface WalkForward {
+  windows: WFWindow[];
+  isProfitPct: number;
+  oosProfitPct: number;
+  avgDrift: number;
+  wfe: number;
+}
+
+export interface MCStat {
+  mean: number;
+  p5: number;
+  p25: number;
+  p50: number;
+  p75: number;
+  p95: number;
+}
+
+export interface MCPath {
+  finalBalance: number;
+  maxDrawDownPct: number;
+  recoveryDays: number;
+}
+
+export interface MCMethodRes {
+  method: string; // shuffle, bootstrap, skip, slippage
+  finalBalance: MCStat;
+  maxDrawDownPct: MCStat;
+  recoveryDays: MCStat;
+  lossProb: number; // 最终余额低于初始的概率百分比
+}
+
+export interface MonteCarloRes {
+  args: {runs: number, skipRate: number, slipPct: number, seed: number, initBalance: number};
+  initBalance: number;
+  orderNum: number;
+  origin: MCPath;
+  methods: MCMethodRes[];
 }
 
 export interface BacktestDetail {
@@ -48,6 +115,8 @@ export interface BacktestDetail {
   totCost: number;
   showDrawDownPct: number;
   showDrawDownVal: number;
+  fatalStops?: FatalStopHit[];
+  walkForward?: WalkForward;
 }
 
 export interface BackTestTask {
@@ -30,4 +30,33 @@ export interface BtTask {
   stakeAmount?: number;
   info?: string;
   note?: string;
+
+  trialNum?: number; // 超参数优化的总轮次
+  bestId?: string; // 超参数优化最佳轮次ID
+  bestPol?: string; // 超参数优化最佳轮次所属策略
+  bestScore?: number; // 超参数优化最佳得分
+}
+
+export interface TrialResult {
+  orderNum: number;
+  maxOpenOrders: number;
+  totProfit: number;
+  totProfitPct: number;
+  totFee: number;
+  winRatePct: number;
+  maxDrawDownPct: number;
+  showDrawDownPct: number;
+  finBalance: number;
+  sharpeRatio: number;
+  sortinoRatio: number;
+}
+
+export interface OptTrial {
+  pol: string; // 策略分组，如 ma:demo/1h/BTC/USDT
+  id: string;
+  score: number;
+  pruned: number; // 被剪枝时的阶段，0表示未剪枝
+  params: Record<string, number>;
+  result?: TrialResult;
+  createAt: number;
 }
@@ -90,6 +90,12 @@ export function initWsConn(cb?: () => void) {
                         return s;
                     });
                 }
+            } else if (result.type === 'optTrial') {
+                // 超参数优化任务的新试验结果
+                const key = `optTrial_${result.taskId}`;
+                if (listeners[key]) {
+                    listeners[key].forEach(callback => callback(result));
+                }
             } else if(result.type){
               console.log(`ws dev unknown msg:`, result);
             }